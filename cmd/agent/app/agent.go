@@ -36,6 +36,10 @@ type Agent struct {
 	httpServer *http.Server
 	logger     *zap.Logger
 	closer     io.Closer
+	// reporters are closed in order when the agent stops, outermost first
+	reporters []io.Closer
+	// discovery stops looking up the collectors when the agent stops
+	discovery io.Closer
 }
 
 // NewAgent creates the new Agent.
@@ -77,4 +81,10 @@ func (a *Agent) Stop() {
 		go processor.Stop()
 	}
 	a.closer.Close()
+	for _, reporter := range a.reporters {
+		reporter.Close()
+	}
+	if a.discovery != nil {
+		a.discovery.Close()
+	}
 }
@@ -22,7 +22,9 @@ package app
 
 import (
 	"fmt"
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/apache/thrift/lib/go/thrift"
 	"github.com/pkg/errors"
@@ -33,9 +35,11 @@ import (
 	"github.com/uber/jaeger/cmd/agent/app/httpserver"
 	"github.com/uber/jaeger/cmd/agent/app/processors"
 	"github.com/uber/jaeger/cmd/agent/app/reporter"
+	httpreporter "github.com/uber/jaeger/cmd/agent/app/reporter/http"
 	tchreporter "github.com/uber/jaeger/cmd/agent/app/reporter/tchannel"
 	"github.com/uber/jaeger/cmd/agent/app/servers"
 	"github.com/uber/jaeger/cmd/agent/app/servers/thriftudp"
+	"github.com/uber/jaeger/pkg/discovery"
 	jmetrics "github.com/uber/jaeger/pkg/metrics"
 	zipkinThrift "github.com/uber/jaeger/thrift-gen/agent"
 	jaegerThrift "github.com/uber/jaeger/thrift-gen/jaeger"
@@ -45,6 +49,7 @@ const (
 	defaultQueueSize     = 1000
 	defaultMaxPacketSize = 65000
 	defaultServerWorkers = 10
+	defaultServerReaders = 1
 	defaultMinPeers      = 3
 
 	defaultHTTPServerHostPort = ":5778"
@@ -57,10 +62,18 @@ const (
 
 	compactProtocol protocol = "compact"
 	binaryProtocol           = "binary"
+
+	tchannelReporterType reporterType = "tchannel"
+	httpReporterType     reporterType = "http"
+
+	dnsDiscoveryType  discoveryType = "dns"
+	fileDiscoveryType discoveryType = "file"
 )
 
 type model string
 type protocol string
+type reporterType string
+type discoveryType string
 
 var (
 	errNoReporters = errors.New("agent requires at least one Reporter")
@@ -82,6 +95,29 @@ type Builder struct {
 	DiscoveryMinPeers    int      `yaml:"minPeers"`
 	CollectorServiceName string   `yaml:"collectorServiceName"`
 
+	// Tokens are the API tokens added to the spans forwarded to the collector
+	Tokens reporter.Tokens `yaml:"tokens"`
+	// TokenFile is a YAML file with the API tokens, it takes precedence over Tokens
+	TokenFile string `yaml:"tokenFile"`
+	// TokenTagKey is the name of the tag holding the API token
+	TokenTagKey string `yaml:"tokenTagKey"`
+
+	// ProcessTags are added to the process of every batch, values can be static, ${ENV_VAR[:default]}, @hostname or @ip
+	ProcessTags map[string]string `yaml:"processTags"`
+	// ProcessTagsPrecedence decides whether the client (default) or the agent value wins when both set a tag
+	ProcessTagsPrecedence string `yaml:"processTagsPrecedence"`
+
+	// ReporterType selects how spans are forwarded to the collectors, tchannel (default) or http
+	ReporterType reporterType `yaml:"reporterType"`
+	// HTTPReporter holds the configuration of the http reporter
+	HTTPReporter httpreporter.Builder `yaml:"httpReporter"`
+	// Buffer configures the buffering and replay of the batches the collectors failed to accept
+	Buffer reporter.BufferOptions `yaml:"buffer"`
+	// Coalesce configures the merging of the small batches of a same process before they are forwarded
+	Coalesce reporter.CoalesceOptions `yaml:"coalesce"`
+	// Discovery configures the discovery of the collectors the TChannel reporter connects to
+	Discovery DiscoveryConfiguration `yaml:"discovery"`
+
 	tchreporter.Builder
 
 	otherReporters []reporter.Reporter
@@ -98,14 +134,59 @@ type ProcessorConfiguration struct {
 
 // ServerConfiguration holds config for a server that receives spans from the network
 type ServerConfiguration struct {
-	QueueSize     int    `yaml:"queueSize"`
-	MaxPacketSize int    `yaml:"maxPacketSize"`
-	HostPort      string `yaml:"hostPort" validate:"nonzero"`
+	QueueSize        int    `yaml:"queueSize"`
+	MaxPacketSize    int    `yaml:"maxPacketSize"`
+	HostPort         string `yaml:"hostPort" validate:"nonzero"`
+	SocketBufferSize int    `yaml:"socketBufferSize"`
+	Readers          int    `yaml:"readers"`
 }
 
 // HTTPServerConfiguration holds config for a server providing sampling strategies and baggage restrictions to clients
 type HTTPServerConfiguration struct {
 	HostPort string `yaml:"hostPort" validate:"nonzero"`
+	// SamplingCacheTTL is how long a sampling strategy is served from the cache before being fetched again
+	SamplingCacheTTL time.Duration `yaml:"samplingCacheTTL"`
+	// SamplingStrategiesFile is a JSON file with the sampling strategies served when the collector
+	// cannot be reached and no strategy of the service is cached
+	SamplingStrategiesFile string `yaml:"samplingStrategiesFile"`
+}
+
+// DiscoveryConfiguration holds config for the discovery of the collectors, used instead of a static list
+type DiscoveryConfiguration struct {
+	// Type is dns or file, discovery is disabled when empty
+	Type discoveryType `yaml:"type"`
+	// DNSNames are resolved to the collectors, host:port for A records or SRV names
+	DNSNames []string `yaml:"dnsNames"`
+	// DNSRecordType is A (default, also resolves AAAA records) or SRV
+	DNSRecordType string `yaml:"dnsRecordType"`
+	// File lists the host:port of the collectors
+	File string `yaml:"file"`
+	// RefreshInterval is the interval between two lookups of the collectors
+	RefreshInterval time.Duration `yaml:"refreshInterval"`
+}
+
+// collectorDiscoverer yields the collectors and notifies the peer list manager when they change
+type collectorDiscoverer interface {
+	discovery.Discoverer
+	discovery.Notifier
+	io.Closer
+}
+
+// createDiscoverer creates the discoverer of the collectors, it returns nil if discovery is disabled
+func (c DiscoveryConfiguration) createDiscoverer(logger *zap.Logger) (collectorDiscoverer, error) {
+	switch c.Type {
+	case "":
+		return nil, nil
+	case dnsDiscoveryType:
+		return discovery.NewDNSDiscoverer(nil, c.DNSNames, c.DNSRecordType, c.RefreshInterval, logger)
+	case fileDiscoveryType:
+		if c.File == "" {
+			return nil, errors.New("no discovery file provided")
+		}
+		return discovery.NewFileDiscoverer(c.File, c.RefreshInterval, logger), nil
+	default:
+		return nil, fmt.Errorf("unknown discovery type %v", c.Type)
+	}
 }
 
 // WithReporter adds auxiliary reporters.
@@ -120,10 +201,20 @@ func (b *Builder) WithMetricsFactory(mf metrics.Factory) *Builder {
 	return b
 }
 
-func (b *Builder) createMainReporter(mFactory metrics.Factory, logger *zap.Logger) (*tchreporter.Reporter, error) {
+func (b *Builder) createMainReporter(
+	mFactory metrics.Factory,
+	logger *zap.Logger,
+	discoverer collectorDiscoverer,
+) (*tchreporter.Reporter, error) {
 	if len(b.Builder.CollectorHostPorts) == 0 {
 		b.Builder.CollectorHostPorts = b.CollectorHostPorts
 	}
+	if discoverer != nil {
+		if len(b.Builder.CollectorHostPorts) != 0 {
+			return nil, errors.New("a static list of collectors cannot be used with discovery")
+		}
+		b.Builder.WithDiscoverer(discoverer).WithDiscoveryNotifier(discoverer)
+	}
 	if b.Builder.CollectorServiceName == "" {
 		b.Builder.CollectorServiceName = b.CollectorServiceName
 	}
@@ -140,30 +231,118 @@ func (b *Builder) getMetricsFactory() (metrics.Factory, error) {
 	return b.Metrics.CreateMetricsFactory("jaeger_agent")
 }
 
+func (b *Builder) getTokens() (reporter.Tokens, error) {
+	if b.TokenFile == "" {
+		return b.Tokens, nil
+	}
+	tokens, err := reporter.LoadTokens(b.TokenFile)
+	if err != nil {
+		return tokens, err
+	}
+	if tokens.Default == "" {
+		tokens.Default = b.Tokens.Default
+	}
+	return tokens, nil
+}
+
 // CreateAgent creates the Agent
 func (b *Builder) CreateAgent(logger *zap.Logger) (*Agent, error) {
 	mFactory, err := b.getMetricsFactory()
 	if err != nil {
 		return nil, errors.Wrap(err, "cannot create metrics factory")
 	}
-	mainReporter, err := b.createMainReporter(mFactory, logger)
+	discoverer, err := b.Discovery.createDiscoverer(logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create collectors discovery")
+	}
+	mainReporter, err := b.createMainReporter(mFactory, logger, discoverer)
 	if err != nil {
 		return nil, errors.Wrap(err, "cannot create main Reporter")
 	}
+	// the TChannel reporter is always created since the sampling and baggage endpoints use its channel
 	var rep reporter.Reporter = mainReporter
+	switch b.ReporterType {
+	case "", tchannelReporterType:
+	case httpReporterType:
+		if rep, err = b.HTTPReporter.CreateReporter(mFactory, logger); err != nil {
+			return nil, errors.Wrap(err, "cannot create HTTP Reporter")
+		}
+	default:
+		return nil, fmt.Errorf("unknown reporter type %v", b.ReporterType)
+	}
+	statusReporter := reporter.NewStatusReporter(rep)
+	rep = statusReporter
+	var closers []io.Closer
+	if b.Buffer.Enabled() {
+		bufferedReporter, err := reporter.NewBufferedReporter(rep, b.Buffer, mFactory, logger)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot create buffered Reporter")
+		}
+		rep = bufferedReporter
+		closers = append(closers, bufferedReporter)
+	}
+	if b.Coalesce.Enabled() {
+		coalescingReporter := reporter.NewCoalescingReporter(rep, b.Coalesce, mFactory, logger)
+		rep = coalescingReporter
+		// the coalesced spans are flushed before the buffer is closed
+		closers = append([]io.Closer{coalescingReporter}, closers...)
+	}
+	tokens, err := b.getTokens()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot load API tokens")
+	}
+	if !tokens.Empty() {
+		tagKey := b.TokenTagKey
+		if tagKey == "" {
+			tagKey = reporter.DefaultTokenTagKey
+		}
+		rep = reporter.NewTokenReporter(rep, tagKey, tokens, mFactory)
+	}
+	if len(b.ProcessTags) > 0 {
+		precedence, err := reporter.ParseTagPrecedence(b.ProcessTagsPrecedence)
+		if err != nil {
+			return nil, err
+		}
+		tags, err := reporter.ResolveProcessTags(b.ProcessTags)
+		if err != nil {
+			return nil, err
+		}
+		rep = reporter.NewProcessTagsReporter(rep, tags, precedence)
+	}
 	if len(b.otherReporters) > 0 {
-		reps := append([]reporter.Reporter{mainReporter}, b.otherReporters...)
+		reps := append([]reporter.Reporter{rep}, b.otherReporters...)
 		rep = reporter.NewMultiReporter(reps...)
 	}
 	processors, err := b.GetProcessors(rep, mFactory)
 	if err != nil {
 		return nil, err
 	}
-	httpServer := b.HTTPServer.GetHTTPServer(b.CollectorServiceName, mainReporter.Channel(), mFactory)
+	httpServer, err := b.HTTPServer.GetHTTPServer(b.CollectorServiceName, mainReporter.Channel(), mFactory)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create HTTP server")
+	}
+	httpserver.RegisterSpanHandlers(httpServer.Handler.(*http.ServeMux), rep, mFactory)
 	if b.metricsFactory == nil {
 		b.Metrics.RegisterHandler(httpServer.Handler.(*http.ServeMux))
 	}
-	return NewAgent(processors, httpServer, logger), nil
+	status := &statusHandler{
+		reporterType: b.ReporterType,
+		peers:        mainReporter.Peers,
+		submissions:  statusReporter,
+		started:      time.Now(),
+	}
+	for i, processor := range processors {
+		if p, ok := processor.(processorStatusProvider); ok {
+			status.processors = append(status.processors, statusProcessor{config: b.Processors[i], processor: p})
+		}
+	}
+	registerStatusHandlers(httpServer.Handler.(*http.ServeMux), status)
+	agent := NewAgent(processors, httpServer, logger)
+	agent.reporters = closers
+	if discoverer != nil {
+		agent.discovery = discoverer
+	}
+	return agent, nil
 }
 
 // GetProcessors creates Processors with attached Reporter
@@ -197,12 +376,20 @@ func (b *Builder) GetProcessors(rep reporter.Reporter, mFactory metrics.Factory)
 }
 
 // GetHTTPServer creates an HTTP server that provides sampling strategies and baggage restrictions to client libraries.
-func (c HTTPServerConfiguration) GetHTTPServer(svc string, channel *tchannel.Channel, mFactory metrics.Factory) *http.Server {
-	mgr := httpserver.NewCollectorProxy(svc, channel, mFactory)
+func (c HTTPServerConfiguration) GetHTTPServer(svc string, channel *tchannel.Channel, mFactory metrics.Factory) (*http.Server, error) {
+	mgr, err := httpserver.NewSamplingCache(
+		httpserver.NewCollectorProxy(svc, channel, mFactory),
+		c.SamplingCacheTTL,
+		c.SamplingStrategiesFile,
+		mFactory,
+	)
+	if err != nil {
+		return nil, err
+	}
 	if c.HostPort == "" {
 		c.HostPort = defaultHTTPServerHostPort
 	}
-	return httpserver.NewHTTPServer(c.HostPort, mgr, mFactory)
+	return httpserver.NewHTTPServer(c.HostPort, mgr, mFactory), nil
 }
 
 // GetThriftProcessor gets a TBufferedServer backed Processor using the collector configuration
@@ -228,6 +415,7 @@ func (c *ProcessorConfiguration) applyDefaults() {
 func (c *ServerConfiguration) applyDefaults() {
 	c.QueueSize = defaultInt(c.QueueSize, defaultQueueSize)
 	c.MaxPacketSize = defaultInt(c.MaxPacketSize, defaultMaxPacketSize)
+	c.Readers = defaultInt(c.Readers, defaultServerReaders)
 }
 
 // getUDPServer gets a TBufferedServer backed server using the server configuration
@@ -237,12 +425,26 @@ func (c *ServerConfiguration) getUDPServer(mFactory metrics.Factory) (servers.Se
 	if c.HostPort == "" {
 		return nil, fmt.Errorf("no host:port provided for udp server: %+v", *c)
 	}
-	transport, err := thriftudp.NewTUDPServerTransport(c.HostPort)
-	if err != nil {
-		return nil, err
+	options := thriftudp.ServerOptions{
+		SocketBufferSize: c.SocketBufferSize,
+		ReusePort:        c.Readers > 1,
+	}
+	transports := make([]thrift.TTransport, 0, c.Readers)
+	hostPort := c.HostPort
+	for i := 0; i < c.Readers; i++ {
+		transport, err := thriftudp.NewTUDPServerTransportWithOptions(hostPort, options)
+		if err != nil {
+			for _, t := range transports {
+				t.Close()
+			}
+			return nil, err
+		}
+		// the remaining readers bind to the resolved address, in case an ephemeral port was requested
+		hostPort = transport.Addr().String()
+		transports = append(transports, transport)
 	}
 
-	return servers.NewTBufferedServer(transport, c.QueueSize, c.MaxPacketSize, mFactory)
+	return servers.NewTBufferedMultiServer(transports, c.QueueSize, c.MaxPacketSize, mFactory)
 }
 
 func defaultInt(value int, defaultVal int) int {
@@ -22,8 +22,14 @@ package app
 
 import (
 	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -31,6 +37,9 @@ import (
 	"go.uber.org/zap"
 	"gopkg.in/yaml.v2"
 
+	"github.com/uber/jaeger/cmd/agent/app/reporter"
+	"github.com/uber/jaeger/cmd/agent/app/servers"
+	"github.com/uber/jaeger/pkg/discovery"
 	"github.com/uber/jaeger/thrift-gen/jaeger"
 	"github.com/uber/jaeger/thrift-gen/zipkincore"
 )
@@ -54,9 +63,13 @@ processors:
         queueSize: 2000
         maxPacketSize: 65001
         hostPort: 3.3.3.3:6832
+        socketBufferSize: 4194304
+        readers: 4
 
 httpServer:
     hostPort: 4.4.4.4:5778
+    samplingCacheTTL: 30s
+    samplingStrategiesFile: /etc/jaeger/strategies.json
 
 collectorHostPorts:
     - 127.0.0.1:14267
@@ -65,6 +78,30 @@ collectorHostPorts:
 
 collectorServiceName: some-collector-service
 minPeers: 4
+
+tokens:
+    default: secret
+    services:
+        frontend: frontend-secret
+tokenTagKey: token
+
+processTags:
+    dc: us-east
+    host: "@hostname"
+processTagsPrecedence: agent
+
+discovery:
+    type: dns
+    dnsNames:
+        - _tchannel._tcp.jaeger-collector
+    dnsRecordType: SRV
+
+reporterType: http
+httpReporter:
+    collectorEndpoint: https://jaeger-collector:14268
+    headers:
+        X-Tenant: acme
+    maxRetries: 5
 `
 
 func TestBuilderFromConfig(t *testing.T) {
@@ -84,6 +121,7 @@ func TestBuilderFromConfig(t *testing.T) {
 			QueueSize:     1000,
 			MaxPacketSize: 65000,
 			HostPort:      "1.1.1.1:5775",
+			Readers:       1,
 		},
 	}, cfg.Processors[0])
 	assert.Equal(t, ProcessorConfiguration{
@@ -94,6 +132,7 @@ func TestBuilderFromConfig(t *testing.T) {
 			QueueSize:     1000,
 			MaxPacketSize: 65000,
 			HostPort:      "2.2.2.2:6831",
+			Readers:       1,
 		},
 	}, cfg.Processors[1])
 	assert.Equal(t, ProcessorConfiguration{
@@ -101,12 +140,16 @@ func TestBuilderFromConfig(t *testing.T) {
 		Protocol: binaryProtocol,
 		Workers:  20,
 		Server: ServerConfiguration{
-			QueueSize:     2000,
-			MaxPacketSize: 65001,
-			HostPort:      "3.3.3.3:6832",
+			QueueSize:        2000,
+			MaxPacketSize:    65001,
+			HostPort:         "3.3.3.3:6832",
+			SocketBufferSize: 4194304,
+			Readers:          4,
 		},
 	}, cfg.Processors[2])
 	assert.Equal(t, "4.4.4.4:5778", cfg.HTTPServer.HostPort)
+	assert.Equal(t, 30*time.Second, cfg.HTTPServer.SamplingCacheTTL)
+	assert.Equal(t, "/etc/jaeger/strategies.json", cfg.HTTPServer.SamplingStrategiesFile)
 
 	assert.Equal(t, 4, cfg.DiscoveryMinPeers)
 	assert.Equal(t, "some-collector-service", cfg.CollectorServiceName)
@@ -114,6 +157,18 @@ func TestBuilderFromConfig(t *testing.T) {
 		t,
 		[]string{"127.0.0.1:14267", "127.0.0.1:14268", "127.0.0.1:14269"},
 		cfg.CollectorHostPorts)
+	assert.Equal(t, "secret", cfg.Tokens.Default)
+	assert.Equal(t, map[string]string{"frontend": "frontend-secret"}, cfg.Tokens.Services)
+	assert.Equal(t, "token", cfg.TokenTagKey)
+	assert.Equal(t, map[string]string{"dc": "us-east", "host": "@hostname"}, cfg.ProcessTags)
+	assert.Equal(t, "agent", cfg.ProcessTagsPrecedence)
+	assert.Equal(t, dnsDiscoveryType, cfg.Discovery.Type)
+	assert.Equal(t, []string{"_tchannel._tcp.jaeger-collector"}, cfg.Discovery.DNSNames)
+	assert.Equal(t, discovery.SRVRecord, cfg.Discovery.DNSRecordType)
+	assert.Equal(t, httpReporterType, cfg.ReporterType)
+	assert.Equal(t, "https://jaeger-collector:14268", cfg.HTTPReporter.CollectorEndpoint)
+	assert.Equal(t, map[string]string{"X-Tenant": "acme"}, cfg.HTTPReporter.Headers)
+	assert.Equal(t, 5, cfg.HTTPReporter.MaxRetries)
 }
 
 func TestBuilderWithExtraReporter(t *testing.T) {
@@ -124,6 +179,158 @@ func TestBuilderWithExtraReporter(t *testing.T) {
 	assert.NotNil(t, agent)
 }
 
+func TestBuilderSpanHandlers(t *testing.T) {
+	cfg := &Builder{}
+	agent, err := cfg.CreateAgent(zap.NewNop())
+	require.NoError(t, err)
+	_, pattern := agent.httpServer.Handler.(*http.ServeMux).Handler(httptest.NewRequest(http.MethodPost, "/api/traces", nil))
+	assert.Equal(t, "/api/", pattern)
+}
+
+func TestBuilderWithTokens(t *testing.T) {
+	file, err := ioutil.TempFile("", "tokens")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+	_, err = file.WriteString("services:\n  frontend: frontend-secret\n")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	cfg := &Builder{TokenFile: file.Name()}
+	cfg.Tokens.Default = "secret"
+	tokens, err := cfg.getTokens()
+	require.NoError(t, err)
+	assert.Equal(t, "secret", tokens.Default)
+	assert.Equal(t, map[string]string{"frontend": "frontend-secret"}, tokens.Services)
+
+	agent, err := cfg.CreateAgent(zap.NewNop())
+	assert.NoError(t, err)
+	assert.NotNil(t, agent)
+}
+
+func TestBuilderWithTokenFileError(t *testing.T) {
+	cfg := &Builder{TokenFile: "/does/not/exist.yaml"}
+	agent, err := cfg.CreateAgent(zap.NewNop())
+	assert.EqualError(t, err, "cannot load API tokens: open /does/not/exist.yaml: no such file or directory")
+	assert.Nil(t, agent)
+}
+
+func TestBuilderWithProcessTags(t *testing.T) {
+	cfg := &Builder{ProcessTags: map[string]string{"dc": "us-east", "host": "@hostname"}}
+	agent, err := cfg.CreateAgent(zap.NewNop())
+	assert.NoError(t, err)
+	assert.NotNil(t, agent)
+
+	cfg.ProcessTagsPrecedence = "collector"
+	_, err = cfg.CreateAgent(zap.NewNop())
+	assert.EqualError(t, err, `unknown tag precedence "collector", expected "client" or "agent"`)
+}
+
+func TestBuilderWithHTTPReporter(t *testing.T) {
+	cfg := &Builder{ReporterType: httpReporterType}
+	cfg.HTTPReporter.CollectorEndpoint = "http://localhost:14268"
+	agent, err := cfg.CreateAgent(zap.NewNop())
+	assert.NoError(t, err)
+	assert.NotNil(t, agent)
+
+	cfg = &Builder{ReporterType: httpReporterType}
+	_, err = cfg.CreateAgent(zap.NewNop())
+	assert.EqualError(t, err, "cannot create HTTP Reporter: no collector endpoint provided")
+
+	cfg = &Builder{ReporterType: reporterType("bad")}
+	_, err = cfg.CreateAgent(zap.NewNop())
+	assert.EqualError(t, err, "unknown reporter type bad")
+}
+
+func TestBuilderWithBuffer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spill")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := &Builder{Buffer: reporter.BufferOptions{MaxBatches: 10, SpillDir: dir}}
+	agent, err := cfg.CreateAgent(zap.NewNop())
+	require.NoError(t, err)
+	require.Len(t, agent.reporters, 1)
+	assert.IsType(t, &reporter.BufferedReporter{}, agent.reporters[0])
+	assert.NoError(t, agent.reporters[0].Close())
+
+	file, err := ioutil.TempFile("", "spill")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+	cfg = &Builder{Buffer: reporter.BufferOptions{SpillDir: file.Name()}}
+	_, err = cfg.CreateAgent(zap.NewNop())
+	assert.Error(t, err)
+}
+
+func TestBuilderWithCoalescing(t *testing.T) {
+	cfg := &Builder{
+		Buffer:   reporter.BufferOptions{MaxBatches: 10},
+		Coalesce: reporter.CoalesceOptions{FlushInterval: time.Second},
+	}
+	agent, err := cfg.CreateAgent(zap.NewNop())
+	require.NoError(t, err)
+	require.Len(t, agent.reporters, 2)
+	assert.IsType(t, &reporter.CoalescingReporter{}, agent.reporters[0])
+	assert.IsType(t, &reporter.BufferedReporter{}, agent.reporters[1])
+	for _, r := range agent.reporters {
+		assert.NoError(t, r.Close())
+	}
+}
+
+func TestBuilderWithFileDiscovery(t *testing.T) {
+	file, err := ioutil.TempFile("", "collectors")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+	_, err = file.WriteString("127.0.0.1:14267\n")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	cfg := &Builder{Discovery: DiscoveryConfiguration{Type: fileDiscoveryType, File: file.Name()}}
+	agent, err := cfg.CreateAgent(zap.NewNop())
+	require.NoError(t, err)
+	require.NotNil(t, agent.discovery)
+	assert.NoError(t, agent.discovery.Close())
+}
+
+func TestBuilderWithDiscoveryErrors(t *testing.T) {
+	testCases := []struct {
+		discovery DiscoveryConfiguration
+		hostPorts []string
+		err       string
+	}{
+		{
+			discovery: DiscoveryConfiguration{Type: "consul"},
+			err:       "cannot create collectors discovery: unknown discovery type consul",
+		},
+		{
+			discovery: DiscoveryConfiguration{Type: fileDiscoveryType},
+			err:       "cannot create collectors discovery: no discovery file provided",
+		},
+		{
+			discovery: DiscoveryConfiguration{Type: dnsDiscoveryType},
+			err:       "cannot create collectors discovery: no DNS name provided",
+		},
+		{
+			discovery: DiscoveryConfiguration{Type: dnsDiscoveryType, DNSNames: []string{"localhost:14267"}},
+			hostPorts: []string{"127.0.0.1:14267"},
+			err:       "cannot create main Reporter: a static list of collectors cannot be used with discovery",
+		},
+	}
+	for _, testCase := range testCases {
+		cfg := &Builder{Discovery: testCase.discovery, CollectorHostPorts: testCase.hostPorts}
+		agent, err := cfg.CreateAgent(zap.NewNop())
+		assert.EqualError(t, err, testCase.err)
+		assert.Nil(t, agent)
+	}
+}
+
+func TestBuilderWithStrategiesFileError(t *testing.T) {
+	cfg := &Builder{}
+	cfg.HTTPServer.SamplingStrategiesFile = "/does/not/exist.json"
+	agent, err := cfg.CreateAgent(zap.NewNop())
+	assert.EqualError(t, err, "cannot create HTTP server: open /does/not/exist.json: no such file or directory")
+	assert.Nil(t, agent)
+}
+
 func TestBuilderMetrics(t *testing.T) {
 	mf := metrics.NullFactory
 	b := new(Builder).WithMetricsFactory(mf)
@@ -157,7 +364,7 @@ func TestBuilderWithProcessorErrors(t *testing.T) {
 	}{
 		{protocol: protocol("bad"), err: "cannot find protocol factory for protocol bad"},
 		{protocol: compactProtocol, model: model("bad"), err: "cannot find agent processor for data model bad"},
-		{protocol: compactProtocol, model: jaegerModel, err: "no host:port provided for udp server: {QueueSize:1000 MaxPacketSize:65000 HostPort:}"},
+		{protocol: compactProtocol, model: jaegerModel, err: "no host:port provided for udp server: {QueueSize:1000 MaxPacketSize:65000 HostPort: SocketBufferSize:0 Readers:1}"},
 		{protocol: compactProtocol, model: zipkinModel, hostPort: "bad-host-port", errContains: "bad-host-port"},
 	}
 	for _, tc := range testCases {
@@ -183,6 +390,29 @@ func TestBuilderWithProcessorErrors(t *testing.T) {
 	}
 }
 
+func TestServerConfigurationWithReaders(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("SO_REUSEPORT is only supported on Linux")
+	}
+	cfg := &ServerConfiguration{
+		HostPort:         "127.0.0.1:0",
+		SocketBufferSize: 1024 * 1024,
+		Readers:          3,
+	}
+	server, err := cfg.getUDPServer(metrics.NullFactory)
+	require.NoError(t, err)
+	go server.Serve()
+	defer server.Stop()
+
+	for i := 0; i < 1000; i++ {
+		if server.IsServing() {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, 3, server.(*servers.TBufferedServer).Status().Readers)
+}
+
 type fakeReporter struct{}
 
 func (fr fakeReporter) EmitZipkinBatch(spans []*zipkincore.Span) (err error) {
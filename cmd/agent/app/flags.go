@@ -24,8 +24,13 @@ import (
 	"flag"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
+
+	"github.com/uber/jaeger/cmd/agent/app/httpserver"
+	"github.com/uber/jaeger/cmd/agent/app/reporter"
+	"github.com/uber/jaeger/pkg/discovery"
 )
 
 const (
@@ -33,9 +38,40 @@ const (
 	suffixServerQueueSize     = "server-queue-size"
 	suffixServerMaxPacketSize = "server-max-packet-size"
 	suffixServerHostPort      = "server-host-port"
+	suffixServerSocketBuffer  = "server-socket-buffer-size"
+	suffixServerReaders       = "server-readers"
 	collectorHostPort         = "collector.host-port"
 	httpServerHostPort        = "http-server.host-port"
+	samplingCacheTTL          = "http-server.sampling-cache-ttl"
+	samplingStrategiesFile    = "http-server.sampling-strategies-file"
 	discoveryMinPeers         = "discovery.min-peers"
+	discoveryTypeFlag         = "discovery.type"
+	discoveryDNSNames         = "discovery.dns-names"
+	discoveryDNSRecordType    = "discovery.dns-record-type"
+	discoveryFile             = "discovery.file"
+	discoveryRefreshInterval  = "discovery.refresh-interval"
+	reporterToken             = "reporter.token"
+	reporterTokenFile         = "reporter.token-file"
+	reporterTokenTagKey       = "reporter.token-tag-key"
+	reporterProcessTags       = "reporter.process-tags"
+	reporterTagsPrecedence    = "reporter.process-tags-precedence"
+	reporterTypeFlag          = "reporter.type"
+	httpCollectorEndpoint     = "reporter.http.collector-endpoint"
+	httpAuthToken             = "reporter.http.auth-token"
+	httpHeaders               = "reporter.http.headers"
+	httpGzip                  = "reporter.http.gzip"
+	httpTimeout               = "reporter.http.timeout"
+	httpMaxRetries            = "reporter.http.max-retries"
+	httpRetryBackoff          = "reporter.http.retry-backoff"
+	bufferMaxBatches          = "reporter.buffer.max-batches"
+	bufferSpillDir            = "reporter.buffer.spill-dir"
+	bufferMaxSpillBytes       = "reporter.buffer.max-spill-bytes"
+	bufferInitialBackoff      = "reporter.buffer.initial-backoff"
+	bufferMaxBackoff          = "reporter.buffer.max-backoff"
+	bufferMaxRetries          = "reporter.buffer.max-retries"
+	coalesceFlushInterval     = "reporter.coalesce.flush-interval"
+	coalesceMaxSpans          = "reporter.coalesce.max-spans"
+	coalesceMaxBytes          = "reporter.coalesce.max-bytes"
 )
 
 var defaultProcessors = []struct {
@@ -56,6 +92,8 @@ func AddFlags(flags *flag.FlagSet) {
 		flags.Int(prefix+suffixServerQueueSize, defaultQueueSize, "length of the queue for the UDP server")
 		flags.Int(prefix+suffixServerMaxPacketSize, defaultMaxPacketSize, "max packet size for the UDP server")
 		flags.String(prefix+suffixServerHostPort, processor.hostPort, "host:port for the UDP server")
+		flags.Int(prefix+suffixServerSocketBuffer, 0, "size of the UDP socket receive buffer (SO_RCVBUF) in bytes, 0 keeps the OS default")
+		flags.Int(prefix+suffixServerReaders, defaultServerReaders, "number of goroutines reading from the UDP socket, more than one requires SO_REUSEPORT (Linux only)")
 	}
 	flags.String(
 		collectorHostPort,
@@ -65,10 +103,127 @@ func AddFlags(flags *flag.FlagSet) {
 		httpServerHostPort,
 		defaultHTTPServerHostPort,
 		"host:port of the http server (e.g. for /sampling point and /baggage endpoint)")
+	flags.Duration(
+		samplingCacheTTL,
+		httpserver.DefaultSamplingCacheTTL,
+		"how long a sampling strategy is served from the cache before being fetched again from the collector")
+	flags.String(
+		samplingStrategiesFile,
+		"",
+		"JSON file with the sampling strategies served when the collector cannot be reached and no strategy is cached")
 	flags.Int(
 		discoveryMinPeers,
 		defaultMinPeers,
 		"if using service discovery, the min number of connections to maintain to the backend")
+	flags.String(
+		discoveryTypeFlag,
+		"",
+		"how the collectors are discovered instead of using a static list: dns or file")
+	flags.String(
+		discoveryDNSNames,
+		"",
+		"comma-separated list of DNS names of the collectors, host:port for A records or SRV names (e.g. _tchannel._tcp.jaeger-collector)")
+	flags.String(
+		discoveryDNSRecordType,
+		discovery.ARecord,
+		"type of the DNS records of the collectors: A (also resolves AAAA records) or SRV")
+	flags.String(
+		discoveryFile,
+		"",
+		"file listing the host:port of the collectors, one per line or comma-separated")
+	flags.Duration(
+		discoveryRefreshInterval,
+		discovery.DefaultRefreshInterval,
+		"interval between two lookups of the collectors")
+	flags.String(
+		reporterToken,
+		"",
+		"API token added to the spans forwarded to the collector, unless the client already set one")
+	flags.String(
+		reporterTokenFile,
+		"",
+		"path to a YAML file with a default API token and per-service API tokens (default and services keys)")
+	flags.String(
+		reporterTokenTagKey,
+		reporter.DefaultTokenTagKey,
+		"name of the tag holding the API token, it must match collector.span-auth-tag-key")
+	flags.String(
+		reporterProcessTags,
+		"",
+		"comma-separated list of name=value tags added to the process of every batch, values can be static, "+
+			"${ENV_VAR} or ${ENV_VAR:default}, @hostname or @ip (e.g. dc=us-east,env=${DEPLOY_ENV:dev},host=@hostname)")
+	flags.String(
+		reporterTagsPrecedence,
+		string(reporter.ClientTagsWin),
+		"which value wins when a process tag is set by both the client and the agent: client or agent")
+	flags.String(
+		reporterTypeFlag,
+		string(tchannelReporterType),
+		"how spans are forwarded to the collectors: tchannel or http")
+	flags.String(
+		httpCollectorEndpoint,
+		"",
+		"base URL of the collector HTTP API used by the http reporter (e.g. https://jaeger-collector:14268)")
+	flags.String(
+		httpAuthToken,
+		"",
+		"bearer token sent in the Authorization header by the http reporter")
+	flags.String(
+		httpHeaders,
+		"",
+		"comma-separated list of name=value headers sent by the http reporter (e.g. X-Tenant=acme)")
+	flags.Bool(
+		httpGzip,
+		true,
+		"whether the http reporter compresses the batches with gzip")
+	flags.Duration(
+		httpTimeout,
+		5*time.Second,
+		"timeout of a single request of the http reporter")
+	flags.Int(
+		httpMaxRetries,
+		3,
+		"how many times the http reporter sends a failed batch again, negative to disable retries")
+	flags.Duration(
+		httpRetryBackoff,
+		100*time.Millisecond,
+		"delay before the first retry of the http reporter, doubled on every following retry")
+	flags.Int(
+		bufferMaxBatches,
+		0,
+		"max number of batches kept in memory when the collectors cannot be reached, buffering is disabled when zero and no spill dir is set")
+	flags.String(
+		bufferSpillDir,
+		"",
+		"directory the batches are written to once the in-memory buffer is full, they are replayed after a restart")
+	flags.Int64(
+		bufferMaxSpillBytes,
+		reporter.DefaultMaxSpillBytes,
+		"max size in bytes of the batches written to the spill dir")
+	flags.Duration(
+		bufferInitialBackoff,
+		reporter.DefaultInitialBackoff,
+		"delay before the first replay of a buffered batch, doubled with some jitter after every failure")
+	flags.Duration(
+		bufferMaxBackoff,
+		reporter.DefaultMaxBackoff,
+		"max delay between two replays of a buffered batch")
+	flags.Int(
+		bufferMaxRetries,
+		reporter.DefaultMaxRetries,
+		"number of failed replays after which a buffered batch is dropped")
+	flags.Duration(
+		coalesceFlushInterval,
+		0,
+		"max time spans are held to be merged with the ones of other batches of the same process, coalescing is disabled when zero")
+	flags.Int(
+		coalesceMaxSpans,
+		reporter.DefaultCoalesceMaxSpans,
+		"number of spans after which a coalesced batch is forwarded")
+	flags.Int(
+		coalesceMaxBytes,
+		reporter.DefaultCoalesceMaxBytes,
+		"size in bytes after which a coalesced batch is forwarded")
 }
 
 // InitFromViper initializes Builder with properties retrieved from Viper.
@@ -82,6 +237,8 @@ func (b *Builder) InitFromViper(v *viper.Viper) {
 		p.Server.QueueSize = v.GetInt(prefix + suffixServerQueueSize)
 		p.Server.MaxPacketSize = v.GetInt(prefix + suffixServerMaxPacketSize)
 		p.Server.HostPort = v.GetString(prefix + suffixServerHostPort)
+		p.Server.SocketBufferSize = v.GetInt(prefix + suffixServerSocketBuffer)
+		p.Server.Readers = v.GetInt(prefix + suffixServerReaders)
 		b.Processors = append(b.Processors, *p)
 	}
 
@@ -89,5 +246,52 @@ func (b *Builder) InitFromViper(v *viper.Viper) {
 		b.CollectorHostPorts = strings.Split(v.GetString(collectorHostPort), ",")
 	}
 	b.HTTPServer.HostPort = v.GetString(httpServerHostPort)
+	b.HTTPServer.SamplingCacheTTL = v.GetDuration(samplingCacheTTL)
+	b.HTTPServer.SamplingStrategiesFile = v.GetString(samplingStrategiesFile)
 	b.DiscoveryMinPeers = v.GetInt(discoveryMinPeers)
+	b.Discovery.Type = discoveryType(v.GetString(discoveryTypeFlag))
+	if len(v.GetString(discoveryDNSNames)) > 0 {
+		b.Discovery.DNSNames = strings.Split(v.GetString(discoveryDNSNames), ",")
+	}
+	b.Discovery.DNSRecordType = v.GetString(discoveryDNSRecordType)
+	b.Discovery.File = v.GetString(discoveryFile)
+	b.Discovery.RefreshInterval = v.GetDuration(discoveryRefreshInterval)
+	b.Tokens.Default = v.GetString(reporterToken)
+	b.TokenFile = v.GetString(reporterTokenFile)
+	b.TokenTagKey = v.GetString(reporterTokenTagKey)
+	b.ProcessTags = parseKeyValues(v.GetString(reporterProcessTags))
+	b.ProcessTagsPrecedence = v.GetString(reporterTagsPrecedence)
+	b.ReporterType = reporterType(v.GetString(reporterTypeFlag))
+	b.HTTPReporter.CollectorEndpoint = v.GetString(httpCollectorEndpoint)
+	b.HTTPReporter.AuthToken = v.GetString(httpAuthToken)
+	b.HTTPReporter.Headers = parseKeyValues(v.GetString(httpHeaders))
+	b.HTTPReporter.DisableCompression = !v.GetBool(httpGzip)
+	b.HTTPReporter.Timeout = v.GetDuration(httpTimeout)
+	b.HTTPReporter.MaxRetries = v.GetInt(httpMaxRetries)
+	b.HTTPReporter.RetryBackoff = v.GetDuration(httpRetryBackoff)
+	b.Buffer.MaxBatches = v.GetInt(bufferMaxBatches)
+	b.Buffer.SpillDir = v.GetString(bufferSpillDir)
+	b.Buffer.MaxSpillBytes = v.GetInt64(bufferMaxSpillBytes)
+	b.Buffer.InitialBackoff = v.GetDuration(bufferInitialBackoff)
+	b.Buffer.MaxBackoff = v.GetDuration(bufferMaxBackoff)
+	b.Buffer.MaxRetries = v.GetInt(bufferMaxRetries)
+	b.Coalesce.FlushInterval = v.GetDuration(coalesceFlushInterval)
+	b.Coalesce.MaxSpans = v.GetInt(coalesceMaxSpans)
+	b.Coalesce.MaxBytes = v.GetInt(coalesceMaxBytes)
+}
+
+// parseKeyValues parses a comma-separated list of name=value pairs
+func parseKeyValues(s string) map[string]string {
+	if s == "" {
+		return nil
+	}
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		headers[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return headers
 }
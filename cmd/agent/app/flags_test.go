@@ -23,11 +23,14 @@ package app
 import (
 	"flag"
 	"testing"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/cmd/agent/app/reporter"
 )
 
 func TestBingFlags(t *testing.T) {
@@ -42,11 +45,33 @@ func TestBingFlags(t *testing.T) {
 	err := command.ParseFlags([]string{
 		"--collector.host-port=1.2.3.4:555,1.2.3.4:666",
 		"--discovery.min-peers=42",
+		"--discovery.type=dns",
+		"--discovery.dns-names=collector-1:14267,collector-2:14267",
+		"--discovery.refresh-interval=1m",
 		"--http-server.host-port=:8080",
+		"--http-server.sampling-strategies-file=/etc/jaeger/strategies.json",
 		"--processor.jaeger-binary.server-host-port=:1111",
 		"--processor.jaeger-binary.server-max-packet-size=4242",
 		"--processor.jaeger-binary.server-queue-size=42",
+		"--processor.jaeger-binary.server-socket-buffer-size=4194304",
+		"--processor.jaeger-binary.server-readers=4",
 		"--processor.jaeger-binary.workers=42",
+		"--reporter.token=secret",
+		"--reporter.token-file=/etc/jaeger/tokens.yaml",
+		"--reporter.process-tags=dc=us-east, env=${DEPLOY_ENV:dev}",
+		"--reporter.process-tags-precedence=agent",
+		"--reporter.type=http",
+		"--reporter.http.collector-endpoint=https://jaeger-collector:14268",
+		"--reporter.http.auth-token=secret",
+		"--reporter.http.headers=X-Tenant=acme, X-Env=prod",
+		"--reporter.http.gzip=false",
+		"--reporter.http.max-retries=5",
+		"--reporter.http.retry-backoff=1s",
+		"--reporter.buffer.max-batches=100",
+		"--reporter.buffer.spill-dir=/var/lib/jaeger",
+		"--reporter.buffer.max-backoff=1m",
+		"--reporter.coalesce.flush-interval=100ms",
+		"--reporter.coalesce.max-spans=200",
 	})
 	require.NoError(t, err)
 
@@ -54,9 +79,39 @@ func TestBingFlags(t *testing.T) {
 	assert.Equal(t, 3, len(b.Processors))
 	assert.Equal(t, []string{"1.2.3.4:555", "1.2.3.4:666"}, b.CollectorHostPorts)
 	assert.Equal(t, 42, b.DiscoveryMinPeers)
+	assert.Equal(t, dnsDiscoveryType, b.Discovery.Type)
+	assert.Equal(t, []string{"collector-1:14267", "collector-2:14267"}, b.Discovery.DNSNames)
+	assert.Equal(t, "A", b.Discovery.DNSRecordType)
+	assert.Equal(t, time.Minute, b.Discovery.RefreshInterval)
 	assert.Equal(t, ":8080", b.HTTPServer.HostPort)
+	assert.Equal(t, time.Minute, b.HTTPServer.SamplingCacheTTL)
+	assert.Equal(t, "/etc/jaeger/strategies.json", b.HTTPServer.SamplingStrategiesFile)
 	assert.Equal(t, ":1111", b.Processors[2].Server.HostPort)
 	assert.Equal(t, 4242, b.Processors[2].Server.MaxPacketSize)
 	assert.Equal(t, 42, b.Processors[2].Server.QueueSize)
+	assert.Equal(t, 4194304, b.Processors[2].Server.SocketBufferSize)
+	assert.Equal(t, 4, b.Processors[2].Server.Readers)
 	assert.Equal(t, 42, b.Processors[2].Workers)
+	assert.Equal(t, "secret", b.Tokens.Default)
+	assert.Equal(t, "/etc/jaeger/tokens.yaml", b.TokenFile)
+	assert.Equal(t, "api-token", b.TokenTagKey)
+	assert.Equal(t, map[string]string{"dc": "us-east", "env": "${DEPLOY_ENV:dev}"}, b.ProcessTags)
+	assert.Equal(t, "agent", b.ProcessTagsPrecedence)
+	assert.Equal(t, httpReporterType, b.ReporterType)
+	assert.Equal(t, "https://jaeger-collector:14268", b.HTTPReporter.CollectorEndpoint)
+	assert.Equal(t, "secret", b.HTTPReporter.AuthToken)
+	assert.Equal(t, map[string]string{"X-Tenant": "acme", "X-Env": "prod"}, b.HTTPReporter.Headers)
+	assert.True(t, b.HTTPReporter.DisableCompression)
+	assert.Equal(t, 5*time.Second, b.HTTPReporter.Timeout)
+	assert.Equal(t, 5, b.HTTPReporter.MaxRetries)
+	assert.Equal(t, time.Second, b.HTTPReporter.RetryBackoff)
+	assert.Equal(t, 100, b.Buffer.MaxBatches)
+	assert.Equal(t, "/var/lib/jaeger", b.Buffer.SpillDir)
+	assert.EqualValues(t, 100*1024*1024, b.Buffer.MaxSpillBytes)
+	assert.Equal(t, time.Second, b.Buffer.InitialBackoff)
+	assert.Equal(t, time.Minute, b.Buffer.MaxBackoff)
+	assert.Equal(t, reporter.DefaultMaxRetries, b.Buffer.MaxRetries)
+	assert.Equal(t, 100*time.Millisecond, b.Coalesce.FlushInterval)
+	assert.Equal(t, 200, b.Coalesce.MaxSpans)
+	assert.Equal(t, 1024*1024, b.Coalesce.MaxBytes)
 }
@@ -0,0 +1,121 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package httpserver
+
+import (
+	"sync"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/thrift-gen/sampling"
+)
+
+// DefaultSamplingCacheTTL is the default time a sampling strategy is served from the cache
+// before being fetched again from the collector
+const DefaultSamplingCacheTTL = time.Minute
+
+type cachedStrategy struct {
+	resp    *sampling.SamplingStrategyResponse
+	fetched time.Time
+}
+
+// samplingCache is a ClientConfigManager decorator caching the last good sampling strategy of
+// every service. Expired strategies are still served when the collector cannot be reached, and
+// the strategies file, if any, is the last resort for the services never fetched successfully.
+type samplingCache struct {
+	ClientConfigManager
+
+	sync.RWMutex
+	ttl        time.Duration
+	strategies map[string]cachedStrategy
+	fallback   *fileStrategies
+	now        func() time.Time
+
+	metrics struct {
+		// Number of sampling strategies served from the cache
+		Hits metrics.Counter `metric:"sampling-cache" tags:"result=hit"`
+
+		// Number of sampling strategies fetched from the collector
+		Misses metrics.Counter `metric:"sampling-cache" tags:"result=miss"`
+
+		// Number of expired sampling strategies served because the collector failed
+		Stale metrics.Counter `metric:"sampling-cache" tags:"result=stale"`
+
+		// Number of sampling strategies served from the strategies file because the collector failed
+		Fallback metrics.Counter `metric:"sampling-cache" tags:"result=file"`
+	}
+}
+
+// NewSamplingCache creates a ClientConfigManager caching the sampling strategies returned by manager
+// for ttl. If strategiesFile is not empty, the strategies it defines are served when neither the
+// collector nor the cache have one.
+func NewSamplingCache(manager ClientConfigManager, ttl time.Duration, strategiesFile string, mFactory metrics.Factory) (ClientConfigManager, error) {
+	c := &samplingCache{
+		ClientConfigManager: manager,
+		ttl:                 ttl,
+		strategies:          make(map[string]cachedStrategy),
+		now:                 time.Now,
+	}
+	if c.ttl == 0 {
+		c.ttl = DefaultSamplingCacheTTL
+	}
+	if strategiesFile != "" {
+		fallback, err := loadStrategies(strategiesFile)
+		if err != nil {
+			return nil, err
+		}
+		c.fallback = fallback
+	}
+	metrics.Init(&c.metrics, mFactory, nil)
+	return c, nil
+}
+
+func (c *samplingCache) GetSamplingStrategy(serviceName string) (*sampling.SamplingStrategyResponse, error) {
+	c.RLock()
+	cached, ok := c.strategies[serviceName]
+	c.RUnlock()
+	if ok && c.now().Sub(cached.fetched) < c.ttl {
+		c.metrics.Hits.Inc(1)
+		return cached.resp, nil
+	}
+
+	c.metrics.Misses.Inc(1)
+	resp, err := c.ClientConfigManager.GetSamplingStrategy(serviceName)
+	if err == nil {
+		c.Lock()
+		c.strategies[serviceName] = cachedStrategy{resp: resp, fetched: c.now()}
+		c.Unlock()
+		return resp, nil
+	}
+	if ok {
+		c.metrics.Stale.Inc(1)
+		return cached.resp, nil
+	}
+	if c.fallback != nil {
+		if resp := c.fallback.forService(serviceName); resp != nil {
+			c.metrics.Fallback.Inc(1)
+			return resp, nil
+		}
+	}
+	return nil, err
+}
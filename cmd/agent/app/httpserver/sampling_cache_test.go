@@ -0,0 +1,111 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package httpserver
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	mTestutils "github.com/uber/jaeger-lib/metrics/testutils"
+)
+
+func writeStrategies(t *testing.T, content string) string {
+	file, err := ioutil.TempFile("", "strategies")
+	require.NoError(t, err)
+	_, err = file.WriteString(content)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	return file.Name()
+}
+
+func TestSamplingCache(t *testing.T) {
+	path := writeStrategies(t, `{"default_strategy": {"type": "probabilistic", "param": 0.001}}`)
+	defer os.Remove(path)
+
+	mgr := &mockManager{
+		samplingResponse: probabilistic(0.5),
+		baggageResponse:  restrictions("luggage", 10),
+	}
+	mFactory := metrics.NewLocalFactory(0)
+	m, err := NewSamplingCache(mgr, time.Minute, path, mFactory)
+	require.NoError(t, err)
+	cache := m.(*samplingCache)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	// fetched from the collector, then served from the cache
+	for i := 0; i < 2; i++ {
+		resp, err := cache.GetSamplingStrategy("frontend")
+		require.NoError(t, err)
+		assert.Equal(t, 0.5, resp.ProbabilisticSampling.SamplingRate)
+	}
+
+	// refreshed once expired
+	mgr.samplingResponse = probabilistic(0.8)
+	now = now.Add(time.Minute)
+	resp, err := cache.GetSamplingStrategy("frontend")
+	require.NoError(t, err)
+	assert.Equal(t, 0.8, resp.ProbabilisticSampling.SamplingRate)
+
+	// the expired strategy is served when the collector fails
+	mgr.samplingResponse = nil
+	now = now.Add(time.Hour)
+	resp, err = cache.GetSamplingStrategy("frontend")
+	require.NoError(t, err)
+	assert.Equal(t, 0.8, resp.ProbabilisticSampling.SamplingRate)
+
+	// the strategies file is the last resort
+	resp, err = cache.GetSamplingStrategy("backend")
+	require.NoError(t, err)
+	assert.Equal(t, 0.001, resp.ProbabilisticSampling.SamplingRate)
+
+	// baggage restrictions are not cached
+	restrictions, err := cache.GetBaggageRestrictions("frontend")
+	require.NoError(t, err)
+	assert.Len(t, restrictions, 1)
+
+	mTestutils.AssertCounterMetrics(t, mFactory, []mTestutils.ExpectedMetric{
+		{Name: "sampling-cache", Tags: map[string]string{"result": "hit"}, Value: 1},
+		{Name: "sampling-cache", Tags: map[string]string{"result": "miss"}, Value: 4},
+		{Name: "sampling-cache", Tags: map[string]string{"result": "stale"}, Value: 1},
+		{Name: "sampling-cache", Tags: map[string]string{"result": "file"}, Value: 1},
+	}...)
+}
+
+func TestSamplingCacheCollectorError(t *testing.T) {
+	m, err := NewSamplingCache(&mockManager{}, 0, "", metrics.NullFactory)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultSamplingCacheTTL, m.(*samplingCache).ttl)
+
+	_, err = m.GetSamplingStrategy("frontend")
+	assert.EqualError(t, err, "no mock response provided")
+}
+
+func TestSamplingCacheStrategiesFileError(t *testing.T) {
+	_, err := NewSamplingCache(&mockManager{}, 0, "/does/not/exist.json", metrics.NullFactory)
+	assert.Error(t, err)
+}
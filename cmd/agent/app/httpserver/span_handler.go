@@ -0,0 +1,99 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package httpserver
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/uber/jaeger-lib/metrics"
+	"github.com/uber/tchannel-go/thrift"
+
+	"github.com/uber/jaeger/cmd/agent/app/reporter"
+	"github.com/uber/jaeger/pkg/httpapi"
+	"github.com/uber/jaeger/pkg/httpapi/zipkin"
+	"github.com/uber/jaeger/thrift-gen/jaeger"
+	"github.com/uber/jaeger/thrift-gen/zipkincore"
+)
+
+// RegisterSpanHandlers adds to serveMux the span endpoints of the collector HTTP API, POST /api/traces
+// for Jaeger batches and POST /api/v1/spans for Zipkin spans, forwarding the spans to the reporter.
+// Unlike UDP the request size is not limited.
+func RegisterSpanHandlers(serveMux *http.ServeMux, rep reporter.Reporter, mFactory metrics.Factory) {
+	handler := newSpanHandler(rep, mFactory)
+	router := mux.NewRouter()
+	httpapi.NewAPIHandler(handler).RegisterRoutes(router)
+	zipkin.NewAPIHandler(handler).RegisterRoutes(router)
+	serveMux.Handle("/api/", router)
+}
+
+// spanHandler submits the spans received by the HTTP API to the reporter
+type spanHandler struct {
+	reporter reporter.Reporter
+	metrics  struct {
+		// Number of Jaeger batches forwarded to the reporter
+		JaegerSuccess metrics.Counter `metric:"http-server.batches" tags:"result=ok,model=jaeger"`
+
+		// Number of Jaeger batches the reporter failed to forward
+		JaegerFailures metrics.Counter `metric:"http-server.batches" tags:"result=err,model=jaeger"`
+
+		// Number of Zipkin batches forwarded to the reporter
+		ZipkinSuccess metrics.Counter `metric:"http-server.batches" tags:"result=ok,model=zipkin"`
+
+		// Number of Zipkin batches the reporter failed to forward
+		ZipkinFailures metrics.Counter `metric:"http-server.batches" tags:"result=err,model=zipkin"`
+	}
+}
+
+func newSpanHandler(rep reporter.Reporter, mFactory metrics.Factory) *spanHandler {
+	handler := &spanHandler{reporter: rep}
+	metrics.Init(&handler.metrics, mFactory, nil)
+	return handler
+}
+
+// SubmitBatches implements httpapi.JaegerBatchesHandler
+func (h *spanHandler) SubmitBatches(ctx thrift.Context, batches []*jaeger.Batch) ([]*jaeger.BatchSubmitResponse, error) {
+	responses := make([]*jaeger.BatchSubmitResponse, 0, len(batches))
+	for _, batch := range batches {
+		if err := h.reporter.EmitBatch(batch); err != nil {
+			h.metrics.JaegerFailures.Inc(1)
+			return nil, err
+		}
+		h.metrics.JaegerSuccess.Inc(1)
+		responses = append(responses, &jaeger.BatchSubmitResponse{Ok: true})
+	}
+	return responses, nil
+}
+
+// SubmitZipkinBatch implements zipkin.SpansHandler
+func (h *spanHandler) SubmitZipkinBatch(ctx thrift.Context, spans []*zipkincore.Span) ([]*zipkincore.Response, error) {
+	if err := h.reporter.EmitZipkinBatch(spans); err != nil {
+		h.metrics.ZipkinFailures.Inc(1)
+		return nil, err
+	}
+	h.metrics.ZipkinSuccess.Inc(1)
+	responses := make([]*zipkincore.Response, len(spans))
+	for i := range spans {
+		responses[i] = &zipkincore.Response{Ok: true}
+	}
+	return responses, nil
+}
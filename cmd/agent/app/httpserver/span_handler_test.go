@@ -0,0 +1,139 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package httpserver
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	mTestutils "github.com/uber/jaeger-lib/metrics/testutils"
+
+	"github.com/uber/jaeger/thrift-gen/jaeger"
+	"github.com/uber/jaeger/thrift-gen/zipkincore"
+)
+
+type mockReporter struct {
+	err     error
+	batches []*jaeger.Batch
+	spans   []*zipkincore.Span
+}
+
+func (r *mockReporter) EmitZipkinBatch(spans []*zipkincore.Span) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.spans = append(r.spans, spans...)
+	return nil
+}
+
+func (r *mockReporter) EmitBatch(batch *jaeger.Batch) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.batches = append(r.batches, batch)
+	return nil
+}
+
+func initSpanServer() (*httptest.Server, *mockReporter, *metrics.LocalFactory) {
+	rep := &mockReporter{}
+	mFactory := metrics.NewLocalFactory(0)
+	serveMux := http.NewServeMux()
+	RegisterSpanHandlers(serveMux, rep, mFactory)
+	return httptest.NewServer(serveMux), rep, mFactory
+}
+
+func post(t *testing.T, url, contentType string, body []byte) int {
+	resp, err := http.Post(url, contentType, bytes.NewReader(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestSpanHandlerJaeger(t *testing.T) {
+	server, rep, mFactory := initSpanServer()
+	defer server.Close()
+
+	batch := &jaeger.Batch{
+		Process: &jaeger.Process{ServiceName: "svc"},
+		Spans:   []*jaeger.Span{{OperationName: "thrift"}},
+	}
+	body, err := thrift.NewTSerializer().Write(batch)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, post(t, server.URL+"/api/traces?format=jaeger.thrift", "application/x-thrift", body))
+
+	body = []byte(`{"process": {"serviceName": "svc"}, "spans": [{"operationName": "json"}]}`)
+	assert.Equal(t, http.StatusAccepted, post(t, server.URL+"/api/traces?format=jaeger.json", "application/json", body))
+
+	require.Len(t, rep.batches, 2)
+	assert.Equal(t, "thrift", rep.batches[0].Spans[0].OperationName)
+	assert.Equal(t, "json", rep.batches[1].Spans[0].OperationName)
+
+	rep.err = errors.New("reporter error")
+	assert.Equal(t, http.StatusInternalServerError, post(t, server.URL+"/api/traces?format=jaeger.json", "application/json", body))
+
+	mTestutils.AssertCounterMetrics(t, mFactory, []mTestutils.ExpectedMetric{
+		{Name: "http-server.batches", Tags: map[string]string{"result": "ok", "model": "jaeger"}, Value: 2},
+		{Name: "http-server.batches", Tags: map[string]string{"result": "err", "model": "jaeger"}, Value: 1},
+	}...)
+}
+
+func TestSpanHandlerZipkin(t *testing.T) {
+	server, rep, mFactory := initSpanServer()
+	defer server.Close()
+
+	buffer := thrift.NewTMemoryBuffer()
+	protocol := thrift.NewTBinaryProtocolTransport(buffer)
+	require.NoError(t, protocol.WriteListBegin(thrift.STRUCT, 1))
+	require.NoError(t, (&zipkincore.Span{Name: "thrift"}).Write(protocol))
+	require.NoError(t, protocol.WriteListEnd())
+	assert.Equal(t, http.StatusAccepted, post(t, server.URL+"/api/v1/spans", "application/x-thrift", buffer.Bytes()))
+
+	body := []byte(`[{"traceId": "1", "id": "2", "name": "json"}]`)
+	assert.Equal(t, http.StatusAccepted, post(t, server.URL+"/api/v1/spans", "application/json", body))
+
+	require.Len(t, rep.spans, 2)
+	assert.Equal(t, "thrift", rep.spans[0].Name)
+	assert.Equal(t, "json", rep.spans[1].Name)
+
+	rep.err = errors.New("reporter error")
+	assert.Equal(t, http.StatusInternalServerError, post(t, server.URL+"/api/v1/spans", "application/json", body))
+
+	mTestutils.AssertCounterMetrics(t, mFactory, []mTestutils.ExpectedMetric{
+		{Name: "http-server.batches", Tags: map[string]string{"result": "ok", "model": "zipkin"}, Value: 2},
+		{Name: "http-server.batches", Tags: map[string]string{"result": "err", "model": "zipkin"}, Value: 1},
+	}...)
+}
+
+func TestSpanHandlerSubmitBatches(t *testing.T) {
+	handler := newSpanHandler(&mockReporter{}, metrics.NullFactory)
+	responses, err := handler.SubmitBatches(nil, []*jaeger.Batch{{}, {}})
+	require.NoError(t, err)
+	assert.Len(t, responses, 2)
+	assert.True(t, responses[1].Ok)
+}
@@ -0,0 +1,122 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package httpserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+
+	"github.com/uber/jaeger/thrift-gen/sampling"
+)
+
+const (
+	probabilisticStrategy = "probabilistic"
+	rateLimitingStrategy  = "ratelimiting"
+)
+
+// strategy is a sampling strategy as defined in the strategies file
+type strategy struct {
+	Type  string  `json:"type"`
+	Param float64 `json:"param"`
+}
+
+// serviceStrategy is the sampling strategy of a service as defined in the strategies file
+type serviceStrategy struct {
+	Service string `json:"service"`
+	strategy
+}
+
+// strategiesFile is the content of the local strategies file, e.g.
+//
+//	{
+//	  "default_strategy": {"type": "probabilistic", "param": 0.001},
+//	  "service_strategies": [
+//	    {"service": "frontend", "type": "ratelimiting", "param": 5}
+//	  ]
+//	}
+type strategiesFile struct {
+	DefaultStrategy   *strategy         `json:"default_strategy"`
+	ServiceStrategies []serviceStrategy `json:"service_strategies"`
+}
+
+// fileStrategies holds the sampling strategies read from the local strategies file
+type fileStrategies struct {
+	defaultStrategy   *sampling.SamplingStrategyResponse
+	serviceStrategies map[string]*sampling.SamplingStrategyResponse
+}
+
+// loadStrategies reads the sampling strategies from a JSON file
+func loadStrategies(path string) (*fileStrategies, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file strategiesFile
+	if err := json.Unmarshal(bytes, &file); err != nil {
+		return nil, fmt.Errorf("cannot parse strategies file %s: %v", path, err)
+	}
+	s := &fileStrategies{serviceStrategies: make(map[string]*sampling.SamplingStrategyResponse)}
+	if file.DefaultStrategy != nil {
+		if s.defaultStrategy, err = file.DefaultStrategy.toThrift(); err != nil {
+			return nil, fmt.Errorf("invalid default strategy in %s: %v", path, err)
+		}
+	}
+	for _, svc := range file.ServiceStrategies {
+		if s.serviceStrategies[svc.Service], err = svc.toThrift(); err != nil {
+			return nil, fmt.Errorf("invalid strategy of service %s in %s: %v", svc.Service, path, err)
+		}
+	}
+	return s, nil
+}
+
+// forService returns the strategy of the service, or the default one if it is not listed
+func (s *fileStrategies) forService(serviceName string) *sampling.SamplingStrategyResponse {
+	if resp, ok := s.serviceStrategies[serviceName]; ok {
+		return resp
+	}
+	return s.defaultStrategy
+}
+
+func (s strategy) toThrift() (*sampling.SamplingStrategyResponse, error) {
+	switch s.Type {
+	case probabilisticStrategy:
+		if s.Param < 0 || s.Param > 1 {
+			return nil, fmt.Errorf("sampling rate must be between 0 and 1, got %v", s.Param)
+		}
+		return &sampling.SamplingStrategyResponse{
+			StrategyType:          sampling.SamplingStrategyType_PROBABILISTIC,
+			ProbabilisticSampling: &sampling.ProbabilisticSamplingStrategy{SamplingRate: s.Param},
+		}, nil
+	case rateLimitingStrategy:
+		if s.Param < 0 || s.Param > math.MaxInt16 {
+			return nil, fmt.Errorf("max traces per second must be between 0 and %d, got %v", math.MaxInt16, s.Param)
+		}
+		return &sampling.SamplingStrategyResponse{
+			StrategyType:         sampling.SamplingStrategyType_RATE_LIMITING,
+			RateLimitingSampling: &sampling.RateLimitingSamplingStrategy{MaxTracesPerSecond: int16(s.Param)},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown strategy type %q, expected %q or %q", s.Type, probabilisticStrategy, rateLimitingStrategy)
+	}
+}
@@ -0,0 +1,98 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package httpserver
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/thrift-gen/sampling"
+)
+
+func TestLoadStrategies(t *testing.T) {
+	path := writeStrategies(t, `{
+		"default_strategy": {"type": "probabilistic", "param": 0.5},
+		"service_strategies": [
+			{"service": "frontend", "type": "ratelimiting", "param": 5},
+			{"service": "backend", "type": "probabilistic", "param": 1}
+		]
+	}`)
+	defer os.Remove(path)
+
+	s, err := loadStrategies(path)
+	require.NoError(t, err)
+
+	frontend := s.forService("frontend")
+	assert.Equal(t, sampling.SamplingStrategyType_RATE_LIMITING, frontend.StrategyType)
+	assert.EqualValues(t, 5, frontend.RateLimitingSampling.MaxTracesPerSecond)
+	backend := s.forService("backend")
+	assert.Equal(t, sampling.SamplingStrategyType_PROBABILISTIC, backend.StrategyType)
+	assert.Equal(t, 1.0, backend.ProbabilisticSampling.SamplingRate)
+	assert.Equal(t, 0.5, s.forService("other").ProbabilisticSampling.SamplingRate)
+}
+
+func TestLoadStrategiesWithoutDefault(t *testing.T) {
+	path := writeStrategies(t, `{"service_strategies": [{"service": "frontend", "type": "probabilistic", "param": 0.1}]}`)
+	defer os.Remove(path)
+
+	s, err := loadStrategies(path)
+	require.NoError(t, err)
+	assert.NotNil(t, s.forService("frontend"))
+	assert.Nil(t, s.forService("other"))
+}
+
+func TestLoadStrategiesErrors(t *testing.T) {
+	testCases := []struct {
+		content string
+		err     string
+	}{
+		{
+			content: `{`,
+			err:     "cannot parse strategies file",
+		},
+		{
+			content: `{"default_strategy": {"type": "probabilistic", "param": 2}}`,
+			err:     "invalid default strategy",
+		},
+		{
+			content: `{"service_strategies": [{"service": "frontend", "type": "ratelimiting", "param": 100000}]}`,
+			err:     "invalid strategy of service frontend",
+		},
+		{
+			content: `{"default_strategy": {"type": "adaptive", "param": 1}}`,
+			err:     `unknown strategy type "adaptive"`,
+		},
+	}
+	for _, tc := range testCases {
+		path := writeStrategies(t, tc.content)
+		_, err := loadStrategies(path)
+		os.Remove(path)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), tc.err)
+		}
+	}
+	_, err := loadStrategies("/does/not/exist.json")
+	assert.Error(t, err)
+}
@@ -23,6 +23,8 @@ package processors
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/apache/thrift/lib/go/thrift"
 	"github.com/uber/jaeger-lib/metrics"
@@ -35,9 +37,14 @@ import (
 type ThriftProcessor struct {
 	server        servers.Server
 	handler       AgentProcessor
+	factory       thrift.TProtocolFactory
 	protocolPool  *sync.Pool
 	numProcessors int
 	processing    sync.WaitGroup
+	busy          int64
+	handlerErrors int64
+	lastError     atomic.Value
+	truncations   *truncationTracker
 	metrics       struct {
 		// Amount of time taken for processor to close
 		ProcessorCloseTimer metrics.Timer `metric:"thrift.udp.t-processor.close-time"`
@@ -47,6 +54,17 @@ type ThriftProcessor struct {
 	}
 }
 
+// Status is a snapshot of the state of a ThriftProcessor
+type Status struct {
+	Workers       int             `json:"workers"`
+	BusyWorkers   int64           `json:"busyWorkers"`
+	HandlerErrors int64           `json:"handlerErrors"`
+	LastError     string          `json:"lastError,omitempty"`
+	Server        *servers.Status `json:"server,omitempty"`
+	// TruncatedPackets are the packets which exceeded the max packet size by client and service
+	TruncatedPackets []TruncatedPackets `json:"truncatedPackets"`
+}
+
 // AgentProcessor handler used by the processor to process thrift and call the reporter with the deserialized struct
 type AgentProcessor interface {
 	Process(iprot, oprot thrift.TProtocol) (success bool, err thrift.TException)
@@ -74,8 +92,10 @@ func NewThriftProcessor(
 	res := &ThriftProcessor{
 		server:        server,
 		handler:       handler,
+		factory:       factory,
 		protocolPool:  protocolPool,
 		numProcessors: numProcessors,
+		truncations:   newTruncationTracker(mFactory),
 	}
 	metrics.Init(&res.metrics, mFactory, nil)
 	return res, nil
@@ -96,6 +116,26 @@ func (s *ThriftProcessor) IsServing() bool {
 	return s.server.IsServing()
 }
 
+// Status returns a snapshot of the state of the processor and, if it reports it, of its server
+func (s *ThriftProcessor) Status() Status {
+	status := Status{
+		Workers:          s.numProcessors,
+		BusyWorkers:      atomic.LoadInt64(&s.busy),
+		HandlerErrors:    atomic.LoadInt64(&s.handlerErrors),
+		TruncatedPackets: s.truncations.snapshot(),
+	}
+	if lastError, ok := s.lastError.Load().(string); ok {
+		status.LastError = lastError
+	}
+	if server, ok := s.server.(interface {
+		Status() servers.Status
+	}); ok {
+		serverStatus := server.Status()
+		status.Server = &serverStatus
+	}
+	return status
+}
+
 // Stop stops the serving of traffic and waits until the queue is
 // emptied by the readers
 func (s *ThriftProcessor) Stop() {
@@ -109,15 +149,35 @@ func (s *ThriftProcessor) Stop() {
 // the processor to process
 func (s *ThriftProcessor) processBuffer() {
 	for readBuf := range s.server.DataChan() {
+		if readBuf.Truncated() {
+			// the packet cannot be decoded, only the service which sent it is looked for
+			s.recordTruncated(readBuf)
+			s.server.DataRecd(readBuf)
+			continue
+		}
+		atomic.AddInt64(&s.busy, 1)
 		protocol := s.protocolPool.Get().(thrift.TProtocol)
 		protocol.Transport().Write(readBuf.GetBytes())
 		s.server.DataRecd(readBuf) // acknowledge receipt and release the buffer
 
-		if ok, _ := s.handler.Process(protocol, protocol); !ok {
+		if ok, err := s.handler.Process(protocol, protocol); !ok {
 			// TODO log the error
+			atomic.AddInt64(&s.handlerErrors, 1)
+			if err != nil {
+				s.lastError.Store(err.Error())
+			}
 			s.metrics.HandlerProcessError.Inc(1)
 		}
 		s.protocolPool.Put(protocol)
+		atomic.AddInt64(&s.busy, -1)
 	}
 	s.processing.Done()
 }
+
+func (s *ThriftProcessor) recordTruncated(readBuf *servers.ReadBuf) {
+	// a new protocol is used since reading a truncated packet may leave it in an inconsistent state
+	trans := &customtransport.TBufferedReadTransport{}
+	trans.Write(readBuf.GetBytes())
+	service := serviceName(s.factory.GetProtocol(trans))
+	s.truncations.record(readBuf.Addr(), service, time.Now())
+}
@@ -148,6 +148,11 @@ func TestProcessor_HandlerError(t *testing.T) {
 		mTestutils.ExpectedMetric{Name: "thrift.udp.t-processor.handler-errors", Value: 1},
 		mTestutils.ExpectedMetric{Name: "thrift.udp.server.packets.processed", Value: 1},
 	)
+	status := processor.(*ThriftProcessor).Status()
+	assert.EqualValues(t, 1, status.HandlerErrors)
+	assert.Equal(t, "doh", status.LastError)
+	require.NotNil(t, status.Server)
+	assert.EqualValues(t, 1, status.Server.PacketsProcessed)
 }
 
 func TestJaegerProcessor(t *testing.T) {
@@ -0,0 +1,195 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processors
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/uber/jaeger-lib/metrics"
+)
+
+const (
+	// maxTruncationSources bounds the number of client and service pairs whose truncated packets
+	// are tracked, as well as the number of services they are counted by in the metrics
+	maxTruncationSources = 100
+
+	unknownService = "unknown"
+	otherServices  = "other"
+)
+
+// servicePaths are the ids of the fields leading to the service name in the arguments of the agent
+// methods, the first element of the lists is followed
+var servicePaths = map[string][]int16{
+	// emitBatch(batch) -> Batch.process -> Process.serviceName
+	"emitBatch": {1, 1, 1},
+	// emitZipkinBatch(spans) -> Span.annotations -> Annotation.host -> Endpoint.service_name
+	"emitZipkinBatch": {1, 6, 3, 3},
+}
+
+// TruncatedPackets counts the packets of a client and service that exceeded the max packet size
+type TruncatedPackets struct {
+	Client   string    `json:"client"`
+	Service  string    `json:"service"`
+	Count    int64     `json:"count"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+type truncationSource struct {
+	client  string
+	service string
+}
+
+// truncationTracker counts the truncated packets by client and service
+type truncationTracker struct {
+	sync.Mutex
+	mFactory metrics.Factory
+	sources  map[truncationSource]*TruncatedPackets
+	counters map[string]metrics.Counter
+}
+
+func newTruncationTracker(mFactory metrics.Factory) *truncationTracker {
+	return &truncationTracker{
+		mFactory: mFactory,
+		sources:  make(map[truncationSource]*TruncatedPackets),
+		counters: make(map[string]metrics.Counter),
+	}
+}
+
+func (t *truncationTracker) record(addr net.Addr, service string, now time.Time) {
+	source := truncationSource{client: clientHost(addr), service: service}
+	t.Lock()
+	defer t.Unlock()
+	counter, ok := t.counters[service]
+	if !ok {
+		if len(t.counters) >= maxTruncationSources {
+			service = otherServices
+		}
+		if counter, ok = t.counters[service]; !ok {
+			counter = t.mFactory.Counter("thrift.udp.t-processor.truncated-packets", map[string]string{"service": service})
+			t.counters[service] = counter
+		}
+	}
+	counter.Inc(1)
+
+	packets, ok := t.sources[source]
+	if !ok {
+		if len(t.sources) >= maxTruncationSources {
+			return
+		}
+		packets = &TruncatedPackets{Client: source.client, Service: source.service}
+		t.sources[source] = packets
+	}
+	packets.Count++
+	packets.LastSeen = now
+}
+
+// snapshot returns the truncated packets, the most frequent first
+func (t *truncationTracker) snapshot() []TruncatedPackets {
+	t.Lock()
+	defer t.Unlock()
+	snapshot := make([]TruncatedPackets, 0, len(t.sources))
+	for _, packets := range t.sources {
+		snapshot = append(snapshot, *packets)
+	}
+	sort.Slice(snapshot, func(i, j int) bool {
+		if snapshot[i].Count != snapshot[j].Count {
+			return snapshot[i].Count > snapshot[j].Count
+		}
+		return snapshot[i].Client+snapshot[i].Service < snapshot[j].Client+snapshot[j].Service
+	})
+	return snapshot
+}
+
+func clientHost(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	// the port of the clients changes with every socket, only their host is kept
+	if host, _, err := net.SplitHostPort(addr.String()); err == nil {
+		return host
+	}
+	return addr.String()
+}
+
+// serviceName reads the name of the service from the beginning of a packet, which may be truncated
+func serviceName(protocol thrift.TProtocol) string {
+	name, _, _, err := protocol.ReadMessageBegin()
+	if err != nil {
+		return unknownService
+	}
+	path, ok := servicePaths[name]
+	if !ok {
+		return unknownService
+	}
+	if _, err := protocol.ReadStructBegin(); err != nil {
+		return unknownService
+	}
+	service, ok := readStringField(protocol, path)
+	if !ok || service == "" {
+		return unknownService
+	}
+	return service
+}
+
+// readStringField follows path in the struct being read and returns the string field it leads to
+func readStringField(protocol thrift.TProtocol, path []int16) (string, bool) {
+	for {
+		_, fieldType, id, err := protocol.ReadFieldBegin()
+		if err != nil || fieldType == thrift.STOP {
+			return "", false
+		}
+		if id != path[0] {
+			if err := protocol.Skip(fieldType); err != nil {
+				return "", false
+			}
+			if err := protocol.ReadFieldEnd(); err != nil {
+				return "", false
+			}
+			continue
+		}
+		switch {
+		case fieldType == thrift.STRING && len(path) == 1:
+			value, err := protocol.ReadString()
+			return value, err == nil
+		case fieldType == thrift.STRUCT && len(path) > 1:
+			if _, err := protocol.ReadStructBegin(); err != nil {
+				return "", false
+			}
+			return readStringField(protocol, path[1:])
+		case fieldType == thrift.LIST && len(path) > 1:
+			elemType, size, err := protocol.ReadListBegin()
+			if err != nil || size == 0 || elemType != thrift.STRUCT {
+				return "", false
+			}
+			if _, err := protocol.ReadStructBegin(); err != nil {
+				return "", false
+			}
+			return readStringField(protocol, path[1:])
+		default:
+			return "", false
+		}
+	}
+}
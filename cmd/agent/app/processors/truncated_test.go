@@ -0,0 +1,153 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processors
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	mTestutils "github.com/uber/jaeger-lib/metrics/testutils"
+
+	"github.com/uber/jaeger/cmd/agent/app/customtransports"
+	"github.com/uber/jaeger/cmd/agent/app/servers"
+	"github.com/uber/jaeger/cmd/agent/app/servers/thriftudp"
+	"github.com/uber/jaeger/cmd/agent/app/testutils"
+	"github.com/uber/jaeger/thrift-gen/agent"
+	"github.com/uber/jaeger/thrift-gen/jaeger"
+	"github.com/uber/jaeger/thrift-gen/zipkincore"
+)
+
+func jaegerPacket(t *testing.T, factory thrift.TProtocolFactory, service string) []byte {
+	buffer := thrift.NewTMemoryBuffer()
+	client := jaeger.NewAgentClientFactory(buffer, factory)
+	require.NoError(t, client.EmitBatch(&jaeger.Batch{
+		Process: &jaeger.Process{ServiceName: service},
+		Spans:   []*jaeger.Span{{OperationName: strings.Repeat("x", 100)}},
+	}))
+	return buffer.Bytes()
+}
+
+func zipkinPacket(t *testing.T, factory thrift.TProtocolFactory, service string) []byte {
+	buffer := thrift.NewTMemoryBuffer()
+	client := agent.NewAgentClientFactory(buffer, factory)
+	require.NoError(t, client.EmitZipkinBatch([]*zipkincore.Span{{
+		Name: "span",
+		Annotations: []*zipkincore.Annotation{
+			{Value: zipkincore.SERVER_RECV, Host: &zipkincore.Endpoint{ServiceName: service}},
+		},
+		BinaryAnnotations: []*zipkincore.BinaryAnnotation{
+			{Key: "k", Value: []byte(strings.Repeat("x", 100)), AnnotationType: zipkincore.AnnotationType_STRING},
+		},
+	}}))
+	return buffer.Bytes()
+}
+
+func readServiceName(factory thrift.TProtocolFactory, packet []byte) string {
+	trans := &customtransport.TBufferedReadTransport{}
+	trans.Write(packet)
+	return serviceName(factory.GetProtocol(trans))
+}
+
+func TestServiceName(t *testing.T) {
+	factories := map[string]thrift.TProtocolFactory{
+		"compact": compactFactory,
+		"binary":  binaryFactory,
+	}
+	for name, factory := range factories {
+		t.Run(name, func(t *testing.T) {
+			packet := jaegerPacket(t, factory, "frontend")
+			assert.Equal(t, "frontend", readServiceName(factory, packet[:len(packet)-50]))
+			assert.Equal(t, unknownService, readServiceName(factory, packet[:10]))
+
+			packet = zipkinPacket(t, factory, "backend")
+			assert.Equal(t, "backend", readServiceName(factory, packet[:len(packet)-50]))
+			assert.Equal(t, unknownService, readServiceName(factory, packet[:10]))
+
+			assert.Equal(t, unknownService, readServiceName(factory, []byte("garbage")))
+		})
+	}
+}
+
+func TestTruncationTracker(t *testing.T) {
+	mFactory := metrics.NewLocalFactory(0)
+	tracker := newTruncationTracker(mFactory)
+	now := time.Unix(1500000000, 0)
+	client := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 12345}
+
+	tracker.record(client, "frontend", now)
+	tracker.record(&net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 23456}, "frontend", now)
+	tracker.record(nil, "backend", now)
+	assert.Equal(t, []TruncatedPackets{
+		{Client: "10.0.0.1", Service: "frontend", Count: 2, LastSeen: now},
+		{Client: "", Service: "backend", Count: 1, LastSeen: now},
+	}, tracker.snapshot())
+
+	for i := 0; i < maxTruncationSources; i++ {
+		tracker.record(client, fmt.Sprintf("service-%d", i), now)
+	}
+	assert.Len(t, tracker.snapshot(), maxTruncationSources)
+	mTestutils.AssertCounterMetrics(t, mFactory,
+		mTestutils.ExpectedMetric{Name: "thrift.udp.t-processor.truncated-packets", Tags: map[string]string{"service": "frontend"}, Value: 2},
+		mTestutils.ExpectedMetric{Name: "thrift.udp.t-processor.truncated-packets", Tags: map[string]string{"service": "other"}, Value: 2},
+	)
+}
+
+func TestProcessorTruncatedPackets(t *testing.T) {
+	transport, err := thriftudp.NewTUDPServerTransport("127.0.0.1:0")
+	require.NoError(t, err)
+	server, err := servers.NewTBufferedServer(transport, 10, 60, metrics.NullFactory)
+	require.NoError(t, err)
+	handler := failingHandler{err: errors.New("truncated packets must not be processed")}
+	processor, err := NewThriftProcessor(server, 1, metrics.NullFactory, compactFactory, handler)
+	require.NoError(t, err)
+	go processor.Serve()
+	defer processor.Stop()
+	time.Sleep(10 * time.Millisecond) // wait for server to start serving
+
+	client, clientCloser, err := testutils.NewJaegerThriftUDPClient(transport.Addr().String(), compactFactory)
+	require.NoError(t, err)
+	defer clientCloser.Close()
+	require.NoError(t, client.EmitBatch(&jaeger.Batch{
+		Process: &jaeger.Process{ServiceName: "frontend"},
+		Spans:   []*jaeger.Span{{OperationName: strings.Repeat("x", 100)}},
+	}))
+
+	var status Status
+	for i := 0; i < 1000; i++ {
+		if status = processor.Status(); len(status.TruncatedPackets) > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	require.Len(t, status.TruncatedPackets, 1)
+	assert.Equal(t, "127.0.0.1", status.TruncatedPackets[0].Client)
+	assert.Equal(t, "frontend", status.TruncatedPackets[0].Service)
+	assert.EqualValues(t, 0, status.HandlerErrors, "truncated packets must not be passed to the handler")
+}
@@ -0,0 +1,508 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reporter
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model/converter/thrift/zipkin"
+	"github.com/uber/jaeger/thrift-gen/jaeger"
+	"github.com/uber/jaeger/thrift-gen/zipkincore"
+)
+
+const (
+	// DefaultMaxSpillBytes is the default limit of the size of the batches spilled to disk
+	DefaultMaxSpillBytes = 100 * 1024 * 1024
+	// DefaultInitialBackoff is the default delay before the first replay of a buffered batch
+	DefaultInitialBackoff = time.Second
+	// DefaultMaxBackoff is the default max delay between two replays of a buffered batch
+	DefaultMaxBackoff = 30 * time.Second
+	// DefaultMaxRetries is the default number of failed replays after which a buffered batch is dropped,
+	// about ten minutes with the default backoffs
+	DefaultMaxRetries = 25
+
+	jaegerKind = "jaeger"
+	zipkinKind = "zipkin"
+
+	spillTmpSuffix = ".tmp"
+)
+
+var errBufferFull = errors.New("reporter buffer is full, batch dropped")
+
+// BufferOptions configures the buffering of the batches that could not be submitted
+type BufferOptions struct {
+	// MaxBatches is the max number of batches buffered in memory
+	MaxBatches int `yaml:"maxBatches"`
+	// SpillDir is the directory the batches are written to once the in-memory buffer is full,
+	// they survive a restart of the agent. Spilling is disabled when empty.
+	SpillDir string `yaml:"spillDir"`
+	// MaxSpillBytes is the max size of the batches spilled to disk, defaults to DefaultMaxSpillBytes
+	MaxSpillBytes int64 `yaml:"maxSpillBytes"`
+	// InitialBackoff is the delay before the first replay of a batch, defaults to DefaultInitialBackoff
+	InitialBackoff time.Duration `yaml:"initialBackoff"`
+	// MaxBackoff is the max delay between two replays of a batch, defaults to DefaultMaxBackoff
+	MaxBackoff time.Duration `yaml:"maxBackoff"`
+	// MaxRetries is the number of failed replays after which a batch is dropped, defaults to DefaultMaxRetries
+	MaxRetries int `yaml:"maxRetries"`
+}
+
+// permanentError is implemented by the errors of the wrapped reporters that tell whether the batch
+// can never be accepted, e.g. because the collector rejected it as invalid
+type permanentError interface {
+	Permanent() bool
+}
+
+// isPermanent returns true if submitting the batch again cannot succeed
+func isPermanent(err error) bool {
+	pErr, ok := err.(permanentError)
+	return ok && pErr.Permanent()
+}
+
+// Enabled returns true if the batches should be buffered
+func (o BufferOptions) Enabled() bool {
+	return o.MaxBatches > 0 || o.SpillDir != ""
+}
+
+func (o BufferOptions) withDefaults() BufferOptions {
+	if o.MaxSpillBytes == 0 {
+		o.MaxSpillBytes = DefaultMaxSpillBytes
+	}
+	if o.InitialBackoff == 0 {
+		o.InitialBackoff = DefaultInitialBackoff
+	}
+	if o.MaxBackoff == 0 {
+		o.MaxBackoff = DefaultMaxBackoff
+	}
+	if o.MaxBackoff < o.InitialBackoff {
+		o.MaxBackoff = o.InitialBackoff
+	}
+	if o.MaxRetries <= 0 {
+		o.MaxRetries = DefaultMaxRetries
+	}
+	return o
+}
+
+type bufferMetrics struct {
+	// Number of batches added to the buffer after a failed submission
+	Buffered metrics.Counter `metric:"buffered"`
+
+	// Number of buffered batches eventually submitted
+	Replayed metrics.Counter `metric:"replayed"`
+
+	// Number of failed replays of buffered batches
+	Retries metrics.Counter `metric:"retries"`
+
+	// Number of batches dropped because the buffer was full, they could not be read back
+	// from disk, they were rejected for good or they exhausted their retries
+	Dropped metrics.Counter `metric:"dropped"`
+
+	// Number of batches buffered in memory
+	Batches metrics.Gauge `metric:"batches"`
+
+	// Size in bytes of the batches buffered in memory
+	Bytes metrics.Gauge `metric:"bytes"`
+
+	// Number of batches spilled to disk
+	SpilledBatches metrics.Gauge `metric:"spill.batches"`
+
+	// Size in bytes of the batches spilled to disk
+	SpilledBytes metrics.Gauge `metric:"spill.bytes"`
+}
+
+// bufferEntry is a batch waiting to be replayed, it is either kept in memory or spilled to path
+type bufferEntry struct {
+	seq   uint64
+	kind  string
+	batch *jaeger.Batch
+	spans []*zipkincore.Span
+	size  int64
+	path  string
+}
+
+// BufferedReporter is a Reporter decorator that keeps the batches the wrapped reporter failed to
+// submit and replays them in order, with an exponential backoff, until they are accepted or
+// exhaust their retries. The batches rejected with a permanent error are dropped right away.
+// While batches are buffered new ones are appended to the buffer, so that the collectors receive
+// them in the order they were emitted.
+type BufferedReporter struct {
+	sync.Mutex
+	wrapped Reporter
+	options BufferOptions
+	metrics bufferMetrics
+	logger  *zap.Logger
+	random  *rand.Rand
+
+	entries     []*bufferEntry
+	nextSeq     uint64
+	memBatches  int
+	memBytes    int64
+	diskBatches int
+	diskBytes   int64
+	closed      bool
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewBufferedReporter creates a BufferedReporter and starts replaying the batches spilled to disk
+// by a previous run
+func NewBufferedReporter(wrapped Reporter, options BufferOptions, mFactory metrics.Factory, logger *zap.Logger) (*BufferedReporter, error) {
+	r := &BufferedReporter{
+		wrapped: wrapped,
+		options: options.withDefaults(),
+		logger:  logger,
+		random:  rand.New(rand.NewSource(time.Now().UnixNano())),
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	metrics.Init(&r.metrics, mFactory.Namespace("buffered-reporter", nil), nil)
+	if r.options.SpillDir != "" {
+		if err := r.loadSpilled(); err != nil {
+			return nil, err
+		}
+	}
+	r.updateGauges()
+	go r.replay()
+	return r, nil
+}
+
+// EmitZipkinBatch implements EmitZipkinBatch() of Reporter
+func (r *BufferedReporter) EmitZipkinBatch(spans []*zipkincore.Span) error {
+	return r.emit(&bufferEntry{kind: zipkinKind, spans: spans})
+}
+
+// EmitBatch implements EmitBatch() of Reporter
+func (r *BufferedReporter) EmitBatch(batch *jaeger.Batch) error {
+	return r.emit(&bufferEntry{kind: jaegerKind, batch: batch})
+}
+
+// Close stops the replays and spills the batches buffered in memory to disk, if enabled.
+// Batches emitted afterwards are passed to the wrapped reporter without buffering.
+func (r *BufferedReporter) Close() error {
+	r.Lock()
+	if r.closed {
+		r.Unlock()
+		return nil
+	}
+	r.closed = true
+	r.Unlock()
+	close(r.stop)
+	<-r.done
+
+	r.Lock()
+	defer r.Unlock()
+	if r.options.SpillDir == "" {
+		if len(r.entries) > 0 {
+			r.logger.Warn("Dropping buffered batches", zap.Int("batches", len(r.entries)))
+			r.metrics.Dropped.Inc(int64(len(r.entries)))
+		}
+		return nil
+	}
+	for _, e := range r.entries {
+		if e.path != "" {
+			continue
+		}
+		if err := r.spill(e); err != nil {
+			r.logger.Error("Could not spill buffered batch", zap.Error(err))
+			r.metrics.Dropped.Inc(1)
+		}
+	}
+	return nil
+}
+
+func (r *BufferedReporter) emit(e *bufferEntry) error {
+	r.Lock()
+	pending := len(r.entries) > 0
+	closed := r.closed
+	r.Unlock()
+	if closed {
+		return r.send(e)
+	}
+	// batches already waiting go first, the new one is only submitted right away if none are
+	if !pending {
+		err := r.send(e)
+		if err == nil {
+			return nil
+		}
+		if isPermanent(err) {
+			r.metrics.Dropped.Inc(1)
+			return err
+		}
+	}
+	return r.add(e)
+}
+
+func (r *BufferedReporter) send(e *bufferEntry) error {
+	if e.kind == zipkinKind {
+		return r.wrapped.EmitZipkinBatch(e.spans)
+	}
+	return r.wrapped.EmitBatch(e.batch)
+}
+
+func (r *BufferedReporter) add(e *bufferEntry) error {
+	data, err := e.serialize()
+	if err != nil {
+		r.metrics.Dropped.Inc(1)
+		return err
+	}
+	e.size = int64(len(data))
+
+	r.Lock()
+	defer r.Unlock()
+	e.seq = r.nextSeq
+	r.nextSeq++
+	// once closed nothing is replayed anymore, so only the batches spilled to disk are kept
+	if !r.closed && r.memBatches < r.options.MaxBatches {
+		r.memBatches++
+		r.memBytes += e.size
+	} else if r.canSpill(e) {
+		if err := r.writeSpilled(e, data); err != nil {
+			r.metrics.Dropped.Inc(1)
+			return err
+		}
+	} else {
+		r.metrics.Dropped.Inc(1)
+		return errBufferFull
+	}
+	r.entries = append(r.entries, e)
+	r.metrics.Buffered.Inc(1)
+	r.updateGauges()
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// replay submits the buffered batches in order, retrying the oldest one until it succeeds
+func (r *BufferedReporter) replay() {
+	defer close(r.done)
+	attempt := 0
+	for {
+		r.Lock()
+		var head *bufferEntry
+		if len(r.entries) > 0 {
+			head = r.entries[0]
+		}
+		r.Unlock()
+		if head == nil {
+			select {
+			case <-r.wake:
+				continue
+			case <-r.stop:
+				return
+			}
+		}
+
+		e, err := head.load()
+		if err != nil {
+			r.logger.Error("Could not read spilled batch, dropping it", zap.String("path", head.path), zap.Error(err))
+			r.metrics.Dropped.Inc(1)
+			r.removeHead(head)
+			continue
+		}
+		if err := r.send(e); err != nil {
+			if isPermanent(err) {
+				r.logger.Error("Dropping batch rejected by the collector", zap.Error(err))
+				r.metrics.Dropped.Inc(1)
+				r.removeHead(head)
+				attempt = 0
+				continue
+			}
+			attempt++
+			if attempt > r.options.MaxRetries {
+				r.logger.Error("Dropping batch after exhausting its retries", zap.Int("retries", r.options.MaxRetries), zap.Error(err))
+				r.metrics.Dropped.Inc(1)
+				r.removeHead(head)
+				attempt = 0
+				continue
+			}
+			r.metrics.Retries.Inc(1)
+			select {
+			case <-time.After(r.backoff(attempt)):
+			case <-r.stop:
+				return
+			}
+			continue
+		}
+		attempt = 0
+		r.metrics.Replayed.Inc(1)
+		r.removeHead(head)
+	}
+}
+
+// backoff returns the exponential delay before the given attempt, with a random jitter of up
+// to half of it so that agents restarted together do not replay in lockstep
+func (r *BufferedReporter) backoff(attempt int) time.Duration {
+	d := r.options.InitialBackoff
+	for i := 1; i < attempt && d < r.options.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > r.options.MaxBackoff {
+		d = r.options.MaxBackoff
+	}
+	half := int64(d / 2)
+	return time.Duration(half + r.random.Int63n(half+1))
+}
+
+func (r *BufferedReporter) removeHead(head *bufferEntry) {
+	r.Lock()
+	defer r.Unlock()
+	r.entries[0] = nil
+	r.entries = r.entries[1:]
+	if head.path != "" {
+		r.diskBatches--
+		r.diskBytes -= head.size
+		if err := os.Remove(head.path); err != nil && !os.IsNotExist(err) {
+			r.logger.Error("Could not remove spilled batch", zap.String("path", head.path), zap.Error(err))
+		}
+	} else {
+		r.memBatches--
+		r.memBytes -= head.size
+	}
+	r.updateGauges()
+}
+
+func (r *BufferedReporter) canSpill(e *bufferEntry) bool {
+	return r.options.SpillDir != "" && r.diskBytes+e.size <= r.options.MaxSpillBytes
+}
+
+// spill moves an entry buffered in memory to disk
+func (r *BufferedReporter) spill(e *bufferEntry) error {
+	if !r.canSpill(e) {
+		return errBufferFull
+	}
+	data, err := e.serialize()
+	if err != nil {
+		return err
+	}
+	if err := r.writeSpilled(e, data); err != nil {
+		return err
+	}
+	r.memBatches--
+	r.memBytes -= e.size
+	r.updateGauges()
+	return nil
+}
+
+// writeSpilled writes the entry to a file named after its sequence number, so that the order is
+// kept across restarts
+func (r *BufferedReporter) writeSpilled(e *bufferEntry, data []byte) error {
+	path := filepath.Join(r.options.SpillDir, fmt.Sprintf("%020d.%s", e.seq, e.kind))
+	tmp := path + spillTmpSuffix
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	e.path = path
+	e.batch = nil
+	e.spans = nil
+	r.diskBatches++
+	r.diskBytes += e.size
+	return nil
+}
+
+// loadSpilled buffers the batches spilled to disk by a previous run
+func (r *BufferedReporter) loadSpilled() error {
+	if err := os.MkdirAll(r.options.SpillDir, 0700); err != nil {
+		return err
+	}
+	files, err := ioutil.ReadDir(r.options.SpillDir)
+	if err != nil {
+		return err
+	}
+	// files are sorted by name, which is the sequence number padded with zeros
+	for _, file := range files {
+		path := filepath.Join(r.options.SpillDir, file.Name())
+		if strings.HasSuffix(file.Name(), spillTmpSuffix) {
+			os.Remove(path)
+			continue
+		}
+		parts := strings.SplitN(file.Name(), ".", 2)
+		if len(parts) != 2 || (parts[1] != jaegerKind && parts[1] != zipkinKind) {
+			continue
+		}
+		seq, err := strconv.ParseUint(parts[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		r.entries = append(r.entries, &bufferEntry{seq: seq, kind: parts[1], size: file.Size(), path: path})
+		r.diskBatches++
+		r.diskBytes += file.Size()
+		r.nextSeq = seq + 1
+	}
+	if len(r.entries) > 0 {
+		r.logger.Info("Replaying spilled batches", zap.Int("batches", len(r.entries)), zap.String("dir", r.options.SpillDir))
+	}
+	return nil
+}
+
+func (r *BufferedReporter) updateGauges() {
+	r.metrics.Batches.Update(int64(r.memBatches))
+	r.metrics.Bytes.Update(r.memBytes)
+	r.metrics.SpilledBatches.Update(int64(r.diskBatches))
+	r.metrics.SpilledBytes.Update(r.diskBytes)
+}
+
+func (e *bufferEntry) serialize() ([]byte, error) {
+	if e.kind == zipkinKind {
+		return zipkin.SerializeThrift(e.spans)
+	}
+	return thrift.NewTSerializer().Write(e.batch)
+}
+
+// load returns the entry with its batch, reading it back from disk if it was spilled
+func (e *bufferEntry) load() (*bufferEntry, error) {
+	if e.path == "" {
+		return e, nil
+	}
+	data, err := ioutil.ReadFile(e.path)
+	if err != nil {
+		return nil, err
+	}
+	loaded := &bufferEntry{kind: e.kind}
+	if e.kind == zipkinKind {
+		loaded.spans, err = zipkin.DeserializeThrift(data)
+		return loaded, err
+	}
+	loaded.batch = &jaeger.Batch{}
+	err = thrift.NewTDeserializer().Read(loaded.batch, data)
+	return loaded, err
+}
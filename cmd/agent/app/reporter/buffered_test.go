@@ -0,0 +1,342 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reporter
+
+import (
+	"errors"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	mTestutils "github.com/uber/jaeger-lib/metrics/testutils"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/thrift-gen/jaeger"
+	"github.com/uber/jaeger/thrift-gen/zipkincore"
+)
+
+// flakyReporter records the batches it accepts and fails while down, it rejects for good the
+// batches of the rejected operation
+type flakyReporter struct {
+	sync.Mutex
+	down       bool
+	rejected   string
+	operations []string
+}
+
+type rejectedError struct{}
+
+func (rejectedError) Error() string   { return "batch rejected" }
+func (rejectedError) Permanent() bool { return true }
+
+func (r *flakyReporter) EmitZipkinBatch(spans []*zipkincore.Span) error {
+	r.Lock()
+	defer r.Unlock()
+	if r.down {
+		return errors.New("collector down")
+	}
+	for _, span := range spans {
+		r.operations = append(r.operations, span.Name)
+	}
+	return nil
+}
+
+func (r *flakyReporter) EmitBatch(batch *jaeger.Batch) error {
+	r.Lock()
+	defer r.Unlock()
+	if r.down {
+		return errors.New("collector down")
+	}
+	for _, span := range batch.Spans {
+		if span.OperationName == r.rejected {
+			return rejectedError{}
+		}
+	}
+	for _, span := range batch.Spans {
+		r.operations = append(r.operations, span.OperationName)
+	}
+	return nil
+}
+
+func (r *flakyReporter) setDown(down bool) {
+	r.Lock()
+	defer r.Unlock()
+	r.down = down
+}
+
+func (r *flakyReporter) received() []string {
+	r.Lock()
+	defer r.Unlock()
+	return append([]string(nil), r.operations...)
+}
+
+func testBatch(operation string) *jaeger.Batch {
+	return &jaeger.Batch{
+		Process: &jaeger.Process{ServiceName: "svc"},
+		Spans:   []*jaeger.Span{{OperationName: operation}},
+	}
+}
+
+func testZipkinSpans(name string) []*zipkincore.Span {
+	return []*zipkincore.Span{{Name: name}}
+}
+
+func waitForOperations(t *testing.T, r *flakyReporter, expected []string) {
+	for i := 0; i < 1000 && len(r.received()) < len(expected); i++ {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, expected, r.received())
+}
+
+var fastBackoff = BufferOptions{InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+
+func TestBufferedReporterPassThrough(t *testing.T) {
+	wrapped := &flakyReporter{}
+	mf := metrics.NewLocalFactory(0)
+	opts := fastBackoff
+	opts.MaxBatches = 10
+	r, err := NewBufferedReporter(wrapped, opts, mf, zap.NewNop())
+	require.NoError(t, err)
+	defer r.Close()
+
+	require.NoError(t, r.EmitBatch(testBatch("a")))
+	require.NoError(t, r.EmitZipkinBatch(testZipkinSpans("b")))
+	assert.Equal(t, []string{"a", "b"}, wrapped.received())
+	mTestutils.AssertCounterMetrics(t, mf, mTestutils.ExpectedMetric{Name: "buffered-reporter.buffered", Value: 0})
+}
+
+func TestBufferedReporterReplaysInOrder(t *testing.T) {
+	wrapped := &flakyReporter{down: true}
+	mf := metrics.NewLocalFactory(0)
+	opts := fastBackoff
+	opts.MaxBatches = 10
+	r, err := NewBufferedReporter(wrapped, opts, mf, zap.NewNop())
+	require.NoError(t, err)
+	defer r.Close()
+
+	require.NoError(t, r.EmitBatch(testBatch("a")))
+	require.NoError(t, r.EmitZipkinBatch(testZipkinSpans("b")))
+	require.NoError(t, r.EmitBatch(testBatch("c")))
+	_, gauges := mf.Snapshot()
+	assert.EqualValues(t, 3, gauges["buffered-reporter.batches"])
+	assert.True(t, gauges["buffered-reporter.bytes"] > 0)
+
+	wrapped.setDown(false)
+	waitForOperations(t, wrapped, []string{"a", "b", "c"})
+
+	// once the buffer is empty the batches are submitted right away
+	require.NoError(t, r.EmitBatch(testBatch("d")))
+	assert.Equal(t, []string{"a", "b", "c", "d"}, wrapped.received())
+
+	mTestutils.AssertCounterMetrics(t, mf, []mTestutils.ExpectedMetric{
+		{Name: "buffered-reporter.buffered", Value: 3},
+		{Name: "buffered-reporter.replayed", Value: 3},
+		{Name: "buffered-reporter.dropped", Value: 0},
+	}...)
+	_, gauges = mf.Snapshot()
+	assert.EqualValues(t, 0, gauges["buffered-reporter.batches"])
+	assert.EqualValues(t, 0, gauges["buffered-reporter.bytes"])
+}
+
+func TestBufferedReporterDropsWhenFull(t *testing.T) {
+	wrapped := &flakyReporter{down: true}
+	mf := metrics.NewLocalFactory(0)
+	opts := fastBackoff
+	opts.MaxBatches = 2
+	r, err := NewBufferedReporter(wrapped, opts, mf, zap.NewNop())
+	require.NoError(t, err)
+
+	require.NoError(t, r.EmitBatch(testBatch("a")))
+	require.NoError(t, r.EmitBatch(testBatch("b")))
+	assert.Equal(t, errBufferFull, r.EmitBatch(testBatch("c")))
+
+	require.NoError(t, r.Close())
+	mTestutils.AssertCounterMetrics(t, mf, []mTestutils.ExpectedMetric{
+		{Name: "buffered-reporter.buffered", Value: 2},
+		// the full buffer, then the two batches lost on close
+		{Name: "buffered-reporter.dropped", Value: 3},
+	}...)
+}
+
+func TestBufferedReporterMaxRetries(t *testing.T) {
+	wrapped := &flakyReporter{down: true}
+	mf := metrics.NewLocalFactory(0)
+	opts := fastBackoff
+	opts.MaxBatches = 2
+	opts.MaxRetries = 2
+	r, err := NewBufferedReporter(wrapped, opts, mf, zap.NewNop())
+	require.NoError(t, err)
+	defer r.Close()
+
+	require.NoError(t, r.EmitBatch(testBatch("a")))
+	for i := 0; i < 1000; i++ {
+		counters, _ := mf.Snapshot()
+		if counters["buffered-reporter.dropped"] == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	mTestutils.AssertCounterMetrics(t, mf, []mTestutils.ExpectedMetric{
+		{Name: "buffered-reporter.retries", Value: 2},
+		{Name: "buffered-reporter.dropped", Value: 1},
+	}...)
+}
+
+func TestBufferedReporterDropsPermanentErrors(t *testing.T) {
+	wrapped := &flakyReporter{down: true, rejected: "bad"}
+	mf := metrics.NewLocalFactory(0)
+	opts := fastBackoff
+	opts.MaxBatches = 10
+	r, err := NewBufferedReporter(wrapped, opts, mf, zap.NewNop())
+	require.NoError(t, err)
+	defer r.Close()
+
+	require.NoError(t, r.EmitBatch(testBatch("a")))
+	require.NoError(t, r.EmitBatch(testBatch("bad")))
+	require.NoError(t, r.EmitBatch(testBatch("c")))
+	// the rejected batch does not hold back the next ones while replaying
+	wrapped.setDown(false)
+	waitForOperations(t, wrapped, []string{"a", "c"})
+
+	// nor is it buffered when submitted right away
+	assert.Equal(t, rejectedError{}, r.EmitBatch(testBatch("bad")))
+	mTestutils.AssertCounterMetrics(t, mf, []mTestutils.ExpectedMetric{
+		{Name: "buffered-reporter.buffered", Value: 3},
+		{Name: "buffered-reporter.replayed", Value: 2},
+		{Name: "buffered-reporter.dropped", Value: 2},
+	}...)
+}
+
+func TestBufferedReporterSpill(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spill")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	wrapped := &flakyReporter{down: true}
+	mf := metrics.NewLocalFactory(0)
+	opts := BufferOptions{MaxBatches: 1, SpillDir: dir, InitialBackoff: time.Hour}
+	r, err := NewBufferedReporter(wrapped, opts, mf, zap.NewNop())
+	require.NoError(t, err)
+
+	require.NoError(t, r.EmitBatch(testBatch("a")))
+	require.NoError(t, r.EmitZipkinBatch(testZipkinSpans("b")))
+	require.NoError(t, r.EmitBatch(testBatch("c")))
+	_, gauges := mf.Snapshot()
+	assert.EqualValues(t, 1, gauges["buffered-reporter.batches"])
+	assert.EqualValues(t, 2, gauges["buffered-reporter.spill.batches"])
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	// the batch kept in memory is spilled on close, before the ones already on disk
+	require.NoError(t, r.Close())
+	files, err = ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 3)
+
+	// a new reporter replays the spilled batches in order and removes them
+	wrapped.setDown(false)
+	mf = metrics.NewLocalFactory(0)
+	opts.InitialBackoff = time.Millisecond
+	r, err = NewBufferedReporter(wrapped, opts, mf, zap.NewNop())
+	require.NoError(t, err)
+	defer r.Close()
+	waitForOperations(t, wrapped, []string{"a", "b", "c"})
+	require.NoError(t, r.EmitBatch(testBatch("d")))
+	assert.Equal(t, []string{"a", "b", "c", "d"}, wrapped.received())
+
+	files, err = ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 0)
+	mTestutils.AssertCounterMetrics(t, mf, mTestutils.ExpectedMetric{Name: "buffered-reporter.replayed", Value: 3})
+}
+
+func TestBufferedReporterSpillLimit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spill")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	wrapped := &flakyReporter{down: true}
+	mf := metrics.NewLocalFactory(0)
+	opts := BufferOptions{SpillDir: dir, MaxSpillBytes: 1, InitialBackoff: time.Hour}
+	r, err := NewBufferedReporter(wrapped, opts, mf, zap.NewNop())
+	require.NoError(t, err)
+	defer r.Close()
+
+	assert.Equal(t, errBufferFull, r.EmitBatch(testBatch("a")))
+	mTestutils.AssertCounterMetrics(t, mf, mTestutils.ExpectedMetric{Name: "buffered-reporter.dropped", Value: 1})
+}
+
+func TestBufferedReporterCorruptSpill(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spill")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(dir+"/00000000000000000007.jaeger", []byte("not thrift"), 0600))
+	require.NoError(t, ioutil.WriteFile(dir+"/00000000000000000008.zipkin.tmp", []byte("partial"), 0600))
+
+	wrapped := &flakyReporter{}
+	mf := metrics.NewLocalFactory(0)
+	r, err := NewBufferedReporter(wrapped, BufferOptions{SpillDir: dir}, mf, zap.NewNop())
+	require.NoError(t, err)
+	defer r.Close()
+
+	for i := 0; i < 1000; i++ {
+		counters, _ := mf.Snapshot()
+		if counters["buffered-reporter.dropped"] == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	mTestutils.AssertCounterMetrics(t, mf, mTestutils.ExpectedMetric{Name: "buffered-reporter.dropped", Value: 1})
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 0)
+	assert.EqualValues(t, 8, r.nextSeq)
+}
+
+func TestBufferedReporterBackoff(t *testing.T) {
+	r := &BufferedReporter{
+		options: BufferOptions{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}.withDefaults(),
+	}
+	r.random = rand.New(rand.NewSource(1))
+	for attempt, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		d := r.backoff(attempt + 1)
+		assert.True(t, d >= expected/2 && d <= expected, "attempt %d: %v not in [%v, %v]", attempt+1, d, expected/2, expected)
+	}
+}
+
+func TestBufferOptions(t *testing.T) {
+	assert.False(t, BufferOptions{}.Enabled())
+	assert.True(t, BufferOptions{MaxBatches: 1}.Enabled())
+	assert.True(t, BufferOptions{SpillDir: "/tmp"}.Enabled())
+
+	opts := BufferOptions{InitialBackoff: time.Minute}.withDefaults()
+	assert.EqualValues(t, DefaultMaxSpillBytes, opts.MaxSpillBytes)
+	assert.Equal(t, DefaultMaxRetries, opts.MaxRetries)
+	assert.Equal(t, time.Minute, opts.MaxBackoff)
+}
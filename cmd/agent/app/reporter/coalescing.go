@@ -0,0 +1,328 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reporter
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model/converter/thrift/zipkin"
+	"github.com/uber/jaeger/thrift-gen/jaeger"
+	"github.com/uber/jaeger/thrift-gen/zipkincore"
+)
+
+const (
+	// DefaultCoalesceMaxSpans is the default max number of spans of a coalesced batch
+	DefaultCoalesceMaxSpans = 1000
+	// DefaultCoalesceMaxBytes is the default max size in bytes of a coalesced batch
+	DefaultCoalesceMaxBytes = 1024 * 1024
+
+	flushSize  = "size"
+	flushBytes = "bytes"
+	flushTime  = "time"
+	flushClose = "close"
+)
+
+// CoalesceOptions configures the merging of small batches into larger ones
+type CoalesceOptions struct {
+	// FlushInterval is the max time spans are held before being forwarded, coalescing is disabled when zero
+	FlushInterval time.Duration `yaml:"flushInterval"`
+	// MaxSpans is the number of spans triggering a flush, defaults to DefaultCoalesceMaxSpans
+	MaxSpans int `yaml:"maxSpans"`
+	// MaxBytes is the serialized size triggering a flush, defaults to DefaultCoalesceMaxBytes
+	MaxBytes int `yaml:"maxBytes"`
+}
+
+// Enabled returns true if the batches should be coalesced
+func (o CoalesceOptions) Enabled() bool {
+	return o.FlushInterval > 0
+}
+
+func (o CoalesceOptions) withDefaults() CoalesceOptions {
+	if o.MaxSpans <= 0 {
+		o.MaxSpans = DefaultCoalesceMaxSpans
+	}
+	if o.MaxBytes <= 0 {
+		o.MaxBytes = DefaultCoalesceMaxBytes
+	}
+	return o
+}
+
+type coalesceMetrics struct {
+	// Number of batches received from the clients
+	Received metrics.Counter `metric:"batches.received"`
+
+	// Number of coalesced batches forwarded to the wrapped reporter
+	Forwarded metrics.Counter `metric:"batches.forwarded"`
+
+	// Number of spans forwarded to the wrapped reporter
+	Spans metrics.Counter `metric:"spans"`
+
+	// Number of received batches per forwarded batch since the start, multiplied by 100
+	Ratio metrics.Gauge `metric:"ratio"`
+
+	// Number of flushes because the max number of spans was reached
+	FlushSize metrics.Counter `metric:"flushes" tags:"reason=size"`
+
+	// Number of flushes because the max size in bytes was reached
+	FlushBytes metrics.Counter `metric:"flushes" tags:"reason=bytes"`
+
+	// Number of flushes because the flush interval elapsed
+	FlushTime metrics.Counter `metric:"flushes" tags:"reason=time"`
+
+	// Number of flushes because the reporter was closed
+	FlushClose metrics.Counter `metric:"flushes" tags:"reason=close"`
+
+	// Number of coalesced batches the wrapped reporter failed to submit
+	Failures metrics.Counter `metric:"failures"`
+}
+
+// pendingBatch accumulates the spans of the received batches until it is flushed
+type pendingBatch struct {
+	process  *jaeger.Process
+	spans    []*jaeger.Span
+	zipkin   []*zipkincore.Span
+	bytes    int
+	received int
+}
+
+func (p *pendingBatch) size() int {
+	return len(p.spans) + len(p.zipkin)
+}
+
+// CoalescingReporter is a Reporter decorator merging the spans of the batches that share the same
+// Process into larger batches, so that clients flushing small batches do not result in as many
+// calls to the collectors. Zipkin spans carry their own endpoints and are all merged together.
+// A batch is forwarded once it holds MaxSpans spans or MaxBytes bytes, spans are held at most
+// FlushInterval.
+type CoalescingReporter struct {
+	sync.Mutex
+	wrapped Reporter
+	options CoalesceOptions
+	metrics coalesceMetrics
+	logger  *zap.Logger
+
+	pending   map[uint64]*pendingBatch
+	zipkin    *pendingBatch
+	received  int64
+	forwarded int64
+	closed    bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewCoalescingReporter creates a CoalescingReporter and starts its periodic flush
+func NewCoalescingReporter(wrapped Reporter, options CoalesceOptions, mFactory metrics.Factory, logger *zap.Logger) *CoalescingReporter {
+	r := &CoalescingReporter{
+		wrapped: wrapped,
+		options: options.withDefaults(),
+		logger:  logger,
+		pending: make(map[uint64]*pendingBatch),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	metrics.Init(&r.metrics, mFactory.Namespace("coalescing-reporter", nil), nil)
+	go r.flushPeriodically()
+	return r
+}
+
+// EmitZipkinBatch implements EmitZipkinBatch() of Reporter
+func (r *CoalescingReporter) EmitZipkinBatch(spans []*zipkincore.Span) error {
+	data, err := zipkin.SerializeThrift(spans)
+	if err != nil {
+		return err
+	}
+	r.Lock()
+	if r.closed {
+		r.Unlock()
+		return r.wrapped.EmitZipkinBatch(spans)
+	}
+	if r.zipkin == nil {
+		r.zipkin = &pendingBatch{}
+	}
+	r.zipkin.zipkin = append(r.zipkin.zipkin, spans...)
+	full := r.add(r.zipkin, len(data))
+	ready := r.takeIfReady(r.zipkin, full, func() { r.zipkin = nil })
+	r.Unlock()
+	return r.forward(ready)
+}
+
+// EmitBatch implements EmitBatch() of Reporter
+func (r *CoalescingReporter) EmitBatch(batch *jaeger.Batch) error {
+	if batch.Process == nil {
+		return r.wrapped.EmitBatch(batch)
+	}
+	key, size, err := hashAndSize(batch)
+	if err != nil {
+		return err
+	}
+	r.Lock()
+	if r.closed {
+		r.Unlock()
+		return r.wrapped.EmitBatch(batch)
+	}
+	p, ok := r.pending[key]
+	if !ok {
+		p = &pendingBatch{process: batch.Process}
+		r.pending[key] = p
+	}
+	p.spans = append(p.spans, batch.Spans...)
+	full := r.add(p, size)
+	ready := r.takeIfReady(p, full, func() { delete(r.pending, key) })
+	r.Unlock()
+	return r.forward(ready)
+}
+
+// Close stops the periodic flush and forwards the spans still held.
+// Batches emitted afterwards are passed to the wrapped reporter as is.
+func (r *CoalescingReporter) Close() error {
+	r.Lock()
+	if r.closed {
+		r.Unlock()
+		return nil
+	}
+	r.closed = true
+	r.Unlock()
+	close(r.stop)
+	<-r.done
+	r.flushAll(r.metrics.FlushClose)
+	return nil
+}
+
+// add records a received batch of the given size, whose spans were appended to p, and returns the reason why p must be flushed, if any.
+// Must be called with the lock held.
+func (r *CoalescingReporter) add(p *pendingBatch, bytes int) string {
+	r.received++
+	r.metrics.Received.Inc(1)
+	p.received++
+	p.bytes += bytes
+	if p.size() >= r.options.MaxSpans {
+		return flushSize
+	}
+	if p.bytes >= r.options.MaxBytes {
+		return flushBytes
+	}
+	return ""
+}
+
+// takeIfReady removes p from the pending batches if it must be flushed and returns it.
+// Must be called with the lock held.
+func (r *CoalescingReporter) takeIfReady(p *pendingBatch, reason string, remove func()) *pendingBatch {
+	switch reason {
+	case flushSize:
+		r.metrics.FlushSize.Inc(1)
+	case flushBytes:
+		r.metrics.FlushBytes.Inc(1)
+	default:
+		return nil
+	}
+	remove()
+	r.countForwarded()
+	return p
+}
+
+// countForwarded updates the forwarding metrics. Must be called with the lock held.
+func (r *CoalescingReporter) countForwarded() {
+	r.forwarded++
+	r.metrics.Forwarded.Inc(1)
+	r.metrics.Ratio.Update(r.received * 100 / r.forwarded)
+}
+
+func (r *CoalescingReporter) forward(p *pendingBatch) error {
+	if p == nil {
+		return nil
+	}
+	var err error
+	if p.process == nil {
+		r.metrics.Spans.Inc(int64(len(p.zipkin)))
+		err = r.wrapped.EmitZipkinBatch(p.zipkin)
+	} else {
+		r.metrics.Spans.Inc(int64(len(p.spans)))
+		err = r.wrapped.EmitBatch(&jaeger.Batch{Process: p.process, Spans: p.spans})
+	}
+	if err != nil {
+		r.metrics.Failures.Inc(1)
+	}
+	return err
+}
+
+func (r *CoalescingReporter) flushPeriodically() {
+	defer close(r.done)
+	ticker := time.NewTicker(r.options.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.flushAll(r.metrics.FlushTime)
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// flushAll forwards all the pending batches, errors are logged since there is no caller to return them to
+func (r *CoalescingReporter) flushAll(counter metrics.Counter) {
+	r.Lock()
+	batches := make([]*pendingBatch, 0, len(r.pending)+1)
+	for _, p := range r.pending {
+		batches = append(batches, p)
+	}
+	if r.zipkin != nil {
+		batches = append(batches, r.zipkin)
+	}
+	r.pending = make(map[uint64]*pendingBatch)
+	r.zipkin = nil
+	for range batches {
+		counter.Inc(1)
+		r.countForwarded()
+	}
+	r.Unlock()
+	for _, p := range batches {
+		if err := r.forward(p); err != nil {
+			r.logger.Error("Could not forward coalesced batch", zap.Int("spans", p.size()), zap.Error(err))
+		}
+	}
+}
+
+// hashAndSize serializes the batch once, returning the hash of its process, which identifies the batches
+// of a same process, and the serialized size of the process and spans.
+func hashAndSize(batch *jaeger.Batch) (uint64, int, error) {
+	buffer := thrift.NewTMemoryBuffer()
+	protocol := thrift.NewTBinaryProtocolTransport(buffer)
+	if err := batch.Process.Write(protocol); err != nil {
+		return 0, 0, err
+	}
+	h := fnv.New64a()
+	h.Write(buffer.Bytes())
+	for _, span := range batch.Spans {
+		if err := span.Write(protocol); err != nil {
+			return 0, 0, err
+		}
+	}
+	return h.Sum64(), buffer.Len(), nil
+}
@@ -0,0 +1,125 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reporter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	mTestutils "github.com/uber/jaeger-lib/metrics/testutils"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/thrift-gen/jaeger"
+)
+
+func TestCoalescingReporterMergesSameProcess(t *testing.T) {
+	recorder := &batchRecorder{}
+	mf := metrics.NewLocalFactory(0)
+	r := NewCoalescingReporter(recorder, CoalesceOptions{FlushInterval: time.Hour, MaxSpans: 3}, mf, zap.NewNop())
+
+	other := &jaeger.Batch{
+		Process: &jaeger.Process{ServiceName: "other"},
+		Spans:   []*jaeger.Span{{OperationName: "x"}},
+	}
+	require.NoError(t, r.EmitBatch(testBatch("a")))
+	require.NoError(t, r.EmitBatch(other))
+	require.NoError(t, r.EmitBatch(testBatch("b")))
+	assert.Empty(t, recorder.batches)
+	require.NoError(t, r.EmitBatch(testBatch("c")))
+	require.Len(t, recorder.batches, 1)
+	assert.Equal(t, "svc", recorder.batches[0].Process.ServiceName)
+	assert.Equal(t, []*jaeger.Span{{OperationName: "a"}, {OperationName: "b"}, {OperationName: "c"}}, recorder.batches[0].Spans)
+
+	require.NoError(t, r.Close())
+	require.Len(t, recorder.batches, 2)
+	assert.Equal(t, other, recorder.batches[1])
+
+	mTestutils.AssertCounterMetrics(t, mf, []mTestutils.ExpectedMetric{
+		{Name: "coalescing-reporter.batches.received", Value: 4},
+		{Name: "coalescing-reporter.batches.forwarded", Value: 2},
+		{Name: "coalescing-reporter.spans", Value: 4},
+		{Name: "coalescing-reporter.flushes", Tags: map[string]string{"reason": "size"}, Value: 1},
+		{Name: "coalescing-reporter.flushes", Tags: map[string]string{"reason": "close"}, Value: 1},
+	}...)
+	_, gauges := mf.Snapshot()
+	assert.EqualValues(t, 200, gauges["coalescing-reporter.ratio"])
+
+	// once closed, batches are passed through
+	require.NoError(t, r.EmitBatch(testBatch("d")))
+	assert.Len(t, recorder.batches, 3)
+}
+
+func TestCoalescingReporterFlushOnBytes(t *testing.T) {
+	recorder := &batchRecorder{}
+	mf := metrics.NewLocalFactory(0)
+	r := NewCoalescingReporter(recorder, CoalesceOptions{FlushInterval: time.Hour, MaxBytes: 1}, mf, zap.NewNop())
+	defer r.Close()
+
+	require.NoError(t, r.EmitBatch(testBatch("a")))
+	require.NoError(t, r.EmitZipkinBatch(testZipkinSpans("z")))
+	assert.Len(t, recorder.batches, 1)
+	assert.Len(t, recorder.zipkinSpans, 1)
+	mTestutils.AssertCounterMetrics(t, mf, mTestutils.ExpectedMetric{
+		Name: "coalescing-reporter.flushes", Tags: map[string]string{"reason": "bytes"}, Value: 2,
+	})
+}
+
+func TestCoalescingReporterFlushOnTime(t *testing.T) {
+	wrapped := &flakyReporter{}
+	mf := metrics.NewLocalFactory(0)
+	r := NewCoalescingReporter(wrapped, CoalesceOptions{FlushInterval: time.Millisecond}, mf, zap.NewNop())
+	defer r.Close()
+
+	require.NoError(t, r.EmitBatch(testBatch("a")))
+	require.NoError(t, r.EmitZipkinBatch(testZipkinSpans("z1")))
+	require.NoError(t, r.EmitZipkinBatch(testZipkinSpans("z2")))
+	waitForOperations(t, wrapped, []string{"a", "z1", "z2"})
+}
+
+func TestCoalescingReporterForwardError(t *testing.T) {
+	wrapped := &flakyReporter{down: true}
+	mf := metrics.NewLocalFactory(0)
+	r := NewCoalescingReporter(wrapped, CoalesceOptions{FlushInterval: time.Hour, MaxSpans: 1}, mf, zap.NewNop())
+	defer r.Close()
+
+	assert.EqualError(t, r.EmitBatch(testBatch("a")), "collector down")
+	mTestutils.AssertCounterMetrics(t, mf, mTestutils.ExpectedMetric{Name: "coalescing-reporter.failures", Value: 1})
+}
+
+func TestHashAndSize(t *testing.T) {
+	keyA, sizeA, err := hashAndSize(testBatch("a"))
+	require.NoError(t, err)
+	keyB, sizeB, err := hashAndSize(&jaeger.Batch{
+		Process: &jaeger.Process{ServiceName: "svc"},
+		Spans:   []*jaeger.Span{{OperationName: "a"}, {OperationName: "b"}},
+	})
+	require.NoError(t, err)
+	keyOther, _, err := hashAndSize(&jaeger.Batch{Process: &jaeger.Process{ServiceName: "other"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, keyA, keyB)
+	assert.NotEqual(t, keyA, keyOther)
+	assert.True(t, sizeB > sizeA)
+}
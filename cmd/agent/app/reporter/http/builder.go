@@ -0,0 +1,173 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package http
+
+import (
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultTimeout       = 5 * time.Second
+	defaultMaxRetries    = 3
+	defaultRetryBackoff  = 100 * time.Millisecond
+	defaultMaxIdleConns  = 10
+	defaultIdleConnTTL   = 90 * time.Second
+	defaultDialKeepAlive = 30 * time.Second
+)
+
+// Builder Struct to hold configurations
+type Builder struct {
+	// CollectorEndpoint is the base URL of the collector HTTP API, e.g. https://jaeger-collector:14268.
+	// Jaeger batches are sent to /api/traces and Zipkin spans to /api/v1/spans.
+	CollectorEndpoint string `yaml:"collectorEndpoint"`
+
+	// AuthToken, if set, is sent as a bearer token in the Authorization header
+	AuthToken string `yaml:"authToken"`
+
+	// Headers are added to every request, e.g. for the authentication expected by a load balancer
+	Headers map[string]string `yaml:"headers"`
+
+	// DisableCompression disables the gzip compression of the request bodies
+	DisableCompression bool `yaml:"disableCompression"`
+
+	// Timeout is the timeout of a single request, defaults to 5s
+	Timeout time.Duration `yaml:"timeout"`
+
+	// MaxRetries is the number of times a failed batch is sent again, defaults to 3.
+	// Negative values disable the retries.
+	MaxRetries int `yaml:"maxRetries"`
+
+	// RetryBackoff is the delay before the first retry, it is doubled on every following retry.
+	// Defaults to 100ms.
+	RetryBackoff time.Duration `yaml:"retryBackoff"`
+
+	client *http.Client
+}
+
+// NewBuilder creates a new reporter builder.
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// WithCollectorEndpoint sets the base URL of the collector HTTP API
+func (b *Builder) WithCollectorEndpoint(endpoint string) *Builder {
+	b.CollectorEndpoint = endpoint
+	return b
+}
+
+// WithClient sets the HTTP client used to reach the collector, the Timeout is then ignored
+func (b *Builder) WithClient(c *http.Client) *Builder {
+	b.client = c
+	return b
+}
+
+// CreateReporter creates the HTTP-based Reporter
+func (b *Builder) CreateReporter(mFactory metrics.Factory, logger *zap.Logger) (*Reporter, error) {
+	if b.CollectorEndpoint == "" {
+		return nil, errors.New("no collector endpoint provided")
+	}
+	endpoint, err := url.Parse(b.CollectorEndpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot parse collector endpoint")
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, errors.Errorf("collector endpoint must be an http or https URL: %s", b.CollectorEndpoint)
+	}
+	base := strings.TrimSuffix(endpoint.String(), "/")
+
+	headers := http.Header{}
+	for name, value := range b.Headers {
+		headers.Set(name, value)
+	}
+	if b.AuthToken != "" {
+		headers.Set("Authorization", "Bearer "+b.AuthToken)
+	}
+
+	client := b.client
+	if client == nil {
+		client = newClient(defaultDuration(b.Timeout, defaultTimeout))
+	}
+
+	maxRetries := b.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = defaultMaxRetries
+	} else if maxRetries < 0 {
+		maxRetries = 0
+	}
+
+	batchesMetrics := map[string]batchMetrics{}
+	httpReporterNS := mFactory.Namespace("http-reporter", nil)
+	for _, s := range []string{zipkinBatches, jaegerBatches} {
+		nsByType := httpReporterNS.Namespace(s, nil)
+		bm := batchMetrics{}
+		metrics.Init(&bm, nsByType, nil)
+		batchesMetrics[s] = bm
+	}
+
+	return &Reporter{
+		client:         client,
+		jaegerURL:      base + jaegerPath,
+		zipkinURL:      base + zipkinPath,
+		headers:        headers,
+		gzip:           !b.DisableCompression,
+		maxRetries:     maxRetries,
+		retryBackoff:   defaultDuration(b.RetryBackoff, defaultRetryBackoff),
+		sleep:          time.Sleep,
+		random:         rand.Int63n,
+		batchesMetrics: batchesMetrics,
+		logger:         logger,
+	}, nil
+}
+
+// newClient creates a client keeping the connections to the collector alive between the batches
+func newClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   timeout,
+				KeepAlive: defaultDialKeepAlive,
+			}).DialContext,
+			MaxIdleConns:        defaultMaxIdleConns,
+			MaxIdleConnsPerHost: defaultMaxIdleConns,
+			IdleConnTimeout:     defaultIdleConnTTL,
+			TLSHandshakeTimeout: timeout,
+		},
+	}
+}
+
+func defaultDuration(value time.Duration, defaultVal time.Duration) time.Duration {
+	if value == 0 {
+		value = defaultVal
+	}
+	return value
+}
@@ -0,0 +1,79 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package http
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+)
+
+func TestBuilderDefaults(t *testing.T) {
+	r, err := NewBuilder().WithCollectorEndpoint("https://jaeger-collector:14268/").CreateReporter(metrics.NullFactory, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, "https://jaeger-collector:14268/api/traces?format=jaeger.thrift", r.jaegerURL)
+	assert.Equal(t, "https://jaeger-collector:14268/api/v1/spans", r.zipkinURL)
+	assert.True(t, r.gzip)
+	assert.Equal(t, defaultMaxRetries, r.maxRetries)
+	assert.Equal(t, defaultRetryBackoff, r.retryBackoff)
+	assert.Equal(t, defaultTimeout, r.client.Timeout)
+	transport := r.client.Transport.(*http.Transport)
+	assert.Equal(t, defaultMaxIdleConns, transport.MaxIdleConnsPerHost)
+}
+
+func TestBuilderOptions(t *testing.T) {
+	client := &http.Client{}
+	b := &Builder{
+		CollectorEndpoint:  "http://localhost:14268",
+		DisableCompression: true,
+		MaxRetries:         -1,
+		RetryBackoff:       time.Second,
+	}
+	r, err := b.WithClient(client).CreateReporter(metrics.NullFactory, zap.NewNop())
+	require.NoError(t, err)
+	assert.False(t, r.gzip)
+	assert.Equal(t, 0, r.maxRetries)
+	assert.Equal(t, time.Second, r.retryBackoff)
+	assert.Equal(t, client, r.client)
+}
+
+func TestBuilderErrors(t *testing.T) {
+	testCases := []struct {
+		endpoint string
+		err      string
+	}{
+		{endpoint: "", err: "no collector endpoint provided"},
+		{endpoint: "localhost:14268", err: "collector endpoint must be an http or https URL: localhost:14268"},
+		{endpoint: "http://[::1", err: "cannot parse collector endpoint"},
+	}
+	for _, tc := range testCases {
+		_, err := NewBuilder().WithCollectorEndpoint(tc.endpoint).CreateReporter(metrics.NullFactory, zap.NewNop())
+		require.Error(t, err)
+		assert.True(t, strings.HasPrefix(err.Error(), tc.err), err.Error())
+	}
+}
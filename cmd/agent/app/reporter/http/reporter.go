@@ -0,0 +1,217 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model/converter/thrift/zipkin"
+	"github.com/uber/jaeger/thrift-gen/jaeger"
+	"github.com/uber/jaeger/thrift-gen/zipkincore"
+)
+
+const (
+	jaegerBatches = "jaeger"
+	zipkinBatches = "zipkin"
+
+	jaegerPath = "/api/traces?format=jaeger.thrift"
+	zipkinPath = "/api/v1/spans"
+
+	thriftContentType = "application/x-thrift"
+
+	// maxErrorBodySize is the max number of bytes of the collector response included in errors
+	maxErrorBodySize = 512
+)
+
+type batchMetrics struct {
+	// Number of successful batch submissions to collector
+	BatchesSubmitted metrics.Counter `metric:"batches.submitted"`
+
+	// Number of failed batch submissions to collector
+	BatchesFailures metrics.Counter `metric:"batches.failures"`
+
+	// Number of spans in a batch submitted to collector
+	BatchSize metrics.Gauge `metric:"batch_size"`
+
+	// Number of successful span submissions to collector
+	SpansSubmitted metrics.Counter `metric:"spans.submitted"`
+
+	// Number of failed span submissions to collector
+	SpansFailures metrics.Counter `metric:"spans.failures"`
+
+	// Number of submissions retried after a failure
+	Retries metrics.Counter `metric:"retries"`
+}
+
+// statusError is returned when the collector does not accept a batch
+type statusError struct {
+	code int
+	body string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("collector responded with status %d: %s", e.code, e.body)
+}
+
+// retryable returns true for the statuses that may succeed when the request is sent again
+func (e *statusError) retryable() bool {
+	return e.code == http.StatusTooManyRequests || e.code >= http.StatusInternalServerError
+}
+
+// Permanent returns true if the collector will never accept the batch, so that it is not buffered
+func (e *statusError) Permanent() bool {
+	return !e.retryable()
+}
+
+// Reporter forwards received spans to central collector tier over HTTP(S).
+type Reporter struct {
+	client         *http.Client
+	jaegerURL      string
+	zipkinURL      string
+	headers        http.Header
+	gzip           bool
+	maxRetries     int
+	retryBackoff   time.Duration
+	sleep          func(time.Duration)
+	random         func(n int64) int64 // random returns a number in [0, n), it is called concurrently
+	batchesMetrics map[string]batchMetrics
+	logger         *zap.Logger
+}
+
+// EmitZipkinBatch implements EmitZipkinBatch() of Reporter
+func (r *Reporter) EmitZipkinBatch(spans []*zipkincore.Span) error {
+	return r.submitAndReport(
+		r.zipkinURL,
+		func() ([]byte, error) { return zipkin.SerializeThrift(spans) },
+		"Could not submit zipkin batch",
+		int64(len(spans)),
+		r.batchesMetrics[zipkinBatches],
+	)
+}
+
+// EmitBatch implements EmitBatch() of Reporter
+func (r *Reporter) EmitBatch(batch *jaeger.Batch) error {
+	return r.submitAndReport(
+		r.jaegerURL,
+		func() ([]byte, error) { return thrift.NewTSerializer().Write(batch) },
+		"Could not submit jaeger batch",
+		int64(len(batch.Spans)),
+		r.batchesMetrics[jaegerBatches],
+	)
+}
+
+func (r *Reporter) submitAndReport(url string, serialize func() ([]byte, error), errMsg string, size int64, batchMetrics batchMetrics) error {
+	err := r.submit(url, serialize, batchMetrics)
+	if err != nil {
+		batchMetrics.BatchesFailures.Inc(1)
+		batchMetrics.SpansFailures.Inc(size)
+		r.logger.Error(errMsg, zap.Error(err))
+		return err
+	}
+	batchMetrics.BatchSize.Update(size)
+	batchMetrics.BatchesSubmitted.Inc(1)
+	batchMetrics.SpansSubmitted.Inc(size)
+	return nil
+}
+
+func (r *Reporter) submit(url string, serialize func() ([]byte, error), batchMetrics batchMetrics) error {
+	body, err := serialize()
+	if err != nil {
+		return err
+	}
+	if r.gzip {
+		if body, err = compress(body); err != nil {
+			return err
+		}
+	}
+	backoff := r.retryBackoff
+	for attempt := 0; ; attempt++ {
+		err = r.post(url, body)
+		if err == nil || attempt >= r.maxRetries {
+			return err
+		}
+		if statusErr, ok := err.(*statusError); ok && !statusErr.retryable() {
+			return err
+		}
+		batchMetrics.Retries.Inc(1)
+		delay := r.jitter(backoff)
+		r.logger.Debug("Retrying batch submission", zap.String("url", url), zap.Duration("backoff", delay), zap.Error(err))
+		r.sleep(delay)
+		backoff *= 2
+	}
+}
+
+// jitter returns a random delay between half of the backoff and the backoff, like BufferedReporter.backoff,
+// so that agents failing together do not retry in lockstep
+func (r *Reporter) jitter(backoff time.Duration) time.Duration {
+	half := int64(backoff / 2)
+	return time.Duration(half + r.random(half+1))
+}
+
+func (r *Reporter) post(url string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for name, values := range r.headers {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", thriftContentType)
+	if r.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+		// the body must be fully read for the connection to be kept alive
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	io.Copy(ioutil.Discard, resp.Body)
+	return &statusError{code: resp.StatusCode, body: string(bytes.TrimSpace(msg))}
+}
+
+func compress(b []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+	gz := gzip.NewWriter(&buffer)
+	if _, err := gz.Write(b); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,307 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package http
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	mTestutils "github.com/uber/jaeger-lib/metrics/testutils"
+	tchanThrift "github.com/uber/tchannel-go/thrift"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/pkg/httpapi"
+	"github.com/uber/jaeger/pkg/httpapi/zipkin"
+	"github.com/uber/jaeger/thrift-gen/jaeger"
+	"github.com/uber/jaeger/thrift-gen/zipkincore"
+)
+
+// mockCollector records the requests received by the collector HTTP API
+type mockCollector struct {
+	sync.Mutex
+	server   *httptest.Server
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newMockCollector(statuses ...int) *mockCollector {
+	c := &mockCollector{statuses: statuses}
+	c.server = httptest.NewServer(http.HandlerFunc(c.handle))
+	return c
+}
+
+func (c *mockCollector) handle(w http.ResponseWriter, r *http.Request) {
+	c.Lock()
+	defer c.Unlock()
+	body := r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body = gz
+	}
+	b, _ := ioutil.ReadAll(body)
+	c.requests = append(c.requests, r)
+	c.bodies = append(c.bodies, b)
+	status := http.StatusAccepted
+	if len(c.statuses) > 0 {
+		status, c.statuses = c.statuses[0], c.statuses[1:]
+	}
+	if status != http.StatusAccepted {
+		http.Error(w, "collector error", status)
+		return
+	}
+	w.WriteHeader(status)
+}
+
+func (c *mockCollector) received() int {
+	c.Lock()
+	defer c.Unlock()
+	return len(c.requests)
+}
+
+func initRequirements(t *testing.T, b *Builder, statuses ...int) (*metrics.LocalFactory, *mockCollector, *Reporter) {
+	collector := newMockCollector(statuses...)
+	metricsFactory := metrics.NewLocalFactory(0)
+	b.CollectorEndpoint = collector.server.URL
+	reporter, err := b.CreateReporter(metricsFactory, zap.NewNop())
+	require.NoError(t, err)
+	reporter.sleep = func(time.Duration) {}
+	return metricsFactory, collector, reporter
+}
+
+func TestJaegerHTTPReporterSuccess(t *testing.T) {
+	metricsFactory, collector, reporter := initRequirements(t, &Builder{AuthToken: "secret", Headers: map[string]string{"X-Tenant": "acme"}})
+	defer collector.server.Close()
+
+	require.NoError(t, submitTestJaegerBatch(reporter))
+
+	require.Equal(t, 1, collector.received())
+	req := collector.requests[0]
+	assert.Equal(t, "/api/traces", req.URL.Path)
+	assert.Equal(t, "jaeger.thrift", req.URL.Query().Get("format"))
+	assert.Equal(t, "application/x-thrift", req.Header.Get("Content-Type"))
+	assert.Equal(t, "gzip", req.Header.Get("Content-Encoding"))
+	assert.Equal(t, "Bearer secret", req.Header.Get("Authorization"))
+	assert.Equal(t, "acme", req.Header.Get("X-Tenant"))
+
+	batch := jaeger.NewBatch()
+	require.NoError(t, thrift.NewTDeserializer().Read(batch, collector.bodies[0]))
+	assert.Equal(t, "span1", batch.Spans[0].OperationName)
+
+	checkCounters(t, metricsFactory, 1, 1, 0, 0, 0, "jaeger")
+}
+
+func TestZipkinHTTPReporterSuccess(t *testing.T) {
+	metricsFactory, collector, reporter := initRequirements(t, &Builder{DisableCompression: true})
+	defer collector.server.Close()
+
+	require.NoError(t, submitTestZipkinBatch(reporter))
+
+	require.Equal(t, 1, collector.received())
+	req := collector.requests[0]
+	assert.Equal(t, "/api/v1/spans", req.URL.Path)
+	assert.Equal(t, "application/x-thrift", req.Header.Get("Content-Type"))
+	assert.Empty(t, req.Header.Get("Content-Encoding"))
+	assert.Empty(t, req.Header.Get("Authorization"))
+
+	buffer := thrift.NewTMemoryBuffer()
+	buffer.Write(collector.bodies[0])
+	protocol := thrift.NewTBinaryProtocolTransport(buffer)
+	_, size, err := protocol.ReadListBegin()
+	require.NoError(t, err)
+	require.Equal(t, 1, size)
+	span := zipkincore.NewSpan()
+	require.NoError(t, span.Read(protocol))
+	assert.Equal(t, "span1", span.Name)
+
+	checkCounters(t, metricsFactory, 1, 1, 0, 0, 0, "zipkin")
+}
+
+func TestHTTPReporterRetries(t *testing.T) {
+	metricsFactory, collector, reporter := initRequirements(t, &Builder{},
+		http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	defer collector.server.Close()
+
+	require.NoError(t, submitTestJaegerBatch(reporter))
+
+	assert.Equal(t, 3, collector.received())
+	checkCounters(t, metricsFactory, 1, 1, 0, 0, 2, "jaeger")
+}
+
+func TestHTTPReporterRetriesExhausted(t *testing.T) {
+	metricsFactory, collector, reporter := initRequirements(t, &Builder{MaxRetries: 1},
+		http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+	defer collector.server.Close()
+
+	err := submitTestZipkinBatch(reporter)
+	assert.EqualError(t, err, "collector responded with status 502: collector error")
+	assert.False(t, err.(*statusError).Permanent())
+
+	assert.Equal(t, 2, collector.received())
+	checkCounters(t, metricsFactory, 0, 0, 1, 1, 1, "zipkin")
+}
+
+func TestHTTPReporterNoRetryOnBadRequest(t *testing.T) {
+	metricsFactory, collector, reporter := initRequirements(t, &Builder{}, http.StatusBadRequest)
+	defer collector.server.Close()
+
+	err := submitTestJaegerBatch(reporter)
+	require.Error(t, err)
+	// the buffered reporter drops the batch rather than replaying it
+	assert.True(t, err.(*statusError).Permanent())
+
+	assert.Equal(t, 1, collector.received())
+	checkCounters(t, metricsFactory, 0, 0, 1, 1, 0, "jaeger")
+}
+
+func TestHTTPReporterBackoff(t *testing.T) {
+	_, collector, reporter := initRequirements(t, &Builder{MaxRetries: 3, RetryBackoff: time.Second},
+		http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+	defer collector.server.Close()
+	var delays []time.Duration
+	reporter.sleep = func(d time.Duration) {
+		delays = append(delays, d)
+	}
+	var jitters []int64
+	reporter.random = func(n int64) int64 {
+		jitters = append(jitters, n)
+		return n - 1
+	}
+
+	require.NoError(t, submitTestJaegerBatch(reporter))
+
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}, delays)
+	// the jitter is up to half of the backoff
+	assert.Equal(t, []int64{int64(time.Second/2) + 1, int64(time.Second) + 1, int64(2*time.Second) + 1}, jitters)
+}
+
+func TestHTTPReporterBackoffJitter(t *testing.T) {
+	reporter := &Reporter{random: func(n int64) int64 { return 0 }}
+	assert.Equal(t, time.Second, reporter.jitter(2*time.Second))
+	reporter.random = rand.Int63n
+	for i := 0; i < 100; i++ {
+		delay := reporter.jitter(2 * time.Second)
+		assert.True(t, delay >= time.Second && delay <= 2*time.Second, delay.String())
+	}
+}
+
+func TestHTTPReporterConnectionError(t *testing.T) {
+	metricsFactory, collector, reporter := initRequirements(t, &Builder{MaxRetries: -1})
+	collector.server.Close()
+
+	require.Error(t, submitTestJaegerBatch(reporter))
+
+	checkCounters(t, metricsFactory, 0, 0, 1, 1, 0, "jaeger")
+}
+
+// collectorHandlers records the batches submitted to the collector HTTP API handlers
+type collectorHandlers struct {
+	sync.Mutex
+	jaegerBatches []*jaeger.Batch
+	zipkinSpans   []*zipkincore.Span
+}
+
+func (h *collectorHandlers) SubmitBatches(ctx tchanThrift.Context, batches []*jaeger.Batch) ([]*jaeger.BatchSubmitResponse, error) {
+	h.Lock()
+	defer h.Unlock()
+	h.jaegerBatches = append(h.jaegerBatches, batches...)
+	return nil, nil
+}
+
+func (h *collectorHandlers) SubmitZipkinBatch(ctx tchanThrift.Context, spans []*zipkincore.Span) ([]*zipkincore.Response, error) {
+	h.Lock()
+	defer h.Unlock()
+	h.zipkinSpans = append(h.zipkinSpans, spans...)
+	return nil, nil
+}
+
+func TestHTTPReporterWithCollectorHandlers(t *testing.T) {
+	// the routes of the collector main HTTP port
+	handlers := &collectorHandlers{}
+	r := mux.NewRouter()
+	httpapi.NewAPIHandler(handlers).RegisterRoutes(r)
+	zipkin.NewAPIHandler(handlers).RegisterRoutes(r)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	for _, disableCompression := range []bool{false, true} {
+		metricsFactory := metrics.NewLocalFactory(0)
+		reporter, err := (&Builder{CollectorEndpoint: server.URL, DisableCompression: disableCompression}).CreateReporter(metricsFactory, zap.NewNop())
+		require.NoError(t, err)
+
+		require.NoError(t, submitTestJaegerBatch(reporter))
+		require.NoError(t, submitTestZipkinBatch(reporter))
+		checkCounters(t, metricsFactory, 1, 1, 0, 0, 0, "jaeger")
+		checkCounters(t, metricsFactory, 1, 1, 0, 0, 0, "zipkin")
+	}
+	require.Len(t, handlers.jaegerBatches, 2)
+	assert.Equal(t, "span1", handlers.jaegerBatches[0].Spans[0].OperationName)
+	require.Len(t, handlers.zipkinSpans, 2)
+	assert.Equal(t, "span1", handlers.zipkinSpans[0].Name)
+}
+
+func submitTestZipkinBatch(reporter *Reporter) error {
+	span := zipkincore.NewSpan()
+	span.Name = "span1"
+
+	return reporter.EmitZipkinBatch([]*zipkincore.Span{span})
+}
+
+func submitTestJaegerBatch(reporter *Reporter) error {
+	batch := jaeger.NewBatch()
+	batch.Process = jaeger.NewProcess()
+	batch.Spans = []*jaeger.Span{{OperationName: "span1"}}
+
+	return reporter.EmitBatch(batch)
+}
+
+func checkCounters(t *testing.T, mf *metrics.LocalFactory, batchesSubmitted, spansSubmitted, batchesFailures, spansFailures, retries int, prefix string) {
+	batchesCounter := fmt.Sprintf("http-reporter.%s.batches.submitted", prefix)
+	batchesFailureCounter := fmt.Sprintf("http-reporter.%s.batches.failures", prefix)
+	spansCounter := fmt.Sprintf("http-reporter.%s.spans.submitted", prefix)
+	spansFailureCounter := fmt.Sprintf("http-reporter.%s.spans.failures", prefix)
+	retriesCounter := fmt.Sprintf("http-reporter.%s.retries", prefix)
+
+	mTestutils.AssertCounterMetrics(t, mf, []mTestutils.ExpectedMetric{
+		{Name: batchesCounter, Value: batchesSubmitted},
+		{Name: spansCounter, Value: spansSubmitted},
+		{Name: batchesFailureCounter, Value: batchesFailures},
+		{Name: spansFailureCounter, Value: spansFailures},
+		{Name: retriesCounter, Value: retries},
+	}...)
+}
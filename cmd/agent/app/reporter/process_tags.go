@@ -0,0 +1,233 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reporter
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/uber/jaeger/model/converter/thrift/zipkin"
+	"github.com/uber/jaeger/thrift-gen/jaeger"
+	"github.com/uber/jaeger/thrift-gen/zipkincore"
+)
+
+// TagPrecedence decides which value is kept when both the client and the agent define a process tag
+type TagPrecedence string
+
+const (
+	// ClientTagsWin keeps the values set by the client libraries
+	ClientTagsWin TagPrecedence = "client"
+	// AgentTagsWin replaces the values set by the client libraries with the ones of the agent
+	AgentTagsWin TagPrecedence = "agent"
+
+	hostnameValue = "@hostname"
+	ipValue       = "@ip"
+)
+
+// ParseTagPrecedence converts a string to a TagPrecedence, the empty string defaults to ClientTagsWin
+func ParseTagPrecedence(s string) (TagPrecedence, error) {
+	switch TagPrecedence(s) {
+	case "", ClientTagsWin:
+		return ClientTagsWin, nil
+	case AgentTagsWin:
+		return AgentTagsWin, nil
+	default:
+		return "", fmt.Errorf("unknown tag precedence %q, expected %q or %q", s, ClientTagsWin, AgentTagsWin)
+	}
+}
+
+// ResolveProcessTags returns the tags with their values resolved:
+//   - ${VAR} and ${VAR:default} are replaced with the value of the environment variable VAR
+//   - @hostname is replaced with the name of the host
+//   - @ip is replaced with the first non-loopback IPv4 address of the host
+//
+// Other values are kept as is. Tags resolved to an empty value are left out.
+func ResolveProcessTags(tags map[string]string) (map[string]string, error) {
+	resolved := make(map[string]string, len(tags))
+	for key, value := range tags {
+		var err error
+		switch {
+		case value == hostnameValue:
+			value, err = os.Hostname()
+		case value == ipValue:
+			value, err = hostIP()
+		case strings.HasPrefix(value, "${") && strings.HasSuffix(value, "}"):
+			value = envValue(value[2 : len(value)-1])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot resolve value of process tag %s: %v", key, err)
+		}
+		if value != "" {
+			resolved[key] = value
+		}
+	}
+	return resolved, nil
+}
+
+func envValue(spec string) string {
+	parts := strings.SplitN(spec, ":", 2)
+	if value := os.Getenv(parts[0]); value != "" {
+		return value
+	}
+	if len(parts) == 2 {
+		return parts[1]
+	}
+	return ""
+}
+
+func hostIP() (string, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return "", err
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+			return ipNet.IP.String(), nil
+		}
+	}
+	return "", fmt.Errorf("no non-loopback IPv4 address found")
+}
+
+// processTag is a tag added by the agent
+type processTag struct {
+	key   string
+	value string
+}
+
+// ProcessTagsReporter is a Reporter decorator that adds the tags of the agent to the process of the
+// Jaeger batches and, as binary annotations of the local endpoint, to the Zipkin spans.
+// The batches are copied before being modified, the received ones are left intact.
+type ProcessTagsReporter struct {
+	wrapped    Reporter
+	tags       []processTag
+	precedence TagPrecedence
+}
+
+// NewProcessTagsReporter creates a ProcessTagsReporter adding tags, whose values should already be resolved
+func NewProcessTagsReporter(wrapped Reporter, tags map[string]string, precedence TagPrecedence) *ProcessTagsReporter {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	r := &ProcessTagsReporter{wrapped: wrapped, precedence: precedence}
+	for _, key := range keys {
+		r.tags = append(r.tags, processTag{key: key, value: tags[key]})
+	}
+	return r
+}
+
+// EmitZipkinBatch implements EmitZipkinBatch() of Reporter
+func (r *ProcessTagsReporter) EmitZipkinBatch(spans []*zipkincore.Span) error {
+	enriched := make([]*zipkincore.Span, len(spans))
+	for i, span := range spans {
+		enriched[i] = r.enrichZipkinSpan(span)
+	}
+	return r.wrapped.EmitZipkinBatch(enriched)
+}
+
+// EmitBatch implements EmitBatch() of Reporter
+func (r *ProcessTagsReporter) EmitBatch(batch *jaeger.Batch) error {
+	return r.wrapped.EmitBatch(r.enrich(batch))
+}
+
+func (r *ProcessTagsReporter) enrich(batch *jaeger.Batch) *jaeger.Batch {
+	if batch.Process == nil {
+		return batch
+	}
+	process := *batch.Process
+	process.Tags = make([]*jaeger.Tag, 0, len(batch.Process.Tags)+len(r.tags))
+	for _, tag := range batch.Process.Tags {
+		if r.precedence == AgentTagsWin && r.hasTag(tag.Key) {
+			continue
+		}
+		process.Tags = append(process.Tags, tag)
+	}
+	for _, tag := range r.tags {
+		if r.precedence == ClientTagsWin && hasJaegerTag(batch.Process.Tags, tag.key) {
+			continue
+		}
+		value := tag.value
+		process.Tags = append(process.Tags, &jaeger.Tag{Key: tag.key, VType: jaeger.TagType_STRING, VStr: &value})
+	}
+	enriched := *batch
+	enriched.Process = &process
+	return &enriched
+}
+
+// enrichZipkinSpan adds the process tags as binary annotations prefixed with zipkin.ProcessTagPrefix,
+// which the collector maps back onto the Process instead of the span.
+func (r *ProcessTagsReporter) enrichZipkinSpan(span *zipkincore.Span) *zipkincore.Span {
+	endpoint := zipkinEndpoint(span)
+	enriched := *span
+	enriched.BinaryAnnotations = make([]*zipkincore.BinaryAnnotation, 0, len(span.BinaryAnnotations)+len(r.tags))
+	for _, annotation := range span.BinaryAnnotations {
+		if r.precedence == AgentTagsWin && r.hasTag(strings.TrimPrefix(annotation.Key, zipkin.ProcessTagPrefix)) {
+			continue
+		}
+		enriched.BinaryAnnotations = append(enriched.BinaryAnnotations, annotation)
+	}
+	for _, tag := range r.tags {
+		if r.precedence == ClientTagsWin &&
+			(hasBinaryAnnotation(span.BinaryAnnotations, tag.key) ||
+				hasBinaryAnnotation(span.BinaryAnnotations, zipkin.ProcessTagPrefix+tag.key)) {
+			continue
+		}
+		enriched.BinaryAnnotations = append(enriched.BinaryAnnotations, &zipkincore.BinaryAnnotation{
+			Key:            zipkin.ProcessTagPrefix + tag.key,
+			Value:          []byte(tag.value),
+			AnnotationType: zipkincore.AnnotationType_STRING,
+			Host:           endpoint,
+		})
+	}
+	return &enriched
+}
+
+func (r *ProcessTagsReporter) hasTag(key string) bool {
+	for _, tag := range r.tags {
+		if tag.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func hasJaegerTag(tags []*jaeger.Tag, key string) bool {
+	for _, tag := range tags {
+		if tag.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func hasBinaryAnnotation(annotations []*zipkincore.BinaryAnnotation, key string) bool {
+	for _, annotation := range annotations {
+		if annotation.Key == key {
+			return true
+		}
+	}
+	return false
+}
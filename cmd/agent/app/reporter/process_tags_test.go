@@ -0,0 +1,138 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reporter
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/model/converter/thrift/zipkin"
+	"github.com/uber/jaeger/thrift-gen/jaeger"
+	"github.com/uber/jaeger/thrift-gen/zipkincore"
+)
+
+var testProcessTags = map[string]string{"dc": "us-east", "env": "prod"}
+
+func TestProcessTagsReporterJaeger(t *testing.T) {
+	testCases := []struct {
+		precedence TagPrecedence
+		tags       []*jaeger.Tag
+		expected   []*jaeger.Tag
+	}{
+		{
+			precedence: ClientTagsWin,
+			tags:       []*jaeger.Tag{stringTag("k", "v")},
+			expected:   []*jaeger.Tag{stringTag("k", "v"), stringTag("dc", "us-east"), stringTag("env", "prod")},
+		},
+		{
+			precedence: ClientTagsWin,
+			tags:       []*jaeger.Tag{stringTag("env", "dev")},
+			expected:   []*jaeger.Tag{stringTag("env", "dev"), stringTag("dc", "us-east")},
+		},
+		{
+			precedence: AgentTagsWin,
+			tags:       []*jaeger.Tag{stringTag("env", "dev"), stringTag("k", "v")},
+			expected:   []*jaeger.Tag{stringTag("k", "v"), stringTag("dc", "us-east"), stringTag("env", "prod")},
+		},
+	}
+	for _, testCase := range testCases {
+		recorder := &batchRecorder{}
+		r := NewProcessTagsReporter(recorder, testProcessTags, testCase.precedence)
+		batch := &jaeger.Batch{Process: &jaeger.Process{ServiceName: "svc", Tags: testCase.tags}}
+		require.NoError(t, r.EmitBatch(batch))
+		require.Len(t, recorder.batches, 1)
+		assert.Equal(t, testCase.expected, recorder.batches[0].Process.Tags)
+		assert.Equal(t, testCase.tags, batch.Process.Tags, "the received batch must not be modified")
+	}
+}
+
+func TestProcessTagsReporterZipkin(t *testing.T) {
+	endpoint := &zipkincore.Endpoint{ServiceName: "svc"}
+	span := &zipkincore.Span{
+		Annotations: []*zipkincore.Annotation{{Value: zipkincore.SERVER_RECV, Host: endpoint}},
+		BinaryAnnotations: []*zipkincore.BinaryAnnotation{
+			{Key: "env", Value: []byte("dev"), AnnotationType: zipkincore.AnnotationType_STRING, Host: endpoint},
+		},
+	}
+
+	recorder := &batchRecorder{}
+	r := NewProcessTagsReporter(recorder, testProcessTags, ClientTagsWin)
+	require.NoError(t, r.EmitZipkinBatch([]*zipkincore.Span{span}))
+	require.Len(t, recorder.zipkinSpans, 1)
+	annotations := recorder.zipkinSpans[0].BinaryAnnotations
+	require.Len(t, annotations, 2)
+	assert.Equal(t, "dev", string(annotations[0].Value))
+	assert.Equal(t, zipkin.ProcessTagPrefix+"dc", annotations[1].Key)
+	assert.Equal(t, "us-east", string(annotations[1].Value))
+	assert.Equal(t, endpoint, annotations[1].Host)
+	assert.Len(t, span.BinaryAnnotations, 1)
+
+	recorder = &batchRecorder{}
+	r = NewProcessTagsReporter(recorder, testProcessTags, AgentTagsWin)
+	require.NoError(t, r.EmitZipkinBatch([]*zipkincore.Span{span}))
+	annotations = recorder.zipkinSpans[0].BinaryAnnotations
+	require.Len(t, annotations, 2)
+	assert.Equal(t, zipkin.ProcessTagPrefix+"dc", annotations[0].Key)
+	assert.Equal(t, zipkin.ProcessTagPrefix+"env", annotations[1].Key)
+	assert.Equal(t, "prod", string(annotations[1].Value))
+
+	jSpan, err := zipkin.ToDomainSpan(recorder.zipkinSpans[0])
+	require.NoError(t, err)
+	assert.Equal(t, model.KeyValues{model.String("span.kind", "server")}, jSpan.Tags)
+	assert.Equal(t, model.KeyValues{model.String("dc", "us-east"), model.String("env", "prod")}, jSpan.Process.Tags)
+}
+
+func TestResolveProcessTags(t *testing.T) {
+	os.Setenv("PROCESS_TAGS_TEST_ENV", "staging")
+	defer os.Unsetenv("PROCESS_TAGS_TEST_ENV")
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+
+	tags, err := ResolveProcessTags(map[string]string{
+		"static":  "value",
+		"env":     "${PROCESS_TAGS_TEST_ENV}",
+		"dc":      "${PROCESS_TAGS_TEST_DC:us-east}",
+		"missing": "${PROCESS_TAGS_TEST_MISSING}",
+		"host":    "@hostname",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"static": "value",
+		"env":    "staging",
+		"dc":     "us-east",
+		"host":   hostname,
+	}, tags)
+}
+
+func TestParseTagPrecedence(t *testing.T) {
+	for s, expected := range map[string]TagPrecedence{"": ClientTagsWin, "client": ClientTagsWin, "agent": AgentTagsWin} {
+		precedence, err := ParseTagPrecedence(s)
+		require.NoError(t, err)
+		assert.Equal(t, expected, precedence)
+	}
+	_, err := ParseTagPrecedence("collector")
+	assert.EqualError(t, err, `unknown tag precedence "collector", expected "client" or "agent"`)
+}
@@ -0,0 +1,96 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reporter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/uber/jaeger/thrift-gen/jaeger"
+	"github.com/uber/jaeger/thrift-gen/zipkincore"
+)
+
+const maxRecentErrors = 10
+
+// SubmissionError is an error returned when submitting a batch
+type SubmissionError struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+// SubmissionStatus describes the submissions of the batches to the collectors
+type SubmissionStatus struct {
+	Submitted    int64             `json:"submitted"`
+	Failed       int64             `json:"failed"`
+	LastSuccess  *time.Time        `json:"lastSuccess,omitempty"`
+	RecentErrors []SubmissionError `json:"recentErrors"`
+}
+
+// StatusReporter is a Reporter decorator keeping track of the last successful submission and of the
+// most recent errors of the wrapped reporter
+type StatusReporter struct {
+	sync.Mutex
+	wrapped Reporter
+	status  SubmissionStatus
+	now     func() time.Time
+}
+
+// NewStatusReporter creates a StatusReporter
+func NewStatusReporter(wrapped Reporter) *StatusReporter {
+	return &StatusReporter{wrapped: wrapped, now: time.Now}
+}
+
+// EmitZipkinBatch implements EmitZipkinBatch() of Reporter
+func (r *StatusReporter) EmitZipkinBatch(spans []*zipkincore.Span) error {
+	return r.record(r.wrapped.EmitZipkinBatch(spans))
+}
+
+// EmitBatch implements EmitBatch() of Reporter
+func (r *StatusReporter) EmitBatch(batch *jaeger.Batch) error {
+	return r.record(r.wrapped.EmitBatch(batch))
+}
+
+// Status returns a snapshot of the submission status, the most recent error last
+func (r *StatusReporter) Status() SubmissionStatus {
+	r.Lock()
+	defer r.Unlock()
+	status := r.status
+	status.RecentErrors = append([]SubmissionError{}, r.status.RecentErrors...)
+	return status
+}
+
+func (r *StatusReporter) record(err error) error {
+	now := r.now()
+	r.Lock()
+	defer r.Unlock()
+	if err == nil {
+		r.status.Submitted++
+		r.status.LastSuccess = &now
+		return nil
+	}
+	r.status.Failed++
+	if len(r.status.RecentErrors) == maxRecentErrors {
+		r.status.RecentErrors = r.status.RecentErrors[1:]
+	}
+	r.status.RecentErrors = append(r.status.RecentErrors, SubmissionError{Time: now, Message: err.Error()})
+	return err
+}
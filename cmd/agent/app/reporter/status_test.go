@@ -0,0 +1,62 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reporter
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusReporter(t *testing.T) {
+	wrapped := &flakyReporter{}
+	r := NewStatusReporter(wrapped)
+	now := time.Unix(1500000000, 0)
+	r.now = func() time.Time { return now }
+
+	status := r.Status()
+	assert.Nil(t, status.LastSuccess)
+	assert.Empty(t, status.RecentErrors)
+
+	require.NoError(t, r.EmitBatch(testBatch("a")))
+	require.NoError(t, r.EmitZipkinBatch(testZipkinSpans("b")))
+	status = r.Status()
+	assert.EqualValues(t, 2, status.Submitted)
+	require.NotNil(t, status.LastSuccess)
+	assert.Equal(t, now, *status.LastSuccess)
+
+	wrapped.setDown(true)
+	for i := 0; i < maxRecentErrors+2; i++ {
+		now = now.Add(time.Second)
+		assert.Error(t, r.EmitBatch(testBatch(fmt.Sprintf("c%d", i))))
+	}
+	status = r.Status()
+	assert.EqualValues(t, maxRecentErrors+2, status.Failed)
+	assert.Equal(t, time.Unix(1500000000, 0), *status.LastSuccess)
+	require.Len(t, status.RecentErrors, maxRecentErrors)
+	assert.Equal(t, SubmissionError{Time: now, Message: "collector down"}, status.RecentErrors[maxRecentErrors-1])
+	assert.Equal(t, now.Add(-(maxRecentErrors-1)*time.Second), status.RecentErrors[0].Time)
+	assert.Equal(t, "collector down", status.RecentErrors[0].Message)
+}
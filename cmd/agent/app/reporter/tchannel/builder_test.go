@@ -70,6 +70,8 @@ func TestBuilderWithDiscovery(t *testing.T) {
 	agent, err := cfg.CreateReporter(metrics.NullFactory, zap.NewNop())
 	assert.NoError(t, err)
 	assert.NotNil(t, agent)
+	require.Len(t, agent.Peers(), 1)
+	assert.Equal(t, "1.1.1.1:80", agent.Peers()[0].HostPort)
 }
 
 func TestBuilderWithCollectorServiceName(t *testing.T) {
@@ -94,6 +96,7 @@ func TestBuilderWithChannel(t *testing.T) {
 	rep, err := cfg.CreateReporter(metrics.NullFactory, zap.NewNop())
 	assert.NoError(t, err)
 	assert.NotNil(t, rep.Channel())
+	assert.Nil(t, rep.Peers())
 }
 
 func TestBuilderWithCollectors(t *testing.T) {
@@ -99,6 +99,14 @@ func (r *Reporter) Channel() *tchannel.Channel {
 	return r.channel
 }
 
+// Peers returns the status of the collectors managed by service discovery, nil without discovery
+func (r *Reporter) Peers() []peerlistmgr.PeerStatus {
+	if r.peerListMgr == nil {
+		return nil
+	}
+	return r.peerListMgr.Peers()
+}
+
 // EmitZipkinBatch implements EmitZipkinBatch() of Reporter
 func (r *Reporter) EmitZipkinBatch(spans []*zipkincore.Span) error {
 	submissionFunc := func(ctx thrift.Context) error {
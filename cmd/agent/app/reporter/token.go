@@ -0,0 +1,208 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reporter
+
+import (
+	"io/ioutil"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"gopkg.in/yaml.v2"
+
+	"github.com/uber/jaeger/thrift-gen/jaeger"
+	"github.com/uber/jaeger/thrift-gen/zipkincore"
+)
+
+// DefaultTokenTagKey is the default tag holding the API token, it matches the default of the collector
+const DefaultTokenTagKey = "api-token"
+
+// Tokens holds the API tokens injected into the spans, as read from a YAML file:
+//
+//	default: 315a1793-a1b7-16a5-88c5-bc76f9c772a1
+//	services:
+//	  frontend: 9c8a3f84-0c6e-4f4f-8f57-24cb5e6a2b0e
+type Tokens struct {
+	// Default is the token of the services not listed in Services
+	Default string `yaml:"default"`
+	// Services maps service names to their tokens
+	Services map[string]string `yaml:"services"`
+}
+
+// LoadTokens reads the tokens from a YAML file
+func LoadTokens(path string) (Tokens, error) {
+	var tokens Tokens
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return tokens, err
+	}
+	err = yaml.Unmarshal(bytes, &tokens)
+	return tokens, err
+}
+
+// Empty returns true if no token is defined
+func (t Tokens) Empty() bool {
+	return t.Default == "" && len(t.Services) == 0
+}
+
+func (t Tokens) forService(service string) string {
+	if token, ok := t.Services[service]; ok {
+		return token
+	}
+	return t.Default
+}
+
+type tokenMetrics struct {
+	// Number of Jaeger batches or Zipkin spans the token was added to
+	Injected metrics.Counter `metric:"injected"`
+
+	// Number of Jaeger batches or Zipkin spans that already had a token set by the client
+	Present metrics.Counter `metric:"present"`
+
+	// Number of Jaeger batches or Zipkin spans of services without a token
+	Missing metrics.Counter `metric:"missing"`
+}
+
+// TokenReporter is a Reporter decorator that adds the API token of the service to the spans,
+// so that client libraries do not have to, unless the client already set a token.
+// The batches are copied before being modified, the received ones are left intact.
+type TokenReporter struct {
+	wrapped       Reporter
+	tagKey        string
+	tokens        Tokens
+	jaegerMetrics tokenMetrics
+	zipkinMetrics tokenMetrics
+}
+
+// NewTokenReporter creates a TokenReporter that puts the token in the tagKey tag of the spans
+func NewTokenReporter(wrapped Reporter, tagKey string, tokens Tokens, mFactory metrics.Factory) *TokenReporter {
+	r := &TokenReporter{
+		wrapped: wrapped,
+		tagKey:  tagKey,
+		tokens:  tokens,
+	}
+	ns := mFactory.Namespace("token-injection", nil)
+	metrics.Init(&r.jaegerMetrics, ns.Namespace("jaeger", nil), nil)
+	metrics.Init(&r.zipkinMetrics, ns.Namespace("zipkin", nil), nil)
+	return r
+}
+
+// EmitZipkinBatch implements EmitZipkinBatch() of Reporter
+func (r *TokenReporter) EmitZipkinBatch(spans []*zipkincore.Span) error {
+	return r.wrapped.EmitZipkinBatch(r.injectZipkin(spans))
+}
+
+// EmitBatch implements EmitBatch() of Reporter
+func (r *TokenReporter) EmitBatch(batch *jaeger.Batch) error {
+	return r.wrapped.EmitBatch(r.inject(batch))
+}
+
+func (r *TokenReporter) inject(batch *jaeger.Batch) *jaeger.Batch {
+	if batch.Process == nil {
+		r.jaegerMetrics.Missing.Inc(1)
+		return batch
+	}
+	if r.hasJaegerToken(batch) {
+		r.jaegerMetrics.Present.Inc(1)
+		return batch
+	}
+	token := r.tokens.forService(batch.Process.ServiceName)
+	if token == "" {
+		r.jaegerMetrics.Missing.Inc(1)
+		return batch
+	}
+	process := *batch.Process
+	process.Tags = make([]*jaeger.Tag, len(batch.Process.Tags), len(batch.Process.Tags)+1)
+	copy(process.Tags, batch.Process.Tags)
+	process.Tags = append(process.Tags, &jaeger.Tag{Key: r.tagKey, VType: jaeger.TagType_STRING, VStr: &token})
+	injected := *batch
+	injected.Process = &process
+	r.jaegerMetrics.Injected.Inc(1)
+	return &injected
+}
+
+func (r *TokenReporter) hasJaegerToken(batch *jaeger.Batch) bool {
+	for _, tag := range batch.Process.Tags {
+		if tag.Key == r.tagKey {
+			return true
+		}
+	}
+	for _, span := range batch.Spans {
+		for _, tag := range span.Tags {
+			if tag.Key == r.tagKey {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *TokenReporter) injectZipkin(spans []*zipkincore.Span) []*zipkincore.Span {
+	injected := make([]*zipkincore.Span, len(spans))
+	for i, span := range spans {
+		injected[i] = r.injectZipkinSpan(span)
+	}
+	return injected
+}
+
+func (r *TokenReporter) injectZipkinSpan(span *zipkincore.Span) *zipkincore.Span {
+	for _, annotation := range span.BinaryAnnotations {
+		if annotation.Key == r.tagKey {
+			r.zipkinMetrics.Present.Inc(1)
+			return span
+		}
+	}
+	endpoint := zipkinEndpoint(span)
+	serviceName := ""
+	if endpoint != nil {
+		serviceName = endpoint.ServiceName
+	}
+	token := r.tokens.forService(serviceName)
+	if token == "" {
+		r.zipkinMetrics.Missing.Inc(1)
+		return span
+	}
+	injected := *span
+	injected.BinaryAnnotations = make([]*zipkincore.BinaryAnnotation, len(span.BinaryAnnotations), len(span.BinaryAnnotations)+1)
+	copy(injected.BinaryAnnotations, span.BinaryAnnotations)
+	injected.BinaryAnnotations = append(injected.BinaryAnnotations, &zipkincore.BinaryAnnotation{
+		Key:            r.tagKey,
+		Value:          []byte(token),
+		AnnotationType: zipkincore.AnnotationType_STRING,
+		Host:           endpoint,
+	})
+	r.zipkinMetrics.Injected.Inc(1)
+	return &injected
+}
+
+// zipkinEndpoint returns the first endpoint of the span annotations, which identifies the service
+func zipkinEndpoint(span *zipkincore.Span) *zipkincore.Endpoint {
+	for _, annotation := range span.Annotations {
+		if annotation.Host != nil {
+			return annotation.Host
+		}
+	}
+	for _, annotation := range span.BinaryAnnotations {
+		if annotation.Host != nil {
+			return annotation.Host
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reporter
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	mTestutils "github.com/uber/jaeger-lib/metrics/testutils"
+
+	"github.com/uber/jaeger/thrift-gen/jaeger"
+	"github.com/uber/jaeger/thrift-gen/zipkincore"
+)
+
+type batchRecorder struct {
+	batches     []*jaeger.Batch
+	zipkinSpans []*zipkincore.Span
+}
+
+func (r *batchRecorder) EmitZipkinBatch(spans []*zipkincore.Span) error {
+	r.zipkinSpans = append(r.zipkinSpans, spans...)
+	return nil
+}
+
+func (r *batchRecorder) EmitBatch(batch *jaeger.Batch) error {
+	r.batches = append(r.batches, batch)
+	return nil
+}
+
+func stringTag(key, value string) *jaeger.Tag {
+	return &jaeger.Tag{Key: key, VType: jaeger.TagType_STRING, VStr: &value}
+}
+
+var testTokens = Tokens{
+	Default:  "default-token",
+	Services: map[string]string{"frontend": "frontend-token", "public": ""},
+}
+
+func TestTokenReporterJaeger(t *testing.T) {
+	testCases := []struct {
+		batch    *jaeger.Batch
+		expected string
+	}{
+		{
+			batch:    &jaeger.Batch{Process: &jaeger.Process{ServiceName: "frontend"}},
+			expected: "frontend-token",
+		},
+		{
+			batch:    &jaeger.Batch{Process: &jaeger.Process{ServiceName: "backend", Tags: []*jaeger.Tag{stringTag("k", "v")}}},
+			expected: "default-token",
+		},
+		{
+			batch:    &jaeger.Batch{Process: &jaeger.Process{ServiceName: "frontend", Tags: []*jaeger.Tag{stringTag("api-token", "client-token")}}},
+			expected: "client-token",
+		},
+		{
+			batch:    &jaeger.Batch{Process: &jaeger.Process{ServiceName: "public"}},
+			expected: "",
+		},
+	}
+	mFactory := metrics.NewLocalFactory(0)
+	recorder := &batchRecorder{}
+	r := NewTokenReporter(recorder, DefaultTokenTagKey, testTokens, mFactory)
+	for i, testCase := range testCases {
+		processTags := len(testCase.batch.Process.Tags)
+		require.NoError(t, r.EmitBatch(testCase.batch))
+		emitted := recorder.batches[i]
+		token := ""
+		for _, tag := range emitted.Process.Tags {
+			if tag.Key == DefaultTokenTagKey {
+				token = *tag.VStr
+			}
+		}
+		assert.Equal(t, testCase.expected, token, "test case #%d", i)
+		assert.Len(t, testCase.batch.Process.Tags, processTags, "the received batch must not be modified")
+	}
+
+	// a token in the span tags is not overwritten either
+	batch := &jaeger.Batch{
+		Process: &jaeger.Process{ServiceName: "frontend"},
+		Spans:   []*jaeger.Span{{Tags: []*jaeger.Tag{stringTag("api-token", "client-token")}}},
+	}
+	require.NoError(t, r.EmitBatch(batch))
+	assert.Equal(t, batch, recorder.batches[len(recorder.batches)-1])
+
+	mTestutils.AssertCounterMetrics(t, mFactory,
+		mTestutils.ExpectedMetric{Name: "token-injection.jaeger.injected", Value: 2},
+		mTestutils.ExpectedMetric{Name: "token-injection.jaeger.present", Value: 2},
+		mTestutils.ExpectedMetric{Name: "token-injection.jaeger.missing", Value: 1},
+	)
+}
+
+func TestTokenReporterZipkin(t *testing.T) {
+	frontend := &zipkincore.Endpoint{ServiceName: "frontend"}
+	spans := []*zipkincore.Span{
+		{Annotations: []*zipkincore.Annotation{{Value: zipkincore.SERVER_RECV, Host: frontend}}},
+		{BinaryAnnotations: []*zipkincore.BinaryAnnotation{{Key: "api-token", Value: []byte("client-token")}}},
+		{},
+	}
+	recorder := &batchRecorder{}
+	r := NewTokenReporter(recorder, DefaultTokenTagKey, testTokens, metrics.NullFactory)
+	require.NoError(t, r.EmitZipkinBatch(spans))
+	require.Len(t, recorder.zipkinSpans, 3)
+
+	assert.Equal(t, []*zipkincore.BinaryAnnotation{{
+		Key:            "api-token",
+		Value:          []byte("frontend-token"),
+		AnnotationType: zipkincore.AnnotationType_STRING,
+		Host:           frontend,
+	}}, recorder.zipkinSpans[0].BinaryAnnotations)
+	assert.Empty(t, spans[0].BinaryAnnotations, "the received span must not be modified")
+	assert.Equal(t, spans[1], recorder.zipkinSpans[1])
+	assert.Equal(t, []byte("default-token"), recorder.zipkinSpans[2].BinaryAnnotations[0].Value)
+}
+
+func TestLoadTokens(t *testing.T) {
+	f, err := ioutil.TempFile("", "tokens")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("default: default-token\nservices:\n  frontend: frontend-token\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	tokens, err := LoadTokens(f.Name())
+	require.NoError(t, err)
+	assert.Equal(t, Tokens{Default: "default-token", Services: map[string]string{"frontend": "frontend-token"}}, tokens)
+	assert.False(t, tokens.Empty())
+	assert.True(t, Tokens{}.Empty())
+
+	_, err = LoadTokens(f.Name() + ".missing")
+	assert.Error(t, err)
+}
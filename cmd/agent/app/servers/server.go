@@ -20,7 +20,10 @@
 
 package servers
 
-import "io"
+import (
+	"io"
+	"net"
+)
 
 // Server is the interface for servers that receive inbound span submissions from client.
 type Server interface {
@@ -31,12 +34,27 @@ type Server interface {
 	DataRecd(*ReadBuf) // must be called by consumer after reading data from the ReadBuf
 }
 
+// Status is a snapshot of the state of a server, counts are since the server was created
+type Status struct {
+	Serving          bool  `json:"serving"`
+	QueueSize        int64 `json:"queueSize"`
+	MaxQueueSize     int   `json:"maxQueueSize"`
+	MaxPacketSize    int   `json:"maxPacketSize"`
+	PacketsProcessed int64 `json:"packetsProcessed"`
+	PacketsDropped   int64 `json:"packetsDropped"`
+	PacketsTruncated int64 `json:"packetsTruncated"`
+	ReadErrors       int64 `json:"readErrors"`
+	Readers          int   `json:"readers"`
+}
+
 // ReadBuf is a structure that holds the bytes to read into as well as the number of bytes
 // that was read. The slice is typically pre-allocated to the max packet size and the buffers
 // themselves are polled to avoid memory allocations for every new inbound message.
 type ReadBuf struct {
-	bytes []byte
-	n     int
+	bytes     []byte
+	n         int
+	addr      net.Addr
+	truncated bool
 }
 
 // GetBytes returns the contents of the Readbuf as bytes
@@ -44,6 +62,16 @@ func (r *ReadBuf) GetBytes() []byte {
 	return r.bytes[:r.n]
 }
 
+// Addr returns the address of the client which sent the packet, nil if unknown
+func (r *ReadBuf) Addr() net.Addr {
+	return r.addr
+}
+
+// Truncated returns true if the packet exceeded the max packet size, only its beginning was read
+func (r *ReadBuf) Truncated() bool {
+	return r.truncated
+}
+
 func (r *ReadBuf) Read(p []byte) (int, error) {
 	if r.n == 0 {
 		return 0, io.EOF
@@ -21,6 +21,8 @@
 package servers
 
 import (
+	"errors"
+	"net"
 	"sync"
 	"sync/atomic"
 
@@ -28,15 +30,26 @@ import (
 	"github.com/uber/jaeger-lib/metrics"
 )
 
-// TBufferedServer is a custom thrift server that reads traffic using the transport provided
-// and places messages into a buffered channel to be processed by the processor provided
+// packetReader is implemented by the transports able to tell the address of the sender of a packet
+type packetReader interface {
+	ReadFrom(buf []byte) (int, net.Addr, error)
+}
+
+// TBufferedServer is a custom thrift server that reads traffic using the transports provided
+// and places messages into a buffered channel to be processed by the processor provided.
+// Each transport is read by its own goroutine.
 type TBufferedServer struct {
 	dataChan      chan *ReadBuf
 	maxPacketSize int
 	maxQueueSize  int
 	queueSize     int64
 	serving       uint32
-	transport     thrift.TTransport
+	processed     int64
+	dropped       int64
+	truncated     int64
+	readErrors    int64
+	transports    []thrift.TTransport
+	readers       sync.WaitGroup
 	readBufPool   *sync.Pool
 	metrics       struct {
 		// Size of the current server queue
@@ -48,6 +61,9 @@ type TBufferedServer struct {
 		// Number of packets dropped by server
 		PacketsDropped metrics.Counter `metric:"thrift.udp.server.packets.dropped"`
 
+		// Number of packets truncated because they exceeded the max packet size
+		PacketsTruncated metrics.Counter `metric:"thrift.udp.server.packets.truncated"`
+
 		// Number of packets processed by server
 		PacketsProcessed metrics.Counter `metric:"thrift.udp.server.packets.processed"`
 
@@ -63,16 +79,31 @@ func NewTBufferedServer(
 	maxPacketSize int,
 	mFactory metrics.Factory,
 ) (*TBufferedServer, error) {
+	return NewTBufferedMultiServer([]thrift.TTransport{transport}, maxQueueSize, maxPacketSize, mFactory)
+}
+
+// NewTBufferedMultiServer creates a TBufferedServer reading from several transports, typically
+// sockets bound to the same address with SO_REUSEPORT
+func NewTBufferedMultiServer(
+	transports []thrift.TTransport,
+	maxQueueSize int,
+	maxPacketSize int,
+	mFactory metrics.Factory,
+) (*TBufferedServer, error) {
+	if len(transports) == 0 {
+		return nil, errors.New("at least one transport is required")
+	}
 	dataChan := make(chan *ReadBuf, maxQueueSize)
 
 	var readBufPool = &sync.Pool{
 		New: func() interface{} {
-			return &ReadBuf{bytes: make([]byte, maxPacketSize)}
+			// one extra byte tells whether a packet exceeded the max packet size
+			return &ReadBuf{bytes: make([]byte, maxPacketSize+1)}
 		},
 	}
 
 	res := &TBufferedServer{dataChan: dataChan,
-		transport:     transport,
+		transports:    transports,
 		maxQueueSize:  maxQueueSize,
 		maxPacketSize: maxPacketSize,
 		readBufPool:   readBufPool,
@@ -84,25 +115,54 @@ func NewTBufferedServer(
 // Serve initiates the readers and starts serving traffic
 func (s *TBufferedServer) Serve() {
 	atomic.StoreUint32(&s.serving, 1)
+	s.readers.Add(len(s.transports))
+	for _, transport := range s.transports[1:] {
+		go s.read(transport)
+	}
+	s.read(s.transports[0])
+}
+
+func (s *TBufferedServer) read(transport thrift.TTransport) {
+	defer s.readers.Done()
 	for s.IsServing() {
 		readBuf := s.readBufPool.Get().(*ReadBuf)
-		n, err := s.transport.Read(readBuf.bytes)
-		if err == nil {
-			readBuf.n = n
-			s.metrics.PacketSize.Update(int64(n))
-			select {
-			case s.dataChan <- readBuf:
-				s.metrics.PacketsProcessed.Inc(1)
-				s.updateQueueSize(1)
-			default:
-				s.metrics.PacketsDropped.Inc(1)
-			}
-		} else {
+		n, addr, err := s.readFrom(transport, readBuf.bytes)
+		if err != nil {
+			s.readBufPool.Put(readBuf)
+			atomic.AddInt64(&s.readErrors, 1)
 			s.metrics.ReadError.Inc(1)
+			continue
+		}
+		readBuf.n = n
+		readBuf.addr = addr
+		readBuf.truncated = n > s.maxPacketSize
+		if readBuf.truncated {
+			readBuf.n = s.maxPacketSize
+			atomic.AddInt64(&s.truncated, 1)
+			s.metrics.PacketsTruncated.Inc(1)
+		}
+		s.metrics.PacketSize.Update(int64(n))
+		select {
+		case s.dataChan <- readBuf:
+			atomic.AddInt64(&s.processed, 1)
+			s.metrics.PacketsProcessed.Inc(1)
+			s.updateQueueSize(1)
+		default:
+			s.readBufPool.Put(readBuf)
+			atomic.AddInt64(&s.dropped, 1)
+			s.metrics.PacketsDropped.Inc(1)
 		}
 	}
 }
 
+func (s *TBufferedServer) readFrom(transport thrift.TTransport, buf []byte) (int, net.Addr, error) {
+	if reader, ok := transport.(packetReader); ok {
+		return reader.ReadFrom(buf)
+	}
+	n, err := transport.Read(buf)
+	return n, nil, err
+}
+
 func (s *TBufferedServer) updateQueueSize(delta int64) {
 	atomic.AddInt64(&s.queueSize, delta)
 	s.metrics.QueueSize.Update(atomic.LoadInt64(&s.queueSize))
@@ -117,7 +177,11 @@ func (s *TBufferedServer) IsServing() bool {
 // emptied by the readers
 func (s *TBufferedServer) Stop() {
 	atomic.StoreUint32(&s.serving, 0)
-	s.transport.Close()
+	for _, transport := range s.transports {
+		transport.Close()
+	}
+	// the readers must be done sending before the channel is closed
+	s.readers.Wait()
 	close(s.dataChan)
 }
 
@@ -126,6 +190,21 @@ func (s *TBufferedServer) DataChan() chan *ReadBuf {
 	return s.dataChan
 }
 
+// Status returns a snapshot of the state of the server
+func (s *TBufferedServer) Status() Status {
+	return Status{
+		Serving:          s.IsServing(),
+		QueueSize:        atomic.LoadInt64(&s.queueSize),
+		MaxQueueSize:     s.maxQueueSize,
+		MaxPacketSize:    s.maxPacketSize,
+		PacketsProcessed: atomic.LoadInt64(&s.processed),
+		PacketsDropped:   atomic.LoadInt64(&s.dropped),
+		PacketsTruncated: atomic.LoadInt64(&s.truncated),
+		ReadErrors:       atomic.LoadInt64(&s.readErrors),
+		Readers:          len(s.transports),
+	}
+}
+
 // DataRecd is called by the consumers every time they read a data item from DataChan
 func (s *TBufferedServer) DataRecd(buf *ReadBuf) {
 	s.updateQueueSize(-1)
@@ -80,6 +80,11 @@ func testTBufferedServer(t *testing.T, queueSize int, testDroppedPackets bool) {
 		for i := 0; i < 50; i++ {
 			c, _ := metricsFactory.Snapshot()
 			if c["thrift.udp.server.packets.dropped"] == 1 {
+				status := server.Status()
+				assert.EqualValues(t, 1, status.PacketsDropped)
+				assert.EqualValues(t, 1, status.PacketsProcessed)
+				assert.EqualValues(t, 1, status.QueueSize)
+				assert.Equal(t, 1, status.MaxQueueSize)
 				return
 			}
 			time.Sleep(time.Millisecond)
@@ -92,6 +97,8 @@ func testTBufferedServer(t *testing.T, queueSize int, testDroppedPackets bool) {
 	select {
 	case readBuf := <-server.DataChan():
 		assert.NotEqual(t, 0, len(readBuf.GetBytes()))
+		assert.NotNil(t, readBuf.Addr())
+		assert.False(t, readBuf.Truncated())
 		protoFact := athrift.NewTCompactProtocolFactory()
 		trans := &customtransport.TBufferedReadTransport{}
 		protocol := protoFact.GetProtocol(trans)
@@ -115,4 +122,78 @@ func testTBufferedServer(t *testing.T, queueSize int, testDroppedPackets bool) {
 		mTestutils.ExpectedMetric{Name: "thrift.udp.server.packet_size", Value: 38},
 		mTestutils.ExpectedMetric{Name: "thrift.udp.server.queue_size", Value: 0},
 	)
+	assert.Equal(t, Status{
+		Serving:          true,
+		MaxQueueSize:     queueSize,
+		MaxPacketSize:    maxPacketSize,
+		PacketsProcessed: 1,
+		Readers:          1,
+	}, server.Status())
+}
+
+func TestTBufferedServerTruncatedPackets(t *testing.T) {
+	metricsFactory := metrics.NewLocalFactory(0)
+
+	transport, err := thriftudp.NewTUDPServerTransport("127.0.0.1:0")
+	require.NoError(t, err)
+
+	maxPacketSize := 20
+	server, err := NewTBufferedServer(transport, 10, maxPacketSize, metricsFactory)
+	require.NoError(t, err)
+	go server.Serve()
+	defer server.Stop()
+	time.Sleep(10 * time.Millisecond) // wait for server to start serving
+
+	client, clientCloser, err := testutils.NewZipkinThriftUDPClient(transport.Addr().String())
+	require.NoError(t, err)
+	defer clientCloser.Close()
+	require.NoError(t, client.EmitZipkinBatch([]*zipkincore.Span{{Name: "a-span-with-a-long-name"}}))
+
+	select {
+	case readBuf := <-server.DataChan():
+		assert.True(t, readBuf.Truncated())
+		assert.Len(t, readBuf.GetBytes(), maxPacketSize)
+		server.DataRecd(readBuf)
+	case <-time.After(time.Second):
+		t.Fatalf("Server should have received span submission")
+	}
+	mTestutils.AssertCounterMetrics(t, metricsFactory,
+		mTestutils.ExpectedMetric{Name: "thrift.udp.server.packets.truncated", Value: 1},
+	)
+	assert.EqualValues(t, 1, server.Status().PacketsTruncated)
+}
+
+func TestTBufferedMultiServer(t *testing.T) {
+	_, err := NewTBufferedMultiServer(nil, 10, 100, metrics.NullFactory)
+	assert.EqualError(t, err, "at least one transport is required")
+
+	var transports []athrift.TTransport
+	var hostPorts []string
+	for i := 0; i < 2; i++ {
+		transport, err := thriftudp.NewTUDPServerTransport("127.0.0.1:0")
+		require.NoError(t, err)
+		transports = append(transports, transport)
+		hostPorts = append(hostPorts, transport.Addr().String())
+	}
+	server, err := NewTBufferedMultiServer(transports, 10, 65000, metrics.NullFactory)
+	require.NoError(t, err)
+	go server.Serve()
+	defer server.Stop()
+	time.Sleep(10 * time.Millisecond) // wait for server to start serving
+
+	for _, hostPort := range hostPorts {
+		client, clientCloser, err := testutils.NewZipkinThriftUDPClient(hostPort)
+		require.NoError(t, err)
+		defer clientCloser.Close()
+		require.NoError(t, client.EmitZipkinBatch([]*zipkincore.Span{{Name: "span"}}))
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case readBuf := <-server.DataChan():
+			server.DataRecd(readBuf)
+		case <-time.After(time.Second):
+			t.Fatalf("Server should have received span submissions from both transports")
+		}
+	}
+	assert.Equal(t, 2, server.Status().Readers)
 }
@@ -0,0 +1,83 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package thriftudp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReusePort(t *testing.T) {
+	options := ServerOptions{ReusePort: true}
+	trans1, err := NewTUDPServerTransportWithOptions(localListenAddr.String(), options)
+	require.NoError(t, err)
+	defer trans1.Close()
+
+	// a second transport can listen on the same address
+	trans2, err := NewTUDPServerTransportWithOptions(trans1.Addr().String(), options)
+	require.NoError(t, err)
+	defer trans2.Close()
+	assert.Equal(t, trans1.Addr().String(), trans2.Addr().String())
+
+	client, err := NewTUDPClientTransport(trans1.Addr().String(), "")
+	require.NoError(t, err)
+	defer client.Close()
+	_, err = client.Write([]byte("test"))
+	require.NoError(t, err)
+	require.NoError(t, client.Flush())
+
+	// the kernel picks the socket receiving the packet based on the client address
+	received := make(chan string, 2)
+	for _, trans := range []*TUDPTransport{trans1, trans2} {
+		go func(trans *TUDPTransport) {
+			buf := make([]byte, 10)
+			if n, err := trans.Read(buf); err == nil {
+				received <- string(buf[:n])
+			}
+		}(trans)
+	}
+	assert.Equal(t, "test", <-received)
+
+	// without SO_REUSEPORT the address is still in use
+	_, err = NewTUDPServerTransport(trans1.Addr().String())
+	assert.Error(t, err)
+}
+
+func TestReusePortDualStack(t *testing.T) {
+	trans, err := NewTUDPServerTransportWithOptions(":0", ServerOptions{ReusePort: true})
+	require.NoError(t, err)
+	defer trans.Close()
+
+	client, err := NewTUDPClientTransport(trans.Addr().String(), "")
+	require.NoError(t, err)
+	defer client.Close()
+	_, err = client.Write([]byte("test"))
+	require.NoError(t, err)
+	require.NoError(t, client.Flush())
+
+	buf := make([]byte, 10)
+	n, err := trans.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "test", string(buf[:n]))
+}
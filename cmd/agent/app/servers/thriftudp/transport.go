@@ -34,6 +34,15 @@ const MaxLength = 65000
 
 var errConnAlreadyClosed = errors.New("connection already closed")
 
+// ServerOptions tunes the socket of a server transport
+type ServerOptions struct {
+	// SocketBufferSize is the size of the socket receive buffer (SO_RCVBUF), the OS default is kept when zero
+	SocketBufferSize int
+	// ReusePort binds the socket with SO_REUSEPORT so that several transports, each read by its own
+	// goroutine, can listen on the same address. It is only supported on Linux.
+	ReusePort bool
+}
+
 // TUDPTransport does UDP as a thrift.TTransport
 type TUDPTransport struct {
 	conn     *net.UDPConn
@@ -77,14 +86,31 @@ func createClient(destAddr, locAddr *net.UDPAddr) (*TUDPTransport, error) {
 // Example:
 // 	trans, err := thriftudp.NewTUDPClientTransport("localhost:9001")
 func NewTUDPServerTransport(hostPort string) (*TUDPTransport, error) {
+	return NewTUDPServerTransportWithOptions(hostPort, ServerOptions{})
+}
+
+// NewTUDPServerTransportWithOptions creates a net.UDPConn-backed TTransport for Thrift servers
+// listening on the specified host/port with a socket tuned by options
+func NewTUDPServerTransportWithOptions(hostPort string, options ServerOptions) (*TUDPTransport, error) {
 	addr, err := net.ResolveUDPAddr("udp", hostPort)
 	if err != nil {
 		return nil, thrift.NewTTransportException(thrift.NOT_OPEN, err.Error())
 	}
-	conn, err := net.ListenUDP(addr.Network(), addr)
+	var conn *net.UDPConn
+	if options.ReusePort {
+		conn, err = listenUDPReusePort(addr)
+	} else {
+		conn, err = net.ListenUDP(addr.Network(), addr)
+	}
 	if err != nil {
 		return nil, thrift.NewTTransportException(thrift.NOT_OPEN, err.Error())
 	}
+	if options.SocketBufferSize > 0 {
+		if err := conn.SetReadBuffer(options.SocketBufferSize); err != nil {
+			conn.Close()
+			return nil, thrift.NewTTransportException(thrift.NOT_OPEN, err.Error())
+		}
+	}
 	return &TUDPTransport{addr: conn.LocalAddr(), conn: conn}, nil
 }
 
@@ -126,6 +152,18 @@ func (p *TUDPTransport) Read(buf []byte) (int, error) {
 	return n, thrift.NewTTransportExceptionFromError(err)
 }
 
+// ReadFrom reads one UDP packet, puts it in the specified buf and returns the address of its sender
+func (p *TUDPTransport) ReadFrom(buf []byte) (int, net.Addr, error) {
+	if !p.IsOpen() {
+		return 0, nil, thrift.NewTTransportException(thrift.NOT_OPEN, "Connection not open")
+	}
+	n, addr, err := p.conn.ReadFromUDP(buf)
+	if addr == nil {
+		return n, nil, thrift.NewTTransportExceptionFromError(err)
+	}
+	return n, addr, thrift.NewTTransportExceptionFromError(err)
+}
+
 // RemainingBytes returns the max number of bytes (same as Thrift's StreamTransport) as we
 // do not know how many bytes we have left.
 func (p *TUDPTransport) RemainingBytes() uint64 {
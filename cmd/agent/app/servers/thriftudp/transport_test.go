@@ -137,6 +137,30 @@ func TestWriteRead(t *testing.T) {
 	require.Equal(t, expected, readBuf[0:n])
 }
 
+func TestReadFrom(t *testing.T) {
+	server, err := NewTUDPServerTransportWithOptions(localListenAddr.String(), ServerOptions{SocketBufferSize: 1024 * 1024})
+	require.Nil(t, err)
+	defer server.Close()
+
+	client, err := NewTUDPClientTransport(server.Addr().String(), "")
+	require.Nil(t, err)
+	defer client.Close()
+
+	_, err = client.Write([]byte("test"))
+	require.Nil(t, err)
+	require.Nil(t, client.Flush())
+
+	readBuf := make([]byte, 20)
+	n, addr, err := server.ReadFrom(readBuf)
+	require.Nil(t, err)
+	assert.Equal(t, []byte("test"), readBuf[0:n])
+	assert.Equal(t, client.conn.LocalAddr().String(), addr.String())
+
+	require.NoError(t, server.Close())
+	_, _, err = server.ReadFrom(readBuf)
+	assert.NotNil(t, err)
+}
+
 func TestDoubleCloseError(t *testing.T) {
 	trans, err := NewTUDPServerTransport(localListenAddr.String())
 	require.Nil(t, err)
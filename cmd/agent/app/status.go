@@ -0,0 +1,151 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/uber/jaeger/cmd/agent/app/processors"
+	"github.com/uber/jaeger/cmd/agent/app/reporter"
+	"github.com/uber/jaeger/pkg/discovery/peerlistmgr"
+)
+
+// processorStatus describes a processor receiving spans from the clients
+type processorStatus struct {
+	Model    model    `json:"model"`
+	Protocol protocol `json:"protocol"`
+	HostPort string   `json:"hostPort"`
+	processors.Status
+}
+
+// agentStatus is the state of the agent served on /status
+type agentStatus struct {
+	Processors          []processorStatus         `json:"processors"`
+	ReporterType        reporterType              `json:"reporterType"`
+	Collectors          []peerlistmgr.PeerStatus  `json:"collectors"`
+	ConnectedCollectors int                       `json:"connectedCollectors"`
+	Submissions         reporter.SubmissionStatus `json:"submissions"`
+}
+
+// runtimeStatus describes the agent process
+type runtimeStatus struct {
+	StartTime  time.Time `json:"startTime"`
+	Uptime     string    `json:"uptime"`
+	GoVersion  string    `json:"goVersion"`
+	GOMAXPROCS int       `json:"gomaxprocs"`
+	Goroutines int       `json:"goroutines"`
+	HeapAlloc  uint64    `json:"heapAlloc"`
+	HeapInuse  uint64    `json:"heapInuse"`
+	NumGC      uint32    `json:"numGC"`
+}
+
+// debugStatus is the state of the agent and of its process served on /debug
+type debugStatus struct {
+	agentStatus
+	Runtime runtimeStatus `json:"runtime"`
+}
+
+// processorStatusProvider is implemented by the processors reporting their state
+type processorStatusProvider interface {
+	Status() processors.Status
+}
+
+type statusProcessor struct {
+	config    ProcessorConfiguration
+	processor processorStatusProvider
+}
+
+// statusHandler serves the state of the agent as JSON
+type statusHandler struct {
+	processors   []statusProcessor
+	reporterType reporterType
+	peers        func() []peerlistmgr.PeerStatus
+	submissions  *reporter.StatusReporter
+	started      time.Time
+}
+
+// registerStatusHandlers adds the /status and /debug endpoints to mux
+func registerStatusHandlers(mux *http.ServeMux, handler *statusHandler) {
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		writeStatus(w, handler.status())
+	})
+	mux.HandleFunc("/debug", func(w http.ResponseWriter, r *http.Request) {
+		writeStatus(w, handler.debugStatus())
+	})
+}
+
+func (h *statusHandler) status() agentStatus {
+	status := agentStatus{
+		Processors:   make([]processorStatus, 0, len(h.processors)),
+		ReporterType: h.reporterType,
+		Collectors:   h.peers(),
+		Submissions:  h.submissions.Status(),
+	}
+	if status.ReporterType == "" {
+		status.ReporterType = tchannelReporterType
+	}
+	for _, p := range h.processors {
+		status.Processors = append(status.Processors, processorStatus{
+			Model:    p.config.Model,
+			Protocol: p.config.Protocol,
+			HostPort: p.config.Server.HostPort,
+			Status:   p.processor.Status(),
+		})
+	}
+	for _, peer := range status.Collectors {
+		if peer.Connected {
+			status.ConnectedCollectors++
+		}
+	}
+	return status
+}
+
+func (h *statusHandler) debugStatus() debugStatus {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	return debugStatus{
+		agentStatus: h.status(),
+		Runtime: runtimeStatus{
+			StartTime:  h.started,
+			Uptime:     time.Since(h.started).String(),
+			GoVersion:  runtime.Version(),
+			GOMAXPROCS: runtime.GOMAXPROCS(0),
+			Goroutines: runtime.NumGoroutine(),
+			HeapAlloc:  memStats.HeapAlloc,
+			HeapInuse:  memStats.HeapInuse,
+			NumGC:      memStats.NumGC,
+		},
+	}
+}
+
+func writeStatus(w http.ResponseWriter, status interface{}) {
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		http.Error(w, "Cannot marshall status to JSON", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
@@ -0,0 +1,82 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestStatusEndpoints(t *testing.T) {
+	cfg := &Builder{
+		Processors: []ProcessorConfiguration{
+			{
+				Model:    jaegerModel,
+				Protocol: compactProtocol,
+				Workers:  2,
+				Server: ServerConfiguration{
+					HostPort:      "127.0.0.1:0",
+					QueueSize:     100,
+					MaxPacketSize: 65000,
+				},
+			},
+		},
+	}
+	cfg.CollectorHostPorts = []string{"127.0.0.1:14267"}
+	agent, err := cfg.CreateAgent(zap.NewNop())
+	require.NoError(t, err)
+
+	get := func(path string, status interface{}) {
+		w := httptest.NewRecorder()
+		agent.httpServer.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), status))
+	}
+
+	var status agentStatus
+	get("/status", &status)
+	require.Len(t, status.Processors, 1)
+	processor := status.Processors[0]
+	assert.Equal(t, jaegerModel, processor.Model)
+	assert.Equal(t, compactProtocol, processor.Protocol)
+	assert.Equal(t, 2, processor.Workers)
+	require.NotNil(t, processor.Server)
+	assert.Equal(t, 100, processor.Server.MaxQueueSize)
+	assert.Equal(t, 65000, processor.Server.MaxPacketSize)
+	assert.Equal(t, tchannelReporterType, status.ReporterType)
+	require.Len(t, status.Collectors, 1)
+	assert.Equal(t, "127.0.0.1:14267", status.Collectors[0].HostPort)
+	assert.Nil(t, status.Submissions.LastSuccess)
+
+	var debug debugStatus
+	get("/debug", &debug)
+	assert.Len(t, debug.Processors, 1)
+	assert.NotEmpty(t, debug.Runtime.GoVersion)
+	assert.NotZero(t, debug.Runtime.Goroutines)
+}
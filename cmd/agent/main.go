@@ -45,7 +45,6 @@ func main() {
 			builder.InitFromViper(v)
 			runtime.GOMAXPROCS(runtime.NumCPU())
 
-			// TODO illustrate discovery service wiring
 			// TODO illustrate additional reporter
 
 			agent, err := builder.CreateAgent(logger)
@@ -22,11 +22,15 @@ package builder
 
 import (
 	"flag"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
 
 	"github.com/uber/jaeger/cmd/collector/app"
+	"github.com/uber/jaeger/cmd/collector/app/sanitizer"
 )
 
 const (
@@ -41,6 +45,22 @@ const (
 	collectorSpanAuthTagKey	 	  = "collector.span-auth-tag-key"
 	collectorAuthManagerCacheSize = "collector.auth-manager-cache-size"
 	collectorAuthManagerCacheTTL  = "collector.auth-manager-cache-ttl"
+	collectorShutdownTimeout      = "collector.shutdown-timeout"
+	collectorMaxOperationNameLen  = "collector.limits.max-operation-name-length"
+	collectorMaxTagValueLen       = "collector.limits.max-tag-value-length"
+	collectorMaxTags              = "collector.limits.max-tags"
+	collectorMaxLogs              = "collector.limits.max-logs"
+	collectorTagsDropPolicy       = "collector.limits.tags-drop-policy"
+	collectorLogsDropPolicy       = "collector.limits.logs-drop-policy"
+	collectorTruncationMarker     = "collector.limits.truncation-marker"
+	collectorTLSPrincipalSource   = "collector.tls.principal-source"
+	collectorFairQueueBy          = "collector.queue.fair-by"
+	collectorFairQueueWeights     = "collector.queue.fair-weights"
+	collectorFairQueueReserved    = "collector.queue.fair-reserved-share"
+	collectorPipelineConfig       = "collector.pipeline-config"
+	collectorSpanMetricsFlush     = "collector.span-metrics-flush-interval"
+	collectorDependencies         = "collector.dependencies.enabled"
+	collectorDependenciesDelay    = "collector.dependencies.delay"
 )
 
 // CollectorOptions holds configuration for collector
@@ -67,6 +87,30 @@ type CollectorOptions struct {
 	AuthenticationManagerCacheSize int
 	// AuthenticationManagerCacheTTL defines the TTL of the auth manager cache items
 	AuthenticationManagerCacheTTL  time.Duration
+	// ShutdownTimeout is how long the collector waits on shutdown for the queued spans to be saved
+	ShutdownTimeout time.Duration
+	// SpanLimits defines the bounds on span sizes, spans over the limits are truncated
+	SpanLimits sanitizer.SpanLimits
+	// TLSPrincipalSource defines which attribute of a verified client certificate identifies the principal
+	// of the spans, empty if client certificates are not used for authentication
+	TLSPrincipalSource string
+	// FairQueueBy enables the fair queueing of spans by service or principal, empty for a FIFO queue
+	FairQueueBy string
+	// FairQueueWeights lists the fair queue weights as comma separated name=weight pairs
+	FairQueueWeights string
+	// FairQueueReservedShare is the fraction of the queue capacity reserved to every service or principal
+	FairQueueReservedShare float64
+	// PipelineConfig is the path of the YAML file declaring the span pipeline stages
+	PipelineConfig string
+	// SpanMetricsFlushInterval is how often the RED metrics aggregated from the spans are written
+	// to the Cassandra storage, 0 disables the metrics
+	SpanMetricsFlushInterval time.Duration
+	// DependenciesEnabled enables the aggregation of the service dependencies from the stored traces
+	// on every dependency-storage.data-frequency, by a single collector at a time
+	DependenciesEnabled bool
+	// DependenciesDelay is how long after the end of a window its dependencies are aggregated,
+	// to leave time for its late spans to be stored
+	DependenciesDelay time.Duration
 }
 
 // AddFlags adds flags for CollectorOptions
@@ -82,6 +126,22 @@ func AddFlags(flags *flag.FlagSet) {
 	flags.String(collectorSpanAuthTagKey, app.DefaultSpanAuthTagKey, "The name of the tag's key associated with password / api token")
 	flags.Int(collectorAuthManagerCacheSize, 1000, "The size of the authentication manager cache")
 	flags.Duration(collectorAuthManagerCacheTTL, time.Second * 3600, "The TTL of the auth manager cache items")
+	flags.Duration(collectorShutdownTimeout, app.DefaultShutdownTimeout, "How long to wait on shutdown for the queued spans to be saved before abandoning them")
+	flags.Int(collectorMaxOperationNameLen, 0, "The maximum length in bytes of span operation names, 0 means unlimited")
+	flags.Int(collectorMaxTagValueLen, 0, "The maximum length in bytes of tag and log field values, 0 means unlimited")
+	flags.Int(collectorMaxTags, 0, "The maximum number of tags per span, 0 means unlimited")
+	flags.Int(collectorMaxLogs, 0, "The maximum number of logs per span, 0 means unlimited")
+	flags.String(collectorTagsDropPolicy, string(sanitizer.KeepFirst), "Which tags to keep when a span has too many: keep-first or keep-last")
+	flags.String(collectorLogsDropPolicy, string(sanitizer.KeepFirst), "Which logs to keep when a span has too many: keep-first or keep-last")
+	flags.String(collectorTruncationMarker, sanitizer.DefaultTruncationMarker, "The marker appended to truncated string values")
+	flags.String(collectorFairQueueBy, "", "Enables fair queueing of spans by service or principal, empty for a FIFO queue")
+	flags.String(collectorFairQueueWeights, "", "The fair queue weights as comma separated name=weight pairs, e.g. frontend=3,backend=2, the default weight is 1")
+	flags.Float64(collectorFairQueueReserved, app.DefaultFairQueueReservedShare, "The fraction of the fair queue capacity reserved to every service or principal")
+	flags.String(collectorPipelineConfig, "", "The path of the YAML file declaring the span pipeline stages, e.g. filters and enrichments")
+	flags.Duration(collectorSpanMetricsFlush, 0, "How often the RED metrics aggregated from the spans are written to Cassandra, 0 disables them")
+	flags.Bool(collectorDependencies, false, "Aggregates the service dependencies from the traces stored in Cassandra or ElasticSearch, instead of an external job")
+	flags.Duration(collectorDependenciesDelay, 5*time.Minute, "How long after the end of a dependency-storage.data-frequency window its dependencies are aggregated")
+	flags.String(collectorTLSPrincipalSource, "", "The attribute of verified client certificates used as the span principal: cn or san, empty to disable")
 }
 
 // InitFromViper initializes CollectorOptions with properties from viper
@@ -97,5 +157,58 @@ func (cOpts *CollectorOptions) InitFromViper(v *viper.Viper) *CollectorOptions {
 	cOpts.SpanAuthTagKey = v.GetString(collectorSpanAuthTagKey)
 	cOpts.AuthenticationManagerCacheSize = v.GetInt(collectorAuthManagerCacheSize)
 	cOpts.AuthenticationManagerCacheTTL = v.GetDuration(collectorAuthManagerCacheTTL)
+	cOpts.ShutdownTimeout = v.GetDuration(collectorShutdownTimeout)
+	cOpts.SpanLimits = sanitizer.SpanLimits{
+		MaxOperationNameLength: v.GetInt(collectorMaxOperationNameLen),
+		MaxTagValueLength:      v.GetInt(collectorMaxTagValueLen),
+		MaxTags:                v.GetInt(collectorMaxTags),
+		MaxLogs:                v.GetInt(collectorMaxLogs),
+		TagsDropPolicy:         sanitizer.DropPolicy(v.GetString(collectorTagsDropPolicy)),
+		LogsDropPolicy:         sanitizer.DropPolicy(v.GetString(collectorLogsDropPolicy)),
+		TruncationMarker:       v.GetString(collectorTruncationMarker),
+	}
+	cOpts.TLSPrincipalSource = v.GetString(collectorTLSPrincipalSource)
+	cOpts.FairQueueBy = v.GetString(collectorFairQueueBy)
+	cOpts.FairQueueWeights = v.GetString(collectorFairQueueWeights)
+	cOpts.FairQueueReservedShare = v.GetFloat64(collectorFairQueueReserved)
+	cOpts.PipelineConfig = v.GetString(collectorPipelineConfig)
+	cOpts.SpanMetricsFlushInterval = v.GetDuration(collectorSpanMetricsFlush)
+	cOpts.DependenciesEnabled = v.GetBool(collectorDependencies)
+	cOpts.DependenciesDelay = v.GetDuration(collectorDependenciesDelay)
 	return cOpts
 }
+
+// fairQueueOptions validates the fair queue flags, it returns nil if fair queueing is disabled
+func (cOpts *CollectorOptions) fairQueueOptions() (*app.FairQueueOptions, error) {
+	switch cOpts.FairQueueBy {
+	case "":
+		return nil, nil
+	case app.FairQueueByService, app.FairQueueByPrincipal:
+	default:
+		return nil, fmt.Errorf("unknown fair queue class %q, expected %q or %q",
+			cOpts.FairQueueBy, app.FairQueueByService, app.FairQueueByPrincipal)
+	}
+	if cOpts.FairQueueReservedShare < 0 || cOpts.FairQueueReservedShare > 1 {
+		return nil, fmt.Errorf("fair queue reserved share must be between 0 and 1, got %v", cOpts.FairQueueReservedShare)
+	}
+	weights := make(map[string]int)
+	for _, pair := range strings.Split(cOpts.FairQueueWeights, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid fair queue weight %q, expected name=weight", pair)
+		}
+		weight, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || weight < 1 {
+			return nil, fmt.Errorf("invalid fair queue weight %q, expected a positive integer", pair)
+		}
+		weights[strings.TrimSpace(parts[0])] = weight
+	}
+	return &app.FairQueueOptions{
+		ClassBy:       cOpts.FairQueueBy,
+		Weights:       weights,
+		ReservedShare: cOpts.FairQueueReservedShare,
+	}, nil
+}
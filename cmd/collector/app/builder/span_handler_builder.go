@@ -22,13 +22,18 @@ package builder
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/uber/jaeger-lib/metrics"
 	"go.uber.org/zap"
 
 	basicB "github.com/uber/jaeger/cmd/builder"
 	"github.com/uber/jaeger/cmd/collector/app"
+	"github.com/uber/jaeger/cmd/collector/app/dependencies"
+	"github.com/uber/jaeger/cmd/collector/app/pipeline"
+	"github.com/uber/jaeger/cmd/collector/app/sanitizer"
 	zs "github.com/uber/jaeger/cmd/collector/app/sanitizer/zipkin"
 	"github.com/uber/jaeger/cmd/flags"
 	"github.com/uber/jaeger/model"
@@ -36,7 +41,12 @@ import (
 	as"github.com/uber/jaeger/security/authenticationstore"
 	cascfg "github.com/uber/jaeger/pkg/cassandra/config"
 	escfg "github.com/uber/jaeger/pkg/es/config"
+	casLock "github.com/uber/jaeger/plugin/pkg/distributedlock/cassandra"
+	esLock "github.com/uber/jaeger/plugin/pkg/distributedlock/es"
+	casDepstore "github.com/uber/jaeger/plugin/storage/cassandra/dependencystore"
+	casMetricstore "github.com/uber/jaeger/plugin/storage/cassandra/metricstore"
 	casSpanstore "github.com/uber/jaeger/plugin/storage/cassandra/spanstore"
+	esDepstore "github.com/uber/jaeger/plugin/storage/es/dependencystore"
 	esSpanstore "github.com/uber/jaeger/plugin/storage/es/spanstore"
 	"github.com/uber/jaeger/storage/spanstore"
 )
@@ -54,19 +64,38 @@ type SpanHandlerBuilder struct {
 	collectorOpts     *CollectorOptions
 	spanWriter        spanstore.Writer
 	spanAuth 		  sec.Authenticator
+	spanProcessor     app.SpanProcessor
+	fairQueue         *app.FairQueueOptions
+	pipeline          *pipeline.Pipeline
+	closeStorage      func()
+	// dependencyDataFrequency is the window of the dependencies aggregated by the collector
+	dependencyDataFrequency time.Duration
 }
 
 // NewSpanHandlerBuilder returns new SpanHandlerBuilder with configured span storage.
 func NewSpanHandlerBuilder(cOpts *CollectorOptions, sFlags *flags.SharedFlags, opts ...basicB.Option) (*SpanHandlerBuilder, error) {
 	options := basicB.ApplyOptions(opts...)
 
+	if err := cOpts.SpanLimits.Validate(); err != nil {
+		return nil, err
+	}
+	fairQueue, err := cOpts.fairQueueOptions()
+	if err != nil {
+		return nil, err
+	}
 	spanHb := &SpanHandlerBuilder{
 		collectorOpts:  cOpts,
 		logger:         options.Logger,
 		metricsFactory: options.MetricsFactory,
+		fairQueue:      fairQueue,
+		dependencyDataFrequency: sFlags.DependencyStorage.DataFrequency,
+	}
+	if cOpts.PipelineConfig != "" {
+		if spanHb.pipeline, err = spanHb.initPipeline(cOpts.PipelineConfig); err != nil {
+			return nil, err
+		}
 	}
 
-	var err error
 	if sFlags.SpanStorage.Type == flags.CassandraStorageType {
 		if options.CassandraSessionBuilder == nil {
 			return nil, errMissingCassandraConfig
@@ -105,18 +134,58 @@ func NewSpanHandlerBuilder(cOpts *CollectorOptions, sFlags *flags.SharedFlags, o
 	return spanHb, nil
 }
 
+func (spanHb *SpanHandlerBuilder) initPipeline(path string) (*pipeline.Pipeline, error) {
+	cfg, err := pipeline.LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return pipeline.New(cfg, pipeline.DefaultRegistry, spanHb.metricsFactory, spanHb.logger)
+}
+
 func (spanHb *SpanHandlerBuilder) initCassStore(builder cascfg.SessionBuilder) (spanstore.Writer, error) {
 	session, err := builder.NewSession()
 	if err != nil {
 		return nil, err
 	}
+	spanHb.closeStorage = session.Close
+	if spanHb.collectorOpts.DependenciesEnabled {
+		depsMetricsFactory := spanHb.metricsFactory.Namespace("dependencies", nil)
+		aggregator := dependencies.NewAggregator(
+			casSpanstore.NewSpanReader(session, depsMetricsFactory, spanHb.logger),
+			casDepstore.NewDependencyStore(session, spanHb.dependencyDataFrequency, depsMetricsFactory, spanHb.logger),
+			casLock.NewLock(session, lockOwner()),
+			spanHb.dependencyDataFrequency,
+			spanHb.collectorOpts.DependenciesDelay,
+			spanHb.logger,
+		)
+		spanHb.closeStorage = func() {
+			aggregator.Close()
+			session.Close()
+		}
+	}
 
-	return casSpanstore.NewSpanWriter(
+	spanWriter := casSpanstore.NewSpanWriter(
 		session,
 		spanHb.collectorOpts.WriteCacheTTL,
 		spanHb.metricsFactory,
 		spanHb.logger,
-	), nil
+	)
+	if spanHb.collectorOpts.SpanMetricsFlushInterval <= 0 {
+		return spanWriter, nil
+	}
+	metricWriter := casMetricstore.NewMetricWriter(
+		session,
+		spanHb.collectorOpts.SpanMetricsFlushInterval,
+		spanHb.metricsFactory,
+		spanHb.logger,
+	)
+	closeSession := spanHb.closeStorage
+	spanHb.closeStorage = func() {
+		// the pending metrics are flushed before the session is closed
+		metricWriter.Close()
+		closeSession()
+	}
+	return spanstore.NewMultiplexWriter(spanWriter, metricWriter), nil
 }
 
 func (spanHb *SpanHandlerBuilder) initElasticStore(esBuilder escfg.ClientBuilder) (spanstore.Writer, error) {
@@ -124,6 +193,31 @@ func (spanHb *SpanHandlerBuilder) initElasticStore(esBuilder escfg.ClientBuilder
 	if err != nil {
 		return nil, err
 	}
+	spanHb.closeStorage = func() {
+		if err := client.Close(); err != nil {
+			spanHb.logger.Error("Failed to close ElasticSearch client", zap.Error(err))
+		}
+	}
+	if spanHb.collectorOpts.DependenciesEnabled {
+		aggregator := dependencies.NewAggregator(
+			esSpanstore.NewSpanReader(
+				client,
+				spanHb.logger,
+				esBuilder.GetMaxSpanAge(),
+				spanHb.metricsFactory.Namespace("dependencies", nil),
+			),
+			esDepstore.NewDependencyStore(client, spanHb.logger),
+			esLock.NewLock(client, lockOwner()),
+			spanHb.dependencyDataFrequency,
+			spanHb.collectorOpts.DependenciesDelay,
+			spanHb.logger,
+		)
+		closeClient := spanHb.closeStorage
+		spanHb.closeStorage = func() {
+			aggregator.Close()
+			closeClient()
+		}
+	}
 
 	return esSpanstore.NewSpanWriter(
 		client,
@@ -146,20 +240,67 @@ func (spanHb *SpanHandlerBuilder) BuildHandlers() (app.ZipkinSpansHandler, app.J
 		zs.NewErrorTagSanitizer(),
 	)
 
-	spanProcessor := app.NewSpanProcessor(
-		spanHb.spanWriter,
+	spanProcessorOpts := []app.Option{
 		app.Options.ServiceMetrics(spanHb.metricsFactory),
 		app.Options.HostMetrics(hostMetrics),
 		app.Options.Logger(spanHb.logger),
 		app.Options.SpanFilter(spanHb.defaultSpanFilter),
 		app.Options.NumWorkers(spanHb.collectorOpts.NumWorkers),
 		app.Options.QueueSize(spanHb.collectorOpts.QueueSize),
-	)
+	}
+	if spanHb.collectorOpts.SpanLimits.Enabled() {
+		spanProcessorOpts = append(spanProcessorOpts, app.Options.Sanitizer(
+			sanitizer.NewLimitsSanitizer(
+				spanHb.collectorOpts.SpanLimits,
+				spanHb.metricsFactory.Namespace("limits", nil),
+			),
+		))
+	}
+
+	if spanHb.pipeline != nil {
+		spanProcessorOpts = append(spanProcessorOpts, app.Options.Pipeline(spanHb.pipeline))
+	}
+	if spanHb.fairQueue != nil {
+		spanProcessorOpts = append(spanProcessorOpts, app.Options.FairQueue(*spanHb.fairQueue))
+	}
+
+	spanProcessor := app.NewSpanProcessor(spanHb.spanWriter, spanProcessorOpts...)
+	spanHb.spanProcessor = spanProcessor
 
 	return app.NewZipkinSpanHandler(spanHb.logger, spanProcessor, zSanitizer),
 		app.NewJaegerSpanHandler(spanHb.logger, spanProcessor)
 }
 
+// Close drains the span processor queue, waiting no longer than the given timeout, and then closes
+// the pipeline stages and the storage clients. It returns the number of spans abandoned at the deadline,
+// in which case the pipeline and the storage are left open for the consumers still saving spans.
+func (spanHb *SpanHandlerBuilder) Close(timeout time.Duration) int {
+	abandoned := 0
+	if spanHb.spanProcessor != nil {
+		abandoned = spanHb.spanProcessor.Drain(timeout)
+	}
+	if abandoned > 0 {
+		spanHb.logger.Warn("Spans are still being processed, not closing the span storage",
+			zap.Int("abandoned", abandoned))
+		return abandoned
+	}
+	if spanHb.pipeline != nil {
+		if err := spanHb.pipeline.Close(); err != nil {
+			spanHb.logger.Error("Failed to close the span pipeline", zap.Error(err))
+		}
+	}
+	if spanHb.closeStorage != nil {
+		spanHb.closeStorage()
+	}
+	return abandoned
+}
+
+// lockOwner identifies this collector process in the leases of the distributed lock
+func lockOwner() string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d", hostname, os.Getpid())
+}
+
 func (spanHb *SpanHandlerBuilder) defaultSpanFilter(span *model.Span) bool {
 	if spanHb.collectorOpts.AuthSpan {
 		token := spanHb.spanAuth.TokenFromSpan(span)
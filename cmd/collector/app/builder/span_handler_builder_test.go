@@ -21,7 +21,10 @@
 package builder
 
 import (
+	"io/ioutil"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -29,6 +32,8 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/uber/jaeger/cmd/builder"
+	"github.com/uber/jaeger/cmd/collector/app"
+	"github.com/uber/jaeger/cmd/collector/app/sanitizer"
 	"github.com/uber/jaeger/cmd/flags"
 	"github.com/uber/jaeger/pkg/cassandra"
 	cascfg "github.com/uber/jaeger/pkg/cassandra/config"
@@ -37,6 +42,7 @@ import (
 	"github.com/uber/jaeger/pkg/es"
 	escfg "github.com/uber/jaeger/pkg/es/config"
 	esMocks "github.com/uber/jaeger/pkg/es/mocks"
+	"github.com/uber/jaeger/storage/spanstore"
 	"github.com/uber/jaeger/storage/spanstore/memory"
 )
 
@@ -47,12 +53,21 @@ func (*mockSessionBuilder) NewSession() (cassandra.Session, error) {
 	return &mocks.Session{}, nil
 }
 
+type sessionBuilder struct {
+	session cassandra.Session
+}
+
+func (b *sessionBuilder) NewSession() (cassandra.Session, error) {
+	return b.session, nil
+}
+
 type mockEsBuilder struct {
 	escfg.Configuration
+	client esMocks.Client
 }
 
 func (mck *mockEsBuilder) NewClient() (es.Client, error) {
-	return &esMocks.Client{}, nil
+	return &mck.client, nil
 }
 
 func TestNewSpanHandlerBuilder(t *testing.T) {
@@ -77,6 +92,55 @@ func TestNewSpanHandlerBuilder(t *testing.T) {
 	assert.True(t, handler.defaultSpanFilter(nil))
 }
 
+func TestNewSpanHandlerBuilderCassandraWithSpanMetrics(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--collector.span-metrics-flush-interval=1m"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, time.Minute, cOpts.SpanMetricsFlushInterval)
+
+	session := &mocks.Session{}
+	session.On("Close").Return()
+	handler, err := NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.LoggerOption(zap.NewNop()),
+		builder.Options.MetricsFactoryOption(metrics.NullFactory),
+		builder.Options.CassandraSessionOption(&sessionBuilder{session: session}),
+	)
+	require.NoError(t, err)
+	assert.IsType(t, &spanstore.MultiplexWriter{}, handler.spanWriter)
+	handler.BuildHandlers()
+
+	assert.Equal(t, 0, handler.Close(cOpts.ShutdownTimeout))
+	session.AssertCalled(t, "Close")
+}
+
+func TestNewSpanHandlerBuilderCassandraWithDependencies(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--collector.dependencies.enabled=true", "--collector.dependencies.delay=1m"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.True(t, cOpts.DependenciesEnabled)
+	assert.Equal(t, time.Minute, cOpts.DependenciesDelay)
+
+	session := &mocks.Session{}
+	session.On("Close").Return()
+	handler, err := NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.LoggerOption(zap.NewNop()),
+		builder.Options.MetricsFactoryOption(metrics.NullFactory),
+		builder.Options.CassandraSessionOption(&sessionBuilder{session: session}),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, sFlags.DependencyStorage.DataFrequency, handler.dependencyDataFrequency)
+	handler.BuildHandlers()
+
+	assert.Equal(t, 0, handler.Close(cOpts.ShutdownTimeout))
+	session.AssertCalled(t, "Close")
+}
+
 func TestNewSpanHandlerBuilderCassandraNoSession(t *testing.T) {
 	v, command := config.Viperize(flags.AddFlags)
 
@@ -185,3 +249,148 @@ func TestNewSpanHandlerBuilderElasticSearchFailure(t *testing.T) {
 	assert.EqualError(t, err, "ElasticSearch not configured")
 	assert.Nil(t, handler)
 }
+
+func TestNewSpanHandlerBuilderWithSpanLimits(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.limits.max-tags=10", "--collector.limits.logs-drop-policy=keep-last"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, 10, cOpts.SpanLimits.MaxTags)
+	assert.Equal(t, sanitizer.KeepLast, cOpts.SpanLimits.LogsDropPolicy)
+	assert.True(t, cOpts.SpanLimits.Enabled())
+
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+	require.NoError(t, err)
+	zHandler, jHandler := handler.BuildHandlers()
+	assert.NotNil(t, zHandler)
+	assert.NotNil(t, jHandler)
+}
+
+func TestNewSpanHandlerBuilderWithFairQueue(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.queue.fair-by=service", "--collector.queue.fair-weights=frontend=3, backend=2"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+	require.NoError(t, err)
+	assert.Equal(t, &app.FairQueueOptions{
+		ClassBy:       app.FairQueueByService,
+		Weights:       map[string]int{"frontend": 3, "backend": 2},
+		ReservedShare: app.DefaultFairQueueReservedShare,
+	}, handler.fairQueue)
+	zHandler, jHandler := handler.BuildHandlers()
+	assert.NotNil(t, zHandler)
+	assert.NotNil(t, jHandler)
+}
+
+func TestNewSpanHandlerBuilderFairQueueErrors(t *testing.T) {
+	testCases := []struct {
+		flag        string
+		expectedErr string
+	}{
+		{flag: "--collector.queue.fair-by=tenant", expectedErr: `unknown fair queue class "tenant", expected "service" or "principal"`},
+		{flag: "--collector.queue.fair-reserved-share=2", expectedErr: "fair queue reserved share must be between 0 and 1, got 2"},
+		{flag: "--collector.queue.fair-weights=frontend", expectedErr: `invalid fair queue weight "frontend", expected name=weight`},
+		{flag: "--collector.queue.fair-weights=frontend=0", expectedErr: `invalid fair queue weight "frontend=0", expected a positive integer`},
+	}
+	for _, testCase := range testCases {
+		v, command := config.Viperize(AddFlags, flags.AddFlags)
+		command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.queue.fair-by=service", testCase.flag})
+		sFlags := new(flags.SharedFlags).InitFromViper(v)
+		cOpts := new(CollectorOptions).InitFromViper(v)
+
+		_, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+		assert.EqualError(t, err, testCase.expectedErr)
+	}
+}
+
+func TestNewSpanHandlerBuilderDropPolicyErrors(t *testing.T) {
+	testCases := []struct {
+		flag        string
+		expectedErr string
+	}{
+		{flag: "--collector.limits.tags-drop-policy=keep-middle", expectedErr: `unknown drop policy "keep-middle", expected "keep-first" or "keep-last"`},
+		{flag: "--collector.limits.logs-drop-policy=last", expectedErr: `unknown drop policy "last", expected "keep-first" or "keep-last"`},
+	}
+	for _, testCase := range testCases {
+		v, command := config.Viperize(AddFlags, flags.AddFlags)
+		command.ParseFlags([]string{"test", "--span-storage.type=memory", testCase.flag})
+		sFlags := new(flags.SharedFlags).InitFromViper(v)
+		cOpts := new(CollectorOptions).InitFromViper(v)
+
+		_, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+		assert.EqualError(t, err, testCase.expectedErr)
+	}
+}
+
+func TestNewSpanHandlerBuilderWithPipeline(t *testing.T) {
+	f, err := ioutil.TempFile("", "pipeline")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("stages: [{type: tag-filter, params: {key: http.url, values: [/health]}}]")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.pipeline-config=" + f.Name()})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+	require.NoError(t, err)
+	require.NotNil(t, handler.pipeline)
+	assert.Equal(t, 1, handler.pipeline.Len())
+	handler.BuildHandlers()
+	assert.Equal(t, 0, handler.Close(time.Second))
+
+	command.ParseFlags([]string{"test", "--collector.pipeline-config=" + f.Name() + ".missing"})
+	_, err = NewSpanHandlerBuilder(new(CollectorOptions).InitFromViper(v), sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+	assert.Error(t, err)
+}
+
+func TestSpanHandlerBuilderClose(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=elasticsearch", "--collector.shutdown-timeout=1s"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, time.Second, cOpts.ShutdownTimeout)
+
+	esBuilder := &mockEsBuilder{}
+	handler, err := NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.LoggerOption(zap.NewNop()),
+		builder.Options.ElasticClientOption(esBuilder),
+	)
+	require.NoError(t, err)
+	handler.BuildHandlers()
+
+	esBuilder.client.On("Close").Return(nil)
+	assert.Equal(t, 0, handler.Close(cOpts.ShutdownTimeout))
+	esBuilder.client.AssertExpectations(t)
+}
+
+type drainingProcessor struct {
+	app.SpanProcessor
+	abandoned int
+}
+
+func (p drainingProcessor) Drain(timeout time.Duration) int {
+	return p.abandoned
+}
+
+func TestSpanHandlerBuilderCloseAbandoned(t *testing.T) {
+	closed := false
+	handler := &SpanHandlerBuilder{
+		logger:        zap.NewNop(),
+		spanProcessor: drainingProcessor{abandoned: 2},
+		closeStorage:  func() { closed = true },
+	}
+	assert.Equal(t, 2, handler.Close(time.Second))
+	assert.False(t, closed, "the storage must stay open for the spans still being saved")
+
+	handler.spanProcessor = drainingProcessor{}
+	assert.Equal(t, 0, handler.Close(time.Second))
+	assert.True(t, closed)
+}
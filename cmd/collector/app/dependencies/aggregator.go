@@ -0,0 +1,206 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dependencies
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/model/analysis"
+	"github.com/uber/jaeger/pkg/distributedlock"
+	"github.com/uber/jaeger/storage/dependencystore"
+	"github.com/uber/jaeger/storage/spanstore"
+)
+
+const (
+	// lockResource is the lock held by the collector that aggregates the dependencies of a window
+	lockResource = "dependencies"
+
+	tracesPageSize = 500
+)
+
+// Aggregator periodically computes the service dependencies from the traces stored during the last window
+// of the dependencies data frequency, and writes them to the dependencies storage. Every collector runs an
+// Aggregator, the distributed lock ensures that only one of them computes each window.
+type Aggregator struct {
+	spanReader       spanstore.Reader
+	dependencyWriter dependencystore.Writer
+	lock             distributedlock.Lock
+	frequency        time.Duration
+	delay            time.Duration
+	logger           *zap.Logger
+	timeNow          func() time.Time
+
+	stop    chan struct{}
+	stopped sync.WaitGroup
+}
+
+// NewAggregator returns an Aggregator that runs delay after the end of every window of the given frequency,
+// so that the late spans of the window are stored by then
+func NewAggregator(
+	spanReader spanstore.Reader,
+	dependencyWriter dependencystore.Writer,
+	lock distributedlock.Lock,
+	frequency time.Duration,
+	delay time.Duration,
+	logger *zap.Logger,
+) *Aggregator {
+	a := newAggregator(spanReader, dependencyWriter, lock, frequency, delay, logger)
+	a.stopped.Add(1)
+	go a.aggregatePeriodically()
+	return a
+}
+
+func newAggregator(
+	spanReader spanstore.Reader,
+	dependencyWriter dependencystore.Writer,
+	lock distributedlock.Lock,
+	frequency time.Duration,
+	delay time.Duration,
+	logger *zap.Logger,
+) *Aggregator {
+	return &Aggregator{
+		spanReader:       spanReader,
+		dependencyWriter: dependencyWriter,
+		lock:             lock,
+		frequency:        frequency,
+		delay:            delay,
+		logger:           logger,
+		timeNow:          time.Now,
+		stop:             make(chan struct{}),
+	}
+}
+
+// Close stops the Aggregator, waiting for a running aggregation to complete
+func (a *Aggregator) Close() error {
+	close(a.stop)
+	a.stopped.Wait()
+	return nil
+}
+
+// Aggregate computes the dependencies of the traces that started in the window of the data frequency
+// ending at end, and writes them with the start of the window as timestamp. It does nothing if another
+// collector holds the lock.
+func (a *Aggregator) Aggregate(end time.Time) error {
+	// the lease outlives the aggregation runs of the other collectors for this window, whose timers can fire
+	// a little later, but expires well before the next window, so a collector that stopped is replaced then
+	acquired, err := a.lock.Acquire(lockResource, a.frequency/2)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		a.logger.Debug("The dependencies are aggregated by another collector", zap.Time("end", end))
+		return nil
+	}
+	start := end.Add(-a.frequency)
+	links, numTraces, err := a.countLinks(start, end)
+	if err != nil {
+		return err
+	}
+	dependencies := links.Links()
+	if err := a.dependencyWriter.WriteDependencies(start, dependencies); err != nil {
+		return err
+	}
+	a.logger.Info("Aggregated the dependencies",
+		zap.Time("start", start),
+		zap.Time("end", end),
+		zap.Int("traces", numTraces),
+		zap.Int("dependencies", len(dependencies)))
+	return nil
+}
+
+// countLinks counts the calls between services in the traces of all the services whose first span started
+// in [start, end), so that a trace spanning two windows is only counted once. The traces are counted page
+// by page, only their IDs are kept for the whole window.
+func (a *Aggregator) countLinks(start, end time.Time) (*analysis.DependencyLinkCounter, int, error) {
+	services, err := a.spanReader.GetServices()
+	if err != nil {
+		return nil, 0, err
+	}
+	found := make(map[model.TraceID]bool)
+	links := analysis.NewDependencyLinkCounter()
+	numTraces := 0
+	for _, service := range services {
+		query := &spanstore.TraceQueryParameters{
+			ServiceName:  service,
+			StartTimeMin: start,
+			StartTimeMax: end,
+			NumTraces:    tracesPageSize,
+		}
+		for {
+			page, err := a.spanReader.FindTracePage(query)
+			if err != nil {
+				return nil, 0, err
+			}
+			for _, trace := range page.Traces {
+				if len(trace.Spans) == 0 || found[trace.Spans[0].TraceID] {
+					continue
+				}
+				found[trace.Spans[0].TraceID] = true
+				if traceStart := startTime(trace); !traceStart.Before(start) && traceStart.Before(end) {
+					links.AddTrace(trace)
+					numTraces++
+				}
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			query.Cursor = page.NextCursor
+		}
+	}
+	return links, numTraces, nil
+}
+
+func startTime(trace *model.Trace) time.Time {
+	start := trace.Spans[0].StartTime
+	for _, span := range trace.Spans[1:] {
+		if span.StartTime.Before(start) {
+			start = span.StartTime
+		}
+	}
+	return start
+}
+
+// nextRun returns the time after now when the last window is complete and its late spans are stored
+func (a *Aggregator) nextRun(now time.Time) time.Time {
+	return now.Add(-a.delay).Truncate(a.frequency).Add(a.frequency + a.delay)
+}
+
+func (a *Aggregator) aggregatePeriodically() {
+	defer a.stopped.Done()
+	for {
+		next := a.nextRun(a.timeNow())
+		timer := time.NewTimer(next.Sub(a.timeNow()))
+		select {
+		case <-timer.C:
+			if err := a.Aggregate(next.Add(-a.delay)); err != nil {
+				a.logger.Error("Failed to aggregate the dependencies", zap.Error(err))
+			}
+		case <-a.stop:
+			timer.Stop()
+			return
+		}
+	}
+}
@@ -0,0 +1,269 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dependencies
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/distributedlock"
+	lockMocks "github.com/uber/jaeger/pkg/distributedlock/mocks"
+	depMocks "github.com/uber/jaeger/storage/dependencystore/mocks"
+	"github.com/uber/jaeger/storage/spanstore"
+	spanMocks "github.com/uber/jaeger/storage/spanstore/mocks"
+)
+
+var (
+	windowEnd   = time.Date(2017, time.October, 10, 12, 0, 0, 0, time.UTC)
+	windowStart = windowEnd.Add(-time.Hour)
+)
+
+type aggregatorTest struct {
+	spanReader       *spanMocks.Reader
+	dependencyWriter *depMocks.Writer
+	lock             *lockMocks.Lock
+	aggregator       *Aggregator
+}
+
+func withAggregator(fn func(a *aggregatorTest)) {
+	a := &aggregatorTest{
+		spanReader:       &spanMocks.Reader{},
+		dependencyWriter: &depMocks.Writer{},
+		lock:             &lockMocks.Lock{},
+	}
+	a.aggregator = newAggregator(a.spanReader, a.dependencyWriter, a.lock, time.Hour, 5*time.Minute, zap.NewNop())
+	fn(a)
+}
+
+// newTrace returns a trace of a frontend span calling a backend span, starting at the given offset from windowStart
+func newTrace(traceID uint64, start time.Duration, isError bool) *model.Trace {
+	child := &model.Span{
+		TraceID:      model.TraceID{Low: traceID},
+		SpanID:       2,
+		ParentSpanID: 1,
+		StartTime:    windowStart.Add(start + time.Millisecond),
+		Process:      &model.Process{ServiceName: "backend"},
+	}
+	if isError {
+		child.Tags = model.KeyValues{model.Bool("error", true)}
+	}
+	return &model.Trace{Spans: []*model.Span{
+		child,
+		{
+			TraceID:   model.TraceID{Low: traceID},
+			SpanID:    1,
+			StartTime: windowStart.Add(start),
+			Process:   &model.Process{ServiceName: "frontend"},
+		},
+	}}
+}
+
+func pageQuery(service, cursor string) *spanstore.TraceQueryParameters {
+	return &spanstore.TraceQueryParameters{
+		ServiceName:  service,
+		StartTimeMin: windowStart,
+		StartTimeMax: windowEnd,
+		NumTraces:    tracesPageSize,
+		Cursor:       cursor,
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	withAggregator(func(a *aggregatorTest) {
+		a.lock.On("Acquire", lockResource, 30*time.Minute).Return(true, nil)
+		a.spanReader.On("GetServices").Return([]string{"backend", "frontend"}, nil)
+		a.spanReader.On("FindTracePage", pageQuery("backend", "")).Return(&spanstore.TracePage{
+			Traces: []*model.Trace{
+				newTrace(1, time.Minute, false),
+				newTrace(2, -time.Minute, false), // started in the previous window
+			},
+			NextCursor: "page2",
+		}, nil)
+		a.spanReader.On("FindTracePage", pageQuery("backend", "page2")).Return(&spanstore.TracePage{
+			Traces: []*model.Trace{newTrace(3, 2*time.Minute, true)},
+		}, nil)
+		a.spanReader.On("FindTracePage", pageQuery("frontend", "")).Return(&spanstore.TracePage{
+			Traces: []*model.Trace{
+				newTrace(1, time.Minute, false), // already found for the backend
+				newTrace(4, time.Hour, false),   // started in the next window
+				{},
+			},
+		}, nil)
+		a.dependencyWriter.On("WriteDependencies", windowStart, []model.DependencyLink{
+			{Parent: "frontend", Child: "backend", CallCount: 2, ErrorCount: 1},
+		}).Return(nil)
+
+		require.NoError(t, a.aggregator.Aggregate(windowEnd))
+		a.dependencyWriter.AssertExpectations(t)
+	})
+}
+
+func TestAggregateNotAcquired(t *testing.T) {
+	withAggregator(func(a *aggregatorTest) {
+		a.lock.On("Acquire", lockResource, 30*time.Minute).Return(false, nil)
+
+		require.NoError(t, a.aggregator.Aggregate(windowEnd))
+		a.spanReader.AssertNotCalled(t, "GetServices")
+		a.dependencyWriter.AssertNotCalled(t, "WriteDependencies", mock.Anything, mock.Anything)
+	})
+}
+
+func TestAggregateErrors(t *testing.T) {
+	testCases := []struct {
+		caption       string
+		lockError     error
+		servicesError error
+		findError     error
+		writeError    error
+		expectedError string
+	}{
+		{
+			caption:       "lock error",
+			lockError:     errors.New("lock failed"),
+			expectedError: "lock failed",
+		},
+		{
+			caption:       "services error",
+			servicesError: errors.New("services failed"),
+			expectedError: "services failed",
+		},
+		{
+			caption:       "find error",
+			findError:     errors.New("find failed"),
+			expectedError: "find failed",
+		},
+		{
+			caption:       "write error",
+			writeError:    errors.New("write failed"),
+			expectedError: "write failed",
+		},
+	}
+	for _, tc := range testCases {
+		testCase := tc // capture loop var
+		t.Run(testCase.caption, func(t *testing.T) {
+			withAggregator(func(a *aggregatorTest) {
+				a.lock.On("Acquire", lockResource, 30*time.Minute).Return(true, testCase.lockError)
+				a.spanReader.On("GetServices").Return([]string{"frontend"}, testCase.servicesError)
+				a.spanReader.On("FindTracePage", mock.Anything).Return(&spanstore.TracePage{}, testCase.findError)
+				a.dependencyWriter.On("WriteDependencies", windowStart, []model.DependencyLink{}).Return(testCase.writeError)
+
+				assert.EqualError(t, a.aggregator.Aggregate(windowEnd), testCase.expectedError)
+			})
+		})
+	}
+}
+
+// leases is an in-memory distributed lock, whose leases expire at the time set by the test
+type leases struct {
+	now     time.Time
+	owners  map[string]string
+	expires map[string]time.Time
+}
+
+type leaseLock struct {
+	leases *leases
+	owner  string
+}
+
+func (l *leases) lock(owner string) distributedlock.Lock {
+	return &leaseLock{leases: l, owner: owner}
+}
+
+func (l *leaseLock) Acquire(resource string, ttl time.Duration) (bool, error) {
+	owner, ok := l.leases.owners[resource]
+	if ok && owner != l.owner && l.leases.now.Before(l.leases.expires[resource]) {
+		return false, nil
+	}
+	l.leases.owners[resource] = l.owner
+	l.leases.expires[resource] = l.leases.now.Add(ttl)
+	return true, nil
+}
+
+func (l *leaseLock) Forfeit(resource string) (bool, error) {
+	if l.leases.owners[resource] != l.owner {
+		return false, nil
+	}
+	delete(l.leases.owners, resource)
+	return true, nil
+}
+
+func TestAggregateHandover(t *testing.T) {
+	spanReader := &spanMocks.Reader{}
+	spanReader.On("GetServices").Return([]string{}, nil)
+	dependencyWriter := &depMocks.Writer{}
+	dependencyWriter.On("WriteDependencies", mock.Anything, []model.DependencyLink{}).Return(nil)
+	leases := &leases{owners: map[string]string{}, expires: map[string]time.Time{}}
+	delay := 5 * time.Minute
+	first := newAggregator(spanReader, dependencyWriter, leases.lock("first"), time.Hour, delay, zap.NewNop())
+	second := newAggregator(spanReader, dependencyWriter, leases.lock("second"), time.Hour, delay, zap.NewNop())
+
+	leases.now = windowEnd.Add(delay)
+	require.NoError(t, first.Aggregate(windowEnd))
+	leases.now = leases.now.Add(time.Second)
+	require.NoError(t, second.Aggregate(windowEnd))
+	dependencyWriter.AssertNumberOfCalls(t, "WriteDependencies", 1)
+	dependencyWriter.AssertCalled(t, "WriteDependencies", windowStart, []model.DependencyLink{})
+
+	// the first collector stopped, the second one aggregates the next window even if its timer fires early
+	nextWindowEnd := windowEnd.Add(time.Hour)
+	leases.now = nextWindowEnd.Add(delay - time.Second)
+	require.NoError(t, second.Aggregate(nextWindowEnd))
+	dependencyWriter.AssertNumberOfCalls(t, "WriteDependencies", 2)
+	dependencyWriter.AssertCalled(t, "WriteDependencies", windowEnd, []model.DependencyLink{})
+}
+
+func TestNextRun(t *testing.T) {
+	withAggregator(func(a *aggregatorTest) {
+		assert.Equal(t, windowEnd.Add(5*time.Minute), a.aggregator.nextRun(windowEnd))
+		assert.Equal(t, windowEnd.Add(5*time.Minute), a.aggregator.nextRun(windowEnd.Add(5*time.Minute-time.Nanosecond)))
+		assert.Equal(t, windowEnd.Add(time.Hour+5*time.Minute), a.aggregator.nextRun(windowEnd.Add(5*time.Minute)))
+		assert.Equal(t, windowEnd.Add(5*time.Minute), a.aggregator.nextRun(windowStart.Add(5*time.Minute)))
+	})
+}
+
+func TestAggregatePeriodically(t *testing.T) {
+	spanReader := &spanMocks.Reader{}
+	dependencyWriter := &depMocks.Writer{}
+	lock := &lockMocks.Lock{}
+	acquired := make(chan struct{}, 1)
+	lock.On("Acquire", lockResource, 5*time.Millisecond).Return(false, nil).Run(func(mock.Arguments) {
+		select {
+		case acquired <- struct{}{}:
+		default:
+		}
+	})
+
+	aggregator := NewAggregator(spanReader, dependencyWriter, lock, 10*time.Millisecond, 0, zap.NewNop())
+	select {
+	case <-acquired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the dependencies were not aggregated")
+	}
+	require.NoError(t, aggregator.Close())
+}
@@ -21,11 +21,14 @@
 package app
 
 import (
+	"time"
+
 	"go.uber.org/zap"
 
 	"github.com/uber/jaeger-lib/metrics"
 	"github.com/uber/jaeger/model"
 
+	"github.com/uber/jaeger/cmd/collector/app/pipeline"
 	"github.com/uber/jaeger/cmd/collector/app/sanitizer"
 )
 
@@ -34,6 +37,19 @@ const (
 	DefaultNumWorkers = 50
 	// DefaultQueueSize is the size of the processor's queue
 	DefaultQueueSize = 2000
+	// DefaultShutdownTimeout is how long the collector waits on shutdown for the processor queue to drain
+	DefaultShutdownTimeout = 10 * time.Second
+	// DefaultFairQueueReservedShare is the default fraction of the fair queue capacity reserved to every class
+	DefaultFairQueueReservedShare = 0.01
+	// FairQueueByService puts the spans of every service in their own sub-queue
+	FairQueueByService = "service"
+	// FairQueueByPrincipal puts the spans of every principal authenticated by a client certificate in
+	// their own sub-queue, the spans without a principal are queued by service
+	FairQueueByPrincipal = "principal"
+
+	serviceClassPrefix   = FairQueueByService + ":"
+	principalClassPrefix = FairQueueByPrincipal + ":"
+
 	// DefaultSpanAuthTagKey is the default name of the tag's key used to lookup for the authentication principal
 	DefaultSpanAuthTagKey = "api-token"
 )
@@ -45,12 +61,24 @@ type options struct {
 	preProcessSpans  ProcessSpans
 	sanitizer        sanitizer.SanitizeSpan
 	preSave          ProcessSpan
+	pipeline         *pipeline.Pipeline
 	spanFilter       FilterSpan
 	numWorkers       int
 	blockingSubmit   bool
 	queueSize        int
 	reportBusy       bool
 	extraFormatTypes []string
+	fairQueue        *FairQueueOptions
+}
+
+// FairQueueOptions configures the fair queueing of spans, see queue.FairQueue
+type FairQueueOptions struct {
+	// ClassBy defines how spans are split into sub-queues, FairQueueByService or FairQueueByPrincipal
+	ClassBy string
+	// Weights defines how many spans of a service or principal are processed in each round, 1 by default
+	Weights map[string]int
+	// ReservedShare is the fraction of the queue capacity reserved to every service or principal
+	ReservedShare float64
 }
 
 // Option is a function that sets some option on StorageBuilder.
@@ -101,6 +129,13 @@ func (options) PreSave(preSave ProcessSpan) Option {
 	}
 }
 
+// Pipeline creates an Option that initializes the configured pipeline, whose stages run after the sanitizer and before preSave
+func (options) Pipeline(pipeline *pipeline.Pipeline) Option {
+	return func(b *options) {
+		b.pipeline = pipeline
+	}
+}
+
 // SpanFilter creates an Option that initializes the spanFilter function
 func (options) SpanFilter(spanFilter FilterSpan) Option {
 	return func(b *options) {
@@ -143,6 +178,13 @@ func (options) ExtraFormatTypes(extraFormatTypes []string) Option {
 	}
 }
 
+// FairQueue creates an Option that replaces the FIFO queue with a fair queue
+func (options) FairQueue(fairQueue FairQueueOptions) Option {
+	return func(b *options) {
+		b.fairQueue = &fairQueue
+	}
+}
+
 func (o options) apply(opts ...Option) options {
 	ret := options{}
 	for _, opt := range opts {
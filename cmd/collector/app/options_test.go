@@ -27,6 +27,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/uber/jaeger-lib/metrics"
+	"github.com/uber/jaeger/cmd/collector/app/pipeline"
 	"github.com/uber/jaeger/model"
 )
 
@@ -45,9 +46,13 @@ func TestAllOptionSet(t *testing.T) {
 		Options.Sanitizer(func(span *model.Span) *model.Span { return span }),
 		Options.QueueSize(10),
 		Options.PreSave(func(span *model.Span) {}),
+		Options.Pipeline(pipeline.NewEmpty(zap.NewNop())),
+		Options.FairQueue(FairQueueOptions{ClassBy: FairQueueByService}),
 	)
 	assert.EqualValues(t, 5, opts.numWorkers)
 	assert.EqualValues(t, 10, opts.queueSize)
+	assert.NotNil(t, opts.pipeline)
+	assert.Equal(t, FairQueueByService, opts.fairQueue.ClassBy)
 }
 
 func TestNoOptionsSet(t *testing.T) {
@@ -61,4 +66,6 @@ func TestNoOptionsSet(t *testing.T) {
 	assert.True(t, opts.spanFilter(nil))
 	span := model.Span{}
 	assert.EqualValues(t, &span, opts.sanitizer(&span))
+	assert.Nil(t, opts.pipeline)
+	assert.Nil(t, opts.fairQueue)
 }
@@ -0,0 +1,167 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pipeline
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ErrorPolicy defines what happens to a span when a stage returns an error
+type ErrorPolicy string
+
+const (
+	// DropOnError drops the span
+	DropOnError ErrorPolicy = "drop"
+	// ContinueOnError passes the span unchanged to the next stage
+	ContinueOnError ErrorPolicy = "continue"
+	// SkipRestOnError skips the remaining stages and saves the span unchanged
+	SkipRestOnError ErrorPolicy = "skip-rest"
+)
+
+// Config is the list of pipeline stages, as declared in YAML:
+//
+//	stages:
+//	  - name: drop-health-checks
+//	    type: tag-filter
+//	    order: 10
+//	    on-error: continue
+//	    params:
+//	      key: http.url
+//	      values: [/health]
+type Config struct {
+	Stages []StageConfig `yaml:"stages"`
+}
+
+// StageConfig declares a pipeline stage. The stages of type FanOutStageType declare their branches,
+// each one a list of stages:
+//
+//	stages:
+//	  - name: copy-to-audit
+//	    type: fan-out
+//	    branches:
+//	      - stages:
+//	          - type: tag-filter
+//	            params:
+//	              key: audit
+type StageConfig struct {
+	// Name is the name of the stage, unique in the pipeline. It defaults to the type.
+	Name string `yaml:"name"`
+	// Type is the registered type of the stage
+	Type string `yaml:"type"`
+	// Order defines the position of the stage in the pipeline, stages with the same order keep the declared order
+	Order int `yaml:"order"`
+	// OnError is the error handling policy of the stage, DropOnError by default
+	OnError ErrorPolicy `yaml:"on-error"`
+	// Params holds the parameters specific to the stage type
+	Params Params `yaml:"params"`
+	// Branches are the pipelines a fan-out stage runs on copies of the span
+	Branches []Config `yaml:"branches"`
+}
+
+// Params holds the parameters of a stage as read from the configuration
+type Params struct {
+	raw map[string]interface{}
+}
+
+// NewParams creates stage parameters from a map, mostly useful in tests
+func NewParams(raw map[string]interface{}) Params {
+	return Params{raw: raw}
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (p *Params) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	return unmarshal(&p.raw)
+}
+
+// Decode decodes the parameters into the given struct, using its yaml tags
+func (p Params) Decode(out interface{}) error {
+	if len(p.raw) == 0 {
+		return nil
+	}
+	bytes, err := yaml.Marshal(p.raw)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(bytes, out)
+}
+
+// LoadConfig reads the pipeline configuration from a YAML file
+func LoadConfig(path string) (*Config, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(bytes)
+}
+
+// ParseConfig parses the YAML pipeline configuration
+func ParseConfig(bytes []byte) (*Config, error) {
+	cfg := &Config{}
+	if err := yaml.Unmarshal(bytes, cfg); err != nil {
+		return nil, fmt.Errorf("cannot parse pipeline configuration: %v", err)
+	}
+	if err := cfg.normalize(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// normalize validates the stages and sets their default name and error policy
+func (cfg *Config) normalize() error {
+	names := make(map[string]bool, len(cfg.Stages))
+	for i := range cfg.Stages {
+		stage := &cfg.Stages[i]
+		if stage.Type == "" {
+			return fmt.Errorf("pipeline stage #%d has no type", i+1)
+		}
+		if stage.Name == "" {
+			stage.Name = stage.Type
+		}
+		if names[stage.Name] {
+			return fmt.Errorf("duplicate pipeline stage name %q", stage.Name)
+		}
+		names[stage.Name] = true
+		switch stage.OnError {
+		case "":
+			stage.OnError = DropOnError
+		case DropOnError, ContinueOnError, SkipRestOnError:
+		default:
+			return fmt.Errorf("unknown error policy %q of pipeline stage %q, expected %q, %q or %q",
+				stage.OnError, stage.Name, DropOnError, ContinueOnError, SkipRestOnError)
+		}
+		if stage.Type == FanOutStageType && len(stage.Branches) == 0 {
+			return fmt.Errorf("fan-out pipeline stage %q has no branches", stage.Name)
+		}
+		if stage.Type != FanOutStageType && len(stage.Branches) > 0 {
+			return fmt.Errorf("pipeline stage %q of type %q cannot have branches", stage.Name, stage.Type)
+		}
+		for j := range stage.Branches {
+			if err := stage.Branches[j].normalize(); err != nil {
+				return fmt.Errorf("branch #%d of pipeline stage %q: %v", j+1, stage.Name, err)
+			}
+		}
+	}
+	return nil
+}
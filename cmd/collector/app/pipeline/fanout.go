@@ -0,0 +1,104 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pipeline
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+)
+
+// FanOutStageType is the type of the stage that runs copies of the span through several branches, e.g. to
+// forward some spans to another system, and passes the span itself unchanged to the next stage. It is built
+// into the pipeline rather than registered, since its branches are pipelines themselves.
+const FanOutStageType = "fan-out"
+
+type fanOutStage struct {
+	name     string
+	branches []*Pipeline
+}
+
+func newFanOutStage(cfg StageConfig, registry *Registry, metricsFactory metrics.Factory, logger *zap.Logger) (Stage, error) {
+	s := &fanOutStage{name: cfg.Name}
+	for i := range cfg.Branches {
+		branchTag := strconv.Itoa(i + 1)
+		branch, err := New(
+			&cfg.Branches[i],
+			registry,
+			metricsFactory.Namespace(cfg.Name, map[string]string{"branch": branchTag}),
+			logger.With(zap.String("stage", cfg.Name), zap.String("branch", branchTag)),
+		)
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("cannot create branch #%s of pipeline stage %q: %v", branchTag, cfg.Name, err)
+		}
+		s.branches = append(s.branches, branch)
+	}
+	return s, nil
+}
+
+func (s *fanOutStage) Name() string {
+	return s.name
+}
+
+func (s *fanOutStage) Process(span *model.Span) (*model.Span, error) {
+	for _, branch := range s.branches {
+		// every branch gets its own copy, which it may modify or drop without affecting the others
+		branch.Process(copySpan(span))
+	}
+	return span, nil
+}
+
+func (s *fanOutStage) Close() error {
+	var firstErr error
+	for _, branch := range s.branches {
+		if err := branch.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// copySpan returns a copy of the span that shares no mutable data with it
+func copySpan(span *model.Span) *model.Span {
+	spanCopy := *span
+	spanCopy.References = append([]model.SpanRef(nil), span.References...)
+	spanCopy.Tags = append(model.KeyValues(nil), span.Tags...)
+	if span.Logs != nil {
+		spanCopy.Logs = make([]model.Log, len(span.Logs))
+		for i, log := range span.Logs {
+			spanCopy.Logs[i] = model.Log{Timestamp: log.Timestamp, Fields: append(model.KeyValues(nil), log.Fields...)}
+		}
+	}
+	spanCopy.Warnings = append([]string(nil), span.Warnings...)
+	if span.Process != nil {
+		spanCopy.Process = &model.Process{
+			ServiceName: span.Process.ServiceName,
+			Tags:        append(model.KeyValues(nil), span.Process.Tags...),
+		}
+	}
+	return &spanCopy
+}
@@ -0,0 +1,126 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+)
+
+const fanOutConfig = `
+stages:
+  - name: copy
+    type: fan-out
+    branches:
+      - stages:
+          - type: tag-filter
+            params:
+              key: audit
+              action: keep
+          - type: audit
+      - stages:
+          - type: add-tags
+            params:
+              tags:
+                branch: two
+          - type: audit
+            name: audit-all
+  - type: add-tags
+    params:
+      tags:
+        env: prod
+`
+
+func TestFanOutStage(t *testing.T) {
+	var audited []*model.Span
+	registry := NewRegistry()
+	registry.Register(TagFilterStageType, newTagFilterStage)
+	registry.Register(AddTagsStageType, newAddTagsStage)
+	registry.Register("audit", func(name string, params Params, logger *zap.Logger) (Stage, error) {
+		return StageFunc(name, func(span *model.Span) (*model.Span, error) {
+			audited = append(audited, span)
+			return span, nil
+		}), nil
+	})
+	cfg, err := ParseConfig([]byte(fanOutConfig))
+	require.NoError(t, err)
+	mFactory := metrics.NewLocalFactory(0)
+	p, err := New(cfg, registry, mFactory, zap.NewNop())
+	require.NoError(t, err)
+
+	span := newTestSpan(model.String("audit", "true"))
+	processed := p.Process(span)
+	require.True(t, processed == span)
+	// the branches got copies, their changes are not seen by the other branches nor by the next stages
+	assert.Equal(t, model.KeyValues{model.String("env", "prod")}, processed.Process.Tags)
+	require.Len(t, audited, 2)
+	assert.Empty(t, audited[0].Process.Tags)
+	assert.Equal(t, model.KeyValues{model.String("branch", "two")}, audited[1].Process.Tags)
+	assert.False(t, audited[0] == span || audited[1] == span)
+
+	// a span dropped by a branch still goes through the pipeline
+	assert.NotNil(t, p.Process(newTestSpan()))
+	assert.Len(t, audited, 3)
+
+	counters, _ := mFactory.Snapshot()
+	assert.EqualValues(t, 1, counters["copy.pipeline.dropped|branch=1|stage=tag-filter"])
+	assert.EqualValues(t, 2, counters["copy.pipeline.spans|branch=2|stage=audit-all"])
+	assert.EqualValues(t, 2, counters["pipeline.spans|stage=copy"])
+	assert.NoError(t, p.Close())
+}
+
+func TestFanOutStageBranchError(t *testing.T) {
+	cfg, err := ParseConfig([]byte("stages: [{type: fan-out, branches: [{stages: [{type: tag-filter}]}]}]"))
+	require.NoError(t, err)
+	_, err = New(cfg, DefaultRegistry, metrics.NullFactory, zap.NewNop())
+	assert.EqualError(t, err,
+		`cannot create branch #1 of pipeline stage "fan-out": cannot create pipeline stage "tag-filter": missing tag key`)
+}
+
+func TestCopySpan(t *testing.T) {
+	span := &model.Span{
+		OperationName: "op",
+		References:    []model.SpanRef{{RefType: model.ChildOf}},
+		Tags:          model.KeyValues{model.String("k", "v")},
+		Logs:          []model.Log{{Fields: model.KeyValues{model.String("event", "x")}}},
+		Warnings:      []string{"w"},
+		Process:       &model.Process{ServiceName: "svc", Tags: model.KeyValues{model.String("p", "v")}},
+	}
+	spanCopy := copySpan(span)
+	assert.Equal(t, span, spanCopy)
+	spanCopy.Tags[0].VStr = "changed"
+	spanCopy.Logs[0].Fields[0].VStr = "changed"
+	spanCopy.Process.Tags[0].VStr = "changed"
+	spanCopy.References[0].RefType = model.FollowsFrom
+	spanCopy.Warnings[0] = "changed"
+	assert.Equal(t, "v", span.Tags[0].VStr)
+	assert.Equal(t, "x", span.Logs[0].Fields[0].VStr)
+	assert.Equal(t, "v", span.Process.Tags[0].VStr)
+	assert.Equal(t, model.ChildOf, span.References[0].RefType)
+	assert.Equal(t, "w", span.Warnings[0])
+}
@@ -0,0 +1,149 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pipeline
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+)
+
+// Pipeline runs spans through an ordered list of stages
+type Pipeline struct {
+	stages []*runningStage
+	logger *zap.Logger
+}
+
+type runningStage struct {
+	stage   Stage
+	onError ErrorPolicy
+	metrics stageMetrics
+}
+
+// New creates the stages declared in the configuration with the factories of the registry. The stage metrics
+// are reported under the "pipeline" namespace of the metrics factory, tagged with the stage name.
+func New(cfg *Config, registry *Registry, metricsFactory metrics.Factory, logger *zap.Logger) (*Pipeline, error) {
+	stageConfigs := make([]StageConfig, len(cfg.Stages))
+	copy(stageConfigs, cfg.Stages)
+	sort.Stable(byOrder(stageConfigs))
+
+	p := NewEmpty(logger)
+	for _, stageCfg := range stageConfigs {
+		if stageCfg.Type == FanOutStageType {
+			stage, err := newFanOutStage(stageCfg, registry, metricsFactory, logger)
+			if err != nil {
+				p.Close()
+				return nil, err
+			}
+			p.Add(stage, stageCfg.OnError, metricsFactory)
+			continue
+		}
+		factory, ok := registry.factory(stageCfg.Type)
+		if !ok {
+			p.Close()
+			return nil, fmt.Errorf("unknown pipeline stage type %q, expected one of %v", stageCfg.Type, registry.Types())
+		}
+		stage, err := factory(stageCfg.Name, stageCfg.Params, logger.With(zap.String("stage", stageCfg.Name)))
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("cannot create pipeline stage %q: %v", stageCfg.Name, err)
+		}
+		p.Add(stage, stageCfg.OnError, metricsFactory)
+	}
+	return p, nil
+}
+
+// NewEmpty creates a pipeline without stages, which passes the spans unchanged
+func NewEmpty(logger *zap.Logger) *Pipeline {
+	return &Pipeline{logger: logger}
+}
+
+// Add appends a stage to the pipeline
+func (p *Pipeline) Add(stage Stage, onError ErrorPolicy, metricsFactory metrics.Factory) {
+	rs := &runningStage{stage: stage, onError: onError}
+	metrics.Init(&rs.metrics, metricsFactory.Namespace("pipeline", nil), map[string]string{"stage": stage.Name()})
+	p.stages = append(p.stages, rs)
+}
+
+// Extend appends the stages of another pipeline, which keep their error policies and metrics
+func (p *Pipeline) Extend(other *Pipeline) {
+	p.stages = append(p.stages, other.stages...)
+}
+
+// Len returns the number of stages
+func (p *Pipeline) Len() int {
+	return len(p.stages)
+}
+
+// Process runs the span through all the stages and returns the resulting span, or nil if the span was dropped
+func (p *Pipeline) Process(span *model.Span) *model.Span {
+	for _, rs := range p.stages {
+		start := time.Now()
+		processed, err := rs.stage.Process(span)
+		rs.metrics.Latency.Record(time.Since(start))
+		rs.metrics.Spans.Inc(1)
+		if err != nil {
+			rs.metrics.Errors.Inc(1)
+			p.logger.Warn("Pipeline stage failed", zap.String("stage", rs.stage.Name()), zap.Error(err))
+			switch rs.onError {
+			case ContinueOnError:
+				continue
+			case SkipRestOnError:
+				return span
+			default:
+				rs.metrics.Dropped.Inc(1)
+				return nil
+			}
+		}
+		if processed == nil {
+			rs.metrics.Dropped.Inc(1)
+			return nil
+		}
+		span = processed
+	}
+	return span
+}
+
+// Close closes the stages that implement io.Closer
+func (p *Pipeline) Close() error {
+	var firstErr error
+	for _, rs := range p.stages {
+		if closer, ok := rs.stage.(io.Closer); ok {
+			if err := closer.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+type byOrder []StageConfig
+
+func (s byOrder) Len() int           { return len(s) }
+func (s byOrder) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byOrder) Less(i, j int) bool { return s[i].Order < s[j].Order }
@@ -0,0 +1,231 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pipeline
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	mTestutils "github.com/uber/jaeger-lib/metrics/testutils"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+)
+
+const testConfig = `
+stages:
+  - name: env
+    type: add-tags
+    order: 20
+    params:
+      tags:
+        env: prod
+  - type: tag-filter
+    order: 10
+    on-error: continue
+    params:
+      key: http.url
+      values: [/health]
+`
+
+func newTestSpan(tags ...model.KeyValue) *model.Span {
+	return &model.Span{
+		OperationName: "op",
+		Tags:          tags,
+		Process:       &model.Process{ServiceName: "frontend"},
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig([]byte(testConfig))
+	require.NoError(t, err)
+	require.Len(t, cfg.Stages, 2)
+	assert.Equal(t, "env", cfg.Stages[0].Name)
+	assert.Equal(t, DropOnError, cfg.Stages[0].OnError)
+	assert.Equal(t, TagFilterStageType, cfg.Stages[1].Name)
+	assert.Equal(t, ContinueOnError, cfg.Stages[1].OnError)
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	testCases := []struct {
+		config      string
+		expectedErr string
+	}{
+		{
+			config:      "stages: [{name: x}]",
+			expectedErr: "pipeline stage #1 has no type",
+		},
+		{
+			config:      "stages: [{type: add-tags}, {type: add-tags}]",
+			expectedErr: `duplicate pipeline stage name "add-tags"`,
+		},
+		{
+			config:      "stages: [{type: add-tags, on-error: panic}]",
+			expectedErr: `unknown error policy "panic" of pipeline stage "add-tags", expected "drop", "continue" or "skip-rest"`,
+		},
+		{
+			config:      "stages: [{type: fan-out}]",
+			expectedErr: `fan-out pipeline stage "fan-out" has no branches`,
+		},
+		{
+			config:      "stages: [{type: add-tags, branches: [{stages: [{type: add-tags}]}]}]",
+			expectedErr: `pipeline stage "add-tags" of type "add-tags" cannot have branches`,
+		},
+		{
+			config:      "stages: [{type: fan-out, branches: [{stages: [{name: x}]}]}]",
+			expectedErr: `branch #1 of pipeline stage "fan-out": pipeline stage #1 has no type`,
+		},
+		{
+			config:      "stages: {",
+			expectedErr: "cannot parse pipeline configuration: yaml: line 1: did not find expected node content",
+		},
+	}
+	for _, testCase := range testCases {
+		_, err := ParseConfig([]byte(testCase.config))
+		assert.EqualError(t, err, testCase.expectedErr)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	f, err := ioutil.TempFile("", "pipeline")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(testConfig)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	cfg, err := LoadConfig(f.Name())
+	require.NoError(t, err)
+	assert.Len(t, cfg.Stages, 2)
+
+	_, err = LoadConfig(f.Name() + ".missing")
+	assert.Error(t, err)
+}
+
+func TestPipelineFromConfig(t *testing.T) {
+	cfg, err := ParseConfig([]byte(testConfig))
+	require.NoError(t, err)
+	mFactory := metrics.NewLocalFactory(0)
+	p, err := New(cfg, DefaultRegistry, mFactory, zap.NewNop())
+	require.NoError(t, err)
+	require.Equal(t, 2, p.Len())
+	// stages run by order, not by declaration
+	assert.Equal(t, TagFilterStageType, p.stages[0].stage.Name())
+
+	span := p.Process(newTestSpan(model.String("http.url", "/api")))
+	require.NotNil(t, span)
+	assert.Equal(t, model.KeyValues{model.String("env", "prod")}, span.Process.Tags)
+
+	assert.Nil(t, p.Process(newTestSpan(model.String("http.url", "/health"))))
+
+	mTestutils.AssertCounterMetrics(t, mFactory,
+		mTestutils.ExpectedMetric{Name: "pipeline.spans", Tags: map[string]string{"stage": TagFilterStageType}, Value: 2},
+		mTestutils.ExpectedMetric{Name: "pipeline.dropped", Tags: map[string]string{"stage": TagFilterStageType}, Value: 1},
+		mTestutils.ExpectedMetric{Name: "pipeline.spans", Tags: map[string]string{"stage": "env"}, Value: 1},
+	)
+	assert.NoError(t, p.Close())
+}
+
+func TestPipelineUnknownStageType(t *testing.T) {
+	cfg, err := ParseConfig([]byte("stages: [{type: enrich}]"))
+	require.NoError(t, err)
+	_, err = New(cfg, NewRegistry(), metrics.NullFactory, zap.NewNop())
+	assert.EqualError(t, err, `unknown pipeline stage type "enrich", expected one of []`)
+}
+
+func TestPipelineStageFactoryError(t *testing.T) {
+	cfg, err := ParseConfig([]byte("stages: [{type: tag-filter}]"))
+	require.NoError(t, err)
+	_, err = New(cfg, DefaultRegistry, metrics.NullFactory, zap.NewNop())
+	assert.EqualError(t, err, `cannot create pipeline stage "tag-filter": missing tag key`)
+}
+
+type closingStage struct {
+	Stage
+	closed bool
+}
+
+func (s *closingStage) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestPipelineErrorPolicies(t *testing.T) {
+	errStage := errors.New("stage error")
+	failing := StageFunc("failing", func(span *model.Span) (*model.Span, error) {
+		return nil, errStage
+	})
+	renaming := StageFunc("renaming", func(span *model.Span) (*model.Span, error) {
+		span.OperationName = "renamed"
+		return span, nil
+	})
+	testCases := []struct {
+		onError           ErrorPolicy
+		expectedOperation string
+	}{
+		{onError: DropOnError},
+		{onError: ContinueOnError, expectedOperation: "renamed"},
+		{onError: SkipRestOnError, expectedOperation: "op"},
+	}
+	for _, testCase := range testCases {
+		mFactory := metrics.NewLocalFactory(0)
+		p := &Pipeline{logger: zap.NewNop()}
+		p.Add(failing, testCase.onError, mFactory)
+		p.Add(renaming, DropOnError, mFactory)
+
+		span := p.Process(newTestSpan())
+		if testCase.expectedOperation == "" {
+			assert.Nil(t, span, string(testCase.onError))
+		} else if assert.NotNil(t, span, string(testCase.onError)) {
+			assert.Equal(t, testCase.expectedOperation, span.OperationName)
+		}
+		mTestutils.AssertCounterMetrics(t, mFactory,
+			mTestutils.ExpectedMetric{Name: "pipeline.errors", Tags: map[string]string{"stage": "failing"}, Value: 1},
+		)
+	}
+}
+
+func TestPipelineClose(t *testing.T) {
+	stage := &closingStage{Stage: StageFunc("closing", nil)}
+	p := &Pipeline{logger: zap.NewNop()}
+	p.Add(stage, DropOnError, metrics.NullFactory)
+	assert.NoError(t, p.Close())
+	assert.True(t, stage.closed)
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	factory := func(name string, params Params, logger *zap.Logger) (Stage, error) {
+		return StageFunc(name, nil), nil
+	}
+	r.Register("b", factory)
+	r.Register("a", factory)
+	assert.Equal(t, []string{"a", "b"}, r.Types())
+	assert.Panics(t, func() { r.Register("a", factory) })
+	assert.Contains(t, DefaultRegistry.Types(), TagFilterStageType)
+	assert.Contains(t, DefaultRegistry.Types(), AddTagsStageType)
+}
@@ -0,0 +1,123 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pipeline
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+)
+
+// Stage is a step of the collector span pipeline, e.g. an enrichment, a filter or a fan-out to another system
+type Stage interface {
+	// Name returns the name of the stage, used in logs and metrics
+	Name() string
+	// Process returns the span passed to the next stage, which may be the given span modified in place
+	// or a new span. It returns a nil span to drop the span.
+	Process(span *model.Span) (*model.Span, error)
+}
+
+// Factory creates a stage with the given name from the stage parameters
+type Factory func(name string, params Params, logger *zap.Logger) (Stage, error)
+
+type stageFunc struct {
+	name    string
+	process func(span *model.Span) (*model.Span, error)
+}
+
+// StageFunc adapts a function to the Stage interface
+func StageFunc(name string, process func(span *model.Span) (*model.Span, error)) Stage {
+	return &stageFunc{name: name, process: process}
+}
+
+func (s *stageFunc) Name() string {
+	return s.name
+}
+
+func (s *stageFunc) Process(span *model.Span) (*model.Span, error) {
+	return s.process(span)
+}
+
+// Registry holds the stage factories by type name
+type Registry struct {
+	sync.RWMutex
+	factories map[string]Factory
+}
+
+// DefaultRegistry is the registry of the built-in stages, other stages can be added with Register
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{factories: make(map[string]Factory)}
+}
+
+// Register adds a stage factory to the registry, it panics if the type is already registered
+func (r *Registry) Register(stageType string, factory Factory) {
+	r.Lock()
+	defer r.Unlock()
+	if _, ok := r.factories[stageType]; ok {
+		panic(fmt.Sprintf("pipeline stage type %q is already registered", stageType))
+	}
+	r.factories[stageType] = factory
+}
+
+// Types returns the sorted list of registered stage types
+func (r *Registry) Types() []string {
+	r.RLock()
+	defer r.RUnlock()
+	types := make([]string, 0, len(r.factories))
+	for t := range r.factories {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+func (r *Registry) factory(stageType string) (Factory, bool) {
+	r.RLock()
+	defer r.RUnlock()
+	factory, ok := r.factories[stageType]
+	return factory, ok
+}
+
+// Register adds a stage factory to the default registry
+func Register(stageType string, factory Factory) {
+	DefaultRegistry.Register(stageType, factory)
+}
+
+// stageMetrics are the metrics reported for every stage, tagged with the stage name
+type stageMetrics struct {
+	// Spans is the number of spans processed by the stage
+	Spans metrics.Counter `metric:"spans"`
+	// Dropped is the number of spans dropped by the stage, or because of a stage error
+	Dropped metrics.Counter `metric:"dropped"`
+	// Errors is the number of errors returned by the stage
+	Errors metrics.Counter `metric:"errors"`
+	// Latency measures how long the stage takes to process a span
+	Latency metrics.Timer `metric:"latency"`
+}
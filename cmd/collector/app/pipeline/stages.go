@@ -0,0 +1,145 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+)
+
+const (
+	// TagFilterStageType is the type of the stage that drops or keeps the spans with a given tag value
+	TagFilterStageType = "tag-filter"
+	// AddTagsStageType is the type of the stage that adds static process tags to spans
+	AddTagsStageType = "add-tags"
+
+	dropAction = "drop"
+	keepAction = "keep"
+)
+
+var (
+	errMissingTagKey = errors.New("missing tag key")
+	errMissingTags   = errors.New("missing tags")
+)
+
+func init() {
+	Register(TagFilterStageType, newTagFilterStage)
+	Register(AddTagsStageType, newAddTagsStage)
+}
+
+type tagFilterStage struct {
+	name   string
+	key    string
+	values map[string]bool
+	keep   bool
+}
+
+// newTagFilterStage creates a stage that drops the spans whose span or process tag has one of the values,
+// or keeps only those spans if the action is "keep". Without values, any value of the tag matches.
+func newTagFilterStage(name string, params Params, logger *zap.Logger) (Stage, error) {
+	var p struct {
+		Key    string   `yaml:"key"`
+		Values []string `yaml:"values"`
+		Action string   `yaml:"action"`
+	}
+	if err := params.Decode(&p); err != nil {
+		return nil, err
+	}
+	if p.Key == "" {
+		return nil, errMissingTagKey
+	}
+	if p.Action != "" && p.Action != dropAction && p.Action != keepAction {
+		return nil, fmt.Errorf("unknown action %q, expected %q or %q", p.Action, dropAction, keepAction)
+	}
+	values := make(map[string]bool, len(p.Values))
+	for _, v := range p.Values {
+		values[v] = true
+	}
+	return &tagFilterStage{name: name, key: p.Key, values: values, keep: p.Action == keepAction}, nil
+}
+
+func (s *tagFilterStage) Name() string {
+	return s.name
+}
+
+func (s *tagFilterStage) Process(span *model.Span) (*model.Span, error) {
+	if s.matches(span) == s.keep {
+		return span, nil
+	}
+	return nil, nil
+}
+
+func (s *tagFilterStage) matches(span *model.Span) bool {
+	kv, ok := span.Tags.FindByKey(s.key)
+	if !ok && span.Process != nil {
+		kv, ok = span.Process.Tags.FindByKey(s.key)
+	}
+	if !ok {
+		return false
+	}
+	return len(s.values) == 0 || s.values[kv.AsString()]
+}
+
+type addTagsStage struct {
+	name string
+	tags model.KeyValues
+}
+
+// newAddTagsStage creates a stage that adds static tags to the span process, unless the process already has them
+func newAddTagsStage(name string, params Params, logger *zap.Logger) (Stage, error) {
+	var p struct {
+		Tags map[string]string `yaml:"tags"`
+	}
+	if err := params.Decode(&p); err != nil {
+		return nil, err
+	}
+	if len(p.Tags) == 0 {
+		return nil, errMissingTags
+	}
+	tags := make(model.KeyValues, 0, len(p.Tags))
+	for k, v := range p.Tags {
+		tags = append(tags, model.String(k, v))
+	}
+	sort.Sort(tags)
+	return &addTagsStage{name: name, tags: tags}, nil
+}
+
+func (s *addTagsStage) Name() string {
+	return s.name
+}
+
+func (s *addTagsStage) Process(span *model.Span) (*model.Span, error) {
+	if span.Process == nil {
+		return span, nil
+	}
+	for _, tag := range s.tags {
+		if _, ok := span.Process.Tags.FindByKey(tag.Key); !ok {
+			span.Process.Tags = append(span.Process.Tags, tag)
+		}
+	}
+	return span, nil
+}
@@ -0,0 +1,101 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+)
+
+func TestTagFilterStage(t *testing.T) {
+	testCases := []struct {
+		params map[string]interface{}
+		span   *model.Span
+		kept   bool
+	}{
+		{
+			params: map[string]interface{}{"key": "http.url", "values": []string{"/health"}},
+			span:   newTestSpan(model.String("http.url", "/health")),
+			kept:   false,
+		},
+		{
+			params: map[string]interface{}{"key": "http.url", "values": []string{"/health"}},
+			span:   newTestSpan(model.String("http.url", "/api")),
+			kept:   true,
+		},
+		{
+			params: map[string]interface{}{"key": "http.status_code", "values": []string{"500"}, "action": "keep"},
+			span:   newTestSpan(model.Int64("http.status_code", 500)),
+			kept:   true,
+		},
+		{
+			params: map[string]interface{}{"key": "http.status_code", "values": []string{"500"}, "action": "keep"},
+			span:   newTestSpan(),
+			kept:   false,
+		},
+		{
+			params: map[string]interface{}{"key": "hostname"},
+			span: &model.Span{Process: &model.Process{
+				ServiceName: "frontend",
+				Tags:        model.KeyValues{model.String("hostname", "test")},
+			}},
+			kept: false,
+		},
+	}
+	for i, testCase := range testCases {
+		stage, err := newTagFilterStage("filter", NewParams(testCase.params), zap.NewNop())
+		require.NoError(t, err)
+		assert.Equal(t, "filter", stage.Name())
+		span, err := stage.Process(testCase.span)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.kept, span != nil, "test case #%d", i)
+	}
+}
+
+func TestTagFilterStageErrors(t *testing.T) {
+	_, err := newTagFilterStage("filter", NewParams(nil), zap.NewNop())
+	assert.EqualError(t, err, "missing tag key")
+
+	_, err = newTagFilterStage("filter", NewParams(map[string]interface{}{"key": "k", "action": "sample"}), zap.NewNop())
+	assert.EqualError(t, err, `unknown action "sample", expected "drop" or "keep"`)
+}
+
+func TestAddTagsStage(t *testing.T) {
+	stage, err := newAddTagsStage("tags", NewParams(map[string]interface{}{
+		"tags": map[string]string{"env": "prod", "region": "eu"},
+	}), zap.NewNop())
+	require.NoError(t, err)
+
+	span := newTestSpan()
+	span.Process.Tags = model.KeyValues{model.String("region", "us")}
+	span, err = stage.Process(span)
+	require.NoError(t, err)
+	assert.Equal(t, model.KeyValues{model.String("region", "us"), model.String("env", "prod")}, span.Process.Tags)
+
+	_, err = newAddTagsStage("tags", NewParams(nil), zap.NewNop())
+	assert.EqualError(t, err, "missing tags")
+}
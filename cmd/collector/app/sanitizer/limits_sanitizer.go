@@ -0,0 +1,269 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sanitizer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/model"
+)
+
+const (
+	// TruncatedTagKey is the key of the span tag listing everything the limits sanitizer cut from the span
+	TruncatedTagKey = "jaeger.truncated"
+	// DefaultTruncationMarker is appended to every string value shortened by the limits sanitizer
+	DefaultTruncationMarker = "..."
+
+	maxServiceNames = 2000
+)
+
+// DropPolicy defines which entries are discarded when a span carries more tags or logs than allowed
+type DropPolicy string
+
+const (
+	// KeepFirst keeps the entries in the order they were reported and drops the tail
+	KeepFirst DropPolicy = "keep-first"
+	// KeepLast keeps the most recently reported entries and drops the head
+	KeepLast DropPolicy = "keep-last"
+)
+
+// SpanLimits holds the upper bounds enforced by the limits sanitizer. A zero limit is not enforced.
+type SpanLimits struct {
+	// MaxOperationNameLength is the maximum length in bytes of the operation name
+	MaxOperationNameLength int
+	// MaxTagValueLength is the maximum length in bytes of string and binary values of tags and log fields
+	MaxTagValueLength int
+	// MaxTags is the maximum number of span tags
+	MaxTags int
+	// MaxLogs is the maximum number of span logs
+	MaxLogs int
+	// TagsDropPolicy defines which tags are dropped when there are more than MaxTags
+	TagsDropPolicy DropPolicy
+	// LogsDropPolicy defines which logs are dropped when there are more than MaxLogs
+	LogsDropPolicy DropPolicy
+	// TruncationMarker is appended to truncated string values
+	TruncationMarker string
+}
+
+// Enabled returns true if at least one of the limits is set.
+func (l SpanLimits) Enabled() bool {
+	return l.MaxOperationNameLength > 0 || l.MaxTagValueLength > 0 || l.MaxTags > 0 || l.MaxLogs > 0
+}
+
+// Validate returns an error if one of the drop policies is unknown.
+func (l SpanLimits) Validate() error {
+	for _, policy := range []DropPolicy{l.TagsDropPolicy, l.LogsDropPolicy} {
+		switch policy {
+		case "", KeepFirst, KeepLast:
+		default:
+			return fmt.Errorf("unknown drop policy %q, expected %q or %q", policy, KeepFirst, KeepLast)
+		}
+	}
+	return nil
+}
+
+type limitsMetrics struct {
+	// SpansTruncated is the number of spans that had anything cut by the limits sanitizer
+	SpansTruncated metrics.Counter `metric:"spans.truncated"`
+	// ValuesTruncated is the number of operation names, tag and log field values that were shortened
+	ValuesTruncated metrics.Counter `metric:"values.truncated"`
+	// TagsDropped is the number of span tags dropped over MaxTags
+	TagsDropped metrics.Counter `metric:"tags.dropped"`
+	// LogsDropped is the number of span logs dropped over MaxLogs
+	LogsDropped metrics.Counter `metric:"logs.dropped"`
+}
+
+// limitsSanitizer truncates long strings and drops excess tags and logs of a span
+type limitsSanitizer struct {
+	limits  SpanLimits
+	metrics limitsMetrics
+
+	factory    metrics.Factory
+	bySvcLock  sync.Mutex
+	bySvcCount map[string]metrics.Counter // truncated spans per service
+}
+
+// NewLimitsSanitizer creates a sanitizer that enforces the given span limits. Everything that is cut is
+// listed in the TruncatedTagKey tag of the span and counted in metrics, in total and per service.
+func NewLimitsSanitizer(limits SpanLimits, metricsFactory metrics.Factory) SanitizeSpan {
+	if limits.TagsDropPolicy == "" {
+		limits.TagsDropPolicy = KeepFirst
+	}
+	if limits.LogsDropPolicy == "" {
+		limits.LogsDropPolicy = KeepFirst
+	}
+	if limits.TruncationMarker == "" {
+		limits.TruncationMarker = DefaultTruncationMarker
+	}
+	s := &limitsSanitizer{
+		limits:     limits,
+		factory:    metricsFactory,
+		bySvcCount: make(map[string]metrics.Counter),
+	}
+	metrics.Init(&s.metrics, metricsFactory, nil)
+	return s.Sanitize
+}
+
+// Sanitize enforces the span limits. The TruncatedTagKey tag counts towards MaxTags and MaxTagValueLength.
+func (s *limitsSanitizer) Sanitize(span *model.Span) *model.Span {
+	var truncated []string
+
+	if max := s.limits.MaxOperationNameLength; max > 0 && len(span.OperationName) > max {
+		span.OperationName = truncateString(span.OperationName, max, s.limits.TruncationMarker)
+		s.metrics.ValuesTruncated.Inc(1)
+		truncated = append(truncated, "operationName")
+	}
+	droppedTagsAt := len(truncated)
+	droppedTags := 0
+	if s.limits.MaxTags > 0 && len(span.Tags) > s.limits.MaxTags {
+		droppedTags = s.dropTags(span, len(span.Tags)-s.limits.MaxTags+1)
+	}
+	if s.limits.MaxLogs > 0 && len(span.Logs) > s.limits.MaxLogs {
+		dropped := len(span.Logs) - s.limits.MaxLogs
+		if s.limits.LogsDropPolicy == KeepLast {
+			span.Logs = span.Logs[dropped:]
+		} else {
+			span.Logs = span.Logs[:s.limits.MaxLogs]
+		}
+		s.metrics.LogsDropped.Inc(int64(dropped))
+		truncated = append(truncated, "logs:"+strconv.Itoa(dropped))
+	}
+	if s.limits.MaxTagValueLength > 0 {
+		truncated = s.truncateValues(span.Tags, "tag:", truncated)
+		if span.Process != nil {
+			truncated = s.truncateValues(span.Process.Tags, "process.tag:", truncated)
+		}
+		fieldsBefore := len(truncated)
+		for _, log := range span.Logs {
+			truncated = s.truncateValues(log.Fields, "log.field:", truncated)
+		}
+		truncated = append(truncated[:fieldsBefore], dedupe(truncated[fieldsBefore:])...)
+	}
+
+	if droppedTags == 0 && len(truncated) == 0 {
+		return span
+	}
+	if s.limits.MaxTags > 0 && len(span.Tags) >= s.limits.MaxTags {
+		// make room for the truncation tag
+		droppedTags += s.dropTags(span, len(span.Tags)-s.limits.MaxTags+1)
+	}
+	if droppedTags > 0 {
+		truncated = append(truncated, "")
+		copy(truncated[droppedTagsAt+1:], truncated[droppedTagsAt:])
+		truncated[droppedTagsAt] = "tags:" + strconv.Itoa(droppedTags)
+	}
+	s.metrics.SpansTruncated.Inc(1)
+	if span.Process != nil {
+		s.countByServiceName(span.Process.ServiceName)
+	}
+	value := strings.Join(truncated, ",")
+	if max := s.limits.MaxTagValueLength; max > 0 && len(value) > max {
+		// the truncation tag obeys the same limit as the tags it reports on
+		value = truncateString(value, max, s.limits.TruncationMarker)
+	}
+	span.Tags = append(span.Tags, model.String(TruncatedTagKey, value))
+	return span
+}
+
+// dropTags drops count span tags according to the TagsDropPolicy and returns count.
+func (s *limitsSanitizer) dropTags(span *model.Span, count int) int {
+	if count > len(span.Tags) {
+		count = len(span.Tags)
+	}
+	if s.limits.TagsDropPolicy == KeepLast {
+		span.Tags = span.Tags[count:]
+	} else {
+		span.Tags = span.Tags[:len(span.Tags)-count]
+	}
+	s.metrics.TagsDropped.Inc(int64(count))
+	return count
+}
+
+// truncateValues shortens the string and binary values that are over MaxTagValueLength and appends
+// the prefixed keys of the shortened values to truncated.
+func (s *limitsSanitizer) truncateValues(keyValues model.KeyValues, prefix string, truncated []string) []string {
+	max := s.limits.MaxTagValueLength
+	for i, kv := range keyValues {
+		switch {
+		case kv.VType == model.StringType && len(kv.VStr) > max:
+			keyValues[i].VStr = truncateString(kv.VStr, max, s.limits.TruncationMarker)
+		case kv.VType == model.BinaryType && len(kv.VBlob) > max:
+			keyValues[i].VBlob = kv.VBlob[:max]
+		default:
+			continue
+		}
+		s.metrics.ValuesTruncated.Inc(1)
+		truncated = append(truncated, prefix+kv.Key)
+	}
+	return truncated
+}
+
+// truncateString cuts the string without splitting a UTF-8 sequence and appends the marker,
+// so that the result is at most max bytes long.
+func truncateString(str string, max int, marker string) string {
+	keep := max - len(marker)
+	if keep < 0 {
+		return truncateString(marker, max, "")
+	}
+	for keep > 0 && !utf8.RuneStart(str[keep]) {
+		keep--
+	}
+	return str[:keep] + marker
+}
+
+// countByServiceName increments the truncation counter of the service. Like the span processor metrics,
+// it stops creating new counters once maxServiceNames services have been seen.
+func (s *limitsSanitizer) countByServiceName(serviceName string) {
+	if serviceName == "" {
+		return
+	}
+	var counter metrics.Counter
+	s.bySvcLock.Lock()
+	if c, ok := s.bySvcCount[serviceName]; ok {
+		counter = c
+	} else if len(s.bySvcCount) < maxServiceNames {
+		counter = s.factory.Counter("spans.truncated-by-svc", map[string]string{"service": serviceName})
+		s.bySvcCount[serviceName] = counter
+	}
+	s.bySvcLock.Unlock()
+	if counter != nil {
+		counter.Inc(1)
+	}
+}
+
+func dedupe(values []string) []string {
+	seen := make(map[string]struct{}, len(values))
+	ret := values[:0]
+	for _, v := range values {
+		if _, ok := seen[v]; !ok {
+			seen[v] = struct{}{}
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
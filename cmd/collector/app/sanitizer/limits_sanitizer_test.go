@@ -0,0 +1,175 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sanitizer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber/jaeger-lib/metrics"
+	mTestutils "github.com/uber/jaeger-lib/metrics/testutils"
+
+	"github.com/uber/jaeger/model"
+)
+
+func limitsTestSpan() *model.Span {
+	return &model.Span{
+		OperationName: "a-very-long-operation-name",
+		Tags: model.KeyValues{
+			model.String("t1", "short"),
+			model.String("t2", "a-very-long-tag-value"),
+			model.Binary("t3", []byte("a-very-long-binary-value")),
+			model.Int64("t4", 42),
+		},
+		Logs: []model.Log{
+			{Timestamp: time.Unix(1, 0), Fields: model.KeyValues{model.String("event", "a-very-long-event")}},
+			{Timestamp: time.Unix(2, 0), Fields: model.KeyValues{model.String("event", "a-very-long-event")}},
+			{Timestamp: time.Unix(3, 0), Fields: model.KeyValues{model.String("event", "short")}},
+		},
+		Process: &model.Process{
+			ServiceName: "svc",
+			Tags:        model.KeyValues{model.String("hostname", "a-very-long-hostname")},
+		},
+	}
+}
+
+func TestLimitsSanitizerDisabled(t *testing.T) {
+	assert.False(t, SpanLimits{TruncationMarker: "~"}.Enabled())
+	assert.True(t, SpanLimits{MaxLogs: 1}.Enabled())
+
+	span := NewLimitsSanitizer(SpanLimits{}, metrics.NullFactory)(limitsTestSpan())
+	assert.Equal(t, limitsTestSpan(), span)
+}
+
+func TestLimitsSanitizerTruncatesValues(t *testing.T) {
+	metricsFactory := metrics.NewLocalFactory(0)
+	s := NewLimitsSanitizer(SpanLimits{MaxOperationNameLength: 6, MaxTagValueLength: 6}, metricsFactory)
+	span := s(limitsTestSpan())
+
+	// the truncated values include the marker
+	assert.Equal(t, "a-v...", span.OperationName)
+	assert.Equal(t, model.KeyValues{
+		model.String("t1", "short"),
+		model.String("t2", "a-v..."),
+		model.Binary("t3", []byte("a-very")),
+		model.Int64("t4", 42),
+		model.String(TruncatedTagKey, "ope..."),
+	}, span.Tags)
+	assert.Equal(t, "a-v...", span.Process.Tags[0].VStr)
+	assert.Equal(t, "a-v...", span.Logs[0].Fields[0].VStr)
+	assert.Equal(t, "a-v...", span.Logs[1].Fields[0].VStr)
+	assert.Equal(t, "short", span.Logs[2].Fields[0].VStr)
+
+	mTestutils.AssertCounterMetrics(t, metricsFactory, []mTestutils.ExpectedMetric{
+		{Name: "spans.truncated", Value: 1},
+		{Name: "values.truncated", Value: 6},
+		{Name: "spans.truncated-by-svc", Tags: map[string]string{"service": "svc"}, Value: 1},
+	}...)
+}
+
+func TestLimitsSanitizerTruncatesTruncationTag(t *testing.T) {
+	s := NewLimitsSanitizer(SpanLimits{MaxTagValueLength: 20}, metrics.NullFactory)
+	span := &model.Span{OperationName: "op"}
+	for _, key := range []string{"k1", "k2", "k3", "k4", "k5"} {
+		span.Tags = append(span.Tags, model.String(key, "a-value-over-the-limit"))
+	}
+	span = s(span)
+
+	// "tag:k1,tag:k2,tag:k3,tag:k4,tag:k5" is over the limit as well
+	assert.Equal(t, model.String(TruncatedTagKey, "tag:k1,tag:k2,tag..."), span.Tags[len(span.Tags)-1])
+}
+
+func TestLimitsSanitizerDropsTagsAndLogs(t *testing.T) {
+	tests := []struct {
+		policy       DropPolicy
+		expectedTags []string
+		expectedLogs []int64
+	}{
+		{policy: KeepFirst, expectedTags: []string{"t1"}, expectedLogs: []int64{1}},
+		{policy: KeepLast, expectedTags: []string{"t4"}, expectedLogs: []int64{3}},
+	}
+	for _, test := range tests {
+		metricsFactory := metrics.NewLocalFactory(0)
+		s := NewLimitsSanitizer(SpanLimits{
+			MaxTags:        2,
+			MaxLogs:        1,
+			TagsDropPolicy: test.policy,
+			LogsDropPolicy: test.policy,
+		}, metricsFactory)
+		span := s(limitsTestSpan())
+
+		var tagKeys []string
+		for _, tag := range span.Tags[:len(span.Tags)-1] {
+			tagKeys = append(tagKeys, tag.Key)
+		}
+		assert.Equal(t, test.expectedTags, tagKeys, string(test.policy))
+		// the truncation tag takes one of the MaxTags
+		assert.Len(t, span.Tags, 2)
+		assert.Equal(t, model.String(TruncatedTagKey, "tags:3,logs:2"), span.Tags[len(span.Tags)-1])
+		var logTimes []int64
+		for _, log := range span.Logs {
+			logTimes = append(logTimes, log.Timestamp.Unix())
+		}
+		assert.Equal(t, test.expectedLogs, logTimes, string(test.policy))
+
+		mTestutils.AssertCounterMetrics(t, metricsFactory, []mTestutils.ExpectedMetric{
+			{Name: "spans.truncated", Value: 1},
+			{Name: "tags.dropped", Value: 3},
+			{Name: "logs.dropped", Value: 2},
+		}...)
+	}
+}
+
+func TestLimitsSanitizerMakesRoomForTruncationTag(t *testing.T) {
+	metricsFactory := metrics.NewLocalFactory(0)
+	s := NewLimitsSanitizer(SpanLimits{MaxTags: 4, MaxOperationNameLength: 6}, metricsFactory)
+	span := s(limitsTestSpan())
+
+	assert.Equal(t, model.KeyValues{
+		model.String("t1", "short"),
+		model.String("t2", "a-very-long-tag-value"),
+		model.Binary("t3", []byte("a-very-long-binary-value")),
+		model.String(TruncatedTagKey, "operationName,tags:1"),
+	}, span.Tags)
+	mTestutils.AssertCounterMetrics(t, metricsFactory, []mTestutils.ExpectedMetric{
+		{Name: "tags.dropped", Value: 1},
+		{Name: "values.truncated", Value: 1},
+	}...)
+}
+
+func TestSpanLimitsValidate(t *testing.T) {
+	assert.NoError(t, SpanLimits{}.Validate())
+	assert.NoError(t, SpanLimits{TagsDropPolicy: KeepFirst, LogsDropPolicy: KeepLast}.Validate())
+	assert.EqualError(t, SpanLimits{TagsDropPolicy: "keep-middle"}.Validate(),
+		`unknown drop policy "keep-middle", expected "keep-first" or "keep-last"`)
+	assert.EqualError(t, SpanLimits{LogsDropPolicy: "first"}.Validate(),
+		`unknown drop policy "first", expected "keep-first" or "keep-last"`)
+}
+
+func TestTruncateStringKeepsUTF8Valid(t *testing.T) {
+	assert.Equal(t, "ab~", truncateString("abçd", 3, "~"))
+	assert.Equal(t, "ab~", truncateString("abçd", 4, "~"))
+	assert.Equal(t, "abç~", truncateString("abçd", 5, "~"))
+	assert.Equal(t, "~", truncateString("çd", 2, "~"))
+	assert.Equal(t, "..", truncateString("abcd", 2, "..."))
+}
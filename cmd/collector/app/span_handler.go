@@ -21,6 +21,9 @@
 package app
 
 import (
+	"context"
+	"time"
+
 	"github.com/uber/tchannel-go/thrift"
 	"go.uber.org/zap"
 
@@ -28,6 +31,7 @@ import (
 	"github.com/uber/jaeger/model"
 	jConv "github.com/uber/jaeger/model/converter/thrift/jaeger"
 	"github.com/uber/jaeger/model/converter/thrift/zipkin"
+	"github.com/uber/jaeger/security"
 	"github.com/uber/jaeger/thrift-gen/jaeger"
 	"github.com/uber/jaeger/thrift-gen/zipkincore"
 )
@@ -57,6 +61,9 @@ type JaegerBatchesHandler interface {
 type SpanProcessor interface {
 	// ProcessSpans processes model spans and return with either a list of true/false success or an error
 	ProcessSpans(mSpans []*model.Span, spanFormat string) ([]bool, error)
+	// Drain stops accepting new spans and waits for the queued spans to be processed, but no longer
+	// than the given timeout. It returns the number of spans abandoned in the queue or still being saved at the deadline.
+	Drain(timeout time.Duration) int
 }
 
 type jaegerBatchesHandler struct {
@@ -80,6 +87,7 @@ func (jbh *jaegerBatchesHandler) SubmitBatches(ctx thrift.Context, batches []*ja
 			mSpan := jConv.ToDomainSpan(span, batch.Process)
 			mSpans = append(mSpans, mSpan)
 		}
+		applyPrincipal(ctx, mSpans)
 		oks, err := jbh.modelProcessor.ProcessSpans(mSpans, JaegerFormatType)
 		if err != nil {
 			return nil, err
@@ -121,6 +129,7 @@ func (h *zipkinSpanHandler) SubmitZipkinBatch(ctx thrift.Context, spans []*zipki
 		sanitized := h.sanitizer.Sanitize(span)
 		mSpans[i] = ConvertZipkinToModel(sanitized, h.logger)
 	}
+	applyPrincipal(ctx, mSpans)
 	bools, err := h.modelProcessor.ProcessSpans(mSpans, ZipkinFormatType)
 	if err != nil {
 		return nil, err
@@ -134,6 +143,32 @@ func (h *zipkinSpanHandler) SubmitZipkinBatch(ctx thrift.Context, spans []*zipki
 	return responses, nil
 }
 
+// applyPrincipal removes the principal tags reported by the client, which cannot be trusted, and tags
+// the process of every span with the principal authenticated by the client certificate, if any
+func applyPrincipal(ctx context.Context, mSpans []*model.Span) {
+	principal, verified := security.PrincipalFromContext(ctx)
+	for _, mSpan := range mSpans {
+		mSpan.Tags = removeTag(mSpan.Tags, security.PrincipalTagKey)
+		if mSpan.Process == nil {
+			continue
+		}
+		mSpan.Process.Tags = removeTag(mSpan.Process.Tags, security.PrincipalTagKey)
+		if verified {
+			mSpan.Process.Tags = append(mSpan.Process.Tags, model.String(security.PrincipalTagKey, principal))
+		}
+	}
+}
+
+func removeTag(tags model.KeyValues, key string) model.KeyValues {
+	filtered := tags[:0]
+	for _, tag := range tags {
+		if tag.Key != key {
+			filtered = append(filtered, tag)
+		}
+	}
+	return filtered
+}
+
 // ConvertZipkinToModel is a helper function that logs warnings during conversion
 func ConvertZipkinToModel(zSpan *zipkincore.Span, logger *zap.Logger) *model.Span {
 	mSpan, err := zipkin.ToDomainSpan(zSpan)
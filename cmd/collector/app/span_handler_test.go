@@ -21,6 +21,7 @@
 package app
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -31,6 +32,7 @@ import (
 
 	"github.com/uber/jaeger/cmd/collector/app/sanitizer/zipkin"
 	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/security"
 	"github.com/uber/jaeger/thrift-gen/jaeger"
 	"github.com/uber/jaeger/thrift-gen/zipkincore"
 )
@@ -85,6 +87,10 @@ func (s *shouldIErrorProcessor) ProcessSpans(mSpans []*model.Span, format string
 	return retMe, nil
 }
 
+func (s *shouldIErrorProcessor) Drain(timeout time.Duration) int {
+	return 0
+}
+
 func TestZipkinSpanHandler(t *testing.T) {
 	testChunks := []struct {
 		expectedErr error
@@ -116,3 +122,82 @@ func TestZipkinSpanHandler(t *testing.T) {
 		}
 	}
 }
+
+type recordingProcessor struct {
+	shouldIErrorProcessor
+	spans []*model.Span
+}
+
+func (p *recordingProcessor) ProcessSpans(mSpans []*model.Span, format string) ([]bool, error) {
+	p.spans = append(p.spans, mSpans...)
+	return p.shouldIErrorProcessor.ProcessSpans(mSpans, format)
+}
+
+func TestJaegerSpanHandlerPrincipal(t *testing.T) {
+	testCases := []struct {
+		ctx      context.Context
+		expected model.KeyValues
+	}{
+		{
+			ctx:      context.Background(),
+			expected: model.KeyValues{model.String("foo", "bar")},
+		},
+		{
+			ctx: security.ContextWithPrincipal(context.Background(), "frontend"),
+			expected: model.KeyValues{
+				model.String("foo", "bar"),
+				model.String(security.PrincipalTagKey, "frontend"),
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		processor := &recordingProcessor{}
+		h := NewJaegerSpanHandler(zap.NewNop(), processor)
+		ctx, cancel := context.WithTimeout(testCase.ctx, time.Minute)
+		defer cancel()
+		_, err := h.SubmitBatches(thrift.Wrap(ctx), []*jaeger.Batch{
+			{
+				Process: &jaeger.Process{
+					ServiceName: "someServiceName",
+					Tags: []*jaeger.Tag{
+						{Key: "foo", VType: jaeger.TagType_STRING, VStr: stringPtr("bar")},
+						{Key: security.PrincipalTagKey, VType: jaeger.TagType_STRING, VStr: stringPtr("forged")},
+					},
+				},
+				Spans: []*jaeger.Span{{
+					SpanId: 21345,
+					Tags: []*jaeger.Tag{
+						{Key: security.PrincipalTagKey, VType: jaeger.TagType_STRING, VStr: stringPtr("forged")},
+					},
+				}},
+			},
+		})
+		assert.NoError(t, err)
+		if assert.Len(t, processor.spans, 1) {
+			assert.Empty(t, processor.spans[0].Tags)
+			assert.Equal(t, testCase.expected, processor.spans[0].Process.Tags)
+		}
+	}
+}
+
+func TestZipkinSpanHandlerPrincipal(t *testing.T) {
+	processor := &recordingProcessor{}
+	h := NewZipkinSpanHandler(zap.NewNop(), processor, zipkin.NewParentIDSanitizer())
+	ctx, cancel := context.WithTimeout(security.ContextWithPrincipal(context.Background(), "frontend"), time.Minute)
+	defer cancel()
+	_, err := h.SubmitZipkinBatch(thrift.Wrap(ctx), []*zipkincore.Span{
+		{
+			ID: 12345,
+		},
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, processor.spans, 1) {
+		principal, ok := processor.spans[0].Process.Tags.FindByKey(security.PrincipalTagKey)
+		assert.True(t, ok)
+		assert.Equal(t, "frontend", principal.VStr)
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -27,18 +27,24 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/security"
 	"github.com/uber/jaeger/storage/spanstore"
 
-	"github.com/uber/jaeger/cmd/collector/app/sanitizer"
+	"github.com/uber/jaeger/cmd/collector/app/pipeline"
 	"github.com/uber/jaeger/pkg/queue"
 )
 
+const (
+	filterStageName    = "filter"
+	sanitizerStageName = "sanitizer"
+)
+
 type spanProcessor struct {
-	queue           *queue.BoundedQueue
+	queue           queue.Queue
 	metrics         *SpanProcessorMetrics
 	preProcessSpans ProcessSpans
-	filterSpan      FilterSpan             // filter is called before the sanitizer but after preProcessSpans
-	sanitizer       sanitizer.SanitizeSpan // sanitizer is called before processSpan
+	receive         *pipeline.Pipeline // receive runs the filter stage before the queue
+	process         *pipeline.Pipeline // process runs the sanitizer and the configured stages before processSpan
 	processSpan     ProcessSpan
 	logger          *zap.Logger
 	spanWriter      spanstore.Writer
@@ -77,15 +83,29 @@ func newSpanProcessor(spanWriter spanstore.Writer, opts ...Option) *spanProcesso
 	droppedItemHandler := func(item interface{}) {
 		handlerMetrics.SpansDropped.Inc(1)
 	}
-	boundedQueue := queue.NewBoundedQueue(options.queueSize, droppedItemHandler)
+	var spanQueue queue.Queue
+	if options.fairQueue != nil {
+		spanQueue = queue.NewFairQueue(
+			queue.FairQueueConfig{
+				Capacity:      options.queueSize,
+				Weights:       classWeights(options.fairQueue),
+				ReservedShare: options.fairQueue.ReservedShare,
+			},
+			newSpanClassifier(options.fairQueue.ClassBy),
+			droppedItemHandler,
+			options.hostMetrics,
+		)
+	} else {
+		spanQueue = queue.NewBoundedQueue(options.queueSize, droppedItemHandler)
+	}
 
 	sp := spanProcessor{
-		queue:           boundedQueue,
+		queue:           spanQueue,
 		metrics:         handlerMetrics,
-		logger:          options.logger,
 		preProcessSpans: options.preProcessSpans,
-		filterSpan:      options.spanFilter,
-		sanitizer:       options.sanitizer,
+		receive:         newReceiveStages(options),
+		process:         newProcessStages(options),
+		logger:          options.logger,
 		reportBusy:      options.reportBusy,
 		numWorkers:      options.numWorkers,
 		spanWriter:      spanWriter,
@@ -98,11 +118,72 @@ func newSpanProcessor(spanWriter spanstore.Writer, opts ...Option) *spanProcesso
 	return &sp
 }
 
+// newReceiveStages returns the stages run on every received span, a dropped span is rejected
+func newReceiveStages(options options) *pipeline.Pipeline {
+	stages := pipeline.NewEmpty(options.logger)
+	stages.Add(pipeline.StageFunc(filterStageName, func(span *model.Span) (*model.Span, error) {
+		if !options.spanFilter(span) {
+			return nil, nil
+		}
+		return span, nil
+	}), pipeline.DropOnError, options.serviceMetrics)
+	return stages
+}
+
+// newProcessStages returns the stages run on every span taken from the queue, a dropped span is not saved
+func newProcessStages(options options) *pipeline.Pipeline {
+	stages := pipeline.NewEmpty(options.logger)
+	stages.Add(pipeline.StageFunc(sanitizerStageName, func(span *model.Span) (*model.Span, error) {
+		return options.sanitizer(span), nil
+	}), pipeline.DropOnError, options.serviceMetrics)
+	if options.pipeline != nil {
+		stages.Extend(options.pipeline)
+	}
+	return stages
+}
+
+// newSpanClassifier returns the fair queue classifier of spans, debug spans skip ahead of all the classes.
+// The classes are prefixed by their kind, so that a principal cannot take the share of a service of the
+// same name, e.g. the spans without a principal.
+func newSpanClassifier(classBy string) queue.Classifier {
+	return func(item interface{}) (string, bool) {
+		span := item.(*queueItem).span
+		debug := span.Flags.IsDebug()
+		if span.Process == nil {
+			return "", debug
+		}
+		if classBy == FairQueueByPrincipal {
+			if principal, ok := span.Process.Tags.FindByKey(security.PrincipalTagKey); ok {
+				return principalClassPrefix + principal.VStr, debug
+			}
+		}
+		return serviceClassPrefix + span.Process.ServiceName, debug
+	}
+}
+
+// classWeights returns the weights of the services or principals keyed by their fair queue class
+func classWeights(fairQueue *FairQueueOptions) map[string]int {
+	prefix := serviceClassPrefix
+	if fairQueue.ClassBy == FairQueueByPrincipal {
+		prefix = principalClassPrefix
+	}
+	weights := make(map[string]int, len(fairQueue.Weights))
+	for name, weight := range fairQueue.Weights {
+		weights[prefix+name] = weight
+	}
+	return weights
+}
+
 // Stop halts the span processor and all its go-routines.
 func (sp *spanProcessor) Stop() {
 	sp.queue.Stop()
 }
 
+// Drain stops accepting new spans and processes the queued ones until the queue is empty or the timeout expires.
+func (sp *spanProcessor) Drain(timeout time.Duration) int {
+	return sp.queue.Drain(timeout)
+}
+
 func (sp *spanProcessor) saveSpan(span *model.Span) {
 	startTime := time.Now()
 	if err := sp.spanWriter.WriteSpan(span); err != nil {
@@ -114,9 +195,9 @@ func (sp *spanProcessor) saveSpan(span *model.Span) {
 }
 
 func (sp *spanProcessor) ProcessSpans(mSpans []*model.Span, spanFormat string) ([]bool, error) {
-	sp.preProcessSpans(mSpans)
 	sp.metrics.GetCountsForFormat(spanFormat).Received.Inc(int64(len(mSpans)))
 	sp.metrics.BatchSize.Update(int64(len(mSpans)))
+	sp.preProcessSpans(mSpans)
 	retMe := make([]bool, len(mSpans))
 	for i, mSpan := range mSpans {
 		ok := sp.enqueueSpan(mSpan, spanFormat)
@@ -129,7 +210,9 @@ func (sp *spanProcessor) ProcessSpans(mSpans []*model.Span, spanFormat string) (
 }
 
 func (sp *spanProcessor) processItemFromQueue(item *queueItem) {
-	sp.processSpan(sp.sanitizer(item.span))
+	if span := sp.process.Process(item.span); span != nil {
+		sp.processSpan(span)
+	}
 	sp.metrics.InQueueLatency.Record(time.Now().Sub(item.queuedTime))
 }
 
@@ -137,7 +220,7 @@ func (sp *spanProcessor) enqueueSpan(span *model.Span, originalFormat string) bo
 	spanCounts := sp.metrics.GetCountsForFormat(originalFormat)
 	spanCounts.ReceivedBySvc.ReportServiceNameForSpan(span)
 
-	if !sp.filterSpan(span) {
+	if span = sp.receive.Process(span); span == nil {
 		spanCounts.Rejected.Inc(int64(1))
 		return true // as in "not dropped", because it's actively rejected
 	}
@@ -22,20 +22,24 @@ package app
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/uber/jaeger-lib/metrics"
 	metricsTest "github.com/uber/jaeger-lib/metrics/testutils"
 	"github.com/uber/tchannel-go/thrift"
 	"go.uber.org/zap"
 	"golang.org/x/net/context"
 
+	"github.com/uber/jaeger/cmd/collector/app/pipeline"
 	zipkinSanitizer "github.com/uber/jaeger/cmd/collector/app/sanitizer/zipkin"
 	"github.com/uber/jaeger/model"
 	"github.com/uber/jaeger/pkg/testutils"
+	"github.com/uber/jaeger/security"
 	"github.com/uber/jaeger/thrift-gen/jaeger"
 	zc "github.com/uber/jaeger/thrift-gen/zipkincore"
 )
@@ -298,3 +302,162 @@ func TestSpanProcessorBusy(t *testing.T) {
 	assert.Error(t, err, "expcting busy error")
 	assert.Nil(t, res)
 }
+
+func TestSpanProcessorDrain(t *testing.T) {
+	w := &blockingWriter{}
+	p := NewSpanProcessor(w,
+		Options.NumWorkers(1),
+		Options.QueueSize(2),
+	).(*spanProcessor)
+
+	// block the writer so that the first span blocks the only worker and the second one stays in the queue
+	w.Lock()
+	res, err := p.ProcessSpans([]*model.Span{
+		{
+			Process: &model.Process{
+				ServiceName: "x",
+			},
+		},
+		{
+			Process: &model.Process{
+				ServiceName: "x",
+			},
+		},
+	}, JaegerFormatType)
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true, true}, res)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		w.Unlock()
+	}()
+	assert.Equal(t, 0, p.Drain(time.Second))
+
+	res, err = p.ProcessSpans([]*model.Span{
+		{
+			Process: &model.Process{
+				ServiceName: "x",
+			},
+		},
+	}, JaegerFormatType)
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{false}, res, "drained processor must not accept new spans")
+}
+
+func TestSpanProcessorFairQueue(t *testing.T) {
+	// the consumers are not started, so that the spans stay in the queue
+	p := newSpanProcessor(&fakeSpanWriter{},
+		Options.QueueSize(4),
+		Options.FairQueue(FairQueueOptions{ClassBy: FairQueueByService, ReservedShare: 0.25}),
+	)
+	defer p.Stop()
+
+	spans := []*model.Span{}
+	for _, serviceName := range []string{"noisy", "noisy", "noisy", "noisy", "quiet", "noisy"} {
+		spans = append(spans, &model.Span{Process: &model.Process{ServiceName: serviceName}})
+	}
+	debugSpan := &model.Span{Flags: debugFlags(), Process: &model.Process{ServiceName: "noisy"}}
+	spans = append(spans, debugSpan)
+
+	res, err := p.ProcessSpans(spans, JaegerFormatType)
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true, true, true, true, true, false, true}, res)
+	assert.Equal(t, 4, p.queue.Size())
+}
+
+func TestSpanClassifier(t *testing.T) {
+	span := &model.Span{
+		Process: &model.Process{
+			ServiceName: "frontend",
+			Tags:        model.KeyValues{model.String(security.PrincipalTagKey, "frontend.example.com")},
+		},
+	}
+	testCases := []struct {
+		classBy  string
+		span     *model.Span
+		class    string
+		priority bool
+	}{
+		{classBy: FairQueueByService, span: span, class: "service:frontend"},
+		{classBy: FairQueueByPrincipal, span: span, class: "principal:frontend.example.com"},
+		{classBy: FairQueueByPrincipal, span: &model.Span{Process: &model.Process{ServiceName: "backend"}}, class: "service:backend"},
+		// a principal named like a service does not share its class
+		{classBy: FairQueueByPrincipal, span: &model.Span{Process: &model.Process{
+			ServiceName: "frontend",
+			Tags:        model.KeyValues{model.String(security.PrincipalTagKey, "backend")},
+		}}, class: "principal:backend"},
+		{classBy: FairQueueByService, span: &model.Span{Flags: debugFlags(), Process: &model.Process{ServiceName: "backend"}}, class: "service:backend", priority: true},
+	}
+	for _, testCase := range testCases {
+		class, priority := newSpanClassifier(testCase.classBy)(&queueItem{span: testCase.span})
+		assert.Equal(t, testCase.class, class)
+		assert.Equal(t, testCase.priority, priority)
+	}
+}
+
+func debugFlags() model.Flags {
+	var flags model.Flags
+	flags.SetDebug()
+	return flags
+}
+
+func TestSpanProcessorPipeline(t *testing.T) {
+	saved := []string{}
+	configured := pipeline.NewEmpty(zap.NewNop())
+	configured.Add(pipeline.StageFunc("drop", func(span *model.Span) (*model.Span, error) {
+		if span.OperationName == "drop" {
+			return nil, nil
+		}
+		return span, nil
+	}), pipeline.DropOnError, metrics.NullFactory)
+	mFactory := metrics.NewLocalFactory(0)
+	p := newSpanProcessor(&fakeSpanWriter{},
+		Options.ServiceMetrics(mFactory),
+		Options.Sanitizer(func(span *model.Span) *model.Span {
+			span.OperationName = strings.TrimPrefix(span.OperationName, "unsanitized-")
+			return span
+		}),
+		Options.Pipeline(configured),
+		Options.PreSave(func(span *model.Span) {
+			saved = append(saved, span.OperationName)
+		}),
+	)
+	// the configured stages run after the sanitizer
+	for _, operationName := range []string{"keep", "unsanitized-drop"} {
+		p.processItemFromQueue(&queueItem{
+			queuedTime: time.Now(),
+			span:       &model.Span{OperationName: operationName, Process: &model.Process{ServiceName: "x"}},
+		})
+	}
+	assert.Equal(t, []string{"keep"}, saved)
+	counters, _ := mFactory.Snapshot()
+	assert.EqualValues(t, 2, counters["pipeline.spans|stage=sanitizer"])
+}
+
+func TestSpanProcessorReceiveStages(t *testing.T) {
+	mFactory := metrics.NewLocalFactory(0)
+	var preProcessed [][]*model.Span
+	p := newSpanProcessor(&fakeSpanWriter{},
+		Options.ServiceMetrics(mFactory),
+		Options.QueueSize(10),
+		Options.PreProcessSpans(func(spans []*model.Span) {
+			preProcessed = append(preProcessed, spans)
+		}),
+		Options.SpanFilter(func(span *model.Span) bool {
+			return span.OperationName != "rejected"
+		}),
+	)
+	res, err := p.ProcessSpans([]*model.Span{
+		{OperationName: "accepted", Process: &model.Process{ServiceName: "x"}},
+		{OperationName: "rejected", Process: &model.Process{ServiceName: "x"}},
+	}, JaegerFormatType)
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, true}, res)
+	require.Len(t, preProcessed, 1, "preProcessSpans is called once per batch")
+	assert.Len(t, preProcessed[0], 2)
+	assert.Equal(t, 1, p.queue.Size())
+	counters, _ := mFactory.Snapshot()
+	assert.EqualValues(t, 2, counters["pipeline.spans|stage=filter"])
+	assert.EqualValues(t, 1, counters["pipeline.dropped|stage=filter"])
+	assert.EqualValues(t, 1, counters["jaeger.spans.rejected"])
+}
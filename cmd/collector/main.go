@@ -21,12 +21,15 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
 	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/spf13/cobra"
@@ -40,13 +43,16 @@ import (
 	basicB "github.com/uber/jaeger/cmd/builder"
 	"github.com/uber/jaeger/cmd/collector/app"
 	"github.com/uber/jaeger/cmd/collector/app/builder"
-	"github.com/uber/jaeger/cmd/collector/app/zipkin"
 	"github.com/uber/jaeger/cmd/flags"
 	casFlags "github.com/uber/jaeger/cmd/flags/cassandra"
 	esFlags "github.com/uber/jaeger/cmd/flags/es"
 	"github.com/uber/jaeger/pkg/config"
+	"github.com/uber/jaeger/pkg/config/tlscfg"
 	"github.com/uber/jaeger/pkg/healthcheck"
+	"github.com/uber/jaeger/pkg/httpapi"
+	"github.com/uber/jaeger/pkg/httpapi/zipkin"
 	"github.com/uber/jaeger/pkg/recoveryhandler"
+	"github.com/uber/jaeger/security"
 	jc "github.com/uber/jaeger/thrift-gen/jaeger"
 	zc "github.com/uber/jaeger/thrift-gen/zipkincore"
 	dbAsFlags"github.com/uber/jaeger/security/flags/sql"
@@ -65,6 +71,10 @@ func main() {
 	memAuthStoreOptions := memAsFlags.NewOptions("authentication-store.memory")
 	dbAuthStoreOptions := dbAsFlags.NewOptions("authentication-store.sql")
 
+	httpTLSOptions := tlscfg.NewOptions("collector.http")
+	zipkinTLSOptions := tlscfg.NewOptions("collector.zipkin")
+	healthCheckTLSOptions := tlscfg.NewOptions("collector.health-check")
+
 	v := viper.New()
 	command := &cobra.Command{
 		Use:   "jaeger-collector",
@@ -78,12 +88,28 @@ func main() {
 			memAuthStoreOptions.InitFromViper(v)
 			dbAuthStoreOptions.InitFromViper(v)
 
+			httpTLSOptions.InitFromViper(v)
+			zipkinTLSOptions.InitFromViper(v)
+			healthCheckTLSOptions.InitFromViper(v)
+
 			baseMetrics := xkit.Wrap(serviceName, expvar.NewFactory(10))
 
 			builderOpts := new(builder.CollectorOptions).InitFromViper(v)
 			sFlags := new(flags.SharedFlags).InitFromViper(v)
 
-			hc, err := healthcheck.Serve(http.StatusServiceUnavailable, builderOpts.CollectorHealthCheckHTTPPort, logger)
+			principalSource, err := security.ParsePrincipalSource(builderOpts.TLSPrincipalSource)
+			if err != nil {
+				logger.Fatal("Invalid principal source", zap.Error(err))
+			}
+			httpTLSConfig := newTLSConfig(httpTLSOptions, logger)
+			zipkinTLSConfig := newTLSConfig(zipkinTLSOptions, logger)
+
+			hc, err := healthcheck.ServeTLS(
+				http.StatusServiceUnavailable,
+				builderOpts.CollectorHealthCheckHTTPPort,
+				newTLSConfig(healthCheckTLSOptions, logger),
+				logger,
+			)
 			if err != nil {
 				logger.Fatal("Could not start the health check server.", zap.Error(err))
 			}
@@ -119,20 +145,36 @@ func main() {
 			ch.Serve(listener)
 
 			r := mux.NewRouter()
-			apiHandler := app.NewAPIHandler(jaegerBatchesHandler)
+			apiHandler := httpapi.NewAPIHandler(jaegerBatchesHandler)
 			apiHandler.RegisterRoutes(r)
+			// the Zipkin API is also served on the main HTTP port, where the agent HTTP reporter sends Zipkin spans
+			zipkin.NewAPIHandler(zipkinSpansHandler).RegisterRoutes(r)
 			httpPortStr := ":" + strconv.Itoa(builderOpts.CollectorHTTPPort)
 			recoveryHandler := recoveryhandler.NewRecoveryHandler(logger, true)
 
-			go startZipkinHTTPAPI(logger, builderOpts.CollectorZipkinHTTPPort, zipkinSpansHandler, recoveryHandler)
+			httpServers := []*http.Server{}
+			if zipkinServer := startZipkinHTTPAPI(
+				logger,
+				builderOpts.CollectorZipkinHTTPPort,
+				zipkinTLSConfig,
+				principalSource,
+				zipkinSpansHandler,
+				recoveryHandler,
+			); zipkinServer != nil {
+				httpServers = append(httpServers, zipkinServer)
+			}
 
 			logger.Info("Starting Jaeger Collector HTTP server", zap.Int("http-port", builderOpts.CollectorHTTPPort))
 
+			httpServer := &http.Server{
+				Addr:    httpPortStr,
+				Handler: recoveryHandler(security.NewCertificatePrincipalHandler(principalSource, r)),
+			}
+			httpServers = append(httpServers, httpServer)
 			go func() {
-				if err := http.ListenAndServe(httpPortStr, recoveryHandler(r)); err != nil {
+				if err := tlscfg.ListenAndServe(httpServer, httpTLSConfig); err != nil && err != http.ErrServerClosed {
 					logger.Fatal("Could not launch service", zap.Error(err))
 				}
-				hc.Set(http.StatusInternalServerError)
 			}()
 
 			hc.Ready()
@@ -140,6 +182,7 @@ func main() {
 			case <-signalsChannel:
 				logger.Info("Jaeger Collector is finishing")
 			}
+			shutdown(logger, hc, builderOpts.ShutdownTimeout, ch, httpServers, handlerBuilder)
 		},
 	}
 
@@ -152,6 +195,9 @@ func main() {
 		esOptions.AddFlags,
 		dbAuthStoreOptions.AddFlags,
 		memAuthStoreOptions.AddFlags,
+		httpTLSOptions.AddFlags,
+		zipkinTLSOptions.AddFlags,
+		healthCheckTLSOptions.AddFlags,
 	)
 
 	if error := command.Execute(); error != nil {
@@ -162,17 +208,65 @@ func main() {
 func startZipkinHTTPAPI(
 	logger *zap.Logger,
 	zipkinPort int,
+	tlsConfig *tls.Config,
+	principalSource security.PrincipalSource,
 	zipkinSpansHandler app.ZipkinSpansHandler,
 	recoveryHandler func(http.Handler) http.Handler,
-) {
-	if zipkinPort != 0 {
-		r := mux.NewRouter()
-		zipkin.NewAPIHandler(zipkinSpansHandler).RegisterRoutes(r)
-		httpPortStr := ":" + strconv.Itoa(zipkinPort)
-		logger.Info("Listening for Zipkin HTTP traffic", zap.Int("zipkin.http-port", zipkinPort))
+) *http.Server {
+	if zipkinPort == 0 {
+		return nil
+	}
+	r := mux.NewRouter()
+	zipkin.NewAPIHandler(zipkinSpansHandler).RegisterRoutes(r)
+	httpPortStr := ":" + strconv.Itoa(zipkinPort)
+	logger.Info("Listening for Zipkin HTTP traffic", zap.Int("zipkin.http-port", zipkinPort))
 
-		if err := http.ListenAndServe(httpPortStr, recoveryHandler(r)); err != nil {
+	server := &http.Server{
+		Addr:    httpPortStr,
+		Handler: recoveryHandler(security.NewCertificatePrincipalHandler(principalSource, r)),
+	}
+	go func() {
+		if err := tlscfg.ListenAndServe(server, tlsConfig); err != nil && err != http.ErrServerClosed {
 			logger.Fatal("Could not launch service", zap.Error(err))
 		}
+	}()
+	return server
+}
+
+func newTLSConfig(opts *tlscfg.Options, logger *zap.Logger) *tls.Config {
+	tlsConfig, err := tlscfg.NewServerConfig(opts, logger)
+	if err != nil {
+		logger.Fatal("Could not load the TLS certificates", zap.Error(err))
+	}
+	return tlsConfig
+}
+
+// shutdown marks the collector as unavailable, stops accepting new requests, waits for the queued spans
+// to be saved and closes the storage. The whole sequence is bounded by the given timeout.
+func shutdown(
+	logger *zap.Logger,
+	hc *healthcheck.State,
+	timeout time.Duration,
+	ch *tchannel.Channel,
+	httpServers []*http.Server,
+	handlerBuilder *builder.SpanHandlerBuilder,
+) {
+	hc.Set(http.StatusServiceUnavailable)
+	deadline := time.Now().Add(timeout)
+
+	// tchannel refuses new calls right away and closes once the calls in progress are completed
+	ch.Close()
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	for _, server := range httpServers {
+		if err := server.Shutdown(ctx); err != nil {
+			logger.Error("Failed to shut down HTTP server", zap.String("addr", server.Addr), zap.Error(err))
+		}
+	}
+
+	if abandoned := handlerBuilder.Close(time.Until(deadline)); abandoned > 0 {
+		logger.Warn("Shutdown deadline reached, abandoning queued spans", zap.Int("spans", abandoned))
+	} else {
+		logger.Info("All queued spans were processed")
 	}
 }
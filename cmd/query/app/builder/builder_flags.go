@@ -22,8 +22,11 @@ package builder
 
 import (
 	"flag"
+	"time"
 
 	"github.com/spf13/viper"
+
+	"github.com/uber/jaeger/storage/spanstore/cache"
 )
 
 const (
@@ -31,6 +34,12 @@ const (
 	queryPrefix              = "query.prefix"
 	queryStaticFiles         = "query.static-files"
 	queryHealthCheckHTTPPort = "query.health-check-http-port"
+	queryImportFile          = "query.import-file"
+	queryCacheMaxTraces      = "query.cache.max-traces"
+	queryCacheTraceTTL       = "query.cache.trace-ttl"
+	queryCacheRecentAge      = "query.cache.recent-trace-age"
+	queryCacheRecentTTL      = "query.cache.recent-trace-ttl"
+	queryCacheServicesTTL    = "query.cache.services-ttl"
 )
 
 // QueryOptions holds configuration for query
@@ -43,6 +52,11 @@ type QueryOptions struct {
 	QueryStaticAssets string
 	// QueryHealthCheckHTTPPort is the port that the health check service listens in on for http requests
 	QueryHealthCheckHTTPPort int
+	// QueryImportFile is the path of a file with traces to load into the memory span storage on startup
+	QueryImportFile string
+	// Cache controls the caching of the traces, services and operations read from the span storage,
+	// it is disabled if Cache.MaxTraces is 0
+	Cache cache.Options
 }
 
 // AddFlags adds flags for QueryOptions
@@ -51,6 +65,12 @@ func AddFlags(flagSet *flag.FlagSet) {
 	flagSet.String(queryPrefix, "api", "The prefix for the url of the query service")
 	flagSet.String(queryStaticFiles, "jaeger-ui-build/build/", "The path for the static assets for the UI")
 	flagSet.Int(queryHealthCheckHTTPPort, 16687, "The http port for the health check service")
+	flagSet.String(queryImportFile, "", "The path of a file with traces, in the UI or Zipkin v2 JSON format, to load on startup (requires memory span storage)")
+	flagSet.Int(queryCacheMaxTraces, 0, "The maximum number of traces cached by the query service, 0 disables the cache of traces, services and operations")
+	flagSet.Duration(queryCacheTraceTTL, time.Hour, "How long a completed trace is cached")
+	flagSet.Duration(queryCacheRecentAge, 5*time.Minute, "How long after the end of its last span a trace may still receive spans")
+	flagSet.Duration(queryCacheRecentTTL, 10*time.Second, "How long a trace that may still receive spans is cached")
+	flagSet.Duration(queryCacheServicesTTL, 30*time.Second, "How long the lists of services and operations are cached")
 }
 
 // InitFromViper initializes QueryOptions with properties from viper
@@ -59,5 +79,13 @@ func (qOpts *QueryOptions) InitFromViper(v *viper.Viper) *QueryOptions {
 	qOpts.QueryPrefix = v.GetString(queryPrefix)
 	qOpts.QueryStaticAssets = v.GetString(queryStaticFiles)
 	qOpts.QueryHealthCheckHTTPPort = v.GetInt(queryHealthCheckHTTPPort)
+	qOpts.QueryImportFile = v.GetString(queryImportFile)
+	qOpts.Cache = cache.Options{
+		MaxTraces:      v.GetInt(queryCacheMaxTraces),
+		TraceTTL:       v.GetDuration(queryCacheTraceTTL),
+		RecentTraceAge: v.GetDuration(queryCacheRecentAge),
+		RecentTraceTTL: v.GetDuration(queryCacheRecentTTL),
+		ServicesTTL:    v.GetDuration(queryCacheServicesTTL),
+	}
 	return qOpts
 }
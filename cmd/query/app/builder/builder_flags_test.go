@@ -22,17 +22,40 @@ package builder
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/uber/jaeger/pkg/config"
+	"github.com/uber/jaeger/storage/spanstore/cache"
 )
 
 func TestQueryBuilderFlags(t *testing.T) {
 	v, command := config.Viperize(AddFlags)
-	command.ParseFlags([]string{"--query.static-files=/dev/null", "--query.prefix=api", "--query.port=80"})
+	command.ParseFlags([]string{"--query.static-files=/dev/null", "--query.prefix=api", "--query.port=80", "--query.import-file=/tmp/traces.json"})
 	qOpts := new(QueryOptions).InitFromViper(v)
 	assert.Equal(t, "/dev/null", qOpts.QueryStaticAssets)
 	assert.Equal(t, "api", qOpts.QueryPrefix)
 	assert.Equal(t, 80, qOpts.QueryPort)
+	assert.Equal(t, "/tmp/traces.json", qOpts.QueryImportFile)
+	assert.Equal(t, 0, qOpts.Cache.MaxTraces)
+}
+
+func TestQueryBuilderCacheFlags(t *testing.T) {
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{
+		"--query.cache.max-traces=1000",
+		"--query.cache.trace-ttl=2h",
+		"--query.cache.recent-trace-age=1m",
+		"--query.cache.recent-trace-ttl=5s",
+		"--query.cache.services-ttl=1m",
+	})
+	qOpts := new(QueryOptions).InitFromViper(v)
+	assert.Equal(t, cache.Options{
+		MaxTraces:      1000,
+		TraceTTL:       2 * time.Hour,
+		RecentTraceAge: time.Minute,
+		RecentTraceTTL: 5 * time.Second,
+		ServicesTTL:    time.Minute,
+	}, qOpts.Cache)
 }
@@ -24,7 +24,9 @@ import (
 	"time"
 
 	"github.com/uber/jaeger/pkg/cassandra/config"
+	"github.com/uber/jaeger/plugin/storage/cassandra/archivestore"
 	"github.com/uber/jaeger/plugin/storage/cassandra/dependencystore"
+	"github.com/uber/jaeger/plugin/storage/cassandra/metricstore"
 	"github.com/uber/jaeger/plugin/storage/cassandra/spanstore"
 )
 
@@ -36,5 +38,9 @@ func (sb *StorageBuilder) newCassandraBuilder(sessionBuilder config.SessionBuild
 
 	sb.SpanReader = spanstore.NewSpanReader(session, sb.metricsFactory, sb.logger)
 	sb.DependencyReader = dependencystore.NewDependencyStore(session, dependencyDataFreq, sb.metricsFactory, sb.logger)
+	sb.MetricReader = metricstore.NewMetricReader(session, sb.metricsFactory, sb.logger)
+	archiveStore := archivestore.NewArchiveStore(session, sb.metricsFactory, sb.logger)
+	sb.ArchiveIndexReader = archiveStore
+	sb.ArchiveIndexWriter = archiveStore
 	return nil
 }
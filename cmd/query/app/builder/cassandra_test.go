@@ -56,4 +56,7 @@ func TestNewBuilderSuccess(t *testing.T) {
 	require.NoError(t, err)
 	assert.NotNil(t, sb.SpanReader)
 	assert.NotNil(t, sb.DependencyReader)
+	assert.NotNil(t, sb.MetricReader)
+	assert.NotNil(t, sb.ArchiveIndexReader)
+	assert.NotNil(t, sb.ArchiveIndexWriter)
 }
@@ -22,7 +22,9 @@ package builder
 
 import (
 	"github.com/uber/jaeger/pkg/es/config"
+	"github.com/uber/jaeger/plugin/storage/es/archivestore"
 	"github.com/uber/jaeger/plugin/storage/es/dependencystore"
+	"github.com/uber/jaeger/plugin/storage/es/metricstore"
 	"github.com/uber/jaeger/plugin/storage/es/spanstore"
 )
 
@@ -34,5 +36,9 @@ func (sb *StorageBuilder) newESBuilder(builder config.ClientBuilder) error {
 
 	sb.SpanReader = spanstore.NewSpanReader(client, sb.logger, builder.GetMaxSpanAge(), sb.metricsFactory)
 	sb.DependencyReader = dependencystore.NewDependencyStore(client, sb.logger)
+	sb.MetricReader = metricstore.NewMetricReader(client, sb.logger)
+	archiveStore := archivestore.NewArchiveStore(client, sb.logger)
+	sb.ArchiveIndexReader = archiveStore
+	sb.ArchiveIndexWriter = archiveStore
 	return nil
 }
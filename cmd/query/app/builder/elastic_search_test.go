@@ -45,6 +45,9 @@ func TestNewESBuilderSuccess(t *testing.T) {
 	require.NoError(t, err)
 	assert.NotNil(t, sb.SpanReader)
 	assert.NotNil(t, sb.DependencyReader)
+	assert.NotNil(t, sb.MetricReader)
+	assert.NotNil(t, sb.ArchiveIndexReader)
+	assert.NotNil(t, sb.ArchiveIndexWriter)
 }
 
 func TestNewESBuilderFailure(t *testing.T) {
@@ -21,10 +21,15 @@
 package builder
 
 import (
+	archiveMemory "github.com/uber/jaeger/storage/archivestore/memory"
 	"github.com/uber/jaeger/storage/spanstore/memory"
 )
 
 func (sb *StorageBuilder) newMemoryStoreBuilder(memStore *memory.Store) {
 	sb.SpanReader = memStore
 	sb.DependencyReader = memStore
+	sb.MetricReader = memStore
+	archiveStore := archiveMemory.NewStore()
+	sb.ArchiveIndexReader = archiveStore
+	sb.ArchiveIndexWriter = archiveStore
 }
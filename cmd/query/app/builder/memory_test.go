@@ -34,4 +34,7 @@ func TestMemoryStoreBuilder(t *testing.T) {
 	sb.newMemoryStoreBuilder(memStore)
 	assert.Equal(t, memStore, sb.SpanReader)
 	assert.Equal(t, memStore, sb.DependencyReader)
+	assert.Equal(t, memStore, sb.MetricReader)
+	assert.NotNil(t, sb.ArchiveIndexReader)
+	assert.Equal(t, sb.ArchiveIndexReader, sb.ArchiveIndexWriter)
 }
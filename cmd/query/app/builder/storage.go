@@ -29,16 +29,21 @@ import (
 
 	basicB "github.com/uber/jaeger/cmd/builder"
 	"github.com/uber/jaeger/cmd/flags"
+	"github.com/uber/jaeger/storage/archivestore"
 	"github.com/uber/jaeger/storage/dependencystore"
+	"github.com/uber/jaeger/storage/metricstore"
 	"github.com/uber/jaeger/storage/spanstore"
 )
 
 // StorageBuilder is the interface that provides the necessary store readers
 type StorageBuilder struct {
-	logger           *zap.Logger
-	metricsFactory   metrics.Factory
-	SpanReader       spanstore.Reader
-	DependencyReader dependencystore.Reader
+	logger             *zap.Logger
+	metricsFactory     metrics.Factory
+	SpanReader         spanstore.Reader
+	DependencyReader   dependencystore.Reader
+	MetricReader       metricstore.Reader
+	ArchiveIndexReader archivestore.Reader
+	ArchiveIndexWriter archivestore.Writer
 }
 
 var (
@@ -23,6 +23,7 @@ package app
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"strconv"
 	"time"
@@ -35,25 +36,52 @@ import (
 
 	"github.com/uber/jaeger/model"
 	"github.com/uber/jaeger/model/adjuster"
+	"github.com/uber/jaeger/model/analysis"
+	"github.com/uber/jaeger/model/converter/canonical"
+	"github.com/uber/jaeger/model/converter/chrome"
 	uiconv "github.com/uber/jaeger/model/converter/json"
+	"github.com/uber/jaeger/model/converter/zipkinv2"
 	ui "github.com/uber/jaeger/model/json"
 	"github.com/uber/jaeger/pkg/multierror"
+	"github.com/uber/jaeger/storage/archivestore"
 	"github.com/uber/jaeger/storage/dependencystore"
+	"github.com/uber/jaeger/storage/metricstore"
 	"github.com/uber/jaeger/storage/spanstore"
 )
 
 const (
 	traceIDParam  = "traceID"
+	traceAParam   = "a"
+	traceBParam   = "b"
+	formatParam   = "format"
 	endTsParam    = "endTs"
 	lookbackParam = "lookback"
 
 	defaultDependencyLookbackDuration = time.Hour * 24
 	defaultTraceQueryLookbackDuration = time.Hour * 24 * 2
 	defaultHTTPPrefix                 = "api"
+	maxImportBodySize                 = 64 << 20
+
+	uiTraceFormat       = "ui"
+	zipkinV2TraceFormat = "zipkin-v2"
+	chromeTraceFormat   = "chrome"
+	modelTraceFormat    = "model"
 )
 
 var (
 	errNoArchiveSpanStorage = errors.New("archive span storage was not configured")
+	errNoArchiveIndex       = errors.New("archive index was not configured")
+	errNoImportSpanStorage  = errors.New("import span storage was not configured")
+	errNoMetricStorage      = errors.New("metric storage was not configured")
+	errNoTracesToCompare    = errors.New("two trace IDs must be provided as parameters 'a' and 'b'")
+
+	// traceExporters convert a trace into the formats supported by the format parameter of /traces/{trace-id},
+	// other than the UI format
+	traceExporters = map[string]func(trace *model.Trace) interface{}{
+		zipkinV2TraceFormat: func(trace *model.Trace) interface{} { return zipkinv2.FromDomain(trace) },
+		chromeTraceFormat:   func(trace *model.Trace) interface{} { return chrome.FromDomain(trace) },
+		modelTraceFormat:    func(trace *model.Trace) interface{} { return canonical.FromDomain(trace) },
+	}
 )
 
 // HTTPHandler handles http requests
@@ -67,6 +95,24 @@ type structuredResponse struct {
 	Limit  int               `json:"limit"`
 	Offset int               `json:"offset"`
 	Errors []structuredError `json:"errors"`
+	// NextCursor is the cursor of the next page of a trace search, empty on the last page
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// metricPoint is a model.MetricPoint with its timestamp in unix milliseconds
+type metricPoint struct {
+	Timestamp int64   `json:"timestamp"`
+	Value     float64 `json:"value"`
+}
+
+// archive is an archivestore.Archive with its archival time in unix microseconds.
+// The author is the free text given by the client when archiving the trace, it is not authenticated.
+type archive struct {
+	TraceID    ui.TraceID `json:"traceID"`
+	Author     string     `json:"author"`
+	ArchivedAt uint64     `json:"archivedAt"`
+	Note       string     `json:"note"`
+	Labels     []string   `json:"labels"`
 }
 
 type structuredError struct {
@@ -77,15 +123,19 @@ type structuredError struct {
 
 // APIHandler implements the query service public API by registering routes at httpPrefix
 type APIHandler struct {
-	spanReader        spanstore.Reader
-	archiveSpanReader spanstore.Reader
-	archiveSpanWriter spanstore.Writer
-	dependencyReader  dependencystore.Reader
-	adjuster          adjuster.Adjuster
-	logger            *zap.Logger
-	queryParser       queryParser
-	httpPrefix        string
-	tracer            opentracing.Tracer
+	spanReader         spanstore.Reader
+	archiveSpanReader  spanstore.Reader
+	archiveSpanWriter  spanstore.Writer
+	archiveIndexReader archivestore.Reader
+	archiveIndexWriter archivestore.Writer
+	importSpanWriter   spanstore.Writer
+	dependencyReader   dependencystore.Reader
+	metricReader       metricstore.Reader
+	adjuster           adjuster.Adjuster
+	logger             *zap.Logger
+	queryParser        queryParser
+	httpPrefix         string
+	tracer             opentracing.Tracer
 }
 
 // NewAPIHandler returns an APIHandler
@@ -119,8 +169,14 @@ func NewAPIHandler(spanReader spanstore.Reader, dependencyReader dependencystore
 
 // RegisterRoutes registers routes for this handler on the given router
 func (aH *APIHandler) RegisterRoutes(router *mux.Router) {
+	aH.handleFunc(router, aH.compareTraces, "/traces/compare").Methods(http.MethodGet)
+	aH.handleFunc(router, aH.importTraces, "/traces/import").Methods(http.MethodPost)
 	aH.handleFunc(router, aH.getTrace, "/traces/{%s}", traceIDParam).Methods(http.MethodGet)
+	aH.handleFunc(router, aH.getCriticalPath, "/traces/{%s}/critical-path", traceIDParam).Methods(http.MethodGet)
+	aH.handleFunc(router, aH.listArchives, "/archive").Methods(http.MethodGet)
 	aH.handleFunc(router, aH.archiveTrace, "/archive/{%s}", traceIDParam).Methods(http.MethodPost)
+	aH.handleFunc(router, aH.updateArchive, "/archive/{%s}", traceIDParam).Methods(http.MethodPut)
+	aH.handleFunc(router, aH.deleteArchive, "/archive/{%s}", traceIDParam).Methods(http.MethodDelete)
 	aH.handleFunc(router, aH.search, "/traces").Methods(http.MethodGet)
 	aH.handleFunc(router, aH.getServices, "/services").Methods(http.MethodGet)
 	// TODO change the UI to use this endpoint. Requires ?service= parameter.
@@ -128,6 +184,9 @@ func (aH *APIHandler) RegisterRoutes(router *mux.Router) {
 	// TODO - remove this when UI catches up
 	aH.handleFunc(router, aH.getOperationsLegacy, "/services/{%s}/operations", serviceParam).Methods(http.MethodGet)
 	aH.handleFunc(router, aH.dependencies, "/dependencies").Methods(http.MethodGet)
+	aH.handleFunc(router, aH.latencies, "/metrics/latencies").Methods(http.MethodGet)
+	aH.handleFunc(router, aH.callRates, "/metrics/calls").Methods(http.MethodGet)
+	aH.handleFunc(router, aH.errorRates, "/metrics/errors").Methods(http.MethodGet)
 }
 
 func (aH *APIHandler) handleFunc(
@@ -203,16 +262,23 @@ func (aH *APIHandler) search(w http.ResponseWriter, r *http.Request) {
 
 	var uiErrors []structuredError
 	var tracesFromStorage []*model.Trace
+	var page *spanstore.TracePage
 	if len(tQuery.traceIDs) > 0 {
 		tracesFromStorage, uiErrors, err = aH.tracesByIDs(tQuery.traceIDs)
 		if aH.handleError(w, err, http.StatusInternalServerError) {
 			return
 		}
 	} else {
-		tracesFromStorage, err = aH.spanReader.FindTraces(&tQuery.TraceQueryParameters)
+		page, err = aH.spanReader.FindTracePage(&tQuery.TraceQueryParameters)
+		cause := errors.Cause(err)
+		if _, unsupported := cause.(*spanstore.UnsupportedTagQueryError); unsupported || cause == spanstore.ErrInvalidCursor {
+			aH.handleError(w, err, http.StatusBadRequest)
+			return
+		}
 		if aH.handleError(w, err, http.StatusInternalServerError) {
 			return
 		}
+		tracesFromStorage = page.Traces
 	}
 
 	uiTraces := make([]*ui.Trace, len(tracesFromStorage))
@@ -228,6 +294,14 @@ func (aH *APIHandler) search(w http.ResponseWriter, r *http.Request) {
 		Data:   uiTraces,
 		Errors: uiErrors,
 	}
+	if page != nil {
+		structuredRes.Limit = tQuery.NumTraces
+		structuredRes.Offset = page.Offset
+		structuredRes.NextCursor = page.NextCursor
+		if page.Total != spanstore.UnknownTotal {
+			structuredRes.Total = page.Total
+		}
+	}
 	aH.writeJSON(w, &structuredRes)
 }
 
@@ -303,20 +377,74 @@ func (aH *APIHandler) deduplicateDependencies(dependencies []model.DependencyLin
 		parent string
 		child  string
 	}
-	links := make(map[Key]uint64)
+	type Counts struct {
+		calls  uint64
+		errors uint64
+	}
+	links := make(map[Key]Counts)
 
 	for _, l := range dependencies {
-		links[Key{l.Parent, l.Child}] += l.CallCount
+		counts := links[Key{l.Parent, l.Child}]
+		counts.calls += l.CallCount
+		counts.errors += l.ErrorCount
+		links[Key{l.Parent, l.Child}] = counts
 	}
 
 	result := make([]ui.DependencyLink, 0, len(links))
 	for k, v := range links {
-		result = append(result, ui.DependencyLink{Parent: k.parent, Child: k.child, CallCount: v})
+		result = append(result, ui.DependencyLink{Parent: k.parent, Child: k.child, CallCount: v.calls, ErrorCount: v.errors})
 	}
 
 	return result
 }
 
+// latencies returns the quantile of the span latencies of a service or operation, in milliseconds
+func (aH *APIHandler) latencies(w http.ResponseWriter, r *http.Request) {
+	aH.metrics(w, r, metricstore.Reader.GetLatencies)
+}
+
+// callRates returns the number of spans per second of a service or operation
+func (aH *APIHandler) callRates(w http.ResponseWriter, r *http.Request) {
+	aH.metrics(w, r, metricstore.Reader.GetCallRates)
+}
+
+// errorRates returns the fraction of the spans of a service or operation that are errors
+func (aH *APIHandler) errorRates(w http.ResponseWriter, r *http.Request) {
+	aH.metrics(w, r, metricstore.Reader.GetErrorRates)
+}
+
+func (aH *APIHandler) metrics(
+	w http.ResponseWriter,
+	r *http.Request,
+	getMetric func(metricstore.Reader, *metricstore.QueryParameters) ([]model.MetricPoint, error),
+) {
+	if aH.metricReader == nil {
+		aH.handleError(w, errNoMetricStorage, http.StatusInternalServerError)
+		return
+	}
+	query, err := aH.queryParser.parseMetricQuery(r)
+	if aH.handleError(w, err, http.StatusBadRequest) {
+		return
+	}
+	points, err := getMetric(aH.metricReader, query)
+	if aH.handleError(w, err, http.StatusInternalServerError) {
+		return
+	}
+	uiPoints := make([]metricPoint, len(points))
+	for i, point := range points {
+		uiPoints[i] = metricPoint{
+			Timestamp: point.Timestamp.UnixNano() / int64(time.Millisecond),
+			Value:     point.Value,
+		}
+	}
+	structuredRes := structuredResponse{
+		Data:   uiPoints,
+		Total:  len(uiPoints),
+		Errors: []structuredError{},
+	}
+	aH.writeJSON(w, &structuredRes)
+}
+
 func (aH *APIHandler) filterDependenciesByService(
 	dependencies []model.DependencyLink,
 	service string,
@@ -345,9 +473,32 @@ func (aH *APIHandler) parseTraceID(w http.ResponseWriter, r *http.Request) (mode
 	return traceID, true
 }
 
-// getTrace implements the REST API /traces/{trace-id}
+// getTrace implements the REST API /traces/{trace-id}.
+// The optional format parameter selects the UI JSON (default), Zipkin v2 JSON,
+// Chrome trace-event JSON, or the canonical model JSON.
 func (aH *APIHandler) getTrace(w http.ResponseWriter, r *http.Request) {
-	aH.getTraceFromReaders(w, r, aH.spanReader, aH.archiveSpanReader)
+	format := r.FormValue(formatParam)
+	if format == "" || format == uiTraceFormat {
+		aH.getTraceFromReaders(w, r, aH.spanReader, aH.archiveSpanReader)
+		return
+	}
+	exporter, ok := traceExporters[format]
+	if !ok {
+		err := fmt.Errorf("unsupported trace format %q, expected one of %q, %q, %q or %q",
+			format, uiTraceFormat, zipkinV2TraceFormat, chromeTraceFormat, modelTraceFormat)
+		aH.handleError(w, err, http.StatusBadRequest)
+		return
+	}
+	aH.withTraceFromReader(w, r, aH.spanReader, aH.archiveSpanReader, func(trace *model.Trace) {
+		trace, err := aH.adjuster.Adjust(trace)
+		if err != nil {
+			// the exported formats have no room for errors, the adjusted trace is still usable
+			aH.logger.Warn("Failed to adjust the exported trace", zap.String("format", format), zap.Error(err))
+		}
+		resp, _ := json.Marshal(exporter(trace))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resp)
+	})
 }
 
 // getTraceFromReader parses trace ID from the path, loads the trace from specified Reader,
@@ -375,6 +526,26 @@ func (aH *APIHandler) getTraceFromReaders(
 	})
 }
 
+// getCriticalPath implements the REST API /traces/{trace-id}/critical-path.
+// It responds with the spans that determine the latency of the trace, after the adjusters have run.
+func (aH *APIHandler) getCriticalPath(w http.ResponseWriter, r *http.Request) {
+	aH.withTraceFromReader(w, r, aH.spanReader, aH.archiveSpanReader, func(trace *model.Trace) {
+		var uiErrors []structuredError
+		trace, err := aH.adjuster.Adjust(trace)
+		if err != nil {
+			uiErrors = append(uiErrors, structuredError{
+				Msg:     err.Error(),
+				TraceID: ui.TraceID(mux.Vars(r)[traceIDParam]),
+			})
+		}
+		structuredRes := structuredResponse{
+			Data:   analysis.ComputeCriticalPath(trace),
+			Errors: uiErrors,
+		}
+		aH.writeJSON(w, &structuredRes)
+	})
+}
+
 // withTraceFromReader tries to load a trace from Reader and if successful
 // execute process() function passing it that trace.
 func (aH *APIHandler) withTraceFromReader(
@@ -388,49 +559,255 @@ func (aH *APIHandler) withTraceFromReader(
 	if !ok {
 		return
 	}
+	trace, ok := aH.readTrace(w, traceID, reader, backupReader)
+	if !ok {
+		return
+	}
+	process(trace)
+}
+
+// readTrace loads a trace from Reader, falling back to backupReader if the trace is not found.
+// It responds to the client with an error and returns false if the trace cannot be loaded.
+func (aH *APIHandler) readTrace(
+	w http.ResponseWriter,
+	traceID model.TraceID,
+	reader spanstore.Reader,
+	backupReader spanstore.Reader,
+) (*model.Trace, bool) {
 	trace, err := reader.GetTrace(traceID)
 	if err == spanstore.ErrTraceNotFound {
 		if backupReader == nil {
 			aH.handleError(w, err, http.StatusNotFound)
-			return
+			return nil, false
 		}
 		trace, err = backupReader.GetTrace(traceID)
 		if err == spanstore.ErrTraceNotFound {
 			aH.handleError(w, err, http.StatusNotFound)
-			return
+			return nil, false
 		}
 	}
 	if aH.handleError(w, err, http.StatusInternalServerError) {
+		return nil, false
+	}
+	return trace, true
+}
+
+// compareTraces implements the REST API /traces/compare?a={trace-id}&b={trace-id}.
+// It aligns the span trees of both traces, after the adjusters have run, and reports their differences.
+func (aH *APIHandler) compareTraces(w http.ResponseWriter, r *http.Request) {
+	traceIDA, traceIDB := r.FormValue(traceAParam), r.FormValue(traceBParam)
+	if traceIDA == "" || traceIDB == "" {
+		aH.handleError(w, errNoTracesToCompare, http.StatusBadRequest)
 		return
 	}
-	process(trace)
+	var traceIDs []model.TraceID
+	for _, traceIDVar := range []string{traceIDA, traceIDB} {
+		traceID, err := model.TraceIDFromString(traceIDVar)
+		if aH.handleError(w, err, http.StatusBadRequest) {
+			return
+		}
+		traceIDs = append(traceIDs, traceID)
+	}
+	var traces []*model.Trace
+	var uiErrors []structuredError
+	for _, traceID := range traceIDs {
+		trace, ok := aH.readTrace(w, traceID, aH.spanReader, aH.archiveSpanReader)
+		if !ok {
+			return
+		}
+		trace, err := aH.adjuster.Adjust(trace)
+		if err != nil {
+			uiErrors = append(uiErrors, structuredError{
+				Msg:     err.Error(),
+				TraceID: ui.TraceID(traceID.String()),
+			})
+		}
+		traces = append(traces, trace)
+	}
+	structuredRes := structuredResponse{
+		Data:   analysis.CompareTraces(traces[0], traces[1]),
+		Errors: uiErrors,
+	}
+	aH.writeJSON(w, &structuredRes)
 }
 
 // archiveTrace implements the REST API POST:/archive/{trace-id}.
 // It reads the trace from the main Reader and saves it to archive Writer.
+// If the archive index is configured, it also records who archived the trace and why,
+// from the parameters author, note and label, and responds with the archive.
+// The author is free text that anyone can set, it does not identify the user who made the request.
 func (aH *APIHandler) archiveTrace(w http.ResponseWriter, r *http.Request) {
 	if aH.archiveSpanWriter == nil {
 		aH.handleError(w, errNoArchiveSpanStorage, http.StatusInternalServerError)
 		return
 	}
-	aH.withTraceFromReader(w, r, aH.spanReader, nil, func(trace *model.Trace) {
-		var writeErrors []error
-		for _, span := range trace.Spans {
-			err := aH.archiveSpanWriter.WriteSpan(span)
-			if err != nil {
-				writeErrors = append(writeErrors, err)
-			}
+	traceID, ok := aH.parseTraceID(w, r)
+	if !ok {
+		return
+	}
+	trace, ok := aH.readTrace(w, traceID, aH.spanReader, nil)
+	if !ok {
+		return
+	}
+	var writeErrors []error
+	for _, span := range trace.Spans {
+		err := aH.archiveSpanWriter.WriteSpan(span)
+		if err != nil {
+			writeErrors = append(writeErrors, err)
+		}
+	}
+	err := multierror.Wrap(writeErrors)
+	if aH.handleError(w, err, http.StatusInternalServerError) {
+		return
+	}
+	structuredRes := structuredResponse{
+		Data:   []string{}, // doens't matter, just want an empty array
+		Errors: []structuredError{},
+	}
+	if aH.archiveIndexWriter != nil {
+		archive := &archivestore.Archive{
+			TraceID:    traceID,
+			Author:     r.FormValue(authorParam),
+			ArchivedAt: aH.queryParser.timeNow(),
+			Note:       r.FormValue(noteParam),
+			Labels:     r.Form[labelParam],
 		}
-		err := multierror.Wrap(writeErrors)
+		err := aH.archiveIndexWriter.WriteArchive(archive)
 		if aH.handleError(w, err, http.StatusInternalServerError) {
 			return
 		}
-		structuredRes := structuredResponse{
-			Data:   []string{}, // doens't matter, just want an empty array
-			Errors: []structuredError{},
-		}
-		aH.writeJSON(w, &structuredRes)
-	})
+		structuredRes.Data = archiveToUI(archive)
+	}
+	aH.writeJSON(w, &structuredRes)
+}
+
+// updateArchive implements the REST API PUT:/archive/{trace-id}.
+// It replaces the note and the labels of an archived trace with the parameters note and label,
+// keeping its author and archival time, and responds with the archive.
+func (aH *APIHandler) updateArchive(w http.ResponseWriter, r *http.Request) {
+	if aH.archiveIndexReader == nil || aH.archiveIndexWriter == nil {
+		aH.handleError(w, errNoArchiveIndex, http.StatusInternalServerError)
+		return
+	}
+	traceID, ok := aH.parseTraceID(w, r)
+	if !ok {
+		return
+	}
+	archive, err := aH.archiveIndexReader.GetArchive(traceID)
+	if err == archivestore.ErrArchiveNotFound {
+		aH.handleError(w, err, http.StatusNotFound)
+		return
+	}
+	if aH.handleError(w, err, http.StatusInternalServerError) {
+		return
+	}
+	archive.Note = r.FormValue(noteParam)
+	archive.Labels = r.Form[labelParam]
+	err = aH.archiveIndexWriter.WriteArchive(archive)
+	if aH.handleError(w, err, http.StatusInternalServerError) {
+		return
+	}
+	structuredRes := structuredResponse{
+		Data:   archiveToUI(archive),
+		Errors: []structuredError{},
+	}
+	aH.writeJSON(w, &structuredRes)
+}
+
+// listArchives implements the REST API GET:/archive.
+// It finds the archived traces that match the parameters parsed by parseArchiveQuery, most recently archived first.
+func (aH *APIHandler) listArchives(w http.ResponseWriter, r *http.Request) {
+	if aH.archiveIndexReader == nil {
+		aH.handleError(w, errNoArchiveIndex, http.StatusInternalServerError)
+		return
+	}
+	query, err := aH.queryParser.parseArchiveQuery(r)
+	if aH.handleError(w, err, http.StatusBadRequest) {
+		return
+	}
+	archives, err := aH.archiveIndexReader.FindArchives(query)
+	if aH.handleError(w, err, http.StatusInternalServerError) {
+		return
+	}
+	uiArchives := make([]*archive, len(archives))
+	for i, archive := range archives {
+		uiArchives[i] = archiveToUI(archive)
+	}
+	structuredRes := structuredResponse{
+		Data:   uiArchives,
+		Total:  len(uiArchives),
+		Limit:  query.Limit,
+		Errors: []structuredError{},
+	}
+	aH.writeJSON(w, &structuredRes)
+}
+
+// deleteArchive implements the REST API DELETE:/archive/{trace-id}.
+// It removes the trace from the archive index. The archived spans are not deleted,
+// they expire with the retention of the archive span storage.
+func (aH *APIHandler) deleteArchive(w http.ResponseWriter, r *http.Request) {
+	if aH.archiveIndexWriter == nil {
+		aH.handleError(w, errNoArchiveIndex, http.StatusInternalServerError)
+		return
+	}
+	traceID, ok := aH.parseTraceID(w, r)
+	if !ok {
+		return
+	}
+	err := aH.archiveIndexWriter.DeleteArchive(traceID)
+	if err == archivestore.ErrArchiveNotFound {
+		aH.handleError(w, err, http.StatusNotFound)
+		return
+	}
+	if aH.handleError(w, err, http.StatusInternalServerError) {
+		return
+	}
+	structuredRes := structuredResponse{
+		Data:   []string{},
+		Errors: []structuredError{},
+	}
+	aH.writeJSON(w, &structuredRes)
+}
+
+func archiveToUI(a *archivestore.Archive) *archive {
+	return &archive{
+		TraceID:    ui.TraceID(a.TraceID.String()),
+		Author:     a.Author,
+		ArchivedAt: model.TimeAsEpochMicroseconds(a.ArchivedAt),
+		Note:       a.Note,
+		Labels:     a.Labels,
+	}
+}
+
+// importTraces writes the traces in the request body, in any of the formats accepted by ParseTraces,
+// and responds with the IDs of the imported traces.
+func (aH *APIHandler) importTraces(w http.ResponseWriter, r *http.Request) {
+	if aH.importSpanWriter == nil {
+		aH.handleError(w, errNoImportSpanStorage, http.StatusInternalServerError)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxImportBodySize))
+	if aH.handleError(w, err, http.StatusBadRequest) {
+		return
+	}
+	traces, err := ParseTraces(body)
+	if aH.handleError(w, err, http.StatusBadRequest) {
+		return
+	}
+	traceIDs, err := WriteTraces(traces, aH.importSpanWriter)
+	if aH.handleError(w, err, http.StatusInternalServerError) {
+		return
+	}
+	uiTraceIDs := make([]ui.TraceID, len(traceIDs))
+	for i, traceID := range traceIDs {
+		uiTraceIDs[i] = ui.TraceID(traceID.String())
+	}
+	structuredRes := structuredResponse{
+		Data:   uiTraceIDs,
+		Total:  len(uiTraceIDs),
+		Errors: []structuredError{},
+	}
+	aH.writeJSON(w, &structuredRes)
 }
 
 func (aH *APIHandler) handleError(w http.ResponseWriter, err error, statusCode int) bool {
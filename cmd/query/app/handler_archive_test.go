@@ -21,14 +21,21 @@
 package app
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"github.com/uber/jaeger/model"
+	ui "github.com/uber/jaeger/model/json"
+	"github.com/uber/jaeger/storage/archivestore"
+	archivestoremocks "github.com/uber/jaeger/storage/archivestore/mocks"
 	"github.com/uber/jaeger/storage/spanstore"
 	spanstoremocks "github.com/uber/jaeger/storage/spanstore/mocks"
 )
@@ -104,3 +111,276 @@ func TestArchiveTrace_WriteErrors(t *testing.T) {
 		assert.EqualError(t, err, `500 error from server: {"data":null,"total":0,"limit":0,"offset":0,"errors":[{"code":500,"msg":"[cannot save, cannot save]"}]}`+"\n")
 	}, HandlerOptions.ArchiveSpanWriter(mockWriter))
 }
+
+func TestArchiveTrace_Index(t *testing.T) {
+	timeNow := time.Unix(100, 0)
+	mockWriter := &spanstoremocks.Writer{}
+	mockWriter.On("WriteSpan", mock.AnythingOfType("*model.Span")).
+		Return(nil).Times(2)
+	indexWriter := &archivestoremocks.Writer{}
+	indexWriter.On("WriteArchive", &archivestore.Archive{
+		TraceID:    mockTraceID,
+		Author:     "alice",
+		ArchivedAt: timeNow,
+		Note:       "slow checkout",
+		Labels:     []string{"latency", "checkout"},
+	}).Return(nil).Once()
+	withTestServer(t, func(ts *testServer) {
+		ts.handler.queryParser.timeNow = func() time.Time { return timeNow }
+		ts.spanReader.On("GetTrace", mockTraceID).Return(mockTrace, nil).Once()
+		var response structuredArchivesResponse
+		url := ts.server.URL + "/api/archive/" + mockTraceID.String() + "?author=alice&note=slow+checkout&label=latency&label=checkout"
+		err := postJSON(url, []string{}, &response)
+		assert.NoError(t, err)
+		var archive archive
+		assert.NoError(t, json.Unmarshal(response.Data, &archive))
+		assert.Equal(t, uiArchive, archive)
+		indexWriter.AssertExpectations(t)
+	}, HandlerOptions.ArchiveSpanWriter(mockWriter), HandlerOptions.ArchiveIndexWriter(indexWriter))
+}
+
+func TestArchiveTrace_IndexError(t *testing.T) {
+	mockWriter := &spanstoremocks.Writer{}
+	mockWriter.On("WriteSpan", mock.AnythingOfType("*model.Span")).
+		Return(nil).Times(2)
+	indexWriter := &archivestoremocks.Writer{}
+	indexWriter.On("WriteArchive", mock.AnythingOfType("*archivestore.Archive")).
+		Return(errors.New("cannot index")).Once()
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("GetTrace", mockTraceID).Return(mockTrace, nil).Once()
+		var response structuredResponse
+		err := postJSON(ts.server.URL+"/api/archive/"+mockTraceID.String(), []string{}, &response)
+		assert.EqualError(t, err, parsedError(500, "cannot index"))
+	}, HandlerOptions.ArchiveSpanWriter(mockWriter), HandlerOptions.ArchiveIndexWriter(indexWriter))
+}
+
+// structuredArchivesResponse is similar to structuredResponse but keeps `data` as raw JSON,
+// which is either an archive or a list of archives
+type structuredArchivesResponse struct {
+	Data   json.RawMessage   `json:"data"`
+	Total  int               `json:"total"`
+	Limit  int               `json:"limit"`
+	Errors []structuredError `json:"errors"`
+}
+
+var (
+	indexedArchive = &archivestore.Archive{
+		TraceID:    mockTraceID,
+		Author:     "alice",
+		ArchivedAt: time.Unix(100, 0),
+		Note:       "slow checkout",
+		Labels:     []string{"latency", "checkout"},
+	}
+	uiArchive = archive{
+		TraceID:    ui.TraceID(mockTraceID.String()),
+		Author:     "alice",
+		ArchivedAt: 100000000,
+		Note:       "slow checkout",
+		Labels:     []string{"latency", "checkout"},
+	}
+)
+
+func TestListArchives(t *testing.T) {
+	indexReader := &archivestoremocks.Reader{}
+	indexReader.On("FindArchives", &archivestore.Query{Author: "alice", Labels: []string{"latency"}, Limit: 10}).
+		Return([]*archivestore.Archive{indexedArchive}, nil).Once()
+	withTestServer(t, func(ts *testServer) {
+		var response structuredArchivesResponse
+		err := getJSON(ts.server.URL+"/api/archive?author=alice&label=latency&limit=10", &response)
+		assert.NoError(t, err)
+		var archives []archive
+		assert.NoError(t, json.Unmarshal(response.Data, &archives))
+		assert.Equal(t, []archive{uiArchive}, archives)
+		assert.Equal(t, 1, response.Total)
+		assert.Equal(t, 10, response.Limit)
+	}, HandlerOptions.ArchiveIndexReader(indexReader))
+}
+
+func TestUpdateArchive(t *testing.T) {
+	indexReader := &archivestoremocks.Reader{}
+	indexReader.On("GetArchive", mockTraceID).Return(func(model.TraceID) *archivestore.Archive {
+		archive := *indexedArchive
+		return &archive
+	}, nil).Once()
+	indexWriter := &archivestoremocks.Writer{}
+	indexWriter.On("WriteArchive", &archivestore.Archive{
+		TraceID:    mockTraceID,
+		Author:     "alice",
+		ArchivedAt: time.Unix(100, 0),
+		Note:       "fixed in 1.2",
+		Labels:     []string{"latency", "fixed"},
+	}).Return(nil).Once()
+	withTestServer(t, func(ts *testServer) {
+		url := ts.server.URL + "/api/archive/" + mockTraceID.String() + "?author=bob&note=fixed+in+1.2&label=latency&label=fixed"
+		request, err := http.NewRequest(http.MethodPut, url, nil)
+		require.NoError(t, err)
+		var response structuredArchivesResponse
+		require.NoError(t, execJSON(request, &response))
+		var archive archive
+		assert.NoError(t, json.Unmarshal(response.Data, &archive))
+		// the author and the archival time are kept
+		assert.Equal(t, "alice", archive.Author)
+		assert.Equal(t, uiArchive.ArchivedAt, archive.ArchivedAt)
+		assert.Equal(t, "fixed in 1.2", archive.Note)
+		assert.Equal(t, []string{"latency", "fixed"}, archive.Labels)
+		indexWriter.AssertExpectations(t)
+	}, HandlerOptions.ArchiveIndexReader(indexReader), HandlerOptions.ArchiveIndexWriter(indexWriter))
+}
+
+func TestUpdateArchive_Errors(t *testing.T) {
+	indexReader := &archivestoremocks.Reader{}
+	indexReader.On("GetArchive", model.TraceID{Low: 1}).Return(nil, archivestore.ErrArchiveNotFound)
+	indexReader.On("GetArchive", model.TraceID{Low: 2}).Return(nil, errors.New("storage error"))
+	indexReader.On("GetArchive", model.TraceID{Low: 3}).Return(&archivestore.Archive{TraceID: model.TraceID{Low: 3}}, nil)
+	indexWriter := &archivestoremocks.Writer{}
+	indexWriter.On("WriteArchive", mock.AnythingOfType("*archivestore.Archive")).Return(errors.New("cannot index"))
+	index := []HandlerOption{HandlerOptions.ArchiveIndexReader(indexReader), HandlerOptions.ArchiveIndexWriter(indexWriter)}
+	testCases := []struct {
+		caption  string
+		traceID  string
+		options  []HandlerOption
+		expected string
+	}{
+		{
+			caption:  "no index",
+			traceID:  mockTraceID.String(),
+			options:  []HandlerOption{HandlerOptions.ArchiveIndexWriter(indexWriter)},
+			expected: parsedError(500, "archive index was not configured"),
+		},
+		{
+			caption:  "bad trace ID",
+			traceID:  "x",
+			options:  index,
+			expected: parsedError(400, `strconv.ParseUint: parsing \"x\": invalid syntax`),
+		},
+		{
+			caption:  "not found",
+			traceID:  "1",
+			options:  index,
+			expected: parsedError(404, "archive not found"),
+		},
+		{
+			caption:  "read error",
+			traceID:  "2",
+			options:  index,
+			expected: parsedError(500, "storage error"),
+		},
+		{
+			caption:  "write error",
+			traceID:  "3",
+			options:  index,
+			expected: parsedError(500, "cannot index"),
+		},
+	}
+	for _, tc := range testCases {
+		testCase := tc // capture loop var
+		t.Run(testCase.caption, func(t *testing.T) {
+			withTestServer(t, func(ts *testServer) {
+				request, err := http.NewRequest(http.MethodPut, ts.server.URL+"/api/archive/"+testCase.traceID+"?note=x", nil)
+				require.NoError(t, err)
+				var response structuredResponse
+				err = execJSON(request, &response)
+				assert.EqualError(t, err, testCase.expected)
+			}, testCase.options...)
+		})
+	}
+}
+
+func TestListArchives_Errors(t *testing.T) {
+	indexReader := &archivestoremocks.Reader{}
+	indexReader.On("FindArchives", mock.AnythingOfType("*archivestore.Query")).
+		Return(nil, errors.New("storage error")).Once()
+	testCases := []struct {
+		caption  string
+		url      string
+		options  []HandlerOption
+		expected string
+	}{
+		{
+			caption:  "no index",
+			url:      "/api/archive",
+			expected: parsedError(500, "archive index was not configured"),
+		},
+		{
+			caption:  "bad request",
+			url:      "/api/archive?limit=string",
+			options:  []HandlerOption{HandlerOptions.ArchiveIndexReader(indexReader)},
+			expected: parsedError(400, `Could not parse limit: strconv.ParseInt: parsing \"string\": invalid syntax`),
+		},
+		{
+			caption:  "storage error",
+			url:      "/api/archive",
+			options:  []HandlerOption{HandlerOptions.ArchiveIndexReader(indexReader)},
+			expected: parsedError(500, "storage error"),
+		},
+	}
+	for _, tc := range testCases {
+		testCase := tc // capture loop var
+		t.Run(testCase.caption, func(t *testing.T) {
+			withTestServer(t, func(ts *testServer) {
+				var response structuredResponse
+				err := getJSON(ts.server.URL+testCase.url, &response)
+				assert.EqualError(t, err, testCase.expected)
+			}, testCase.options...)
+		})
+	}
+}
+
+func TestDeleteArchive(t *testing.T) {
+	indexWriter := &archivestoremocks.Writer{}
+	indexWriter.On("DeleteArchive", mockTraceID).Return(nil).Once()
+	indexWriter.On("DeleteArchive", model.TraceID{Low: 1}).Return(archivestore.ErrArchiveNotFound).Once()
+	indexWriter.On("DeleteArchive", model.TraceID{Low: 2}).Return(errors.New("storage error")).Once()
+	testCases := []struct {
+		caption  string
+		traceID  string
+		options  []HandlerOption
+		expected string
+	}{
+		{
+			caption:  "no index",
+			traceID:  mockTraceID.String(),
+			expected: parsedError(500, "archive index was not configured"),
+		},
+		{
+			caption: "success",
+			traceID: mockTraceID.String(),
+			options: []HandlerOption{HandlerOptions.ArchiveIndexWriter(indexWriter)},
+		},
+		{
+			caption:  "bad trace ID",
+			traceID:  "x",
+			options:  []HandlerOption{HandlerOptions.ArchiveIndexWriter(indexWriter)},
+			expected: parsedError(400, `strconv.ParseUint: parsing \"x\": invalid syntax`),
+		},
+		{
+			caption:  "not found",
+			traceID:  "1",
+			options:  []HandlerOption{HandlerOptions.ArchiveIndexWriter(indexWriter)},
+			expected: parsedError(404, "archive not found"),
+		},
+		{
+			caption:  "storage error",
+			traceID:  "2",
+			options:  []HandlerOption{HandlerOptions.ArchiveIndexWriter(indexWriter)},
+			expected: parsedError(500, "storage error"),
+		},
+	}
+	for _, tc := range testCases {
+		testCase := tc // capture loop var
+		t.Run(testCase.caption, func(t *testing.T) {
+			withTestServer(t, func(ts *testServer) {
+				request, err := http.NewRequest(http.MethodDelete, ts.server.URL+"/api/archive/"+testCase.traceID, nil)
+				require.NoError(t, err)
+				var response structuredResponse
+				err = execJSON(request, &response)
+				if testCase.expected == "" {
+					assert.NoError(t, err)
+				} else {
+					assert.EqualError(t, err, testCase.expected)
+				}
+			}, testCase.options...)
+		})
+	}
+	indexWriter.AssertExpectations(t)
+}
@@ -0,0 +1,174 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package app
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/model/adjuster"
+	"github.com/uber/jaeger/model/analysis"
+	"github.com/uber/jaeger/storage/spanstore"
+	spanstoremocks "github.com/uber/jaeger/storage/spanstore/mocks"
+)
+
+// structuredCompareResponse is similar to structuredResponse but defines `data`
+// explicitly as *analysis.TraceComparison, making it easier to parse & validate.
+type structuredCompareResponse struct {
+	Comparison *analysis.TraceComparison `json:"data"`
+	Errors     []structuredError         `json:"errors"`
+}
+
+func compareTestTrace(traceID model.TraceID, rootDuration, childDuration time.Duration) *model.Trace {
+	start := time.Unix(1500000000, 0)
+	process := &model.Process{ServiceName: "frontend"}
+	return &model.Trace{
+		Spans: []*model.Span{
+			{
+				TraceID:       traceID,
+				SpanID:        model.SpanID(1),
+				OperationName: "GET",
+				StartTime:     start,
+				Duration:      rootDuration,
+				Process:       process,
+			},
+			{
+				TraceID:       traceID,
+				SpanID:        model.SpanID(2),
+				ParentSpanID:  model.SpanID(1),
+				OperationName: "render",
+				StartTime:     start.Add(time.Millisecond),
+				Duration:      childDuration,
+				Process:       process,
+			},
+		},
+	}
+}
+
+func TestCompareTracesSuccess(t *testing.T) {
+	traceIDA, traceIDB := model.TraceID{Low: 1}, model.TraceID{Low: 2}
+	archiveReader := &spanstoremocks.Reader{}
+	archiveReader.On("GetTrace", traceIDB).
+		Return(compareTestTrace(traceIDB, 30*time.Millisecond, 20*time.Millisecond), nil).Once()
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("GetTrace", traceIDA).
+			Return(compareTestTrace(traceIDA, 10*time.Millisecond, 5*time.Millisecond), nil).Once()
+		ts.spanReader.On("GetTrace", traceIDB).
+			Return(nil, spanstore.ErrTraceNotFound).Once()
+
+		var response structuredCompareResponse
+		err := getJSON(ts.server.URL+"/api/traces/compare?a="+traceIDA.String()+"&b="+traceIDB.String(), &response)
+		require.NoError(t, err)
+		assert.Empty(t, response.Errors)
+		c := response.Comparison
+		require.NotNil(t, c)
+		assert.Equal(t, traceIDA, c.TraceIDA)
+		assert.Equal(t, traceIDB, c.TraceIDB)
+		assert.Equal(t, 20*time.Millisecond, c.DurationDelta)
+		assert.Equal(t, analysis.CompareSummary{Matched: 2}, c.Summary)
+		require.Len(t, c.Nodes, 2)
+		assert.Equal(t, "/frontend:GET/frontend:render", c.Nodes[1].Path)
+		assert.Equal(t, 15*time.Millisecond, c.Nodes[1].DurationDelta)
+	}, HandlerOptions.ArchiveSpanReader(archiveReader))
+}
+
+func TestCompareTracesAdjustmentFailure(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("GetTrace", mock.AnythingOfType("model.TraceID")).
+			Return(compareTestTrace(mockTraceID, time.Second, time.Millisecond), nil).Twice()
+
+		var response structuredCompareResponse
+		err := getJSON(ts.server.URL+"/api/traces/compare?a=1&b=2", &response)
+		require.NoError(t, err)
+		require.Len(t, response.Errors, 2)
+		assert.Equal(t, errAdjustment.Error(), response.Errors[0].Msg)
+		assert.EqualValues(t, "1", response.Errors[0].TraceID)
+		assert.EqualValues(t, "2", response.Errors[1].TraceID)
+		assert.Equal(t, analysis.CompareSummary{Matched: 2}, response.Comparison.Summary)
+	}, HandlerOptions.Adjusters(
+		adjuster.Func(func(trace *model.Trace) (*model.Trace, error) {
+			return trace, errAdjustment
+		}),
+	))
+}
+
+func TestCompareTracesFailures(t *testing.T) {
+	testCases := []struct {
+		name   string
+		query  string
+		code   int
+		err    string
+		mockFn func(reader *spanstoremocks.Reader)
+	}{
+		{
+			name:  "missing trace ID",
+			query: "?a=1",
+			code:  http.StatusBadRequest,
+			err:   errNoTracesToCompare.Error(),
+		},
+		{
+			name:  "bad trace ID",
+			query: "?a=1&b=chumbawumba",
+			code:  http.StatusBadRequest,
+			err:   `strconv.ParseUint: parsing \"chumbawumba\": invalid syntax`,
+		},
+		{
+			name:  "trace not found",
+			query: "?a=1&b=2",
+			code:  http.StatusNotFound,
+			err:   spanstore.ErrTraceNotFound.Error(),
+			mockFn: func(reader *spanstoremocks.Reader) {
+				reader.On("GetTrace", mock.AnythingOfType("model.TraceID")).
+					Return(nil, spanstore.ErrTraceNotFound).Once()
+			},
+		},
+		{
+			name:  "storage failure",
+			query: "?a=1&b=2",
+			code:  http.StatusInternalServerError,
+			err:   errStorageMsg,
+			mockFn: func(reader *spanstoremocks.Reader) {
+				reader.On("GetTrace", mock.AnythingOfType("model.TraceID")).
+					Return(nil, errStorage).Once()
+			},
+		},
+	}
+	for _, tc := range testCases {
+		testCase := tc // capture loop var
+		t.Run(testCase.name, func(t *testing.T) {
+			withTestServer(t, func(ts *testServer) {
+				if testCase.mockFn != nil {
+					testCase.mockFn(ts.spanReader)
+				}
+				var response structuredCompareResponse
+				err := getJSON(ts.server.URL+"/api/traces/compare"+testCase.query, &response)
+				assert.EqualError(t, err, parsedError(testCase.code, testCase.err))
+			})
+		})
+	}
+}
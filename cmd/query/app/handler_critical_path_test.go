@@ -0,0 +1,110 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package app
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/model/adjuster"
+	"github.com/uber/jaeger/model/analysis"
+	"github.com/uber/jaeger/storage/spanstore"
+	spanstoremocks "github.com/uber/jaeger/storage/spanstore/mocks"
+)
+
+// structuredCriticalPathResponse is similar to structuredResponse but defines `data`
+// explicitly as *analysis.CriticalPath, making it easier to parse & validate.
+type structuredCriticalPathResponse struct {
+	CriticalPath *analysis.CriticalPath `json:"data"`
+	Errors       []structuredError      `json:"errors"`
+}
+
+func TestGetCriticalPathSuccess(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("GetTrace", mockTraceID).
+			Return(compareTestTrace(mockTraceID, 10*time.Millisecond, 6*time.Millisecond), nil).Once()
+
+		var response structuredCriticalPathResponse
+		err := getJSON(ts.server.URL+"/api/traces/"+mockTraceID.String()+"/critical-path", &response)
+		require.NoError(t, err)
+		assert.Empty(t, response.Errors)
+		path := response.CriticalPath
+		require.NotNil(t, path)
+		assert.Equal(t, mockTraceID, path.TraceID)
+		assert.Equal(t, 10*time.Millisecond, path.Duration)
+		require.Len(t, path.Spans, 2)
+		assert.Equal(t, 4*time.Millisecond, path.Spans[0].SelfTime)
+		assert.Equal(t, 6*time.Millisecond, path.Spans[1].SelfTime)
+		assert.Equal(t, "render", path.SelfTimes[0].Operation)
+	})
+}
+
+func TestGetCriticalPathFromArchive(t *testing.T) {
+	archiveReader := &spanstoremocks.Reader{}
+	archiveReader.On("GetTrace", mockTraceID).
+		Return(compareTestTrace(mockTraceID, 10*time.Millisecond, 6*time.Millisecond), nil).Once()
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("GetTrace", mockTraceID).
+			Return(nil, spanstore.ErrTraceNotFound).Once()
+
+		var response structuredCriticalPathResponse
+		err := getJSON(ts.server.URL+"/api/traces/"+mockTraceID.String()+"/critical-path", &response)
+		require.NoError(t, err)
+		assert.Len(t, response.CriticalPath.Spans, 2)
+	}, HandlerOptions.ArchiveSpanReader(archiveReader))
+}
+
+func TestGetCriticalPathAdjustmentFailure(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("GetTrace", mock.AnythingOfType("model.TraceID")).
+			Return(compareTestTrace(mockTraceID, time.Second, time.Millisecond), nil).Once()
+
+		var response structuredCriticalPathResponse
+		err := getJSON(ts.server.URL+"/api/traces/"+mockTraceID.String()+"/critical-path", &response)
+		require.NoError(t, err)
+		require.Len(t, response.Errors, 1)
+		assert.Equal(t, errAdjustment.Error(), response.Errors[0].Msg)
+		assert.Len(t, response.CriticalPath.Spans, 2)
+	}, HandlerOptions.Adjusters(
+		adjuster.Func(func(trace *model.Trace) (*model.Trace, error) {
+			return trace, errAdjustment
+		}),
+	))
+}
+
+func TestGetCriticalPathFailures(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("GetTrace", mock.AnythingOfType("model.TraceID")).
+			Return(nil, spanstore.ErrTraceNotFound).Once()
+		err := getJSON(ts.server.URL+"/api/traces/"+mockTraceID.String()+"/critical-path", nil)
+		assert.EqualError(t, err, parsedError(http.StatusNotFound, spanstore.ErrTraceNotFound.Error()))
+
+		err = getJSON(ts.server.URL+"/api/traces/chumbawumba/critical-path", nil)
+		assert.EqualError(t, err, parsedError(http.StatusBadRequest, `strconv.ParseUint: parsing \"chumbawumba\": invalid syntax`))
+	})
+}
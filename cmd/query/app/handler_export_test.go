@@ -0,0 +1,111 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package app
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/model/adjuster"
+	"github.com/uber/jaeger/model/converter/chrome"
+	"github.com/uber/jaeger/model/converter/zipkinv2"
+	"github.com/uber/jaeger/storage/spanstore"
+	spanstoremocks "github.com/uber/jaeger/storage/spanstore/mocks"
+)
+
+func TestGetTraceExportFormats(t *testing.T) {
+	exportURL := func(ts *testServer, format string) string {
+		return ts.server.URL + "/api/traces/" + mockTraceID.String() + "?format=" + format
+	}
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("GetTrace", mockTraceID).
+			Return(compareTestTrace(mockTraceID, 10*time.Millisecond, 5*time.Millisecond), nil)
+
+		var zipkinSpans []*zipkinv2.Span
+		require.NoError(t, getJSON(exportURL(ts, "zipkin-v2"), &zipkinSpans))
+		require.Len(t, zipkinSpans, 2)
+		assert.Equal(t, "000000000001e240", zipkinSpans[0].TraceID)
+		assert.Equal(t, "frontend", zipkinSpans[0].LocalEndpoint.ServiceName)
+		assert.Equal(t, "0000000000000001", zipkinSpans[1].ParentID)
+
+		var chromeTrace chrome.Trace
+		require.NoError(t, getJSON(exportURL(ts, "chrome"), &chromeTrace))
+		assert.Len(t, chromeTrace.TraceEvents, 3)
+		assert.Equal(t, mockTraceID.String(), chromeTrace.OtherData["traceID"])
+
+		var trace model.Trace
+		require.NoError(t, getJSON(exportURL(ts, "model"), &trace))
+		require.Len(t, trace.Spans, 2)
+		assert.Equal(t, model.SpanID(1), trace.Spans[0].SpanID)
+		assert.Equal(t, "render", trace.Spans[1].OperationName)
+
+		var response structuredTraceResponse
+		require.NoError(t, getJSON(exportURL(ts, "ui"), &response))
+		assert.Len(t, response.Traces, 1)
+	})
+}
+
+func TestGetTraceExportFromArchive(t *testing.T) {
+	archiveReader := &spanstoremocks.Reader{}
+	archiveReader.On("GetTrace", mockTraceID).
+		Return(compareTestTrace(mockTraceID, 10*time.Millisecond, 5*time.Millisecond), nil).Once()
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("GetTrace", mockTraceID).
+			Return(nil, spanstore.ErrTraceNotFound).Once()
+		var zipkinSpans []*zipkinv2.Span
+		require.NoError(t, getJSON(ts.server.URL+"/api/traces/"+mockTraceID.String()+"?format=zipkin-v2", &zipkinSpans))
+		assert.Len(t, zipkinSpans, 2)
+	}, HandlerOptions.ArchiveSpanReader(archiveReader))
+}
+
+func TestGetTraceExportAdjustmentFailure(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("GetTrace", mock.AnythingOfType("model.TraceID")).
+			Return(compareTestTrace(mockTraceID, time.Second, time.Millisecond), nil).Once()
+		var trace model.Trace
+		require.NoError(t, getJSON(ts.server.URL+"/api/traces/"+mockTraceID.String()+"?format=model", &trace))
+		assert.Len(t, trace.Spans, 2)
+	}, HandlerOptions.Adjusters(
+		adjuster.Func(func(trace *model.Trace) (*model.Trace, error) {
+			return trace, errAdjustment
+		}),
+	))
+}
+
+func TestGetTraceExportFailures(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		err := getJSON(ts.server.URL+"/api/traces/"+mockTraceID.String()+"?format=pdf", nil)
+		assert.EqualError(t, err, parsedError(http.StatusBadRequest,
+			`unsupported trace format \"pdf\", expected one of \"ui\", \"zipkin-v2\", \"chrome\" or \"model\"`))
+
+		ts.spanReader.On("GetTrace", mock.AnythingOfType("model.TraceID")).
+			Return(nil, spanstore.ErrTraceNotFound).Once()
+		err = getJSON(ts.server.URL+"/api/traces/"+mockTraceID.String()+"?format=chrome", nil)
+		assert.EqualError(t, err, parsedError(http.StatusNotFound, spanstore.ErrTraceNotFound.Error()))
+	})
+}
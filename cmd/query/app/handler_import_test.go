@@ -0,0 +1,101 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/storage/spanstore/memory"
+	spanstoremocks "github.com/uber/jaeger/storage/spanstore/mocks"
+)
+
+type structuredImportResponse struct {
+	TraceIDs []string          `json:"data"`
+	Total    int               `json:"total"`
+	Errors   []structuredError `json:"errors"`
+}
+
+func TestImportTracesRoundTrip(t *testing.T) {
+	for _, format := range []string{"ui", "zipkin-v2"} {
+		t.Run(format, func(t *testing.T) {
+			store := memory.NewStore()
+			withTestServer(t, func(ts *testServer) {
+				ts.spanReader.On("GetTrace", mockTraceID).
+					Return(compareTestTrace(mockTraceID, 10*time.Millisecond, 5*time.Millisecond), nil).Once()
+
+				var exported json.RawMessage
+				require.NoError(t, getJSON(ts.server.URL+"/api/traces/"+mockTraceID.String()+"?format="+format, &exported))
+
+				var response structuredImportResponse
+				require.NoError(t, postJSON(ts.server.URL+"/api/traces/import", exported, &response))
+				assert.Equal(t, []string{mockTraceID.String()}, response.TraceIDs)
+				assert.Equal(t, 1, response.Total)
+
+				trace, err := store.GetTrace(mockTraceID)
+				require.NoError(t, err)
+				require.Len(t, trace.Spans, 2)
+				assert.Equal(t, "frontend", trace.Spans[0].Process.ServiceName)
+				assert.Equal(t, model.SpanID(1), trace.Spans[1].ParentSpanID)
+				assert.Equal(t, "render", trace.Spans[1].OperationName)
+			}, HandlerOptions.ImportSpanWriter(store))
+		})
+	}
+}
+
+func TestImportTracesNoStorage(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		var response structuredResponse
+		err := postJSON(ts.server.URL+"/api/traces/import", []string{}, &response)
+		assert.EqualError(t, err, `500 error from server: {"data":null,"total":0,"limit":0,"offset":0,"errors":[{"code":500,"msg":"import span storage was not configured"}]}`+"\n")
+	})
+}
+
+func TestImportTracesBadRequest(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		var response structuredResponse
+		err := postJSON(ts.server.URL+"/api/traces/import", map[string]string{"foo": "bar"}, &response)
+		assert.EqualError(t, err, `400 error from server: {"data":null,"total":0,"limit":0,"offset":0,"errors":[{"code":400,"msg":"unrecognized trace format, expected the UI JSON returned by /api/traces or an array of Zipkin v2 spans"}]}`+"\n")
+	}, HandlerOptions.ImportSpanWriter(memory.NewStore()))
+}
+
+func TestImportTracesWriteErrors(t *testing.T) {
+	mockWriter := &spanstoremocks.Writer{}
+	mockWriter.On("WriteSpan", mock.AnythingOfType("*model.Span")).
+		Return(errors.New("cannot save")).Times(2)
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("GetTrace", mockTraceID).Return(mockTrace, nil).Once()
+		var exported json.RawMessage
+		require.NoError(t, getJSON(ts.server.URL+"/api/traces/"+mockTraceID.String(), &exported))
+
+		var response structuredResponse
+		err := postJSON(ts.server.URL+"/api/traces/import", exported, &response)
+		assert.EqualError(t, err, `500 error from server: {"data":null,"total":0,"limit":0,"offset":0,"errors":[{"code":500,"msg":"[cannot write span 1: cannot save, cannot write span 2: cannot save]"}]}`+"\n")
+	}, HandlerOptions.ImportSpanWriter(mockWriter))
+}
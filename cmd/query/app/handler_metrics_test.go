@@ -0,0 +1,109 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package app
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/storage/metricstore"
+	metricstoremocks "github.com/uber/jaeger/storage/metricstore/mocks"
+)
+
+type structuredMetricResponse struct {
+	Points []metricPoint     `json:"data"`
+	Total  int               `json:"total"`
+	Errors []structuredError `json:"errors"`
+}
+
+var mockMetricPoints = []model.MetricPoint{
+	{Timestamp: time.Unix(60, 0), Value: 12.5},
+	{Timestamp: time.Unix(120, 0), Value: 0.25},
+}
+
+func TestGetMetrics(t *testing.T) {
+	tests := []struct {
+		route  string
+		method string
+	}{
+		{route: "/api/metrics/latencies", method: "GetLatencies"},
+		{route: "/api/metrics/calls", method: "GetCallRates"},
+		{route: "/api/metrics/errors", method: "GetErrorRates"},
+	}
+	for _, tc := range tests {
+		test := tc // capture loop var
+		t.Run(test.method, func(t *testing.T) {
+			reader := &metricstoremocks.Reader{}
+			withTestServer(t, func(ts *testServer) {
+				reader.On(test.method, &metricstore.QueryParameters{
+					ServiceName:   "frontend",
+					OperationName: "render",
+					EndTime:       time.Unix(180, 0),
+					Lookback:      2 * time.Minute,
+					Step:          time.Minute,
+					Quantile:      0.99,
+				}).Return(mockMetricPoints, nil).Once()
+
+				var response structuredMetricResponse
+				err := getJSON(ts.server.URL+test.route+"?service=frontend&operation=render&quantile=0.99&endTs=180000&lookback=120000&step=60000", &response)
+				require.NoError(t, err)
+				assert.Equal(t, []metricPoint{{Timestamp: 60000, Value: 12.5}, {Timestamp: 120000, Value: 0.25}}, response.Points)
+				assert.Equal(t, 2, response.Total)
+				assert.Len(t, response.Errors, 0)
+				reader.AssertExpectations(t)
+			}, HandlerOptions.MetricReader(reader))
+		})
+	}
+}
+
+func TestGetMetricsNoStorage(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		var response structuredResponse
+		err := getJSON(ts.server.URL+"/api/metrics/latencies?service=frontend", &response)
+		assert.EqualError(t, err, parsedError(500, "metric storage was not configured"))
+	})
+}
+
+func TestGetMetricsBadRequest(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		var response structuredResponse
+		err := getJSON(ts.server.URL+"/api/metrics/calls", &response)
+		assert.EqualError(t, err, parsedError(400, "Parameter 'service' is required"))
+	}, HandlerOptions.MetricReader(&metricstoremocks.Reader{}))
+}
+
+func TestGetMetricsStorageFailure(t *testing.T) {
+	reader := &metricstoremocks.Reader{}
+	reader.On("GetErrorRates", mock.AnythingOfType("*metricstore.QueryParameters")).
+		Return(nil, errors.New("storage error")).Once()
+	withTestServer(t, func(ts *testServer) {
+		var response structuredResponse
+		err := getJSON(ts.server.URL+"/api/metrics/errors?service=frontend", &response)
+		assert.EqualError(t, err, parsedError(500, "storage error"))
+	}, HandlerOptions.MetricReader(reader))
+}
@@ -27,6 +27,8 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/uber/jaeger/model/adjuster"
+	"github.com/uber/jaeger/storage/archivestore"
+	"github.com/uber/jaeger/storage/metricstore"
 	"github.com/uber/jaeger/storage/spanstore"
 )
 
@@ -67,6 +69,13 @@ func (handlerOptions) QueryLookbackDuration(queryLookbackDuration time.Duration)
 	}
 }
 
+// MetricReader creates a HandlerOption that initializes the reader of the span-derived RED metrics
+func (handlerOptions) MetricReader(reader metricstore.Reader) HandlerOption {
+	return func(apiHandler *APIHandler) {
+		apiHandler.metricReader = reader
+	}
+}
+
 // ArchiveSpanReader creates a HandlerOption that initializes lookback duration
 func (handlerOptions) ArchiveSpanReader(reader spanstore.Reader) HandlerOption {
 	return func(apiHandler *APIHandler) {
@@ -81,6 +90,29 @@ func (handlerOptions) ArchiveSpanWriter(writer spanstore.Writer) HandlerOption {
 	}
 }
 
+// ArchiveIndexReader creates a HandlerOption that initializes the reader of the archive index,
+// which lists the archived traces
+func (handlerOptions) ArchiveIndexReader(reader archivestore.Reader) HandlerOption {
+	return func(apiHandler *APIHandler) {
+		apiHandler.archiveIndexReader = reader
+	}
+}
+
+// ArchiveIndexWriter creates a HandlerOption that initializes the writer of the archive index,
+// which records who archived a trace and why
+func (handlerOptions) ArchiveIndexWriter(writer archivestore.Writer) HandlerOption {
+	return func(apiHandler *APIHandler) {
+		apiHandler.archiveIndexWriter = writer
+	}
+}
+
+// ImportSpanWriter creates a HandlerOption that initializes the span writer used by the trace import endpoint
+func (handlerOptions) ImportSpanWriter(writer spanstore.Writer) HandlerOption {
+	return func(apiHandler *APIHandler) {
+		apiHandler.importSpanWriter = writer
+	}
+}
+
 // Tracer creates a HandlerOption that initializes OpenTracing tracer
 func (handlerOptions) Tracer(tracer opentracing.Tracer) HandlerOption {
 	return func(apiHandler *APIHandler) {
@@ -23,7 +23,6 @@ package app
 import (
 	"bytes"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -33,6 +32,7 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.uber.org/zap"
@@ -41,6 +41,7 @@ import (
 	"github.com/uber/jaeger/model"
 	"github.com/uber/jaeger/model/adjuster"
 	ui "github.com/uber/jaeger/model/json"
+	casSpanstore "github.com/uber/jaeger/plugin/storage/cassandra/spanstore"
 	depsmocks "github.com/uber/jaeger/storage/dependencystore/mocks"
 	"github.com/uber/jaeger/storage/spanstore"
 	spanstoremocks "github.com/uber/jaeger/storage/spanstore/mocks"
@@ -215,8 +216,8 @@ func TestGetTraceBadTraceID(t *testing.T) {
 func TestSearchSuccess(t *testing.T) {
 	server, readMock, _ := initializeTestServer()
 	defer server.Close()
-	readMock.On("FindTraces", mock.AnythingOfType("*spanstore.TraceQueryParameters")).
-		Return([]*model.Trace{mockTrace}, nil).Once()
+	readMock.On("FindTracePage", mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+		Return(&spanstore.TracePage{Traces: []*model.Trace{mockTrace}, Total: spanstore.UnknownTotal}, nil).Once()
 
 	var response structuredResponse
 	err := getJSON(server.URL+`/api/traces?service=service&start=0&end=0&operation=operation&limit=200&minDuration=20ms`, &response)
@@ -224,6 +225,81 @@ func TestSearchSuccess(t *testing.T) {
 	assert.Len(t, response.Errors, 0)
 }
 
+func TestSearchPage(t *testing.T) {
+	server, readMock, _ := initializeTestServer()
+	defer server.Close()
+	readMock.On("FindTracePage", mock.MatchedBy(func(query *spanstore.TraceQueryParameters) bool {
+		return query.Cursor == "page-2"
+	})).Return(&spanstore.TracePage{
+		Traces:     []*model.Trace{mockTrace},
+		NextCursor: "page-3",
+		Offset:     20,
+		Total:      45,
+	}, nil).Once()
+
+	var response structuredResponse
+	err := getJSON(server.URL+`/api/traces?service=service&start=0&end=0&limit=20&cursor=page-2`, &response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Errors, 0)
+	assert.Len(t, response.Data, 1)
+	assert.Equal(t, 20, response.Limit)
+	assert.Equal(t, 20, response.Offset)
+	assert.Equal(t, 45, response.Total)
+	assert.Equal(t, "page-3", response.NextCursor)
+}
+
+func TestSearchInvalidCursor(t *testing.T) {
+	server, readMock, _ := initializeTestServer()
+	defer server.Close()
+	readMock.On("FindTracePage", mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+		Return(nil, spanstore.ErrInvalidCursor).Once()
+
+	var response structuredResponse
+	err := getJSON(server.URL+`/api/traces?service=service&start=0&end=0&cursor=bogus`, &response)
+	assert.EqualError(t, err, parsedError(400, spanstore.ErrInvalidCursor.Error()))
+}
+
+func TestSearchUnsupportedTagQuery(t *testing.T) {
+	server, readMock, _ := initializeTestServer()
+	defer server.Close()
+	unsupported := &spanstore.UnsupportedTagQueryError{
+		Storage: "Cassandra",
+		Query:   &spanstore.TagExists{Key: "error"},
+	}
+	readMock.On("FindTracePage", mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+		Return(nil, unsupported).Once()
+
+	var response structuredResponse
+	err := getJSON(server.URL+`/api/traces?service=service&tagQuery=error:*`, &response)
+	assert.EqualError(t, err, parsedError(400, "Cassandra span storage does not support the tag query error:*"))
+}
+
+func TestSearchWrappedUnsupportedTagQuery(t *testing.T) {
+	server, readMock, _ := initializeTestServer()
+	defer server.Close()
+	unsupported := &spanstore.UnsupportedTagQueryError{
+		Storage: "Cassandra",
+		Query:   &spanstore.TagExists{Key: "error"},
+	}
+	readMock.On("FindTracePage", mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+		Return(nil, errors.Wrap(unsupported, "cannot find traces")).Once()
+
+	var response structuredResponse
+	err := getJSON(server.URL+`/api/traces?service=service&tagQuery=error:*`, &response)
+	assert.EqualError(t, err, parsedError(400, "cannot find traces: Cassandra span storage does not support the tag query error:*"))
+}
+
+func TestSearchTagAndDurationUnsupported(t *testing.T) {
+	server, readMock, _ := initializeTestServer()
+	defer server.Close()
+	readMock.On("FindTracePage", mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+		Return(nil, casSpanstore.ErrDurationAndTagQueryNotSupported).Once()
+
+	var response structuredResponse
+	err := getJSON(server.URL+`/api/traces?service=service&minDuration=20ms&tag=k:v`, &response)
+	assert.EqualError(t, err, parsedError(400, "Cassandra span storage does not support tag queries with a duration"))
+}
+
 func TestSearchByTraceIDSuccess(t *testing.T) {
 	server, readMock, _ := initializeTestServer()
 	defer server.Close()
@@ -271,8 +347,8 @@ func TestSearchModelConversionFailure(t *testing.T) {
 		),
 	)
 	defer server.Close()
-	readMock.On("FindTraces", mock.AnythingOfType("*spanstore.TraceQueryParameters")).
-		Return([]*model.Trace{mockTrace}, nil).Once()
+	readMock.On("FindTracePage", mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+		Return(&spanstore.TracePage{Traces: []*model.Trace{mockTrace}, Total: spanstore.UnknownTotal}, nil).Once()
 	var response structuredResponse
 	err := getJSON(server.URL+`/api/traces?service=service&start=0&end=0&operation=operation&limit=200&minDuration=20ms`, &response)
 	assert.NoError(t, err)
@@ -283,7 +359,7 @@ func TestSearchModelConversionFailure(t *testing.T) {
 func TestSearchDBFailure(t *testing.T) {
 	server, readMock, _ := initializeTestServer()
 	defer server.Close()
-	readMock.On("FindTraces", mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+	readMock.On("FindTracePage", mock.AnythingOfType("*spanstore.TraceQueryParameters")).
 		Return(nil, fmt.Errorf("whatsamattayou")).Once()
 
 	var response structuredResponse
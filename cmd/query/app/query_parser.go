@@ -30,6 +30,8 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/storage/archivestore"
+	"github.com/uber/jaeger/storage/metricstore"
 	"github.com/uber/jaeger/storage/spanstore"
 )
 
@@ -38,17 +40,32 @@ const (
 
 	operationParam   = "operation"
 	tagParam         = "tag"
+	tagQueryParam    = "tagQuery"
 	startTimeParam   = "start"
 	limitParam       = "limit"
 	minDurationParam = "minDuration"
 	maxDurationParam = "maxDuration"
 	serviceParam     = "service"
 	endTimeParam     = "end"
+	cursorParam      = "cursor"
+	quantileParam    = "quantile"
+	stepParam        = "step"
+	authorParam      = "author"
+	noteParam        = "note"
+	labelParam       = "label"
+	textParam        = "text"
+
+	defaultMetricQuantile = 0.95
+	defaultMetricStep     = time.Minute
+	defaultMetricLookback = time.Hour
+	maxMetricSteps        = 10000
 )
 
 var (
-	errCannotQueryTagAndDuration = fmt.Errorf("Cannot query for tags when '%s' is specified", minDurationParam)
 	errMaxDurationGreaterThanMin = fmt.Errorf("'%s' should be greater than '%s'", maxDurationParam, minDurationParam)
+	errInvalidQuantile           = fmt.Errorf("'%s' should be greater than 0 and at most 1", quantileParam)
+	errNonPositiveMetricStep     = fmt.Errorf("'%s' and '%s' should be greater than 0", stepParam, lookbackParam)
+	errTooManyMetricSteps        = fmt.Errorf("'%s' should be at most %d times '%s'", lookbackParam, maxMetricSteps, stepParam)
 
 	// ErrServiceParameterRequired occurs when no service name is defined
 	ErrServiceParameterRequired = fmt.Errorf("Parameter '%s' is required", serviceParam)
@@ -68,7 +85,7 @@ type traceQueryParameters struct {
 // parse takes a request and constructs a model of parameters
 // Trace query syntax:
 //     query ::= param | param '&' query
-//     param ::= service | operation | limit | start | end | minDuration | maxDuration | tag
+//     param ::= service | operation | limit | start | end | minDuration | maxDuration | tag | tagQuery | cursor
 //     service ::= 'service=' strValue
 //     operation ::= 'operation=' strValue
 //     limit ::= 'limit=' intValue
@@ -79,6 +96,8 @@ type traceQueryParameters struct {
 //     tag ::= 'tag=' key | 'tag=' keyvalue
 //     key := strValue
 //     keyValue := strValue ':' strValue
+//     tagQuery ::= 'tagQuery=' strValue in the syntax of tagQueryParser
+//     cursor ::= 'cursor=' strValue returned as nextCursor by the previous page
 func (p *queryParser) parse(r *http.Request) (*traceQueryParameters, error) {
 	service := r.FormValue(serviceParam)
 	operation := r.FormValue(operationParam)
@@ -107,16 +126,18 @@ func (p *queryParser) parse(r *http.Request) (*traceQueryParameters, error) {
 		limit = int(limitParsed)
 	}
 
+	var tagQuery spanstore.TagQuery
+	if value := r.FormValue(tagQueryParam); value != "" {
+		if tagQuery, err = parseTagQuery(value); err != nil {
+			return nil, err
+		}
+	}
+
 	minDuration, err := p.parseDuration(minDurationParam, r)
 	if err != nil {
 		return nil, err
 	}
 
-	if minDuration != 0 && len(tags) > 0 {
-		// This is because querying for this almost certainly returns no results due to intersections
-		return nil, errCannotQueryTagAndDuration
-	}
-
 	maxDuration, err := p.parseDuration(maxDurationParam, r)
 	if err != nil {
 		return nil, err
@@ -138,9 +159,11 @@ func (p *queryParser) parse(r *http.Request) (*traceQueryParameters, error) {
 			StartTimeMin:  startTime,
 			StartTimeMax:  endTime,
 			Tags:          tags,
+			TagQuery:      tagQuery,
 			NumTraces:     limit,
 			DurationMin:   minDuration,
 			DurationMax:   maxDuration,
+			Cursor:        r.FormValue(cursorParam),
 		},
 		traceIDs: traceIDs,
 	}
@@ -151,6 +174,93 @@ func (p *queryParser) parse(r *http.Request) (*traceQueryParameters, error) {
 	return traceQuery, nil
 }
 
+// parseMetricQuery takes a request and constructs the parameters of a metrics query
+// Metric query syntax:
+//     query ::= param | param '&' query
+//     param ::= service | operation | quantile | step | endTs | lookback
+//     service ::= 'service=' strValue
+//     operation ::= 'operation=' strValue
+//     quantile ::= 'quantile=' floatValue in (0, 1], only used for latencies
+//     step ::= 'step=' intValue in milliseconds
+//     endTs ::= 'endTs=' intValue in unix milliseconds
+//     lookback ::= 'lookback=' intValue in milliseconds
+func (p *queryParser) parseMetricQuery(r *http.Request) (*metricstore.QueryParameters, error) {
+	query := &metricstore.QueryParameters{
+		ServiceName:   r.FormValue(serviceParam),
+		OperationName: r.FormValue(operationParam),
+		EndTime:       p.timeNow(),
+		Lookback:      defaultMetricLookback,
+		Step:          defaultMetricStep,
+		Quantile:      defaultMetricQuantile,
+	}
+	if query.ServiceName == "" {
+		return nil, ErrServiceParameterRequired
+	}
+	if value := r.FormValue(quantileParam); value != "" {
+		quantile, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Could not parse %s", quantileParam)
+		}
+		if !(quantile > 0 && quantile <= 1) {
+			return nil, errInvalidQuantile
+		}
+		query.Quantile = quantile
+	}
+	if value := r.FormValue(endTsParam); value != "" {
+		millis, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Could not parse %s", endTsParam)
+		}
+		query.EndTime = time.Unix(0, 0).Add(time.Duration(millis) * time.Millisecond)
+	}
+	if err := p.parseMillis(stepParam, r, &query.Step); err != nil {
+		return nil, err
+	}
+	if err := p.parseMillis(lookbackParam, r, &query.Lookback); err != nil {
+		return nil, err
+	}
+	if query.Step <= 0 || query.Lookback <= 0 {
+		return nil, errNonPositiveMetricStep
+	}
+	if query.Lookback/query.Step > maxMetricSteps {
+		return nil, errTooManyMetricSteps
+	}
+	return query, nil
+}
+
+// parseArchiveQuery takes a request and constructs the parameters of a search of the archived traces
+// Archive query syntax:
+//     query ::= param | param '&' query
+//     param ::= author | label | text | start | end | limit
+//     author ::= 'author=' strValue
+//     label ::= 'label=' strValue, repeated labels must all be present
+//     text ::= 'text=' strValue contained in the note, ignoring case
+//     start ::= 'start=' intValue in unix microseconds
+//     end ::= 'end=' intValue in unix microseconds
+//     limit ::= 'limit=' intValue
+func (p *queryParser) parseArchiveQuery(r *http.Request) (*archivestore.Query, error) {
+	query := &archivestore.Query{
+		Author:     r.FormValue(authorParam),
+		Labels:     r.Form[labelParam],
+		Text:       r.FormValue(textParam),
+		Limit:      defaultQueryLimit,
+	}
+	if value := r.FormValue(limitParam); value != "" {
+		limit, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Could not parse %s", limitParam)
+		}
+		query.Limit = int(limit)
+	}
+	if err := p.parseMicros(startTimeParam, r, &query.ArchivedAfter); err != nil {
+		return nil, err
+	}
+	if err := p.parseMicros(endTimeParam, r, &query.ArchivedBefore); err != nil {
+		return nil, err
+	}
+	return query, nil
+}
+
 func (p *queryParser) parseTime(param string, r *http.Request) (time.Time, error) {
 	value := r.FormValue(param)
 	if value == "" {
@@ -166,6 +276,34 @@ func (p *queryParser) parseTime(param string, r *http.Request) (time.Time, error
 	return time.Unix(0, 0).Add(time.Duration(micros) * time.Microsecond), nil
 }
 
+// parseMillis overrides duration with the value of param in milliseconds, if present
+func (p *queryParser) parseMillis(param string, r *http.Request, duration *time.Duration) error {
+	value := r.FormValue(param)
+	if value == "" {
+		return nil
+	}
+	millis, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return errors.Wrapf(err, "Could not parse %s", param)
+	}
+	*duration = time.Duration(millis) * time.Millisecond
+	return nil
+}
+
+// parseMicros overrides ts with the value of param in unix microseconds, if present
+func (p *queryParser) parseMicros(param string, r *http.Request, ts *time.Time) error {
+	value := r.FormValue(param)
+	if value == "" {
+		return nil
+	}
+	micros, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return errors.Wrapf(err, "Could not parse %s", param)
+	}
+	*ts = time.Unix(0, 0).Add(time.Duration(micros) * time.Microsecond)
+	return nil
+}
+
 func (p *queryParser) parseDuration(durationParam string, r *http.Request) (time.Duration, error) {
 	durationInput := r.FormValue(durationParam)
 	if len(durationInput) > 0 {
@@ -29,6 +29,8 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/storage/archivestore"
+	"github.com/uber/jaeger/storage/metricstore"
 	"github.com/uber/jaeger/storage/spanstore"
 )
 
@@ -47,7 +49,6 @@ func TestParseTraceQuery(t *testing.T) {
 		{"x?service=service&limit=string", errParseInt, nil},
 		{"x?service=service&start=0&end=0&operation=operation&limit=200&minDuration=20", "Could not parse minDuration: time: missing unit in duration 20", nil},
 		{"x?service=service&start=0&end=0&operation=operation&limit=200&minDuration=20s&maxDuration=30", "Could not parse maxDuration: time: missing unit in duration 30", nil},
-		{"x?service=service&start=0&end=0&operation=operation&limit=200&tag=k:v&minDuration=1s", `Cannot query for tags when 'minDuration' is specified`, nil},
 		{"x?service=service&start=0&end=0&operation=operation&limit=200&tag=k:v&tag=x:y&tag=k&log=k:v&log=k", `Malformed 'tag' parameter, expecting key:value, received: k`, nil},
 		{"x?service=service&start=0&end=0&operation=operation&limit=200&minDuration=25s&maxDuration=1s", `'maxDuration' should be greater than 'minDuration'`, nil},
 		{"x?service=service&start=0&end=0&operation=operation&limit=200&tag=k:v&tag=x:y", ``,
@@ -62,6 +63,24 @@ func TestParseTraceQuery(t *testing.T) {
 				},
 			},
 		},
+		{"x?service=service&start=0&end=0&operation=operation&limit=200&tagQuery=k:", "Could not parse tagQuery: missing value of 'k' at position 2", nil},
+		{"x?service=service&start=0&end=0&operation=operation&limit=200&tag=k:v&minDuration=1s&tagQuery=http.status_code:[500+TO+*]+OR+error:true", ``,
+			&traceQueryParameters{
+				TraceQueryParameters: spanstore.TraceQueryParameters{
+					ServiceName:   "service",
+					OperationName: "operation",
+					StartTimeMin:  time.Unix(0, 0),
+					StartTimeMax:  time.Unix(0, 0),
+					NumTraces:     200,
+					DurationMin:   time.Second,
+					Tags:          map[string]string{"k": "v"},
+					TagQuery: &spanstore.TagOr{Queries: []spanstore.TagQuery{
+						&spanstore.TagRange{Key: "http.status_code", Min: floatPointer(500)},
+						&spanstore.TagEquals{Key: "error", Value: "true"},
+					}},
+				},
+			},
+		},
 		{"x?service=service&start=0&end=0&operation=operation&limit=200&minDuration=10s&maxDuration=20s", ``,
 			&traceQueryParameters{
 				TraceQueryParameters: spanstore.TraceQueryParameters{
@@ -89,6 +108,18 @@ func TestParseTraceQuery(t *testing.T) {
 				},
 			},
 		},
+		{"x?service=service&start=0&end=0&limit=20&cursor=eyJvZmZzZXQiOjIwfQ", ``,
+			&traceQueryParameters{
+				TraceQueryParameters: spanstore.TraceQueryParameters{
+					ServiceName:  "service",
+					StartTimeMin: time.Unix(0, 0),
+					StartTimeMax: time.Unix(0, 0),
+					NumTraces:    20,
+					Tags:         make(map[string]string),
+					Cursor:       "eyJvZmZzZXQiOjIwfQ",
+				},
+			},
+		},
 		{"x?traceID=100&traceID=200", ``,
 			&traceQueryParameters{
 				TraceQueryParameters: spanstore.TraceQueryParameters{
@@ -142,3 +173,99 @@ func TestParseTraceQuery(t *testing.T) {
 		})
 	}
 }
+
+func TestParseMetricQuery(t *testing.T) {
+	timeNow := time.Now()
+	tests := []struct {
+		urlStr        string
+		errMsg        string
+		expectedQuery *metricstore.QueryParameters
+	}{
+		{"x", "Parameter 'service' is required", nil},
+		{"x?service=service&quantile=string", `Could not parse quantile: strconv.ParseFloat: parsing "string": invalid syntax`, nil},
+		{"x?service=service&quantile=0", `'quantile' should be greater than 0 and at most 1`, nil},
+		{"x?service=service&quantile=1.5", `'quantile' should be greater than 0 and at most 1`, nil},
+		{"x?service=service&endTs=string", "Could not parse endTs: " + errParseInt, nil},
+		{"x?service=service&step=string", "Could not parse step: " + errParseInt, nil},
+		{"x?service=service&lookback=string", "Could not parse lookback: " + errParseInt, nil},
+		{"x?service=service&step=0", `'step' and 'lookback' should be greater than 0`, nil},
+		{"x?service=service&lookback=-1", `'step' and 'lookback' should be greater than 0`, nil},
+		{"x?service=service&step=1&lookback=86400000", `'lookback' should be at most 10000 times 'step'`, nil},
+		{"x?service=service", "",
+			&metricstore.QueryParameters{
+				ServiceName: "service",
+				EndTime:     timeNow,
+				Lookback:    time.Hour,
+				Step:        time.Minute,
+				Quantile:    0.95,
+			},
+		},
+		{"x?service=service&operation=operation&quantile=0.5&endTs=60000&step=5000&lookback=30000", "",
+			&metricstore.QueryParameters{
+				ServiceName:   "service",
+				OperationName: "operation",
+				EndTime:       time.Unix(60, 0),
+				Lookback:      30 * time.Second,
+				Step:          5 * time.Second,
+				Quantile:      0.5,
+			},
+		},
+	}
+	for _, tc := range tests {
+		test := tc // capture loop var
+		t.Run(test.urlStr, func(t *testing.T) {
+			request, err := http.NewRequest(http.MethodGet, test.urlStr, nil)
+			assert.NoError(t, err)
+			parser := &queryParser{
+				timeNow: func() time.Time {
+					return timeNow
+				},
+			}
+			actualQuery, err := parser.parseMetricQuery(request)
+			if test.errMsg == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedQuery, actualQuery)
+			} else {
+				assert.EqualError(t, err, test.errMsg)
+			}
+		})
+	}
+}
+
+func TestParseArchiveQuery(t *testing.T) {
+	tests := []struct {
+		urlStr        string
+		errMsg        string
+		expectedQuery *archivestore.Query
+	}{
+		{"x?limit=string", "Could not parse limit: " + errParseInt, nil},
+		{"x?start=string", "Could not parse start: " + errParseInt, nil},
+		{"x?end=string", "Could not parse end: " + errParseInt, nil},
+		{"x", "", &archivestore.Query{Limit: 100}},
+		{"x?author=alice&label=latency&label=checkout&text=slow&start=100&end=200&limit=5", "",
+			&archivestore.Query{
+				Author:         "alice",
+				Labels:         []string{"latency", "checkout"},
+				Text:           "slow",
+				ArchivedAfter:  time.Unix(0, 100000),
+				ArchivedBefore: time.Unix(0, 200000),
+				Limit:          5,
+			},
+		},
+	}
+	for _, tc := range tests {
+		test := tc // capture loop var
+		t.Run(test.urlStr, func(t *testing.T) {
+			request, err := http.NewRequest(http.MethodGet, test.urlStr, nil)
+			assert.NoError(t, err)
+			parser := &queryParser{}
+			actualQuery, err := parser.parseArchiveQuery(request)
+			if test.errMsg == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedQuery, actualQuery)
+			} else {
+				assert.EqualError(t, err, test.errMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/uber/jaeger/storage/spanstore"
+)
+
+const (
+	andKeyword   = "AND"
+	orKeyword    = "OR"
+	notKeyword   = "NOT"
+	rangeKeyword = "TO"
+	wildcard     = "*"
+)
+
+// tagQueryParser parses a tag query into a spanstore.TagQuery
+// Tag query syntax:
+//     query ::= and | and 'OR' query
+//     and ::= unary | unary and | unary 'AND' and
+//     unary ::= 'NOT' unary | '(' query ')' | term
+//     term ::= key ':' value | key ':' prefix '*' | key ':' '*' | key ':' regex | key ':' range
+//     key ::= strValue without whitespace, parentheses or ':'
+//     value ::= strValue without whitespace or parentheses | '"' strValue with '\"' and '\\' escapes '"'
+//     regex ::= '/' strValue with '\/' escapes '/'
+//     range ::= '[' bound 'TO' bound ']' of numeric values, inclusive
+//     bound ::= floatValue | '*'
+type tagQueryParser struct {
+	input string
+	pos   int
+}
+
+// parseTagQuery parses the value of the tagQuery parameter
+func parseTagQuery(input string) (spanstore.TagQuery, error) {
+	p := &tagQueryParser{input: input}
+	query, err := p.parseOr()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Could not parse %s", tagQueryParam)
+	}
+	if p.skipSpaces(); p.pos < len(p.input) {
+		return nil, errors.Errorf("Could not parse %s: unexpected '%c' at position %d", tagQueryParam, p.input[p.pos], p.pos)
+	}
+	return query, nil
+}
+
+func (p *tagQueryParser) parseOr() (spanstore.TagQuery, error) {
+	var queries []spanstore.TagQuery
+	for {
+		query, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, query)
+		if p.peekWord() != orKeyword {
+			break
+		}
+		p.pos += len(orKeyword)
+	}
+	if len(queries) == 1 {
+		return queries[0], nil
+	}
+	return &spanstore.TagOr{Queries: queries}, nil
+}
+
+func (p *tagQueryParser) parseAnd() (spanstore.TagQuery, error) {
+	var queries []spanstore.TagQuery
+	for {
+		query, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, query)
+		if p.skipSpaces(); p.pos == len(p.input) || p.input[p.pos] == ')' {
+			break
+		}
+		word := p.peekWord()
+		if word == orKeyword {
+			break
+		}
+		if word == andKeyword {
+			p.pos += len(andKeyword)
+		}
+	}
+	if len(queries) == 1 {
+		return queries[0], nil
+	}
+	return &spanstore.TagAnd{Queries: queries}, nil
+}
+
+func (p *tagQueryParser) parseUnary() (spanstore.TagQuery, error) {
+	if p.skipSpaces(); p.pos == len(p.input) {
+		return nil, errors.New("unexpected end of query")
+	}
+	if p.input[p.pos] == '(' {
+		p.pos++
+		query, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.skipSpaces(); p.pos == len(p.input) || p.input[p.pos] != ')' {
+			return nil, fmt.Errorf("missing ')' at position %d", p.pos)
+		}
+		p.pos++
+		return query, nil
+	}
+	if p.peekWord() == notKeyword {
+		p.pos += len(notKeyword)
+		query, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &spanstore.TagNot{Query: query}, nil
+	}
+	return p.parseTerm()
+}
+
+func (p *tagQueryParser) parseTerm() (spanstore.TagQuery, error) {
+	start := p.pos
+	for p.pos < len(p.input) && p.input[p.pos] != ':' && !isTagQueryDelimiter(p.input[p.pos]) {
+		p.pos++
+	}
+	key := p.input[start:p.pos]
+	if key == "" || p.pos == len(p.input) || p.input[p.pos] != ':' {
+		return nil, fmt.Errorf("expecting key:value at position %d", start)
+	}
+	p.pos++
+	if p.pos == len(p.input) {
+		return nil, fmt.Errorf("missing value of '%s' at position %d", key, p.pos)
+	}
+	switch p.input[p.pos] {
+	case '"':
+		value, err := p.readDelimited('"', true)
+		if err != nil {
+			return nil, err
+		}
+		return &spanstore.TagEquals{Key: key, Value: value}, nil
+	case '/':
+		pattern, err := p.readDelimited('/', false)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, errors.Wrapf(err, "invalid regex of '%s'", key)
+		}
+		return &spanstore.TagRegexp{Key: key, Pattern: pattern}, nil
+	case '[':
+		return p.parseRange(key)
+	}
+	start = p.pos
+	for p.pos < len(p.input) && !isTagQueryDelimiter(p.input[p.pos]) {
+		p.pos++
+	}
+	value := p.input[start:p.pos]
+	switch {
+	case value == "":
+		return nil, fmt.Errorf("missing value of '%s' at position %d", key, start)
+	case value == wildcard:
+		return &spanstore.TagExists{Key: key}, nil
+	case strings.HasSuffix(value, wildcard):
+		return &spanstore.TagPrefix{Key: key, Prefix: strings.TrimSuffix(value, wildcard)}, nil
+	}
+	return &spanstore.TagEquals{Key: key, Value: value}, nil
+}
+
+func (p *tagQueryParser) parseRange(key string) (spanstore.TagQuery, error) {
+	start := p.pos
+	end := strings.IndexByte(p.input[start:], ']')
+	if end < 0 {
+		return nil, fmt.Errorf("missing ']' of the range at position %d", start)
+	}
+	p.pos = start + end + 1
+	bounds := strings.Fields(p.input[start+1 : start+end])
+	if len(bounds) != 3 || bounds[1] != rangeKeyword {
+		return nil, fmt.Errorf("expecting a range [min TO max] at position %d", start)
+	}
+	query := &spanstore.TagRange{Key: key}
+	for i, bound := range []**float64{&query.Min, &query.Max} {
+		value := bounds[2*i]
+		if value == wildcard {
+			continue
+		}
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid bound of the range of '%s'", key)
+		}
+		*bound = &number
+	}
+	if query.Min != nil && query.Max != nil && *query.Min > *query.Max {
+		return nil, fmt.Errorf("the range of '%s' is empty", key)
+	}
+	return query, nil
+}
+
+// readDelimited reads the string between the delimiter at the current position and the next unescaped one.
+// Escaped delimiters are unescaped, and so are escaped backslashes if unescapeBackslash.
+func (p *tagQueryParser) readDelimited(delimiter byte, unescapeBackslash bool) (string, error) {
+	start := p.pos
+	var value []byte
+	for p.pos++; p.pos < len(p.input); p.pos++ {
+		c := p.input[p.pos]
+		if c == delimiter {
+			p.pos++
+			return string(value), nil
+		}
+		if c == '\\' && p.pos+1 < len(p.input) {
+			if next := p.input[p.pos+1]; next == delimiter || (unescapeBackslash && next == '\\') {
+				c = next
+				p.pos++
+			}
+		}
+		value = append(value, c)
+	}
+	return "", fmt.Errorf("missing closing '%c' of the value at position %d", delimiter, start)
+}
+
+// peekWord returns the word at the current position, after spaces, without consuming it
+func (p *tagQueryParser) peekWord() string {
+	p.skipSpaces()
+	end := p.pos
+	for end < len(p.input) && !isTagQueryDelimiter(p.input[end]) {
+		end++
+	}
+	return p.input[p.pos:end]
+}
+
+func (p *tagQueryParser) skipSpaces() {
+	for p.pos < len(p.input) && isSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+func isTagQueryDelimiter(c byte) bool {
+	return isSpace(c) || c == '(' || c == ')'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -0,0 +1,139 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/uber/jaeger/storage/spanstore"
+)
+
+func floatPointer(f float64) *float64 {
+	return &f
+}
+
+func TestParseTagQuery(t *testing.T) {
+	tests := []struct {
+		input    string
+		errMsg   string
+		expected spanstore.TagQuery
+	}{
+		{
+			input:    "error:true",
+			expected: &spanstore.TagEquals{Key: "error", Value: "true"},
+		},
+		{
+			input:    "http.url:http://example.com/a:b",
+			expected: &spanstore.TagEquals{Key: "http.url", Value: "http://example.com/a:b"},
+		},
+		{
+			input:    `message:"a \"quoted\" (value) with \\ and *"`,
+			expected: &spanstore.TagEquals{Key: "message", Value: `a "quoted" (value) with \ and *`},
+		},
+		{
+			input:    "http.url:https://*",
+			expected: &spanstore.TagPrefix{Key: "http.url", Prefix: "https://"},
+		},
+		{
+			input:    "peer.service:*",
+			expected: &spanstore.TagExists{Key: "peer.service"},
+		},
+		{
+			input:    `http.url:/.*\/users\/[0-9]+/`,
+			expected: &spanstore.TagRegexp{Key: "http.url", Pattern: `.*/users/[0-9]+`},
+		},
+		{
+			input:    "retries:[1 TO 3.5]",
+			expected: &spanstore.TagRange{Key: "retries", Min: floatPointer(1), Max: floatPointer(3.5)},
+		},
+		{
+			input:    "size:[* TO 1e3]",
+			expected: &spanstore.TagRange{Key: "size", Max: floatPointer(1000)},
+		},
+		{
+			input: "a:1 b:2 AND c:3",
+			expected: &spanstore.TagAnd{Queries: []spanstore.TagQuery{
+				&spanstore.TagEquals{Key: "a", Value: "1"},
+				&spanstore.TagEquals{Key: "b", Value: "2"},
+				&spanstore.TagEquals{Key: "c", Value: "3"},
+			}},
+		},
+		{
+			input: "a:1 OR b:2 c:3",
+			expected: &spanstore.TagOr{Queries: []spanstore.TagQuery{
+				&spanstore.TagEquals{Key: "a", Value: "1"},
+				&spanstore.TagAnd{Queries: []spanstore.TagQuery{
+					&spanstore.TagEquals{Key: "b", Value: "2"},
+					&spanstore.TagEquals{Key: "c", Value: "3"},
+				}},
+			}},
+		},
+		{
+			input: " NOT (a:1 OR b:2) AND NOT:x ",
+			expected: &spanstore.TagAnd{Queries: []spanstore.TagQuery{
+				&spanstore.TagNot{Query: &spanstore.TagOr{Queries: []spanstore.TagQuery{
+					&spanstore.TagEquals{Key: "a", Value: "1"},
+					&spanstore.TagEquals{Key: "b", Value: "2"},
+				}}},
+				&spanstore.TagEquals{Key: "NOT", Value: "x"},
+			}},
+		},
+		{input: "", errMsg: "Could not parse tagQuery: unexpected end of query"},
+		{input: "a:1 OR", errMsg: "Could not parse tagQuery: unexpected end of query"},
+		{input: "a", errMsg: "Could not parse tagQuery: expecting key:value at position 0"},
+		{input: ":a", errMsg: "Could not parse tagQuery: expecting key:value at position 0"},
+		{input: "a: b:1", errMsg: "Could not parse tagQuery: missing value of 'a' at position 2"},
+		{input: "(a:1", errMsg: "Could not parse tagQuery: missing ')' at position 4"},
+		{input: "a:1)", errMsg: "Could not parse tagQuery: unexpected ')' at position 3"},
+		{input: `a:"b`, errMsg: `Could not parse tagQuery: missing closing '"' of the value at position 2`},
+		{input: "a:/(/", errMsg: "Could not parse tagQuery: invalid regex of 'a': error parsing regexp: missing closing ): `(`"},
+		{input: "a:[1 TO 2", errMsg: "Could not parse tagQuery: missing ']' of the range at position 2"},
+		{input: "a:[1 2]", errMsg: "Could not parse tagQuery: expecting a range [min TO max] at position 2"},
+		{input: "a:[x TO 2]", errMsg: `Could not parse tagQuery: invalid bound of the range of 'a': strconv.ParseFloat: parsing "x": invalid syntax`},
+		{input: "a:[2 TO 1]", errMsg: "Could not parse tagQuery: the range of 'a' is empty"},
+	}
+	for _, tc := range tests {
+		test := tc // capture loop var
+		t.Run(test.input, func(t *testing.T) {
+			query, err := parseTagQuery(test.input)
+			if test.errMsg == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, query)
+			} else {
+				assert.EqualError(t, err, test.errMsg)
+			}
+		})
+	}
+}
+
+func TestTagQueryString(t *testing.T) {
+	input := `NOT (a:"x \"y\"" OR b:c*) AND d:* AND e:/f\/g/ AND h:[1 TO *]`
+	query, err := parseTagQuery(input)
+	assert.NoError(t, err)
+	assert.Equal(t, `(NOT (a:"x \"y\"" OR b:c*) AND d:* AND e:/f\/g/ AND h:[1 TO *])`, query.String())
+
+	reparsed, err := parseTagQuery(query.String())
+	assert.NoError(t, err)
+	assert.Equal(t, query, reparsed)
+}
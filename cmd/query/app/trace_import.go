@@ -0,0 +1,165 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+	uiconv "github.com/uber/jaeger/model/converter/json"
+	"github.com/uber/jaeger/model/converter/zipkinv2"
+	ui "github.com/uber/jaeger/model/json"
+	"github.com/uber/jaeger/pkg/multierror"
+	"github.com/uber/jaeger/storage/spanstore"
+)
+
+var errUnrecognizedImportFormat = errors.New(
+	"unrecognized trace format, expected the UI JSON returned by /api/traces or an array of Zipkin v2 spans")
+
+// ImportFile writes the traces of the file, in any of the formats accepted by ParseTraces, through the writer.
+// It is used to load traces into the memory span storage on startup.
+func ImportFile(path string, writer spanstore.Writer, logger *zap.Logger) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(err, "cannot read the import file")
+	}
+	traces, err := ParseTraces(data)
+	if err != nil {
+		return errors.Wrap(err, "cannot parse the import file")
+	}
+	traceIDs, err := WriteTraces(traces, writer)
+	if err != nil {
+		return err
+	}
+	logger.Info("Imported traces", zap.String("file", path), zap.Int("traces", len(traceIDs)))
+	return nil
+}
+
+// ParseTraces decodes traces for import. It accepts the UI JSON format, either as the response
+// of /api/traces/{trace-id} and /api/traces, a single trace or an array of traces,
+// as well as an array of spans in the Zipkin v2 JSON format, which may span several traces.
+func ParseTraces(data []byte) ([]*model.Trace, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, errUnrecognizedImportFormat
+	}
+	switch data[0] {
+	case '{':
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, err
+		}
+		if response, ok := fields["data"]; ok {
+			return parseUITraces(response)
+		}
+		if _, ok := fields["spans"]; ok {
+			return parseUITraces(append(append([]byte{'['}, data...), ']'))
+		}
+	case '[':
+		var elements []map[string]json.RawMessage
+		if err := json.Unmarshal(data, &elements); err != nil {
+			return nil, err
+		}
+		if len(elements) == 0 {
+			return nil, nil
+		}
+		if _, ok := elements[0]["spans"]; ok {
+			return parseUITraces(data)
+		}
+		if _, ok := elements[0]["traceId"]; ok {
+			return parseZipkinV2Spans(data)
+		}
+	}
+	return nil, errUnrecognizedImportFormat
+}
+
+func parseUITraces(data []byte) ([]*model.Trace, error) {
+	var uiTraces []*ui.Trace
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// preserve the precision of int64 tags
+	decoder.UseNumber()
+	if err := decoder.Decode(&uiTraces); err != nil {
+		return nil, err
+	}
+	traces := make([]*model.Trace, 0, len(uiTraces))
+	for _, uiTrace := range uiTraces {
+		trace, err := uiconv.TraceToDomain(uiTrace)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot convert trace %s", uiTrace.TraceID)
+		}
+		traces = append(traces, trace)
+	}
+	return traces, nil
+}
+
+func parseZipkinV2Spans(data []byte) ([]*model.Trace, error) {
+	var zSpans []*zipkinv2.Span
+	if err := json.Unmarshal(data, &zSpans); err != nil {
+		return nil, err
+	}
+	trace, err := zipkinv2.ToDomain(zSpans)
+	if err != nil {
+		return nil, err
+	}
+	return splitByTraceID(trace.Spans), nil
+}
+
+// splitByTraceID groups spans into traces, in the order in which the traces first appear
+func splitByTraceID(spans []*model.Span) []*model.Trace {
+	var traces []*model.Trace
+	tracesByID := make(map[model.TraceID]*model.Trace)
+	for _, span := range spans {
+		trace, ok := tracesByID[span.TraceID]
+		if !ok {
+			trace = &model.Trace{}
+			tracesByID[span.TraceID] = trace
+			traces = append(traces, trace)
+		}
+		trace.Spans = append(trace.Spans, span)
+	}
+	return traces
+}
+
+// WriteTraces writes all spans of the traces through the writer, and returns the IDs of the imported traces.
+// Writing continues after a failed span, the failures are reported in the returned error.
+func WriteTraces(traces []*model.Trace, writer spanstore.Writer) ([]model.TraceID, error) {
+	var writeErrors []error
+	traceIDs := make([]model.TraceID, 0, len(traces))
+	for _, trace := range traces {
+		if len(trace.Spans) == 0 {
+			continue
+		}
+		for _, span := range trace.Spans {
+			if err := writer.WriteSpan(span); err != nil {
+				writeErrors = append(writeErrors, fmt.Errorf("cannot write span %v: %v", span.SpanID, err))
+			}
+		}
+		traceIDs = append(traceIDs, trace.Spans[0].TraceID)
+	}
+	return traceIDs, multierror.Wrap(writeErrors)
+}
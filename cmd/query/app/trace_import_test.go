@@ -0,0 +1,129 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/storage/spanstore/memory"
+)
+
+const importTestUITrace = `{
+	"traceID": "1e240",
+	"spans": [
+		{"traceID": "1e240", "spanID": "1", "operationName": "GET", "startTime": 1000, "duration": 10, "processID": "p1"},
+		{"traceID": "1e240", "spanID": "2", "operationName": "render", "startTime": 1001, "duration": 5, "processID": "p1",
+		 "references": [{"refType": "CHILD_OF", "traceID": "1e240", "spanID": "1"}],
+		 "tags": [{"key": "big", "type": "int64", "value": 9007199254740993}]}
+	],
+	"processes": {"p1": {"serviceName": "frontend", "tags": []}}
+}`
+
+func TestParseTracesUI(t *testing.T) {
+	testCases := map[string]string{
+		"single trace":   importTestUITrace,
+		"array":          "[" + importTestUITrace + "]",
+		"query response": `{"data": [` + importTestUITrace + `], "total": 1, "errors": null}`,
+	}
+	for name, input := range testCases {
+		t.Run(name, func(t *testing.T) {
+			traces, err := ParseTraces([]byte(input))
+			require.NoError(t, err)
+			require.Len(t, traces, 1)
+			require.Len(t, traces[0].Spans, 2)
+			child := traces[0].Spans[1]
+			assert.Equal(t, model.TraceID{Low: 123456}, child.TraceID)
+			assert.Equal(t, model.SpanID(1), child.ParentSpanID)
+			assert.Nil(t, child.References)
+			assert.Equal(t, "frontend", child.Process.ServiceName)
+			assert.Equal(t, model.KeyValues{model.Int64("big", 9007199254740993)}, child.Tags)
+		})
+	}
+}
+
+func TestParseTracesZipkinV2(t *testing.T) {
+	traces, err := ParseTraces([]byte(`[
+		{"traceId": "000000000000000a", "id": "0000000000000001", "name": "get", "localEndpoint": {"serviceName": "a"}},
+		{"traceId": "000000000000000b", "id": "0000000000000002", "name": "get", "localEndpoint": {"serviceName": "b"}},
+		{"traceId": "000000000000000a", "id": "0000000000000003", "parentId": "0000000000000001", "name": "put", "localEndpoint": {"serviceName": "a"}}
+	]`))
+	require.NoError(t, err)
+	require.Len(t, traces, 2)
+	require.Len(t, traces[0].Spans, 2)
+	assert.Equal(t, model.TraceID{Low: 10}, traces[0].Spans[1].TraceID)
+	assert.Equal(t, model.SpanID(1), traces[0].Spans[1].ParentSpanID)
+	require.Len(t, traces[1].Spans, 1)
+	assert.Equal(t, "b", traces[1].Spans[0].Process.ServiceName)
+}
+
+func TestParseTracesErrors(t *testing.T) {
+	testCases := []struct {
+		input string
+		err   string
+	}{
+		{input: "", err: errUnrecognizedImportFormat.Error()},
+		{input: `"trace"`, err: errUnrecognizedImportFormat.Error()},
+		{input: `[{"foo": "bar"}]`, err: errUnrecognizedImportFormat.Error()},
+		{input: `{"foo"`, err: "unexpected end of JSON input"},
+		{input: `[{"traceId": "x", "id": "1"}]`, err: `invalid Zipkin trace ID "x": strconv.ParseUint: parsing "x": invalid syntax`},
+		{
+			input: `{"traceID": "1", "spans": [{"traceID": "1", "spanID": "1", "processID": "p1"}]}`,
+			err:   "cannot convert trace 1: span 1 references unknown process p1",
+		},
+	}
+	for _, testCase := range testCases {
+		traces, err := ParseTraces([]byte(testCase.input))
+		assert.Nil(t, traces, testCase.input)
+		assert.EqualError(t, err, testCase.err, testCase.input)
+	}
+}
+
+func TestParseTracesEmpty(t *testing.T) {
+	traces, err := ParseTraces([]byte(" [] "))
+	assert.NoError(t, err)
+	assert.Empty(t, traces)
+}
+
+func TestImportFile(t *testing.T) {
+	file, err := ioutil.TempFile("", "traces")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(importTestUITrace)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	store := memory.NewStore()
+	require.NoError(t, ImportFile(file.Name(), store, zap.NewNop()))
+	trace, err := store.GetTrace(model.TraceID{Low: 123456})
+	require.NoError(t, err)
+	assert.Len(t, trace.Spans, 2)
+
+	err = ImportFile(file.Name()+".missing", store, zap.NewNop())
+	assert.Contains(t, err.Error(), "cannot read the import file")
+}
@@ -41,8 +41,12 @@ import (
 	"github.com/uber/jaeger/cmd/query/app"
 	"github.com/uber/jaeger/cmd/query/app/builder"
 	"github.com/uber/jaeger/pkg/config"
+	"github.com/uber/jaeger/pkg/config/tlscfg"
 	"github.com/uber/jaeger/pkg/healthcheck"
 	"github.com/uber/jaeger/pkg/recoveryhandler"
+	"github.com/uber/jaeger/storage/spanstore"
+	"github.com/uber/jaeger/storage/spanstore/cache"
+	"github.com/uber/jaeger/storage/spanstore/memory"
 )
 
 func main() {
@@ -52,6 +56,8 @@ func main() {
 	logger, _ := zap.NewProduction()
 	casOptions := casFlags.NewOptions("cassandra", "cassandra.archive")
 	esOptions := esFlags.NewOptions("es", "es.archive")
+	tlsOptions := tlscfg.NewOptions("query")
+	healthCheckTLSOptions := tlscfg.NewOptions("query.health-check")
 	v := viper.New()
 
 	var command = &cobra.Command{
@@ -61,32 +67,81 @@ func main() {
 		Run: func(cmd *cobra.Command, args []string) {
 			casOptions.InitFromViper(v)
 			esOptions.InitFromViper(v)
+			tlsOptions.InitFromViper(v)
+			healthCheckTLSOptions.InitFromViper(v)
 			queryOpts := new(builder.QueryOptions).InitFromViper(v)
 			sFlags := new(flags.SharedFlags).InitFromViper(v)
 
-			hc, err := healthcheck.Serve(http.StatusServiceUnavailable, queryOpts.QueryHealthCheckHTTPPort, logger)
+			tlsConfig, err := tlscfg.NewServerConfig(tlsOptions, logger)
+			if err != nil {
+				logger.Fatal("Could not load the TLS certificates", zap.Error(err))
+			}
+			healthCheckTLSConfig, err := tlscfg.NewServerConfig(healthCheckTLSOptions, logger)
+			if err != nil {
+				logger.Fatal("Could not load the health check TLS certificates", zap.Error(err))
+			}
+
+			hc, err := healthcheck.ServeTLS(http.StatusServiceUnavailable, queryOpts.QueryHealthCheckHTTPPort, healthCheckTLSConfig, logger)
 			if err != nil {
 				logger.Fatal("Could not start the health check server.", zap.Error(err))
 			}
 
 			metricsFactory := xkit.Wrap("jaeger-query", expvar.NewFactory(10))
 
-			storageBuild, err := builder.NewStorageBuilder(
-				sFlags.SpanStorage.Type,
-				sFlags.DependencyStorage.DataFrequency,
+			storageOptions := []basicB.Option{
 				basicB.Options.LoggerOption(logger),
 				basicB.Options.MetricsFactoryOption(metricsFactory),
 				basicB.Options.CassandraSessionOption(casOptions.GetPrimary()),
 				basicB.Options.ElasticClientOption(esOptions.GetPrimary()),
+			}
+			// the memory store is created here so that traces can also be imported into it
+			var memoryStore *memory.Store
+			if sFlags.SpanStorage.Type == flags.MemoryStorageType {
+				memoryStore = memory.NewStore()
+				storageOptions = append(storageOptions, basicB.Options.MemoryStoreOption(memoryStore))
+			}
+			storageBuild, err := builder.NewStorageBuilder(
+				sFlags.SpanStorage.Type,
+				sFlags.DependencyStorage.DataFrequency,
+				storageOptions...,
 			)
 			if err != nil {
 				logger.Fatal("Failed to init storage builder", zap.Error(err))
 			}
+			handlerOptions := []app.HandlerOption{
+				app.HandlerOptions.Prefix(queryOpts.QueryPrefix),
+				app.HandlerOptions.Logger(logger),
+				app.HandlerOptions.MetricReader(storageBuild.MetricReader),
+				app.HandlerOptions.ArchiveIndexReader(storageBuild.ArchiveIndexReader),
+				app.HandlerOptions.ArchiveIndexWriter(storageBuild.ArchiveIndexWriter),
+			}
+			spanReader := storageBuild.SpanReader
+			var readCache *cache.ReadCacheDecorator
+			if queryOpts.Cache.MaxTraces > 0 {
+				readCache = cache.NewReadCacheDecorator(spanReader, queryOpts.Cache, metricsFactory.Namespace("cache", nil))
+				spanReader = readCache
+			}
+			if memoryStore != nil {
+				var importSpanWriter spanstore.Writer = memoryStore
+				if readCache != nil {
+					// the imported traces replace the cached ones
+					importSpanWriter = cache.NewInvalidatingWriter(memoryStore, readCache)
+				}
+				handlerOptions = append(handlerOptions, app.HandlerOptions.ImportSpanWriter(importSpanWriter))
+			}
+			if queryOpts.QueryImportFile != "" {
+				if memoryStore == nil {
+					logger.Fatal("Traces can only be imported on startup with the memory span storage",
+						zap.String("span-storage.type", sFlags.SpanStorage.Type))
+				}
+				if err := app.ImportFile(queryOpts.QueryImportFile, memoryStore, logger); err != nil {
+					logger.Fatal("Could not import traces", zap.String("file", queryOpts.QueryImportFile), zap.Error(err))
+				}
+			}
 			rHandler := app.NewAPIHandler(
-				storageBuild.SpanReader,
+				spanReader,
 				storageBuild.DependencyReader,
-				app.HandlerOptions.Prefix(queryOpts.QueryPrefix),
-				app.HandlerOptions.Logger(logger))
+				handlerOptions...)
 			sHandler := app.NewStaticAssetsHandler(queryOpts.QueryStaticAssets)
 			r := mux.NewRouter()
 			rHandler.RegisterRoutes(r)
@@ -96,7 +151,8 @@ func main() {
 
 			go func() {
 				logger.Info("Starting jaeger-query HTTP server", zap.Int("port", queryOpts.QueryPort))
-				if err := http.ListenAndServe(portStr, recoveryHandler(r)); err != nil {
+				server := &http.Server{Addr: portStr, Handler: recoveryHandler(r)}
+				if err := tlscfg.ListenAndServe(server, tlsConfig); err != nil {
 					logger.Fatal("Could not launch service", zap.Error(err))
 				}
 				hc.Set(http.StatusInternalServerError)
@@ -118,6 +174,8 @@ func main() {
 		casOptions.AddFlags,
 		esOptions.AddFlags,
 		builder.AddFlags,
+		tlsOptions.AddFlags,
+		healthCheckTLSOptions.AddFlags,
 	)
 
 	if error := command.Execute(); error != nil {
@@ -28,6 +28,7 @@ import (
 	"strconv"
 
 	"github.com/gorilla/mux"
+	"github.com/opentracing/opentracing-go"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	jaegerClientConfig "github.com/uber/jaeger-client-go/config"
@@ -42,13 +43,16 @@ import (
 	basic "github.com/uber/jaeger/cmd/builder"
 	collectorApp "github.com/uber/jaeger/cmd/collector/app"
 	collector "github.com/uber/jaeger/cmd/collector/app/builder"
-	"github.com/uber/jaeger/cmd/collector/app/zipkin"
 	"github.com/uber/jaeger/cmd/flags"
 	queryApp "github.com/uber/jaeger/cmd/query/app"
 	query "github.com/uber/jaeger/cmd/query/app/builder"
 	"github.com/uber/jaeger/pkg/config"
+	"github.com/uber/jaeger/pkg/httpapi"
+	"github.com/uber/jaeger/pkg/httpapi/zipkin"
 	pMetrics "github.com/uber/jaeger/pkg/metrics"
 	"github.com/uber/jaeger/pkg/recoveryhandler"
+	"github.com/uber/jaeger/storage/spanstore"
+	"github.com/uber/jaeger/storage/spanstore/cache"
 	"github.com/uber/jaeger/storage/spanstore/memory"
 	jc "github.com/uber/jaeger/thrift-gen/jaeger"
 	zc "github.com/uber/jaeger/thrift-gen/zipkincore"
@@ -156,7 +160,7 @@ func startCollector(
 	logger.Info("Starting jaeger-collector TChannel server", zap.Int("port", cOpts.CollectorPort))
 
 	r := mux.NewRouter()
-	apiHandler := collectorApp.NewAPIHandler(jaegerBatchesHandler)
+	apiHandler := httpapi.NewAPIHandler(jaegerBatchesHandler)
 	apiHandler.RegisterRoutes(r)
 	httpPortStr := ":" + strconv.Itoa(cOpts.CollectorHTTPPort)
 	recoveryHandler := recoveryhandler.NewRecoveryHandler(logger, true)
@@ -219,12 +223,16 @@ func startQuery(
 		logger.Fatal("Failed to initialize tracer", zap.Error(err))
 	}
 	defer closer.Close()
+	if qOpts.QueryImportFile != "" {
+		if err := queryApp.ImportFile(qOpts.QueryImportFile, memoryStore, logger); err != nil {
+			logger.Fatal("Could not import traces", zap.String("file", qOpts.QueryImportFile), zap.Error(err))
+		}
+	}
+	spanReader, importSpanWriter := querySpanStorage(qOpts, storageBuild, memoryStore, metricsFactory)
 	rHandler := queryApp.NewAPIHandler(
-		storageBuild.SpanReader,
+		spanReader,
 		storageBuild.DependencyReader,
-		queryApp.HandlerOptions.Prefix(qOpts.QueryPrefix),
-		queryApp.HandlerOptions.Logger(logger),
-		queryApp.HandlerOptions.Tracer(tracer))
+		queryHandlerOptions(qOpts, storageBuild, importSpanWriter, logger, tracer)...)
 	sHandler := queryApp.NewStaticAssetsHandler(qOpts.QueryStaticAssets)
 	r := mux.NewRouter()
 	rHandler.RegisterRoutes(r)
@@ -236,3 +244,37 @@ func startQuery(
 		logger.Fatal("Could not launch jaeger-query service", zap.Error(err))
 	}
 }
+
+// querySpanStorage returns the span reader of the query service, which caches the memory store if enabled,
+// and the writer of the imported traces, which replace the cached ones
+func querySpanStorage(
+	qOpts *query.QueryOptions,
+	storageBuild *query.StorageBuilder,
+	memoryStore *memory.Store,
+	metricsFactory metrics.Factory,
+) (spanstore.Reader, spanstore.Writer) {
+	if qOpts.Cache.MaxTraces <= 0 {
+		return storageBuild.SpanReader, memoryStore
+	}
+	readCache := cache.NewReadCacheDecorator(storageBuild.SpanReader, qOpts.Cache, metricsFactory.Namespace("cache", nil))
+	return readCache, cache.NewInvalidatingWriter(memoryStore, readCache)
+}
+
+// queryHandlerOptions returns the options of the query API handler, traces are imported with importSpanWriter
+func queryHandlerOptions(
+	qOpts *query.QueryOptions,
+	storageBuild *query.StorageBuilder,
+	importSpanWriter spanstore.Writer,
+	logger *zap.Logger,
+	tracer opentracing.Tracer,
+) []queryApp.HandlerOption {
+	return []queryApp.HandlerOption{
+		queryApp.HandlerOptions.Prefix(qOpts.QueryPrefix),
+		queryApp.HandlerOptions.Logger(logger),
+		queryApp.HandlerOptions.Tracer(tracer),
+		queryApp.HandlerOptions.ImportSpanWriter(importSpanWriter),
+		queryApp.HandlerOptions.MetricReader(storageBuild.MetricReader),
+		queryApp.HandlerOptions.ArchiveIndexReader(storageBuild.ArchiveIndexReader),
+		queryApp.HandlerOptions.ArchiveIndexWriter(storageBuild.ArchiveIndexWriter),
+	}
+}
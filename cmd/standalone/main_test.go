@@ -0,0 +1,135 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	basic "github.com/uber/jaeger/cmd/builder"
+	"github.com/uber/jaeger/cmd/flags"
+	queryApp "github.com/uber/jaeger/cmd/query/app"
+	query "github.com/uber/jaeger/cmd/query/app/builder"
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/storage/spanstore/cache"
+	"github.com/uber/jaeger/storage/spanstore/memory"
+)
+
+const testTrace = `{"traceID": "2a", "spans": [{"traceID": "2a", "spanID": "1", "operationName": "GET", "processID": "p1"}],
+	"processes": {"p1": {"serviceName": "frontend"}}}`
+
+func newTestQueryServer(t *testing.T, memoryStore *memory.Store) *httptest.Server {
+	qOpts := &query.QueryOptions{
+		QueryPrefix: "api",
+		Cache:       cache.Options{MaxTraces: 10, TraceTTL: time.Hour, RecentTraceTTL: time.Hour},
+	}
+	storageBuild, err := query.NewStorageBuilder(
+		flags.MemoryStorageType,
+		0,
+		basic.Options.LoggerOption(zap.NewNop()),
+		basic.Options.MemoryStoreOption(memoryStore),
+	)
+	require.NoError(t, err)
+	spanReader, importSpanWriter := querySpanStorage(qOpts, storageBuild, memoryStore, metrics.NullFactory)
+	handler := queryApp.NewAPIHandler(
+		spanReader,
+		storageBuild.DependencyReader,
+		queryHandlerOptions(qOpts, storageBuild, importSpanWriter, zap.NewNop(), opentracing.NoopTracer{})...)
+	r := mux.NewRouter()
+	handler.RegisterRoutes(r)
+	return httptest.NewServer(r)
+}
+
+func TestQueryHandlerOptionsImport(t *testing.T) {
+	memoryStore := memory.NewStore()
+	server := newTestQueryServer(t, memoryStore)
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/api/traces/import", "application/json", strings.NewReader(testTrace))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	trace, err := memoryStore.GetTrace(model.TraceID{Low: 42})
+	require.NoError(t, err)
+	assert.Len(t, trace.Spans, 1)
+}
+
+func TestQueryHandlerOptionsMetrics(t *testing.T) {
+	memoryStore := memory.NewStore()
+	server := newTestQueryServer(t, memoryStore)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/metrics/calls?service=frontend")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestQueryHandlerOptionsArchives(t *testing.T) {
+	memoryStore := memory.NewStore()
+	server := newTestQueryServer(t, memoryStore)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/archive")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestQueryImportReplacesCachedTrace(t *testing.T) {
+	memoryStore := memory.NewStore()
+	server := newTestQueryServer(t, memoryStore)
+	defer server.Close()
+
+	getTrace := func() string {
+		resp, err := http.Get(server.URL + "/api/traces/2a")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+	importTrace := func(trace string) {
+		resp, err := http.Post(server.URL+"/api/traces/import", "application/json", strings.NewReader(trace))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	importTrace(testTrace)
+	assert.NotContains(t, getTrace(), `"operationName":"POST"`)
+	importTrace(`{"traceID": "2a", "spans": [{"traceID": "2a", "spanID": "2", "operationName": "POST", "processID": "p1"}],
+		"processes": {"p1": {"serviceName": "frontend"}}}`)
+	assert.Contains(t, getTrace(), `"operationName":"POST"`)
+}
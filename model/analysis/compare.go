@@ -0,0 +1,253 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/uber/jaeger/model"
+)
+
+// Presence describes in which of the compared traces a span was found
+type Presence string
+
+const (
+	// InBoth means the span was aligned with a span of the other trace
+	InBoth Presence = "both"
+	// OnlyInA means the span exists only in the first trace
+	OnlyInA Presence = "a"
+	// OnlyInB means the span exists only in the second trace
+	OnlyInB Presence = "b"
+)
+
+// TraceComparison is the result of comparing two traces
+type TraceComparison struct {
+	TraceIDA      model.TraceID  `json:"traceIDA"`
+	TraceIDB      model.TraceID  `json:"traceIDB"`
+	DurationA     time.Duration  `json:"durationA"`
+	DurationB     time.Duration  `json:"durationB"`
+	DurationDelta time.Duration  `json:"durationDelta"`
+	Nodes         []NodeDiff     `json:"nodes"`
+	Summary       CompareSummary `json:"summary"`
+}
+
+// CompareSummary counts the aligned and unmatched spans of a comparison
+type CompareSummary struct {
+	Matched int `json:"matched"`
+	OnlyInA int `json:"onlyInA"`
+	OnlyInB int `json:"onlyInB"`
+}
+
+// NodeDiff describes a node of the aligned span trees. The path identifies the node
+// by the service and operation names of the node and its ancestors, with a #N suffix
+// for the N-th sibling sharing the same service and operation.
+type NodeDiff struct {
+	Path          string        `json:"path"`
+	Depth         int           `json:"depth"`
+	Service       string        `json:"service"`
+	Operation     string        `json:"operation"`
+	Presence      Presence      `json:"presence"`
+	SpanIDA       *model.SpanID `json:"spanIDA,omitempty"`
+	SpanIDB       *model.SpanID `json:"spanIDB,omitempty"`
+	DurationA     time.Duration `json:"durationA"`
+	DurationB     time.Duration `json:"durationB"`
+	DurationDelta time.Duration `json:"durationDelta"`
+	TagDiffs      []TagDiff     `json:"tagDiffs,omitempty"`
+}
+
+// TagDiff describes a span tag that is different between two aligned spans.
+// A nil value means the tag is absent from that span.
+type TagDiff struct {
+	Key    string  `json:"key"`
+	ValueA *string `json:"valueA"`
+	ValueB *string `json:"valueB"`
+}
+
+type nodeKey struct {
+	service   string
+	operation string
+}
+
+// CompareTraces aligns the span trees of two traces by service, operation and
+// position in the tree, and reports duration deltas, unmatched spans and tag
+// differences. Both traces are expected to have gone through the adjusters.
+// Sibling spans with the same service and operation are aligned in start time order.
+func CompareTraces(a, b *model.Trace) *TraceComparison {
+	c := &TraceComparison{
+		DurationA: traceDuration(a),
+		DurationB: traceDuration(b),
+		Nodes:     []NodeDiff{},
+	}
+	c.TraceIDA = traceID(a)
+	c.TraceIDB = traceID(b)
+	c.DurationDelta = c.DurationB - c.DurationA
+	c.alignChildren("", 0, BuildTree(a), BuildTree(b))
+	return c
+}
+
+func (c *TraceComparison) alignChildren(parentPath string, depth int, a, b []*SpanNode) {
+	groupsA, keys := groupByKey(a, nil)
+	groupsB, keys := groupByKey(b, keys)
+	for _, key := range keys {
+		nodesA, nodesB := groupsA[key], groupsB[key]
+		for i := 0; i < len(nodesA) || i < len(nodesB); i++ {
+			path := parentPath + "/" + key.service + ":" + key.operation
+			if i > 0 {
+				path = fmt.Sprintf("%s#%d", path, i+1)
+			}
+			switch {
+			case i >= len(nodesB):
+				c.addUnmatched(path, depth, nodesA[i], OnlyInA)
+			case i >= len(nodesA):
+				c.addUnmatched(path, depth, nodesB[i], OnlyInB)
+			default:
+				c.addMatched(path, depth, nodesA[i], nodesB[i])
+			}
+		}
+	}
+}
+
+// groupByKey groups the nodes by service and operation, and appends the keys
+// not seen yet to the given keys, preserving the order of first appearance.
+func groupByKey(nodes []*SpanNode, keys []nodeKey) (map[nodeKey][]*SpanNode, []nodeKey) {
+	seen := make(map[nodeKey]bool, len(keys))
+	for _, key := range keys {
+		seen[key] = true
+	}
+	groups := make(map[nodeKey][]*SpanNode)
+	for _, node := range nodes {
+		key := nodeKey{service: serviceName(node.Span), operation: node.Span.OperationName}
+		groups[key] = append(groups[key], node)
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return groups, keys
+}
+
+func (c *TraceComparison) addMatched(path string, depth int, a, b *SpanNode) {
+	spanIDA, spanIDB := a.Span.SpanID, b.Span.SpanID
+	c.Nodes = append(c.Nodes, NodeDiff{
+		Path:          path,
+		Depth:         depth,
+		Service:       serviceName(a.Span),
+		Operation:     a.Span.OperationName,
+		Presence:      InBoth,
+		SpanIDA:       &spanIDA,
+		SpanIDB:       &spanIDB,
+		DurationA:     a.Span.Duration,
+		DurationB:     b.Span.Duration,
+		DurationDelta: b.Span.Duration - a.Span.Duration,
+		TagDiffs:      compareTags(a.Span.Tags, b.Span.Tags),
+	})
+	c.Summary.Matched++
+	c.alignChildren(path, depth+1, a.Children, b.Children)
+}
+
+func (c *TraceComparison) addUnmatched(path string, depth int, node *SpanNode, presence Presence) {
+	spanID := node.Span.SpanID
+	diff := NodeDiff{
+		Path:      path,
+		Depth:     depth,
+		Service:   serviceName(node.Span),
+		Operation: node.Span.OperationName,
+		Presence:  presence,
+	}
+	if presence == OnlyInA {
+		diff.SpanIDA = &spanID
+		diff.DurationA = node.Span.Duration
+		diff.DurationDelta = -node.Span.Duration
+		c.Summary.OnlyInA++
+	} else {
+		diff.SpanIDB = &spanID
+		diff.DurationB = node.Span.Duration
+		diff.DurationDelta = node.Span.Duration
+		c.Summary.OnlyInB++
+	}
+	c.Nodes = append(c.Nodes, diff)
+	if presence == OnlyInA {
+		c.alignChildren(path, depth+1, node.Children, nil)
+	} else {
+		c.alignChildren(path, depth+1, nil, node.Children)
+	}
+}
+
+// compareTags returns the tags whose values differ between the two spans, sorted by key.
+// When a key is repeated, only its first value is compared.
+func compareTags(a, b model.KeyValues) []TagDiff {
+	valuesA, valuesB := tagValues(a), tagValues(b)
+	var diffs []TagDiff
+	for key, valueA := range valuesA {
+		valueA := valueA
+		if valueB, ok := valuesB[key]; !ok {
+			diffs = append(diffs, TagDiff{Key: key, ValueA: &valueA})
+		} else if valueA != valueB {
+			diffs = append(diffs, TagDiff{Key: key, ValueA: &valueA, ValueB: &valueB})
+		}
+	}
+	for key, valueB := range valuesB {
+		valueB := valueB
+		if _, ok := valuesA[key]; !ok {
+			diffs = append(diffs, TagDiff{Key: key, ValueB: &valueB})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Key < diffs[j].Key
+	})
+	return diffs
+}
+
+func tagValues(tags model.KeyValues) map[string]string {
+	values := make(map[string]string, len(tags))
+	for i := range tags {
+		if _, ok := values[tags[i].Key]; !ok {
+			values[tags[i].Key] = tags[i].AsString()
+		}
+	}
+	return values
+}
+
+func traceID(trace *model.Trace) model.TraceID {
+	if len(trace.Spans) == 0 {
+		return model.TraceID{}
+	}
+	return trace.Spans[0].TraceID
+}
+
+// traceDuration returns the time between the start of the earliest span
+// and the end of the latest one
+func traceDuration(trace *model.Trace) time.Duration {
+	var start, end time.Time
+	for i, span := range trace.Spans {
+		spanEnd := span.StartTime.Add(span.Duration)
+		if i == 0 || span.StartTime.Before(start) {
+			start = span.StartTime
+		}
+		if i == 0 || spanEnd.After(end) {
+			end = spanEnd
+		}
+	}
+	return end.Sub(start)
+}
@@ -0,0 +1,111 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package analysis
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/model"
+)
+
+func TestCompareTraces(t *testing.T) {
+	a := &model.Trace{
+		Spans: []*model.Span{
+			newSpan(1, 0, "frontend", "GET", 0, 50),
+			newSpan(2, 1, "backend", "query", 5, 10),
+			newSpan(3, 1, "backend", "query", 20, 10),
+			newSpan(4, 1, "cache", "get", 1, 2),
+		},
+	}
+	a.Spans[1].Tags = model.KeyValues{model.String("db", "users"), model.Int64("rows", 10)}
+	b := &model.Trace{
+		Spans: []*model.Span{
+			newSpan(11, 0, "frontend", "GET", 0, 120),
+			newSpan(12, 11, "backend", "query", 5, 100),
+			newSpan(13, 12, "db", "select", 6, 90),
+		},
+	}
+	b.Spans[0].TraceID = model.TraceID{Low: 2}
+	b.Spans[1].Tags = model.KeyValues{model.String("db", "users"), model.Int64("rows", 10000), model.Bool("slow", true)}
+
+	c := CompareTraces(a, b)
+	assert.Equal(t, model.TraceID{Low: 1}, c.TraceIDA)
+	assert.Equal(t, model.TraceID{Low: 2}, c.TraceIDB)
+	assert.Equal(t, 50*time.Millisecond, c.DurationA)
+	assert.Equal(t, 120*time.Millisecond, c.DurationB)
+	assert.Equal(t, 70*time.Millisecond, c.DurationDelta)
+	assert.Equal(t, CompareSummary{Matched: 2, OnlyInA: 2, OnlyInB: 1}, c.Summary)
+
+	paths := make([]string, len(c.Nodes))
+	for i, node := range c.Nodes {
+		paths[i] = node.Path
+	}
+	assert.Equal(t, []string{
+		"/frontend:GET",
+		"/frontend:GET/cache:get",
+		"/frontend:GET/backend:query",
+		"/frontend:GET/backend:query/db:select",
+		"/frontend:GET/backend:query#2",
+	}, paths)
+
+	root := c.Nodes[0]
+	assert.Equal(t, InBoth, root.Presence)
+	assert.Equal(t, model.SpanID(1), *root.SpanIDA)
+	assert.Equal(t, model.SpanID(11), *root.SpanIDB)
+	assert.Equal(t, 70*time.Millisecond, root.DurationDelta)
+	assert.Empty(t, root.TagDiffs)
+
+	assert.Equal(t, OnlyInA, c.Nodes[1].Presence)
+	assert.Nil(t, c.Nodes[1].SpanIDB)
+	assert.Equal(t, -2*time.Millisecond, c.Nodes[1].DurationDelta)
+
+	query := c.Nodes[2]
+	assert.Equal(t, 1, query.Depth)
+	assert.Equal(t, 90*time.Millisecond, query.DurationDelta)
+	require.Len(t, query.TagDiffs, 2)
+	assert.Equal(t, "rows", query.TagDiffs[0].Key)
+	assert.Equal(t, "10", *query.TagDiffs[0].ValueA)
+	assert.Equal(t, "10000", *query.TagDiffs[0].ValueB)
+	assert.Equal(t, "slow", query.TagDiffs[1].Key)
+	assert.Nil(t, query.TagDiffs[1].ValueA)
+	assert.Equal(t, "true", *query.TagDiffs[1].ValueB)
+
+	assert.Equal(t, OnlyInB, c.Nodes[3].Presence)
+	assert.Equal(t, 2, c.Nodes[3].Depth)
+	assert.Equal(t, OnlyInA, c.Nodes[4].Presence)
+	assert.Equal(t, model.SpanID(3), *c.Nodes[4].SpanIDA)
+}
+
+func TestCompareTracesEmpty(t *testing.T) {
+	c := CompareTraces(&model.Trace{}, &model.Trace{})
+	assert.Equal(t, time.Duration(0), c.DurationDelta)
+	assert.Empty(t, c.Nodes)
+
+	out, err := json.Marshal(c)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `"nodes":[]`)
+}
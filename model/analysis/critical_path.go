@@ -0,0 +1,246 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package analysis
+
+import (
+	"sort"
+	"time"
+
+	"github.com/uber/jaeger/model"
+)
+
+// CriticalPath describes the chain of spans that determines the end-to-end latency of a trace
+type CriticalPath struct {
+	TraceID  model.TraceID `json:"traceID"`
+	Duration time.Duration `json:"duration"`
+	// Gap is the time on the path not covered by any span, e.g. between the end of a span
+	// and the start of the span that follows from it, or between disconnected root spans
+	Gap       time.Duration   `json:"gap"`
+	Spans     []CriticalSpan  `json:"spans"`
+	Segments  []PathSegment   `json:"segments"`
+	SelfTimes []OperationTime `json:"selfTimes"`
+}
+
+// CriticalSpan is a span on the critical path, with the time attributed to the span itself
+// rather than to its children on the path
+type CriticalSpan struct {
+	SpanID    model.SpanID  `json:"spanID"`
+	Service   string        `json:"service"`
+	Operation string        `json:"operation"`
+	StartTime time.Time     `json:"startTime"`
+	Duration  time.Duration `json:"duration"`
+	SelfTime  time.Duration `json:"selfTime"`
+}
+
+// PathSegment is an interval of the critical path during which the given span was the one
+// holding up the trace
+type PathSegment struct {
+	SpanID    model.SpanID  `json:"spanID"`
+	StartTime time.Time     `json:"startTime"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// OperationTime is the self-time on the critical path aggregated by service and operation
+type OperationTime struct {
+	Service    string        `json:"service"`
+	Operation  string        `json:"operation"`
+	SelfTime   time.Duration `json:"selfTime"`
+	Percentage float64       `json:"percentage"`
+}
+
+type criticalPathBuilder struct {
+	path     *CriticalPath
+	spans    map[*SpanNode]*CriticalSpan
+	order    []*SpanNode
+	segments []PathSegment
+}
+
+// ComputeCriticalPath walks the span trees of the trace backwards from the latest end time,
+// at each step descending into the child that finished last. Time during which no child was
+// running is attributed to the parent span as self-time. Concurrent children that finished
+// earlier are off the path. A FOLLOWS_FROM child does not have to finish before its parent,
+// so it extends the path beyond the parent's end, with the time between the end of the parent
+// and the start of the child counted as a gap. The trace is expected to have gone through the adjusters.
+func ComputeCriticalPath(trace *model.Trace) *CriticalPath {
+	b := &criticalPathBuilder{
+		path: &CriticalPath{
+			TraceID:   traceID(trace),
+			Spans:     []CriticalSpan{},
+			SelfTimes: []OperationTime{},
+		},
+		spans: make(map[*SpanNode]*CriticalSpan),
+	}
+	roots := BuildTree(trace)
+	if len(roots) == 0 {
+		b.path.Segments = []PathSegment{}
+		return b.path
+	}
+	// the roots are treated as children of a virtual span covering the whole trace
+	virtual := &SpanNode{Children: roots}
+	start, end := roots[0].Span.StartTime, roots[0].Span.StartTime
+	for _, root := range roots {
+		if rootEnd := extendedEnd(root); rootEnd.After(end) {
+			end = rootEnd
+		}
+	}
+	b.path.Duration = end.Sub(start)
+	b.walk(virtual, start, end)
+	b.finish()
+	return b.path
+}
+
+// walk computes the critical path of the node within the [from, to] window
+func (b *criticalPathBuilder) walk(node *SpanNode, from, to time.Time) {
+	if node.Span != nil {
+		b.spans[node] = &CriticalSpan{
+			SpanID:    node.Span.SpanID,
+			Service:   serviceName(node.Span),
+			Operation: node.Span.OperationName,
+			StartTime: node.Span.StartTime,
+			Duration:  node.Span.Duration,
+		}
+		b.order = append(b.order, node)
+	}
+	cursor := to
+	for {
+		child, childEnd := lastFinishedChild(node, cursor)
+		if child == nil || !childEnd.After(from) {
+			break
+		}
+		b.attribute(node, childEnd, cursor)
+		childStart := child.Span.StartTime
+		if childStart.Before(from) {
+			childStart = from
+		}
+		b.walk(child, childStart, childEnd)
+		cursor = childStart
+	}
+	b.attribute(node, from, cursor)
+}
+
+// lastFinishedChild returns the child that started before the cursor and finished
+// the latest, together with its end time clipped to the cursor
+func lastFinishedChild(node *SpanNode, cursor time.Time) (*SpanNode, time.Time) {
+	var last *SpanNode
+	var lastEnd time.Time
+	for _, child := range node.Children {
+		if !child.Span.StartTime.Before(cursor) {
+			continue
+		}
+		end := extendedEnd(child)
+		if end.After(cursor) {
+			end = cursor
+		}
+		if last == nil || end.After(lastEnd) || (end.Equal(lastEnd) && child.Span.StartTime.After(last.Span.StartTime)) {
+			last, lastEnd = child, end
+		}
+	}
+	return last, lastEnd
+}
+
+// extendedEnd returns the end time of the span, or the end time of the latest of its
+// FOLLOWS_FROM descendants, which may finish after it
+func extendedEnd(node *SpanNode) time.Time {
+	end := node.Span.StartTime.Add(node.Span.Duration)
+	for _, child := range node.Children {
+		if !isFollowsFrom(child.Span, node.Span.SpanID) {
+			continue
+		}
+		if childEnd := extendedEnd(child); childEnd.After(end) {
+			end = childEnd
+		}
+	}
+	return end
+}
+
+// attribute assigns the [from, to] interval of the path to the node. The part of the interval
+// after the end of the span, only possible with FOLLOWS_FROM children, is counted as a gap.
+func (b *criticalPathBuilder) attribute(node *SpanNode, from, to time.Time) {
+	if !to.After(from) {
+		return
+	}
+	if node.Span == nil {
+		b.path.Gap += to.Sub(from)
+		return
+	}
+	if end := node.Span.StartTime.Add(node.Span.Duration); to.After(end) {
+		if end.After(from) {
+			b.path.Gap += to.Sub(end)
+			to = end
+		} else {
+			b.path.Gap += to.Sub(from)
+			return
+		}
+	}
+	b.spans[node].SelfTime += to.Sub(from)
+	b.segments = append(b.segments, PathSegment{
+		SpanID:    node.Span.SpanID,
+		StartTime: from,
+		Duration:  to.Sub(from),
+	})
+}
+
+// finish orders the spans and segments by start time and aggregates the self-times
+func (b *criticalPathBuilder) finish() {
+	selfTimes := make(map[nodeKey]time.Duration)
+	var keys []nodeKey
+	for _, node := range b.order {
+		span := b.spans[node]
+		b.path.Spans = append(b.path.Spans, *span)
+		key := nodeKey{service: span.Service, operation: span.Operation}
+		if _, ok := selfTimes[key]; !ok {
+			keys = append(keys, key)
+		}
+		selfTimes[key] += span.SelfTime
+	}
+	sort.SliceStable(b.path.Spans, func(i, j int) bool {
+		return b.path.Spans[i].StartTime.Before(b.path.Spans[j].StartTime)
+	})
+
+	// segments were collected backwards in time
+	b.path.Segments = make([]PathSegment, len(b.segments))
+	for i, segment := range b.segments {
+		b.path.Segments[len(b.segments)-1-i] = segment
+	}
+
+	for _, key := range keys {
+		operationTime := OperationTime{
+			Service:   key.service,
+			Operation: key.operation,
+			SelfTime:  selfTimes[key],
+		}
+		if b.path.Duration > 0 {
+			operationTime.Percentage = 100 * float64(operationTime.SelfTime) / float64(b.path.Duration)
+		}
+		b.path.SelfTimes = append(b.path.SelfTimes, operationTime)
+	}
+	sort.Slice(b.path.SelfTimes, func(i, j int) bool {
+		x, y := b.path.SelfTimes[i], b.path.SelfTimes[j]
+		if x.SelfTime != y.SelfTime {
+			return x.SelfTime > y.SelfTime
+		}
+		if x.Service != y.Service {
+			return x.Service < y.Service
+		}
+		return x.Operation < y.Operation
+	})
+}
@@ -0,0 +1,152 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package analysis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/uber/jaeger/model"
+)
+
+func criticalSpanIDs(path *CriticalPath) []model.SpanID {
+	ids := make([]model.SpanID, len(path.Spans))
+	for i, span := range path.Spans {
+		ids[i] = span.SpanID
+	}
+	return ids
+}
+
+func selfTimes(path *CriticalPath) map[model.SpanID]time.Duration {
+	times := make(map[model.SpanID]time.Duration)
+	for _, span := range path.Spans {
+		times[span.SpanID] = span.SelfTime
+	}
+	return times
+}
+
+func TestCriticalPathConcurrentChildren(t *testing.T) {
+	// 1: [0, 100]
+	//   2: [10, 50]   concurrent with 3, but finishes earlier
+	//   3: [20, 60]
+	//     4: [30, 40]
+	//   5: [70, 90]
+	trace := &model.Trace{
+		Spans: []*model.Span{
+			newSpan(1, 0, "frontend", "GET", 0, 100),
+			newSpan(2, 1, "backend", "auth", 10, 40),
+			newSpan(3, 1, "backend", "query", 20, 40),
+			newSpan(4, 3, "db", "select", 30, 10),
+			newSpan(5, 1, "cache", "set", 70, 20),
+		},
+	}
+	path := ComputeCriticalPath(trace)
+	assert.Equal(t, model.TraceID{Low: 1}, path.TraceID)
+	assert.Equal(t, 100*time.Millisecond, path.Duration)
+	assert.Equal(t, time.Duration(0), path.Gap)
+	assert.Equal(t, []model.SpanID{1, 2, 3, 4, 5}, criticalSpanIDs(path))
+	assert.Equal(t, map[model.SpanID]time.Duration{
+		1: 30 * time.Millisecond, // [0, 10], [60, 70], [90, 100]
+		2: 10 * time.Millisecond, // [10, 20], until 3 takes over
+		3: 30 * time.Millisecond, // [20, 30], [40, 60]
+		4: 10 * time.Millisecond,
+		5: 20 * time.Millisecond,
+	}, selfTimes(path))
+
+	var total time.Duration
+	for i, segment := range path.Segments {
+		total += segment.Duration
+		if i > 0 {
+			prev := path.Segments[i-1]
+			assert.Equal(t, prev.StartTime.Add(prev.Duration), segment.StartTime, "segments must be contiguous")
+		}
+	}
+	assert.Equal(t, path.Duration, total)
+	assert.Equal(t, model.SpanID(1), path.Segments[0].SpanID)
+	assert.Equal(t, model.SpanID(1), path.Segments[len(path.Segments)-1].SpanID)
+
+	assert.Equal(t, []OperationTime{
+		{Service: "backend", Operation: "query", SelfTime: 30 * time.Millisecond, Percentage: 30},
+		{Service: "frontend", Operation: "GET", SelfTime: 30 * time.Millisecond, Percentage: 30},
+		{Service: "cache", Operation: "set", SelfTime: 20 * time.Millisecond, Percentage: 20},
+		{Service: "backend", Operation: "auth", SelfTime: 10 * time.Millisecond, Percentage: 10},
+		{Service: "db", Operation: "select", SelfTime: 10 * time.Millisecond, Percentage: 10},
+	}, path.SelfTimes)
+}
+
+func TestCriticalPathChildOutlivesParent(t *testing.T) {
+	trace := &model.Trace{
+		Spans: []*model.Span{
+			newSpan(1, 0, "frontend", "GET", 0, 50),
+			newSpan(2, 1, "backend", "query", 40, 30),
+		},
+	}
+	path := ComputeCriticalPath(trace)
+	assert.Equal(t, 50*time.Millisecond, path.Duration)
+	assert.Equal(t, map[model.SpanID]time.Duration{
+		1: 40 * time.Millisecond,
+		2: 10 * time.Millisecond,
+	}, selfTimes(path))
+}
+
+func TestCriticalPathFollowsFrom(t *testing.T) {
+	followsFrom := newSpan(3, 1, "worker", "process", 60, 30)
+	followsFrom.References = []model.SpanRef{{RefType: model.FollowsFrom, TraceID: followsFrom.TraceID, SpanID: 1}}
+	trace := &model.Trace{
+		Spans: []*model.Span{
+			newSpan(1, 0, "frontend", "POST", 0, 50),
+			newSpan(2, 1, "queue", "publish", 10, 20),
+			followsFrom,
+		},
+	}
+	path := ComputeCriticalPath(trace)
+	assert.Equal(t, 90*time.Millisecond, path.Duration)
+	assert.Equal(t, 10*time.Millisecond, path.Gap)
+	assert.Equal(t, []model.SpanID{1, 2, 3}, criticalSpanIDs(path))
+	assert.Equal(t, map[model.SpanID]time.Duration{
+		1: 30 * time.Millisecond,
+		2: 20 * time.Millisecond,
+		3: 30 * time.Millisecond,
+	}, selfTimes(path))
+}
+
+func TestCriticalPathDisconnectedRoots(t *testing.T) {
+	trace := &model.Trace{
+		Spans: []*model.Span{
+			newSpan(1, 0, "frontend", "GET", 0, 10),
+			newSpan(2, 99, "backend", "async", 30, 20),
+		},
+	}
+	path := ComputeCriticalPath(trace)
+	assert.Equal(t, 50*time.Millisecond, path.Duration)
+	assert.Equal(t, 20*time.Millisecond, path.Gap)
+	assert.Equal(t, []model.SpanID{1, 2}, criticalSpanIDs(path))
+}
+
+func TestCriticalPathEmptyTrace(t *testing.T) {
+	path := ComputeCriticalPath(&model.Trace{})
+	assert.Equal(t, time.Duration(0), path.Duration)
+	assert.Empty(t, path.Spans)
+	assert.NotNil(t, path.Segments)
+}
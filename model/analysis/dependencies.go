@@ -0,0 +1,94 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package analysis
+
+import (
+	"sort"
+
+	"github.com/uber/jaeger/model"
+)
+
+// DependencyLinks counts the calls between services in the traces. Every span whose parent span
+// belongs to another service is a call from the parent's service to the span's service, and the
+// call failed if the span has the error tag. The links are sorted by parent and child.
+func DependencyLinks(traces []*model.Trace) []model.DependencyLink {
+	counter := NewDependencyLinkCounter()
+	for _, trace := range traces {
+		counter.AddTrace(trace)
+	}
+	return counter.Links()
+}
+
+type dependencyLinkKey struct {
+	parent string
+	child  string
+}
+
+// DependencyLinkCounter counts the calls between services like DependencyLinks, for traces that are
+// added one at a time, so that they do not all have to be kept
+type DependencyLinkCounter struct {
+	links map[dependencyLinkKey]*model.DependencyLink
+}
+
+// NewDependencyLinkCounter returns a DependencyLinkCounter without any calls
+func NewDependencyLinkCounter() *DependencyLinkCounter {
+	return &DependencyLinkCounter{links: make(map[dependencyLinkKey]*model.DependencyLink)}
+}
+
+// AddTrace counts the calls between services in the trace
+func (c *DependencyLinkCounter) AddTrace(trace *model.Trace) {
+	spans := make(map[model.SpanID]*model.Span, len(trace.Spans))
+	for _, span := range trace.Spans {
+		spans[span.SpanID] = span
+	}
+	for _, span := range trace.Spans {
+		parent, ok := spans[span.ParentSpanID]
+		if !ok || span.ParentSpanID == 0 || parent.Process.ServiceName == span.Process.ServiceName {
+			continue
+		}
+		key := dependencyLinkKey{parent: parent.Process.ServiceName, child: span.Process.ServiceName}
+		link, ok := c.links[key]
+		if !ok {
+			link = &model.DependencyLink{Parent: key.parent, Child: key.child}
+			c.links[key] = link
+		}
+		link.CallCount++
+		if span.IsError() {
+			link.ErrorCount++
+		}
+	}
+}
+
+// Links returns the links counted so far, sorted by parent and child
+func (c *DependencyLinkCounter) Links() []model.DependencyLink {
+	result := make([]model.DependencyLink, 0, len(c.links))
+	for _, link := range c.links {
+		result = append(result, *link)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Parent != result[j].Parent {
+			return result[i].Parent < result[j].Parent
+		}
+		return result[i].Child < result[j].Child
+	})
+	return result
+}
@@ -0,0 +1,74 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/uber/jaeger/model"
+)
+
+func TestDependencyLinks(t *testing.T) {
+	failed := newSpan(4, 2, "db", "select", 30, 10)
+	failed.Tags = model.KeyValues{model.Bool("error", true)}
+	trace1 := &model.Trace{
+		Spans: []*model.Span{
+			newSpan(1, 0, "frontend", "GET", 0, 100),
+			newSpan(2, 1, "backend", "query", 10, 40),
+			newSpan(3, 2, "backend", "local", 20, 10), // same service
+			failed,
+			newSpan(5, 9, "cache", "get", 60, 10), // parent not found
+		},
+	}
+	trace2 := &model.Trace{
+		Spans: []*model.Span{
+			newSpan(1, 0, "frontend", "GET", 0, 100),
+			newSpan(2, 1, "backend", "query", 10, 40),
+			newSpan(3, 1, "backend", "query", 60, 30),
+		},
+	}
+	assert.Equal(t, []model.DependencyLink{
+		{Parent: "backend", Child: "db", CallCount: 1, ErrorCount: 1},
+		{Parent: "frontend", Child: "backend", CallCount: 3},
+	}, DependencyLinks([]*model.Trace{trace1, trace2}))
+}
+
+func TestDependencyLinksEmpty(t *testing.T) {
+	assert.Equal(t, []model.DependencyLink{}, DependencyLinks(nil))
+}
+
+func TestDependencyLinkCounter(t *testing.T) {
+	counter := NewDependencyLinkCounter()
+	trace := &model.Trace{
+		Spans: []*model.Span{
+			newSpan(1, 0, "frontend", "GET", 0, 100),
+			newSpan(2, 1, "backend", "query", 10, 40),
+		},
+	}
+	counter.AddTrace(trace)
+	assert.Equal(t, []model.DependencyLink{{Parent: "frontend", Child: "backend", CallCount: 1}}, counter.Links())
+
+	counter.AddTrace(trace)
+	assert.Equal(t, []model.DependencyLink{{Parent: "frontend", Child: "backend", CallCount: 2}}, counter.Links())
+}
@@ -0,0 +1,139 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package analysis
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/uber/jaeger/model"
+)
+
+const warningFormatMissingParent = "parent span ID=%s not found in the trace; span attached to the root"
+
+// SpanNode is a span in the tree of spans of a trace
+type SpanNode struct {
+	Span     *model.Span
+	Children []*SpanNode
+}
+
+// BuildTree arranges the spans of the trace into trees, using the parent span ID
+// or, when absent, the first reference of each span. Spans whose parent is not part
+// of the trace become roots, and reference cycles are broken so that every span
+// appears in the trees exactly once. Roots and children are ordered by start time.
+func BuildTree(trace *model.Trace) []*SpanNode {
+	nodes := make(map[model.SpanID]*SpanNode, len(trace.Spans))
+	for _, span := range trace.Spans {
+		nodes[span.SpanID] = &SpanNode{Span: span}
+	}
+	var roots []*SpanNode
+	for _, span := range trace.Spans {
+		node := nodes[span.SpanID]
+		if node.Span != span {
+			continue // duplicate span IDs are expected to be fixed by the adjusters
+		}
+		if parent, ok := nodes[span.ParentID()]; ok && parent != node {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	roots = breakCycles(trace, nodes, roots)
+	sortNodes(roots)
+	for _, node := range nodes {
+		sortNodes(node.Children)
+	}
+	return roots
+}
+
+// breakCycles turns spans unreachable from the roots, which can only be part of
+// reference cycles, into roots themselves. A span whose parent cannot be found,
+// which should not happen, is turned into a root with a warning.
+func breakCycles(trace *model.Trace, nodes map[model.SpanID]*SpanNode, roots []*SpanNode) []*SpanNode {
+	visited := make(map[*SpanNode]bool, len(nodes))
+	var visit func(node *SpanNode)
+	visit = func(node *SpanNode) {
+		visited[node] = true
+		for _, child := range node.Children {
+			if !visited[child] {
+				visit(child)
+			}
+		}
+	}
+	for _, root := range roots {
+		visit(root)
+	}
+	for _, span := range trace.Spans {
+		node := nodes[span.SpanID]
+		if visited[node] {
+			continue
+		}
+		// with duplicate span IDs the node holds another span, whose parent may differ
+		parent := nodes[node.Span.ParentID()]
+		if parent == nil {
+			node.Span.Warnings = append(node.Span.Warnings, fmt.Sprintf(warningFormatMissingParent, node.Span.ParentID()))
+			roots = append(roots, node)
+			visit(node)
+			continue
+		}
+		for i, child := range parent.Children {
+			if child == node {
+				parent.Children = append(parent.Children[:i], parent.Children[i+1:]...)
+				break
+			}
+		}
+		roots = append(roots, node)
+		visit(node)
+	}
+	return roots
+}
+
+// isFollowsFrom returns true if the span only has a FOLLOWS_FROM reference to the given parent
+func isFollowsFrom(span *model.Span, parentID model.SpanID) bool {
+	followsFrom := false
+	for _, ref := range span.References {
+		if ref.SpanID != parentID {
+			continue
+		}
+		if ref.RefType != model.FollowsFrom {
+			return false
+		}
+		followsFrom = true
+	}
+	return followsFrom
+}
+
+func sortNodes(nodes []*SpanNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		if !nodes[i].Span.StartTime.Equal(nodes[j].Span.StartTime) {
+			return nodes[i].Span.StartTime.Before(nodes[j].Span.StartTime)
+		}
+		return nodes[i].Span.SpanID < nodes[j].Span.SpanID
+	})
+}
+
+func serviceName(span *model.Span) string {
+	if span.Process == nil {
+		return ""
+	}
+	return span.Process.ServiceName
+}
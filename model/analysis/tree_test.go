@@ -0,0 +1,108 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package analysis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/model"
+)
+
+var epoch = time.Unix(1500000000, 0)
+
+func newSpan(spanID, parentID model.SpanID, service, operation string, start, duration int) *model.Span {
+	return &model.Span{
+		TraceID:       model.TraceID{Low: 1},
+		SpanID:        spanID,
+		ParentSpanID:  parentID,
+		OperationName: operation,
+		StartTime:     epoch.Add(time.Duration(start) * time.Millisecond),
+		Duration:      time.Duration(duration) * time.Millisecond,
+		Process:       &model.Process{ServiceName: service},
+	}
+}
+
+func spanIDs(nodes []*SpanNode) []model.SpanID {
+	ids := make([]model.SpanID, len(nodes))
+	for i, node := range nodes {
+		ids[i] = node.Span.SpanID
+	}
+	return ids
+}
+
+func TestBuildTree(t *testing.T) {
+	followsFrom := newSpan(5, 0, "worker", "process", 40, 10)
+	followsFrom.References = []model.SpanRef{{RefType: model.FollowsFrom, SpanID: 1}}
+	trace := &model.Trace{
+		Spans: []*model.Span{
+			newSpan(3, 1, "backend", "query", 20, 10),
+			newSpan(1, 0, "frontend", "GET", 0, 50),
+			newSpan(2, 1, "backend", "auth", 5, 10),
+			newSpan(4, 99, "orphan", "lost", 1, 1),
+			followsFrom,
+		},
+	}
+	roots := BuildTree(trace)
+	assert.Equal(t, []model.SpanID{1, 4}, spanIDs(roots))
+	assert.Equal(t, []model.SpanID{2, 3, 5}, spanIDs(roots[0].Children))
+	assert.Empty(t, roots[1].Children)
+}
+
+func TestBuildTreeCycle(t *testing.T) {
+	trace := &model.Trace{
+		Spans: []*model.Span{
+			newSpan(1, 2, "svc", "a", 0, 10),
+			newSpan(2, 1, "svc", "b", 1, 5),
+		},
+	}
+	roots := BuildTree(trace)
+	require.Equal(t, []model.SpanID{1}, spanIDs(roots))
+	assert.Equal(t, []model.SpanID{2}, spanIDs(roots[0].Children))
+	assert.Empty(t, roots[0].Children[0].Children)
+}
+
+func TestBuildTreeDuplicateSpanIDInCycle(t *testing.T) {
+	trace := &model.Trace{
+		Spans: []*model.Span{
+			newSpan(1, 99, "svc", "dup", 0, 10),
+			newSpan(1, 2, "svc", "a", 0, 10),
+			newSpan(2, 1, "svc", "b", 1, 5),
+		},
+	}
+	roots := BuildTree(trace)
+	require.Equal(t, []model.SpanID{1}, spanIDs(roots))
+	assert.Equal(t, "a", roots[0].Span.OperationName)
+	assert.Equal(t, []model.SpanID{2}, spanIDs(roots[0].Children))
+}
+
+func TestBreakCyclesMissingParent(t *testing.T) {
+	span := newSpan(1, 2, "svc", "a", 0, 10)
+	trace := &model.Trace{Spans: []*model.Span{span}}
+	nodes := map[model.SpanID]*SpanNode{1: {Span: span}}
+	roots := breakCycles(trace, nodes, nil)
+	require.Equal(t, []model.SpanID{1}, spanIDs(roots))
+	assert.Equal(t, []string{"parent span ID=2 not found in the trace; span attached to the root"}, span.Warnings)
+}
@@ -0,0 +1,93 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package canonical
+
+import (
+	"github.com/uber/jaeger/model"
+)
+
+// FromDomain returns a copy of the trace in canonical form: spans are sorted by span ID,
+// tags, logs and process tags are sorted, and timestamps are in UTC. The original trace
+// is left untouched. The result serializes to JSON through the model's own JSON tags.
+func FromDomain(trace *model.Trace) *model.Trace {
+	result := &model.Trace{
+		Spans:    make([]*model.Span, len(trace.Spans)),
+		Warnings: trace.Warnings,
+	}
+	processes := make(map[*model.Process]*model.Process)
+	for i, span := range trace.Spans {
+		result.Spans[i] = copySpan(span, processes)
+	}
+	model.SortTrace(result)
+	return result
+}
+
+// ToDomain returns the trace decoded from the canonical JSON. Since the canonical form
+// is the domain model itself, it only restores the sharing of identical processes.
+func ToDomain(trace *model.Trace) *model.Trace {
+	var processes []*model.Process
+	for _, span := range trace.Spans {
+		if span.Process == nil {
+			continue
+		}
+		shared := false
+		for _, process := range processes {
+			if process.Equal(span.Process) {
+				span.Process = process
+				shared = true
+				break
+			}
+		}
+		if !shared {
+			processes = append(processes, span.Process)
+		}
+	}
+	return trace
+}
+
+func copySpan(span *model.Span, processes map[*model.Process]*model.Process) *model.Span {
+	result := *span
+	result.References = append([]model.SpanRef(nil), span.References...)
+	result.Tags = append(model.KeyValues(nil), span.Tags...)
+	result.Warnings = append([]string(nil), span.Warnings...)
+	if span.Logs != nil {
+		result.Logs = make([]model.Log, len(span.Logs))
+		for i, log := range span.Logs {
+			result.Logs[i] = model.Log{
+				Timestamp: log.Timestamp,
+				Fields:    append([]model.KeyValue(nil), log.Fields...),
+			}
+		}
+	}
+	if span.Process != nil {
+		process, ok := processes[span.Process]
+		if !ok {
+			process = &model.Process{
+				ServiceName: span.Process.ServiceName,
+				Tags:        append(model.KeyValues(nil), span.Process.Tags...),
+			}
+			processes[span.Process] = process
+		}
+		result.Process = process
+	}
+	return &result
+}
@@ -0,0 +1,95 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package canonical
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/model"
+)
+
+func testTrace() *model.Trace {
+	startTime := time.Unix(1500000000, 0).In(time.FixedZone("EST", -5*3600))
+	process := &model.Process{
+		ServiceName: "frontend",
+		Tags:        model.KeyValues{model.String("z", "1"), model.Int64("a", 2)},
+	}
+	return &model.Trace{
+		Spans: []*model.Span{
+			{
+				TraceID:       model.TraceID{Low: 1},
+				SpanID:        model.SpanID(2),
+				ParentSpanID:  model.SpanID(1),
+				OperationName: "child",
+				StartTime:     startTime.Add(time.Millisecond),
+				Duration:      time.Millisecond,
+				Tags:          model.KeyValues{model.Binary("blob", []byte{1, 2}), model.Bool("b", true)},
+				Logs: []model.Log{
+					{Timestamp: startTime.Add(2 * time.Millisecond), Fields: []model.KeyValue{model.String("event", "second")}},
+					{Timestamp: startTime.Add(time.Millisecond), Fields: []model.KeyValue{model.Float64("y", 1.5), model.String("x", "first")}},
+				},
+				Process: process,
+			},
+			{
+				TraceID:       model.TraceID{Low: 1},
+				SpanID:        model.SpanID(1),
+				OperationName: "root",
+				StartTime:     startTime,
+				Duration:      5 * time.Millisecond,
+				Process:       process,
+				Warnings:      []string{"warning"},
+			},
+		},
+		Warnings: []string{"trace warning"},
+	}
+}
+
+func TestFromDomain(t *testing.T) {
+	original := testTrace()
+	trace := FromDomain(original)
+	assert.Equal(t, testTrace(), original, "the original trace must not be modified")
+
+	require.Len(t, trace.Spans, 2)
+	assert.Equal(t, model.SpanID(1), trace.Spans[0].SpanID)
+	assert.Equal(t, time.UTC, trace.Spans[0].StartTime.Location())
+	assert.Equal(t, "a", trace.Spans[1].Process.Tags[0].Key)
+	assert.Equal(t, "b", trace.Spans[1].Tags[0].Key)
+	assert.Equal(t, "x", trace.Spans[1].Logs[0].Fields[0].Key)
+	assert.True(t, trace.Spans[0].Process == trace.Spans[1].Process, "processes must remain shared")
+	assert.Equal(t, []string{"trace warning"}, trace.Warnings)
+}
+
+func TestRoundTrip(t *testing.T) {
+	out, err := json.Marshal(FromDomain(testTrace()))
+	require.NoError(t, err)
+	var decoded model.Trace
+	require.NoError(t, json.Unmarshal(out, &decoded))
+
+	trace := ToDomain(&decoded)
+	assert.Equal(t, FromDomain(testTrace()), trace)
+	assert.True(t, trace.Spans[0].Process == trace.Spans[1].Process, "processes must be shared")
+}
@@ -0,0 +1,193 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package chrome
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/uber/jaeger/model"
+)
+
+// DefaultLogFieldKey is the log field whose value names the instant event of a log
+const DefaultLogFieldKey = "event"
+
+const defaultLogEventName = "log"
+
+// FromDomain converts model.Trace into the trace-event format. Each service becomes a process,
+// each span a complete event and each span log an instant event. Overlapping spans of a service
+// that do not nest are placed on separate threads, since the viewers expect the complete events
+// of a thread to be properly nested. Tags and log fields keep their JSON type, except binary
+// values which are encoded as base64 strings.
+func FromDomain(trace *model.Trace) *Trace {
+	fd := &fromDomain{}
+	spans := make([]*model.Span, len(trace.Spans))
+	copy(spans, trace.Spans)
+	sort.SliceStable(spans, func(i, j int) bool {
+		if !spans[i].StartTime.Equal(spans[j].StartTime) {
+			return spans[i].StartTime.Before(spans[j].StartTime)
+		}
+		return spans[i].Duration > spans[j].Duration
+	})
+	var spanEvents []Event
+	for _, span := range spans {
+		spanEvents = append(spanEvents, fd.convertSpan(span)...)
+	}
+	events := make([]Event, 0, len(fd.processes)+len(spanEvents))
+	for i, process := range fd.processes {
+		events = append(events, Event{
+			Name: processNameEvent,
+			Ph:   MetadataPhase,
+			Pid:  i + 1,
+			Args: marshalArgs(ProcessArgs{
+				Name: process.ServiceName,
+				Tags: convertKeyValues(process.Tags),
+			}),
+		})
+	}
+	events = append(events, spanEvents...)
+
+	result := &Trace{
+		TraceEvents:     events,
+		DisplayTimeUnit: "ms",
+	}
+	if len(trace.Spans) > 0 {
+		result.OtherData = map[string]string{"traceID": trace.Spans[0].TraceID.String()}
+	}
+	return result
+}
+
+type fromDomain struct {
+	processes []*model.Process
+	// lanes holds, for each process, the end times of the nested spans open on each thread
+	lanes map[int][][]time.Time
+}
+
+func (fd *fromDomain) convertSpan(span *model.Span) []Event {
+	pid := fd.processID(span.Process)
+	tid := fd.threadID(pid, span)
+	events := []Event{{
+		Name: span.OperationName,
+		Cat:  serviceName(span),
+		Ph:   CompletePhase,
+		Ts:   model.TimeAsEpochMicroseconds(span.StartTime),
+		Dur:  model.DurationAsMicroseconds(span.Duration),
+		Pid:  pid,
+		Tid:  tid,
+		Args: marshalArgs(SpanArgs{
+			TraceID:      span.TraceID,
+			SpanID:       span.SpanID,
+			ParentSpanID: span.ParentSpanID,
+			References:   span.References,
+			Flags:        span.Flags,
+			Tags:         convertKeyValues(span.Tags),
+			Warnings:     span.Warnings,
+		}),
+	}}
+	for _, log := range span.Logs {
+		events = append(events, Event{
+			Name:  logEventName(log),
+			Cat:   serviceName(span),
+			Ph:    InstantPhase,
+			Ts:    model.TimeAsEpochMicroseconds(log.Timestamp),
+			Pid:   pid,
+			Tid:   tid,
+			Scope: threadScope,
+			Args: marshalArgs(LogArgs{
+				SpanID: span.SpanID,
+				Fields: convertKeyValues(log.Fields),
+			}),
+		})
+	}
+	return events
+}
+
+// processID returns the 1-based index of the process, adding it if it was not seen yet
+func (fd *fromDomain) processID(process *model.Process) int {
+	if process == nil {
+		process = &model.Process{}
+	}
+	for i, p := range fd.processes {
+		if p == process || p.Equal(process) {
+			return i + 1
+		}
+	}
+	fd.processes = append(fd.processes, process)
+	return len(fd.processes)
+}
+
+// threadID returns the 1-based index of the first thread of the process on which
+// the span is either nested in the innermost open span, or the only open span.
+// It expects spans to be visited in start time order.
+func (fd *fromDomain) threadID(pid int, span *model.Span) int {
+	if fd.lanes == nil {
+		fd.lanes = make(map[int][][]time.Time)
+	}
+	lanes := fd.lanes[pid]
+	end := span.StartTime.Add(span.Duration)
+	for i, stack := range lanes {
+		for len(stack) > 0 && !stack[len(stack)-1].After(span.StartTime) {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 || !end.After(stack[len(stack)-1]) {
+			lanes[i] = append(stack, end)
+			return i + 1
+		}
+		lanes[i] = stack
+	}
+	fd.lanes[pid] = append(lanes, []time.Time{end})
+	return len(fd.lanes[pid])
+}
+
+func serviceName(span *model.Span) string {
+	if span.Process == nil {
+		return ""
+	}
+	return span.Process.ServiceName
+}
+
+func logEventName(log model.Log) string {
+	for i := range log.Fields {
+		if log.Fields[i].Key == DefaultLogFieldKey {
+			return log.Fields[i].AsString()
+		}
+	}
+	return defaultLogEventName
+}
+
+// convertKeyValues converts tags into a map of their values. When a key is repeated, the last value wins.
+func convertKeyValues(keyValues model.KeyValues) map[string]interface{} {
+	if len(keyValues) == 0 {
+		return nil
+	}
+	values := make(map[string]interface{}, len(keyValues))
+	for i := range keyValues {
+		values[keyValues[i].Key] = keyValues[i].Value()
+	}
+	return values
+}
+
+func marshalArgs(args interface{}) json.RawMessage {
+	out, _ := json.Marshal(args)
+	return out
+}
@@ -0,0 +1,127 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package chrome
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/model"
+)
+
+var (
+	startTime = time.Unix(1500000000, 123456000).UTC()
+	traceID   = model.TraceID{High: 1, Low: 2}
+	frontend  = model.NewProcess("frontend", []model.KeyValue{model.String("hostname", "host-1")})
+	backend   = model.NewProcess("backend", nil)
+)
+
+func newSpan(spanID, parentID model.SpanID, process *model.Process, start, duration int) *model.Span {
+	return &model.Span{
+		TraceID:       traceID,
+		SpanID:        spanID,
+		ParentSpanID:  parentID,
+		OperationName: "op-" + spanID.String(),
+		StartTime:     startTime.Add(time.Duration(start) * time.Millisecond),
+		Duration:      time.Duration(duration) * time.Millisecond,
+		Process:       process,
+	}
+}
+
+func testTrace() *model.Trace {
+	root := newSpan(1, 0, frontend, 0, 100)
+	root.Tags = model.KeyValues{
+		model.Bool("error", true),
+		model.Float64("ratio", 0.5),
+		model.Int64("http.status_code", 500),
+		model.String("http.method", "GET"),
+	}
+	root.Logs = []model.Log{
+		{Timestamp: startTime.Add(time.Millisecond), Fields: []model.KeyValue{model.String("event", "retry")}},
+		{Timestamp: startTime.Add(2 * time.Millisecond), Fields: []model.KeyValue{model.Int64("attempt", 2)}},
+	}
+	root.Flags = model.Flags(1)
+	async := newSpan(4, 0, frontend, 90, 30)
+	async.References = []model.SpanRef{{RefType: model.FollowsFrom, TraceID: traceID, SpanID: 1}}
+	async.Warnings = []string{"clock skew"}
+	return &model.Trace{
+		Spans: []*model.Span{
+			newSpan(3, 1, backend, 20, 10),
+			root,
+			newSpan(2, 1, &model.Process{ServiceName: "backend"}, 10, 50),
+			async,
+		},
+	}
+}
+
+func TestFromDomain(t *testing.T) {
+	trace := FromDomain(testTrace())
+	assert.Equal(t, "ms", trace.DisplayTimeUnit)
+	assert.Equal(t, map[string]string{"traceID": "10000000000000002"}, trace.OtherData)
+	require.Len(t, trace.TraceEvents, 8)
+
+	frontendMeta := trace.TraceEvents[0]
+	assert.Equal(t, Event{Name: "process_name", Ph: "M", Pid: 1}, Event{Name: frontendMeta.Name, Ph: frontendMeta.Ph, Pid: frontendMeta.Pid})
+	assert.JSONEq(t, `{"name":"frontend","tags":{"hostname":"host-1"}}`, string(frontendMeta.Args))
+	assert.JSONEq(t, `{"name":"backend"}`, string(trace.TraceEvents[1].Args))
+
+	root := trace.TraceEvents[2]
+	assert.Equal(t, "op-1", root.Name)
+	assert.Equal(t, "frontend", root.Cat)
+	assert.Equal(t, CompletePhase, root.Ph)
+	assert.Equal(t, uint64(1500000000123456), root.Ts)
+	assert.Equal(t, uint64(100000), root.Dur)
+	assert.JSONEq(t, `{
+		"traceID": "10000000000000002",
+		"spanID": "1",
+		"flags": 1,
+		"tags": {"error": true, "ratio": 0.5, "http.status_code": 500, "http.method": "GET"}
+	}`, string(root.Args))
+
+	assert.Equal(t, "retry", trace.TraceEvents[3].Name)
+	assert.Equal(t, InstantPhase, trace.TraceEvents[3].Ph)
+	assert.Equal(t, "t", trace.TraceEvents[3].Scope)
+	assert.Equal(t, "log", trace.TraceEvents[4].Name)
+	assert.JSONEq(t, `{"spanID":"1","fields":{"attempt":2}}`, string(trace.TraceEvents[4].Args))
+
+	_, err := json.Marshal(trace)
+	require.NoError(t, err)
+}
+
+func TestFromDomainThreads(t *testing.T) {
+	trace := FromDomain(testTrace())
+	threads := make(map[string]int)
+	pids := make(map[string]int)
+	for _, event := range trace.TraceEvents {
+		if event.Ph == CompletePhase {
+			threads[event.Name] = event.Tid
+			pids[event.Name] = event.Pid
+		}
+	}
+	assert.Equal(t, map[string]int{"op-1": 1, "op-2": 2, "op-3": 2, "op-4": 1}, pids)
+	// span 4 starts before span 1 ends but is not nested in it
+	assert.Equal(t, map[string]int{"op-1": 1, "op-2": 1, "op-3": 1, "op-4": 2}, threads)
+}
@@ -0,0 +1,86 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package chrome
+
+import (
+	"encoding/json"
+
+	"github.com/uber/jaeger/model"
+)
+
+// Event phases used by the converter, see
+// https://docs.google.com/document/d/1CvAClvFfyA5R-PhYUmn5OOQtYMH4h6I0nSsKchNAySU
+const (
+	// CompletePhase marks an event with a duration, used for spans
+	CompletePhase = "X"
+	// InstantPhase marks an event without a duration, used for span logs
+	InstantPhase = "i"
+	// MetadataPhase marks a metadata event, used for naming processes after services
+	MetadataPhase = "M"
+
+	processNameEvent = "process_name"
+	threadScope      = "t"
+)
+
+// Trace is a trace in the JSON object format of the trace-event format
+type Trace struct {
+	TraceEvents     []Event           `json:"traceEvents"`
+	DisplayTimeUnit string            `json:"displayTimeUnit,omitempty"`
+	OtherData       map[string]string `json:"otherData,omitempty"`
+}
+
+// Event is a trace event. Timestamps and durations are in microseconds.
+// The arguments hold SpanArgs, LogArgs or ProcessArgs depending on the phase.
+type Event struct {
+	Name  string          `json:"name"`
+	Cat   string          `json:"cat,omitempty"`
+	Ph    string          `json:"ph"`
+	Ts    uint64          `json:"ts"`
+	Dur   uint64          `json:"dur,omitempty"`
+	Pid   int             `json:"pid"`
+	Tid   int             `json:"tid"`
+	Scope string          `json:"s,omitempty"`
+	Args  json.RawMessage `json:"args,omitempty"`
+}
+
+// SpanArgs are the arguments of the complete event of a span
+type SpanArgs struct {
+	TraceID      model.TraceID          `json:"traceID"`
+	SpanID       model.SpanID           `json:"spanID"`
+	ParentSpanID model.SpanID           `json:"parentSpanID,omitempty"`
+	References   []model.SpanRef        `json:"references,omitempty"`
+	Flags        model.Flags            `json:"flags,omitempty"`
+	Tags         map[string]interface{} `json:"tags,omitempty"`
+	Warnings     []string               `json:"warnings,omitempty"`
+}
+
+// LogArgs are the arguments of the instant event of a span log
+type LogArgs struct {
+	SpanID model.SpanID           `json:"spanID"`
+	Fields map[string]interface{} `json:"fields,omitempty"`
+}
+
+// ProcessArgs are the arguments of the metadata event naming a process
+type ProcessArgs struct {
+	Name string                 `json:"name"`
+	Tags map[string]interface{} `json:"tags,omitempty"`
+}
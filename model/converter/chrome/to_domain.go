@@ -0,0 +1,143 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package chrome
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/multierror"
+)
+
+// ToDomain converts a trace in the trace-event format, as produced by FromDomain, into model.Trace.
+// JSON numbers become int64 tags when they are integral and float64 tags otherwise, and strings
+// become string tags. Events that cannot be converted are skipped and reported in the returned error.
+func ToDomain(trace *Trace) (*model.Trace, error) {
+	var errors []error
+	processes := make(map[int]*model.Process)
+	for _, event := range trace.TraceEvents {
+		if event.Ph != MetadataPhase || event.Name != processNameEvent {
+			continue
+		}
+		var args ProcessArgs
+		if err := unmarshalArgs(event.Args, &args); err != nil {
+			errors = append(errors, fmt.Errorf("invalid process %d: %v", event.Pid, err))
+			continue
+		}
+		processes[event.Pid] = model.NewProcess(args.Name, toDomainKeyValues(args.Tags))
+	}
+
+	result := &model.Trace{}
+	spans := make(map[model.SpanID]*model.Span)
+	for _, event := range trace.TraceEvents {
+		if event.Ph != CompletePhase {
+			continue
+		}
+		var args SpanArgs
+		if err := unmarshalArgs(event.Args, &args); err != nil {
+			errors = append(errors, fmt.Errorf("invalid span event %s: %v", event.Name, err))
+			continue
+		}
+		process, ok := processes[event.Pid]
+		if !ok {
+			process = model.NewProcess(event.Cat, nil)
+			processes[event.Pid] = process
+		}
+		span := &model.Span{
+			TraceID:       args.TraceID,
+			SpanID:        args.SpanID,
+			ParentSpanID:  args.ParentSpanID,
+			OperationName: event.Name,
+			References:    args.References,
+			Flags:         args.Flags,
+			StartTime:     model.EpochMicrosecondsAsTime(event.Ts),
+			Duration:      model.MicrosecondsAsDuration(event.Dur),
+			Tags:          toDomainKeyValues(args.Tags),
+			Process:       process,
+			Warnings:      args.Warnings,
+		}
+		spans[span.SpanID] = span
+		result.Spans = append(result.Spans, span)
+	}
+
+	for _, event := range trace.TraceEvents {
+		if event.Ph != InstantPhase {
+			continue
+		}
+		var args LogArgs
+		if err := unmarshalArgs(event.Args, &args); err != nil {
+			errors = append(errors, fmt.Errorf("invalid log event %s: %v", event.Name, err))
+			continue
+		}
+		span, ok := spans[args.SpanID]
+		if !ok {
+			errors = append(errors, fmt.Errorf("log event %s references unknown span %v", event.Name, args.SpanID))
+			continue
+		}
+		span.Logs = append(span.Logs, model.Log{
+			Timestamp: model.EpochMicrosecondsAsTime(event.Ts),
+			Fields:    toDomainKeyValues(args.Fields),
+		})
+	}
+	return result, multierror.Wrap(errors)
+}
+
+// unmarshalArgs decodes the event arguments, keeping numbers as json.Number
+// so that integers can be told apart from floats
+func unmarshalArgs(args json.RawMessage, out interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(args))
+	decoder.UseNumber()
+	return decoder.Decode(out)
+}
+
+func toDomainKeyValues(values map[string]interface{}) model.KeyValues {
+	if len(values) == 0 {
+		return nil
+	}
+	keyValues := make(model.KeyValues, 0, len(values))
+	for key, value := range values {
+		keyValues = append(keyValues, toDomainKeyValue(key, value))
+	}
+	sort.Sort(keyValues)
+	return keyValues
+}
+
+func toDomainKeyValue(key string, value interface{}) model.KeyValue {
+	switch v := value.(type) {
+	case bool:
+		return model.Bool(key, v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return model.Int64(key, i)
+		}
+		f, _ := v.Float64()
+		return model.Float64(key, f)
+	case string:
+		return model.String(key, v)
+	default:
+		out, _ := json.Marshal(v)
+		return model.String(key, string(out))
+	}
+}
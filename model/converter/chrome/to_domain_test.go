@@ -0,0 +1,75 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package chrome
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/model"
+)
+
+func TestRoundTrip(t *testing.T) {
+	out, err := json.Marshal(FromDomain(testTrace()))
+	require.NoError(t, err)
+	var chromeTrace Trace
+	require.NoError(t, json.Unmarshal(out, &chromeTrace))
+
+	trace, err := ToDomain(&chromeTrace)
+	require.NoError(t, err)
+	trace.NormalizeTimestamps()
+	model.SortTrace(trace)
+
+	expected := testTrace()
+	expected.Spans[2].Process = backend
+	model.SortTrace(expected)
+	assert.Equal(t, expected, trace)
+}
+
+func TestToDomainErrors(t *testing.T) {
+	trace, err := ToDomain(&Trace{
+		TraceEvents: []Event{
+			{Name: "process_name", Ph: MetadataPhase, Pid: 1, Args: json.RawMessage(`[]`)},
+			{Name: "bad", Ph: CompletePhase, Pid: 1, Args: json.RawMessage(`{"spanID":"x"}`)},
+			{Name: "good", Cat: "svc", Ph: CompletePhase, Pid: 2, Args: json.RawMessage(`{"traceID":"1","spanID":"1"}`)},
+			{Name: "bad-log", Ph: InstantPhase, Pid: 2, Args: json.RawMessage(`{"spanID":1}`)},
+			{Name: "orphan-log", Ph: InstantPhase, Pid: 2, Args: json.RawMessage(`{"spanID":"2"}`)},
+			{Name: "counter", Ph: "C", Pid: 2},
+		},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid process 1")
+	assert.Contains(t, err.Error(), "invalid span event bad")
+	assert.Contains(t, err.Error(), "invalid log event bad-log")
+	assert.Contains(t, err.Error(), "log event orphan-log references unknown span 2")
+	require.Len(t, trace.Spans, 1)
+	assert.Equal(t, "good", trace.Spans[0].OperationName)
+	assert.Equal(t, "svc", trace.Spans[0].Process.ServiceName)
+}
+
+func TestToDomainKeyValue(t *testing.T) {
+	assert.Equal(t, model.Float64("k", 1e100), toDomainKeyValue("k", json.Number("1e100")))
+	assert.Equal(t, model.String("k", `{"a":1}`), toDomainKeyValue("k", map[string]interface{}{"a": 1}))
+}
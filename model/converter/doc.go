@@ -19,5 +19,5 @@
 // THE SOFTWARE.
 
 // Package converter contains various utilities for converting model.Trace
-// to/from other data modes, like Thrift, or UI JSON.
+// to/from other data modes, like Thrift, UI JSON, Zipkin v2 JSON, or Chrome trace-event JSON.
 package converter
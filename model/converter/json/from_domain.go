@@ -197,9 +197,10 @@ func DependenciesFromDomain(dependencyLinks []model.DependencyLink) []json.Depen
 		retMe = append(
 			retMe,
 			json.DependencyLink{
-				Parent:    dependencyLink.Parent,
-				Child:     dependencyLink.Child,
-				CallCount: dependencyLink.CallCount,
+				Parent:     dependencyLink.Parent,
+				Child:      dependencyLink.Child,
+				CallCount:  dependencyLink.CallCount,
+				ErrorCount: dependencyLink.ErrorCount,
 			},
 		)
 	}
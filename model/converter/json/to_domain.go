@@ -21,7 +21,9 @@
 package json
 
 import (
+	"encoding/base64"
 	"encoding/hex"
+	stdjson "encoding/json"
 	"fmt"
 	"strconv"
 
@@ -35,7 +37,62 @@ func SpanToDomain(span *json.Span) (*model.Span, error) {
 	return toDomain{}.spanToDomain(span)
 }
 
-type toDomain struct{}
+// TraceToDomain converts json.Trace in the UI format, where spans reference processes by ID
+// and parent span IDs are expressed as CHILD_OF references, into model.Trace format.
+// Warnings are not converted, since they are produced by the adjusters when the trace is read.
+// Numeric tag values may be either float64 or json.Number, if the trace was decoded with UseNumber.
+func TraceToDomain(trace *json.Trace) (*model.Trace, error) {
+	td := toDomain{typedValues: true}
+	processes := make(map[json.ProcessID]*model.Process, len(trace.Processes))
+	for id := range trace.Processes {
+		process := trace.Processes[id]
+		p, err := td.convertProcess(&process)
+		if err != nil {
+			return nil, err
+		}
+		processes[id] = p
+	}
+	result := &model.Trace{Spans: make([]*model.Span, 0, len(trace.Spans))}
+	for i := range trace.Spans {
+		jSpan := trace.Spans[i]
+		if jSpan.Process == nil {
+			process, ok := trace.Processes[jSpan.ProcessID]
+			if !ok {
+				return nil, fmt.Errorf("span %s references unknown process %s", jSpan.SpanID, jSpan.ProcessID)
+			}
+			jSpan.Process = &process
+		}
+		span, err := td.spanToDomain(&jSpan)
+		if err != nil {
+			return nil, err
+		}
+		if p, ok := processes[jSpan.ProcessID]; ok {
+			span.Process = p
+		}
+		td.extractParentSpanID(span)
+		result.Spans = append(result.Spans, span)
+	}
+	return result, nil
+}
+
+type toDomain struct {
+	// typedValues indicates that tag values are stored as native JSON types, as in the UI format,
+	// rather than as strings, as in the storage format
+	typedValues bool
+}
+
+// extractParentSpanID reverts the conversion of the parent span ID into the first CHILD_OF reference
+func (td toDomain) extractParentSpanID(span *model.Span) {
+	if span.ParentSpanID == 0 && len(span.References) > 0 {
+		if ref := span.References[0]; ref.RefType == model.ChildOf && ref.TraceID == span.TraceID {
+			span.ParentSpanID = ref.SpanID
+			span.References = span.References[1:]
+		}
+	}
+	if len(span.References) == 0 {
+		span.References = nil
+	}
+}
 
 func (td toDomain) spanToDomain(dbSpan *json.Span) (*model.Span, error) {
 	tags, err := td.convertKeyValues(dbSpan.Tags)
@@ -63,9 +120,11 @@ func (td toDomain) spanToDomain(dbSpan *json.Span) (*model.Span, error) {
 	if err != nil {
 		return nil, err
 	}
-	parentSpanIDInt, err := model.SpanIDFromString(string(dbSpan.ParentSpanID))
-	if err != nil {
-		return nil, err
+	var parentSpanIDInt model.SpanID
+	if dbSpan.ParentSpanID != "" {
+		if parentSpanIDInt, err = model.SpanIDFromString(string(dbSpan.ParentSpanID)); err != nil {
+			return nil, err
+		}
 	}
 
 	span := &model.Span{
@@ -137,6 +196,9 @@ func (td toDomain) convertKeyValue(tag *json.KeyValue) (model.KeyValue, error) {
 }
 
 func (td toDomain) convertKeyValueOfType(tag *json.KeyValue, vType model.ValueType) (model.KeyValue, error) {
+	if td.typedValues {
+		return td.convertTypedKeyValue(tag, vType)
+	}
 	tagValue := tag.Value.(string)
 	switch vType {
 	case model.StringType:
@@ -169,6 +231,49 @@ func (td toDomain) convertKeyValueOfType(tag *json.KeyValue, vType model.ValueTy
 	return model.KeyValue{}, fmt.Errorf("not a valid ValueType string %s", vType.String())
 }
 
+func (td toDomain) convertTypedKeyValue(tag *json.KeyValue, vType model.ValueType) (model.KeyValue, error) {
+	switch value := tag.Value.(type) {
+	case string:
+		switch vType {
+		case model.StringType:
+			return model.String(tag.Key, value), nil
+		case model.BinaryType:
+			binary, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return model.KeyValue{}, err
+			}
+			return model.Binary(tag.Key, binary), nil
+		}
+	case bool:
+		if vType == model.BoolType {
+			return model.Bool(tag.Key, value), nil
+		}
+	case float64:
+		switch vType {
+		case model.Int64Type:
+			return model.Int64(tag.Key, int64(value)), nil
+		case model.Float64Type:
+			return model.Float64(tag.Key, value), nil
+		}
+	case stdjson.Number:
+		switch vType {
+		case model.Int64Type:
+			i, err := value.Int64()
+			if err != nil {
+				return model.KeyValue{}, err
+			}
+			return model.Int64(tag.Key, i), nil
+		case model.Float64Type:
+			f, err := value.Float64()
+			if err != nil {
+				return model.KeyValue{}, err
+			}
+			return model.Float64(tag.Key, f), nil
+		}
+	}
+	return model.KeyValue{}, fmt.Errorf("invalid %s value %v for tag %s", vType.String(), tag.Value, tag.Key)
+}
+
 func (td toDomain) convertLogs(logs []json.Log) ([]model.Log, error) {
 	retMe := make([]model.Log, len(logs))
 	for i, l := range logs {
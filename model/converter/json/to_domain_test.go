@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -256,3 +257,94 @@ func TestFailureBadParentSpanID(t *testing.T) {
 	badParentSpanIDESSpan.ParentSpanID = "zz"
 	failingSpanTransformAnyMsg(t, &badParentSpanIDESSpan)
 }
+
+func TestTraceToDomain(t *testing.T) {
+	for i := 1; i <= NumberOfFixtures; i++ {
+		inStr, outStr := testReadFixtures(t, i, false)
+
+		var expectedTrace model.Trace
+		require.NoError(t, json.Unmarshal(inStr, &expectedTrace))
+		var uiTrace jModel.Trace
+		require.NoError(t, json.Unmarshal(outStr, &uiTrace))
+
+		actualTrace, err := TraceToDomain(&uiTrace)
+		require.NoError(t, err)
+		require.Len(t, actualTrace.Spans, len(expectedTrace.Spans))
+		for j := range expectedTrace.Spans {
+			expectedSpan := expectedTrace.Spans[j]
+			// the UI format has microsecond precision and does not carry span warnings
+			expectedSpan.StartTime = expectedSpan.StartTime.Truncate(time.Microsecond)
+			expectedSpan.Warnings = nil
+			for k := range expectedSpan.Logs {
+				expectedSpan.Logs[k].Timestamp = expectedSpan.Logs[k].Timestamp.Truncate(time.Microsecond)
+			}
+			CompareModelSpans(t, normalizeEmpty(expectedSpan), normalizeEmpty(actualTrace.Spans[j]))
+		}
+	}
+}
+
+func normalizeEmpty(span *model.Span) *model.Span {
+	if len(span.Tags) == 0 {
+		span.Tags = nil
+	}
+	if len(span.Logs) == 0 {
+		span.Logs = nil
+	}
+	if len(span.Process.Tags) == 0 {
+		span.Process.Tags = nil
+	}
+	return span
+}
+
+func TestTraceToDomainUnknownProcess(t *testing.T) {
+	_, outStr := testReadFixtures(t, 1, false)
+	var uiTrace jModel.Trace
+	require.NoError(t, json.Unmarshal(outStr, &uiTrace))
+	uiTrace.Processes = nil
+
+	trace, err := TraceToDomain(&uiTrace)
+	assert.Nil(t, trace)
+	assert.EqualError(t, err, "span 2 references unknown process p1")
+}
+
+func TestTraceToDomainTypedValues(t *testing.T) {
+	testCases := []struct {
+		tag      jModel.KeyValue
+		expected model.KeyValue
+		err      string
+	}{
+		{
+			tag:      jModel.KeyValue{Key: "k", Type: jModel.Int64Type, Value: json.Number("9007199254740993")},
+			expected: model.Int64("k", 9007199254740993),
+		},
+		{
+			tag:      jModel.KeyValue{Key: "k", Type: jModel.Float64Type, Value: json.Number("72.5")},
+			expected: model.Float64("k", 72.5),
+		},
+		{
+			tag:      jModel.KeyValue{Key: "k", Type: jModel.BinaryType, Value: "AAAwOQ=="},
+			expected: model.Binary("k", []byte{0, 0, 48, 57}),
+		},
+		{
+			tag: jModel.KeyValue{Key: "k", Type: jModel.BoolType, Value: "true"},
+			err: "invalid bool value true for tag k",
+		},
+		{
+			tag: jModel.KeyValue{Key: "k", Type: jModel.Int64Type, Value: json.Number("1.5")},
+			err: `strconv.ParseInt: parsing "1.5": invalid syntax`,
+		},
+		{
+			tag: jModel.KeyValue{Key: "k", Type: jModel.BinaryType, Value: "!"},
+			err: "illegal base64 data at input byte 0",
+		},
+	}
+	for _, testCase := range testCases {
+		kv, err := toDomain{typedValues: true}.convertKeyValue(&testCase.tag)
+		if testCase.err != "" {
+			assert.EqualError(t, err, testCase.err)
+		} else {
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, kv)
+		}
+	}
+}
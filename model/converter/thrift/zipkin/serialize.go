@@ -0,0 +1,67 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zipkin
+
+import (
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/uber/jaeger/thrift-gen/zipkincore"
+)
+
+// SerializeThrift encodes Zipkin spans as a TBinaryProtocol list, the body accepted by the Zipkin HTTP API
+func SerializeThrift(spans []*zipkincore.Span) ([]byte, error) {
+	buffer := thrift.NewTMemoryBuffer()
+	protocol := thrift.NewTBinaryProtocolTransport(buffer)
+	if err := protocol.WriteListBegin(thrift.STRUCT, len(spans)); err != nil {
+		return nil, err
+	}
+	for _, span := range spans {
+		if err := span.Write(protocol); err != nil {
+			return nil, err
+		}
+	}
+	if err := protocol.WriteListEnd(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// DeserializeThrift decodes Zipkin spans encoded by SerializeThrift
+func DeserializeThrift(b []byte) ([]*zipkincore.Span, error) {
+	buffer := thrift.NewTMemoryBuffer()
+	buffer.Write(b)
+	protocol := thrift.NewTBinaryProtocolTransport(buffer)
+	_, size, err := protocol.ReadListBegin()
+	if err != nil {
+		return nil, err
+	}
+	// the size is not used to preallocate the slice, since it can be unreasonably large on bad input
+	var spans []*zipkincore.Span
+	for i := 0; i < size; i++ {
+		span := &zipkincore.Span{}
+		if err := span.Read(protocol); err != nil {
+			return nil, err
+		}
+		spans = append(spans, span)
+	}
+	return spans, nil
+}
@@ -0,0 +1,49 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zipkin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/thrift-gen/zipkincore"
+)
+
+func TestSerializeThrift(t *testing.T) {
+	spans := []*zipkincore.Span{{Name: "a", ID: 1}, {Name: "b", ID: 2}}
+	b, err := SerializeThrift(spans)
+	require.NoError(t, err)
+	deserialized, err := DeserializeThrift(b)
+	require.NoError(t, err)
+	require.Len(t, deserialized, 2)
+	for i, span := range deserialized {
+		assert.Equal(t, spans[i].Name, span.Name)
+		assert.Equal(t, spans[i].ID, span.ID)
+	}
+
+	_, err = DeserializeThrift(b[:len(b)-3])
+	assert.Error(t, err)
+	_, err = DeserializeThrift(nil)
+	assert.Error(t, err)
+}
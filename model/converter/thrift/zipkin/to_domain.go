@@ -26,6 +26,7 @@ import (
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/opentracing/opentracing-go/ext"
 
@@ -67,6 +68,10 @@ var (
 	// IPTagName is the Jaeger tag name for an IPv4/IPv6 IP address.
 	// TODO move to domain model
 	IPTagName = "ip"
+
+	// ProcessTagPrefix marks the binary annotations holding a tag of the process rather than of the span,
+	// e.g. the tags added by the agent: "jaeger.process.dc" becomes the "dc" tag of the Process.
+	ProcessTagPrefix = "jaeger.process."
 )
 
 // ToDomain transforms a trace in zipkin.thrift format into model.Trace.
@@ -150,11 +155,17 @@ func (td toDomain) getFlags(zSpan *zipkincore.Span) model.Flags {
 // An optional error may also be returned, but it is not fatal.
 func (td toDomain) generateProcess(zSpan *zipkincore.Span) (*model.Process, error) {
 	tags := td.getTags(zSpan.BinaryAnnotations, td.isProcessTag)
+	hasIP := false
 	for i, tag := range tags {
-		tags[i].Key = processTagAnnotations[tag.Key]
+		if key, ok := processTagAnnotations[tag.Key]; ok {
+			tags[i].Key = key
+		} else {
+			tags[i].Key = strings.TrimPrefix(tag.Key, ProcessTagPrefix)
+		}
+		hasIP = hasIP || tags[i].Key == IPTagName
 	}
 	serviceName, ipv4, err := td.findServiceNameAndIP(zSpan)
-	if ipv4 != 0 {
+	if ipv4 != 0 && !hasIP {
 		// If the ip process tag already exists, don't add it again
 		tags = append(tags, model.Int64(IPTagName, int64(uint64(ipv4))))
 	}
@@ -191,7 +202,7 @@ func (td toDomain) isCoreAnnotation(annotation *zipkincore.Annotation) bool {
 
 func (td toDomain) isProcessTag(binaryAnnotation *zipkincore.BinaryAnnotation) bool {
 	_, ok := processTagAnnotations[binaryAnnotation.Key]
-	return ok
+	return ok || strings.HasPrefix(binaryAnnotation.Key, ProcessTagPrefix)
 }
 
 func (td toDomain) isSpanTag(binaryAnnotation *zipkincore.BinaryAnnotation) bool {
@@ -80,6 +80,22 @@ func TestToDomainNoServiceNameError(t *testing.T) {
 	assert.Equal(t, "unknown-service-name", trace.Spans[0].Process.ServiceName)
 }
 
+func TestToDomainProcessTagPrefix(t *testing.T) {
+	endpoint := &z.Endpoint{ServiceName: "svc", Ipv4: 1}
+	zSpan := &z.Span{
+		Annotations: []*z.Annotation{{Value: z.SERVER_RECV, Host: endpoint}},
+		BinaryAnnotations: []*z.BinaryAnnotation{
+			{Key: "http.method", Value: []byte("GET"), AnnotationType: z.AnnotationType_STRING, Host: endpoint},
+			{Key: ProcessTagPrefix + "dc", Value: []byte("us-east"), AnnotationType: z.AnnotationType_STRING, Host: endpoint},
+			{Key: ProcessTagPrefix + IPTagName, Value: []byte("10.0.0.1"), AnnotationType: z.AnnotationType_STRING, Host: endpoint},
+		},
+	}
+	span, err := ToDomainSpan(zSpan)
+	require.NoError(t, err)
+	assert.Equal(t, model.KeyValues{model.String("http.method", "GET"), model.String("span.kind", "server")}, span.Tags)
+	assert.Equal(t, model.KeyValues{model.String("dc", "us-east"), model.String(IPTagName, "10.0.0.1")}, span.Process.Tags)
+}
+
 func TestInvalidAnnotationTypeError(t *testing.T) {
 	_, err := toDomain{}.transformBinaryAnnotation(&z.BinaryAnnotation{
 		AnnotationType: -1,
@@ -0,0 +1,182 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zipkinv2
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/opentracing/opentracing-go/ext"
+
+	"github.com/uber/jaeger/model"
+)
+
+const (
+	// DefaultLogFieldKey is the log field key which translates directly into Annotation.Value,
+	// provided it's the only field in the log. In all other cases the fields are encoded
+	// into Annotation.Value as JSON string.
+	DefaultLogFieldKey = "event"
+
+	// IPTagName is the Jaeger process tag name for an IPv4/IPv6 IP address,
+	// which translates into the IP of the local endpoint
+	IPTagName = "ip"
+)
+
+var spanKinds = map[string]string{
+	string(ext.SpanKindRPCClientEnum): "CLIENT",
+	string(ext.SpanKindRPCServerEnum): "SERVER",
+	string(ext.SpanKindProducerEnum):  "PRODUCER",
+	string(ext.SpanKindConsumerEnum):  "CONSUMER",
+}
+
+// FromDomain converts model.Trace into Zipkin v2 spans.
+// Zipkin has no notion of a process, so the process tags other than the IP become span tags,
+// and the tags are converted to strings. Only the parent reference of each span is kept.
+func FromDomain(trace *model.Trace) []*Span {
+	spans := make([]*Span, len(trace.Spans))
+	for i, span := range trace.Spans {
+		spans[i] = FromDomainSpan(span)
+	}
+	return spans
+}
+
+// FromDomainSpan converts model.Span into a Zipkin v2 span.
+func FromDomainSpan(span *model.Span) *Span {
+	zSpan := &Span{
+		TraceID:     traceIDToString(span.TraceID),
+		ID:          spanIDToString(span.SpanID),
+		Name:        span.OperationName,
+		Timestamp:   model.TimeAsEpochMicroseconds(span.StartTime),
+		Duration:    model.DurationAsMicroseconds(span.Duration),
+		Debug:       span.Flags.IsDebug(),
+		Annotations: convertLogs(span.Logs),
+	}
+	if parentID := span.ParentID(); parentID != 0 {
+		zSpan.ParentID = spanIDToString(parentID)
+	}
+	tags := make(map[string]string)
+	remote := &Endpoint{}
+	for i := range span.Tags {
+		tag := &span.Tags[i]
+		switch tag.Key {
+		case string(ext.SpanKind):
+			if kind, ok := spanKinds[tag.AsString()]; ok {
+				zSpan.Kind = kind
+				continue
+			}
+		case string(ext.PeerService):
+			remote.ServiceName = tag.AsString()
+			continue
+		case string(ext.PeerHostIPv4):
+			remote.IPv4 = ipToString(tag)
+			continue
+		case string(ext.PeerHostIPv6):
+			remote.IPv6 = ipv6ToString(tag)
+			continue
+		case string(ext.PeerPort):
+			if tag.VType == model.Int64Type {
+				remote.Port = int(tag.Int64())
+				continue
+			}
+		}
+		tags[tag.Key] = tag.AsString()
+	}
+	if *remote != (Endpoint{}) {
+		zSpan.RemoteEndpoint = remote
+	}
+	if span.Process != nil {
+		local := &Endpoint{ServiceName: span.Process.ServiceName}
+		for i := range span.Process.Tags {
+			tag := &span.Process.Tags[i]
+			if tag.Key != IPTagName {
+				tags[tag.Key] = tag.AsString()
+			} else if ip := ipToString(tag); strings.Contains(ip, ":") {
+				local.IPv6 = ip
+			} else {
+				local.IPv4 = ip
+			}
+		}
+		zSpan.LocalEndpoint = local
+	}
+	if len(tags) > 0 {
+		zSpan.Tags = tags
+	}
+	return zSpan
+}
+
+func traceIDToString(traceID model.TraceID) string {
+	if traceID.High == 0 {
+		return fmt.Sprintf("%016x", traceID.Low)
+	}
+	return fmt.Sprintf("%016x%016x", traceID.High, traceID.Low)
+}
+
+func spanIDToString(spanID model.SpanID) string {
+	return fmt.Sprintf("%016x", uint64(spanID))
+}
+
+// ipToString converts an IP tag, either an IPv4 packed into an int64 or a string, into its textual representation
+func ipToString(tag *model.KeyValue) string {
+	if tag.VType == model.Int64Type {
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, uint32(tag.Int64()))
+		return ip.String()
+	}
+	return tag.AsString()
+}
+
+// ipv6ToString converts an IPv6 tag, either packed into 16 bytes or a string, into its textual representation
+func ipv6ToString(tag *model.KeyValue) string {
+	if tag.VType == model.BinaryType && len(tag.Binary()) == net.IPv6len {
+		return net.IP(tag.Binary()).String()
+	}
+	return tag.AsString()
+}
+
+func convertLogs(logs []model.Log) []Annotation {
+	if len(logs) == 0 {
+		return nil
+	}
+	annotations := make([]Annotation, len(logs))
+	for i, log := range logs {
+		annotations[i] = Annotation{
+			Timestamp: model.TimeAsEpochMicroseconds(log.Timestamp),
+			Value:     logValue(log.Fields),
+		}
+	}
+	return annotations
+}
+
+func logValue(fields []model.KeyValue) string {
+	if len(fields) == 1 && fields[0].Key == DefaultLogFieldKey {
+		return fields[0].AsString()
+	}
+	values := make(map[string]string, len(fields))
+	for i := range fields {
+		values[fields[i].Key] = fields[i].AsString()
+	}
+	value, _ := json.Marshal(values)
+	return string(value)
+}
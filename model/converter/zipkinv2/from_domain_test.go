@@ -0,0 +1,132 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zipkinv2
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/model"
+)
+
+var (
+	startTime = time.Unix(1500000000, 123456000).UTC()
+
+	testTrace = &model.Trace{
+		Spans: []*model.Span{
+			{
+				TraceID:       model.TraceID{High: 1, Low: 2},
+				SpanID:        model.SpanID(3),
+				OperationName: "GET /customer",
+				StartTime:     startTime,
+				Duration:      5 * time.Millisecond,
+				Tags: model.KeyValues{
+					model.String("http.method", "GET"),
+					model.String("peer.service", "mysql"),
+					model.Int64("peer.ipv4", 0x0a000001),
+					model.Int64("peer.port", 3306),
+					model.String("span.kind", "client"),
+				},
+				Logs: []model.Log{
+					{
+						Timestamp: startTime.Add(time.Millisecond),
+						Fields:    []model.KeyValue{model.String("event", "retry")},
+					},
+					{
+						Timestamp: startTime.Add(2 * time.Millisecond),
+						Fields:    []model.KeyValue{model.String("level", "error"), model.String("message", "timeout")},
+					},
+				},
+				Process: model.NewProcess("customer", []model.KeyValue{
+					model.String("hostname", "host-1"),
+					model.Int64("ip", 0xc0a80001),
+				}),
+			},
+			{
+				TraceID:       model.TraceID{High: 1, Low: 2},
+				SpanID:        model.SpanID(4),
+				ParentSpanID:  model.SpanID(3),
+				OperationName: "SQL SELECT",
+				Flags:         model.Flags(2),
+				StartTime:     startTime.Add(time.Millisecond),
+				Duration:      3 * time.Millisecond,
+				Tags: model.KeyValues{
+					model.String("span.kind", "server"),
+				},
+				Process: model.NewProcess("mysql", nil),
+			},
+		},
+	}
+)
+
+func TestFromDomain(t *testing.T) {
+	spans := FromDomain(testTrace)
+	require.Len(t, spans, 2)
+	out, err := json.Marshal(spans[0])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"traceId": "00000000000000010000000000000002",
+		"id": "0000000000000003",
+		"name": "GET /customer",
+		"kind": "CLIENT",
+		"timestamp": 1500000000123456,
+		"duration": 5000,
+		"localEndpoint": {"serviceName": "customer", "ipv4": "192.168.0.1"},
+		"remoteEndpoint": {"serviceName": "mysql", "ipv4": "10.0.0.1", "port": 3306},
+		"annotations": [
+			{"timestamp": 1500000000124456, "value": "retry"},
+			{"timestamp": 1500000000125456, "value": "{\"level\":\"error\",\"message\":\"timeout\"}"}
+		],
+		"tags": {"http.method": "GET", "hostname": "host-1"}
+	}`, string(out))
+
+	assert.Equal(t, "0000000000000003", spans[1].ParentID)
+	assert.Equal(t, "SERVER", spans[1].Kind)
+	assert.True(t, spans[1].Debug)
+	assert.Nil(t, spans[1].RemoteEndpoint)
+	assert.Nil(t, spans[1].Tags)
+}
+
+func TestFromDomainSpanReferencesAndIPv6(t *testing.T) {
+	span := &model.Span{
+		TraceID:    model.TraceID{Low: 1},
+		SpanID:     model.SpanID(2),
+		References: []model.SpanRef{{RefType: model.FollowsFrom, TraceID: model.TraceID{Low: 1}, SpanID: 1}},
+		Tags: model.KeyValues{
+			model.String("span.kind", "unknown"),
+			model.String("peer.port", "http"),
+			model.Binary("peer.ipv6", []byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}),
+		},
+		Process: model.NewProcess("svc", []model.KeyValue{model.String("ip", "::1")}),
+	}
+	zSpan := FromDomainSpan(span)
+	assert.Equal(t, "0000000000000001", zSpan.TraceID)
+	assert.Equal(t, "0000000000000001", zSpan.ParentID)
+	assert.Empty(t, zSpan.Kind)
+	assert.Equal(t, map[string]string{"span.kind": "unknown", "peer.port": "http"}, zSpan.Tags)
+	assert.Equal(t, &Endpoint{IPv6: "2001:db8::1"}, zSpan.RemoteEndpoint)
+	assert.Equal(t, &Endpoint{ServiceName: "svc", IPv6: "::1"}, zSpan.LocalEndpoint)
+}
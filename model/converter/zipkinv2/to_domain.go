@@ -0,0 +1,162 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zipkinv2
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/opentracing/opentracing-go/ext"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/multierror"
+)
+
+// UnknownServiceName is the service name given to model.Process when a Zipkin span has no local endpoint
+const UnknownServiceName = "unknown-service-name"
+
+// processTags are the span tags written by FromDomain that originally described the process
+var processTags = map[string]bool{
+	"jaeger.version": true,
+	"hostname":       true,
+}
+
+// ToDomain transforms Zipkin v2 spans into model.Trace.
+// Spans with invalid IDs are skipped and reported in the returned error,
+// while the valid spans are always returned in the trace.
+func ToDomain(zSpans []*Span) (*model.Trace, error) {
+	var errors []error
+	trace := &model.Trace{}
+	for _, zSpan := range zSpans {
+		span, err := ToDomainSpan(zSpan)
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		trace.Spans = append(trace.Spans, span)
+	}
+	return trace, multierror.Wrap(errors)
+}
+
+// ToDomainSpan transforms a Zipkin v2 span into model.Span.
+// All tags are converted to string tags, except for the ones describing the remote endpoint.
+func ToDomainSpan(zSpan *Span) (*model.Span, error) {
+	traceID, err := model.TraceIDFromString(zSpan.TraceID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Zipkin trace ID %q: %v", zSpan.TraceID, err)
+	}
+	spanID, err := model.SpanIDFromString(zSpan.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Zipkin span ID %q: %v", zSpan.ID, err)
+	}
+	span := &model.Span{
+		TraceID:       traceID,
+		SpanID:        spanID,
+		OperationName: zSpan.Name,
+		StartTime:     model.EpochMicrosecondsAsTime(zSpan.Timestamp),
+		Duration:      model.MicrosecondsAsDuration(zSpan.Duration),
+		Logs:          toDomainLogs(zSpan.Annotations),
+	}
+	if zSpan.ParentID != "" {
+		parentID, err := model.SpanIDFromString(zSpan.ParentID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Zipkin parent span ID %q: %v", zSpan.ParentID, err)
+		}
+		span.ParentSpanID = parentID
+	}
+	if zSpan.Debug {
+		span.Flags.SetDebug()
+	}
+
+	var tags, procTags []model.KeyValue
+	for key, value := range zSpan.Tags {
+		if processTags[key] {
+			procTags = append(procTags, model.String(key, value))
+		} else {
+			tags = append(tags, model.String(key, value))
+		}
+	}
+	if zSpan.Kind != "" {
+		tags = append(tags, model.String(string(ext.SpanKind), strings.ToLower(zSpan.Kind)))
+	}
+	if remote := zSpan.RemoteEndpoint; remote != nil {
+		if remote.ServiceName != "" {
+			tags = append(tags, model.String(string(ext.PeerService), remote.ServiceName))
+		}
+		if ip := net.ParseIP(remote.IPv4).To4(); ip != nil {
+			tags = append(tags, model.Int64(string(ext.PeerHostIPv4), int64(binary.BigEndian.Uint32(ip))))
+		}
+		if ip := net.ParseIP(remote.IPv6); ip != nil {
+			tags = append(tags, model.Binary(string(ext.PeerHostIPv6), ip.To16()))
+		}
+		if remote.Port != 0 {
+			tags = append(tags, model.Int64(string(ext.PeerPort), int64(remote.Port)))
+		}
+	}
+	span.Tags = model.KeyValues(tags)
+	span.Tags.Sort()
+
+	serviceName := UnknownServiceName
+	if local := zSpan.LocalEndpoint; local != nil {
+		if local.ServiceName != "" {
+			serviceName = local.ServiceName
+		}
+		if ip := net.ParseIP(local.IPv4).To4(); ip != nil {
+			procTags = append(procTags, model.Int64(IPTagName, int64(binary.BigEndian.Uint32(ip))))
+		} else if local.IPv6 != "" {
+			procTags = append(procTags, model.String(IPTagName, local.IPv6))
+		}
+	}
+	span.Process = model.NewProcess(serviceName, procTags)
+	return span, nil
+}
+
+func toDomainLogs(annotations []Annotation) []model.Log {
+	if len(annotations) == 0 {
+		return nil
+	}
+	logs := make([]model.Log, len(annotations))
+	for i, annotation := range annotations {
+		logs[i] = model.Log{
+			Timestamp: model.EpochMicrosecondsAsTime(annotation.Timestamp),
+			Fields:    toDomainLogFields(annotation.Value),
+		}
+	}
+	return logs
+}
+
+// toDomainLogFields decodes the log fields encoded by logValue
+func toDomainLogFields(value string) []model.KeyValue {
+	var values map[string]string
+	if err := json.Unmarshal([]byte(value), &values); err == nil {
+		fields := make(model.KeyValues, 0, len(values))
+		for k, v := range values {
+			fields = append(fields, model.String(k, v))
+		}
+		fields.Sort()
+		return fields
+	}
+	return []model.KeyValue{model.String(DefaultLogFieldKey, value)}
+}
@@ -0,0 +1,85 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zipkinv2
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/model"
+)
+
+func TestRoundTrip(t *testing.T) {
+	out, err := json.Marshal(FromDomain(testTrace))
+	require.NoError(t, err)
+	var zSpans []*Span
+	require.NoError(t, json.Unmarshal(out, &zSpans))
+
+	trace, err := ToDomain(zSpans)
+	require.NoError(t, err)
+	trace.NormalizeTimestamps()
+	model.SortTrace(trace)
+
+	expected := &model.Trace{}
+	for _, span := range testTrace.Spans {
+		s := *span
+		s.Tags = append(model.KeyValues{}, span.Tags...)
+		expected.Spans = append(expected.Spans, &s)
+	}
+	model.SortTrace(expected)
+	assert.Equal(t, expected, trace)
+}
+
+func TestToDomainErrors(t *testing.T) {
+	zSpans := []*Span{
+		{TraceID: "x", ID: "1"},
+		{TraceID: "1", ID: "x"},
+		{TraceID: "1", ID: "1", ParentID: "x"},
+		{TraceID: "1", ID: "2"},
+	}
+	trace, err := ToDomain(zSpans)
+	assert.EqualError(t, err, `[invalid Zipkin trace ID "x": strconv.ParseUint: parsing "x": invalid syntax, `+
+		`invalid Zipkin span ID "x": strconv.ParseUint: parsing "x": invalid syntax, `+
+		`invalid Zipkin parent span ID "x": strconv.ParseUint: parsing "x": invalid syntax]`)
+	require.Len(t, trace.Spans, 1)
+	assert.Equal(t, model.SpanID(2), trace.Spans[0].SpanID)
+	assert.Equal(t, UnknownServiceName, trace.Spans[0].Process.ServiceName)
+}
+
+func TestToDomainEndpoints(t *testing.T) {
+	span, err := ToDomainSpan(&Span{
+		TraceID:        "1",
+		ID:             "2",
+		LocalEndpoint:  &Endpoint{ServiceName: "svc", IPv6: "::1"},
+		RemoteEndpoint: &Endpoint{IPv6: "2001:db8::1"},
+		Annotations:    []Annotation{{Timestamp: 1, Value: "{not json"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, model.NewProcess("svc", []model.KeyValue{model.String("ip", "::1")}), span.Process)
+	assert.Equal(t, model.KeyValues{
+		model.Binary("peer.ipv6", []byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}),
+	}, span.Tags)
+	assert.Equal(t, []model.KeyValue{model.String("event", "{not json")}, span.Logs[0].Fields)
+}
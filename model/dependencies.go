@@ -25,4 +25,6 @@ type DependencyLink struct {
 	Parent    string `json:"parent"`
 	Child     string `json:"child"`
 	CallCount uint64 `json:"callCount"`
+	// ErrorCount is the number of calls that failed, i.e. whose child span has the error tag
+	ErrorCount uint64 `json:"errorCount,omitempty"`
 }
@@ -115,9 +115,10 @@ type KeyValue struct {
 
 // DependencyLink shows dependencies between services
 type DependencyLink struct {
-	Parent    string `json:"parent"`
-	Child     string `json:"child"`
-	CallCount uint64 `json:"callCount"`
+	Parent     string `json:"parent"`
+	Child      string `json:"child"`
+	CallCount  uint64 `json:"callCount"`
+	ErrorCount uint64 `json:"errorCount,omitempty"`
 }
 
 // FromFile reads a Trace from a JSON file.
@@ -94,6 +94,23 @@ func (s *Span) IsRPCServer() bool {
 	return s.HasSpanKind(ext.SpanKindRPCServerEnum)
 }
 
+// IsError returns true if the span represents a failed operation,
+// as indicated by the `error` tag set to true.
+func (s *Span) IsError() bool {
+	if tag, ok := s.Tags.FindByKey(string(ext.Error)); ok {
+		return tag.AsString() == "true"
+	}
+	return false
+}
+
+// ParentID returns the parent span ID or, when absent, the span ID of the first reference.
+func (s *Span) ParentID() SpanID {
+	if s.ParentSpanID != 0 || len(s.References) == 0 {
+		return s.ParentSpanID
+	}
+	return s.References[0].SpanID
+}
+
 // NormalizeTimestamps changes all timestamps in this span to UTC.
 func (s *Span) NormalizeTimestamps() {
 	s.StartTime = s.StartTime.UTC()
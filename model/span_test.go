@@ -157,6 +157,30 @@ func TestIsRPCClientServer(t *testing.T) {
 	assert.False(t, span2.IsRPCServer())
 }
 
+func TestIsError(t *testing.T) {
+	testCases := []struct {
+		tags    model.KeyValues
+		isError bool
+	}{
+		{tags: model.KeyValues{model.Bool(string(ext.Error), true)}, isError: true},
+		{tags: model.KeyValues{model.String(string(ext.Error), "true")}, isError: true},
+		{tags: model.KeyValues{model.Bool(string(ext.Error), false)}, isError: false},
+		{tags: model.KeyValues{model.String("foo", "true")}, isError: false},
+		{isError: false},
+	}
+	for _, testCase := range testCases {
+		span := &model.Span{Tags: testCase.tags}
+		assert.Equal(t, testCase.isError, span.IsError(), "tags %v", testCase.tags)
+	}
+}
+
+func TestParentID(t *testing.T) {
+	refs := []model.SpanRef{{RefType: model.FollowsFrom, SpanID: 3}, {RefType: model.ChildOf, SpanID: 4}}
+	assert.Equal(t, model.SpanID(2), (&model.Span{ParentSpanID: 2, References: refs}).ParentID())
+	assert.Equal(t, model.SpanID(3), (&model.Span{References: refs}).ParentID())
+	assert.Equal(t, model.SpanID(0), (&model.Span{}).ParentID())
+}
+
 func TestIsDebug(t *testing.T) {
 	flags := model.Flags(0)
 	flags.SetDebug()
@@ -93,6 +93,11 @@ func (q CQLQuery) PageSize(n int) cassandra.Query {
 	return WrapCQLQuery(q.query.PageSize(n))
 }
 
+// PageState delegates to gocql.Query#PageState and wraps the result as Query.
+func (q CQLQuery) PageState(state []byte) cassandra.Query {
+	return WrapCQLQuery(q.query.PageState(state))
+}
+
 // ---
 
 // CQLIterator is a wrapper around gocql.Iter.
@@ -110,6 +115,11 @@ func (i CQLIterator) Scan(dest ...interface{}) bool {
 	return i.iter.Scan(dest...)
 }
 
+// PageState delegates to gocql.Iter#PageState.
+func (i CQLIterator) PageState() []byte {
+	return i.iter.PageState()
+}
+
 // Close delegates to gocql.Iter#Close.
 func (i CQLIterator) Close() error {
 	return i.iter.Close()
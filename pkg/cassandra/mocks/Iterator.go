@@ -42,6 +42,22 @@ func (_m *Iterator) Close() error {
 	return r0
 }
 
+// PageState provides a mock function with given fields:
+func (_m *Iterator) PageState() []byte {
+	ret := _m.Called()
+
+	var r0 []byte
+	if rf, ok := ret.Get(0).(func() []byte); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	return r0
+}
+
 // Scan provides a mock function with given fields: dest
 func (_m *Iterator) Scan(dest ...interface{}) bool {
 	ret := _m.Called(dest)
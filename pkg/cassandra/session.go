@@ -69,10 +69,12 @@ type Query interface {
 	Bind(v ...interface{}) Query
 	Consistency(level Consistency) Query
 	PageSize(int) Query
+	PageState(state []byte) Query
 }
 
 // Iterator is an abstraction of gocql.Iter
 type Iterator interface {
 	Scan(dest ...interface{}) bool
+	PageState() []byte
 	Close() error
 }
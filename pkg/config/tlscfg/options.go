@@ -0,0 +1,96 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tlscfg
+
+import (
+	"flag"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	suffixCert           = ".tls.cert"
+	suffixKey            = ".tls.key"
+	suffixClientCA       = ".tls.client-ca"
+	suffixReloadInterval = ".tls.reload-interval"
+
+	// DefaultReloadInterval is how often the certificate files are checked for changes
+	DefaultReloadInterval = 10 * time.Second
+)
+
+// Options describes the TLS configuration of a server listener
+type Options struct {
+	// CertPath is the path of the PEM encoded server certificate
+	CertPath string
+	// KeyPath is the path of the PEM encoded server private key
+	KeyPath string
+	// ClientCAPath is the path of the PEM encoded CA bundle used to verify client certificates.
+	// When it is set, clients are required to present a valid certificate (mutual TLS).
+	ClientCAPath string
+	// ReloadInterval is how often the files are checked for changes
+	ReloadInterval time.Duration
+
+	namespace string
+}
+
+// NewOptions creates TLS options whose flags are prefixed with the given namespace, e.g. "collector.http"
+func NewOptions(namespace string) *Options {
+	return &Options{
+		ReloadInterval: DefaultReloadInterval,
+		namespace:      namespace,
+	}
+}
+
+// AddFlags adds flags for Options
+func (opt *Options) AddFlags(flagSet *flag.FlagSet) {
+	flagSet.String(
+		opt.namespace+suffixCert,
+		opt.CertPath,
+		"Path to the TLS certificate file, enables TLS together with "+opt.namespace+suffixKey)
+	flagSet.String(
+		opt.namespace+suffixKey,
+		opt.KeyPath,
+		"Path to the TLS private key file")
+	flagSet.String(
+		opt.namespace+suffixClientCA,
+		opt.ClientCAPath,
+		"Path to the CA certificates file used to verify client certificates, enables mutual TLS")
+	flagSet.Duration(
+		opt.namespace+suffixReloadInterval,
+		opt.ReloadInterval,
+		"How often the TLS files are checked for changes")
+}
+
+// InitFromViper initializes Options with properties from viper
+func (opt *Options) InitFromViper(v *viper.Viper) *Options {
+	opt.CertPath = v.GetString(opt.namespace + suffixCert)
+	opt.KeyPath = v.GetString(opt.namespace + suffixKey)
+	opt.ClientCAPath = v.GetString(opt.namespace + suffixClientCA)
+	opt.ReloadInterval = v.GetDuration(opt.namespace + suffixReloadInterval)
+	return opt
+}
+
+// Enabled returns true if a certificate or a key is configured
+func (opt *Options) Enabled() bool {
+	return opt.CertPath != "" || opt.KeyPath != ""
+}
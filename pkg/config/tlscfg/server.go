@@ -0,0 +1,55 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tlscfg
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// NewServerConfig returns the TLS configuration of a server listener, or nil if TLS is not enabled.
+// The certificates are reloaded for as long as the process runs.
+func NewServerConfig(opts *Options, logger *zap.Logger) (*tls.Config, error) {
+	if !opts.Enabled() {
+		return nil, nil
+	}
+	w, err := NewWatcher(*opts, logger)
+	if err != nil {
+		return nil, err
+	}
+	return w.TLSConfig(), nil
+}
+
+// ListenAndServe listens on the server address and serves HTTP, or HTTPS if tlsConfig is not nil
+func ListenAndServe(server *http.Server, tlsConfig *tls.Config) error {
+	if tlsConfig == nil {
+		return server.ListenAndServe()
+	}
+	l, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return err
+	}
+	return server.Serve(tls.NewListener(l, tlsConfig))
+}
@@ -0,0 +1,186 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tlscfg
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var errMissingCertOrKey = errors.New("both the TLS certificate and key must be provided")
+
+// Watcher loads the TLS certificates of a server and reloads them when the files change on disk
+type Watcher struct {
+	opts   Options
+	logger *zap.Logger
+
+	sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  map[string]time.Time
+
+	stopCh chan struct{}
+	stopWG sync.WaitGroup
+}
+
+// NewWatcher loads the certificates described by the options and starts checking them for changes
+func NewWatcher(opts Options, logger *zap.Logger) (*Watcher, error) {
+	if opts.CertPath == "" || opts.KeyPath == "" {
+		return nil, errMissingCertOrKey
+	}
+	w := &Watcher{
+		opts:     opts,
+		logger:   logger,
+		modTimes: make(map[string]time.Time),
+		stopCh:   make(chan struct{}),
+	}
+	if err := w.load(); err != nil {
+		return nil, err
+	}
+	if opts.ReloadInterval > 0 {
+		w.stopWG.Add(1)
+		go w.watch()
+	}
+	return w, nil
+}
+
+// TLSConfig returns a server TLS configuration that always uses the latest loaded certificates
+func (w *Watcher) TLSConfig() *tls.Config {
+	config := w.newConfig()
+	if w.opts.ClientCAPath != "" {
+		// the client CA pool cannot be swapped in place, so every handshake gets a fresh configuration
+		config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return w.newConfig(), nil
+		}
+	}
+	return config
+}
+
+// Close stops checking the files for changes
+func (w *Watcher) Close() {
+	close(w.stopCh)
+	w.stopWG.Wait()
+}
+
+func (w *Watcher) newConfig() *tls.Config {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			w.RLock()
+			defer w.RUnlock()
+			return w.cert, nil
+		},
+	}
+	if w.opts.ClientCAPath != "" {
+		w.RLock()
+		config.ClientCAs = w.clientCAs
+		w.RUnlock()
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config
+}
+
+func (w *Watcher) watch() {
+	defer w.stopWG.Done()
+	ticker := time.NewTicker(w.opts.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if !w.changed() {
+				continue
+			}
+			if err := w.load(); err != nil {
+				w.logger.Error("Failed to reload TLS certificates, keeping the previous ones", zap.Error(err))
+			} else {
+				w.logger.Info("Reloaded TLS certificates", zap.String("cert", w.opts.CertPath))
+			}
+		case <-w.stopCh:
+			return
+		}
+	}
+}
+
+// changed returns true if any of the files was modified since it was last loaded
+func (w *Watcher) changed() bool {
+	w.RLock()
+	defer w.RUnlock()
+	for _, path := range w.paths() {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if !info.ModTime().Equal(w.modTimes[path]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Watcher) load() error {
+	modTimes := make(map[string]time.Time)
+	for _, path := range w.paths() {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		modTimes[path] = info.ModTime()
+	}
+	cert, err := tls.LoadX509KeyPair(w.opts.CertPath, w.opts.KeyPath)
+	if err != nil {
+		return err
+	}
+	var clientCAs *x509.CertPool
+	if w.opts.ClientCAPath != "" {
+		pem, err := ioutil.ReadFile(w.opts.ClientCAPath)
+		if err != nil {
+			return err
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no CA certificates found in %s", w.opts.ClientCAPath)
+		}
+	}
+
+	w.Lock()
+	defer w.Unlock()
+	w.cert = &cert
+	w.clientCAs = clientCAs
+	w.modTimes = modTimes
+	return nil
+}
+
+func (w *Watcher) paths() []string {
+	paths := []string{w.opts.CertPath, w.opts.KeyPath}
+	if w.opts.ClientCAPath != "" {
+		paths = append(paths, w.opts.ClientCAPath)
+	}
+	return paths
+}
@@ -0,0 +1,242 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tlscfg
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/pkg/config"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCert(t *testing.T, cn string, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signerCert, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+	} else {
+		signerCert, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCert{cert: cert, key: key}
+}
+
+func (c *testCert) write(t *testing.T, certPath, keyPath string) {
+	require.NoError(t, ioutil.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0600))
+	if keyPath == "" {
+		return
+	}
+	der, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600))
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key}
+}
+
+type testFiles struct {
+	dir      string
+	ca       *testCert
+	certPath string
+	keyPath  string
+	caPath   string
+}
+
+func newTestFiles(t *testing.T) *testFiles {
+	dir, err := ioutil.TempDir("", "tlscfg")
+	require.NoError(t, err)
+	f := &testFiles{
+		dir:      dir,
+		ca:       newTestCert(t, "ca", nil),
+		certPath: filepath.Join(dir, "server.crt"),
+		keyPath:  filepath.Join(dir, "server.key"),
+		caPath:   filepath.Join(dir, "ca.crt"),
+	}
+	f.ca.write(t, f.caPath, "")
+	newTestCert(t, "server", f.ca).write(t, f.certPath, f.keyPath)
+	return f
+}
+
+func serve(t *testing.T, tlsConfig *tls.Config) (string, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.VerifiedChains) > 0 {
+			w.Write([]byte(r.TLS.VerifiedChains[0][0].Subject.CommonName))
+		}
+	})}
+	go server.Serve(tls.NewListener(l, tlsConfig))
+	return "https://" + l.Addr().String(), func() { server.Close() }
+}
+
+func get(url string, ca *testCert, clientCert *testCert) (*http.Response, error) {
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(ca.cert)
+	clientConfig := &tls.Config{RootCAs: rootCAs}
+	if clientCert != nil {
+		clientConfig.Certificates = []tls.Certificate{clientCert.tlsCertificate()}
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig}}
+	return client.Get(url)
+}
+
+func TestOptionsFromFlags(t *testing.T) {
+	opts := NewOptions("query")
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags([]string{
+		"--query.tls.cert=cert.pem",
+		"--query.tls.key=key.pem",
+		"--query.tls.client-ca=ca.pem",
+		"--query.tls.reload-interval=1m",
+	})
+	opts.InitFromViper(v)
+	assert.Equal(t, "cert.pem", opts.CertPath)
+	assert.Equal(t, "key.pem", opts.KeyPath)
+	assert.Equal(t, "ca.pem", opts.ClientCAPath)
+	assert.Equal(t, time.Minute, opts.ReloadInterval)
+	assert.True(t, opts.Enabled())
+	assert.False(t, NewOptions("query").Enabled())
+}
+
+func TestNewServerConfigDisabled(t *testing.T) {
+	tlsConfig, err := NewServerConfig(NewOptions("query"), zap.NewNop())
+	assert.NoError(t, err)
+	assert.Nil(t, tlsConfig)
+}
+
+func TestNewWatcherErrors(t *testing.T) {
+	f := newTestFiles(t)
+	defer os.RemoveAll(f.dir)
+
+	_, err := NewWatcher(Options{CertPath: f.certPath}, zap.NewNop())
+	assert.Equal(t, errMissingCertOrKey, err)
+
+	_, err = NewWatcher(Options{CertPath: f.certPath, KeyPath: filepath.Join(f.dir, "missing")}, zap.NewNop())
+	assert.Error(t, err)
+
+	_, err = NewWatcher(Options{CertPath: f.certPath, KeyPath: f.keyPath, ClientCAPath: f.keyPath}, zap.NewNop())
+	assert.Error(t, err)
+}
+
+func TestServerTLS(t *testing.T) {
+	f := newTestFiles(t)
+	defer os.RemoveAll(f.dir)
+
+	tlsConfig, err := NewServerConfig(&Options{CertPath: f.certPath, KeyPath: f.keyPath}, zap.NewNop())
+	require.NoError(t, err)
+	url, closeServer := serve(t, tlsConfig)
+	defer closeServer()
+
+	resp, err := get(url, f.ca, nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestServerMutualTLS(t *testing.T) {
+	f := newTestFiles(t)
+	defer os.RemoveAll(f.dir)
+
+	w, err := NewWatcher(Options{CertPath: f.certPath, KeyPath: f.keyPath, ClientCAPath: f.caPath}, zap.NewNop())
+	require.NoError(t, err)
+	defer w.Close()
+	url, closeServer := serve(t, w.TLSConfig())
+	defer closeServer()
+
+	_, err = get(url, f.ca, nil)
+	assert.Error(t, err, "client certificate is required")
+
+	_, err = get(url, f.ca, newTestCert(t, "rogue", newTestCert(t, "other-ca", nil)))
+	assert.Error(t, err, "client certificate must be signed by the client CA")
+
+	resp, err := get(url, f.ca, newTestCert(t, "client-a", f.ca))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "client-a", string(body))
+}
+
+func TestWatcherReloadsChangedCertificate(t *testing.T) {
+	f := newTestFiles(t)
+	defer os.RemoveAll(f.dir)
+
+	w, err := NewWatcher(Options{CertPath: f.certPath, KeyPath: f.keyPath, ReloadInterval: time.Millisecond}, zap.NewNop())
+	require.NoError(t, err)
+	defer w.Close()
+	url, closeServer := serve(t, w.TLSConfig())
+	defer closeServer()
+
+	serverCN := func() string {
+		resp, err := get(url, f.ca, nil)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.TLS.PeerCertificates[0].Subject.CommonName
+	}
+	assert.Equal(t, "server", serverCN())
+
+	newTestCert(t, "renewed-server", f.ca).write(t, f.certPath, f.keyPath)
+	// make sure the modification time differs even on file systems with a coarse resolution
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(f.certPath, future, future))
+	for i := 0; i < 1000 && serverCN() != "renewed-server"; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, "renewed-server", serverCN())
+}
@@ -0,0 +1,138 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// ARecord resolves host:port names to the IPv4 and IPv6 addresses of the host, keeping the port
+	ARecord = "A"
+	// SRVRecord resolves names to the targets and ports of their SRV records
+	SRVRecord = "SRV"
+
+	dnsLookupTimeout = 5 * time.Second
+)
+
+// Resolver is the part of net.Resolver used by the DNSDiscoverer
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// DNSDiscoverer periodically resolves DNS names into instances and notifies the observers
+// when they change. Depending on the record type the names are either host:port, whose
+// A and AAAA records are resolved, or fully qualified SRV names such as _tchannel._tcp.example.com.
+type DNSDiscoverer struct {
+	*refresher
+	resolver   Resolver
+	names      []string
+	recordType string
+}
+
+// NewDNSDiscoverer creates a DNSDiscoverer and starts resolving names every refreshInterval.
+// The resolver defaults to net.DefaultResolver.
+func NewDNSDiscoverer(
+	resolver Resolver,
+	names []string,
+	recordType string,
+	refreshInterval time.Duration,
+	logger *zap.Logger,
+) (*DNSDiscoverer, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no DNS name provided")
+	}
+	switch recordType {
+	case "":
+		recordType = ARecord
+	case ARecord, SRVRecord:
+	default:
+		return nil, fmt.Errorf("unknown DNS record type %s, expected %s or %s", recordType, ARecord, SRVRecord)
+	}
+	if recordType == ARecord {
+		for _, name := range names {
+			if _, _, err := net.SplitHostPort(name); err != nil {
+				return nil, fmt.Errorf("invalid DNS name %s, expected host:port: %v", name, err)
+			}
+		}
+	}
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	d := &DNSDiscoverer{resolver: resolver, names: names, recordType: recordType}
+	d.refresher = newRefresher(d.resolve, refreshInterval, logger)
+	d.start()
+	return d, nil
+}
+
+func (d *DNSDiscoverer) resolve() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dnsLookupTimeout)
+	defer cancel()
+	var instances []string
+	for _, name := range d.names {
+		var resolved []string
+		var err error
+		if d.recordType == SRVRecord {
+			resolved, err = d.resolveSRV(ctx, name)
+		} else {
+			resolved, err = d.resolveHost(ctx, name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, resolved...)
+	}
+	return instances, nil
+}
+
+func (d *DNSDiscoverer) resolveHost(ctx context.Context, name string) ([]string, error) {
+	host, port, _ := net.SplitHostPort(name)
+	addrs, err := d.resolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	instances := make([]string, len(addrs))
+	for i, addr := range addrs {
+		instances[i] = net.JoinHostPort(addr, port)
+	}
+	return instances, nil
+}
+
+func (d *DNSDiscoverer) resolveSRV(ctx context.Context, name string) ([]string, error) {
+	_, records, err := d.resolver.LookupSRV(ctx, "", "", name)
+	if err != nil {
+		return nil, err
+	}
+	instances := make([]string, len(records))
+	for i, record := range records {
+		instances[i] = net.JoinHostPort(strings.TrimSuffix(record.Target, "."), strconv.Itoa(int(record.Port)))
+	}
+	return instances, nil
+}
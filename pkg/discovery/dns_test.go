@@ -0,0 +1,129 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package discovery
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type stubResolver struct {
+	sync.Mutex
+	hosts map[string][]string
+	srv   map[string][]*net.SRV
+	err   error
+}
+
+func (r *stubResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	r.Lock()
+	defer r.Unlock()
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.hosts[host], nil
+}
+
+func (r *stubResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	r.Lock()
+	defer r.Unlock()
+	if r.err != nil {
+		return "", nil, r.err
+	}
+	return name, r.srv[name], nil
+}
+
+func (r *stubResolver) setHosts(host string, addrs []string, err error) {
+	r.Lock()
+	defer r.Unlock()
+	r.hosts[host] = addrs
+	r.err = err
+}
+
+func TestDNSDiscovererA(t *testing.T) {
+	resolver := &stubResolver{hosts: map[string][]string{
+		"collector": {"10.0.0.1", "fd00::1"},
+		"other":     {"10.0.0.2"},
+	}}
+	d, err := NewDNSDiscoverer(resolver, []string{"collector:14267", "other:14268"}, "", time.Millisecond, zap.NewNop())
+	require.NoError(t, err)
+	defer d.Close()
+
+	instances, err := d.Instances()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1:14267", "[fd00::1]:14267", "10.0.0.2:14268"}, instances)
+
+	ch := make(chan []string, 10)
+	d.Register(ch)
+	// lookup errors keep the previous instances
+	resolver.setHosts("collector", nil, errors.New("no such host"))
+	time.Sleep(5 * time.Millisecond)
+	resolver.setHosts("collector", []string{"10.0.0.3"}, nil)
+	select {
+	case instances := <-ch:
+		assert.Equal(t, []string{"10.0.0.3:14267", "10.0.0.2:14268"}, instances)
+	case <-time.After(time.Second):
+		t.Fatal("no notification received")
+	}
+	d.Unregister(ch)
+}
+
+func TestDNSDiscovererSRV(t *testing.T) {
+	resolver := &stubResolver{srv: map[string][]*net.SRV{
+		"_tchannel._tcp.example.com": {
+			{Target: "collector-1.example.com.", Port: 14267},
+			{Target: "collector-2.example.com.", Port: 14268},
+		},
+	}}
+	d, err := NewDNSDiscoverer(resolver, []string{"_tchannel._tcp.example.com"}, SRVRecord, time.Hour, zap.NewNop())
+	require.NoError(t, err)
+	defer d.Close()
+
+	instances, err := d.Instances()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"collector-1.example.com:14267", "collector-2.example.com:14268"}, instances)
+}
+
+func TestDNSDiscovererErrors(t *testing.T) {
+	_, err := NewDNSDiscoverer(nil, nil, "", time.Hour, zap.NewNop())
+	assert.EqualError(t, err, "no DNS name provided")
+
+	_, err = NewDNSDiscoverer(nil, []string{"collector:14267"}, "MX", time.Hour, zap.NewNop())
+	assert.EqualError(t, err, "unknown DNS record type MX, expected A or SRV")
+
+	_, err = NewDNSDiscoverer(nil, []string{"collector"}, ARecord, time.Hour, zap.NewNop())
+	assert.Error(t, err)
+
+	resolver := &stubResolver{err: errors.New("no such host")}
+	d, err := NewDNSDiscoverer(resolver, []string{"collector:14267"}, ARecord, time.Hour, zap.NewNop())
+	require.NoError(t, err)
+	defer d.Close()
+	_, err = d.Instances()
+	assert.EqualError(t, err, "no such host")
+}
@@ -0,0 +1,73 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package discovery
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// FileDiscoverer periodically reads a list of host:port instances from a file and notifies the
+// observers when it changes. Instances are separated by new lines or commas, empty lines and
+// lines starting with # are ignored.
+type FileDiscoverer struct {
+	*refresher
+	path string
+}
+
+// NewFileDiscoverer creates a FileDiscoverer and starts reading path every refreshInterval
+func NewFileDiscoverer(path string, refreshInterval time.Duration, logger *zap.Logger) *FileDiscoverer {
+	d := &FileDiscoverer{path: path}
+	d.refresher = newRefresher(d.read, refreshInterval, logger)
+	d.start()
+	return d
+}
+
+func (d *FileDiscoverer) read() ([]string, error) {
+	data, err := ioutil.ReadFile(d.path)
+	if err != nil {
+		return nil, err
+	}
+	var instances []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, instance := range strings.Split(line, ",") {
+			instance = strings.TrimSpace(instance)
+			if instance == "" {
+				continue
+			}
+			if _, _, err := net.SplitHostPort(instance); err != nil {
+				return nil, fmt.Errorf("invalid instance %s in %s: %v", instance, d.path, err)
+			}
+			instances = append(instances, instance)
+		}
+	}
+	return instances, nil
+}
@@ -0,0 +1,78 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package discovery
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestFileDiscoverer(t *testing.T) {
+	file, err := ioutil.TempFile("", "collectors")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+	_, err = file.WriteString("# collectors\n10.0.0.1:14267, 10.0.0.2:14267\n\ncollector:14267\n")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	d := NewFileDiscoverer(file.Name(), time.Millisecond, zap.NewNop())
+	defer d.Close()
+	instances, err := d.Instances()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1:14267", "10.0.0.2:14267", "collector:14267"}, instances)
+
+	ch := make(chan []string, 10)
+	d.Register(ch)
+	require.NoError(t, ioutil.WriteFile(file.Name(), []byte("10.0.0.3:14267\n"), 0644))
+	select {
+	case instances := <-ch:
+		assert.Equal(t, []string{"10.0.0.3:14267"}, instances)
+	case <-time.After(time.Second):
+		t.Fatal("no notification received")
+	}
+	d.Unregister(ch)
+}
+
+func TestFileDiscovererErrors(t *testing.T) {
+	d := NewFileDiscoverer("/does/not/exist", time.Hour, zap.NewNop())
+	defer d.Close()
+	_, err := d.Instances()
+	assert.Error(t, err)
+
+	file, err := ioutil.TempFile("", "collectors")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+	_, err = file.WriteString("collector\n")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	d = NewFileDiscoverer(file.Name(), time.Hour, zap.NewNop())
+	defer d.Close()
+	_, err = d.Instances()
+	assert.EqualError(t, err, "invalid instance collector in "+file.Name()+": address collector: missing port in address")
+}
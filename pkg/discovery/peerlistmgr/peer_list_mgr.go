@@ -23,6 +23,7 @@ package peerlistmgr
 import (
 	"context"
 	"math/rand"
+	"sort"
 	"sync"
 	"time"
 
@@ -48,6 +49,14 @@ type PeerListManager struct {
 	exitWG  sync.WaitGroup // used to block Stop() until go-routines have exited
 }
 
+// PeerStatus describes the connections to a peer
+type PeerStatus struct {
+	HostPort            string `json:"hostPort"`
+	Connected           bool   `json:"connected"`
+	InboundConnections  int    `json:"inboundConnections"`
+	OutboundConnections int    `json:"outboundConnections"`
+}
+
 // New creates new PeerListManager.
 func New(
 	peerList *tchannel.PeerList,
@@ -88,6 +97,26 @@ func (m *PeerListManager) Stop() {
 	m.exitWG.Wait()
 }
 
+// Peers returns the status of the peers sorted by host:port, a peer is connected if there is at
+// least one outbound connection to it
+func (m *PeerListManager) Peers() []PeerStatus {
+	peers := m.peers.Copy()
+	statuses := make([]PeerStatus, 0, len(peers))
+	for hostPort, peer := range peers {
+		in, out := peer.NumConnections()
+		statuses = append(statuses, PeerStatus{
+			HostPort:            hostPort,
+			Connected:           out > 0,
+			InboundConnections:  in,
+			OutboundConnections: out,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].HostPort < statuses[j].HostPort
+	})
+	return statuses
+}
+
 func (m *PeerListManager) processDiscoveryNotifications() {
 	defer m.exitWG.Done()
 	for instances := range m.discoCh {
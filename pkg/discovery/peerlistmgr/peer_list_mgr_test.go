@@ -139,6 +139,16 @@ func TestPeerListManager_updatePeers(t *testing.T) {
 
 }
 
+func TestPeerListManager_Peers(t *testing.T) {
+	withTestManager(t, func(tm *testManager) {
+		tm.mgr.updatePeers([]string{"0.0.0.2:12345", "0.0.0.1:12345"})
+		assert.Equal(t, []PeerStatus{
+			{HostPort: "0.0.0.1:12345"},
+			{HostPort: "0.0.0.2:12345"},
+		}, tm.mgr.Peers())
+	})
+}
+
 func TestPeerListManager_getMinPeers(t *testing.T) {
 	withTestManager(t, func(tm *testManager) {
 		n1 := tm.mgr.getMinPeers(map[string]*tchannel.Peer{
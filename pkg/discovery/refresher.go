@@ -0,0 +1,121 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package discovery
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultRefreshInterval is the default interval between two lookups of the instances
+const DefaultRefreshInterval = 30 * time.Second
+
+// refresher periodically looks up the instances and notifies the observers when they change
+type refresher struct {
+	Dispatcher
+	lookup   func() ([]string, error)
+	interval time.Duration
+	logger   *zap.Logger
+
+	mux       sync.Mutex
+	instances []string
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func newRefresher(lookup func() ([]string, error), interval time.Duration, logger *zap.Logger) *refresher {
+	if interval <= 0 {
+		interval = DefaultRefreshInterval
+	}
+	return &refresher{
+		lookup:   lookup,
+		interval: interval,
+		logger:   logger,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Instances implements Discoverer by looking the instances up
+func (r *refresher) Instances() ([]string, error) {
+	instances, err := r.lookup()
+	if err != nil {
+		return nil, err
+	}
+	r.update(instances)
+	return instances, nil
+}
+
+// Close stops the periodic lookups
+func (r *refresher) Close() error {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+		<-r.done
+	})
+	return nil
+}
+
+func (r *refresher) start() {
+	go r.refresh()
+}
+
+func (r *refresher) refresh() {
+	defer close(r.done)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			instances, err := r.lookup()
+			if err != nil {
+				// keep the last known instances rather than removing all the peers
+				r.logger.Error("Cannot look up instances", zap.Error(err))
+				continue
+			}
+			if r.update(instances) {
+				r.logger.Info("Instances changed", zap.Strings("instances", instances))
+				r.Notify(instances)
+			}
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// update stores the instances and returns true if they differ from the previous ones
+func (r *refresher) update(instances []string) bool {
+	sorted := append([]string(nil), instances...)
+	sort.Strings(sorted)
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	changed := len(sorted) != len(r.instances)
+	for i := 0; !changed && i < len(sorted); i++ {
+		changed = sorted[i] != r.instances[i]
+	}
+	r.instances = sorted
+	return changed
+}
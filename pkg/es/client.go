@@ -31,8 +31,11 @@ type Client interface {
 	IndexExists(index string) IndicesExistsService
 	CreateIndex(index string) IndicesCreateService
 	Index() IndexService
+	Delete() DeleteService
+	Get() GetService
 	Search(indices ...string) SearchService
 	MultiSearch() MultiSearchService
+	Close() error
 }
 
 // IndicesExistsService is an abstraction for elastic.IndicesExistsService
@@ -52,9 +55,28 @@ type IndexService interface {
 	Type(typ string) IndexService
 	Id(id string) IndexService
 	BodyJson(body interface{}) IndexService
+	OpType(opType string) IndexService
+	Version(version int64) IndexService
 	Do(ctx context.Context) (*elastic.IndexResponse, error)
 }
 
+// DeleteService is an abstraction for elastic.DeleteService
+type DeleteService interface {
+	Index(index string) DeleteService
+	Type(typ string) DeleteService
+	Id(id string) DeleteService
+	Version(version int64) DeleteService
+	Do(ctx context.Context) (*elastic.DeleteResponse, error)
+}
+
+// GetService is an abstraction for elastic.GetService
+type GetService interface {
+	Index(index string) GetService
+	Type(typ string) GetService
+	Id(id string) GetService
+	Do(ctx context.Context) (*elastic.GetResult, error)
+}
+
 // SearchService is an abstraction for elastic.SearchService
 type SearchService interface {
 	Type(typ string) SearchService
@@ -62,6 +84,9 @@ type SearchService interface {
 	Aggregation(name string, aggregation elastic.Aggregation) SearchService
 	IgnoreUnavailable(ignoreUnavailable bool) SearchService
 	Query(query elastic.Query) SearchService
+	Sort(field string, ascending bool) SearchService
+	SearchAfter(sortValues ...interface{}) SearchService
+	FetchSourceContext(fetchSourceContext *elastic.FetchSourceContext) SearchService
 	Do(ctx context.Context) (*elastic.SearchResult, error)
 }
 
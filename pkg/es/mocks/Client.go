@@ -29,6 +29,20 @@ type Client struct {
 	mock.Mock
 }
 
+// Close provides a mock function with given fields:
+func (_m *Client) Close() error {
+	ret := _m.Called()
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // CreateIndex provides a mock function with given fields: index
 func (_m *Client) CreateIndex(index string) es.IndicesCreateService {
 	ret := _m.Called(index)
@@ -45,6 +59,38 @@ func (_m *Client) CreateIndex(index string) es.IndicesCreateService {
 	return r0
 }
 
+// Delete provides a mock function with given fields:
+func (_m *Client) Delete() es.DeleteService {
+	ret := _m.Called()
+
+	var r0 es.DeleteService
+	if rf, ok := ret.Get(0).(func() es.DeleteService); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(es.DeleteService)
+		}
+	}
+
+	return r0
+}
+
+// Get provides a mock function with given fields:
+func (_m *Client) Get() es.GetService {
+	ret := _m.Called()
+
+	var r0 es.GetService
+	if rf, ok := ret.Get(0).(func() es.GetService); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(es.GetService)
+		}
+	}
+
+	return r0
+}
+
 // Index provides a mock function with given fields:
 func (_m *Client) Index() es.IndexService {
 	ret := _m.Called()
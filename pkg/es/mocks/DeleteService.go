@@ -0,0 +1,119 @@
+// Code generated by mockery v1.0.0
+
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package mocks
+
+import context "context"
+import elastic "github.com/olivere/elastic"
+import es "github.com/uber/jaeger/pkg/es"
+import mock "github.com/stretchr/testify/mock"
+
+// DeleteService is an autogenerated mock type for the DeleteService type
+type DeleteService struct {
+	mock.Mock
+}
+
+// Do provides a mock function with given fields: ctx
+func (_m *DeleteService) Do(ctx context.Context) (*elastic.DeleteResponse, error) {
+	ret := _m.Called(ctx)
+
+	var r0 *elastic.DeleteResponse
+	if rf, ok := ret.Get(0).(func(context.Context) *elastic.DeleteResponse); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*elastic.DeleteResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Id provides a mock function with given fields: id
+func (_m *DeleteService) Id(id string) es.DeleteService {
+	ret := _m.Called(id)
+
+	var r0 es.DeleteService
+	if rf, ok := ret.Get(0).(func(string) es.DeleteService); ok {
+		r0 = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(es.DeleteService)
+		}
+	}
+
+	return r0
+}
+
+// Index provides a mock function with given fields: index
+func (_m *DeleteService) Index(index string) es.DeleteService {
+	ret := _m.Called(index)
+
+	var r0 es.DeleteService
+	if rf, ok := ret.Get(0).(func(string) es.DeleteService); ok {
+		r0 = rf(index)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(es.DeleteService)
+		}
+	}
+
+	return r0
+}
+
+// Type provides a mock function with given fields: typ
+func (_m *DeleteService) Type(typ string) es.DeleteService {
+	ret := _m.Called(typ)
+
+	var r0 es.DeleteService
+	if rf, ok := ret.Get(0).(func(string) es.DeleteService); ok {
+		r0 = rf(typ)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(es.DeleteService)
+		}
+	}
+
+	return r0
+}
+
+// Version provides a mock function with given fields: version
+func (_m *DeleteService) Version(version int64) es.DeleteService {
+	ret := _m.Called(version)
+
+	var r0 es.DeleteService
+	if rf, ok := ret.Get(0).(func(int64) es.DeleteService); ok {
+		r0 = rf(version)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(es.DeleteService)
+		}
+	}
+
+	return r0
+}
@@ -102,6 +102,22 @@ func (_m *IndexService) Index(index string) es.IndexService {
 	return r0
 }
 
+// OpType provides a mock function with given fields: opType
+func (_m *IndexService) OpType(opType string) es.IndexService {
+	ret := _m.Called(opType)
+
+	var r0 es.IndexService
+	if rf, ok := ret.Get(0).(func(string) es.IndexService); ok {
+		r0 = rf(opType)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(es.IndexService)
+		}
+	}
+
+	return r0
+}
+
 // Type provides a mock function with given fields: typ
 func (_m *IndexService) Type(typ string) es.IndexService {
 	ret := _m.Called(typ)
@@ -117,3 +133,19 @@ func (_m *IndexService) Type(typ string) es.IndexService {
 
 	return r0
 }
+
+// Version provides a mock function with given fields: version
+func (_m *IndexService) Version(version int64) es.IndexService {
+	ret := _m.Called(version)
+
+	var r0 es.IndexService
+	if rf, ok := ret.Get(0).(func(int64) es.IndexService); ok {
+		r0 = rf(version)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(es.IndexService)
+		}
+	}
+
+	return r0
+}
@@ -70,6 +70,22 @@ func (_m *SearchService) Do(ctx context.Context) (*elastic.SearchResult, error)
 	return r0, r1
 }
 
+// FetchSourceContext provides a mock function with given fields: fetchSourceContext
+func (_m *SearchService) FetchSourceContext(fetchSourceContext *elastic.FetchSourceContext) es.SearchService {
+	ret := _m.Called(fetchSourceContext)
+
+	var r0 es.SearchService
+	if rf, ok := ret.Get(0).(func(*elastic.FetchSourceContext) es.SearchService); ok {
+		r0 = rf(fetchSourceContext)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(es.SearchService)
+		}
+	}
+
+	return r0
+}
+
 // IgnoreUnavailable provides a mock function with given fields: ignoreUnavailable
 func (_m *SearchService) IgnoreUnavailable(ignoreUnavailable bool) es.SearchService {
 	ret := _m.Called(ignoreUnavailable)
@@ -102,6 +118,24 @@ func (_m *SearchService) Query(query elastic.Query) es.SearchService {
 	return r0
 }
 
+// SearchAfter provides a mock function with given fields: sortValues
+func (_m *SearchService) SearchAfter(sortValues ...interface{}) es.SearchService {
+	var _ca []interface{}
+	_ca = append(_ca, sortValues...)
+	ret := _m.Called(_ca...)
+
+	var r0 es.SearchService
+	if rf, ok := ret.Get(0).(func(...interface{}) es.SearchService); ok {
+		r0 = rf(sortValues...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(es.SearchService)
+		}
+	}
+
+	return r0
+}
+
 // Size provides a mock function with given fields: size
 func (_m *SearchService) Size(size int) es.SearchService {
 	ret := _m.Called(size)
@@ -118,6 +152,22 @@ func (_m *SearchService) Size(size int) es.SearchService {
 	return r0
 }
 
+// Sort provides a mock function with given fields: field, ascending
+func (_m *SearchService) Sort(field string, ascending bool) es.SearchService {
+	ret := _m.Called(field, ascending)
+
+	var r0 es.SearchService
+	if rf, ok := ret.Get(0).(func(string, bool) es.SearchService); ok {
+		r0 = rf(field, ascending)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(es.SearchService)
+		}
+	}
+
+	return r0
+}
+
 // Type provides a mock function with given fields: typ
 func (_m *SearchService) Type(typ string) es.SearchService {
 	ret := _m.Called(typ)
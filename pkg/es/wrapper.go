@@ -53,6 +53,16 @@ func (c ESClient) Index() IndexService {
 	return WrapESIndexService(c.client.Index())
 }
 
+// Delete calls this function to internal client.
+func (c ESClient) Delete() DeleteService {
+	return WrapESDeleteService(c.client.Delete())
+}
+
+// Get calls this function to internal client.
+func (c ESClient) Get() GetService {
+	return WrapESGetService(c.client.Get())
+}
+
 // Search calls this function to internal client.
 func (c ESClient) Search(indices ...string) SearchService {
 	return WrapESSearchService(c.client.Search(indices...))
@@ -63,6 +73,12 @@ func (c ESClient) MultiSearch() MultiSearchService {
 	return WrapESMultiSearchService(c.client.MultiSearch())
 }
 
+// Close stops the background processes of the internal client.
+func (c ESClient) Close() error {
+	c.client.Stop()
+	return nil
+}
+
 // ---
 
 // ESIndicesExistsService is a wrapper around elastic.IndicesExistsService
@@ -134,6 +150,16 @@ func (i ESIndexService) BodyJson(body interface{}) IndexService {
 	return WrapESIndexService(i.indexService.BodyJson(body))
 }
 
+// OpType calls this function to internal service.
+func (i ESIndexService) OpType(opType string) IndexService {
+	return WrapESIndexService(i.indexService.OpType(opType))
+}
+
+// Version calls this function to internal service.
+func (i ESIndexService) Version(version int64) IndexService {
+	return WrapESIndexService(i.indexService.Version(version))
+}
+
 // Do calls this function to internal service.
 func (i ESIndexService) Do(ctx context.Context) (*elastic.IndexResponse, error) {
 	return i.indexService.Do(ctx)
@@ -141,6 +167,75 @@ func (i ESIndexService) Do(ctx context.Context) (*elastic.IndexResponse, error)
 
 // ---
 
+// ESDeleteService is a wrapper around elastic.DeleteService
+type ESDeleteService struct {
+	deleteService *elastic.DeleteService
+}
+
+// WrapESDeleteService creates an ESDeleteService out of *elastic.DeleteService.
+func WrapESDeleteService(deleteService *elastic.DeleteService) ESDeleteService {
+	return ESDeleteService{deleteService: deleteService}
+}
+
+// Index calls this function to internal service.
+func (d ESDeleteService) Index(index string) DeleteService {
+	return WrapESDeleteService(d.deleteService.Index(index))
+}
+
+// Type calls this function to internal service.
+func (d ESDeleteService) Type(typ string) DeleteService {
+	return WrapESDeleteService(d.deleteService.Type(typ))
+}
+
+// Id calls this function to internal service.
+func (d ESDeleteService) Id(id string) DeleteService {
+	return WrapESDeleteService(d.deleteService.Id(id))
+}
+
+// Version calls this function to internal service.
+func (d ESDeleteService) Version(version int64) DeleteService {
+	return WrapESDeleteService(d.deleteService.Version(version))
+}
+
+// Do calls this function to internal service.
+func (d ESDeleteService) Do(ctx context.Context) (*elastic.DeleteResponse, error) {
+	return d.deleteService.Do(ctx)
+}
+
+// ---
+
+// ESGetService is a wrapper around elastic.GetService
+type ESGetService struct {
+	getService *elastic.GetService
+}
+
+// WrapESGetService creates an ESGetService out of *elastic.GetService.
+func WrapESGetService(getService *elastic.GetService) ESGetService {
+	return ESGetService{getService: getService}
+}
+
+// Index calls this function to internal service.
+func (g ESGetService) Index(index string) GetService {
+	return WrapESGetService(g.getService.Index(index))
+}
+
+// Type calls this function to internal service.
+func (g ESGetService) Type(typ string) GetService {
+	return WrapESGetService(g.getService.Type(typ))
+}
+
+// Id calls this function to internal service.
+func (g ESGetService) Id(id string) GetService {
+	return WrapESGetService(g.getService.Id(id))
+}
+
+// Do calls this function to internal service.
+func (g ESGetService) Do(ctx context.Context) (*elastic.GetResult, error) {
+	return g.getService.Do(ctx)
+}
+
+// ---
+
 // ESSearchService is a wrapper around elastic.ESSearchService
 type ESSearchService struct {
 	searchService *elastic.SearchService
@@ -176,6 +271,21 @@ func (s ESSearchService) Query(query elastic.Query) SearchService {
 	return WrapESSearchService(s.searchService.Query(query))
 }
 
+// Sort calls this function to internal service.
+func (s ESSearchService) Sort(field string, ascending bool) SearchService {
+	return WrapESSearchService(s.searchService.Sort(field, ascending))
+}
+
+// SearchAfter calls this function to internal service.
+func (s ESSearchService) SearchAfter(sortValues ...interface{}) SearchService {
+	return WrapESSearchService(s.searchService.SearchAfter(sortValues...))
+}
+
+// FetchSourceContext calls this function to internal service.
+func (s ESSearchService) FetchSourceContext(fetchSourceContext *elastic.FetchSourceContext) SearchService {
+	return WrapESSearchService(s.searchService.FetchSourceContext(fetchSourceContext))
+}
+
 // Do calls this function to internal service.
 func (s ESSearchService) Do(ctx context.Context) (*elastic.SearchResult, error) {
 	return s.searchService.Do(ctx)
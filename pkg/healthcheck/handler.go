@@ -21,6 +21,7 @@
 package healthcheck
 
 import (
+	"crypto/tls"
 	"net"
 	"net/http"
 	"strconv"
@@ -37,6 +38,12 @@ type State struct {
 
 // Serve requests on the specified port. The initial state is what's specified with the state parameter
 func Serve(state int, port int, logger *zap.Logger) (*State, error) {
+	return ServeTLS(state, port, nil, logger)
+}
+
+// ServeTLS serves requests on the specified port over TLS, or over plain HTTP if tlsConfig is nil.
+// The initial state is what's specified with the state parameter
+func ServeTLS(state int, port int, tlsConfig *tls.Config, logger *zap.Logger) (*State, error) {
 	hs, err := NewState(state, logger)
 	handler, err := NewHandler(hs)
 
@@ -47,6 +54,9 @@ func Serve(state int, port int, logger *zap.Logger) (*State, error) {
 		logger.Error("failed to listen", zap.Error(err))
 		return nil, err
 	}
+	if tlsConfig != nil {
+		l = tls.NewListener(l, tlsConfig)
+	}
 	ServeWithListener(l, s, logger)
 
 	hs.logger.Info("Health Check server started", zap.Int("http-port", port))
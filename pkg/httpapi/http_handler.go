@@ -18,9 +18,12 @@
 // OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
 // THE SOFTWARE.
 
-package app
+package httpapi
 
 import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -40,7 +43,13 @@ const (
 	UnableToReadBodyErrFormat = "Unable to process request body: %v"
 )
 
-// APIHandler handles all HTTP calls to the collector
+// JaegerBatchesHandler consumes the Jaeger batches received by the API, e.g. the collector span handler
+type JaegerBatchesHandler interface {
+	// SubmitBatches records a batch of spans in Jaeger Thrift format
+	SubmitBatches(ctx tchanThrift.Context, batches []*tJaeger.Batch) ([]*tJaeger.BatchSubmitResponse, error)
+}
+
+// APIHandler handles the HTTP calls submitting Jaeger batches
 type APIHandler struct {
 	jaegerBatchesHandler JaegerBatchesHandler
 }
@@ -60,35 +69,51 @@ func (aH *APIHandler) RegisterRoutes(router *mux.Router) {
 }
 
 func (aH *APIHandler) saveSpan(w http.ResponseWriter, r *http.Request) {
-	bodyBytes, err := ioutil.ReadAll(r.Body)
-	r.Body.Close()
+	bRead := r.Body
+	defer r.Body.Close()
+
+	if strings.Contains(r.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(UnableToReadBodyErrFormat, err), http.StatusBadRequest)
+			return
+		}
+		defer gz.Close()
+		bRead = gz
+	}
+
+	bodyBytes, err := ioutil.ReadAll(bRead)
 	if err != nil {
 		http.Error(w, fmt.Sprintf(UnableToReadBodyErrFormat, err), http.StatusInternalServerError)
 		return
 	}
 
 	format := r.FormValue(formatParam)
+	// (NB): We decided to use this struct instead of straight batches to be as consistent with tchannel intake as possible.
+	batch := &tJaeger.Batch{}
 	switch strings.ToLower(format) {
 	case "jaeger.thrift":
 		tdes := thrift.NewTDeserializer()
-		// (NB): We decided to use this struct instead of straight batches to be as consistent with tchannel intake as possible.
-		batch := &tJaeger.Batch{}
-		if err = tdes.Read(batch, bodyBytes); err != nil {
-			http.Error(w, fmt.Sprintf(UnableToReadBodyErrFormat, err), http.StatusBadRequest)
-			return
-		}
-		ctx, cancel := tchanThrift.NewContext(time.Minute)
-		defer cancel()
-		batches := []*tJaeger.Batch{batch}
-		if _, err = aH.jaegerBatchesHandler.SubmitBatches(ctx, batches); err != nil {
-			http.Error(w, fmt.Sprintf("Cannot submit Jaeger batch: %v", err), http.StatusInternalServerError)
-			return
-		}
-
+		err = tdes.Read(batch, bodyBytes)
+	case "jaeger.json":
+		err = json.Unmarshal(bodyBytes, batch)
 	default:
 		http.Error(w, fmt.Sprintf("Unsupported format type: %v", format), http.StatusBadRequest)
 		return
 	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf(UnableToReadBodyErrFormat, err), http.StatusBadRequest)
+		return
+	}
+
+	// the request context carries the principal authenticated by the client certificate
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+	batches := []*tJaeger.Batch{batch}
+	if _, err = aH.jaegerBatchesHandler.SubmitBatches(tchanThrift.Wrap(ctx), batches); err != nil {
+		http.Error(w, fmt.Sprintf("Cannot submit Jaeger batch: %v", err), http.StatusInternalServerError)
+		return
+	}
 
 	w.WriteHeader(http.StatusAccepted)
 }
@@ -18,10 +18,11 @@
 // OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
 // THE SOFTWARE.
 
-package app
+package httpapi
 
 import (
 	"bytes"
+	"compress/gzip"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -96,6 +97,62 @@ func TestThriftFormat(t *testing.T) {
 	assert.EqualValues(t, "Cannot submit Jaeger batch: Bad times ahead\n", resBodyStr)
 }
 
+func TestJSONFormat(t *testing.T) {
+	server, handler := initializeTestServer(nil)
+	defer server.Close()
+
+	body := []byte(`{"process": {"serviceName": "serviceName"}, "spans": [{"traceIdLow": 1, "spanId": 2, "operationName": "opName", "tags": [{"key": "k", "vType": "STRING", "vStr": "v"}]}]}`)
+	statusCode, resBodyStr, err := postBytes(server.URL+`/api/traces?format=jaeger.json`, body)
+	assert.NoError(t, err)
+	assert.EqualValues(t, http.StatusAccepted, statusCode)
+	assert.EqualValues(t, "", resBodyStr)
+	batches := handler.jaegerBatchesHandler.(*mockJaegerHandler).getBatches()
+	if assert.Len(t, batches, 1) {
+		assert.Equal(t, "serviceName", batches[0].Process.ServiceName)
+		assert.Equal(t, "opName", batches[0].Spans[0].OperationName)
+		assert.Equal(t, jaeger.TagType_STRING, batches[0].Spans[0].Tags[0].VType)
+	}
+
+	statusCode, _, err = postBytes(server.URL+`/api/traces?format=jaeger.json`, []byte("not json"))
+	assert.NoError(t, err)
+	assert.EqualValues(t, http.StatusBadRequest, statusCode)
+}
+
+func TestGzipEncoding(t *testing.T) {
+	batch := jaeger.Batch{
+		Process: &jaeger.Process{ServiceName: "serviceName"},
+		Spans:   []*jaeger.Span{{OperationName: "opName"}},
+	}
+	someBytes, err := thrift.NewTSerializer().Write(&batch)
+	assert.NoError(t, err)
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	gz.Write(someBytes)
+	gz.Close()
+	server, handler := initializeTestServer(nil)
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+`/api/traces?format=jaeger.thrift`, &gzipped)
+	assert.NoError(t, err)
+	req.Header.Set("Content-Encoding", "gzip")
+	res, err := httpClient.Do(req)
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.EqualValues(t, http.StatusAccepted, res.StatusCode)
+	batches := handler.jaegerBatchesHandler.(*mockJaegerHandler).getBatches()
+	if assert.Len(t, batches, 1) {
+		assert.Equal(t, "opName", batches[0].Spans[0].OperationName)
+	}
+
+	req, err = http.NewRequest(http.MethodPost, server.URL+`/api/traces?format=jaeger.thrift`, bytes.NewBufferString("not good"))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Encoding", "gzip")
+	res, err = httpClient.Do(req)
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.EqualValues(t, http.StatusBadRequest, res.StatusCode)
+}
+
 func TestViaClient(t *testing.T) {
 	server, handler := initializeTestServer(nil)
 	defer server.Close()
@@ -22,6 +22,7 @@ package zipkin
 
 import (
 	"compress/gzip"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -32,18 +33,24 @@ import (
 	"github.com/gorilla/mux"
 	tchanThrift "github.com/uber/tchannel-go/thrift"
 
-	"github.com/uber/jaeger/cmd/collector/app"
+	"github.com/uber/jaeger/pkg/httpapi"
 	"github.com/uber/jaeger/thrift-gen/zipkincore"
 )
 
-// APIHandler handles all HTTP calls to the collector
+// SpansHandler consumes the Zipkin spans received by the API, e.g. the collector span handler
+type SpansHandler interface {
+	// SubmitZipkinBatch records a batch of spans in Zipkin Thrift format
+	SubmitZipkinBatch(ctx tchanThrift.Context, spans []*zipkincore.Span) ([]*zipkincore.Response, error)
+}
+
+// APIHandler handles the HTTP calls of the Zipkin API
 type APIHandler struct {
-	zipkinSpansHandler app.ZipkinSpansHandler
+	zipkinSpansHandler SpansHandler
 }
 
 // NewAPIHandler returns a new APIHandler
 func NewAPIHandler(
-	zipkinSpansHandler app.ZipkinSpansHandler,
+	zipkinSpansHandler SpansHandler,
 ) *APIHandler {
 	return &APIHandler{
 		zipkinSpansHandler: zipkinSpansHandler,
@@ -62,7 +69,7 @@ func (aH *APIHandler) saveSpans(w http.ResponseWriter, r *http.Request) {
 	if strings.Contains(r.Header.Get("Content-Encoding"), "gzip") {
 		gz, err := gzip.NewReader(r.Body)
 		if err != nil {
-			http.Error(w, fmt.Sprintf(app.UnableToReadBodyErrFormat, err), http.StatusBadRequest)
+			http.Error(w, fmt.Sprintf(httpapi.UnableToReadBodyErrFormat, err), http.StatusBadRequest)
 			return
 		}
 		defer gz.Close()
@@ -71,7 +78,7 @@ func (aH *APIHandler) saveSpans(w http.ResponseWriter, r *http.Request) {
 
 	bodyBytes, err := ioutil.ReadAll(bRead)
 	if err != nil {
-		http.Error(w, fmt.Sprintf(app.UnableToReadBodyErrFormat, err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf(httpapi.UnableToReadBodyErrFormat, err), http.StatusInternalServerError)
 		return
 	}
 
@@ -86,13 +93,15 @@ func (aH *APIHandler) saveSpans(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if err != nil {
-		http.Error(w, fmt.Sprintf(app.UnableToReadBodyErrFormat, err), http.StatusBadRequest)
+		http.Error(w, fmt.Sprintf(httpapi.UnableToReadBodyErrFormat, err), http.StatusBadRequest)
 		return
 	}
 
 	if len(tSpans) > 0 {
-		ctx, _ := tchanThrift.NewContext(time.Minute)
-		if _, err = aH.zipkinSpansHandler.SubmitZipkinBatch(ctx, tSpans); err != nil {
+		// the request context carries the principal authenticated by the client certificate
+		ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+		defer cancel()
+		if _, err = aH.zipkinSpansHandler.SubmitZipkinBatch(tchanThrift.Wrap(ctx), tSpans); err != nil {
 			http.Error(w, fmt.Sprintf("Cannot submit Zipkin batch: %v", err), http.StatusInternalServerError)
 			return
 		}
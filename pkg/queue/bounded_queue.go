@@ -28,6 +28,9 @@ import (
 	"github.com/uber/jaeger-lib/metrics"
 )
 
+// drainCheckInterval is how often Drain checks whether the queue is empty
+const drainCheckInterval = 10 * time.Millisecond
+
 // BoundedQueue implements a producer-consumer exchange similar to a ring buffer queue,
 // where the queue is bounded and if it fills up due to slow consumers, the new items written by
 // the producer force the earliest items to be dropped. The implementation is actually based on
@@ -36,6 +39,7 @@ import (
 type BoundedQueue struct {
 	capacity      int
 	size          int32
+	inFlight      int32 // items taken from the queue and still being consumed
 	onDroppedItem func(item interface{})
 	items         chan interface{}
 	stopCh        chan struct{}
@@ -67,8 +71,10 @@ func (q *BoundedQueue) StartConsumers(num int, consumer func(item interface{}))
 			for {
 				select {
 				case item := <-q.items:
+					atomic.AddInt32(&q.inFlight, 1)
 					atomic.AddInt32(&q.size, -1)
 					consumer(item)
+					atomic.AddInt32(&q.inFlight, -1)
 				case <-q.stopCh:
 					return
 				}
@@ -81,7 +87,9 @@ func (q *BoundedQueue) StartConsumers(num int, consumer func(item interface{}))
 // Produce is used by the producer to submit new item to the queue. Returns false in case of queue overflow.
 func (q *BoundedQueue) Produce(item interface{}) bool {
 	if atomic.LoadInt32(&q.stopped) != 0 {
-		q.onDroppedItem(item)
+		if q.onDroppedItem != nil {
+			q.onDroppedItem(item)
+		}
 		return false
 	}
 	select {
@@ -105,6 +113,41 @@ func (q *BoundedQueue) Stop() {
 	close(q.items)
 }
 
+// Drain stops accepting new items, waits for the consumers to empty the queue and then stops the queue
+// just like Stop, but it never waits longer than the given timeout. At the deadline, it returns the number
+// of items left in the queue or still being processed, without waiting for the consumers.
+func (q *BoundedQueue) Drain(timeout time.Duration) int {
+	atomic.StoreInt32(&q.stopped, 1) // disable producer
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(drainCheckInterval)
+	defer ticker.Stop()
+
+	for q.Size() > 0 {
+		select {
+		case <-ticker.C:
+		case <-deadline.C:
+			go q.Stop()
+			return q.pending()
+		}
+	}
+	stopped := make(chan struct{})
+	go func() {
+		q.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-deadline.C:
+	}
+	return q.pending()
+}
+
+// pending returns the number of items in the queue and in the consumers
+func (q *BoundedQueue) pending() int {
+	return int(atomic.LoadInt32(&q.inFlight) + atomic.LoadInt32(&q.size))
+}
+
 // Size returns the current size of the queue
 func (q *BoundedQueue) Size() int {
 	return int(atomic.LoadInt32(&q.size))
@@ -112,6 +112,84 @@ func TestBoundedQueue(t *testing.T) {
 	assert.False(t, q.Produce("x"), "cannot push to closed queue")
 }
 
+func TestBoundedQueueDrain(t *testing.T) {
+	q := NewBoundedQueue(10, nil)
+
+	var startLock sync.Mutex
+	startLock.Lock() // block consumers
+	consumerState := newConsumerState(t)
+	q.StartConsumers(1, func(item interface{}) {
+		consumerState.record(item.(string))
+		startLock.Lock()
+		startLock.Unlock()
+	})
+
+	for _, item := range []string{"a", "b", "c"} {
+		require.True(t, q.Produce(item))
+	}
+	consumerState.waitToConsumeOnce()
+
+	// the consumer is blocked on "a", so the remaining items cannot be drained in time
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		startLock.Unlock()
+	}()
+	remaining := q.Drain(time.Millisecond)
+	assert.Equal(t, 3, remaining, "a is still being consumed and b, c are left in the queue")
+	assert.False(t, q.Produce("x"), "cannot push to drained queue")
+}
+
+func TestBoundedQueueDrainEmptiesQueue(t *testing.T) {
+	q := NewBoundedQueue(10, nil)
+
+	var startLock sync.Mutex
+	startLock.Lock() // block consumers
+	consumerState := newConsumerState(t)
+	q.StartConsumers(1, func(item interface{}) {
+		consumerState.record(item.(string))
+		startLock.Lock()
+		startLock.Unlock()
+	})
+
+	for _, item := range []string{"a", "b", "c"} {
+		require.True(t, q.Produce(item))
+	}
+	consumerState.waitToConsumeOnce()
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		startLock.Unlock()
+	}()
+	assert.Equal(t, 0, q.Drain(time.Second))
+	consumerState.assertConsumed(map[string]bool{
+		"a": true,
+		"b": true,
+		"c": true,
+	})
+}
+
+func TestBoundedQueueDrainDoesNotWaitPastDeadline(t *testing.T) {
+	q := NewBoundedQueue(10, nil)
+
+	var startLock sync.Mutex
+	startLock.Lock() // block consumers
+	defer startLock.Unlock()
+	consumerState := newConsumerState(t)
+	q.StartConsumers(1, func(item interface{}) {
+		consumerState.record(item.(string))
+		startLock.Lock()
+		startLock.Unlock()
+	})
+
+	require.True(t, q.Produce("a"))
+	consumerState.waitToConsumeOnce()
+
+	// the queue is empty but the consumer is still blocked on "a"
+	start := time.Now()
+	assert.Equal(t, 1, q.Drain(10*time.Millisecond), "the item being consumed is not processed yet")
+	assert.True(t, time.Since(start) < time.Second, "Drain must return at the deadline")
+}
+
 type consumerState struct {
 	sync.Mutex
 	t            *testing.T
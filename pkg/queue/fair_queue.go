@@ -0,0 +1,392 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package queue
+
+import (
+	"sync"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+)
+
+const (
+	// PriorityClass is the class under which the length of the priority lane is reported
+	PriorityClass = "priority"
+	// OverflowClass is shared by the items of all the classes beyond FairQueueConfig.MaxClasses
+	OverflowClass = "other"
+	// DefaultMaxClasses is the default bound on the number of distinct classes
+	DefaultMaxClasses = 2000
+)
+
+// Classifier returns the class of an item, and whether the item skips ahead of all the classes
+type Classifier func(item interface{}) (class string, priority bool)
+
+// FairQueueConfig describes how a FairQueue shares its capacity between the item classes
+type FairQueueConfig struct {
+	// Capacity is the maximum number of items held by the queue, across all classes
+	Capacity int
+	// Weights defines how many items of a class are consumed in each round, classes not listed have a weight of 1
+	Weights map[string]int
+	// ReservedShare is the fraction of the capacity reserved to every class. When the queue is full,
+	// an item of a class holding less than its reserved share evicts the newest item of the largest class.
+	ReservedShare float64
+	// MaxClasses bounds the number of distinct classes, defaults to DefaultMaxClasses
+	MaxClasses int
+	// PriorityCapacity is the maximum number of priority items held by the queue, so that they cannot take
+	// the whole capacity, defaults to a tenth of the Capacity
+	PriorityCapacity int
+}
+
+// FairQueue is a bounded producer-consumer queue holding a FIFO sub-queue per class of items.
+// The consumers drain the sub-queues in weighted round-robin order, so that a burst of items of
+// one class does not delay or push out the items of the other classes. Priority items are held
+// in a separate lane that is always consumed first.
+type FairQueue struct {
+	sync.Mutex
+	config         FairQueueConfig
+	reserved       int
+	classify       Classifier
+	onDroppedItem  func(item interface{})
+	metricsFactory metrics.Factory
+	notEmpty       *sync.Cond
+
+	priority        []interface{}
+	priorityGauge   metrics.Gauge
+	priorityDropped metrics.Counter
+	classes         map[string]*classQueue
+	active          []*classQueue // non-empty classes in round-robin order
+	next            int           // index in active of the class being consumed
+	credit          int           // number of items the class being consumed may still take in this round
+	size            int
+	inFlight        int  // items taken from the queue and still being consumed
+	stopped         bool // producer disabled
+	closed          bool // consumers stopped
+
+	stopCh chan struct{}
+	stopWG sync.WaitGroup
+}
+
+type classQueue struct {
+	items  []interface{}
+	weight int
+	active bool
+	gauge  metrics.Gauge
+}
+
+// NewFairQueue constructs a fair queue, with an optional callback for dropped items. The length of
+// every class is reported as the "queue-length" gauge of the metrics factory, tagged with the class,
+// and the priority items dropped over the PriorityCapacity as the "queue-dropped" counter.
+func NewFairQueue(
+	config FairQueueConfig,
+	classify Classifier,
+	onDroppedItem func(item interface{}),
+	metricsFactory metrics.Factory,
+) *FairQueue {
+	if config.MaxClasses <= 0 {
+		config.MaxClasses = DefaultMaxClasses
+	}
+	if config.PriorityCapacity <= 0 {
+		config.PriorityCapacity = config.Capacity / 10
+		if config.PriorityCapacity < 1 {
+			config.PriorityCapacity = 1
+		}
+	}
+	if metricsFactory == nil {
+		metricsFactory = metrics.NullFactory
+	}
+	q := &FairQueue{
+		config:          config,
+		reserved:        int(config.ReservedShare * float64(config.Capacity)),
+		classify:        classify,
+		onDroppedItem:   onDroppedItem,
+		metricsFactory:  metricsFactory,
+		priorityGauge:   metricsFactory.Gauge("queue-length", map[string]string{"class": PriorityClass}),
+		priorityDropped: metricsFactory.Counter("queue-dropped", map[string]string{"class": PriorityClass}),
+		classes:         make(map[string]*classQueue),
+		stopCh:          make(chan struct{}),
+	}
+	q.notEmpty = sync.NewCond(&q.Mutex)
+	return q
+}
+
+// StartConsumers starts a given number of goroutines consuming items from the queue
+// and passing them into the consumer callback.
+func (q *FairQueue) StartConsumers(num int, consumer func(item interface{})) {
+	for i := 0; i < num; i++ {
+		q.stopWG.Add(1)
+		go func() {
+			defer q.stopWG.Done()
+			for {
+				item, ok := q.take()
+				if !ok {
+					return
+				}
+				consumer(item)
+				q.Lock()
+				q.inFlight--
+				q.Unlock()
+			}
+		}()
+	}
+}
+
+// Produce is used by the producer to submit new item to the queue. Returns false if the item was dropped.
+func (q *FairQueue) Produce(item interface{}) bool {
+	class, priority := q.classify(item)
+	q.Lock()
+	accepted, evicted := q.add(item, class, priority)
+	q.Unlock()
+	if accepted {
+		q.notEmpty.Signal()
+	}
+	if q.onDroppedItem != nil {
+		if evicted != nil {
+			q.onDroppedItem(evicted)
+		}
+		if !accepted {
+			q.onDroppedItem(item)
+		}
+	}
+	return accepted
+}
+
+// Stop stops all consumers, as well as the length reporter if started. It blocks until all consumers have stopped.
+func (q *FairQueue) Stop() {
+	q.Lock()
+	q.stopped = true
+	q.closed = true
+	q.Unlock()
+	q.notEmpty.Broadcast()
+	close(q.stopCh)
+	q.stopWG.Wait()
+}
+
+// Drain stops accepting new items, waits for the consumers to empty the queue and then stops the queue
+// just like Stop, but it never waits longer than the given timeout. At the deadline, it returns the number
+// of items left in the queue or still being processed, without waiting for the consumers.
+func (q *FairQueue) Drain(timeout time.Duration) int {
+	q.Lock()
+	q.stopped = true
+	q.Unlock()
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(drainCheckInterval)
+	defer ticker.Stop()
+
+	for q.Size() > 0 {
+		select {
+		case <-ticker.C:
+		case <-deadline.C:
+			go q.Stop()
+			return q.pending()
+		}
+	}
+	stopped := make(chan struct{})
+	go func() {
+		q.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-deadline.C:
+	}
+	return q.pending()
+}
+
+// pending returns the number of items in the queue and in the consumers
+func (q *FairQueue) pending() int {
+	q.Lock()
+	defer q.Unlock()
+	return q.size + q.inFlight
+}
+
+// Size returns the current size of the queue
+func (q *FairQueue) Size() int {
+	q.Lock()
+	defer q.Unlock()
+	return q.size
+}
+
+// Capacity returns capacity of the queue
+func (q *FairQueue) Capacity() int {
+	return q.config.Capacity
+}
+
+// StartLengthReporting starts a timer-based goroutine that periodically reports the current queue
+// length to a given metrics gauge, and the length of every class to the per class gauges.
+func (q *FairQueue) StartLengthReporting(reportPeriod time.Duration, gauge metrics.Gauge) {
+	ticker := time.NewTicker(reportPeriod)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				q.reportLengths(gauge)
+			case <-q.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+func (q *FairQueue) reportLengths(gauge metrics.Gauge) {
+	q.Lock()
+	defer q.Unlock()
+	gauge.Update(int64(q.size))
+	q.priorityGauge.Update(int64(len(q.priority)))
+	for _, c := range q.classes {
+		c.gauge.Update(int64(len(c.items)))
+	}
+}
+
+// add puts the item in its class queue, or in the priority lane. When the queue is full, it either
+// rejects the item or evicts the newest item of the largest class to make room for it.
+func (q *FairQueue) add(item interface{}, class string, priority bool) (accepted bool, evicted interface{}) {
+	if q.stopped {
+		return false, nil
+	}
+	var c *classQueue
+	if priority {
+		if len(q.priority) >= q.config.PriorityCapacity {
+			q.priorityDropped.Inc(1)
+			return false, nil
+		}
+	} else {
+		c = q.classQueue(class)
+	}
+	if q.size >= q.config.Capacity {
+		if !priority && len(c.items) >= q.reserved {
+			return false, nil
+		}
+		largest := q.largest()
+		if largest == nil || (!priority && len(largest.items) <= q.reserved) {
+			return false, nil
+		}
+		evicted = largest.items[len(largest.items)-1]
+		largest.items[len(largest.items)-1] = nil
+		largest.items = largest.items[:len(largest.items)-1]
+		q.size--
+		if len(largest.items) == 0 {
+			q.deactivate(largest)
+		}
+	}
+	if priority {
+		q.priority = append(q.priority, item)
+	} else {
+		c.items = append(c.items, item)
+		if !c.active {
+			c.active = true
+			q.active = append(q.active, c)
+		}
+	}
+	q.size++
+	return true, evicted
+}
+
+// take blocks until an item is available and returns it, or returns false once the queue is stopped
+func (q *FairQueue) take() (interface{}, bool) {
+	q.Lock()
+	defer q.Unlock()
+	for q.size == 0 && !q.closed {
+		q.notEmpty.Wait()
+	}
+	if q.closed {
+		return nil, false
+	}
+	q.size--
+	q.inFlight++
+	if len(q.priority) > 0 {
+		item := q.priority[0]
+		q.priority[0] = nil
+		q.priority = q.priority[1:]
+		return item, true
+	}
+	if q.next >= len(q.active) {
+		q.next = 0
+		q.credit = 0
+	}
+	c := q.active[q.next]
+	if q.credit == 0 {
+		q.credit = c.weight
+	}
+	item := c.items[0]
+	c.items[0] = nil
+	c.items = c.items[1:]
+	q.credit--
+	if len(c.items) == 0 {
+		q.deactivate(c)
+	} else if q.credit == 0 {
+		q.next++
+	}
+	return item, true
+}
+
+func (q *FairQueue) classQueue(class string) *classQueue {
+	if c, ok := q.classes[class]; ok {
+		return c
+	}
+	if len(q.classes) >= q.config.MaxClasses {
+		class = OverflowClass
+		if c, ok := q.classes[class]; ok {
+			return c
+		}
+	}
+	weight := q.config.Weights[class]
+	if weight <= 0 {
+		weight = 1
+	}
+	c := &classQueue{
+		weight: weight,
+		gauge:  q.metricsFactory.Gauge("queue-length", map[string]string{"class": class}),
+	}
+	q.classes[class] = c
+	return c
+}
+
+func (q *FairQueue) largest() *classQueue {
+	var largest *classQueue
+	for _, c := range q.active {
+		if largest == nil || len(c.items) > len(largest.items) {
+			largest = c
+		}
+	}
+	return largest
+}
+
+// deactivate removes an emptied class from the round-robin order
+func (q *FairQueue) deactivate(c *classQueue) {
+	for i, a := range q.active {
+		if a != c {
+			continue
+		}
+		copy(q.active[i:], q.active[i+1:])
+		q.active[len(q.active)-1] = nil
+		q.active = q.active[:len(q.active)-1]
+		if i < q.next {
+			q.next--
+		} else if i == q.next {
+			q.credit = 0
+		}
+		break
+	}
+	c.active = false
+}
@@ -0,0 +1,210 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package queue
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber/jaeger-lib/metrics"
+)
+
+// classifyByPrefix uses the first letter of an item as its class, items starting with "!" are priority items
+func classifyByPrefix(item interface{}) (string, bool) {
+	s := item.(string)
+	return s[:1], s[0] == '!'
+}
+
+type recordingConsumer struct {
+	sync.Mutex
+	items []string
+	wg    sync.WaitGroup
+}
+
+func newRecordingConsumer(expected int) *recordingConsumer {
+	c := &recordingConsumer{}
+	c.wg.Add(expected)
+	return c
+}
+
+func (c *recordingConsumer) consume(item interface{}) {
+	c.Lock()
+	c.items = append(c.items, item.(string))
+	c.Unlock()
+	c.wg.Done()
+}
+
+func (c *recordingConsumer) wait(t *testing.T) []string {
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the consumer")
+	}
+	c.Lock()
+	defer c.Unlock()
+	return c.items
+}
+
+func TestFairQueueWeightedRoundRobin(t *testing.T) {
+	q := NewFairQueue(FairQueueConfig{Capacity: 10, Weights: map[string]int{"a": 2}}, classifyByPrefix, nil, nil)
+	defer q.Stop()
+	for _, item := range []string{"a1", "a2", "a3", "a4", "a5", "b1", "b2", "c1"} {
+		assert.True(t, q.Produce(item))
+	}
+	assert.True(t, q.Produce("!1"))
+	assert.Equal(t, 9, q.Size())
+
+	consumer := newRecordingConsumer(9)
+	q.StartConsumers(1, consumer.consume)
+	assert.Equal(t, []string{"!1", "a1", "a2", "b1", "c1", "a3", "a4", "b2", "a5"}, consumer.wait(t))
+	assert.Equal(t, 0, q.Size())
+}
+
+func TestFairQueueReservedShare(t *testing.T) {
+	dropped := []string{}
+	q := NewFairQueue(
+		FairQueueConfig{Capacity: 10, ReservedShare: 0.2},
+		classifyByPrefix,
+		func(item interface{}) { dropped = append(dropped, item.(string)) },
+		nil,
+	)
+	defer q.Stop()
+	for i := 0; i < 10; i++ {
+		assert.True(t, q.Produce("a"+strconv.Itoa(i)))
+	}
+	assert.False(t, q.Produce("a-overflow"))
+
+	// b holds less than its reserved share, so it pushes out the newest items of a
+	assert.True(t, q.Produce("b1"))
+	assert.True(t, q.Produce("b2"))
+	assert.False(t, q.Produce("b3"))
+	// priority items always get in while the classes hold items
+	assert.True(t, q.Produce("!1"))
+	assert.Equal(t, 10, q.Size())
+	assert.Equal(t, []string{"a-overflow", "a9", "a8", "b3", "a7"}, dropped)
+}
+
+func TestFairQueueMaxClasses(t *testing.T) {
+	q := NewFairQueue(FairQueueConfig{Capacity: 10, MaxClasses: 2}, classifyByPrefix, nil, nil)
+	defer q.Stop()
+	for _, item := range []string{"a1", "b1", "c1", "d1"} {
+		assert.True(t, q.Produce(item))
+	}
+	q.Lock()
+	defer q.Unlock()
+	assert.Len(t, q.classes, 3)
+	assert.Equal(t, 2, len(q.classes[OverflowClass].items))
+}
+
+func TestFairQueueLengthReporting(t *testing.T) {
+	mFact := metrics.NewLocalFactory(0)
+	q := NewFairQueue(FairQueueConfig{Capacity: 10}, classifyByPrefix, nil, mFact)
+	defer q.Stop()
+	for _, item := range []string{"a1", "a2", "b1", "!1"} {
+		assert.True(t, q.Produce(item))
+	}
+	q.StartLengthReporting(time.Millisecond, mFact.Gauge("size", nil))
+	for i := 0; i < 1000; i++ {
+		_, g := mFact.Snapshot()
+		if g["size"] == 4 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	_, g := mFact.Snapshot()
+	assert.EqualValues(t, 4, g["size"])
+	assert.EqualValues(t, 2, g["queue-length|class=a"])
+	assert.EqualValues(t, 1, g["queue-length|class=b"])
+	assert.EqualValues(t, 1, g["queue-length|class="+PriorityClass])
+}
+
+func TestFairQueueDrain(t *testing.T) {
+	q := NewFairQueue(FairQueueConfig{Capacity: 10}, classifyByPrefix, nil, nil)
+	for _, item := range []string{"a1", "a2", "b1"} {
+		assert.True(t, q.Produce(item))
+	}
+	consumer := newRecordingConsumer(3)
+	q.StartConsumers(2, consumer.consume)
+	assert.Equal(t, 0, q.Drain(time.Second))
+	assert.Len(t, consumer.wait(t), 3)
+	assert.False(t, q.Produce("a3"))
+}
+
+func TestFairQueueDrainTimeout(t *testing.T) {
+	q := NewFairQueue(FairQueueConfig{Capacity: 10}, classifyByPrefix, nil, nil)
+	for _, item := range []string{"a1", "a2", "b1"} {
+		assert.True(t, q.Produce(item))
+	}
+	// no consumers, so nothing is drained
+	assert.Equal(t, 3, q.Drain(20*time.Millisecond))
+}
+
+func TestFairQueuePriorityCapacity(t *testing.T) {
+	mFact := metrics.NewLocalFactory(0)
+	dropped := []string{}
+	q := NewFairQueue(
+		FairQueueConfig{Capacity: 10, PriorityCapacity: 2},
+		classifyByPrefix,
+		func(item interface{}) { dropped = append(dropped, item.(string)) },
+		mFact,
+	)
+	defer q.Stop()
+	assert.True(t, q.Produce("!1"))
+	assert.True(t, q.Produce("!2"))
+	assert.False(t, q.Produce("!3"))
+	// the classes still get the rest of the capacity
+	assert.True(t, q.Produce("a1"))
+	assert.Equal(t, []string{"!3"}, dropped)
+	c, _ := mFact.Snapshot()
+	assert.EqualValues(t, 1, c["queue-dropped|class="+PriorityClass])
+}
+
+func TestFairQueueDefaultPriorityCapacity(t *testing.T) {
+	assert.Equal(t, 10, NewFairQueue(FairQueueConfig{Capacity: 100}, classifyByPrefix, nil, nil).config.PriorityCapacity)
+	assert.Equal(t, 1, NewFairQueue(FairQueueConfig{Capacity: 5}, classifyByPrefix, nil, nil).config.PriorityCapacity)
+}
+
+func TestFairQueueDrainDoesNotWaitPastDeadline(t *testing.T) {
+	q := NewFairQueue(FairQueueConfig{Capacity: 10}, classifyByPrefix, nil, nil)
+	block := make(chan struct{})
+	defer close(block)
+	consumed := make(chan struct{})
+	q.StartConsumers(1, func(item interface{}) {
+		close(consumed)
+		<-block
+	})
+	assert.True(t, q.Produce("a1"))
+	<-consumed
+
+	// the queue is empty but the consumer is still blocked on "a1"
+	start := time.Now()
+	assert.Equal(t, 1, q.Drain(10*time.Millisecond), "the item being consumed is not processed yet")
+	assert.True(t, time.Since(start) < time.Second, "Drain must return at the deadline")
+}
@@ -0,0 +1,52 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package queue
+
+import (
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+)
+
+// Queue is a bounded producer-consumer queue
+type Queue interface {
+	// StartConsumers starts a given number of goroutines consuming items from the queue
+	StartConsumers(num int, consumer func(item interface{}))
+	// Produce submits a new item to the queue, it returns false if the item was dropped
+	Produce(item interface{}) bool
+	// Stop stops all consumers and blocks until they are stopped
+	Stop()
+	// Drain stops accepting new items and waits for the queue to be emptied, but no longer than the timeout.
+	// It returns the number of items left in the queue or still being consumed.
+	Drain(timeout time.Duration) int
+	// Size returns the current size of the queue
+	Size() int
+	// Capacity returns capacity of the queue
+	Capacity() int
+	// StartLengthReporting periodically reports the queue length to the gauge
+	StartLengthReporting(reportPeriod time.Duration, gauge metrics.Gauge)
+}
+
+var (
+	_ Queue = (*BoundedQueue)(nil)
+	_ Queue = (*FairQueue)(nil)
+)
@@ -0,0 +1,191 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package es
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/olivere/elastic"
+	"github.com/pkg/errors"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/es"
+)
+
+const (
+	defaultTTL = 60 * time.Second
+
+	leaseType   = "lease"
+	leasesIndex = "jaeger-leases"
+
+	leasesMapping = `{
+   "settings":{
+      "index.mapper.dynamic":false
+   },
+   "mappings":{
+      "` + leaseType + `":{
+         "properties":{
+            "owner":{
+               "type":"keyword",
+               "index":false
+            },
+            "expiresAt":{
+               "type":"long",
+               "index":false
+            }
+         }
+      }
+   }
+}`
+)
+
+var (
+	errLockOwnership = errors.New("This host does not own the resource lock")
+)
+
+// leaseDocument is stored in the leases index with the resource as its ID. ElasticSearch has no TTL,
+// so a lease whose expiresAt (in microseconds since epoch) is in the past is free to be taken over.
+type leaseDocument struct {
+	Owner     string `json:"owner"`
+	ExpiresAt uint64 `json:"expiresAt"`
+}
+
+// Lock is a distributed lock based off ElasticSearch. Races between hosts are resolved by the
+// optimistic concurrency control of ElasticSearch documents.
+type Lock struct {
+	ctx      context.Context
+	client   es.Client
+	tenantID string
+	timeNow  func() time.Time
+}
+
+// NewLock creates a new instance of a distributed locking mechanism based off ElasticSearch.
+func NewLock(client es.Client, tenantID string) *Lock {
+	return &Lock{
+		ctx:      context.Background(),
+		client:   client,
+		tenantID: tenantID,
+		timeNow:  time.Now,
+	}
+}
+
+// Acquire acquires a lease around a given resource. If this host already owns the lease, it is extended.
+func (l *Lock) Acquire(resource string, ttl time.Duration) (bool, error) {
+	if ttl == 0 {
+		ttl = defaultTTL
+	}
+	if err := l.createIndex(); err != nil {
+		return false, err
+	}
+	lease, version, err := l.getLease(resource)
+	if err != nil {
+		return false, errors.Wrap(err, "Failed to acquire resource lock due to elasticsearch error")
+	}
+	now := l.timeNow()
+	if lease != nil && lease.Owner != l.tenantID && lease.ExpiresAt > model.TimeAsEpochMicroseconds(now) {
+		return false, nil
+	}
+	index := l.client.Index().Index(leasesIndex).
+		Type(leaseType).
+		Id(resource).
+		BodyJson(&leaseDocument{
+			Owner:     l.tenantID,
+			ExpiresAt: model.TimeAsEpochMicroseconds(now.Add(ttl)),
+		})
+	if lease == nil {
+		index = index.OpType("create")
+	} else {
+		index = index.Version(version)
+	}
+	if _, err := index.Do(l.ctx); err != nil {
+		if isConflict(err) {
+			// Another host created or updated the lease since it was read
+			return false, nil
+		}
+		return false, errors.Wrap(err, "Failed to acquire resource lock due to elasticsearch error")
+	}
+	return true, nil
+}
+
+// Forfeit forfeits an existing lease around a given resource.
+func (l *Lock) Forfeit(resource string) (bool, error) {
+	lease, version, err := l.getLease(resource)
+	if err != nil {
+		return false, errors.Wrap(err, "Failed to forfeit resource lock due to elasticsearch error")
+	}
+	if lease == nil || lease.Owner != l.tenantID {
+		return false, errors.Wrap(errLockOwnership, "Failed to forfeit resource lock")
+	}
+	_, err = l.client.Delete().Index(leasesIndex).
+		Type(leaseType).
+		Id(resource).
+		Version(version).
+		Do(l.ctx)
+	if err != nil {
+		if isConflict(err) || elastic.IsNotFound(err) {
+			return false, errors.Wrap(errLockOwnership, "Failed to forfeit resource lock")
+		}
+		return false, errors.Wrap(err, "Failed to forfeit resource lock due to elasticsearch error")
+	}
+	return true, nil
+}
+
+// getLease returns the current lease around a given resource with its document version, or nil if there is none.
+func (l *Lock) getLease(resource string) (*leaseDocument, int64, error) {
+	result, err := l.client.Get().Index(leasesIndex).
+		Type(leaseType).
+		Id(resource).
+		Do(l.ctx)
+	if elastic.IsNotFound(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if !result.Found || result.Source == nil || result.Version == nil {
+		return nil, 0, nil
+	}
+	var lease leaseDocument
+	if err := json.Unmarshal(*result.Source, &lease); err != nil {
+		return nil, 0, err
+	}
+	return &lease, *result.Version, nil
+}
+
+func (l *Lock) createIndex() error {
+	// the error is not checked because exists is false anyway if there is an error
+	if exists, _ := l.client.IndexExists(leasesIndex).Do(l.ctx); exists {
+		return nil
+	}
+	if _, err := l.client.CreateIndex(leasesIndex).Body(leasesMapping).Do(l.ctx); err != nil {
+		return errors.Wrap(err, "Failed to create index")
+	}
+	return nil
+}
+
+func isConflict(err error) bool {
+	e, ok := err.(*elastic.Error)
+	return ok && e.Status == http.StatusConflict
+}
@@ -0,0 +1,297 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package es
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/olivere/elastic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/distributedlock"
+	"github.com/uber/jaeger/pkg/es/mocks"
+)
+
+var (
+	localhost    = "localhost"
+	samplingLock = "sampling_lock"
+	testingNow   = time.Unix(1000, 0)
+)
+
+var _ distributedlock.Lock = &Lock{} // check API conformance
+
+type esLockTest struct {
+	client *mocks.Client
+	lock   *Lock
+}
+
+func withESLock(fn func(r *esLockTest)) {
+	client := &mocks.Client{}
+	lock := NewLock(client, localhost)
+	lock.timeNow = func() time.Time { return testingNow }
+	fn(&esLockTest{
+		client: client,
+		lock:   lock,
+	})
+}
+
+func (r *esLockTest) mockGet(lease *leaseDocument, err error) {
+	getService := &mocks.GetService{}
+	r.client.On("Get").Return(getService)
+	getService.On("Index", leasesIndex).Return(getService)
+	getService.On("Type", leaseType).Return(getService)
+	getService.On("Id", samplingLock).Return(getService)
+	if lease == nil {
+		getService.On("Do", mock.Anything).Return(nil, err)
+		return
+	}
+	source, _ := json.Marshal(lease)
+	rawSource := json.RawMessage(source)
+	version := int64(3)
+	getService.On("Do", mock.Anything).Return(&elastic.GetResult{
+		Found:   true,
+		Source:  &rawSource,
+		Version: &version,
+	}, err)
+}
+
+func (r *esLockTest) mockIndexExists() {
+	existsService := &mocks.IndicesExistsService{}
+	r.client.On("IndexExists", leasesIndex).Return(existsService)
+	existsService.On("Do", mock.Anything).Return(true, nil)
+}
+
+var (
+	notFoundError = &elastic.Error{Status: http.StatusNotFound}
+	conflictError = &elastic.Error{Status: http.StatusConflict}
+)
+
+func TestAcquire(t *testing.T) {
+	expiresAt := model.TimeAsEpochMicroseconds(testingNow.Add(time.Minute))
+	expiredAt := model.TimeAsEpochMicroseconds(testingNow.Add(-time.Minute))
+	testCases := []struct {
+		caption          string
+		lease            *leaseDocument
+		getError         error
+		expectedOpType   string
+		expectedVersion  int64
+		indexError       error
+		expectedAcquired bool
+		expectedErrMsg   string
+	}{
+		{
+			caption:          "no lease",
+			getError:         notFoundError,
+			expectedOpType:   "create",
+			expectedAcquired: true,
+		},
+		{
+			caption:          "no lease, created concurrently",
+			getError:         notFoundError,
+			expectedOpType:   "create",
+			indexError:       conflictError,
+			expectedAcquired: false,
+		},
+		{
+			caption:          "own lease is extended",
+			lease:            &leaseDocument{Owner: localhost, ExpiresAt: expiresAt},
+			expectedVersion:  3,
+			expectedAcquired: true,
+		},
+		{
+			caption:          "expired lease is taken over",
+			lease:            &leaseDocument{Owner: "otherhost", ExpiresAt: expiredAt},
+			expectedVersion:  3,
+			expectedAcquired: true,
+		},
+		{
+			caption:          "expired lease, taken over concurrently",
+			lease:            &leaseDocument{Owner: "otherhost", ExpiresAt: expiredAt},
+			expectedVersion:  3,
+			indexError:       conflictError,
+			expectedAcquired: false,
+		},
+		{
+			caption:          "lease owned by another host",
+			lease:            &leaseDocument{Owner: "otherhost", ExpiresAt: expiresAt},
+			expectedAcquired: false,
+		},
+		{
+			caption:        "get error",
+			getError:       errors.New("get failed"),
+			expectedErrMsg: "Failed to acquire resource lock due to elasticsearch error: get failed",
+		},
+		{
+			caption:        "index error",
+			getError:       notFoundError,
+			expectedOpType: "create",
+			indexError:     errors.New("index failed"),
+			expectedErrMsg: "Failed to acquire resource lock due to elasticsearch error: index failed",
+		},
+	}
+	for _, tc := range testCases {
+		testCase := tc // capture loop var
+		t.Run(testCase.caption, func(t *testing.T) {
+			withESLock(func(r *esLockTest) {
+				r.mockIndexExists()
+				r.mockGet(testCase.lease, testCase.getError)
+				indexService := &mocks.IndexService{}
+				r.client.On("Index").Return(indexService)
+				indexService.On("Index", leasesIndex).Return(indexService)
+				indexService.On("Type", leaseType).Return(indexService)
+				indexService.On("Id", samplingLock).Return(indexService)
+				indexService.On("BodyJson", &leaseDocument{
+					Owner:     localhost,
+					ExpiresAt: expiresAt,
+				}).Return(indexService)
+				indexService.On("OpType", "create").Return(indexService)
+				indexService.On("Version", int64(3)).Return(indexService)
+				indexService.On("Do", mock.Anything).Return(nil, testCase.indexError)
+
+				acquired, err := r.lock.Acquire(samplingLock, time.Minute)
+				if testCase.expectedErrMsg == "" {
+					require.NoError(t, err)
+				} else {
+					assert.EqualError(t, err, testCase.expectedErrMsg)
+				}
+				assert.Equal(t, testCase.expectedAcquired, acquired)
+				if testCase.expectedOpType != "" {
+					indexService.AssertCalled(t, "OpType", testCase.expectedOpType)
+				} else {
+					indexService.AssertNotCalled(t, "OpType", mock.Anything)
+				}
+				if testCase.expectedVersion != 0 {
+					indexService.AssertCalled(t, "Version", testCase.expectedVersion)
+				} else {
+					indexService.AssertNotCalled(t, "Version", mock.Anything)
+				}
+			})
+		})
+	}
+}
+
+func TestAcquireCreatesIndex(t *testing.T) {
+	testCases := []struct {
+		createIndexError error
+		expectedErrMsg   string
+	}{
+		{},
+		{
+			createIndexError: errors.New("index not created"),
+			expectedErrMsg:   "Failed to create index: index not created",
+		},
+	}
+	for _, testCase := range testCases {
+		withESLock(func(r *esLockTest) {
+			existsService := &mocks.IndicesExistsService{}
+			createService := &mocks.IndicesCreateService{}
+			r.client.On("IndexExists", leasesIndex).Return(existsService)
+			r.client.On("CreateIndex", leasesIndex).Return(createService)
+			existsService.On("Do", mock.Anything).Return(false, nil)
+			createService.On("Body", leasesMapping).Return(createService)
+			createService.On("Do", mock.Anything).Return(nil, testCase.createIndexError)
+			r.mockGet(&leaseDocument{Owner: "otherhost", ExpiresAt: model.TimeAsEpochMicroseconds(testingNow) + 1}, nil)
+
+			acquired, err := r.lock.Acquire(samplingLock, 0)
+			if testCase.expectedErrMsg == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, testCase.expectedErrMsg)
+			}
+			assert.False(t, acquired)
+		})
+	}
+}
+
+func TestForfeit(t *testing.T) {
+	testCases := []struct {
+		caption           string
+		lease             *leaseDocument
+		getError          error
+		deleteError       error
+		expectedForfeited bool
+		expectedErrMsg    string
+	}{
+		{
+			caption:           "successfully forfeited",
+			lease:             &leaseDocument{Owner: localhost},
+			expectedForfeited: true,
+		},
+		{
+			caption:        "no lease",
+			getError:       notFoundError,
+			expectedErrMsg: "Failed to forfeit resource lock: This host does not own the resource lock",
+		},
+		{
+			caption:        "lease owned by another host",
+			lease:          &leaseDocument{Owner: "otherhost"},
+			expectedErrMsg: "Failed to forfeit resource lock: This host does not own the resource lock",
+		},
+		{
+			caption:        "lease taken over concurrently",
+			lease:          &leaseDocument{Owner: localhost},
+			deleteError:    conflictError,
+			expectedErrMsg: "Failed to forfeit resource lock: This host does not own the resource lock",
+		},
+		{
+			caption:        "get error",
+			getError:       errors.New("get failed"),
+			expectedErrMsg: "Failed to forfeit resource lock due to elasticsearch error: get failed",
+		},
+		{
+			caption:        "delete error",
+			lease:          &leaseDocument{Owner: localhost},
+			deleteError:    errors.New("delete failed"),
+			expectedErrMsg: "Failed to forfeit resource lock due to elasticsearch error: delete failed",
+		},
+	}
+	for _, tc := range testCases {
+		testCase := tc // capture loop var
+		t.Run(testCase.caption, func(t *testing.T) {
+			withESLock(func(r *esLockTest) {
+				r.mockGet(testCase.lease, testCase.getError)
+				deleteService := &mocks.DeleteService{}
+				r.client.On("Delete").Return(deleteService)
+				deleteService.On("Index", leasesIndex).Return(deleteService)
+				deleteService.On("Type", leaseType).Return(deleteService)
+				deleteService.On("Id", samplingLock).Return(deleteService)
+				deleteService.On("Version", int64(3)).Return(deleteService)
+				deleteService.On("Do", mock.Anything).Return(nil, testCase.deleteError)
+
+				forfeited, err := r.lock.Forfeit(samplingLock)
+				if testCase.expectedErrMsg == "" {
+					require.NoError(t, err)
+				} else {
+					assert.EqualError(t, err, testCase.expectedErrMsg)
+				}
+				assert.Equal(t, testCase.expectedForfeited, forfeited)
+			})
+		})
+	}
+}
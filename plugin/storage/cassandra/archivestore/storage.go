@@ -0,0 +1,217 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package archivestore
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/cassandra"
+	casMetrics "github.com/uber/jaeger/pkg/cassandra/metrics"
+	"github.com/uber/jaeger/plugin/storage/cassandra/spanstore/dbmodel"
+	"github.com/uber/jaeger/storage/archivestore"
+)
+
+const (
+	archiveInsertStmt      = "INSERT INTO archives(trace_id, author, archived_at, note, labels) VALUES (?, ?, ?, ?, ?)"
+	archiveDeleteStmt      = "DELETE FROM archives WHERE trace_id = ? IF EXISTS"
+	archiveSelectStmt      = "SELECT trace_id, author, archived_at, note, labels FROM archives WHERE trace_id = ?"
+	archiveIndexInsertStmt = "INSERT INTO archive_index(bucket, archived_at, trace_id) VALUES (?, ?, ?)"
+	archiveIndexSelectStmt = `
+		SELECT trace_id, archived_at
+		FROM archive_index
+		WHERE bucket = ? AND archived_at >= ? AND archived_at <= ?`
+
+	// archiveBucketSize is the time range of the archives in a partition of the archive_index table
+	archiveBucketSize = 24 * time.Hour
+
+	// maxArchiveAge bounds the partitions read by FindArchives when the query has no ArchivedAfter
+	maxArchiveAge = 30 * 24 * time.Hour
+)
+
+// ArchiveStore handles all queries and insertions to the Cassandra archives and archive_index tables
+type ArchiveStore struct {
+	session                  cassandra.Session
+	archivesTableMetrics     *casMetrics.Table
+	archiveIndexTableMetrics *casMetrics.Table
+	logger                   *zap.Logger
+	timeNow                  func() time.Time
+}
+
+// NewArchiveStore returns an ArchiveStore
+func NewArchiveStore(session cassandra.Session, metricsFactory metrics.Factory, logger *zap.Logger) *ArchiveStore {
+	return &ArchiveStore{
+		session:                  session,
+		archivesTableMetrics:     casMetrics.NewTable(metricsFactory, "Archives"),
+		archiveIndexTableMetrics: casMetrics.NewTable(metricsFactory, "ArchiveIndex"),
+		logger:                   logger,
+		timeNow:                  time.Now,
+	}
+}
+
+// WriteArchive implements archivestore.Writer#WriteArchive. The archive is also added to the archive_index
+// partition of its day, the row of its previous archival time is skipped by FindArchives.
+func (s *ArchiveStore) WriteArchive(archive *archivestore.Archive) error {
+	traceID := dbmodel.TraceIDFromDomain(archive.TraceID)
+	archivedAt := int64(model.TimeAsEpochMicroseconds(archive.ArchivedAt))
+	query := s.session.Query(
+		archiveInsertStmt,
+		traceID,
+		archive.Author,
+		archivedAt,
+		archive.Note,
+		archive.Labels,
+	)
+	if err := s.archivesTableMetrics.Exec(query, s.logger); err != nil {
+		return err
+	}
+	indexQuery := s.session.Query(archiveIndexInsertStmt, archive.ArchivedAt.Truncate(archiveBucketSize), archivedAt, traceID)
+	return s.archiveIndexTableMetrics.Exec(indexQuery, s.logger)
+}
+
+// DeleteArchive implements archivestore.Writer#DeleteArchive. The archive_index row of the archive is left
+// to expire, FindArchives skips it.
+func (s *ArchiveStore) DeleteArchive(traceID model.TraceID) error {
+	query := s.session.Query(archiveDeleteStmt, dbmodel.TraceIDFromDomain(traceID))
+	start := time.Now()
+	applied, err := query.ScanCAS()
+	s.archivesTableMetrics.Emit(err, time.Since(start))
+	if err != nil {
+		s.logger.Error("Failed to delete archive", zap.String("trace_id", traceID.String()), zap.Error(err))
+		return errors.Wrap(err, "Error deleting archive from storage")
+	}
+	if !applied {
+		return archivestore.ErrArchiveNotFound
+	}
+	return nil
+}
+
+// GetArchive implements archivestore.Reader#GetArchive.
+func (s *ArchiveStore) GetArchive(traceID model.TraceID) (*archivestore.Archive, error) {
+	query := s.session.Query(archiveSelectStmt, dbmodel.TraceIDFromDomain(traceID))
+	archives, err := s.readArchives(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(archives) == 0 {
+		return nil, archivestore.ErrArchiveNotFound
+	}
+	return archives[0], nil
+}
+
+// FindArchives implements archivestore.Reader#FindArchives. It reads the archive_index partitions of the days
+// between ArchivedBefore, or now, and ArchivedAfter, at most maxArchiveAge earlier, newest first,
+// and stops once it has found Limit archives.
+func (s *ArchiveStore) FindArchives(query *archivestore.Query) ([]*archivestore.Archive, error) {
+	archivedBefore := query.ArchivedBefore
+	if archivedBefore.IsZero() {
+		archivedBefore = s.timeNow()
+	}
+	archivedAfter := query.ArchivedAfter
+	if oldest := archivedBefore.Add(-maxArchiveAge); archivedAfter.Before(oldest) {
+		archivedAfter = oldest
+	}
+	lastBucket := archivedAfter.Truncate(archiveBucketSize)
+	var archives []*archivestore.Archive
+	for bucket := archivedBefore.Truncate(archiveBucketSize); !bucket.Before(lastBucket); bucket = bucket.Add(-archiveBucketSize) {
+		indexQuery := s.session.Query(
+			archiveIndexSelectStmt,
+			bucket,
+			int64(model.TimeAsEpochMicroseconds(archivedAfter)),
+			int64(model.TimeAsEpochMicroseconds(archivedBefore)),
+		)
+		var err error
+		if archives, err = s.readArchiveIndex(indexQuery, query, archives); err != nil {
+			return nil, err
+		}
+		if query.Limit > 0 && len(archives) >= query.Limit {
+			break
+		}
+	}
+	return archives, nil
+}
+
+// readArchiveIndex appends to archives the archives of the index rows that match the query, until there
+// are Limit archives. The rows of deleted archives, or of an earlier archival of the trace, are skipped.
+func (s *ArchiveStore) readArchiveIndex(
+	indexQuery cassandra.Query,
+	query *archivestore.Query,
+	archives []*archivestore.Archive,
+) ([]*archivestore.Archive, error) {
+	iter := indexQuery.Consistency(cassandra.One).Iter()
+
+	var traceID dbmodel.TraceID
+	var archivedAt int64
+	for iter.Scan(&traceID, &archivedAt) {
+		archive, err := s.GetArchive(traceID.ToDomain())
+		if err == archivestore.ErrArchiveNotFound {
+			continue
+		}
+		if err != nil {
+			iter.Close()
+			return nil, err
+		}
+		if int64(model.TimeAsEpochMicroseconds(archive.ArchivedAt)) != archivedAt || !query.Matches(archive) {
+			continue
+		}
+		archives = append(archives, archive)
+		if query.Limit > 0 && len(archives) >= query.Limit {
+			break
+		}
+	}
+
+	if err := iter.Close(); err != nil {
+		s.logger.Error("Failure to read archive index", zap.Error(err))
+		return nil, errors.Wrap(err, "Error reading archive index from storage")
+	}
+	return archives, nil
+}
+
+func (s *ArchiveStore) readArchives(query cassandra.Query) ([]*archivestore.Archive, error) {
+	iter := query.Consistency(cassandra.One).Iter()
+
+	var archives []*archivestore.Archive
+	var traceID dbmodel.TraceID
+	var author, note string
+	var archivedAt int64
+	var labels []string
+	for iter.Scan(&traceID, &author, &archivedAt, &note, &labels) {
+		archives = append(archives, &archivestore.Archive{
+			TraceID:    traceID.ToDomain(),
+			Author:     author,
+			ArchivedAt: model.EpochMicrosecondsAsTime(uint64(archivedAt)),
+			Note:       note,
+			Labels:     labels,
+		})
+		labels = nil
+	}
+
+	if err := iter.Close(); err != nil {
+		s.logger.Error("Failure to read archives", zap.Error(err))
+		return nil, errors.Wrap(err, "Error reading archives from storage")
+	}
+	return archives, nil
+}
@@ -0,0 +1,367 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package archivestore
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/cassandra"
+	"github.com/uber/jaeger/pkg/cassandra/mocks"
+	"github.com/uber/jaeger/pkg/testutils"
+	"github.com/uber/jaeger/plugin/storage/cassandra/spanstore/dbmodel"
+	"github.com/uber/jaeger/storage/archivestore"
+)
+
+type archiveStorageTest struct {
+	session   *mocks.Session
+	logger    *zap.Logger
+	logBuffer *testutils.Buffer
+	storage   *ArchiveStore
+}
+
+func withArchiveStore(fn func(s *archiveStorageTest)) {
+	session := &mocks.Session{}
+	logger, logBuffer := testutils.NewLogger()
+	metricsFactory := metrics.NewLocalFactory(time.Second)
+	defer metricsFactory.Stop()
+	s := &archiveStorageTest{
+		session:   session,
+		logger:    logger,
+		logBuffer: logBuffer,
+		storage:   NewArchiveStore(session, metricsFactory, logger),
+	}
+	fn(s)
+}
+
+var _ archivestore.Reader = &ArchiveStore{} // check API conformance
+var _ archivestore.Writer = &ArchiveStore{} // check API conformance
+
+var (
+	testingTraceID = model.TraceID{High: 1, Low: 2}
+	testingArchive = &archivestore.Archive{
+		TraceID:    testingTraceID,
+		Author:     "alice",
+		ArchivedAt: time.Unix(100, 5000),
+		Note:       "Slow checkout",
+		Labels:     []string{"latency"},
+	}
+	otherArchive = &archivestore.Archive{
+		TraceID:    model.TraceID{Low: 3},
+		Author:     "bob",
+		ArchivedAt: time.Unix(200, 0),
+	}
+)
+
+func TestArchiveStoreWrite(t *testing.T) {
+	withArchiveStore(func(s *archiveStorageTest) {
+		query := &mocks.Query{}
+		query.On("Exec").Return(nil)
+
+		var args, indexArgs []interface{}
+		s.session.On("Query", archiveInsertStmt, matchEverything()).Return(captureArgs(&args, query))
+		s.session.On("Query", archiveIndexInsertStmt, matchEverything()).Return(captureArgs(&indexArgs, query))
+
+		require.NoError(t, s.storage.WriteArchive(testingArchive))
+		assert.Equal(t, []interface{}{
+			dbmodel.TraceIDFromDomain(testingTraceID),
+			"alice",
+			int64(100000005),
+			"Slow checkout",
+			[]string{"latency"},
+		}, args)
+		assert.Equal(t, []interface{}{
+			time.Unix(0, 0),
+			int64(100000005),
+			dbmodel.TraceIDFromDomain(testingTraceID),
+		}, indexArgs)
+	})
+}
+
+func TestArchiveStoreWriteError(t *testing.T) {
+	withArchiveStore(func(s *archiveStorageTest) {
+		query := &mocks.Query{}
+		query.On("Exec").Return(errors.New("query error"))
+		query.On("String").Return("select from archives")
+		s.session.On("Query", archiveInsertStmt, matchEverything()).Return(query)
+
+		assert.EqualError(t, s.storage.WriteArchive(testingArchive), "failed to Exec query 'select from archives': query error")
+		s.session.AssertNotCalled(t, "Query", archiveIndexInsertStmt, matchEverything())
+	})
+}
+
+func TestArchiveStoreDelete(t *testing.T) {
+	testCases := []struct {
+		caption       string
+		applied       bool
+		queryError    error
+		expectedError string
+		expectedLogs  []string
+	}{
+		{
+			caption: "success",
+			applied: true,
+		},
+		{
+			caption:       "not found",
+			expectedError: archivestore.ErrArchiveNotFound.Error(),
+		},
+		{
+			caption:       "failure",
+			queryError:    errors.New("query error"),
+			expectedError: "Error deleting archive from storage: query error",
+			expectedLogs:  []string{"Failed to delete archive"},
+		},
+	}
+	for _, tc := range testCases {
+		testCase := tc // capture loop var
+		t.Run(testCase.caption, func(t *testing.T) {
+			withArchiveStore(func(s *archiveStorageTest) {
+				query := &mocks.Query{}
+				query.On("ScanCAS", matchEverything()).Return(testCase.applied, testCase.queryError)
+				s.session.On("Query", archiveDeleteStmt, matchEverything()).Return(query)
+
+				err := s.storage.DeleteArchive(testingTraceID)
+				if testCase.expectedError == "" {
+					assert.NoError(t, err)
+				} else {
+					assert.EqualError(t, err, testCase.expectedError)
+				}
+				for _, expectedLog := range testCase.expectedLogs {
+					assert.True(t, strings.Contains(s.logBuffer.String(), expectedLog), "Log must contain %s, but was %s", expectedLog, s.logBuffer.String())
+				}
+				if len(testCase.expectedLogs) == 0 {
+					assert.Equal(t, "", s.logBuffer.String())
+				}
+			})
+		})
+	}
+}
+
+func TestArchiveStoreGetArchive(t *testing.T) {
+	testCases := []struct {
+		caption       string
+		archives      []*archivestore.Archive
+		queryError    error
+		expected      *archivestore.Archive
+		expectedError string
+	}{
+		{
+			caption:  "success",
+			archives: []*archivestore.Archive{testingArchive},
+			expected: testingArchive,
+		},
+		{
+			caption:       "not found",
+			expectedError: archivestore.ErrArchiveNotFound.Error(),
+		},
+		{
+			caption:       "failure",
+			queryError:    errors.New("query error"),
+			expectedError: "Error reading archives from storage: query error",
+		},
+	}
+	for _, tc := range testCases {
+		testCase := tc // capture loop var
+		t.Run(testCase.caption, func(t *testing.T) {
+			withArchiveStore(func(s *archiveStorageTest) {
+				s.session.On("Query", archiveSelectStmt, matchEverything()).
+					Return(archivesQuery(testCase.archives, testCase.queryError))
+
+				archive, err := s.storage.GetArchive(testingTraceID)
+				if testCase.expectedError == "" {
+					require.NoError(t, err)
+					assert.Equal(t, testCase.expected, archive)
+				} else {
+					assert.EqualError(t, err, testCase.expectedError)
+				}
+			})
+		})
+	}
+}
+
+func TestArchiveStoreFindArchives(t *testing.T) {
+	deletedTraceID := dbmodel.TraceIDFromDomain(model.TraceID{Low: 9})
+	// the archive_index rows by bucket, with a row of an earlier archival of testingArchive,
+	// and a row of a deleted archive
+	indexRows := map[time.Time][][]interface{}{
+		time.Unix(0, 0): {
+			{dbmodel.TraceIDFromDomain(otherArchive.TraceID), int64(200000000)},
+			{deletedTraceID, int64(150000000)},
+			{dbmodel.TraceIDFromDomain(testingTraceID), int64(100000005)},
+			{dbmodel.TraceIDFromDomain(testingTraceID), int64(50000000)},
+		},
+	}
+	archivesByTraceID := map[dbmodel.TraceID]*archivestore.Archive{
+		dbmodel.TraceIDFromDomain(testingTraceID):       testingArchive,
+		dbmodel.TraceIDFromDomain(otherArchive.TraceID): otherArchive,
+	}
+	testCases := []struct {
+		caption         string
+		query           archivestore.Query
+		expected        []*archivestore.Archive
+		expectedBuckets int
+	}{
+		{
+			caption:         "all",
+			query:           archivestore.Query{},
+			expected:        []*archivestore.Archive{otherArchive, testingArchive},
+			expectedBuckets: 31,
+		},
+		{
+			caption:         "labels",
+			query:           archivestore.Query{Labels: []string{"latency"}},
+			expected:        []*archivestore.Archive{testingArchive},
+			expectedBuckets: 31,
+		},
+		{
+			caption:         "limit",
+			query:           archivestore.Query{Limit: 1},
+			expected:        []*archivestore.Archive{otherArchive},
+			expectedBuckets: 3,
+		},
+		{
+			caption:         "time range",
+			query:           archivestore.Query{ArchivedAfter: time.Unix(150, 0), ArchivedBefore: time.Unix(250, 0)},
+			expected:        []*archivestore.Archive{otherArchive},
+			expectedBuckets: 1,
+		},
+	}
+	for _, tc := range testCases {
+		testCase := tc // capture loop var
+		t.Run(testCase.caption, func(t *testing.T) {
+			withArchiveStore(func(s *archiveStorageTest) {
+				s.storage.timeNow = func() time.Time { return time.Unix(200, 0).Add(2 * archiveBucketSize) }
+				var buckets []time.Time
+				s.session.On("Query", archiveIndexSelectStmt, matchEverything()).Return(
+					func(stmt string, values ...interface{}) cassandra.Query {
+						bucket := values[0].(time.Time)
+						buckets = append(buckets, bucket)
+						return indexQuery(indexRows[bucket], nil)
+					})
+				s.session.On("Query", archiveSelectStmt, matchEverything()).Return(
+					func(stmt string, values ...interface{}) cassandra.Query {
+						if archive, ok := archivesByTraceID[values[0].(dbmodel.TraceID)]; ok {
+							return archivesQuery([]*archivestore.Archive{archive}, nil)
+						}
+						return archivesQuery(nil, nil)
+					})
+
+				archives, err := s.storage.FindArchives(&testCase.query)
+				require.NoError(t, err)
+				assert.Equal(t, testCase.expected, archives)
+				assert.Len(t, buckets, testCase.expectedBuckets)
+			})
+		})
+	}
+}
+
+func TestArchiveStoreFindArchivesError(t *testing.T) {
+	withArchiveStore(func(s *archiveStorageTest) {
+		s.session.On("Query", archiveIndexSelectStmt, matchEverything()).
+			Return(indexQuery(nil, errors.New("query error")))
+
+		_, err := s.storage.FindArchives(&archivestore.Query{})
+		assert.EqualError(t, err, "Error reading archive index from storage: query error")
+		assert.Contains(t, s.logBuffer.String(), "Failure to read archive index")
+	})
+}
+
+func TestArchiveStoreFindArchivesReadError(t *testing.T) {
+	withArchiveStore(func(s *archiveStorageTest) {
+		rows := [][]interface{}{{dbmodel.TraceIDFromDomain(testingTraceID), int64(100000005)}}
+		s.session.On("Query", archiveIndexSelectStmt, matchEverything()).
+			Return(indexQuery(rows, nil))
+		s.session.On("Query", archiveSelectStmt, matchEverything()).
+			Return(archivesQuery(nil, errors.New("query error")))
+
+		_, err := s.storage.FindArchives(&archivestore.Query{})
+		assert.EqualError(t, err, "Error reading archives from storage: query error")
+	})
+}
+
+// indexQuery returns a query whose iterator scans the given archive_index rows, then closes with err
+func indexQuery(rows [][]interface{}, err error) *mocks.Query {
+	iter := &mocks.Iterator{}
+	iter.On("Scan", matchEverything()).Return(func(dest ...interface{}) bool {
+		if len(rows) == 0 {
+			return false
+		}
+		*dest[0].(*dbmodel.TraceID) = rows[0][0].(dbmodel.TraceID)
+		*dest[1].(*int64) = rows[0][1].(int64)
+		rows = rows[1:]
+		return true
+	})
+	iter.On("Close").Return(err)
+
+	query := &mocks.Query{}
+	query.On("Consistency", cassandra.One).Return(query)
+	query.On("Iter").Return(iter)
+	return query
+}
+
+// archivesQuery returns a query whose iterator scans the given archives, then closes with err
+func archivesQuery(archives []*archivestore.Archive, err error) *mocks.Query {
+	scanFunc := func(args []interface{}) bool {
+		if len(archives) == 0 {
+			return false
+		}
+		archive := archives[0]
+		archives = archives[1:]
+		*args[0].(*dbmodel.TraceID) = dbmodel.TraceIDFromDomain(archive.TraceID)
+		*args[1].(*string) = archive.Author
+		*args[2].(*int64) = int64(model.TimeAsEpochMicroseconds(archive.ArchivedAt))
+		*args[3].(*string) = archive.Note
+		*args[4].(*[]string) = archive.Labels
+		return true
+	}
+	iter := &mocks.Iterator{}
+	iter.On("Scan", mock.MatchedBy(scanFunc)).Return(true)
+	iter.On("Scan", matchEverything()).Return(false)
+	iter.On("Close").Return(err)
+
+	query := &mocks.Query{}
+	query.On("Consistency", cassandra.One).Return(query)
+	query.On("Iter").Return(iter)
+	return query
+}
+
+func matchEverything() interface{} {
+	return mock.MatchedBy(func(v []interface{}) bool { return true })
+}
+
+// captureArgs returns a mock return function that saves the values bound to the query into args
+func captureArgs(args *[]interface{}, query cassandra.Query) func(string, ...interface{}) cassandra.Query {
+	return func(stmt string, values ...interface{}) cassandra.Query {
+		*args = values
+		return query
+	}
+}
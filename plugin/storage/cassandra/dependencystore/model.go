@@ -31,6 +31,8 @@ type Dependency struct {
 	Parent    string `cql:"parent"`
 	Child     string `cql:"child"`
 	CallCount int64  `cql:"call_count"` // always unsigned, but we cannot explicitly read uint64 from Cassandra
+	// ErrorCount is absent from the dependency type of keyspaces created before it was added, and then always 0
+	ErrorCount int64 `cql:"error_count"`
 }
 
 // MarshalUDT handles marshalling a Dependency.
@@ -42,6 +44,8 @@ func (d *Dependency) MarshalUDT(name string, info gocql.TypeInfo) ([]byte, error
 		return gocql.Marshal(info, d.Child)
 	case "call_count":
 		return gocql.Marshal(info, d.CallCount)
+	case "error_count":
+		return gocql.Marshal(info, d.ErrorCount)
 	default:
 		return nil, fmt.Errorf("unknown column for position: %q", name)
 	}
@@ -56,6 +60,8 @@ func (d *Dependency) UnmarshalUDT(name string, info gocql.TypeInfo, data []byte)
 		return gocql.Unmarshal(info, data, &d.Child)
 	case "call_count":
 		return gocql.Unmarshal(info, data, &d.CallCount)
+	case "error_count":
+		return gocql.Unmarshal(info, data, &d.ErrorCount)
 	default:
 		return fmt.Errorf("unknown column for position: %q", name)
 	}
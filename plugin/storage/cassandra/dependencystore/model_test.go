@@ -30,9 +30,10 @@ import (
 
 func TestDependencyUDT(t *testing.T) {
 	dependency := &Dependency{
-		Parent:    "goo",
-		Child:     "gle",
-		CallCount: 123,
+		Parent:     "goo",
+		Child:      "gle",
+		CallCount:  123,
+		ErrorCount: 7,
 	}
 
 	testCase := testutils.UDTTestCase{
@@ -43,6 +44,7 @@ func TestDependencyUDT(t *testing.T) {
 			{Name: "parent", Type: gocql.TypeAscii, ValIn: []byte("goo"), Err: false},
 			{Name: "child", Type: gocql.TypeAscii, ValIn: []byte("gle"), Err: false},
 			{Name: "call_count", Type: gocql.TypeBigInt, ValIn: []byte{0, 0, 0, 0, 0, 0, 0, 123}, Err: false},
+			{Name: "error_count", Type: gocql.TypeBigInt, ValIn: []byte{0, 0, 0, 0, 0, 0, 0, 7}, Err: false},
 			{Name: "wrong-field", Err: true},
 		},
 	}
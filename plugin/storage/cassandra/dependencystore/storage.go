@@ -65,9 +65,10 @@ func (s *DependencyStore) WriteDependencies(ts time.Time, dependencies []model.D
 	deps := make([]Dependency, len(dependencies))
 	for i, d := range dependencies {
 		deps[i] = Dependency{
-			Parent:    d.Parent,
-			Child:     d.Child,
-			CallCount: int64(d.CallCount),
+			Parent:     d.Parent,
+			Child:      d.Child,
+			CallCount:  int64(d.CallCount),
+			ErrorCount: int64(d.ErrorCount),
 		}
 	}
 	query := s.session.Query(depsInsertStmt, ts, ts, deps)
@@ -85,9 +86,10 @@ func (s *DependencyStore) GetDependencies(endTs time.Time, lookback time.Duratio
 	for iter.Scan(&ts, &dependencies) {
 		for _, dependency := range dependencies {
 			mDependency = append(mDependency, model.DependencyLink{
-				Parent:    dependency.Parent,
-				Child:     dependency.Child,
-				CallCount: uint64(dependency.CallCount),
+				Parent:     dependency.Parent,
+				Child:      dependency.Child,
+				CallCount:  uint64(dependency.CallCount),
+				ErrorCount: uint64(dependency.ErrorCount),
 			})
 		}
 	}
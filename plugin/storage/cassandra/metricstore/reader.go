@@ -0,0 +1,158 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package metricstore
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/cassandra"
+	casMetrics "github.com/uber/jaeger/pkg/cassandra/metrics"
+	"github.com/uber/jaeger/storage/metricstore"
+)
+
+const queryOperationMetrics = `
+		SELECT ts, calls, errors, latencies
+		FROM operation_metrics
+		WHERE service_name = ? AND operation_name = ? AND day = ? AND ts >= ? AND ts <= ?`
+
+// MetricReader reads the RED metrics that a MetricWriter aggregated into Cassandra
+type MetricReader struct {
+	session      cassandra.Session
+	tableMetrics *casMetrics.Table
+	logger       *zap.Logger
+}
+
+// NewMetricReader returns a MetricReader
+func NewMetricReader(session cassandra.Session, metricsFactory metrics.Factory, logger *zap.Logger) *MetricReader {
+	return &MetricReader{
+		session:      session,
+		tableMetrics: casMetrics.NewTable(metricsFactory, "OperationMetrics"),
+		logger:       logger,
+	}
+}
+
+// stepMetrics are the metrics of the spans that start in a step
+type stepMetrics struct {
+	start time.Time
+	operationMetrics
+}
+
+// GetLatencies implements metricstore.Reader#GetLatencies
+func (r *MetricReader) GetLatencies(query *metricstore.QueryParameters) ([]model.MetricPoint, error) {
+	steps, err := r.findStepMetrics(query)
+	if err != nil {
+		return nil, err
+	}
+	var points []model.MetricPoint
+	for _, step := range steps {
+		if step.calls > 0 {
+			points = append(points, model.MetricPoint{Timestamp: step.start, Value: metricstore.LatencyQuantile(step.latencies, query.Quantile)})
+		}
+	}
+	return points, nil
+}
+
+// GetCallRates implements metricstore.Reader#GetCallRates
+func (r *MetricReader) GetCallRates(query *metricstore.QueryParameters) ([]model.MetricPoint, error) {
+	steps, err := r.findStepMetrics(query)
+	if err != nil {
+		return nil, err
+	}
+	step := query.AtResolution(metricsBucketSize).Step
+	points := make([]model.MetricPoint, len(steps))
+	for i, s := range steps {
+		points[i] = model.MetricPoint{Timestamp: s.start, Value: float64(s.calls) / step.Seconds()}
+	}
+	return points, nil
+}
+
+// GetErrorRates implements metricstore.Reader#GetErrorRates
+func (r *MetricReader) GetErrorRates(query *metricstore.QueryParameters) ([]model.MetricPoint, error) {
+	steps, err := r.findStepMetrics(query)
+	if err != nil {
+		return nil, err
+	}
+	var points []model.MetricPoint
+	for _, step := range steps {
+		if step.calls > 0 {
+			points = append(points, model.MetricPoint{Timestamp: step.start, Value: float64(step.errors) / float64(step.calls)})
+		}
+	}
+	return points, nil
+}
+
+// findStepMetrics sums the operation metrics of the query by step
+func (r *MetricReader) findStepMetrics(query *metricstore.QueryParameters) ([]*stepMetrics, error) {
+	stepQuery := query.AtResolution(metricsBucketSize)
+	var steps []*stepMetrics
+	stepsByStart := map[int64]*stepMetrics{}
+	for _, start := range stepQuery.StepStarts() {
+		step := &stepMetrics{start: start, operationMetrics: operationMetrics{latencies: map[int]int64{}}}
+		steps = append(steps, step)
+		stepsByStart[start.Unix()] = step
+	}
+	startTime := steps[0].start
+	for day := startTime.Truncate(dayBucketSize); !day.After(query.EndTime); day = day.Add(dayBucketSize) {
+		err := r.readDay(day, startTime, query, func(ts time.Time, m *operationMetrics) {
+			step := stepsByStart[stepQuery.StepStart(ts).Unix()]
+			step.calls += m.calls
+			step.errors += m.errors
+			for bucket, count := range m.latencies {
+				step.latencies[bucket] += count
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return steps, nil
+}
+
+func (r *MetricReader) readDay(
+	day, startTime time.Time,
+	query *metricstore.QueryParameters,
+	add func(ts time.Time, m *operationMetrics),
+) error {
+	start := time.Now()
+	iter := r.session.Query(queryOperationMetrics, query.ServiceName, query.OperationName, day, startTime, query.EndTime).
+		Consistency(cassandra.One).
+		Iter()
+	var ts time.Time
+	var m operationMetrics
+	for iter.Scan(&ts, &m.calls, &m.errors, &m.latencies) {
+		add(ts, &m)
+		m.latencies = nil
+	}
+	err := iter.Close()
+	r.tableMetrics.Emit(err, time.Since(start))
+	if err != nil {
+		r.logger.Error("Failure to read operation metrics", zap.String("service_name", query.ServiceName),
+			zap.String("operation_name", query.OperationName), zap.Time("day", day), zap.Error(err))
+		return errors.Wrap(err, "Error reading operation metrics from storage")
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package metricstore
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/cassandra"
+	"github.com/uber/jaeger/pkg/cassandra/mocks"
+	"github.com/uber/jaeger/pkg/testutils"
+	"github.com/uber/jaeger/storage/metricstore"
+)
+
+var _ metricstore.Reader = &MetricReader{} // check API conformance
+
+// metricsRow is a row of the operation_metrics table
+type metricsRow struct {
+	ts        time.Time
+	calls     int64
+	errors    int64
+	latencies map[int]int64
+}
+
+func withMetricReader(rowsByDay map[int64][]metricsRow, readErr error, fn func(reader *MetricReader, session *mocks.Session)) {
+	session := &mocks.Session{}
+	session.On("Query", queryOperationMetrics, mock.Anything).Return(func(stmt string, values ...interface{}) cassandra.Query {
+		rows := rowsByDay[values[2].(time.Time).Unix()]
+		iter := &mocks.Iterator{}
+		iter.On("Scan", mock.Anything).Return(func(dest ...interface{}) bool {
+			if len(rows) == 0 {
+				return false
+			}
+			*dest[0].(*time.Time) = rows[0].ts
+			*dest[1].(*int64) = rows[0].calls
+			*dest[2].(*int64) = rows[0].errors
+			*dest[3].(*map[int]int64) = rows[0].latencies
+			rows = rows[1:]
+			return true
+		})
+		iter.On("Close").Return(readErr)
+		query := &mocks.Query{}
+		query.On("Consistency", cassandra.One).Return(query)
+		query.On("Iter").Return(iter)
+		return query
+	})
+	logger, _ := testutils.NewLogger()
+	fn(NewMetricReader(session, metrics.NullFactory, logger), session)
+}
+
+// testRows are metrics on both sides of the first day after epoch
+var testRows = map[int64][]metricsRow{
+	0: {
+		{ts: time.Unix(86400-120, 0), calls: 3, errors: 1, latencies: map[int]int64{40: 3}},
+		{ts: time.Unix(86400-60, 0), calls: 1, latencies: map[int]int64{44: 1}},
+	},
+	86400: {
+		{ts: time.Unix(86400, 0), calls: 2, errors: 2, latencies: map[int]int64{48: 2}},
+	},
+}
+
+var testQuery = &metricstore.QueryParameters{
+	ServiceName:   "service",
+	OperationName: "operation",
+	EndTime:       time.Unix(86400+150, 0),
+	Lookback:      250 * time.Second,
+	Step:          90 * time.Second, // rounded up to 2 minutes
+	Quantile:      0.5,
+}
+
+func TestMetricReaderGetLatencies(t *testing.T) {
+	withMetricReader(testRows, nil, func(reader *MetricReader, session *mocks.Session) {
+		points, err := reader.GetLatencies(testQuery)
+		assert.NoError(t, err)
+		assert.Equal(t, []model.MetricPoint{
+			{Timestamp: model.EpochMicrosecondsAsTime(uint64(86400-120) * 1000000), Value: 1.024},
+			{Timestamp: model.EpochMicrosecondsAsTime(86400 * 1000000), Value: 4.096},
+		}, points)
+		session.AssertNumberOfCalls(t, "Query", 2)
+	})
+}
+
+func TestMetricReaderGetCallRates(t *testing.T) {
+	withMetricReader(testRows, nil, func(reader *MetricReader, session *mocks.Session) {
+		points, err := reader.GetCallRates(testQuery)
+		assert.NoError(t, err)
+		assert.Equal(t, []model.MetricPoint{
+			{Timestamp: model.EpochMicrosecondsAsTime(uint64(86400-120) * 1000000), Value: 4.0 / 120},
+			{Timestamp: model.EpochMicrosecondsAsTime(86400 * 1000000), Value: 2.0 / 120},
+			{Timestamp: model.EpochMicrosecondsAsTime(uint64(86400+120) * 1000000), Value: 0},
+		}, points)
+	})
+}
+
+func TestMetricReaderGetErrorRates(t *testing.T) {
+	withMetricReader(testRows, nil, func(reader *MetricReader, session *mocks.Session) {
+		points, err := reader.GetErrorRates(testQuery)
+		assert.NoError(t, err)
+		assert.Equal(t, []model.MetricPoint{
+			{Timestamp: model.EpochMicrosecondsAsTime(uint64(86400-120) * 1000000), Value: 0.25},
+			{Timestamp: model.EpochMicrosecondsAsTime(86400 * 1000000), Value: 1},
+		}, points)
+	})
+}
+
+func TestMetricReaderFailure(t *testing.T) {
+	withMetricReader(testRows, errors.New("read failure"), func(reader *MetricReader, session *mocks.Session) {
+		for _, get := range []func(*metricstore.QueryParameters) ([]model.MetricPoint, error){
+			reader.GetLatencies,
+			reader.GetCallRates,
+			reader.GetErrorRates,
+		} {
+			points, err := get(testQuery)
+			assert.EqualError(t, err, "Error reading operation metrics from storage: read failure")
+			assert.Nil(t, points)
+		}
+	})
+}
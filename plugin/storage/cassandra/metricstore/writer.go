@@ -0,0 +1,161 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package metricstore
+
+import (
+	"sync"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/cassandra"
+	casMetrics "github.com/uber/jaeger/pkg/cassandra/metrics"
+	"github.com/uber/jaeger/storage/metricstore"
+)
+
+const (
+	// flush_id is generated by Cassandra so that the rows of every flush of every collector are kept
+	insertOperationMetrics = `
+		INSERT
+		INTO operation_metrics(service_name, operation_name, day, ts, flush_id, calls, errors, latencies)
+		VALUES (?, ?, ?, ?, now(), ?, ?, ?)`
+
+	// metricsBucketSize is the time resolution of the operation metrics
+	metricsBucketSize = time.Minute
+	dayBucketSize     = 24 * time.Hour
+)
+
+// metricsKey identifies the metrics of an operation over a minute,
+// the metrics of all the operations of a service have an empty operation name
+type metricsKey struct {
+	serviceName   string
+	operationName string
+	ts            int64
+}
+
+// operationMetrics are the RED metrics of an operation, the latencies are the number of spans per latency bucket
+type operationMetrics struct {
+	calls     int64
+	errors    int64
+	latencies map[int]int64
+}
+
+// MetricWriter aggregates the metrics of the spans it is given per operation and minute,
+// and periodically writes the aggregates to Cassandra
+type MetricWriter struct {
+	sync.Mutex
+	session      cassandra.Session
+	tableMetrics *casMetrics.Table
+	logger       *zap.Logger
+	metrics      map[metricsKey]*operationMetrics
+	stop         chan struct{}
+	stopped      sync.WaitGroup
+}
+
+// NewMetricWriter returns a MetricWriter that writes the aggregated metrics every flushInterval
+func NewMetricWriter(
+	session cassandra.Session,
+	flushInterval time.Duration,
+	metricsFactory metrics.Factory,
+	logger *zap.Logger,
+) *MetricWriter {
+	w := &MetricWriter{
+		session:      session,
+		tableMetrics: casMetrics.NewTable(metricsFactory, "OperationMetrics"),
+		logger:       logger,
+		metrics:      map[metricsKey]*operationMetrics{},
+		stop:         make(chan struct{}),
+	}
+	w.stopped.Add(1)
+	go w.flushPeriodically(flushInterval)
+	return w
+}
+
+// WriteSpan adds the span to the metrics of its operation and of its service
+func (w *MetricWriter) WriteSpan(span *model.Span) error {
+	ts := span.StartTime.Truncate(metricsBucketSize).Unix()
+	latency := metricstore.LatencyBucket(span.Duration)
+	isError := span.IsError()
+	w.Lock()
+	defer w.Unlock()
+	for _, operationName := range []string{"", span.OperationName} {
+		key := metricsKey{serviceName: span.Process.ServiceName, operationName: operationName, ts: ts}
+		m, ok := w.metrics[key]
+		if !ok {
+			m = &operationMetrics{latencies: map[int]int64{}}
+			w.metrics[key] = m
+		}
+		m.calls++
+		if isError {
+			m.errors++
+		}
+		m.latencies[latency]++
+	}
+	return nil
+}
+
+// Flush writes the metrics aggregated since the last flush
+func (w *MetricWriter) Flush() {
+	w.Lock()
+	pending := w.metrics
+	w.metrics = map[metricsKey]*operationMetrics{}
+	w.Unlock()
+	for key, m := range pending {
+		ts := time.Unix(key.ts, 0)
+		query := w.session.Query(
+			insertOperationMetrics,
+			key.serviceName,
+			key.operationName,
+			ts.Truncate(dayBucketSize),
+			ts,
+			m.calls,
+			m.errors,
+			m.latencies,
+		)
+		// the error is logged by Exec, and the metrics are dropped rather than retried
+		w.tableMetrics.Exec(query, w.logger)
+	}
+}
+
+// Close stops the periodic flushes and flushes the pending metrics
+func (w *MetricWriter) Close() error {
+	close(w.stop)
+	w.stopped.Wait()
+	w.Flush()
+	return nil
+}
+
+func (w *MetricWriter) flushPeriodically(flushInterval time.Duration) {
+	defer w.stopped.Done()
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.Flush()
+		case <-w.stop:
+			return
+		}
+	}
+}
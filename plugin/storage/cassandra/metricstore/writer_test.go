@@ -0,0 +1,112 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package metricstore
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/cassandra/mocks"
+	"github.com/uber/jaeger/pkg/testutils"
+	"github.com/uber/jaeger/storage/spanstore"
+)
+
+var _ spanstore.Writer = &MetricWriter{} // check API conformance
+
+func TestMetricWriterFlush(t *testing.T) {
+	session := &mocks.Session{}
+	logger, logBuffer := testutils.NewLogger()
+	writer := NewMetricWriter(session, time.Hour, metrics.NewLocalFactory(0), logger)
+
+	rows := map[string][]interface{}{}
+	query := &mocks.Query{}
+	query.On("Exec").Return(nil).Once()
+	query.On("Exec").Return(errors.New("exec failure")).Once()
+	query.On("String").Return("insert")
+	session.On("Query", insertOperationMetrics, mock.Anything).Run(func(args mock.Arguments) {
+		values := args.Get(1).([]interface{})
+		rows[values[1].(string)] = values
+	}).Return(query)
+
+	startTime := time.Unix(86400+90, 0)
+	for i, d := range []time.Duration{time.Millisecond, 2 * time.Millisecond} {
+		span := &model.Span{
+			Process:       &model.Process{ServiceName: "service"},
+			OperationName: "operation",
+			StartTime:     startTime.Add(time.Duration(i) * time.Second),
+			Duration:      d,
+		}
+		if i == 1 {
+			span.Tags = model.KeyValues{model.Bool("error", true)}
+		}
+		assert.NoError(t, writer.WriteSpan(span))
+	}
+	writer.Flush()
+
+	expectedRow := func(operationName string) []interface{} {
+		return []interface{}{
+			"service", operationName, time.Unix(86400, 0), time.Unix(86400+60, 0),
+			int64(2), int64(1), map[int]int64{40: 1, 44: 1},
+		}
+	}
+	assert.Equal(t, map[string][]interface{}{
+		"":          expectedRow(""),
+		"operation": expectedRow("operation"),
+	}, rows)
+	assert.Contains(t, logBuffer.String(), "exec failure")
+
+	// nothing is left to write on close
+	assert.NoError(t, writer.Close())
+	session.AssertNumberOfCalls(t, "Query", 2)
+}
+
+func TestMetricWriterFlushPeriodically(t *testing.T) {
+	session := &mocks.Session{}
+	query := &mocks.Query{}
+	query.On("Exec").Return(nil)
+	flushed := make(chan struct{}, 2)
+	session.On("Query", insertOperationMetrics, mock.Anything).Run(func(args mock.Arguments) {
+		flushed <- struct{}{}
+	}).Return(query)
+	logger, _ := testutils.NewLogger()
+	writer := NewMetricWriter(session, time.Millisecond, metrics.NullFactory, logger)
+	defer writer.Close()
+
+	writer.WriteSpan(&model.Span{
+		Process:       &model.Process{ServiceName: "service"},
+		OperationName: "operation",
+		StartTime:     time.Now(),
+	})
+	for i := 0; i < 2; i++ {
+		select {
+		case <-flushed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the metrics were not flushed")
+		}
+	}
+}
@@ -84,7 +84,10 @@ var (
 	ErrMalformedRequestObject = errors.New("Malformed request object")
 
 	// ErrDurationAndTagQueryNotSupported occurs when duration and tags are both set
-	ErrDurationAndTagQueryNotSupported = errors.New("Cannot query for duration and tags simultaneously")
+	ErrDurationAndTagQueryNotSupported error = &spanstore.UnsupportedTagQueryError{
+		Storage: "Cassandra",
+		Reason:  "with a duration",
+	}
 
 	// ErrStartAndEndTimeNotSet occurs when start time and end time are not set
 	ErrStartAndEndTimeNotSet = errors.New("Start and End Time must be set")
@@ -207,7 +210,7 @@ func validateQuery(p *spanstore.TraceQueryParameters) error {
 	if p == nil {
 		return ErrMalformedRequestObject
 	}
-	if p.ServiceName == "" && len(p.Tags) > 0 {
+	if p.ServiceName == "" && (len(p.Tags) > 0 || p.TagQuery != nil) {
 		return ErrServiceNameNotSet
 	}
 	if p.StartTimeMin.IsZero() || p.StartTimeMax.IsZero() {
@@ -219,9 +222,12 @@ func validateQuery(p *spanstore.TraceQueryParameters) error {
 	if p.DurationMin != 0 && p.DurationMax != 0 && p.DurationMin > p.DurationMax {
 		return ErrDurationMinGreaterThanMax
 	}
-	if (p.DurationMin != 0 || p.DurationMax != 0) && len(p.Tags) > 0 {
+	if (p.DurationMin != 0 || p.DurationMax != 0) && (len(p.Tags) > 0 || p.TagQuery != nil) {
 		return ErrDurationAndTagQueryNotSupported
 	}
+	if p.TagQuery != nil {
+		return validateTagQuery(p.TagQuery)
+	}
 	return nil
 }
 
@@ -247,52 +253,56 @@ func (s *SpanReader) FindTraces(traceQuery *spanstore.TraceQueryParameters) ([]*
 			s.logger.Error("Failure to read trace", zap.String("trace_id", traceID.String()), zap.Error(err))
 			continue
 		}
+		if traceQuery.TagQuery != nil && !matchTagQuery(jTrace, traceQuery.TagQuery) {
+			continue
+		}
 		retMe = append(retMe, jTrace)
 	}
 	return retMe, nil
 }
 
 func (s *SpanReader) findTraceIDs(traceQuery *spanstore.TraceQueryParameters) (dbmodel.UniqueTraceIDs, error) {
+	var results []dbmodel.UniqueTraceIDs
 	if traceQuery.DurationMin != 0 || traceQuery.DurationMax != 0 {
-		return s.queryByDuration(traceQuery)
-	}
-
-	if traceQuery.OperationName != "" {
-		traceIds, err := s.queryByServiceNameAndOperation(traceQuery)
+		traceIds, err := s.queryByDuration(traceQuery)
 		if err != nil {
 			return nil, err
 		}
-		if len(traceQuery.Tags) > 0 {
-			tagTraceIds, err := s.queryByTagsAndLogs(traceQuery)
-			if err != nil {
-				return nil, err
-			}
-			return dbmodel.IntersectTraceIDs([]dbmodel.UniqueTraceIDs{
-				traceIds,
-				tagTraceIds,
-			}), nil
+		results = append(results, traceIds)
+	} else if traceQuery.OperationName != "" {
+		traceIds, err := s.queryByServiceNameAndOperation(traceQuery)
+		if err != nil {
+			return nil, err
 		}
-		return traceIds, nil
+		results = append(results, traceIds)
 	}
 	if len(traceQuery.Tags) > 0 {
-		return s.queryByTagsAndLogs(traceQuery)
+		tagTraceIds, err := s.queryByTagsAndLogs(traceQuery)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, tagTraceIds)
 	}
-	return s.queryByService(traceQuery)
+	if traceQuery.TagQuery != nil {
+		tagTraceIds, err := s.queryByTagQuery(traceQuery, traceQuery.TagQuery)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, tagTraceIds)
+	}
+	switch len(results) {
+	case 0:
+		return s.queryByService(traceQuery)
+	case 1:
+		return results[0], nil
+	}
+	return dbmodel.IntersectTraceIDs(results), nil
 }
 
 func (s *SpanReader) queryByTagsAndLogs(tq *spanstore.TraceQueryParameters) (dbmodel.UniqueTraceIDs, error) {
 	results := make([]dbmodel.UniqueTraceIDs, 0, len(tq.Tags))
 	for k, v := range tq.Tags {
-		query := s.session.Query(
-			queryByTag,
-			tq.ServiceName,
-			k,
-			v,
-			model.TimeAsEpochMicroseconds(tq.StartTimeMin),
-			model.TimeAsEpochMicroseconds(tq.StartTimeMax),
-			tq.NumTraces*limitMultiple,
-		).PageSize(0)
-		t, err := s.executeQuery(query, s.metrics.queryTagIndex)
+		t, err := s.queryByTag(tq, k, v)
 		if err != nil {
 			return nil, err
 		}
@@ -301,6 +311,19 @@ func (s *SpanReader) queryByTagsAndLogs(tq *spanstore.TraceQueryParameters) (dbm
 	return dbmodel.IntersectTraceIDs(results), nil
 }
 
+func (s *SpanReader) queryByTag(tq *spanstore.TraceQueryParameters, k, v string) (dbmodel.UniqueTraceIDs, error) {
+	query := s.session.Query(
+		queryByTag,
+		tq.ServiceName,
+		k,
+		v,
+		model.TimeAsEpochMicroseconds(tq.StartTimeMin),
+		model.TimeAsEpochMicroseconds(tq.StartTimeMax),
+		tq.NumTraces*limitMultiple,
+	).PageSize(0)
+	return s.executeQuery(query, s.metrics.queryTagIndex)
+}
+
 func (s *SpanReader) queryByDuration(traceQuery *spanstore.TraceQueryParameters) (dbmodel.UniqueTraceIDs, error) {
 	results := dbmodel.UniqueTraceIDs{}
 
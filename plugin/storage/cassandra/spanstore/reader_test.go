@@ -167,6 +167,7 @@ func TestSpanReaderFindTraces(t *testing.T) {
 		queryTags                         bool
 		queryOperation                    bool
 		queryDuration                     bool
+		tagQuery                          spanstore.TagQuery
 		mainQueryError                    error
 		tagsQueryError                    error
 		serviceNameAndOperationQueryError error
@@ -249,6 +250,37 @@ func TestSpanReaderFindTraces(t *testing.T) {
 			numTraces:     1,
 			expectedCount: 1,
 		},
+		{
+			caption: "tag query expression",
+			tagQuery: &spanstore.TagOr{Queries: []spanstore.TagQuery{
+				&spanstore.TagEquals{Key: "x", Value: "y"},
+				&spanstore.TagEquals{Key: "x", Value: "z"},
+			}},
+			expectedCount: 2,
+		},
+		{
+			caption: "tag query expression matched by different spans",
+			tagQuery: &spanstore.TagAnd{Queries: []spanstore.TagQuery{
+				&spanstore.TagEquals{Key: "x", Value: "y"},
+				&spanstore.TagEquals{Key: "x", Value: "z"},
+			}},
+			expectedCount: 0,
+		},
+		{
+			caption:        "tag query expression error",
+			tagQuery:       &spanstore.TagEquals{Key: "x", Value: "y"},
+			tagsQueryError: errors.New("tags query error"),
+			expectedError:  "tags query error",
+			expectedLogs: []string{
+				"Failed to exec query",
+				"tags query error",
+			},
+		},
+		{
+			caption:       "unsupported tag query expression",
+			tagQuery:      &spanstore.TagPrefix{Key: "x", Prefix: "y"},
+			expectedError: "Cassandra span storage does not support the tag query x:y*",
+		},
 		{
 			caption:            "duration query error",
 			queryDuration:      true,
@@ -275,23 +307,28 @@ func TestSpanReaderFindTraces(t *testing.T) {
 		testCase := tc // capture loop var
 		t.Run(testCase.caption, func(t *testing.T) {
 			withSpanReader(func(r *spanReaderTest) {
-				// scanMatcher can match Iter.Scan() parameters and set trace ID fields
+				// scanMatcher can match Iter.Scan() parameters and set trace ID and span tags fields.
+				// The first row read has the tag x=y, and the second one has the tag x=z.
 				scanMatcher := func(name string) interface{} {
 					traceIDs := []dbmodel.TraceID{
 						dbmodel.TraceIDFromDomain(model.TraceID{Low: 1}),
 						dbmodel.TraceIDFromDomain(model.TraceID{Low: 2}),
 					}
+					tagValues := []string{"y", "z"}
 					scanFunc := func(args []interface{}) bool {
 						if len(traceIDs) == 0 {
 							return false
 						}
 						for _, arg := range args {
-							if ptr, ok := arg.(*dbmodel.TraceID); ok {
+							switch ptr := arg.(type) {
+							case *dbmodel.TraceID:
 								*ptr = traceIDs[0]
-								break
+							case *[]dbmodel.KeyValue:
+								*ptr = []dbmodel.KeyValue{{Key: "x", ValueType: "string", ValueString: tagValues[0]}}
 							}
 						}
 						traceIDs = traceIDs[1:]
+						tagValues = tagValues[1:]
 						return true
 					}
 					return mock.MatchedBy(scanFunc)
@@ -357,6 +394,7 @@ func TestSpanReaderFindTraces(t *testing.T) {
 					queryParams.DurationMax = time.Minute * 3
 
 				}
+				queryParams.TagQuery = testCase.tagQuery
 				res, err := r.reader.FindTraces(queryParams)
 				if testCase.expectedError == "" {
 					assert.NoError(t, err)
@@ -403,6 +441,20 @@ func TestTraceQueryParameterValidation(t *testing.T) {
 	err = validateQuery(tsp)
 	assert.EqualError(t, err, ErrDurationAndTagQueryNotSupported.Error())
 
+	tsp.Tags = nil
+	tsp.TagQuery = &spanstore.TagEquals{Key: "michael", Value: "jordan"}
+	err = validateQuery(tsp)
+	assert.EqualError(t, err, ErrDurationAndTagQueryNotSupported.Error())
+
+	tsp.DurationMin = 0
+	tsp.DurationMax = 0
+	tsp.TagQuery = &spanstore.TagOr{Queries: []spanstore.TagQuery{
+		&spanstore.TagEquals{Key: "michael", Value: "jordan"},
+		&spanstore.TagNot{Query: &spanstore.TagEquals{Key: "michael", Value: "jackson"}},
+	}}
+	err = validateQuery(tsp)
+	assert.EqualError(t, err, `Cassandra span storage does not support the tag query NOT michael:"jackson"`)
+
 	tsp.StartTimeMin = time.Time{} //time.Unix(0,0) doesn't work because timezones
 	tsp.StartTimeMax = time.Time{}
 	err = validateQuery(tsp)
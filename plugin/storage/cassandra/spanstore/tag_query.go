@@ -0,0 +1,139 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package spanstore
+
+import (
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/plugin/storage/cassandra/spanstore/dbmodel"
+	"github.com/uber/jaeger/storage/spanstore"
+)
+
+// validateTagQuery returns an error if the tag index cannot serve the query, since it can only
+// find the spans with a given tag value
+func validateTagQuery(query spanstore.TagQuery) error {
+	switch q := query.(type) {
+	case *spanstore.TagEquals:
+		return nil
+	case *spanstore.TagAnd:
+		return validateTagQueries(q.Queries)
+	case *spanstore.TagOr:
+		return validateTagQueries(q.Queries)
+	}
+	return &spanstore.UnsupportedTagQueryError{Storage: "Cassandra", Query: query}
+}
+
+func validateTagQueries(queries []spanstore.TagQuery) error {
+	for _, query := range queries {
+		if err := validateTagQuery(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// queryByTagQuery returns the traces with spans that match a query validated by validateTagQuery.
+// The index can only intersect the traces of the conditions of an AND, so the traces it returns
+// must be checked with matchTagQuery.
+func (s *SpanReader) queryByTagQuery(tq *spanstore.TraceQueryParameters, query spanstore.TagQuery) (dbmodel.UniqueTraceIDs, error) {
+	switch q := query.(type) {
+	case *spanstore.TagEquals:
+		return s.queryByTag(tq, q.Key, q.Value)
+	case *spanstore.TagAnd:
+		results, err := s.queryByTagQueries(tq, q.Queries)
+		if err != nil {
+			return nil, err
+		}
+		return dbmodel.IntersectTraceIDs(results), nil
+	case *spanstore.TagOr:
+		results, err := s.queryByTagQueries(tq, q.Queries)
+		if err != nil {
+			return nil, err
+		}
+		union := dbmodel.UniqueTraceIDs{}
+		for _, traceIDs := range results {
+			for traceID := range traceIDs {
+				union.Add(traceID)
+			}
+		}
+		return union, nil
+	}
+	return nil, validateTagQuery(query)
+}
+
+func (s *SpanReader) queryByTagQueries(tq *spanstore.TraceQueryParameters, queries []spanstore.TagQuery) ([]dbmodel.UniqueTraceIDs, error) {
+	results := make([]dbmodel.UniqueTraceIDs, len(queries))
+	for i, query := range queries {
+		traceIDs, err := s.queryByTagQuery(tq, query)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = traceIDs
+	}
+	return results, nil
+}
+
+// matchTagQuery reports whether a span of the trace matches a query validated by validateTagQuery
+func matchTagQuery(trace *model.Trace, query spanstore.TagQuery) bool {
+	for _, span := range trace.Spans {
+		if matchSpanTagQuery(spanTags(span), query) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSpanTagQuery(tags model.KeyValues, query spanstore.TagQuery) bool {
+	switch q := query.(type) {
+	case *spanstore.TagEquals:
+		for i := range tags {
+			if tags[i].Key == q.Key && tags[i].AsString() == q.Value {
+				return true
+			}
+		}
+	case *spanstore.TagAnd:
+		for _, query := range q.Queries {
+			if !matchSpanTagQuery(tags, query) {
+				return false
+			}
+		}
+		return true
+	case *spanstore.TagOr:
+		for _, query := range q.Queries {
+			if matchSpanTagQuery(tags, query) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// spanTags returns the tags indexed for the span: its own tags, its process tags and its log fields
+func spanTags(span *model.Span) model.KeyValues {
+	tags := append(model.KeyValues{}, span.Tags...)
+	if span.Process != nil {
+		tags = append(tags, span.Process.Tags...)
+	}
+	for _, log := range span.Logs {
+		tags = append(tags, log.Fields...)
+	}
+	return tags
+}
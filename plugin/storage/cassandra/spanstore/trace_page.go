@@ -0,0 +1,359 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package spanstore
+
+import (
+	"time"
+
+	"github.com/gocql/gocql"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/cassandra"
+	casMetrics "github.com/uber/jaeger/pkg/cassandra/metrics"
+	"github.com/uber/jaeger/plugin/storage/cassandra/spanstore/dbmodel"
+	"github.com/uber/jaeger/storage/spanstore"
+)
+
+const (
+	// the paged queries read a partition of an index, which Cassandra can page through with the paging state
+	queryPageByTag = `
+		SELECT trace_id
+		FROM tag_index
+		WHERE service_name = ? AND tag_key = ? AND tag_value = ? and start_time > ? and start_time < ?
+		ORDER BY start_time DESC`
+	queryPageByServiceAndOperationName = `
+		SELECT trace_id
+		FROM service_operation_index
+		WHERE service_name = ? AND operation_name = ? AND start_time > ? AND start_time < ?
+		ORDER BY start_time DESC`
+	queryPageByDuration = `
+		SELECT trace_id
+		FROM duration_index
+		WHERE bucket = ? AND service_name = ? AND operation_name = ? AND duration > ? AND duration < ?`
+	// Cassandra cannot page a query with both IN and ORDER BY, so the service name index is paged by start time
+	querySeekByServiceName = `
+		SELECT trace_id, start_time
+		FROM service_name_index
+		WHERE bucket IN ` + bucketRange + ` AND service_name = ? AND start_time > ? AND start_time <= ?
+		ORDER BY start_time DESC
+		LIMIT ?`
+)
+
+// pageCursor is the position of FindTracePage in the index that serves a query. Since the indices
+// have a row per span, the spans of a trace can be spread over several pages: the Skip rows already
+// read from the index page are read again, so that their traces are not repeated on the next page.
+type pageCursor struct {
+	Offset int `json:"offset"`
+	// PageState is the paging state of the index page being read, and Skip the number of its rows already read
+	PageState []byte `json:"pageState,omitempty"`
+	Skip      int    `json:"skip,omitempty"`
+	// Bucket is the duration index bucket being read, in seconds since epoch
+	Bucket int64 `json:"bucket,omitempty"`
+	// StartTime is the upper bound of the start times of the service name index query being read
+	StartTime int64 `json:"startTime,omitempty"`
+}
+
+// traceIDPager reads the trace IDs of a page, skipping the ones in seen, and returns the position
+// after the last trace of the page, or nil if the index has no more rows
+type traceIDPager func(position *pageCursor, numTraces int, seen dbmodel.UniqueTraceIDs) ([]dbmodel.TraceID, *pageCursor, error)
+
+// FindTracePage retrieves a page of the traces that match the traceQuery. Queries served by a single index
+// are paged through it, but queries that intersect several indices or have a TagQuery return a single page.
+// Traces whose spans were read from earlier index pages than the one the cursor points to may be repeated.
+// The traces found by the index for a TagQuery are dropped if none of their spans matches it, so such a page
+// can hold fewer traces than requested.
+// The Total of the page is always spanstore.UnknownTotal.
+func (s *SpanReader) FindTracePage(traceQuery *spanstore.TraceQueryParameters) (*spanstore.TracePage, error) {
+	if err := validateQuery(traceQuery); err != nil {
+		return nil, err
+	}
+	position := &pageCursor{}
+	if traceQuery.Cursor != "" {
+		if err := spanstore.DecodeCursor(traceQuery.Cursor, position); err != nil {
+			return nil, err
+		}
+	}
+	seen := dbmodel.UniqueTraceIDs{}
+	numTraces := traceQuery.NumTraces
+	if numTraces == 0 {
+		numTraces = defaultNumTraces
+	}
+
+	var traceIDs []dbmodel.TraceID
+	var next *pageCursor
+	var err error
+	if pager := s.traceIDPager(traceQuery); pager != nil {
+		traceIDs, next, err = pager(position, numTraces, seen)
+	} else {
+		traceIDs, err = s.findTraceIDList(traceQuery, position, numTraces)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	page := &spanstore.TracePage{
+		Offset: position.Offset,
+		Total:  spanstore.UnknownTotal,
+	}
+	for _, traceID := range traceIDs {
+		trace, err := s.readTrace(traceID)
+		if err != nil {
+			s.logger.Error("Failure to read trace", zap.String("trace_id", traceID.String()), zap.Error(err))
+			continue
+		}
+		if traceQuery.TagQuery != nil && !matchTagQuery(trace, traceQuery.TagQuery) {
+			continue
+		}
+		page.Traces = append(page.Traces, trace)
+	}
+	if next != nil {
+		next.Offset = position.Offset + len(page.Traces)
+		if page.NextCursor, err = spanstore.EncodeCursor(next); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// traceIDPager returns the pager of the index that serves the query on its own, or nil if the query
+// intersects several indices
+func (s *SpanReader) traceIDPager(tq *spanstore.TraceQueryParameters) traceIDPager {
+	startTimeMin := model.TimeAsEpochMicroseconds(tq.StartTimeMin)
+	startTimeMax := model.TimeAsEpochMicroseconds(tq.StartTimeMax)
+	switch {
+	case tq.TagQuery != nil:
+		return nil
+	case (tq.DurationMin != 0 || tq.DurationMax != 0) && len(tq.Tags) == 0:
+		return s.pageByDuration(tq)
+	case tq.DurationMin != 0 || tq.DurationMax != 0:
+		return nil
+	case tq.OperationName != "" && len(tq.Tags) == 0:
+		return s.pageByQuery(func() cassandra.Query {
+			return s.session.Query(queryPageByServiceAndOperationName, tq.ServiceName, tq.OperationName, startTimeMin, startTimeMax)
+		}, s.metrics.queryServiceOperationIndex)
+	case tq.OperationName == "" && len(tq.Tags) == 1:
+		for k, v := range tq.Tags {
+			return s.pageByQuery(func() cassandra.Query {
+				return s.session.Query(queryPageByTag, tq.ServiceName, k, v, startTimeMin, startTimeMax)
+			}, s.metrics.queryTagIndex)
+		}
+	case tq.OperationName == "" && len(tq.Tags) == 0:
+		return s.seekByServiceName(tq)
+	}
+	return nil
+}
+
+// findTraceIDList returns the first page of the trace IDs of a query that cannot be paged
+func (s *SpanReader) findTraceIDList(tq *spanstore.TraceQueryParameters, position *pageCursor, numTraces int) ([]dbmodel.TraceID, error) {
+	if position.Offset != 0 {
+		return nil, spanstore.ErrInvalidCursor
+	}
+	query := *tq
+	query.NumTraces = numTraces
+	uniqueTraceIDs, err := s.findTraceIDs(&query)
+	if err != nil {
+		return nil, err
+	}
+	var traceIDs []dbmodel.TraceID
+	for traceID := range uniqueTraceIDs {
+		if len(traceIDs) == numTraces {
+			break
+		}
+		traceIDs = append(traceIDs, traceID)
+	}
+	return traceIDs, nil
+}
+
+// pageByQuery returns a pager of the rows of a query, using the Cassandra paging state
+func (s *SpanReader) pageByQuery(newQuery func() cassandra.Query, tableMetrics *casMetrics.Table) traceIDPager {
+	return func(position *pageCursor, numTraces int, seen dbmodel.UniqueTraceIDs) ([]dbmodel.TraceID, *pageCursor, error) {
+		var traceIDs []dbmodel.TraceID
+		pageState, skip := position.PageState, position.Skip
+		// only the first paging state comes from the cursor, the next ones are returned by Cassandra
+		fromCursor := len(pageState) > 0
+		for {
+			query := newQuery().PageSize(numTraces * limitMultiple).PageState(pageState)
+			rows, nextPageState, err := s.readIndexPage(query, tableMetrics)
+			if err != nil {
+				if fromCursor && isInvalidPageState(err) {
+					return nil, nil, spanstore.ErrInvalidCursor
+				}
+				return nil, nil, err
+			}
+			fromCursor = false
+			for i := 0; i < len(rows); i++ {
+				if i < skip {
+					seen.Add(rows[i])
+					continue
+				}
+				if _, ok := seen[rows[i]]; ok {
+					continue
+				}
+				seen.Add(rows[i])
+				traceIDs = append(traceIDs, rows[i])
+				if len(traceIDs) == numTraces {
+					return traceIDs, &pageCursor{PageState: pageState, Skip: i + 1}, nil
+				}
+			}
+			if len(nextPageState) == 0 {
+				return traceIDs, nil, nil
+			}
+			pageState, skip = nextPageState, 0
+		}
+	}
+}
+
+// pageByDuration returns a pager of the duration index, which is read bucket by bucket, newest first
+func (s *SpanReader) pageByDuration(tq *spanstore.TraceQueryParameters) traceIDPager {
+	minDurationMicros := model.DurationAsMicroseconds(tq.DurationMin)
+	maxDurationMicros := model.DurationAsMicroseconds(time.Hour * 24)
+	if tq.DurationMax != 0 {
+		maxDurationMicros = model.DurationAsMicroseconds(tq.DurationMax)
+	}
+	startTimeByHour := tq.StartTimeMin.Round(durationBucketSize)
+	endTimeByHour := tq.StartTimeMax.Round(durationBucketSize)
+
+	return func(position *pageCursor, numTraces int, seen dbmodel.UniqueTraceIDs) ([]dbmodel.TraceID, *pageCursor, error) {
+		var traceIDs []dbmodel.TraceID
+		timeBucket := endTimeByHour
+		if position.Bucket != 0 {
+			timeBucket = time.Unix(position.Bucket, 0)
+		}
+		bucketPosition := &pageCursor{PageState: position.PageState, Skip: position.Skip}
+		for ; !timeBucket.Before(startTimeByHour); timeBucket = timeBucket.Add(-1 * durationBucketSize) {
+			bucket := timeBucket
+			pager := s.pageByQuery(func() cassandra.Query {
+				return s.session.Query(queryPageByDuration, bucket, tq.ServiceName, tq.OperationName, minDurationMicros, maxDurationMicros)
+			}, s.metrics.queryDurationIndex)
+			bucketTraceIDs, next, err := pager(bucketPosition, numTraces-len(traceIDs), seen)
+			if err != nil {
+				return nil, nil, err
+			}
+			traceIDs = append(traceIDs, bucketTraceIDs...)
+			if next != nil {
+				next.Bucket = bucket.Unix()
+				return traceIDs, next, nil
+			}
+			bucketPosition = &pageCursor{}
+		}
+		return traceIDs, nil, nil
+	}
+}
+
+// seekByServiceName returns a pager of the service name index, which continues each page from the start time
+// of the last row of the previous one. The rows of that start time already read are skipped by the next page.
+func (s *SpanReader) seekByServiceName(tq *spanstore.TraceQueryParameters) traceIDPager {
+	startTimeMin := int64(model.TimeAsEpochMicroseconds(tq.StartTimeMin))
+	return func(position *pageCursor, numTraces int, seen dbmodel.UniqueTraceIDs) ([]dbmodel.TraceID, *pageCursor, error) {
+		var traceIDs []dbmodel.TraceID
+		startTimeMax := int64(model.TimeAsEpochMicroseconds(tq.StartTimeMax))
+		skip := 0
+		if position.StartTime != 0 {
+			startTimeMax, skip = position.StartTime, position.Skip
+		}
+		limit := numTraces * limitMultiple
+		if skip >= limit {
+			// the previous page was read with a larger number of traces
+			limit = skip + numTraces
+		}
+		for {
+			query := s.session.Query(querySeekByServiceName, tq.ServiceName, startTimeMin, startTimeMax, limit)
+			rows, startTimes, err := s.readIndexRows(query, s.metrics.queryServiceNameIndex)
+			if err != nil {
+				return nil, nil, err
+			}
+			for i := range rows {
+				if i < skip {
+					seen.Add(rows[i])
+					continue
+				}
+				if _, ok := seen[rows[i]]; ok {
+					continue
+				}
+				seen.Add(rows[i])
+				traceIDs = append(traceIDs, rows[i])
+				if len(traceIDs) == numTraces {
+					return traceIDs, &pageCursor{StartTime: startTimeMax, Skip: i + 1}, nil
+				}
+			}
+			if len(rows) < limit {
+				return traceIDs, nil, nil
+			}
+			if lastStartTime := startTimes[len(rows)-1]; lastStartTime < startTimeMax {
+				startTimeMax, skip = lastStartTime, 0
+			} else {
+				// all the rows have the start time of the window, which is read again with more rows
+				// so that the skipped rows break the tie
+				skip = len(rows)
+				limit = skip + numTraces*limitMultiple
+			}
+		}
+	}
+}
+
+// isInvalidPageState returns true if Cassandra rejected a query because of its paging state, which
+// happens when the paging state of a cursor was altered or was created by another query or cluster version
+func isInvalidPageState(err error) bool {
+	if requestErr, ok := err.(gocql.RequestError); ok {
+		return requestErr.Code() == gocql.ErrCodeProtocol || requestErr.Code() == gocql.ErrCodeInvalid
+	}
+	return false
+}
+
+func (s *SpanReader) readIndexPage(query cassandra.Query, tableMetrics *casMetrics.Table) ([]dbmodel.TraceID, []byte, error) {
+	start := time.Now()
+	i := query.Consistency(s.consistency).Iter()
+	var traceIDs []dbmodel.TraceID
+	var traceID dbmodel.TraceID
+	for i.Scan(&traceID) {
+		traceIDs = append(traceIDs, traceID)
+	}
+	pageState := i.PageState()
+	err := i.Close()
+	tableMetrics.Emit(err, time.Since(start))
+	if err != nil {
+		s.logger.Error("Failed to exec query", zap.Error(err))
+		return nil, nil, err
+	}
+	return traceIDs, pageState, nil
+}
+
+func (s *SpanReader) readIndexRows(query cassandra.Query, tableMetrics *casMetrics.Table) ([]dbmodel.TraceID, []int64, error) {
+	start := time.Now()
+	i := query.Consistency(s.consistency).Iter()
+	var traceIDs []dbmodel.TraceID
+	var startTimes []int64
+	var traceID dbmodel.TraceID
+	var startTime int64
+	for i.Scan(&traceID, &startTime) {
+		traceIDs = append(traceIDs, traceID)
+		startTimes = append(startTimes, startTime)
+	}
+	err := i.Close()
+	tableMetrics.Emit(err, time.Since(start))
+	if err != nil {
+		s.logger.Error("Failed to exec query", zap.Error(err))
+		return nil, nil, err
+	}
+	return traceIDs, startTimes, nil
+}
@@ -0,0 +1,358 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package spanstore
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/cassandra"
+	"github.com/uber/jaeger/pkg/cassandra/mocks"
+	"github.com/uber/jaeger/plugin/storage/cassandra/spanstore/dbmodel"
+	"github.com/uber/jaeger/storage/spanstore"
+)
+
+func testTraceID(low uint64) dbmodel.TraceID {
+	return dbmodel.TraceIDFromDomain(model.TraceID{Low: low})
+}
+
+// rowIterator returns an iterator over the rows, which hold trace IDs and start times
+func rowIterator(rows [][]interface{}, pageState []byte, closeErr error) *mocks.Iterator {
+	iter := &mocks.Iterator{}
+	iter.On("Scan", matchEverything()).Return(func(dest ...interface{}) bool {
+		if len(rows) == 0 {
+			return false
+		}
+		for i, v := range rows[0] {
+			switch ptr := dest[i].(type) {
+			case *dbmodel.TraceID:
+				*ptr = v.(dbmodel.TraceID)
+			case *int64:
+				*ptr = v.(int64)
+			}
+		}
+		rows = rows[1:]
+		return true
+	})
+	iter.On("PageState").Return(pageState)
+	iter.On("Close").Return(closeErr)
+	return iter
+}
+
+func iteratorQuery(iter cassandra.Iterator) *mocks.Query {
+	query := &mocks.Query{}
+	query.On("Consistency", cassandra.One).Return(query)
+	query.On("Iter").Return(iter)
+	return query
+}
+
+// pagedQuery returns a query whose iterators are looked up by page state
+func pagedQuery(pages map[string]cassandra.Iterator) *mocks.Query {
+	query := &mocks.Query{}
+	query.On("PageSize", mock.Anything).Return(query)
+	query.On("PageState", mock.Anything).Return(func(state []byte) cassandra.Query {
+		return iteratorQuery(pages[string(state)])
+	})
+	return query
+}
+
+// withTraces makes each trace read by the reader hold a single span
+func withTraces(r *spanReaderTest) {
+	r.session.On("Query", stringMatcher("FROM traces"), matchEverything()).Return(
+		func(stmt string, values ...interface{}) cassandra.Query {
+			row := []interface{}{values[0].(dbmodel.TraceID)}
+			return iteratorQuery(rowIterator([][]interface{}{row}, nil, nil))
+		})
+}
+
+// withTaggedTraces makes each trace read by the reader hold a span for each of the tag lists of its trace ID
+func withTaggedTraces(r *spanReaderTest, spanTags map[uint64][][]dbmodel.KeyValue) {
+	r.session.On("Query", stringMatcher("FROM traces"), matchEverything()).Return(
+		func(stmt string, values ...interface{}) cassandra.Query {
+			traceID := values[0].(dbmodel.TraceID)
+			tags := spanTags[traceID.ToDomain().Low]
+			iter := &mocks.Iterator{}
+			iter.On("Scan", matchEverything()).Return(func(dest ...interface{}) bool {
+				if len(tags) == 0 {
+					return false
+				}
+				*dest[0].(*dbmodel.TraceID) = traceID
+				*dest[7].(*[]dbmodel.KeyValue) = tags[0]
+				tags = tags[1:]
+				return true
+			})
+			iter.On("Close").Return(nil)
+			return iteratorQuery(iter)
+		})
+}
+
+func stringTag(key, value string) dbmodel.KeyValue {
+	return dbmodel.KeyValue{Key: key, ValueType: "string", ValueString: value}
+}
+
+// readAllPages follows the cursors of FindTracePage and returns the trace IDs and offset of each page
+func readAllPages(t *testing.T, r *spanReaderTest, query *spanstore.TraceQueryParameters) ([][]uint64, []int) {
+	var pages [][]uint64
+	var offsets []int
+	for len(pages) < 10 {
+		page, err := r.reader.FindTracePage(query)
+		require.NoError(t, err)
+		assert.Equal(t, spanstore.UnknownTotal, page.Total)
+		traceIDs := []uint64{}
+		for _, trace := range page.Traces {
+			traceIDs = append(traceIDs, trace.Spans[0].TraceID.Low)
+		}
+		pages = append(pages, traceIDs)
+		offsets = append(offsets, page.Offset)
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	return pages, offsets
+}
+
+func TestSpanReaderFindTracePageByTag(t *testing.T) {
+	withSpanReader(func(r *spanReaderTest) {
+		query := pagedQuery(map[string]cassandra.Iterator{
+			"": rowIterator([][]interface{}{
+				{testTraceID(1)}, {testTraceID(2)}, {testTraceID(1)},
+			}, []byte("p2"), nil),
+			"p2": rowIterator([][]interface{}{{testTraceID(3)}}, nil, nil),
+		})
+		r.session.On("Query", stringMatcher("FROM tag_index"), matchEverything()).Return(query)
+		withTraces(r)
+
+		pages, offsets := readAllPages(t, r, &spanstore.TraceQueryParameters{
+			ServiceName:  "service-a",
+			Tags:         map[string]string{"k": "v"},
+			StartTimeMin: time.Now().Add(-time.Hour),
+			StartTimeMax: time.Now(),
+			NumTraces:    2,
+		})
+		assert.Equal(t, [][]uint64{{1, 2}, {3}}, pages)
+		assert.Equal(t, []int{0, 2}, offsets)
+	})
+}
+
+func TestSpanReaderFindTracePageByTagQuery(t *testing.T) {
+	withSpanReader(func(r *spanReaderTest) {
+		traceIDsByTagValue := map[string][]dbmodel.TraceID{
+			"v1": {testTraceID(1), testTraceID(2), testTraceID(4)},
+			"v2": {testTraceID(2), testTraceID(3), testTraceID(4)},
+		}
+		r.session.On("Query", stringMatcher("FROM tag_index"), matchEverything()).Return(
+			func(stmt string, values ...interface{}) cassandra.Query {
+				var rows [][]interface{}
+				for _, traceID := range traceIDsByTagValue[values[2].(string)] {
+					rows = append(rows, []interface{}{traceID})
+				}
+				query := iteratorQuery(rowIterator(rows, nil, nil))
+				query.On("PageSize", 0).Return(query)
+				return query
+			})
+		// the tags of trace 4 are matched by different spans, so it does not match the AND
+		withTaggedTraces(r, map[uint64][][]dbmodel.KeyValue{
+			1: {{stringTag("k", "v1")}},
+			2: {{stringTag("k", "v1"), stringTag("k", "v2")}},
+			3: {{stringTag("k", "v2")}},
+			4: {{stringTag("k", "v1")}, {stringTag("k", "v2")}},
+		})
+
+		pages, offsets := readAllPages(t, r, &spanstore.TraceQueryParameters{
+			ServiceName: "service-a",
+			TagQuery: &spanstore.TagAnd{Queries: []spanstore.TagQuery{
+				&spanstore.TagEquals{Key: "k", Value: "v1"},
+				&spanstore.TagEquals{Key: "k", Value: "v2"},
+			}},
+			StartTimeMin: time.Now().Add(-time.Hour),
+			StartTimeMax: time.Now(),
+			NumTraces:    2,
+		})
+		assert.Equal(t, [][]uint64{{2}}, pages)
+		assert.Equal(t, []int{0}, offsets)
+	})
+}
+
+func TestSpanReaderFindTracePageByServiceName(t *testing.T) {
+	withSpanReader(func(r *spanReaderTest) {
+		rows := [][]interface{}{
+			{testTraceID(1), int64(300)},
+			{testTraceID(1), int64(250)},
+			{testTraceID(2), int64(200)},
+		}
+		r.session.On("Query", stringMatcher("FROM service_name_index"), matchEverything()).Return(
+			func(stmt string, values ...interface{}) cassandra.Query {
+				startTimeMax, limit := values[2].(int64), values[3].(int)
+				var matches [][]interface{}
+				for _, row := range rows {
+					if row[1].(int64) <= startTimeMax && len(matches) < limit {
+						matches = append(matches, row)
+					}
+				}
+				return iteratorQuery(rowIterator(matches, nil, nil))
+			})
+		withTraces(r)
+
+		pages, offsets := readAllPages(t, r, &spanstore.TraceQueryParameters{
+			ServiceName:  "service-a",
+			StartTimeMin: time.Unix(0, 0),
+			StartTimeMax: time.Unix(0, 400000),
+			NumTraces:    1,
+		})
+		assert.Equal(t, [][]uint64{{1}, {2}, {}}, pages)
+		assert.Equal(t, []int{0, 1, 2}, offsets)
+	})
+}
+
+func TestSpanReaderFindTracePageByServiceNameSameStartTime(t *testing.T) {
+	withSpanReader(func(r *spanReaderTest) {
+		// the spans of trace 1 fill the limit of the index query, in several buckets at the same start time
+		rows := [][]interface{}{
+			{testTraceID(1), int64(300)},
+			{testTraceID(1), int64(300)},
+			{testTraceID(1), int64(300)},
+			{testTraceID(2), int64(300)},
+			{testTraceID(3), int64(300)},
+			{testTraceID(4), int64(200)},
+		}
+		r.session.On("Query", stringMatcher("FROM service_name_index"), matchEverything()).Return(
+			func(stmt string, values ...interface{}) cassandra.Query {
+				startTimeMax, limit := values[2].(int64), values[3].(int)
+				var matches [][]interface{}
+				for _, row := range rows {
+					if row[1].(int64) <= startTimeMax && len(matches) < limit {
+						matches = append(matches, row)
+					}
+				}
+				return iteratorQuery(rowIterator(matches, nil, nil))
+			})
+		withTraces(r)
+
+		pages, offsets := readAllPages(t, r, &spanstore.TraceQueryParameters{
+			ServiceName:  "service-a",
+			StartTimeMin: time.Unix(0, 0),
+			StartTimeMax: time.Unix(0, 300000),
+			NumTraces:    1,
+		})
+		assert.Equal(t, [][]uint64{{1}, {2}, {3}, {4}, {}}, pages)
+		assert.Equal(t, []int{0, 1, 2, 3, 4}, offsets)
+	})
+}
+
+func TestSpanReaderFindTracePageQueryError(t *testing.T) {
+	withSpanReader(func(r *spanReaderTest) {
+		query := pagedQuery(map[string]cassandra.Iterator{
+			"": rowIterator(nil, nil, errors.New("query error")),
+		})
+		r.session.On("Query", stringMatcher("FROM service_operation_index"), matchEverything()).Return(query)
+
+		page, err := r.reader.FindTracePage(&spanstore.TraceQueryParameters{
+			ServiceName:   "service-a",
+			OperationName: "operation-a",
+			StartTimeMin:  time.Now().Add(-time.Hour),
+			StartTimeMax:  time.Now(),
+		})
+		assert.EqualError(t, err, "query error")
+		assert.Nil(t, page)
+	})
+}
+
+// requestError is a Cassandra error response
+type requestError int
+
+func (e requestError) Code() int       { return int(e) }
+func (e requestError) Message() string { return "request error" }
+func (e requestError) Error() string   { return "request error" }
+
+func TestSpanReaderFindTracePageInvalidCursor(t *testing.T) {
+	badPageState, err := spanstore.EncodeCursor(pageCursor{PageState: []byte("bad"), Skip: 1})
+	require.NoError(t, err)
+	secondPage, err := spanstore.EncodeCursor(pageCursor{Offset: 10})
+	require.NoError(t, err)
+	testCases := []struct {
+		caption string
+		cursor  string
+		tags    map[string]string
+	}{
+		{caption: "undecodable cursor", cursor: "!"},
+		{caption: "invalid paging state", cursor: badPageState},
+		{caption: "query without pages", cursor: secondPage, tags: map[string]string{"k": "v"}},
+	}
+	for _, tc := range testCases {
+		testCase := tc // capture loop var
+		t.Run(testCase.caption, func(t *testing.T) {
+			withSpanReader(func(r *spanReaderTest) {
+				query := pagedQuery(map[string]cassandra.Iterator{
+					"bad": rowIterator(nil, nil, requestError(gocql.ErrCodeProtocol)),
+				})
+				r.session.On("Query", stringMatcher("FROM service_operation_index"), matchEverything()).Return(query)
+				page, err := r.reader.FindTracePage(&spanstore.TraceQueryParameters{
+					ServiceName:   "service-a",
+					OperationName: "operation-a",
+					Tags:          testCase.tags,
+					StartTimeMin:  time.Now().Add(-time.Hour),
+					StartTimeMax:  time.Now(),
+					Cursor:        testCase.cursor,
+				})
+				assert.Equal(t, spanstore.ErrInvalidCursor, err)
+				assert.Nil(t, page)
+			})
+		})
+	}
+}
+
+func TestSpanReaderFindTracePageCursor(t *testing.T) {
+	withSpanReader(func(r *spanReaderTest) {
+		query := pagedQuery(map[string]cassandra.Iterator{
+			"p1": rowIterator([][]interface{}{
+				{testTraceID(1)}, {testTraceID(2)}, {testTraceID(1)}, {testTraceID(3)},
+			}, nil, nil),
+		})
+		r.session.On("Query", stringMatcher("FROM service_operation_index"), matchEverything()).Return(query)
+		withTraces(r)
+
+		cursor, err := spanstore.EncodeCursor(pageCursor{Offset: 2, PageState: []byte("p1"), Skip: 2})
+		require.NoError(t, err)
+		page, err := r.reader.FindTracePage(&spanstore.TraceQueryParameters{
+			ServiceName:   "service-a",
+			OperationName: "operation-a",
+			StartTimeMin:  time.Now().Add(-time.Hour),
+			StartTimeMax:  time.Now(),
+			NumTraces:     1,
+			Cursor:        cursor,
+		})
+		require.NoError(t, err)
+		require.Len(t, page.Traces, 1)
+		assert.Equal(t, uint64(3), page.Traces[0].Spans[0].TraceID.Low)
+		var next pageCursor
+		require.NoError(t, spanstore.DecodeCursor(page.NextCursor, &next))
+		assert.Equal(t, pageCursor{Offset: 3, PageState: []byte("p1"), Skip: 4}, next)
+	})
+}
@@ -0,0 +1,209 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package archivestore
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/olivere/elastic"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/es"
+	"github.com/uber/jaeger/storage/archivestore"
+)
+
+const (
+	archiveType  = "archive"
+	archiveIndex = "jaeger-archives"
+
+	traceIDField       = "traceID"
+	authorField        = "author"
+	archivedAtField    = "archivedAt"
+	noteLowercaseField = "noteLowercase"
+	labelsField        = "labels"
+
+	defaultArchivesLimit = 10000 // the default elasticsearch allowed limit
+)
+
+var wildcardEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`)
+
+// archiveDocument is the JSON representation of an archivestore.Archive, with a lowercase copy of the note
+// so that it can be searched ignoring case
+type archiveDocument struct {
+	TraceID       string   `json:"traceID"`
+	Author        string   `json:"author"`
+	ArchivedAt    uint64   `json:"archivedAt"`
+	Note          string   `json:"note"`
+	NoteLowercase string   `json:"noteLowercase"`
+	Labels        []string `json:"labels"`
+}
+
+// ArchiveStore handles all queries and insertions to the ElasticSearch archives index
+type ArchiveStore struct {
+	ctx    context.Context
+	client es.Client
+	logger *zap.Logger
+}
+
+// NewArchiveStore returns an ArchiveStore
+func NewArchiveStore(client es.Client, logger *zap.Logger) *ArchiveStore {
+	return &ArchiveStore{
+		ctx:    context.Background(),
+		client: client,
+		logger: logger,
+	}
+}
+
+// WriteArchive implements archivestore.Writer#WriteArchive. The document ID is the trace ID, so that
+// archiving a trace again replaces its metadata.
+func (s *ArchiveStore) WriteArchive(archive *archivestore.Archive) error {
+	if err := s.createIndex(); err != nil {
+		return err
+	}
+	traceID := archive.TraceID.String()
+	_, err := s.client.Index().Index(archiveIndex).
+		Type(archiveType).
+		Id(traceID).
+		BodyJson(&archiveDocument{
+			TraceID:       traceID,
+			Author:        archive.Author,
+			ArchivedAt:    model.TimeAsEpochMicroseconds(archive.ArchivedAt),
+			Note:          archive.Note,
+			NoteLowercase: strings.ToLower(archive.Note),
+			Labels:        archive.Labels,
+		}).
+		Do(s.ctx)
+	if err != nil {
+		return errors.Wrap(err, "Failed to write archive")
+	}
+	return nil
+}
+
+func (s *ArchiveStore) createIndex() error {
+	// the error is not checked because exists is false anyway if there is an error
+	if exists, _ := s.client.IndexExists(archiveIndex).Do(s.ctx); exists {
+		return nil
+	}
+	if _, err := s.client.CreateIndex(archiveIndex).Body(archivesMapping).Do(s.ctx); err != nil {
+		return errors.Wrap(err, "Failed to create index")
+	}
+	return nil
+}
+
+// DeleteArchive implements archivestore.Writer#DeleteArchive.
+func (s *ArchiveStore) DeleteArchive(traceID model.TraceID) error {
+	_, err := s.client.Delete().Index(archiveIndex).
+		Type(archiveType).
+		Id(traceID.String()).
+		Do(s.ctx)
+	if elastic.IsNotFound(err) {
+		return archivestore.ErrArchiveNotFound
+	}
+	if err != nil {
+		return errors.Wrap(err, "Failed to delete archive")
+	}
+	return nil
+}
+
+// GetArchive implements archivestore.Reader#GetArchive.
+func (s *ArchiveStore) GetArchive(traceID model.TraceID) (*archivestore.Archive, error) {
+	archives, err := s.search(elastic.NewTermQuery(traceIDField, traceID.String()), 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(archives) == 0 {
+		return nil, archivestore.ErrArchiveNotFound
+	}
+	return archives[0], nil
+}
+
+// FindArchives implements archivestore.Reader#FindArchives.
+func (s *ArchiveStore) FindArchives(query *archivestore.Query) ([]*archivestore.Archive, error) {
+	limit := defaultArchivesLimit
+	if query.Limit > 0 && query.Limit < limit {
+		limit = query.Limit
+	}
+	return s.search(buildArchivesQuery(query), limit)
+}
+
+func (s *ArchiveStore) search(query elastic.Query, size int) ([]*archivestore.Archive, error) {
+	searchResult, err := s.client.Search(archiveIndex).
+		Type(archiveType).
+		Size(size).
+		Query(query).
+		Sort(archivedAtField, false).
+		Sort(traceIDField, true).
+		IgnoreUnavailable(true).
+		Do(s.ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to search for archives")
+	}
+
+	archives := make([]*archivestore.Archive, 0, len(searchResult.Hits.Hits))
+	for _, hit := range searchResult.Hits.Hits {
+		var document archiveDocument
+		if err := json.Unmarshal(*hit.Source, &document); err != nil {
+			return nil, errors.New("Unmarshalling ElasticSearch documents failed")
+		}
+		traceID, err := model.TraceIDFromString(document.TraceID)
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid trace ID in ElasticSearch document")
+		}
+		archives = append(archives, &archivestore.Archive{
+			TraceID:    traceID,
+			Author:     document.Author,
+			ArchivedAt: model.EpochMicrosecondsAsTime(document.ArchivedAt),
+			Note:       document.Note,
+			Labels:     document.Labels,
+		})
+	}
+	return archives, nil
+}
+
+func buildArchivesQuery(query *archivestore.Query) elastic.Query {
+	boolQuery := elastic.NewBoolQuery()
+	if query.Author != "" {
+		boolQuery.Must(elastic.NewTermQuery(authorField, query.Author))
+	}
+	for _, label := range query.Labels {
+		boolQuery.Must(elastic.NewTermQuery(labelsField, label))
+	}
+	if query.Text != "" {
+		pattern := "*" + wildcardEscaper.Replace(strings.ToLower(query.Text)) + "*"
+		boolQuery.Must(elastic.NewWildcardQuery(noteLowercaseField, pattern))
+	}
+	if !query.ArchivedAfter.IsZero() || !query.ArchivedBefore.IsZero() {
+		rangeQuery := elastic.NewRangeQuery(archivedAtField)
+		if !query.ArchivedAfter.IsZero() {
+			rangeQuery.Gte(model.TimeAsEpochMicroseconds(query.ArchivedAfter))
+		}
+		if !query.ArchivedBefore.IsZero() {
+			rangeQuery.Lte(model.TimeAsEpochMicroseconds(query.ArchivedBefore))
+		}
+		boolQuery.Must(rangeQuery)
+	}
+	return boolQuery
+}
@@ -0,0 +1,292 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package archivestore
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/olivere/elastic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/es/mocks"
+	"github.com/uber/jaeger/pkg/testutils"
+	"github.com/uber/jaeger/storage/archivestore"
+)
+
+type archiveStorageTest struct {
+	client    *mocks.Client
+	logger    *zap.Logger
+	logBuffer *testutils.Buffer
+	storage   *ArchiveStore
+}
+
+func withArchiveStorage(fn func(r *archiveStorageTest)) {
+	client := &mocks.Client{}
+	logger, logBuffer := testutils.NewLogger()
+	r := &archiveStorageTest{
+		client:    client,
+		logger:    logger,
+		logBuffer: logBuffer,
+		storage:   NewArchiveStore(client, logger),
+	}
+	fn(r)
+}
+
+var _ archivestore.Reader = &ArchiveStore{} // check API conformance
+var _ archivestore.Writer = &ArchiveStore{} // check API conformance
+
+var (
+	testingTraceID = model.TraceID{High: 1, Low: 2}
+	testingArchive = &archivestore.Archive{
+		TraceID:    testingTraceID,
+		Author:     "alice",
+		ArchivedAt: model.EpochMicrosecondsAsTime(100000005),
+		Note:       "Slow Checkout",
+		Labels:     []string{"latency"},
+	}
+	testingDocument = &archiveDocument{
+		TraceID:       "10000000000000002",
+		Author:        "alice",
+		ArchivedAt:    100000005,
+		Note:          "Slow Checkout",
+		NoteLowercase: "slow checkout",
+		Labels:        []string{"latency"},
+	}
+)
+
+func TestWriteArchive(t *testing.T) {
+	testCases := []struct {
+		indexExists      bool
+		createIndexError error
+		writeError       error
+		expectedError    string
+	}{
+		{
+			createIndexError: errors.New("index not created"),
+			expectedError:    "Failed to create index: index not created",
+		},
+		{
+			indexExists:   true,
+			writeError:    errors.New("write failed"),
+			expectedError: "Failed to write archive: write failed",
+		},
+		{},
+		{indexExists: true},
+	}
+	for _, testCase := range testCases {
+		withArchiveStorage(func(r *archiveStorageTest) {
+			existsService := &mocks.IndicesExistsService{}
+			indexService := &mocks.IndicesCreateService{}
+			writeService := &mocks.IndexService{}
+			r.client.On("IndexExists", archiveIndex).Return(existsService)
+			r.client.On("CreateIndex", archiveIndex).Return(indexService)
+			r.client.On("Index").Return(writeService)
+
+			existsService.On("Do", mock.Anything).Return(testCase.indexExists, nil)
+			indexService.On("Body", archivesMapping).Return(indexService)
+			indexService.On("Do", mock.Anything).Return(nil, testCase.createIndexError)
+
+			writeService.On("Index", archiveIndex).Return(writeService)
+			writeService.On("Type", archiveType).Return(writeService)
+			writeService.On("Id", testingDocument.TraceID).Return(writeService)
+			writeService.On("BodyJson", testingDocument).Return(writeService)
+			writeService.On("Do", mock.Anything).Return(nil, testCase.writeError)
+
+			err := r.storage.WriteArchive(testingArchive)
+			if testCase.expectedError != "" {
+				assert.EqualError(t, err, testCase.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+			if testCase.indexExists {
+				r.client.AssertNotCalled(t, "CreateIndex", archiveIndex)
+			}
+		})
+	}
+}
+
+func TestDeleteArchive(t *testing.T) {
+	testCases := []struct {
+		deleteError   error
+		expectedError string
+	}{
+		{},
+		{
+			deleteError:   &elastic.Error{Status: 404},
+			expectedError: archivestore.ErrArchiveNotFound.Error(),
+		},
+		{
+			deleteError:   errors.New("delete failed"),
+			expectedError: "Failed to delete archive: delete failed",
+		},
+	}
+	for _, testCase := range testCases {
+		withArchiveStorage(func(r *archiveStorageTest) {
+			deleteService := &mocks.DeleteService{}
+			r.client.On("Delete").Return(deleteService)
+			deleteService.On("Index", archiveIndex).Return(deleteService)
+			deleteService.On("Type", archiveType).Return(deleteService)
+			deleteService.On("Id", testingDocument.TraceID).Return(deleteService)
+			deleteService.On("Do", mock.Anything).Return(nil, testCase.deleteError)
+
+			err := r.storage.DeleteArchive(testingTraceID)
+			if testCase.expectedError != "" {
+				assert.EqualError(t, err, testCase.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func mockSearchService(r *archiveStorageTest, size int, query elastic.Query) *mock.Call {
+	searchService := &mocks.SearchService{}
+	searchService.On("Type", archiveType).Return(searchService)
+	searchService.On("Size", size).Return(searchService)
+	searchService.On("Query", query).Return(searchService)
+	searchService.On("Sort", archivedAtField, false).Return(searchService)
+	searchService.On("Sort", traceIDField, true).Return(searchService)
+	searchService.On("IgnoreUnavailable", true).Return(searchService)
+	r.client.On("Search", archiveIndex).Return(searchService)
+	return searchService.On("Do", mock.Anything)
+}
+
+func searchResult(documents ...interface{}) *elastic.SearchResult {
+	hits := make([]*elastic.SearchHit, len(documents))
+	for i, document := range documents {
+		source, _ := json.Marshal(document)
+		rawMessage := json.RawMessage(source)
+		hits[i] = &elastic.SearchHit{Source: &rawMessage}
+	}
+	return &elastic.SearchResult{Hits: &elastic.SearchHits{Hits: hits}}
+}
+
+func TestGetArchive(t *testing.T) {
+	testCases := []struct {
+		caption       string
+		result        *elastic.SearchResult
+		searchError   error
+		expected      *archivestore.Archive
+		expectedError string
+	}{
+		{
+			caption:  "found",
+			result:   searchResult(testingDocument),
+			expected: testingArchive,
+		},
+		{
+			caption:       "not found",
+			result:        searchResult(),
+			expectedError: archivestore.ErrArchiveNotFound.Error(),
+		},
+		{
+			caption:       "search error",
+			searchError:   errors.New("search failed"),
+			expectedError: "Failed to search for archives: search failed",
+		},
+		{
+			caption:       "invalid document",
+			result:        searchResult("not an archive"),
+			expectedError: "Unmarshalling ElasticSearch documents failed",
+		},
+		{
+			caption:       "invalid trace ID",
+			result:        searchResult(&archiveDocument{TraceID: "x"}),
+			expectedError: `Invalid trace ID in ElasticSearch document: strconv.ParseUint: parsing "x": invalid syntax`,
+		},
+	}
+	for _, tc := range testCases {
+		testCase := tc // capture loop var
+		t.Run(testCase.caption, func(t *testing.T) {
+			withArchiveStorage(func(r *archiveStorageTest) {
+				mockSearchService(r, 1, elastic.NewTermQuery(traceIDField, testingDocument.TraceID)).
+					Return(testCase.result, testCase.searchError)
+
+				archive, err := r.storage.GetArchive(testingTraceID)
+				if testCase.expectedError != "" {
+					assert.EqualError(t, err, testCase.expectedError)
+				} else {
+					require.NoError(t, err)
+					assert.Equal(t, testCase.expected, archive)
+				}
+			})
+		})
+	}
+}
+
+func TestFindArchives(t *testing.T) {
+	withArchiveStorage(func(r *archiveStorageTest) {
+		query := &archivestore.Query{Limit: 5}
+		mockSearchService(r, 5, buildArchivesQuery(query)).Return(searchResult(testingDocument), nil)
+
+		archives, err := r.storage.FindArchives(query)
+		require.NoError(t, err)
+		assert.Equal(t, []*archivestore.Archive{testingArchive}, archives)
+	})
+}
+
+func TestFindArchivesDefaultLimit(t *testing.T) {
+	withArchiveStorage(func(r *archiveStorageTest) {
+		query := &archivestore.Query{}
+		mockSearchService(r, defaultArchivesLimit, buildArchivesQuery(query)).Return(searchResult(), nil)
+
+		archives, err := r.storage.FindArchives(query)
+		require.NoError(t, err)
+		assert.Empty(t, archives)
+	})
+}
+
+func TestBuildArchivesQuery(t *testing.T) {
+	query := buildArchivesQuery(&archivestore.Query{
+		Author:         "alice",
+		Labels:         []string{"latency", "checkout"},
+		Text:           "Slow*",
+		ArchivedAfter:  model.EpochMicrosecondsAsTime(100),
+		ArchivedBefore: model.EpochMicrosecondsAsTime(200),
+	})
+	source, err := query.Source()
+	require.NoError(t, err)
+	actual, err := json.Marshal(source)
+	require.NoError(t, err)
+	expected := `{"bool":{"must":[` +
+		`{"term":{"author":"alice"}},` +
+		`{"term":{"labels":"latency"}},` +
+		`{"term":{"labels":"checkout"}},` +
+		`{"wildcard":{"noteLowercase":{"wildcard":"*slow\\**"}}},` +
+		`{"range":{"archivedAt":{"from":100,"include_lower":true,"include_upper":true,"to":200}}}` +
+		`]}}`
+	assert.Equal(t, expected, string(actual))
+}
+
+func TestBuildArchivesQueryEmpty(t *testing.T) {
+	source, err := buildArchivesQuery(&archivestore.Query{}).Source()
+	require.NoError(t, err)
+	actual, err := json.Marshal(source)
+	require.NoError(t, err)
+	assert.Equal(t, `{"bool":{}}`, string(actual))
+}
@@ -72,6 +72,11 @@ func (s *DependencyStore) WriteDependencies(ts time.Time, dependencies []model.D
 }
 
 func (s *DependencyStore) createIndex(indexName string) error {
+	// the index already exists if the dependencies are written more than once a day,
+	// the error is not checked because exists is false anyway if there is an error
+	if exists, _ := s.client.IndexExists(indexName).Do(s.ctx); exists {
+		return nil
+	}
 	_, err := s.client.CreateIndex(indexName).Body(dependenciesMapping).Do(s.ctx)
 	if err != nil {
 		return errors.Wrap(err, "Failed to create index")
@@ -62,6 +62,7 @@ var _ dependencystore.Writer = &DependencyStore{} // check API conformance
 
 func TestWriteDependencies(t *testing.T) {
 	testCases := []struct {
+		indexExists      bool
 		createIndexError error
 		writeError       error
 		expectedError    string
@@ -75,17 +76,21 @@ func TestWriteDependencies(t *testing.T) {
 			expectedError: "Failed to write dependencies: write failed",
 		},
 		{},
+		{indexExists: true},
 	}
 	for _, testCase := range testCases {
 		withDepStorage(func(r *depStorageTest) {
 			fixedTime := time.Date(1995, time.April, 21, 4, 21, 19, 95, time.UTC)
 			indexName := indexName(fixedTime)
 
+			existsService := &mocks.IndicesExistsService{}
 			indexService := &mocks.IndicesCreateService{}
 			writeService := &mocks.IndexService{}
 			r.client.On("Index").Return(writeService)
+			r.client.On("IndexExists", stringMatcher(indexName)).Return(existsService)
 			r.client.On("CreateIndex", stringMatcher(indexName)).Return(indexService)
 
+			existsService.On("Do", mock.Anything).Return(testCase.indexExists, nil)
 			indexService.On("Body", stringMatcher(dependenciesMapping)).Return(indexService)
 			indexService.On("Do", mock.Anything).Return(nil, testCase.createIndexError)
 
@@ -100,6 +105,9 @@ func TestWriteDependencies(t *testing.T) {
 			} else {
 				assert.NoError(t, err)
 			}
+			if testCase.indexExists {
+				r.client.AssertNotCalled(t, "CreateIndex", mock.Anything)
+			}
 		})
 
 	}
@@ -0,0 +1,198 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package metricstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/olivere/elastic"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/es"
+	"github.com/uber/jaeger/plugin/storage/es/spanstore"
+	"github.com/uber/jaeger/storage/metricstore"
+)
+
+const (
+	spanType = "span"
+
+	startTimeField     = "startTime"
+	durationField      = "duration"
+	serviceNameField   = "process.serviceName"
+	operationNameField = "operationName"
+	tagsField          = "tags"
+	tagKeyField        = "tags.key"
+	tagValueField      = "tags.value"
+
+	stepsAggregation   = "steps"
+	latencyAggregation = "latency"
+	errorsAggregation  = "errors"
+)
+
+var errNoStepsAggregation = errors.New("Could not find the aggregation of steps")
+
+// stepMetrics are the metrics of the spans that start in a step
+type stepMetrics struct {
+	start   time.Time
+	calls   int64
+	errors  int64
+	latency float64
+}
+
+// MetricReader computes RED metrics from the spans stored in ElasticSearch
+type MetricReader struct {
+	ctx    context.Context
+	client es.Client
+	logger *zap.Logger
+}
+
+// NewMetricReader returns a MetricReader
+func NewMetricReader(client es.Client, logger *zap.Logger) *MetricReader {
+	return &MetricReader{
+		ctx:    context.Background(),
+		client: client,
+		logger: logger,
+	}
+}
+
+// GetLatencies implements metricstore.Reader#GetLatencies
+func (r *MetricReader) GetLatencies(query *metricstore.QueryParameters) ([]model.MetricPoint, error) {
+	steps, err := r.findStepMetrics(query, true)
+	if err != nil {
+		return nil, err
+	}
+	var points []model.MetricPoint
+	for _, step := range steps {
+		if step.calls > 0 {
+			points = append(points, model.MetricPoint{Timestamp: step.start, Value: step.latency})
+		}
+	}
+	return points, nil
+}
+
+// GetCallRates implements metricstore.Reader#GetCallRates
+func (r *MetricReader) GetCallRates(query *metricstore.QueryParameters) ([]model.MetricPoint, error) {
+	steps, err := r.findStepMetrics(query, false)
+	if err != nil {
+		return nil, err
+	}
+	points := make([]model.MetricPoint, len(steps))
+	for i, step := range steps {
+		points[i] = model.MetricPoint{Timestamp: step.start, Value: float64(step.calls) / query.Step.Seconds()}
+	}
+	return points, nil
+}
+
+// GetErrorRates implements metricstore.Reader#GetErrorRates
+func (r *MetricReader) GetErrorRates(query *metricstore.QueryParameters) ([]model.MetricPoint, error) {
+	steps, err := r.findStepMetrics(query, false)
+	if err != nil {
+		return nil, err
+	}
+	var points []model.MetricPoint
+	for _, step := range steps {
+		if step.calls > 0 {
+			points = append(points, model.MetricPoint{Timestamp: step.start, Value: float64(step.errors) / float64(step.calls)})
+		}
+	}
+	return points, nil
+}
+
+// findStepMetrics aggregates the spans of the query by step, the latency quantile is only computed if withLatency is set
+func (r *MetricReader) findStepMetrics(query *metricstore.QueryParameters, withLatency bool) ([]stepMetrics, error) {
+	startTime := query.EndTime.Add(-query.Lookback)
+	searchResult, err := r.client.Search(spanstore.SpanIndices(startTime, query.EndTime)...).
+		Type(spanType).
+		Size(0). // only the aggregations are needed
+		Query(buildQuery(query, startTime)).
+		Aggregation(stepsAggregation, buildStepsAggregation(query, withLatency)).
+		IgnoreUnavailable(true).
+		Do(r.ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "Search service failed")
+	}
+	histogram, found := searchResult.Aggregations.Histogram(stepsAggregation)
+	if !found {
+		return nil, errNoStepsAggregation
+	}
+	steps := make([]stepMetrics, 0, len(histogram.Buckets))
+	for _, bucket := range histogram.Buckets {
+		step := stepMetrics{
+			start: model.EpochMicrosecondsAsTime(uint64(bucket.Key)),
+			calls: bucket.DocCount,
+		}
+		if errorsBucket, ok := bucket.Filter(errorsAggregation); ok {
+			step.errors = errorsBucket.DocCount
+		}
+		if percentiles, ok := bucket.Percentiles(latencyAggregation); ok {
+			// a single percentile is requested, whose key is formatted by ElasticSearch
+			for _, micros := range percentiles.Values {
+				step.latency = micros / float64(time.Millisecond/time.Microsecond)
+			}
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+func buildQuery(query *metricstore.QueryParameters, startTime time.Time) elastic.Query {
+	boolQuery := elastic.NewBoolQuery().Must(
+		elastic.NewMatchQuery(serviceNameField, query.ServiceName),
+		elastic.NewRangeQuery(startTimeField).
+			Gte(model.TimeAsEpochMicroseconds(startTime)).
+			Lte(model.TimeAsEpochMicroseconds(query.EndTime)),
+	)
+	if query.OperationName != "" {
+		boolQuery.Must(elastic.NewMatchQuery(operationNameField, query.OperationName))
+	}
+	return boolQuery
+}
+
+// buildStepsAggregation buckets the spans by step. The start time of the spans is in microseconds since epoch,
+// which a date_histogram would take for milliseconds, so a histogram with an interval of a step is used instead.
+func buildStepsAggregation(query *metricstore.QueryParameters, withLatency bool) elastic.Aggregation {
+	stepMicros := float64(model.DurationAsMicroseconds(query.Step))
+	firstStep := float64(model.TimeAsEpochMicroseconds(query.StepStart(query.EndTime.Add(-query.Lookback))))
+	lastStep := float64(model.TimeAsEpochMicroseconds(query.StepStart(query.EndTime)))
+	aggregation := elastic.NewHistogramAggregation().
+		Field(startTimeField).
+		Interval(stepMicros).
+		MinDocCount(0).
+		ExtendedBounds(firstStep, lastStep).
+		SubAggregation(errorsAggregation, elastic.NewFilterAggregation().Filter(buildErrorQuery()))
+	if withLatency {
+		aggregation.SubAggregation(latencyAggregation, elastic.NewPercentilesAggregation().
+			Field(durationField).
+			Percentiles(query.Quantile*100))
+	}
+	return aggregation
+}
+
+func buildErrorQuery() elastic.Query {
+	return elastic.NewNestedQuery(tagsField, elastic.NewBoolQuery().Must(
+		elastic.NewTermQuery(tagKeyField, "error"),
+		elastic.NewTermQuery(tagValueField, "true"),
+	))
+}
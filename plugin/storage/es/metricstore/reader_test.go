@@ -0,0 +1,165 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package metricstore
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/olivere/elastic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/es/mocks"
+	"github.com/uber/jaeger/pkg/testutils"
+	"github.com/uber/jaeger/storage/metricstore"
+)
+
+const stepsResult = `{
+	"buckets": [
+		{"key": 0, "doc_count": 4, "errors": {"doc_count": 1}, "latency": {"values": {"95.0": 12500}}},
+		{"key": 60000000, "doc_count": 0, "errors": {"doc_count": 0}, "latency": {"values": {"95.0": null}}},
+		{"key": 120000000, "doc_count": 3, "errors": {"doc_count": 0}, "latency": {"values": {"95.0": 3000}}}
+	]
+}`
+
+type metricReaderTest struct {
+	client    *mocks.Client
+	logger    *zap.Logger
+	logBuffer *testutils.Buffer
+	reader    *MetricReader
+}
+
+func withMetricReader(fn func(r *metricReaderTest)) {
+	client := &mocks.Client{}
+	logger, logBuffer := testutils.NewLogger()
+	r := &metricReaderTest{
+		client:    client,
+		logger:    logger,
+		logBuffer: logBuffer,
+		reader:    NewMetricReader(client, logger),
+	}
+	fn(r)
+}
+
+var _ metricstore.Reader = &MetricReader{} // check API conformance
+
+var testQuery = &metricstore.QueryParameters{
+	ServiceName:   "service",
+	OperationName: "operation",
+	EndTime:       time.Unix(150, 0),
+	Lookback:      100 * time.Second,
+	Step:          time.Minute,
+	Quantile:      0.95,
+}
+
+func mockSearchService(r *metricReaderTest, withLatency bool) *mock.Call {
+	searchService := &mocks.SearchService{}
+	searchService.On("Type", spanType).Return(searchService)
+	searchService.On("Size", 0).Return(searchService)
+	searchService.On("Query", mock.Anything).Return(searchService)
+	searchService.On("Aggregation", stepsAggregation, mock.MatchedBy(func(aggregation elastic.Aggregation) bool {
+		source, err := aggregation.Source()
+		if err != nil {
+			return false
+		}
+		subAggregations := source.(map[string]interface{})["aggregations"].(map[string]interface{})
+		_, hasLatency := subAggregations[latencyAggregation]
+		return hasLatency == withLatency
+	})).Return(searchService)
+	searchService.On("IgnoreUnavailable", true).Return(searchService)
+	r.client.On("Search", "jaeger-span-1970-01-01").Return(searchService)
+	return searchService.On("Do", mock.AnythingOfType("*context.emptyCtx"))
+}
+
+func stepsSearchResult() *elastic.SearchResult {
+	rawMessage := json.RawMessage(stepsResult)
+	return &elastic.SearchResult{
+		Aggregations: elastic.Aggregations{stepsAggregation: &rawMessage},
+	}
+}
+
+func TestGetLatencies(t *testing.T) {
+	withMetricReader(func(r *metricReaderTest) {
+		mockSearchService(r, true).Return(stepsSearchResult(), nil)
+		points, err := r.reader.GetLatencies(testQuery)
+		assert.NoError(t, err)
+		assert.Equal(t, []model.MetricPoint{
+			{Timestamp: model.EpochMicrosecondsAsTime(0), Value: 12.5},
+			{Timestamp: model.EpochMicrosecondsAsTime(120000000), Value: 3},
+		}, points)
+	})
+}
+
+func TestGetCallRates(t *testing.T) {
+	withMetricReader(func(r *metricReaderTest) {
+		mockSearchService(r, false).Return(stepsSearchResult(), nil)
+		points, err := r.reader.GetCallRates(testQuery)
+		assert.NoError(t, err)
+		assert.Equal(t, []model.MetricPoint{
+			{Timestamp: model.EpochMicrosecondsAsTime(0), Value: 4.0 / 60},
+			{Timestamp: model.EpochMicrosecondsAsTime(60000000), Value: 0},
+			{Timestamp: model.EpochMicrosecondsAsTime(120000000), Value: 3.0 / 60},
+		}, points)
+	})
+}
+
+func TestGetErrorRates(t *testing.T) {
+	withMetricReader(func(r *metricReaderTest) {
+		mockSearchService(r, false).Return(stepsSearchResult(), nil)
+		points, err := r.reader.GetErrorRates(testQuery)
+		assert.NoError(t, err)
+		assert.Equal(t, []model.MetricPoint{
+			{Timestamp: model.EpochMicrosecondsAsTime(0), Value: 0.25},
+			{Timestamp: model.EpochMicrosecondsAsTime(120000000), Value: 0},
+		}, points)
+	})
+}
+
+func TestGetMetricsFailures(t *testing.T) {
+	testCases := []struct {
+		searchResult  *elastic.SearchResult
+		searchError   error
+		expectedError string
+	}{
+		{
+			searchError:   errors.New("search failure"),
+			expectedError: "Search service failed: search failure",
+		},
+		{
+			searchResult:  &elastic.SearchResult{},
+			expectedError: errNoStepsAggregation.Error(),
+		},
+	}
+	for _, testCase := range testCases {
+		withMetricReader(func(r *metricReaderTest) {
+			mockSearchService(r, true).Return(testCase.searchResult, testCase.searchError)
+			points, err := r.reader.GetLatencies(testQuery)
+			assert.EqualError(t, err, testCase.expectedError)
+			assert.Nil(t, points)
+		})
+	}
+}
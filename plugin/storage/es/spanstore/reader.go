@@ -167,6 +167,11 @@ func findIndices(prefix string, startTime time.Time, endTime time.Time) []string
 	return append(indices, firstIndex)
 }
 
+// SpanIndices returns the daily indices of the spans that started between startTime and endTime, newest first
+func SpanIndices(startTime, endTime time.Time) []string {
+	return findIndices(spanIndexPrefix, startTime, endTime)
+}
+
 func indexWithDate(prefix string, date time.Time) string {
 	return prefix + date.UTC().Format("2006-01-02")
 }
@@ -254,7 +259,7 @@ func validateQuery(p *spanstore.TraceQueryParameters) error {
 	if p == nil {
 		return ErrMalformedRequestObject
 	}
-	if p.ServiceName == "" && len(p.Tags) > 0 {
+	if p.ServiceName == "" && (len(p.Tags) > 0 || p.TagQuery != nil) {
 		return ErrServiceNameNotSet
 	}
 	if p.StartTimeMin.IsZero() || p.StartTimeMax.IsZero() {
@@ -316,7 +321,10 @@ func (s *SpanReader) findTraceIDs(traceQuery *spanstore.TraceQueryParameters) ([
 	//      "aggs": { "traceIDs" : { "terms" : {"size": 100,"field": "traceID" }}}
 	//  }
 	aggregation := s.buildTraceIDAggregation(traceQuery.NumTraces)
-	boolQuery := s.buildFindTraceIDsQuery(traceQuery)
+	boolQuery, err := s.buildFindTraceIDsQuery(traceQuery)
+	if err != nil {
+		return nil, err
+	}
 
 	jaegerIndices := findIndices(spanIndexPrefix, traceQuery.StartTimeMin, traceQuery.StartTimeMax)
 
@@ -354,7 +362,7 @@ func (s *SpanReader) buildTraceIDSubAggregation() elastic.Aggregation {
 		Field(startTimeField)
 }
 
-func (s *SpanReader) buildFindTraceIDsQuery(traceQuery *spanstore.TraceQueryParameters) elastic.Query {
+func (s *SpanReader) buildFindTraceIDsQuery(traceQuery *spanstore.TraceQueryParameters) (elastic.Query, error) {
 	boolQuery := elastic.NewBoolQuery()
 
 	//add duration query
@@ -383,7 +391,15 @@ func (s *SpanReader) buildFindTraceIDsQuery(traceQuery *spanstore.TraceQueryPara
 		tagQuery := s.buildTagQuery(k, v)
 		boolQuery.Must(tagQuery)
 	}
-	return boolQuery
+
+	if traceQuery.TagQuery != nil {
+		tagQuery, err := s.buildTagExpressionQuery(traceQuery.TagQuery)
+		if err != nil {
+			return nil, err
+		}
+		boolQuery.Must(tagQuery)
+	}
+	return boolQuery, nil
 }
 
 func (s *SpanReader) buildDurationQuery(durationMin time.Duration, durationMax time.Duration) elastic.Query {
@@ -289,6 +289,14 @@ func TestSpanReaderFindIndices(t *testing.T) {
 	}
 }
 
+func TestSpanIndices(t *testing.T) {
+	endTime := time.Date(2017, time.March, 3, 1, 0, 0, 0, time.UTC)
+	assert.Equal(t, []string{"jaeger-span-2017-03-03"}, SpanIndices(endTime.Add(-time.Hour), endTime))
+	assert.Equal(t,
+		[]string{"jaeger-span-2017-03-03", "jaeger-span-2017-03-02", "jaeger-span-2017-03-01"},
+		SpanIndices(endTime.Add(-48*time.Hour), endTime))
+}
+
 func TestSpanReader_indexWithDate(t *testing.T) {
 	withSpanReader(func(r *spanReaderTest) {
 		actual := indexWithDate(spanIndexPrefix, time.Date(1995, time.April, 21, 4, 21, 19, 95, time.UTC))
@@ -706,9 +714,11 @@ func TestSpanReader_buildFindTraceIDsQuery(t *testing.T) {
 			Tags: map[string]string{
 				"hello": "world",
 			},
+			TagQuery: &spanstore.TagExists{Key: "error"},
 		}
 
-		actualQuery := r.reader.buildFindTraceIDsQuery(traceQuery)
+		actualQuery, err := r.reader.buildFindTraceIDsQuery(traceQuery)
+		require.NoError(t, err)
 		actual, err := actualQuery.Source()
 		require.NoError(t, err)
 		expectedQuery := elastic.NewBoolQuery().
@@ -718,6 +728,7 @@ func TestSpanReader_buildFindTraceIDsQuery(t *testing.T) {
 				r.reader.buildServiceNameQuery("s"),
 				r.reader.buildOperationNameQuery("o"),
 				r.reader.buildTagQuery("hello", "world"),
+				r.reader.buildTagValueQuery("error", nil),
 			)
 		expected, err := expectedQuery.Source()
 		require.NoError(t, err)
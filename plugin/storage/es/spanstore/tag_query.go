@@ -0,0 +1,125 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package spanstore
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/olivere/elastic"
+
+	"github.com/uber/jaeger/storage/spanstore"
+)
+
+const (
+	// tagRangeScript matches the nested tags whose value parses as a number in [min, max].
+	// Tag values are indexed as keywords, so numeric strings can match as well.
+	tagRangeScript = `
+		if (doc[params.field].size() == 0) {
+			return false;
+		}
+		try {
+			double value = Double.parseDouble(doc[params.field].value);
+			return value >= params.min && value <= params.max;
+		} catch (NumberFormatException e) {
+			return false;
+		}`
+	painlessLang = "painless"
+)
+
+// buildTagExpressionQuery translates a TagQuery into a query of the spans whose tags, process tags
+// or log fields match it
+func (s *SpanReader) buildTagExpressionQuery(query spanstore.TagQuery) (elastic.Query, error) {
+	switch q := query.(type) {
+	case *spanstore.TagAnd:
+		queries, err := s.buildTagExpressionQueries(q.Queries)
+		if err != nil {
+			return nil, err
+		}
+		return elastic.NewBoolQuery().Must(queries...), nil
+	case *spanstore.TagOr:
+		queries, err := s.buildTagExpressionQueries(q.Queries)
+		if err != nil {
+			return nil, err
+		}
+		return elastic.NewBoolQuery().Should(queries...).MinimumNumberShouldMatch(1), nil
+	case *spanstore.TagNot:
+		notQuery, err := s.buildTagExpressionQuery(q.Query)
+		if err != nil {
+			return nil, err
+		}
+		return elastic.NewBoolQuery().MustNot(notQuery), nil
+	case *spanstore.TagEquals:
+		return s.buildTagQuery(q.Key, q.Value), nil
+	case *spanstore.TagPrefix:
+		return s.buildTagValueQuery(q.Key, func(valueField string) elastic.Query {
+			return elastic.NewPrefixQuery(valueField, q.Prefix)
+		}), nil
+	case *spanstore.TagRegexp:
+		return s.buildTagValueQuery(q.Key, func(valueField string) elastic.Query {
+			return elastic.NewRegexpQuery(valueField, q.Pattern)
+		}), nil
+	case *spanstore.TagExists:
+		return s.buildTagValueQuery(q.Key, nil), nil
+	case *spanstore.TagRange:
+		min, max := -math.MaxFloat64, math.MaxFloat64
+		if q.Min != nil {
+			min = *q.Min
+		}
+		if q.Max != nil {
+			max = *q.Max
+		}
+		return s.buildTagValueQuery(q.Key, func(valueField string) elastic.Query {
+			script := elastic.NewScript(tagRangeScript).
+				Lang(painlessLang).
+				Params(map[string]interface{}{"field": valueField, "min": min, "max": max})
+			return elastic.NewScriptQuery(script)
+		}), nil
+	}
+	return nil, &spanstore.UnsupportedTagQueryError{Storage: "Elasticsearch", Query: query}
+}
+
+func (s *SpanReader) buildTagExpressionQueries(tagQueries []spanstore.TagQuery) ([]elastic.Query, error) {
+	queries := make([]elastic.Query, len(tagQueries))
+	for i, tagQuery := range tagQueries {
+		query, err := s.buildTagExpressionQuery(tagQuery)
+		if err != nil {
+			return nil, err
+		}
+		queries[i] = query
+	}
+	return queries, nil
+}
+
+// buildTagValueQuery returns a query of the spans that have a tag k in any of the tag fields,
+// and whose value matches the query built by valueQuery, if not nil
+func (s *SpanReader) buildTagValueQuery(k string, valueQuery func(valueField string) elastic.Query) elastic.Query {
+	queries := make([]elastic.Query, len(tagFieldList))
+	for i, field := range tagFieldList {
+		tagBoolQuery := elastic.NewBoolQuery().Must(elastic.NewMatchQuery(fmt.Sprintf("%s.%s", field, tagKeyField), k))
+		if valueQuery != nil {
+			tagBoolQuery.Must(valueQuery(fmt.Sprintf("%s.%s", field, tagValueField)))
+		}
+		queries[i] = elastic.NewNestedQuery(field, tagBoolQuery)
+	}
+	return elastic.NewBoolQuery().Should(queries...)
+}
@@ -0,0 +1,173 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package spanstore
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/olivere/elastic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/storage/spanstore"
+)
+
+func floatPointer(f float64) *float64 {
+	return &f
+}
+
+func TestSpanReader_buildTagExpressionQuery(t *testing.T) {
+	withSpanReader(func(r *spanReaderTest) {
+		prefixQuery := func(valueField string) elastic.Query {
+			return elastic.NewPrefixQuery(valueField, "https://")
+		}
+		regexpQuery := func(valueField string) elastic.Query {
+			return elastic.NewRegexpQuery(valueField, ".*/users/[0-9]+")
+		}
+		tests := []struct {
+			query    spanstore.TagQuery
+			expected elastic.Query
+		}{
+			{
+				query:    &spanstore.TagEquals{Key: "error", Value: "true"},
+				expected: r.reader.buildTagQuery("error", "true"),
+			},
+			{
+				query:    &spanstore.TagPrefix{Key: "http.url", Prefix: "https://"},
+				expected: r.reader.buildTagValueQuery("http.url", prefixQuery),
+			},
+			{
+				query:    &spanstore.TagRegexp{Key: "http.url", Pattern: ".*/users/[0-9]+"},
+				expected: r.reader.buildTagValueQuery("http.url", regexpQuery),
+			},
+			{
+				query:    &spanstore.TagExists{Key: "error"},
+				expected: r.reader.buildTagValueQuery("error", nil),
+			},
+			{
+				query: &spanstore.TagAnd{Queries: []spanstore.TagQuery{
+					&spanstore.TagEquals{Key: "a", Value: "1"},
+					&spanstore.TagNot{Query: &spanstore.TagExists{Key: "b"}},
+				}},
+				expected: elastic.NewBoolQuery().Must(
+					r.reader.buildTagQuery("a", "1"),
+					elastic.NewBoolQuery().MustNot(r.reader.buildTagValueQuery("b", nil)),
+				),
+			},
+			{
+				query: &spanstore.TagOr{Queries: []spanstore.TagQuery{
+					&spanstore.TagEquals{Key: "a", Value: "1"},
+					&spanstore.TagEquals{Key: "b", Value: "2"},
+				}},
+				expected: elastic.NewBoolQuery().Should(
+					r.reader.buildTagQuery("a", "1"),
+					r.reader.buildTagQuery("b", "2"),
+				).MinimumNumberShouldMatch(1),
+			},
+		}
+		for _, test := range tests {
+			query, err := r.reader.buildTagExpressionQuery(test.query)
+			require.NoError(t, err)
+			actual, err := query.Source()
+			require.NoError(t, err)
+			expected, err := test.expected.Source()
+			require.NoError(t, err)
+			assert.Equal(t, expected, actual, test.query.String())
+		}
+	})
+}
+
+func TestSpanReader_buildTagValueQuery(t *testing.T) {
+	expectedStr :=
+		`{ "bool": {
+		   "should": [
+		      { "nested" : {
+			 "path" : "tags",
+			 "query" : {
+			    "bool" : {
+			      "must" : [
+				 { "match" : {"tags.key" : {"query":"http.url"}} },
+				 { "prefix" : {"tags.value" : "https://"} }
+			      ]
+		      }}}},
+		      { "nested" : {
+			 "path" : "process.tags",
+			 "query" : {
+			    "bool" : {
+			      "must" : [
+				 { "match" : {"process.tags.key" : {"query":"http.url"}} },
+				 { "prefix" : {"process.tags.value" : "https://"} }
+			      ]
+		      }}}},
+		      { "nested" : {
+			 "path" : "logs.fields",
+			 "query" : {
+		            "bool" : {
+			       "must" : [
+			         { "match" : {"logs.fields.key" : {"query":"http.url"}} },
+			         { "prefix" : {"logs.fields.value" : "https://"} }
+			       ]
+		      }}}}
+		   ]
+		}}`
+	withSpanReader(func(r *spanReaderTest) {
+		tagQuery, err := r.reader.buildTagExpressionQuery(&spanstore.TagPrefix{Key: "http.url", Prefix: "https://"})
+		require.NoError(t, err)
+		actual, err := tagQuery.Source()
+		require.NoError(t, err)
+		actualJSON, err := json.Marshal(actual)
+		require.NoError(t, err)
+
+		assert.JSONEq(t, expectedStr, string(actualJSON))
+	})
+}
+
+func TestSpanReader_buildTagRangeQuery(t *testing.T) {
+	withSpanReader(func(r *spanReaderTest) {
+		for _, test := range []struct {
+			query    *spanstore.TagRange
+			min, max float64
+		}{
+			{&spanstore.TagRange{Key: "k", Min: floatPointer(500), Max: floatPointer(599)}, 500, 599},
+			{&spanstore.TagRange{Key: "k", Min: floatPointer(500)}, 500, math.MaxFloat64},
+			{&spanstore.TagRange{Key: "k", Max: floatPointer(0.5)}, -math.MaxFloat64, 0.5},
+		} {
+			query, err := r.reader.buildTagExpressionQuery(test.query)
+			require.NoError(t, err)
+			actual, err := query.Source()
+			require.NoError(t, err)
+
+			script := elastic.NewScript(tagRangeScript).
+				Lang(painlessLang).
+				Params(map[string]interface{}{"field": "tags.value", "min": test.min, "max": test.max})
+			expectedQuery := elastic.NewNestedQuery("tags", elastic.NewBoolQuery().Must(
+				elastic.NewMatchQuery("tags.key", "k"),
+				elastic.NewScriptQuery(script),
+			))
+			expected, err := expectedQuery.Source()
+			require.NoError(t, err)
+			assert.Equal(t, expected, actual.(map[string]interface{})["bool"].(map[string]interface{})["should"].([]interface{})[0])
+		}
+	})
+}
@@ -0,0 +1,252 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package spanstore
+
+import (
+	"github.com/olivere/elastic"
+	"github.com/pkg/errors"
+
+	"github.com/uber/jaeger/storage/spanstore"
+)
+
+const (
+	traceCountAggregation = "traceCount"
+
+	// pageBatchMultiple is the number of matching spans read by each search of FindTracePage,
+	// as a multiple of the number of traces in a page
+	pageBatchMultiple = 10
+	// maxPageBatches is the maximum number of searches of matching spans read by FindTracePage, which
+	// returns a shorter page when they hold fewer traces than requested
+	maxPageBatches = 10
+	// traceCountPrecision is the precision threshold of the approximate count of the matching traces
+	traceCountPrecision = 40000
+)
+
+var errInvalidSortValues = errors.New("Unexpected sort values in span search results")
+
+// pageCursor is the position of FindTracePage in the matching spans, which are sorted by
+// start time, newest first, and then by trace ID
+type pageCursor struct {
+	Offset    int    `json:"offset"`
+	StartTime uint64 `json:"startTime"`
+	TraceID   string `json:"traceID"`
+}
+
+// FindTracePage retrieves a page of the traces that match the traceQuery. Traces are ordered by the start time
+// of their latest matching span, newest first, and the pages are read with search_after on the matching spans.
+// The Total of the page is an approximate count of the matching traces. A page can hold fewer traces than
+// requested, even none, and still have a NextCursor when the traces are spread over too many matching spans.
+func (s *SpanReader) FindTracePage(traceQuery *spanstore.TraceQueryParameters) (*spanstore.TracePage, error) {
+	if err := validateQuery(traceQuery); err != nil {
+		return nil, err
+	}
+	var position *pageCursor
+	if traceQuery.Cursor != "" {
+		position = &pageCursor{}
+		if err := spanstore.DecodeCursor(traceQuery.Cursor, position); err != nil {
+			return nil, err
+		}
+	}
+	numTraces := traceQuery.NumTraces
+	if numTraces == 0 {
+		numTraces = defaultNumTraces
+	}
+	traceIDs, total, next, err := s.findTraceIDPage(traceQuery, numTraces, position)
+	if err != nil {
+		return nil, err
+	}
+	traces, err := s.multiRead(traceIDs, traceQuery.StartTimeMin, traceQuery.StartTimeMax)
+	if err != nil {
+		return nil, err
+	}
+	page := &spanstore.TracePage{Traces: traces, Total: total}
+	if position != nil {
+		page.Offset = position.Offset
+	}
+	if next != nil {
+		next.Offset = page.Offset + len(traceIDs)
+		if page.NextCursor, err = spanstore.EncodeCursor(next); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// findTraceIDPage returns the IDs of the traces in the page after position, the approximate count of
+// the matching traces, and the position of the next page, or nil if there are no more matching spans.
+// It stops after maxPageBatches searches, and the next page starts after the last span read.
+func (s *SpanReader) findTraceIDPage(
+	traceQuery *spanstore.TraceQueryParameters,
+	numTraces int,
+	position *pageCursor,
+) ([]string, int, *pageCursor, error) {
+	boolQuery, err := s.buildFindTraceIDsQuery(traceQuery)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	jaegerIndices := findIndices(spanIndexPrefix, traceQuery.StartTimeMin, traceQuery.StartTimeMax)
+	batchSize := numTraces * pageBatchMultiple
+	if batchSize > defaultDocCount {
+		batchSize = defaultDocCount
+	}
+
+	total := spanstore.UnknownTotal
+	var traceIDs []string
+	seen := make(map[string]bool)
+	after := position
+	for batch := 0; ; batch++ {
+		searchService := s.client.Search(jaegerIndices...).
+			Type(spanType).
+			Size(batchSize).
+			IgnoreUnavailable(true).
+			Query(boolQuery).
+			FetchSourceContext(elastic.NewFetchSourceContext(false)). // the sort values are enough
+			Sort(startTimeField, false).
+			Sort(traceIDField, true)
+		if after != nil {
+			searchService = searchService.SearchAfter(after.StartTime, after.TraceID)
+		}
+		if batch == 0 {
+			searchService = searchService.Aggregation(traceCountAggregation, s.buildTraceCountAggregation())
+		}
+		searchResult, err := searchService.Do(s.ctx)
+		if err != nil {
+			return nil, 0, nil, errors.Wrap(err, "Search service failed")
+		}
+		if batch == 0 {
+			total = traceCount(searchResult)
+		}
+		if searchResult.Hits == nil {
+			return nil, 0, nil, errNilHits
+		}
+		hits := make([]*pageCursor, len(searchResult.Hits.Hits))
+		var newTraceIDs []string
+		for i, hit := range searchResult.Hits.Hits {
+			if hits[i], err = hitPosition(hit); err != nil {
+				return nil, 0, nil, err
+			}
+			if !seen[hits[i].TraceID] {
+				newTraceIDs = append(newTraceIDs, hits[i].TraceID)
+			}
+		}
+		// traces whose latest matching span precedes the cursor were returned by the previous pages
+		var previous map[string]bool
+		if position != nil && len(newTraceIDs) > 0 {
+			if previous, err = s.findTracesBefore(boolQuery, jaegerIndices, newTraceIDs, position); err != nil {
+				return nil, 0, nil, err
+			}
+		}
+		for _, hit := range hits {
+			after = hit
+			if seen[hit.TraceID] {
+				continue
+			}
+			seen[hit.TraceID] = true
+			if previous[hit.TraceID] {
+				continue
+			}
+			traceIDs = append(traceIDs, hit.TraceID)
+			if len(traceIDs) == numTraces {
+				return traceIDs, total, after, nil
+			}
+		}
+		if len(hits) < batchSize {
+			return traceIDs, total, nil, nil
+		}
+		if batch+1 == maxPageBatches {
+			return traceIDs, total, after, nil
+		}
+	}
+}
+
+// findTracesBefore returns which of the traceIDs have a span that matches the query and precedes the position
+func (s *SpanReader) findTracesBefore(
+	query elastic.Query,
+	indices []string,
+	traceIDs []string,
+	position *pageCursor,
+) (map[string]bool, error) {
+	values := make([]interface{}, len(traceIDs))
+	for i, traceID := range traceIDs {
+		values[i] = traceID
+	}
+	beforeQuery := elastic.NewBoolQuery().Should(
+		elastic.NewRangeQuery(startTimeField).Gt(position.StartTime),
+		elastic.NewBoolQuery().Must(
+			elastic.NewTermQuery(startTimeField, position.StartTime),
+			elastic.NewRangeQuery(traceIDField).Lte(position.TraceID),
+		),
+	)
+	searchResult, err := s.client.Search(indices...).
+		Type(spanType).
+		Size(0).
+		Aggregation(traceIDAggregation, elastic.NewTermsAggregation().Field(traceIDField).Size(len(traceIDs))).
+		IgnoreUnavailable(true).
+		Query(elastic.NewBoolQuery().Must(query, elastic.NewTermsQuery(traceIDField, values...), beforeQuery)).
+		Do(s.ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "Search service failed")
+	}
+	bucket, found := searchResult.Aggregations.Terms(traceIDAggregation)
+	if !found {
+		return nil, ErrUnableToFindTraceIDAggregation
+	}
+	previousTraceIDs, err := bucketToStringArray(bucket.Buckets)
+	if err != nil {
+		return nil, err
+	}
+	previous := make(map[string]bool, len(previousTraceIDs))
+	for _, traceID := range previousTraceIDs {
+		previous[traceID] = true
+	}
+	return previous, nil
+}
+
+func (s *SpanReader) buildTraceCountAggregation() elastic.Aggregation {
+	return elastic.NewCardinalityAggregation().
+		Field(traceIDField).
+		PrecisionThreshold(traceCountPrecision)
+}
+
+func traceCount(searchResult *elastic.SearchResult) int {
+	count, found := searchResult.Aggregations.Cardinality(traceCountAggregation)
+	if !found || count.Value == nil {
+		return spanstore.UnknownTotal
+	}
+	return int(*count.Value)
+}
+
+// hitPosition returns the position of a span hit from its sort values
+func hitPosition(hit *elastic.SearchHit) (*pageCursor, error) {
+	if len(hit.Sort) != 2 {
+		return nil, errInvalidSortValues
+	}
+	startTime, ok := hit.Sort[0].(float64)
+	if !ok {
+		return nil, errInvalidSortValues
+	}
+	traceID, ok := hit.Sort[1].(string)
+	if !ok {
+		return nil, errInvalidSortValues
+	}
+	return &pageCursor{StartTime: uint64(startTime), TraceID: traceID}, nil
+}
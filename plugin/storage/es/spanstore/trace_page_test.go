@@ -0,0 +1,205 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package spanstore
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/olivere/elastic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/pkg/es/mocks"
+	"github.com/uber/jaeger/storage/spanstore"
+)
+
+const pageTestIndex = "jaeger-span-2017-01-26"
+
+var pageTestQuery = spanstore.TraceQueryParameters{
+	ServiceName:  serviceName,
+	StartTimeMin: time.Date(2017, time.January, 26, 10, 0, 0, 0, time.UTC),
+	StartTimeMax: time.Date(2017, time.January, 26, 12, 0, 0, 0, time.UTC),
+	NumTraces:    2,
+}
+
+func mockPageSearchService(r *spanReaderTest) *mocks.SearchService {
+	searchService := &mocks.SearchService{}
+	searchService.On("Type", stringMatcher(spanType)).Return(searchService)
+	searchService.On("Size", mock.AnythingOfType("int")).Return(searchService)
+	searchService.On("IgnoreUnavailable", true).Return(searchService)
+	searchService.On("Query", mock.Anything).Return(searchService)
+	searchService.On("FetchSourceContext", mock.AnythingOfType("*elastic.FetchSourceContext")).Return(searchService)
+	searchService.On("Sort", startTimeField, false).Return(searchService)
+	searchService.On("Sort", traceIDField, true).Return(searchService)
+	searchService.On("Aggregation", traceCountAggregation, mock.AnythingOfType("*elastic.CardinalityAggregation")).Return(searchService)
+	searchService.On("Aggregation", traceIDAggregation, mock.AnythingOfType("*elastic.TermsAggregation")).Return(searchService)
+	r.client.On("Search", pageTestIndex).Return(searchService)
+	return searchService
+}
+
+func mockPageMultiSearchService(r *spanReaderTest, numTraces int) {
+	hits := &elastic.SearchHits{Hits: []*elastic.SearchHit{{Source: (*json.RawMessage)(&exampleESSpan)}}}
+	responses := make([]*elastic.SearchResult, numTraces)
+	for i := range responses {
+		responses[i] = &elastic.SearchResult{Hits: hits}
+	}
+	multiSearchService := &mocks.MultiSearchService{}
+	requests := make([]interface{}, numTraces)
+	for i := range requests {
+		requests[i] = mock.AnythingOfType("*elastic.SearchRequest")
+	}
+	multiSearchService.On("Add", requests...).Return(multiSearchService)
+	multiSearchService.On("Index", pageTestIndex).Return(multiSearchService)
+	multiSearchService.On("Do", mock.Anything).Return(&elastic.MultiSearchResult{Responses: responses}, nil).Once()
+	r.client.On("MultiSearch").Return(multiSearchService).Once()
+}
+
+func spanHits(positions ...interface{}) *elastic.SearchHits {
+	hits := &elastic.SearchHits{}
+	for i := 0; i < len(positions); i += 2 {
+		hits.Hits = append(hits.Hits, &elastic.SearchHit{Sort: []interface{}{positions[i], positions[i+1]}})
+	}
+	return hits
+}
+
+func rawAggregations(name string, aggregation string) elastic.Aggregations {
+	raw := json.RawMessage(aggregation)
+	return elastic.Aggregations{name: &raw}
+}
+
+func TestSpanReader_FindTracePage(t *testing.T) {
+	withSpanReader(func(r *spanReaderTest) {
+		searchService := mockPageSearchService(r)
+		searchService.On("Do", mock.Anything).Return(&elastic.SearchResult{
+			Hits:         spanHits(500.0, "1", 400.0, "2", 400.0, "2", 300.0, "1", 200.0, "3"),
+			Aggregations: rawAggregations(traceCountAggregation, `{"value": 3}`),
+		}, nil).Once()
+		mockPageMultiSearchService(r, 2)
+
+		query := pageTestQuery
+		page, err := r.reader.FindTracePage(&query)
+		require.NoError(t, err)
+		assert.Len(t, page.Traces, 2)
+		assert.Equal(t, 0, page.Offset)
+		assert.Equal(t, 3, page.Total)
+
+		var position pageCursor
+		require.NoError(t, spanstore.DecodeCursor(page.NextCursor, &position))
+		assert.Equal(t, pageCursor{Offset: 2, StartTime: 400, TraceID: "2"}, position)
+
+		// the next page continues after the cursor, and skips the traces returned by the first page
+		searchService.On("SearchAfter", uint64(400), "2").Return(searchService).Once()
+		searchService.On("Do", mock.Anything).Return(&elastic.SearchResult{
+			Hits:         spanHits(400.0, "2", 300.0, "1", 200.0, "3"),
+			Aggregations: rawAggregations(traceCountAggregation, `{"value": 3}`),
+		}, nil).Once()
+		searchService.On("Do", mock.Anything).Return(&elastic.SearchResult{
+			Aggregations: rawAggregations(traceIDAggregation, `{"buckets": [{"key": "1"}, {"key": "2"}]}`),
+		}, nil).Once()
+		mockPageMultiSearchService(r, 1)
+
+		query.Cursor = page.NextCursor
+		page, err = r.reader.FindTracePage(&query)
+		require.NoError(t, err)
+		assert.Len(t, page.Traces, 1)
+		assert.Equal(t, 2, page.Offset)
+		assert.Equal(t, 3, page.Total)
+		assert.Equal(t, "", page.NextCursor)
+		searchService.AssertExpectations(t)
+	})
+}
+
+func TestSpanReader_FindTracePageMaxBatches(t *testing.T) {
+	withSpanReader(func(r *spanReaderTest) {
+		// every batch holds the spans of the same trace, so the page is never filled
+		var positions []interface{}
+		for i := 0; i < pageTestQuery.NumTraces*pageBatchMultiple; i++ {
+			positions = append(positions, float64(500-i), "1")
+		}
+		searchService := mockPageSearchService(r)
+		searchService.On("SearchAfter", mock.Anything, mock.Anything).Return(searchService)
+		searchService.On("Do", mock.Anything).Return(&elastic.SearchResult{
+			Hits:         spanHits(positions...),
+			Aggregations: rawAggregations(traceCountAggregation, `{"value": 1}`),
+		}, nil)
+		mockPageMultiSearchService(r, 1)
+
+		query := pageTestQuery
+		page, err := r.reader.FindTracePage(&query)
+		require.NoError(t, err)
+		assert.Len(t, page.Traces, 1)
+		searchService.AssertNumberOfCalls(t, "Do", maxPageBatches)
+
+		var position pageCursor
+		require.NoError(t, spanstore.DecodeCursor(page.NextCursor, &position))
+		assert.Equal(t, pageCursor{Offset: 1, StartTime: 500 - uint64(len(positions)/2) + 1, TraceID: "1"}, position)
+	})
+}
+
+func TestSpanReader_FindTracePageErrors(t *testing.T) {
+	testCases := []struct {
+		caption      string
+		cursor       string
+		searchResult *elastic.SearchResult
+		err          string
+	}{
+		{
+			caption: "invalid cursor",
+			cursor:  "!",
+			err:     spanstore.ErrInvalidCursor.Error(),
+		},
+		{
+			caption:      "nil hits",
+			searchResult: &elastic.SearchResult{},
+			err:          errNilHits.Error(),
+		},
+		{
+			caption:      "invalid sort values",
+			searchResult: &elastic.SearchResult{Hits: spanHits("400", "2")},
+			err:          errInvalidSortValues.Error(),
+		},
+		{
+			caption:      "missing aggregation",
+			cursor:       "eyJvZmZzZXQiOjIsInN0YXJ0VGltZSI6NDAwLCJ0cmFjZUlEIjoiMiJ9",
+			searchResult: &elastic.SearchResult{Hits: spanHits(300.0, "1")},
+			err:          ErrUnableToFindTraceIDAggregation.Error(),
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.caption, func(t *testing.T) {
+			withSpanReader(func(r *spanReaderTest) {
+				searchService := mockPageSearchService(r)
+				searchService.On("SearchAfter", mock.Anything, mock.Anything).Return(searchService)
+				searchService.On("Do", mock.Anything).Return(testCase.searchResult, nil)
+
+				query := pageTestQuery
+				query.Cursor = testCase.cursor
+				page, err := r.reader.FindTracePage(&query)
+				assert.Nil(t, page)
+				assert.EqualError(t, err, testCase.err)
+			})
+		})
+	}
+}
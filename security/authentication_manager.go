@@ -28,6 +28,8 @@ import (
 	"time"
 )
 
+const verifiedCacheKeyPrefix = "verified:"
+
 // Authenticator authenticates inbound spans
 type Authenticator interface {
 	Authenticate(token *AuthenticationToken) bool
@@ -62,15 +64,24 @@ func NewAuthenticationManager(
 
 // TokenFromSpan builds an authentication token from span / process tags.
 // The same tag value is used for both user name and password fields.
+// The principal authenticated by a client certificate takes precedence over the token tags.
 func (am AuthenticationManager) TokenFromSpan(span *model.Span) *AuthenticationToken {
+	if kv, ok := span.Process.Tags.FindByKey(PrincipalTagKey); ok {
+		return &AuthenticationToken{
+			Username: kv.VStr,
+			Verified: true,
+		}
+	}
 	return am.findByKey(span.Tags, span.Process.Tags)
 }
 
 // Authenticate authenticates the inbound span. It collaborates with underlying authentication store
 // to obtain principal info. Upon successful authentication, the context is cached to avoid subsequent
 // round trips to the authentication store, and thus reducing the overall I/O.
+// Tokens of principals verified by a client certificate skip the password check, the principal still has to
+// be known to the store and not locked.
 func (am AuthenticationManager) Authenticate(token *AuthenticationToken) bool {
-	ctx := am.ctxFromCache(token.Username)
+	ctx := am.ctxFromCache(token)
 	if ctx == nil {
 		var err error
 		ctx, err = am.store.FindPrincipal(*token)
@@ -78,8 +89,11 @@ func (am AuthenticationManager) Authenticate(token *AuthenticationToken) bool {
 			am.logger.Warn("Failed to load principal", zap.Error(err))
 			return false
 		}
-		if ctx.PasswordEquals(token.Password) && !ctx.Locked {
-			am.ctxToCache(token.Username, ctx)
+		if ctx == nil {
+			return false
+		}
+		if (token.Verified || ctx.PasswordEquals(token.Password)) && !ctx.Locked {
+			am.ctxToCache(token, ctx)
 			return true
 		}
 		return false
@@ -87,16 +101,25 @@ func (am AuthenticationManager) Authenticate(token *AuthenticationToken) bool {
 	return !ctx.Locked
 }
 
-func (am *AuthenticationManager) ctxFromCache(username string) *AuthenticationContext {
-	ctx := am.cache.Get(username)
+// cacheKey keeps the principals verified by certificates apart from the ones authenticated by
+// password, so that a cached certificate principal never lets a token in without the password check
+func cacheKey(token *AuthenticationToken) string {
+	if token.Verified {
+		return verifiedCacheKeyPrefix + token.Username
+	}
+	return token.Username
+}
+
+func (am *AuthenticationManager) ctxFromCache(token *AuthenticationToken) *AuthenticationContext {
+	ctx := am.cache.Get(cacheKey(token))
 	if c, ok := ctx.(*AuthenticationContext); ok {
 		return c
 	}
 	return nil
 }
 
-func (am *AuthenticationManager) ctxToCache(username string, ctx *AuthenticationContext) {
-	am.cache.Put(username, ctx)
+func (am *AuthenticationManager) ctxToCache(token *AuthenticationToken, ctx *AuthenticationContext) {
+	am.cache.Put(cacheKey(token), ctx)
 }
 
 func (am *AuthenticationManager) findByKey(kvss ...model.KeyValues) *AuthenticationToken {
@@ -215,3 +215,67 @@ func TestAuthenticatePrincipalNotFound(t *testing.T) {
 	assert.Equal(t, authenticationManager.cache.Size(), 0)
 }
 
+
+func TestAuthenticateVerifiedPrincipal(t *testing.T) {
+
+	store := new(authenticationStoreMock)
+	logger := zap.NewNop()
+
+	ctx := AuthenticationContext{
+		Principal: "frontend.example.com",
+		Password: "315a1793-a1b7-16a5-88c5-bc76f9c772a1",
+		Locked: false,
+	}
+	authenticationManager := NewAuthenticationManager(
+		store,
+		logger,
+		"api-token",
+		100,
+		time.Second * 60,
+	)
+	span := &model.Span {
+		Tags: model.KeyValues{
+			model.String("api-token", "wrong-token"),
+		},
+		Process: &model.Process{
+			Tags: model.KeyValues{
+				model.String(PrincipalTagKey, "frontend.example.com"),
+			},
+		},
+	}
+	token := authenticationManager.TokenFromSpan(span)
+	assert.Equal(t, &AuthenticationToken{Username: "frontend.example.com", Verified: true}, token)
+	store.On("FindPrincipal", *token).Return(ctx, nil)
+
+	success := authenticationManager.Authenticate(token)
+	assert.True(t, success)
+	assert.Equal(t, authenticationManager.cache.Size(), 1)
+
+	authenticationManager.Authenticate(token)
+	store.AssertNumberOfCalls(t, "FindPrincipal", 1)
+
+	// a password token named after the verified principal is not served from the cache
+	unverified := &AuthenticationToken{Username: "frontend.example.com", Password: "frontend.example.com"}
+	store.On("FindPrincipal", *unverified).Return(ctx, nil)
+	assert.False(t, authenticationManager.Authenticate(unverified))
+	store.AssertNumberOfCalls(t, "FindPrincipal", 2)
+}
+
+type nilAuthenticationStore struct{}
+
+func (nilAuthenticationStore) FindPrincipal(token AuthenticationToken) (*AuthenticationContext, error) {
+	return nil, nil
+}
+
+func TestAuthenticateVerifiedPrincipalUnknown(t *testing.T) {
+	authenticationManager := NewAuthenticationManager(
+		nilAuthenticationStore{},
+		zap.NewNop(),
+		"api-token",
+		100,
+		time.Second * 60,
+	)
+	token := &AuthenticationToken{Username: "unknown.example.com", Verified: true}
+	assert.False(t, authenticationManager.Authenticate(token))
+	assert.Equal(t, authenticationManager.cache.Size(), 0)
+}
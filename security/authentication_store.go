@@ -24,6 +24,9 @@ package security
 type AuthenticationToken struct {
 	Username string
 	Password string
+	// Verified is set when the principal was already authenticated by a verified client certificate,
+	// in which case the password is not checked
+	Verified bool
 }
 
 type AuthenticationStore interface {
@@ -0,0 +1,101 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package security
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+)
+
+// PrincipalTagKey is the key of the process tag holding the principal authenticated by a client certificate.
+// It is set by the collector only, any value reported by a client is removed.
+const PrincipalTagKey = "jaeger.principal"
+
+// PrincipalSource defines which attribute of a client certificate identifies the principal
+type PrincipalSource string
+
+const (
+	// NoPrincipalSource disables certificate based authentication
+	NoPrincipalSource PrincipalSource = ""
+	// CommonNamePrincipalSource uses the certificate subject common name as the principal
+	CommonNamePrincipalSource PrincipalSource = "cn"
+	// SANPrincipalSource uses the first subject alternative name of the certificate as the principal,
+	// checking the DNS names first, then the email addresses
+	SANPrincipalSource PrincipalSource = "san"
+)
+
+type principalContextKey struct{}
+
+// ParsePrincipalSource validates the name of a principal source
+func ParsePrincipalSource(s string) (PrincipalSource, error) {
+	switch source := PrincipalSource(s); source {
+	case NoPrincipalSource, CommonNamePrincipalSource, SANPrincipalSource:
+		return source, nil
+	default:
+		return NoPrincipalSource, fmt.Errorf("unknown principal source %q, expected %q or %q", s, CommonNamePrincipalSource, SANPrincipalSource)
+	}
+}
+
+// PrincipalFromCertificate returns the principal identified by the certificate, or an empty string
+func PrincipalFromCertificate(cert *x509.Certificate, source PrincipalSource) string {
+	switch source {
+	case CommonNamePrincipalSource:
+		return cert.Subject.CommonName
+	case SANPrincipalSource:
+		if len(cert.DNSNames) > 0 {
+			return cert.DNSNames[0]
+		}
+		if len(cert.EmailAddresses) > 0 {
+			return cert.EmailAddresses[0]
+		}
+	}
+	return ""
+}
+
+// ContextWithPrincipal returns a copy of the context that carries the authenticated principal
+func ContextWithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal carried by the context, if any
+func PrincipalFromContext(ctx context.Context) (string, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(string)
+	return principal, ok && principal != ""
+}
+
+// NewCertificatePrincipalHandler creates an HTTP handler that puts the principal identified by the verified
+// client certificate of the request into the request context
+func NewCertificatePrincipalHandler(source PrincipalSource, next http.Handler) http.Handler {
+	if source == NoPrincipalSource {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+			if principal := PrincipalFromCertificate(r.TLS.VerifiedChains[0][0], source); principal != "" {
+				r = r.WithContext(ContextWithPrincipal(r.Context(), principal))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,106 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package security
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePrincipalSource(t *testing.T) {
+	for _, source := range []PrincipalSource{NoPrincipalSource, CommonNamePrincipalSource, SANPrincipalSource} {
+		parsed, err := ParsePrincipalSource(string(source))
+		require.NoError(t, err)
+		assert.Equal(t, source, parsed)
+	}
+	_, err := ParsePrincipalSource("serial")
+	assert.EqualError(t, err, `unknown principal source "serial", expected "cn" or "san"`)
+}
+
+func TestPrincipalFromCertificate(t *testing.T) {
+	cert := &x509.Certificate{
+		Subject:        pkix.Name{CommonName: "frontend"},
+		DNSNames:       []string{"frontend.example.com", "www.example.com"},
+		EmailAddresses: []string{"ops@example.com"},
+	}
+	testCases := []struct {
+		cert     *x509.Certificate
+		source   PrincipalSource
+		expected string
+	}{
+		{cert: cert, source: NoPrincipalSource, expected: ""},
+		{cert: cert, source: CommonNamePrincipalSource, expected: "frontend"},
+		{cert: cert, source: SANPrincipalSource, expected: "frontend.example.com"},
+		{cert: &x509.Certificate{EmailAddresses: []string{"ops@example.com"}}, source: SANPrincipalSource, expected: "ops@example.com"},
+		{cert: &x509.Certificate{}, source: SANPrincipalSource, expected: ""},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, PrincipalFromCertificate(testCase.cert, testCase.source))
+	}
+}
+
+func TestPrincipalFromContext(t *testing.T) {
+	_, ok := PrincipalFromContext(context.Background())
+	assert.False(t, ok)
+
+	_, ok = PrincipalFromContext(ContextWithPrincipal(context.Background(), ""))
+	assert.False(t, ok)
+
+	principal, ok := PrincipalFromContext(ContextWithPrincipal(context.Background(), "frontend"))
+	assert.True(t, ok)
+	assert.Equal(t, "frontend", principal)
+}
+
+func TestCertificatePrincipalHandler(t *testing.T) {
+	var principal string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = PrincipalFromContext(r.Context())
+	})
+	verified := &tls.ConnectionState{
+		VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "frontend"}}}},
+	}
+	testCases := []struct {
+		source   PrincipalSource
+		state    *tls.ConnectionState
+		expected string
+	}{
+		{source: CommonNamePrincipalSource, state: verified, expected: "frontend"},
+		{source: CommonNamePrincipalSource, state: nil, expected: ""},
+		{source: CommonNamePrincipalSource, state: &tls.ConnectionState{}, expected: ""},
+		{source: NoPrincipalSource, state: verified, expected: ""},
+	}
+	for _, testCase := range testCases {
+		principal = ""
+		r := httptest.NewRequest(http.MethodPost, "/api/traces", nil)
+		r.TLS = testCase.state
+		NewCertificatePrincipalHandler(testCase.source, next).ServeHTTP(httptest.NewRecorder(), r)
+		assert.Equal(t, testCase.expected, principal)
+	}
+}
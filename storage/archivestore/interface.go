@@ -0,0 +1,126 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package archivestore
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/uber/jaeger/model"
+)
+
+// ErrArchiveNotFound is returned by Reader's GetArchive and Writer's DeleteArchive if the trace is not archived.
+var ErrArchiveNotFound = errors.New("archive not found")
+
+// Archive is the metadata of an archived trace
+type Archive struct {
+	TraceID model.TraceID
+	// Author is the free text name given by whoever archived the trace, it is not authenticated
+	Author     string
+	ArchivedAt time.Time
+	Note       string
+	Labels     []string
+}
+
+// Query contains the parameters of a search of the archives. Empty parameters match every archive.
+type Query struct {
+	Author string
+	// Labels must all be labels of the archive
+	Labels []string
+	// Text must be in the note of the archive, ignoring case
+	Text           string
+	ArchivedAfter  time.Time
+	ArchivedBefore time.Time
+	// Limit is the maximum number of archives to find, if positive
+	Limit int
+}
+
+// Writer writes and deletes the metadata of archived traces. The archived spans themselves are written
+// to a spanstore.Writer, and expire with the retention of that storage.
+type Writer interface {
+	// WriteArchive inserts or replaces the metadata of an archived trace
+	WriteArchive(archive *Archive) error
+	DeleteArchive(traceID model.TraceID) error
+}
+
+// Reader finds the metadata of archived traces.
+type Reader interface {
+	GetArchive(traceID model.TraceID) (*Archive, error)
+	// FindArchives returns the archives that match the query, most recently archived first
+	FindArchives(query *Query) ([]*Archive, error)
+}
+
+// Matches returns true if the archive matches all the parameters of the query except the Limit
+func (q *Query) Matches(archive *Archive) bool {
+	if q.Author != "" && q.Author != archive.Author {
+		return false
+	}
+	if !q.ArchivedAfter.IsZero() && archive.ArchivedAt.Before(q.ArchivedAfter) {
+		return false
+	}
+	if !q.ArchivedBefore.IsZero() && archive.ArchivedAt.After(q.ArchivedBefore) {
+		return false
+	}
+	if q.Text != "" && !strings.Contains(strings.ToLower(archive.Note), strings.ToLower(q.Text)) {
+		return false
+	}
+	for _, label := range q.Labels {
+		found := false
+		for _, archiveLabel := range archive.Labels {
+			if archiveLabel == label {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterArchives returns the archives that match the query, most recently archived first,
+// for the storages that cannot search the archives themselves
+func FilterArchives(archives []*Archive, query *Query) []*Archive {
+	var matches []*Archive
+	for _, archive := range archives {
+		if query.Matches(archive) {
+			matches = append(matches, archive)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		ai, aj := matches[i], matches[j]
+		if !ai.ArchivedAt.Equal(aj.ArchivedAt) {
+			return ai.ArchivedAt.After(aj.ArchivedAt)
+		}
+		if ai.TraceID.High != aj.TraceID.High {
+			return ai.TraceID.High < aj.TraceID.High
+		}
+		return ai.TraceID.Low < aj.TraceID.Low
+	})
+	if query.Limit > 0 && len(matches) > query.Limit {
+		matches = matches[:query.Limit]
+	}
+	return matches
+}
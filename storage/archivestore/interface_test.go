@@ -0,0 +1,82 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package archivestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/uber/jaeger/model"
+)
+
+var (
+	oldArchive = &Archive{
+		TraceID:    model.TraceID{Low: 1},
+		Author:     "alice",
+		ArchivedAt: time.Unix(100, 0),
+		Note:       "Slow checkout",
+		Labels:     []string{"latency", "checkout"},
+	}
+	newArchive = &Archive{
+		TraceID:    model.TraceID{Low: 2},
+		Author:     "bob",
+		ArchivedAt: time.Unix(200, 0),
+		Note:       "Failed payment",
+		Labels:     []string{"error"},
+	}
+	sameTimeArchive = &Archive{
+		TraceID:    model.TraceID{High: 1},
+		Author:     "bob",
+		ArchivedAt: time.Unix(200, 0),
+	}
+)
+
+func TestQueryMatches(t *testing.T) {
+	testCases := []struct {
+		query   Query
+		matches bool
+	}{
+		{Query{}, true},
+		{Query{Author: "alice"}, true},
+		{Query{Author: "bob"}, false},
+		{Query{Labels: []string{"checkout", "latency"}}, true},
+		{Query{Labels: []string{"checkout", "error"}}, false},
+		{Query{Text: "CHECKOUT"}, true},
+		{Query{Text: "payment"}, false},
+		{Query{ArchivedAfter: time.Unix(100, 0), ArchivedBefore: time.Unix(100, 0)}, true},
+		{Query{ArchivedAfter: time.Unix(101, 0)}, false},
+		{Query{ArchivedBefore: time.Unix(99, 0)}, false},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.matches, testCase.query.Matches(oldArchive), "%+v", testCase.query)
+	}
+}
+
+func TestFilterArchives(t *testing.T) {
+	archives := []*Archive{oldArchive, newArchive, sameTimeArchive}
+	assert.Equal(t, []*Archive{newArchive, sameTimeArchive, oldArchive}, FilterArchives(archives, &Query{}))
+	assert.Equal(t, []*Archive{newArchive, sameTimeArchive}, FilterArchives(archives, &Query{Author: "bob"}))
+	assert.Equal(t, []*Archive{newArchive}, FilterArchives(archives, &Query{Limit: 1}))
+	assert.Empty(t, FilterArchives(archives, &Query{Author: "carol"}))
+}
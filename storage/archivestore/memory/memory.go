@@ -0,0 +1,84 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package memory
+
+import (
+	"sync"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/storage/archivestore"
+)
+
+// Store is an unbounded in-memory store of the metadata of archived traces
+type Store struct {
+	sync.RWMutex
+	archives map[model.TraceID]archivestore.Archive
+}
+
+// NewStore creates an in-memory archive store
+func NewStore() *Store {
+	return &Store{
+		archives: map[model.TraceID]archivestore.Archive{},
+	}
+}
+
+// WriteArchive inserts or replaces the metadata of an archived trace
+func (m *Store) WriteArchive(archive *archivestore.Archive) error {
+	m.Lock()
+	defer m.Unlock()
+	m.archives[archive.TraceID] = *archive
+	return nil
+}
+
+// DeleteArchive removes the metadata of an archived trace
+func (m *Store) DeleteArchive(traceID model.TraceID) error {
+	m.Lock()
+	defer m.Unlock()
+	if _, ok := m.archives[traceID]; !ok {
+		return archivestore.ErrArchiveNotFound
+	}
+	delete(m.archives, traceID)
+	return nil
+}
+
+// GetArchive returns the metadata of an archived trace
+func (m *Store) GetArchive(traceID model.TraceID) (*archivestore.Archive, error) {
+	m.RLock()
+	defer m.RUnlock()
+	archive, ok := m.archives[traceID]
+	if !ok {
+		return nil, archivestore.ErrArchiveNotFound
+	}
+	return &archive, nil
+}
+
+// FindArchives returns the archives that match the query, most recently archived first
+func (m *Store) FindArchives(query *archivestore.Query) ([]*archivestore.Archive, error) {
+	m.RLock()
+	defer m.RUnlock()
+	archives := make([]*archivestore.Archive, 0, len(m.archives))
+	for _, archive := range m.archives {
+		archive := archive
+		archives = append(archives, &archive)
+	}
+	return archivestore.FilterArchives(archives, query), nil
+}
@@ -0,0 +1,91 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/storage/archivestore"
+)
+
+var testingArchive = &archivestore.Archive{
+	TraceID:    model.TraceID{Low: 1, High: 2},
+	Author:     "alice",
+	ArchivedAt: time.Unix(100, 0),
+	Note:       "Slow checkout",
+	Labels:     []string{"latency"},
+}
+
+func TestStoreWriteAndGetArchive(t *testing.T) {
+	store := NewStore()
+	require.NoError(t, store.WriteArchive(testingArchive))
+	archive, err := store.GetArchive(testingArchive.TraceID)
+	require.NoError(t, err)
+	assert.Equal(t, testingArchive, archive)
+
+	_, err = store.GetArchive(model.TraceID{Low: 3})
+	assert.Equal(t, archivestore.ErrArchiveNotFound, err)
+}
+
+func TestStoreReplaceArchive(t *testing.T) {
+	store := NewStore()
+	require.NoError(t, store.WriteArchive(testingArchive))
+	replacement := *testingArchive
+	replacement.Note = "Replaced"
+	require.NoError(t, store.WriteArchive(&replacement))
+	archive, err := store.GetArchive(testingArchive.TraceID)
+	require.NoError(t, err)
+	assert.Equal(t, "Replaced", archive.Note)
+}
+
+func TestStoreDeleteArchive(t *testing.T) {
+	store := NewStore()
+	require.NoError(t, store.WriteArchive(testingArchive))
+	require.NoError(t, store.DeleteArchive(testingArchive.TraceID))
+	_, err := store.GetArchive(testingArchive.TraceID)
+	assert.Equal(t, archivestore.ErrArchiveNotFound, err)
+	assert.Equal(t, archivestore.ErrArchiveNotFound, store.DeleteArchive(testingArchive.TraceID))
+}
+
+func TestStoreFindArchives(t *testing.T) {
+	store := NewStore()
+	other := &archivestore.Archive{
+		TraceID:    model.TraceID{Low: 3},
+		Author:     "bob",
+		ArchivedAt: time.Unix(200, 0),
+	}
+	require.NoError(t, store.WriteArchive(testingArchive))
+	require.NoError(t, store.WriteArchive(other))
+
+	archives, err := store.FindArchives(&archivestore.Query{})
+	require.NoError(t, err)
+	assert.Equal(t, []*archivestore.Archive{other, testingArchive}, archives)
+
+	archives, err = store.FindArchives(&archivestore.Query{Labels: []string{"latency"}})
+	require.NoError(t, err)
+	assert.Equal(t, []*archivestore.Archive{testingArchive}, archives)
+}
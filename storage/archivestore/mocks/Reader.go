@@ -0,0 +1,77 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mocks
+
+import archivestore "github.com/uber/jaeger/storage/archivestore"
+import mock "github.com/stretchr/testify/mock"
+import model "github.com/uber/jaeger/model"
+
+// Reader is an autogenerated mock type for the Reader type
+type Reader struct {
+	mock.Mock
+}
+
+// FindArchives provides a mock function with given fields: query
+func (_m *Reader) FindArchives(query *archivestore.Query) ([]*archivestore.Archive, error) {
+	ret := _m.Called(query)
+
+	var r0 []*archivestore.Archive
+	if rf, ok := ret.Get(0).(func(*archivestore.Query) []*archivestore.Archive); ok {
+		r0 = rf(query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*archivestore.Archive)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*archivestore.Query) error); ok {
+		r1 = rf(query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetArchive provides a mock function with given fields: traceID
+func (_m *Reader) GetArchive(traceID model.TraceID) (*archivestore.Archive, error) {
+	ret := _m.Called(traceID)
+
+	var r0 *archivestore.Archive
+	if rf, ok := ret.Get(0).(func(model.TraceID) *archivestore.Archive); ok {
+		r0 = rf(traceID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*archivestore.Archive)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(model.TraceID) error); ok {
+		r1 = rf(traceID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
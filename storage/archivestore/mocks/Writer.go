@@ -0,0 +1,59 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mocks
+
+import archivestore "github.com/uber/jaeger/storage/archivestore"
+import mock "github.com/stretchr/testify/mock"
+import model "github.com/uber/jaeger/model"
+
+// Writer is an autogenerated mock type for the Writer type
+type Writer struct {
+	mock.Mock
+}
+
+// DeleteArchive provides a mock function with given fields: traceID
+func (_m *Writer) DeleteArchive(traceID model.TraceID) error {
+	ret := _m.Called(traceID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(model.TraceID) error); ok {
+		r0 = rf(traceID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// WriteArchive provides a mock function with given fields: archive
+func (_m *Writer) WriteArchive(archive *archivestore.Archive) error {
+	ret := _m.Called(archive)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*archivestore.Archive) error); ok {
+		r0 = rf(archive)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mocks
+
+import dependencystore "github.com/uber/jaeger/storage/dependencystore"
+import mock "github.com/stretchr/testify/mock"
+import model "github.com/uber/jaeger/model"
+import time "time"
+
+// Writer is an autogenerated mock type for the Writer type
+type Writer struct {
+	mock.Mock
+}
+
+// WriteDependencies provides a mock function with given fields: ts, dependencies
+func (_m *Writer) WriteDependencies(ts time.Time, dependencies []model.DependencyLink) error {
+	ret := _m.Called(ts, dependencies)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(time.Time, []model.DependencyLink) error); ok {
+		r0 = rf(ts, dependencies)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+var _ dependencystore.Writer = (*Writer)(nil)
@@ -0,0 +1,67 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package metricstore
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// bucketsPerPowerOfTwo is the number of latency buckets between a duration and its double,
+// which bounds the error of the latency quantiles to 2^(1/4), about 19%
+const bucketsPerPowerOfTwo = 4
+
+// LatencyBucket returns the bucket of a duration, whose upper bound is 2^(bucket/bucketsPerPowerOfTwo) microseconds
+func LatencyBucket(duration time.Duration) int {
+	micros := float64(duration / time.Microsecond)
+	if micros <= 1 {
+		return 0
+	}
+	return int(math.Ceil(math.Log2(micros) * bucketsPerPowerOfTwo))
+}
+
+// LatencyBucketUpperBound returns the upper bound of a bucket in milliseconds
+func LatencyBucketUpperBound(bucket int) float64 {
+	return math.Pow(2, float64(bucket)/bucketsPerPowerOfTwo) / float64(time.Millisecond/time.Microsecond)
+}
+
+// LatencyQuantile estimates the quantile of a histogram of the number of spans per latency bucket
+// by the upper bound of the bucket that holds it
+func LatencyQuantile(histogram map[int]int64, quantile float64) float64 {
+	var total int64
+	buckets := make([]int, 0, len(histogram))
+	for bucket, count := range histogram {
+		buckets = append(buckets, bucket)
+		total += count
+	}
+	sort.Ints(buckets)
+	rank := int64(math.Ceil(quantile * float64(total)))
+	var count int64
+	for _, bucket := range buckets {
+		count += histogram[bucket]
+		if count >= rank {
+			return LatencyBucketUpperBound(bucket)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,58 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package metricstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyBucket(t *testing.T) {
+	testCases := []struct {
+		duration time.Duration
+		bucket   int
+	}{
+		{duration: 0, bucket: 0},
+		{duration: time.Microsecond, bucket: 0},
+		{duration: 2 * time.Microsecond, bucket: 4},
+		{duration: 3 * time.Microsecond, bucket: 7},
+		{duration: time.Millisecond, bucket: 40},
+		{duration: 1024 * time.Microsecond, bucket: 40},
+		{duration: 1025 * time.Microsecond, bucket: 41},
+	}
+	for _, testCase := range testCases {
+		bucket := LatencyBucket(testCase.duration)
+		assert.Equal(t, testCase.bucket, bucket, "duration %v", testCase.duration)
+		assert.True(t, testCase.duration <= time.Duration(LatencyBucketUpperBound(bucket)*float64(time.Millisecond)))
+	}
+}
+
+func TestLatencyQuantile(t *testing.T) {
+	histogram := map[int]int64{40: 90, 44: 9, 48: 1}
+	assert.Equal(t, 1.024, LatencyQuantile(histogram, 0.5))
+	assert.Equal(t, 1.024, LatencyQuantile(histogram, 0.9))
+	assert.Equal(t, 2.048, LatencyQuantile(histogram, 0.95))
+	assert.Equal(t, 4.096, LatencyQuantile(histogram, 1))
+	assert.Equal(t, 0.0, LatencyQuantile(map[int]int64{}, 0.5))
+}
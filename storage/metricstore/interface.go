@@ -0,0 +1,78 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package metricstore
+
+import (
+	"time"
+
+	"github.com/uber/jaeger/model"
+)
+
+// QueryParameters contains the parameters of a query for the metrics of a service or operation
+type QueryParameters struct {
+	ServiceName string
+	// OperationName is empty to query the metrics of all the operations of the service
+	OperationName string
+	EndTime       time.Time
+	Lookback      time.Duration
+	Step          time.Duration
+	// Quantile of the latencies, in (0, 1]
+	Quantile float64
+}
+
+// Reader can load RED (rate, errors, duration) metrics derived from the stored spans.
+// The metrics are time series of a point per step over [EndTime-Lookback, EndTime].
+// Steps are aligned on multiples of Step since epoch.
+type Reader interface {
+	// GetLatencies returns the Quantile of the span durations in milliseconds, for the steps that have spans
+	GetLatencies(query *QueryParameters) ([]model.MetricPoint, error)
+	// GetCallRates returns the number of spans per second, for every step
+	GetCallRates(query *QueryParameters) ([]model.MetricPoint, error)
+	// GetErrorRates returns the fraction of the spans that are errors, for the steps that have spans
+	GetErrorRates(query *QueryParameters) ([]model.MetricPoint, error)
+}
+
+// AtResolution returns a copy of the query whose Step is rounded up to a multiple of resolution,
+// the time resolution at which a storage aggregates the metrics
+func (q *QueryParameters) AtResolution(resolution time.Duration) *QueryParameters {
+	query := *q
+	if remainder := query.Step % resolution; remainder != 0 || query.Step == 0 {
+		query.Step += resolution - remainder
+	}
+	return &query
+}
+
+// StepStart returns the start of the step that contains t
+func (q *QueryParameters) StepStart(t time.Time) time.Time {
+	micros := model.TimeAsEpochMicroseconds(t)
+	stepMicros := model.DurationAsMicroseconds(q.Step)
+	return model.EpochMicrosecondsAsTime(micros - micros%stepMicros)
+}
+
+// StepStarts returns the start of every step of the query
+func (q *QueryParameters) StepStarts() []time.Time {
+	var starts []time.Time
+	for start := q.StepStart(q.EndTime.Add(-q.Lookback)); !start.After(q.EndTime); start = start.Add(q.Step) {
+		starts = append(starts, start)
+	}
+	return starts
+}
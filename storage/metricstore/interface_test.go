@@ -0,0 +1,46 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package metricstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAtResolution(t *testing.T) {
+	testCases := []struct {
+		step     time.Duration
+		expected time.Duration
+	}{
+		{step: 0, expected: time.Minute},
+		{step: 15 * time.Second, expected: time.Minute},
+		{step: time.Minute, expected: time.Minute},
+		{step: 90 * time.Second, expected: 2 * time.Minute},
+	}
+	for _, testCase := range testCases {
+		query := &QueryParameters{ServiceName: "svc", Step: testCase.step}
+		assert.Equal(t, &QueryParameters{ServiceName: "svc", Step: testCase.expected}, query.AtResolution(time.Minute))
+		assert.Equal(t, testCase.step, query.Step)
+	}
+}
@@ -0,0 +1,102 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mocks
+
+import metricstore "github.com/uber/jaeger/storage/metricstore"
+import mock "github.com/stretchr/testify/mock"
+import model "github.com/uber/jaeger/model"
+
+// Reader is an autogenerated mock type for the Reader type
+type Reader struct {
+	mock.Mock
+}
+
+// GetCallRates provides a mock function with given fields: query
+func (_m *Reader) GetCallRates(query *metricstore.QueryParameters) ([]model.MetricPoint, error) {
+	ret := _m.Called(query)
+
+	var r0 []model.MetricPoint
+	if rf, ok := ret.Get(0).(func(*metricstore.QueryParameters) []model.MetricPoint); ok {
+		r0 = rf(query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.MetricPoint)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*metricstore.QueryParameters) error); ok {
+		r1 = rf(query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetErrorRates provides a mock function with given fields: query
+func (_m *Reader) GetErrorRates(query *metricstore.QueryParameters) ([]model.MetricPoint, error) {
+	ret := _m.Called(query)
+
+	var r0 []model.MetricPoint
+	if rf, ok := ret.Get(0).(func(*metricstore.QueryParameters) []model.MetricPoint); ok {
+		r0 = rf(query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.MetricPoint)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*metricstore.QueryParameters) error); ok {
+		r1 = rf(query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetLatencies provides a mock function with given fields: query
+func (_m *Reader) GetLatencies(query *metricstore.QueryParameters) ([]model.MetricPoint, error) {
+	ret := _m.Called(query)
+
+	var r0 []model.MetricPoint
+	if rf, ok := ret.Get(0).(func(*metricstore.QueryParameters) []model.MetricPoint); ok {
+		r0 = rf(query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.MetricPoint)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*metricstore.QueryParameters) error); ok {
+		r1 = rf(query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+var _ metricstore.Reader = (*Reader)(nil)
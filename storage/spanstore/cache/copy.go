@@ -0,0 +1,74 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cache
+
+import "github.com/uber/jaeger/model"
+
+// copyTrace returns a copy of the trace that shares no mutable data with it,
+// because the query service adjusters modify the spans in place
+func copyTrace(trace *model.Trace) *model.Trace {
+	if trace == nil {
+		return nil
+	}
+	processes := make(map[*model.Process]*model.Process)
+	spans := make([]*model.Span, len(trace.Spans))
+	for i, span := range trace.Spans {
+		spanCopy := *span
+		spanCopy.References = append([]model.SpanRef(nil), span.References...)
+		spanCopy.Tags = copyKeyValues(span.Tags)
+		if span.Logs != nil {
+			spanCopy.Logs = make([]model.Log, len(span.Logs))
+			for j, log := range span.Logs {
+				spanCopy.Logs[j] = model.Log{Timestamp: log.Timestamp, Fields: copyKeyValues(log.Fields)}
+			}
+		}
+		spanCopy.Warnings = copyStrings(span.Warnings)
+		if span.Process != nil {
+			// the spans of a process keep sharing a single copy of it
+			process, ok := processes[span.Process]
+			if !ok {
+				process = &model.Process{ServiceName: span.Process.ServiceName, Tags: copyKeyValues(span.Process.Tags)}
+				processes[span.Process] = process
+			}
+			spanCopy.Process = process
+		}
+		spans[i] = &spanCopy
+	}
+	return &model.Trace{
+		Spans:    spans,
+		Warnings: copyStrings(trace.Warnings),
+	}
+}
+
+func copyKeyValues(kvs model.KeyValues) model.KeyValues {
+	if kvs == nil {
+		return nil
+	}
+	return append(model.KeyValues(nil), kvs...)
+}
+
+func copyStrings(strings []string) []string {
+	if strings == nil {
+		return nil
+	}
+	return append([]string(nil), strings...)
+}
@@ -0,0 +1,229 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/cache"
+	"github.com/uber/jaeger/storage/spanstore"
+)
+
+const (
+	// listsCacheSize bounds the number of services whose operations are cached
+	listsCacheSize = 10000
+
+	servicesKey         = "services"
+	operationsKeyPrefix = "operations/"
+)
+
+// Options control the staleness of the cached query results
+type Options struct {
+	// MaxTraces is the maximum number of traces in the cache
+	MaxTraces int
+	// TraceTTL is how long a completed trace is cached
+	TraceTTL time.Duration
+	// RecentTraceAge is how long after the end of its last span a trace is considered to be still receiving spans
+	RecentTraceAge time.Duration
+	// RecentTraceTTL is how long a trace still receiving spans is cached
+	RecentTraceTTL time.Duration
+	// ServicesTTL is how long the lists of services and operations are cached
+	ServicesTTL time.Duration
+	// TimeNow is used to override the behavior of default time.Now(), e.g. in tests.
+	TimeNow func() time.Time
+}
+
+// ReadCacheDecorator wraps a spanstore.Reader and caches the traces, services and operations it returns.
+// Concurrent reads of the same trace are coalesced into a single read of the underlying storage.
+// Trace searches are not cached. The spans written to the storage are only seen once their trace expires
+// from the cache, unless they are written through an InvalidatingWriter.
+type ReadCacheDecorator struct {
+	spanReader spanstore.Reader
+	options    Options
+	traces     cache.Cache
+	lists      cache.Cache
+
+	getTraceMetrics      *cacheMetrics
+	getServicesMetrics   *cacheMetrics
+	getOperationsMetrics *cacheMetrics
+	coalescedTraces      metrics.Counter
+
+	callsMu sync.Mutex // protects calls
+	calls   map[model.TraceID]*traceCall
+}
+
+type cacheMetrics struct {
+	Hits   metrics.Counter `metric:"hits"`
+	Misses metrics.Counter `metric:"misses"`
+}
+
+// cachedTrace is a trace with the time after which it must be read again from the storage
+type cachedTrace struct {
+	trace      *model.Trace
+	expiration time.Time
+}
+
+// traceCall is a read of a trace from the storage, shared by all the concurrent reads of the trace
+type traceCall struct {
+	done  sync.WaitGroup
+	trace *model.Trace
+	err   error
+	// invalidated is set if spans of the trace were written during the read, which may have missed them
+	invalidated bool
+}
+
+// NewReadCacheDecorator returns a new ReadCacheDecorator.
+func NewReadCacheDecorator(spanReader spanstore.Reader, options Options, metricsFactory metrics.Factory) *ReadCacheDecorator {
+	if options.TimeNow == nil {
+		options.TimeNow = time.Now
+	}
+	return &ReadCacheDecorator{
+		spanReader: spanReader,
+		options:    options,
+		traces:     cache.NewLRUWithOptions(options.MaxTraces, &cache.Options{TimeNow: options.TimeNow}),
+		lists: cache.NewLRUWithOptions(listsCacheSize, &cache.Options{
+			TTL:     options.ServicesTTL,
+			TimeNow: options.TimeNow,
+		}),
+		getTraceMetrics:      buildCacheMetrics("GetTrace", metricsFactory),
+		getServicesMetrics:   buildCacheMetrics("GetServices", metricsFactory),
+		getOperationsMetrics: buildCacheMetrics("GetOperations", metricsFactory),
+		coalescedTraces:      metricsFactory.Namespace("GetTrace", nil).Counter("coalesced", nil),
+		calls:                make(map[model.TraceID]*traceCall),
+	}
+}
+
+func buildCacheMetrics(namespace string, metricsFactory metrics.Factory) *cacheMetrics {
+	cMetrics := &cacheMetrics{}
+	metrics.Init(cMetrics, metricsFactory.Namespace(namespace, nil), nil)
+	return cMetrics
+}
+
+// GetTrace implements spanstore.Reader#GetTrace. The callers receive their own copy of the trace,
+// which they may adjust without changing the cached trace.
+func (d *ReadCacheDecorator) GetTrace(traceID model.TraceID) (*model.Trace, error) {
+	key := traceID.String()
+	if value := d.traces.Get(key); value != nil {
+		cached := value.(*cachedTrace)
+		if d.options.TimeNow().Before(cached.expiration) {
+			d.getTraceMetrics.Hits.Inc(1)
+			return copyTrace(cached.trace), nil
+		}
+		d.traces.Delete(key)
+	}
+
+	d.callsMu.Lock()
+	if call, ok := d.calls[traceID]; ok {
+		d.callsMu.Unlock()
+		d.coalescedTraces.Inc(1)
+		call.done.Wait()
+		return copyTrace(call.trace), call.err
+	}
+	call := &traceCall{}
+	call.done.Add(1)
+	d.calls[traceID] = call
+	d.callsMu.Unlock()
+
+	d.getTraceMetrics.Misses.Inc(1)
+	call.trace, call.err = d.spanReader.GetTrace(traceID)
+
+	d.callsMu.Lock()
+	if call.err == nil && !call.invalidated {
+		// the storage may keep updating the trace it returned, like the memory store does
+		d.traces.Put(key, &cachedTrace{trace: copyTrace(call.trace), expiration: d.traceExpiration(call.trace)})
+	}
+	delete(d.calls, traceID)
+	d.callsMu.Unlock()
+	call.done.Done()
+	return copyTrace(call.trace), call.err
+}
+
+// traceExpiration returns when a trace read now must be read again, which is sooner if the trace
+// may still receive spans
+func (d *ReadCacheDecorator) traceExpiration(trace *model.Trace) time.Time {
+	now := d.options.TimeNow()
+	var end time.Time
+	for _, span := range trace.Spans {
+		if spanEnd := span.StartTime.Add(span.Duration); spanEnd.After(end) {
+			end = spanEnd
+		}
+	}
+	if now.Sub(end) < d.options.RecentTraceAge {
+		return now.Add(d.options.RecentTraceTTL)
+	}
+	return now.Add(d.options.TraceTTL)
+}
+
+// invalidateSpan removes the trace of a span that was written from the cache, as well as the lists of services
+// and of the operations of its service. A read of the trace in progress is not cached.
+func (d *ReadCacheDecorator) invalidateSpan(span *model.Span) {
+	d.callsMu.Lock()
+	d.traces.Delete(span.TraceID.String())
+	if call, ok := d.calls[span.TraceID]; ok {
+		call.invalidated = true
+	}
+	d.callsMu.Unlock()
+	d.lists.Delete(servicesKey)
+	if span.Process != nil {
+		d.lists.Delete(operationsKeyPrefix + span.Process.ServiceName)
+	}
+}
+
+// GetServices implements spanstore.Reader#GetServices
+func (d *ReadCacheDecorator) GetServices() ([]string, error) {
+	return d.getList(servicesKey, d.getServicesMetrics, d.spanReader.GetServices)
+}
+
+// GetOperations implements spanstore.Reader#GetOperations
+func (d *ReadCacheDecorator) GetOperations(service string) ([]string, error) {
+	return d.getList(operationsKeyPrefix+service, d.getOperationsMetrics, func() ([]string, error) {
+		return d.spanReader.GetOperations(service)
+	})
+}
+
+func (d *ReadCacheDecorator) getList(key string, cMetrics *cacheMetrics, read func() ([]string, error)) ([]string, error) {
+	if value := d.lists.Get(key); value != nil {
+		cMetrics.Hits.Inc(1)
+		return copyStrings(value.([]string)), nil
+	}
+	cMetrics.Misses.Inc(1)
+	list, err := read()
+	if err != nil {
+		return nil, err
+	}
+	d.lists.Put(key, list)
+	return copyStrings(list), nil
+}
+
+// FindTraces implements spanstore.Reader#FindTraces
+func (d *ReadCacheDecorator) FindTraces(query *spanstore.TraceQueryParameters) ([]*model.Trace, error) {
+	return d.spanReader.FindTraces(query)
+}
+
+// FindTracePage implements spanstore.Reader#FindTracePage
+func (d *ReadCacheDecorator) FindTracePage(query *spanstore.TraceQueryParameters) (*spanstore.TracePage, error) {
+	return d.spanReader.FindTracePage(query)
+}
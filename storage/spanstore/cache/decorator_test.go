@@ -0,0 +1,312 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cache
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/storage/spanstore"
+	"github.com/uber/jaeger/storage/spanstore/mocks"
+)
+
+var (
+	testingNow     = time.Unix(10000, 0)
+	testingTraceID = model.TraceID{Low: 1}
+)
+
+var _ spanstore.Reader = &ReadCacheDecorator{} // check API conformance
+
+type cacheTest struct {
+	reader         *mocks.Reader
+	metricsFactory *metrics.LocalFactory
+	now            time.Time
+	decorator      *ReadCacheDecorator
+}
+
+func withCache(fn func(c *cacheTest)) {
+	c := &cacheTest{
+		reader:         &mocks.Reader{},
+		metricsFactory: metrics.NewLocalFactory(0),
+		now:            testingNow,
+	}
+	c.decorator = NewReadCacheDecorator(c.reader, Options{
+		MaxTraces:      2,
+		TraceTTL:       time.Hour,
+		RecentTraceAge: 5 * time.Minute,
+		RecentTraceTTL: 10 * time.Second,
+		ServicesTTL:    30 * time.Second,
+		TimeNow:        func() time.Time { return c.now },
+	}, c.metricsFactory)
+	fn(c)
+}
+
+func (c *cacheTest) assertCounters(t *testing.T, expected map[string]int64) {
+	counters, _ := c.metricsFactory.Snapshot()
+	for name, value := range expected {
+		assert.Equal(t, value, counters[name], name)
+	}
+}
+
+// newTrace returns a trace whose span ended at the given time
+func newTrace(end time.Time) *model.Trace {
+	return &model.Trace{Spans: []*model.Span{
+		{
+			TraceID:   testingTraceID,
+			SpanID:    1,
+			StartTime: end.Add(-time.Second),
+			Duration:  time.Second,
+			Tags:      model.KeyValues{model.String("k", "v")},
+			Logs:      []model.Log{{Timestamp: end, Fields: []model.KeyValue{model.String("event", "done")}}},
+			Process:   &model.Process{ServiceName: "service"},
+		},
+	}}
+}
+
+func TestGetTraceCached(t *testing.T) {
+	withCache(func(c *cacheTest) {
+		trace := newTrace(testingNow.Add(-time.Hour))
+		c.reader.On("GetTrace", testingTraceID).Return(trace, nil).Once()
+
+		first, err := c.decorator.GetTrace(testingTraceID)
+		require.NoError(t, err)
+		assert.Equal(t, trace, first)
+		// the callers may adjust their copy without changing the cached trace
+		first.Spans[0].StartTime = time.Time{}
+		first.Spans[0].Tags[0] = model.String("k", "adjusted")
+		first.Spans[0].Logs[0].Fields[0] = model.String("event", "adjusted")
+		first.Spans[0].Process.ServiceName = "adjusted"
+
+		c.now = testingNow.Add(time.Hour - time.Second)
+		second, err := c.decorator.GetTrace(testingTraceID)
+		require.NoError(t, err)
+		assert.Equal(t, newTrace(testingNow.Add(-time.Hour)), second)
+		c.reader.AssertNumberOfCalls(t, "GetTrace", 1)
+		c.assertCounters(t, map[string]int64{
+			"GetTrace.hits":   1,
+			"GetTrace.misses": 1,
+		})
+	})
+}
+
+func TestGetTraceCachedCopy(t *testing.T) {
+	withCache(func(c *cacheTest) {
+		trace := newTrace(testingNow.Add(-time.Hour))
+		c.reader.On("GetTrace", testingTraceID).Return(trace, nil).Once()
+
+		_, err := c.decorator.GetTrace(testingTraceID)
+		require.NoError(t, err)
+		// the storage updates the trace it returned
+		trace.Spans = append(trace.Spans, &model.Span{TraceID: testingTraceID, SpanID: 2})
+
+		cached, err := c.decorator.GetTrace(testingTraceID)
+		require.NoError(t, err)
+		assert.Equal(t, newTrace(testingNow.Add(-time.Hour)), cached)
+	})
+}
+
+func TestGetTraceExpiration(t *testing.T) {
+	testCases := []struct {
+		caption string
+		end     time.Time
+		ttl     time.Duration
+	}{
+		{
+			caption: "completed trace",
+			end:     testingNow.Add(-5 * time.Minute),
+			ttl:     time.Hour,
+		},
+		{
+			caption: "recent trace",
+			end:     testingNow.Add(-5*time.Minute + time.Second),
+			ttl:     10 * time.Second,
+		},
+	}
+	for _, tc := range testCases {
+		testCase := tc // capture loop var
+		t.Run(testCase.caption, func(t *testing.T) {
+			withCache(func(c *cacheTest) {
+				c.reader.On("GetTrace", testingTraceID).Return(newTrace(testCase.end), nil)
+
+				_, err := c.decorator.GetTrace(testingTraceID)
+				require.NoError(t, err)
+				c.now = testingNow.Add(testCase.ttl - time.Nanosecond)
+				_, err = c.decorator.GetTrace(testingTraceID)
+				require.NoError(t, err)
+				c.reader.AssertNumberOfCalls(t, "GetTrace", 1)
+
+				c.now = testingNow.Add(testCase.ttl)
+				_, err = c.decorator.GetTrace(testingTraceID)
+				require.NoError(t, err)
+				c.reader.AssertNumberOfCalls(t, "GetTrace", 2)
+			})
+		})
+	}
+}
+
+func TestGetTraceErrorNotCached(t *testing.T) {
+	withCache(func(c *cacheTest) {
+		c.reader.On("GetTrace", testingTraceID).Return(nil, spanstore.ErrTraceNotFound)
+
+		for i := 0; i < 2; i++ {
+			trace, err := c.decorator.GetTrace(testingTraceID)
+			assert.Equal(t, spanstore.ErrTraceNotFound, err)
+			assert.Nil(t, trace)
+		}
+		c.reader.AssertNumberOfCalls(t, "GetTrace", 2)
+	})
+}
+
+func TestGetTraceEviction(t *testing.T) {
+	withCache(func(c *cacheTest) {
+		for i := uint64(1); i <= 3; i++ {
+			traceID := model.TraceID{Low: i}
+			c.reader.On("GetTrace", traceID).Return(newTrace(testingNow.Add(-time.Hour)), nil)
+			_, err := c.decorator.GetTrace(traceID)
+			require.NoError(t, err)
+		}
+		// the cache holds 2 traces, so the first one was evicted
+		_, err := c.decorator.GetTrace(model.TraceID{Low: 1})
+		require.NoError(t, err)
+		c.reader.AssertNumberOfCalls(t, "GetTrace", 4)
+	})
+}
+
+func TestGetTraceCoalesced(t *testing.T) {
+	withCache(func(c *cacheTest) {
+		release := make(chan struct{})
+		c.reader.On("GetTrace", testingTraceID).Return(newTrace(testingNow), nil).Run(func(mock.Arguments) {
+			<-release
+		})
+
+		const readers = 5
+		var wg sync.WaitGroup
+		traces := make([]*model.Trace, readers)
+		wg.Add(readers)
+		for i := 0; i < readers; i++ {
+			go func(i int) {
+				defer wg.Done()
+				traces[i], _ = c.decorator.GetTrace(testingTraceID)
+			}(i)
+		}
+		for i := 0; i < 1000; i++ {
+			if counters, _ := c.metricsFactory.Snapshot(); counters["GetTrace.coalesced"] == readers-1 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		close(release)
+		wg.Wait()
+
+		c.reader.AssertNumberOfCalls(t, "GetTrace", 1)
+		c.assertCounters(t, map[string]int64{
+			"GetTrace.coalesced": readers - 1,
+			"GetTrace.misses":    1,
+		})
+		for _, trace := range traces {
+			assert.Equal(t, newTrace(testingNow), trace)
+		}
+		// every reader has its own copy
+		assert.False(t, traces[0] == traces[1])
+	})
+}
+
+func TestGetServicesAndOperations(t *testing.T) {
+	withCache(func(c *cacheTest) {
+		c.reader.On("GetServices").Return([]string{"frontend", "backend"}, nil)
+		c.reader.On("GetOperations", "frontend").Return([]string{"GET"}, nil)
+		c.reader.On("GetOperations", "backend").Return([]string{"query"}, nil)
+
+		for i := 0; i < 2; i++ {
+			services, err := c.decorator.GetServices()
+			require.NoError(t, err)
+			assert.Equal(t, []string{"frontend", "backend"}, services)
+			services[0] = "sorted"
+
+			operations, err := c.decorator.GetOperations("frontend")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"GET"}, operations)
+		}
+		operations, err := c.decorator.GetOperations("backend")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"query"}, operations)
+		c.reader.AssertNumberOfCalls(t, "GetServices", 1)
+		c.reader.AssertNumberOfCalls(t, "GetOperations", 2)
+
+		c.now = testingNow.Add(31 * time.Second)
+		_, err = c.decorator.GetServices()
+		require.NoError(t, err)
+		c.reader.AssertNumberOfCalls(t, "GetServices", 2)
+		c.assertCounters(t, map[string]int64{
+			"GetServices.hits":     1,
+			"GetServices.misses":   2,
+			"GetOperations.hits":   1,
+			"GetOperations.misses": 2,
+		})
+	})
+}
+
+func TestGetServicesErrorNotCached(t *testing.T) {
+	withCache(func(c *cacheTest) {
+		c.reader.On("GetServices").Return(nil, errors.New("storage down"))
+		c.reader.On("GetOperations", "frontend").Return(nil, errors.New("storage down"))
+
+		for i := 0; i < 2; i++ {
+			_, err := c.decorator.GetServices()
+			assert.EqualError(t, err, "storage down")
+			_, err = c.decorator.GetOperations("frontend")
+			assert.EqualError(t, err, "storage down")
+		}
+		c.reader.AssertNumberOfCalls(t, "GetServices", 2)
+		c.reader.AssertNumberOfCalls(t, "GetOperations", 2)
+	})
+}
+
+func TestFindTracesNotCached(t *testing.T) {
+	withCache(func(c *cacheTest) {
+		query := &spanstore.TraceQueryParameters{
+			ServiceName: "frontend",
+			Cursor:      "next",
+		}
+		c.reader.On("FindTraces", query).Return([]*model.Trace{newTrace(testingNow)}, nil)
+		c.reader.On("FindTracePage", query).Return(&spanstore.TracePage{NextCursor: "last"}, nil)
+
+		for i := 0; i < 2; i++ {
+			traces, err := c.decorator.FindTraces(query)
+			require.NoError(t, err)
+			assert.Len(t, traces, 1)
+			page, err := c.decorator.FindTracePage(query)
+			require.NoError(t, err)
+			assert.Equal(t, "last", page.NextCursor)
+		}
+		c.reader.AssertNumberOfCalls(t, "FindTraces", 2)
+		c.reader.AssertNumberOfCalls(t, "FindTracePage", 2)
+	})
+}
@@ -0,0 +1,50 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cache
+
+import (
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/storage/spanstore"
+)
+
+// InvalidatingWriter wraps a spanstore.Writer of the storage read through a ReadCacheDecorator, such as
+// the writer of imported traces, so that the cached traces, services and operations it updates are read again.
+type InvalidatingWriter struct {
+	spanWriter spanstore.Writer
+	readCache  *ReadCacheDecorator
+}
+
+// NewInvalidatingWriter returns a new InvalidatingWriter.
+func NewInvalidatingWriter(spanWriter spanstore.Writer, readCache *ReadCacheDecorator) *InvalidatingWriter {
+	return &InvalidatingWriter{
+		spanWriter: spanWriter,
+		readCache:  readCache,
+	}
+}
+
+// WriteSpan implements spanstore.Writer#WriteSpan. The trace of the span is removed from the cache even if
+// the write fails, since the span may have been partially written.
+func (w *InvalidatingWriter) WriteSpan(span *model.Span) error {
+	err := w.spanWriter.WriteSpan(span)
+	w.readCache.invalidateSpan(span)
+	return err
+}
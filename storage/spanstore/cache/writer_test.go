@@ -0,0 +1,109 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/storage/spanstore"
+	"github.com/uber/jaeger/storage/spanstore/mocks"
+)
+
+var _ spanstore.Writer = &InvalidatingWriter{} // check API conformance
+
+func TestInvalidatingWriter(t *testing.T) {
+	withCache(func(c *cacheTest) {
+		trace := newTrace(testingNow.Add(-time.Hour))
+		c.reader.On("GetTrace", testingTraceID).Return(trace, nil)
+		c.reader.On("GetServices").Return([]string{"service"}, nil)
+		c.reader.On("GetOperations", "service").Return([]string{"op"}, nil)
+		writer := &mocks.Writer{}
+		writer.On("WriteSpan", trace.Spans[0]).Return(nil).Once()
+		writer.On("WriteSpan", trace.Spans[0]).Return(errors.New("write failed")).Once()
+		invalidatingWriter := NewInvalidatingWriter(writer, c.decorator)
+
+		read := func() {
+			_, err := c.decorator.GetTrace(testingTraceID)
+			require.NoError(t, err)
+			_, err = c.decorator.GetServices()
+			require.NoError(t, err)
+			_, err = c.decorator.GetOperations("service")
+			require.NoError(t, err)
+		}
+		read()
+		read()
+		require.NoError(t, invalidatingWriter.WriteSpan(trace.Spans[0]))
+		read()
+		assert.EqualError(t, invalidatingWriter.WriteSpan(trace.Spans[0]), "write failed")
+		read()
+
+		for _, method := range []string{"GetTrace", "GetServices", "GetOperations"} {
+			c.reader.AssertNumberOfCalls(t, method, 3)
+		}
+	})
+}
+
+func TestInvalidatingWriterDuringRead(t *testing.T) {
+	withCache(func(c *cacheTest) {
+		trace := newTrace(testingNow.Add(-time.Hour))
+		reading := make(chan struct{})
+		release := make(chan struct{})
+		c.reader.On("GetTrace", testingTraceID).Return(trace, nil).Run(func(mock.Arguments) {
+			reading <- struct{}{}
+			<-release
+		}).Once()
+		c.reader.On("GetTrace", testingTraceID).Return(trace, nil)
+		writer := &mocks.Writer{}
+		writer.On("WriteSpan", trace.Spans[0]).Return(nil)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			c.decorator.GetTrace(testingTraceID)
+		}()
+		<-reading
+		require.NoError(t, NewInvalidatingWriter(writer, c.decorator).WriteSpan(trace.Spans[0]))
+		close(release)
+		<-done
+
+		// the trace read before the write was not cached
+		_, err := c.decorator.GetTrace(testingTraceID)
+		require.NoError(t, err)
+		c.reader.AssertNumberOfCalls(t, "GetTrace", 2)
+	})
+}
+
+func TestInvalidatingWriterWithoutProcess(t *testing.T) {
+	withCache(func(c *cacheTest) {
+		span := &model.Span{TraceID: testingTraceID}
+		writer := &mocks.Writer{}
+		writer.On("WriteSpan", span).Return(nil)
+		assert.NoError(t, NewInvalidatingWriter(writer, c.decorator).WriteSpan(span))
+	})
+}
@@ -0,0 +1,53 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package spanstore_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/uber/jaeger/storage/spanstore"
+)
+
+type testPosition struct {
+	Offset    int    `json:"o"`
+	PageState []byte `json:"p"`
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor, err := EncodeCursor(testPosition{Offset: 20, PageState: []byte{1, 2, 3}})
+	require.NoError(t, err)
+	var position testPosition
+	require.NoError(t, DecodeCursor(cursor, &position))
+	assert.Equal(t, testPosition{Offset: 20, PageState: []byte{1, 2, 3}}, position)
+}
+
+func TestCursorErrors(t *testing.T) {
+	_, err := EncodeCursor(func() {})
+	assert.Error(t, err)
+
+	var position testPosition
+	assert.Equal(t, ErrInvalidCursor, DecodeCursor("!", &position))
+	assert.Equal(t, ErrInvalidCursor, DecodeCursor("bm90IGpzb24", &position))
+}
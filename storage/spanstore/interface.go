@@ -35,14 +35,21 @@ type Writer interface {
 var (
 	// ErrTraceNotFound is returned by Reader's GetTrace if no data is found for given trace ID.
 	ErrTraceNotFound = errors.New("trace not found")
+
+	// ErrInvalidCursor is returned by Reader's FindTracePage if the cursor was not returned by the same storage.
+	ErrInvalidCursor = errors.New("invalid cursor")
 )
 
+// UnknownTotal is the Total of a TracePage when the storage cannot cheaply count the matching traces.
+const UnknownTotal = -1
+
 // Reader finds and loads traces and other data from storage.
 type Reader interface {
 	GetTrace(traceID model.TraceID) (*model.Trace, error)
 	GetServices() ([]string, error)
 	GetOperations(service string) ([]string, error)
 	FindTraces(query *TraceQueryParameters) ([]*model.Trace, error)
+	FindTracePage(query *TraceQueryParameters) (*TracePage, error)
 }
 
 // TraceQueryParameters contains parameters of a trace query.
@@ -55,4 +62,21 @@ type TraceQueryParameters struct {
 	DurationMin   time.Duration
 	DurationMax   time.Duration
 	NumTraces     int
+	// TagQuery is an optional expression on the tags of the spans, in addition to Tags
+	TagQuery TagQuery
+	// Cursor is the NextCursor of the previous page of the same query, or empty for the first page.
+	// It is only used by FindTracePage.
+	Cursor string
+}
+
+// TracePage is a page of the traces that match a query.
+type TracePage struct {
+	Traces []*model.Trace
+	// NextCursor is an opaque cursor that retrieves the next page when passed in TraceQueryParameters,
+	// or empty if there are no more traces.
+	NextCursor string
+	// Offset is the number of traces returned by the previous pages.
+	Offset int
+	// Total is the number of traces that match the query, or UnknownTotal.
+	Total int
 }
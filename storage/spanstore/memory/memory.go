@@ -22,16 +22,25 @@ package memory
 
 import (
 	"errors"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/uber/jaeger/model"
 	"github.com/uber/jaeger/model/adjuster"
+	"github.com/uber/jaeger/model/analysis"
 	"github.com/uber/jaeger/storage/spanstore"
 )
 
+const defaultNumTraces = 100
+
 var errTraceNotFound = errors.New("Trace was not found")
 
+// cursor is the position of a FindTracePage query in the sorted matching traces
+type cursor struct {
+	Offset int `json:"offset"`
+}
+
 // Store is an unbounded in-memory store of traces
 type Store struct {
 	// TODO: make this a bounded memory store
@@ -39,6 +48,7 @@ type Store struct {
 	traces     map[model.TraceID]*model.Trace
 	services   map[string]struct{}
 	operations map[string]map[string]struct{}
+	metrics    map[metricsKey]*operationMetrics
 	deduper    adjuster.Adjuster
 }
 
@@ -48,6 +58,7 @@ func NewStore() *Store {
 		traces:     map[model.TraceID]*model.Trace{},
 		services:   map[string]struct{}{},
 		operations: map[string]map[string]struct{}{},
+		metrics:    map[metricsKey]*operationMetrics{},
 		deduper:    adjuster.SpanIDDeduper(),
 	}
 }
@@ -57,46 +68,16 @@ func (m *Store) GetDependencies(endTs time.Time, lookback time.Duration) ([]mode
 	// deduper used below can modify the spans, so we take an exclusive lock
 	m.Lock()
 	defer m.Unlock()
-	deps := map[string]*model.DependencyLink{}
+	var traces []*model.Trace
 	startTs := endTs.Add(-1 * lookback)
 	for _, orig := range m.traces {
 		// SpanIDDeduper never returns an err
 		trace, _ := m.deduper.Adjust(orig)
 		if m.traceIsBetweenStartAndEnd(startTs, endTs, trace) {
-			for _, s := range trace.Spans {
-				parentSpan := m.findSpan(trace, s.ParentSpanID)
-				if parentSpan != nil {
-					if parentSpan.Process.ServiceName == s.Process.ServiceName {
-						continue
-					}
-					depKey := parentSpan.Process.ServiceName + "&&&" + s.Process.ServiceName
-					if _, ok := deps[depKey]; !ok {
-						deps[depKey] = &model.DependencyLink{
-							Parent:    parentSpan.Process.ServiceName,
-							Child:     s.Process.ServiceName,
-							CallCount: 1,
-						}
-					} else {
-						deps[depKey].CallCount++
-					}
-				}
-			}
-		}
-	}
-	retMe := make([]model.DependencyLink, 0, len(deps))
-	for _, dep := range deps {
-		retMe = append(retMe, *dep)
-	}
-	return retMe, nil
-}
-
-func (m *Store) findSpan(trace *model.Trace, spanID model.SpanID) *model.Span {
-	for _, s := range trace.Spans {
-		if s.SpanID == spanID {
-			return s
+			traces = append(traces, trace)
 		}
 	}
-	return nil
+	return analysis.DependencyLinks(traces), nil
 }
 
 func (m *Store) traceIsBetweenStartAndEnd(startTs, endTs time.Time, trace *model.Trace) bool {
@@ -121,6 +102,7 @@ func (m *Store) WriteSpan(span *model.Span) error {
 		m.traces[span.TraceID] = &model.Trace{}
 	}
 	m.traces[span.TraceID].Spans = append(m.traces[span.TraceID].Spans, span)
+	m.addMetrics(span)
 
 	return nil
 }
@@ -163,6 +145,10 @@ func (m *Store) GetOperations(service string) ([]string, error) {
 
 // FindTraces returns all traces in the query parameters are satisfied by a trace's span
 func (m *Store) FindTraces(query *spanstore.TraceQueryParameters) ([]*model.Trace, error) {
+	matchTags, err := newTagMatcher(query.TagQuery)
+	if err != nil {
+		return nil, err
+	}
 	m.RLock()
 	defer m.RUnlock()
 	var retMe []*model.Trace
@@ -170,23 +156,104 @@ func (m *Store) FindTraces(query *spanstore.TraceQueryParameters) ([]*model.Trac
 		if len(retMe) >= query.NumTraces {
 			return retMe, nil
 		}
-		if m.validTrace(trace, query) {
+		if m.validTrace(trace, query, matchTags) {
 			retMe = append(retMe, trace)
 		}
 	}
 	return retMe, nil
 }
 
-func (m *Store) validTrace(trace *model.Trace, query *spanstore.TraceQueryParameters) bool {
+// FindTracePage returns a page of the traces that satisfy the query, newest first
+func (m *Store) FindTracePage(query *spanstore.TraceQueryParameters) (*spanstore.TracePage, error) {
+	var position cursor
+	if query.Cursor != "" {
+		if err := spanstore.DecodeCursor(query.Cursor, &position); err != nil {
+			return nil, err
+		}
+		if position.Offset < 0 {
+			return nil, spanstore.ErrInvalidCursor
+		}
+	}
+	numTraces := query.NumTraces
+	if numTraces <= 0 {
+		numTraces = defaultNumTraces
+	}
+	matches, err := m.findSortedTraces(query)
+	if err != nil {
+		return nil, err
+	}
+	page := &spanstore.TracePage{
+		Offset: position.Offset,
+		Total:  len(matches),
+	}
+	if position.Offset >= len(matches) {
+		return page, nil
+	}
+	end := position.Offset + numTraces
+	if end < len(matches) {
+		nextCursor, err := spanstore.EncodeCursor(cursor{Offset: end})
+		if err != nil {
+			return nil, err
+		}
+		page.NextCursor = nextCursor
+	} else {
+		end = len(matches)
+	}
+	page.Traces = matches[position.Offset:end]
+	return page, nil
+}
+
+// findSortedTraces returns all traces that satisfy the query, ordered by start time, newest first,
+// so that the pages returned by FindTracePage are stable
+func (m *Store) findSortedTraces(query *spanstore.TraceQueryParameters) ([]*model.Trace, error) {
+	matchTags, err := newTagMatcher(query.TagQuery)
+	if err != nil {
+		return nil, err
+	}
+	m.RLock()
+	defer m.RUnlock()
+	var matches []*model.Trace
+	startTimes := make(map[*model.Trace]time.Time)
+	for _, trace := range m.traces {
+		if m.validTrace(trace, query, matchTags) {
+			matches = append(matches, trace)
+			startTimes[trace] = m.traceStartTime(trace)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		ti, tj := startTimes[matches[i]], startTimes[matches[j]]
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		idi, idj := matches[i].Spans[0].TraceID, matches[j].Spans[0].TraceID
+		if idi.High != idj.High {
+			return idi.High < idj.High
+		}
+		return idi.Low < idj.Low
+	})
+	return matches, nil
+}
+
+func (m *Store) traceStartTime(trace *model.Trace) time.Time {
+	var startTime time.Time
+	for _, span := range trace.Spans {
+		if startTime.IsZero() || span.StartTime.Before(startTime) {
+			startTime = span.StartTime
+		}
+	}
+	return startTime
+}
+
+func (m *Store) validTrace(trace *model.Trace, query *spanstore.TraceQueryParameters, matchTags tagMatcher) bool {
 	for _, span := range trace.Spans {
-		if m.validSpan(span, query) {
+		if m.validSpan(span, query, matchTags) {
 			return true
 		}
 	}
 	return false
 }
 
-func (m *Store) validSpan(span *model.Span, query *spanstore.TraceQueryParameters) bool {
+func (m *Store) validSpan(span *model.Span, query *spanstore.TraceQueryParameters, matchTags tagMatcher) bool {
 	if query.ServiceName != span.Process.ServiceName {
 		return false
 	}
@@ -219,7 +286,7 @@ func (m *Store) validSpan(span *model.Span, query *spanstore.TraceQueryParameter
 			return false
 		}
 	}
-	return true
+	return matchTags(spanKVs)
 }
 
 // TODO: this is a good candidate function to have on a span
@@ -310,3 +310,60 @@ func TestStoreGetTrace(t *testing.T) {
 		})
 	}
 }
+
+func TestStoreFindTracePage(t *testing.T) {
+	withMemoryStore(func(store *Store) {
+		for i := 1; i <= 5; i++ {
+			store.WriteSpan(&model.Span{
+				TraceID:       model.TraceID{Low: uint64(i)},
+				SpanID:        model.SpanID(1),
+				Process:       &model.Process{ServiceName: "service"},
+				OperationName: "operation",
+				StartTime:     time.Unix(int64(100*i), 0),
+			})
+		}
+		query := &spanstore.TraceQueryParameters{ServiceName: "service", NumTraces: 2}
+		var traceIDs []uint64
+		var offsets []int
+		for {
+			page, err := store.FindTracePage(query)
+			assert.NoError(t, err)
+			assert.Equal(t, 5, page.Total)
+			offsets = append(offsets, page.Offset)
+			for _, trace := range page.Traces {
+				traceIDs = append(traceIDs, trace.Spans[0].TraceID.Low)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			query.Cursor = page.NextCursor
+		}
+		assert.Equal(t, []uint64{5, 4, 3, 2, 1}, traceIDs)
+		assert.Equal(t, []int{0, 2, 4}, offsets)
+	})
+}
+
+func TestStoreFindTracePageInvalidCursor(t *testing.T) {
+	withPopulatedMemoryStore(func(store *Store) {
+		for _, cursor := range []string{"!", "eyJvZmZzZXQiOi0xfQ"} {
+			page, err := store.FindTracePage(&spanstore.TraceQueryParameters{Cursor: cursor})
+			assert.Nil(t, page)
+			assert.Equal(t, spanstore.ErrInvalidCursor, err)
+		}
+	})
+}
+
+func TestStoreFindTracePagePastEnd(t *testing.T) {
+	withPopulatedMemoryStore(func(store *Store) {
+		cursor, err := spanstore.EncodeCursor(map[string]int{"offset": 10})
+		assert.NoError(t, err)
+		page, err := store.FindTracePage(&spanstore.TraceQueryParameters{
+			ServiceName: testingSpan.Process.ServiceName,
+			Cursor:      cursor,
+		})
+		assert.NoError(t, err)
+		assert.Empty(t, page.Traces)
+		assert.Equal(t, 1, page.Total)
+		assert.Equal(t, "", page.NextCursor)
+	})
+}
@@ -0,0 +1,141 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package memory
+
+import (
+	"time"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/storage/metricstore"
+)
+
+// metricsResolution is the time resolution of the operation metrics
+const metricsResolution = time.Minute
+
+// metricsKey identifies the metrics of an operation over a minute,
+// the metrics of all the operations of a service have an empty operation name
+type metricsKey struct {
+	serviceName   string
+	operationName string
+	ts            int64
+}
+
+// operationMetrics are the RED metrics of an operation, the latencies are the number of spans per latency bucket
+type operationMetrics struct {
+	calls     int64
+	errors    int64
+	latencies map[int]int64
+}
+
+func (m *operationMetrics) add(other *operationMetrics) {
+	m.calls += other.calls
+	m.errors += other.errors
+	for bucket, count := range other.latencies {
+		m.latencies[bucket] += count
+	}
+}
+
+// stepMetrics are the metrics of the spans that start in a step
+type stepMetrics struct {
+	start time.Time
+	operationMetrics
+}
+
+// addMetrics adds the span to the metrics of its operation and of its service. Must be called with the lock held.
+func (m *Store) addMetrics(span *model.Span) {
+	ts := span.StartTime.Truncate(metricsResolution).Unix()
+	latency := metricstore.LatencyBucket(span.Duration)
+	isError := span.IsError()
+	for _, operationName := range []string{"", span.OperationName} {
+		key := metricsKey{serviceName: span.Process.ServiceName, operationName: operationName, ts: ts}
+		metrics, ok := m.metrics[key]
+		if !ok {
+			metrics = &operationMetrics{latencies: map[int]int64{}}
+			m.metrics[key] = metrics
+		}
+		metrics.calls++
+		if isError {
+			metrics.errors++
+		}
+		metrics.latencies[latency]++
+	}
+}
+
+// GetLatencies implements metricstore.Reader#GetLatencies
+func (m *Store) GetLatencies(query *metricstore.QueryParameters) ([]model.MetricPoint, error) {
+	var points []model.MetricPoint
+	for _, step := range m.findStepMetrics(query) {
+		if step.calls > 0 {
+			points = append(points, model.MetricPoint{
+				Timestamp: step.start,
+				Value:     metricstore.LatencyQuantile(step.latencies, query.Quantile),
+			})
+		}
+	}
+	return points, nil
+}
+
+// GetCallRates implements metricstore.Reader#GetCallRates
+func (m *Store) GetCallRates(query *metricstore.QueryParameters) ([]model.MetricPoint, error) {
+	steps := m.findStepMetrics(query)
+	step := query.AtResolution(metricsResolution).Step
+	points := make([]model.MetricPoint, len(steps))
+	for i, s := range steps {
+		points[i] = model.MetricPoint{Timestamp: s.start, Value: float64(s.calls) / step.Seconds()}
+	}
+	return points, nil
+}
+
+// GetErrorRates implements metricstore.Reader#GetErrorRates
+func (m *Store) GetErrorRates(query *metricstore.QueryParameters) ([]model.MetricPoint, error) {
+	var points []model.MetricPoint
+	for _, step := range m.findStepMetrics(query) {
+		if step.calls > 0 {
+			points = append(points, model.MetricPoint{
+				Timestamp: step.start,
+				Value:     float64(step.errors) / float64(step.calls),
+			})
+		}
+	}
+	return points, nil
+}
+
+// findStepMetrics sums the operation metrics of the query by step
+func (m *Store) findStepMetrics(query *metricstore.QueryParameters) []*stepMetrics {
+	stepQuery := query.AtResolution(metricsResolution)
+	var steps []*stepMetrics
+	stepsByStart := map[int64]*stepMetrics{}
+	for _, start := range stepQuery.StepStarts() {
+		step := &stepMetrics{start: start, operationMetrics: operationMetrics{latencies: map[int]int64{}}}
+		steps = append(steps, step)
+		stepsByStart[start.Unix()] = step
+	}
+	m.RLock()
+	defer m.RUnlock()
+	for ts := steps[0].start; !ts.After(query.EndTime); ts = ts.Add(metricsResolution) {
+		key := metricsKey{serviceName: query.ServiceName, operationName: query.OperationName, ts: ts.Unix()}
+		if metrics, ok := m.metrics[key]; ok {
+			stepsByStart[stepQuery.StepStart(ts).Unix()].add(metrics)
+		}
+	}
+	return steps
+}
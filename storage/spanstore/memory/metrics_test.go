@@ -0,0 +1,149 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/storage/metricstore"
+)
+
+var _ metricstore.Reader = &Store{} // check API conformance
+
+func withMetricSpans(f func(store *Store)) {
+	withMemoryStore(func(store *Store) {
+		spans := []struct {
+			operation string
+			start     int64
+			duration  time.Duration
+			isError   bool
+		}{
+			{"op-a", 0, 10 * time.Millisecond, false},
+			{"op-a", 10, 20 * time.Millisecond, true},
+			{"op-b", 20, 30 * time.Millisecond, false},
+			{"op-a", 50, 40 * time.Millisecond, false},
+			{"op-a", 130, 50 * time.Millisecond, false},
+		}
+		for i, s := range spans {
+			span := &model.Span{
+				TraceID:       model.TraceID{Low: uint64(i + 1)},
+				SpanID:        model.SpanID(1),
+				Process:       &model.Process{ServiceName: "service"},
+				OperationName: s.operation,
+				StartTime:     time.Unix(1000+s.start, 0),
+				Duration:      s.duration,
+			}
+			if s.isError {
+				span.Tags = model.KeyValues{model.Bool("error", true)}
+			}
+			store.WriteSpan(span)
+		}
+		f(store)
+	})
+}
+
+func metricsQuery(operation string) *metricstore.QueryParameters {
+	return &metricstore.QueryParameters{
+		ServiceName:   "service",
+		OperationName: operation,
+		EndTime:       time.Unix(1190, 0),
+		Lookback:      190 * time.Second,
+		Step:          time.Minute,
+		Quantile:      0.5,
+	}
+}
+
+// latency returns the upper bound of the latency bucket of a duration, in milliseconds
+func latency(duration time.Duration) float64 {
+	return metricstore.LatencyBucketUpperBound(metricstore.LatencyBucket(duration))
+}
+
+func TestStoreGetLatencies(t *testing.T) {
+	withMetricSpans(func(store *Store) {
+		points, err := store.GetLatencies(metricsQuery(""))
+		assert.NoError(t, err)
+		assert.Equal(t, []model.MetricPoint{
+			{Timestamp: time.Unix(960, 0), Value: latency(10 * time.Millisecond)},
+			{Timestamp: time.Unix(1020, 0), Value: latency(30 * time.Millisecond)},
+			{Timestamp: time.Unix(1080, 0), Value: latency(50 * time.Millisecond)},
+		}, points)
+
+		points, err = store.GetLatencies(metricsQuery("op-a"))
+		assert.NoError(t, err)
+		assert.Equal(t, []model.MetricPoint{
+			{Timestamp: time.Unix(960, 0), Value: latency(10 * time.Millisecond)},
+			{Timestamp: time.Unix(1020, 0), Value: latency(40 * time.Millisecond)},
+			{Timestamp: time.Unix(1080, 0), Value: latency(50 * time.Millisecond)},
+		}, points)
+	})
+}
+
+func TestStoreMetricsUpdatedOnWrite(t *testing.T) {
+	withMetricSpans(func(store *Store) {
+		// the traces are not read by the metrics queries
+		store.traces = map[model.TraceID]*model.Trace{}
+		points, err := store.GetCallRates(metricsQuery(""))
+		assert.NoError(t, err)
+		assert.Equal(t, 2.0/60, points[0].Value)
+
+		store.WriteSpan(&model.Span{
+			TraceID:       model.TraceID{Low: 10},
+			Process:       &model.Process{ServiceName: "service"},
+			OperationName: "op-c",
+			StartTime:     time.Unix(1005, 0),
+			Duration:      time.Millisecond,
+		})
+		points, err = store.GetCallRates(metricsQuery(""))
+		assert.NoError(t, err)
+		assert.Equal(t, 3.0/60, points[0].Value)
+		assert.Len(t, store.metrics, 8)
+	})
+}
+
+func TestStoreGetCallRates(t *testing.T) {
+	withMetricSpans(func(store *Store) {
+		points, err := store.GetCallRates(metricsQuery("op-a"))
+		assert.NoError(t, err)
+		assert.Equal(t, []model.MetricPoint{
+			{Timestamp: time.Unix(960, 0), Value: 2.0 / 60},
+			{Timestamp: time.Unix(1020, 0), Value: 1.0 / 60},
+			{Timestamp: time.Unix(1080, 0), Value: 1.0 / 60},
+			{Timestamp: time.Unix(1140, 0), Value: 0},
+		}, points)
+	})
+}
+
+func TestStoreGetErrorRates(t *testing.T) {
+	withMetricSpans(func(store *Store) {
+		points, err := store.GetErrorRates(metricsQuery(""))
+		assert.NoError(t, err)
+		assert.Equal(t, []model.MetricPoint{
+			{Timestamp: time.Unix(960, 0), Value: 0.5},
+			{Timestamp: time.Unix(1020, 0), Value: 0},
+			{Timestamp: time.Unix(1080, 0), Value: 0},
+		}, points)
+	})
+}
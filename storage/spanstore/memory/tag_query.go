@@ -0,0 +1,139 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package memory
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/storage/spanstore"
+)
+
+// tagMatcher reports whether the tags of a span match a spanstore.TagQuery
+type tagMatcher func(tags model.KeyValues) bool
+
+// newTagMatcher compiles a spanstore.TagQuery into a tagMatcher
+func newTagMatcher(query spanstore.TagQuery) (tagMatcher, error) {
+	switch q := query.(type) {
+	case nil:
+		return func(model.KeyValues) bool { return true }, nil
+	case *spanstore.TagAnd:
+		matchers, err := newTagMatchers(q.Queries)
+		if err != nil {
+			return nil, err
+		}
+		return func(tags model.KeyValues) bool {
+			for _, matcher := range matchers {
+				if !matcher(tags) {
+					return false
+				}
+			}
+			return true
+		}, nil
+	case *spanstore.TagOr:
+		matchers, err := newTagMatchers(q.Queries)
+		if err != nil {
+			return nil, err
+		}
+		return func(tags model.KeyValues) bool {
+			for _, matcher := range matchers {
+				if matcher(tags) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case *spanstore.TagNot:
+		matcher, err := newTagMatcher(q.Query)
+		if err != nil {
+			return nil, err
+		}
+		return func(tags model.KeyValues) bool { return !matcher(tags) }, nil
+	case *spanstore.TagEquals:
+		return matchTagValue(q.Key, func(kv *model.KeyValue) bool {
+			return kv.AsString() == q.Value
+		}), nil
+	case *spanstore.TagPrefix:
+		return matchTagValue(q.Key, func(kv *model.KeyValue) bool {
+			return strings.HasPrefix(kv.AsString(), q.Prefix)
+		}), nil
+	case *spanstore.TagRegexp:
+		// the pattern must match the whole value, like Lucene regular expressions
+		re, err := regexp.Compile("^(?:" + q.Pattern + ")$")
+		if err != nil {
+			return nil, err
+		}
+		return matchTagValue(q.Key, func(kv *model.KeyValue) bool {
+			return re.MatchString(kv.AsString())
+		}), nil
+	case *spanstore.TagExists:
+		return matchTagValue(q.Key, func(*model.KeyValue) bool { return true }), nil
+	case *spanstore.TagRange:
+		return matchTagValue(q.Key, func(kv *model.KeyValue) bool {
+			var value float64
+			switch kv.VType {
+			case model.Int64Type:
+				value = float64(kv.Int64())
+			case model.Float64Type:
+				value = kv.Float64()
+			case model.StringType:
+				// like Elasticsearch, which indexes all tag values as strings
+				v, err := strconv.ParseFloat(kv.VStr, 64)
+				if err != nil {
+					return false
+				}
+				value = v
+			default:
+				return false
+			}
+			return (q.Min == nil || value >= *q.Min) && (q.Max == nil || value <= *q.Max)
+		}), nil
+	}
+	return nil, &spanstore.UnsupportedTagQueryError{Storage: "memory", Query: query}
+}
+
+func newTagMatchers(queries []spanstore.TagQuery) ([]tagMatcher, error) {
+	matchers := make([]tagMatcher, len(queries))
+	for i, query := range queries {
+		matcher, err := newTagMatcher(query)
+		if err != nil {
+			return nil, err
+		}
+		matchers[i] = matcher
+	}
+	return matchers, nil
+}
+
+// matchTagValue returns a tagMatcher of the spans that have a tag key whose value matches.
+// A span can have several tags with the same key.
+func matchTagValue(key string, matches func(kv *model.KeyValue) bool) tagMatcher {
+	return func(tags model.KeyValues) bool {
+		for i := range tags {
+			if tags[i].Key == key && matches(&tags[i]) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,149 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/storage/spanstore"
+)
+
+func floatPointer(f float64) *float64 {
+	return &f
+}
+
+func TestTagMatcher(t *testing.T) {
+	tags := model.KeyValues{
+		model.String("http.url", "https://example.com/users/42"),
+		model.Int64("http.status_code", 503),
+		model.Float64("sampler.param", 0.5),
+		model.String("retries", "2"),
+		model.Bool("error", true),
+	}
+	tests := []struct {
+		query   spanstore.TagQuery
+		matches bool
+	}{
+		{nil, true},
+		{&spanstore.TagEquals{Key: "error", Value: "true"}, true},
+		{&spanstore.TagEquals{Key: "error", Value: "false"}, false},
+		{&spanstore.TagEquals{Key: "missing", Value: "true"}, false},
+		{&spanstore.TagPrefix{Key: "http.url", Prefix: "https://"}, true},
+		{&spanstore.TagPrefix{Key: "http.url", Prefix: "http://"}, false},
+		{&spanstore.TagRegexp{Key: "http.url", Pattern: `.*/users/[0-9]+`}, true},
+		{&spanstore.TagRegexp{Key: "http.url", Pattern: `/users/[0-9]+`}, false},
+		{&spanstore.TagExists{Key: "http.status_code"}, true},
+		{&spanstore.TagExists{Key: "missing"}, false},
+		{&spanstore.TagRange{Key: "http.status_code", Min: floatPointer(500)}, true},
+		{&spanstore.TagRange{Key: "http.status_code", Min: floatPointer(400), Max: floatPointer(499)}, false},
+		{&spanstore.TagRange{Key: "sampler.param", Max: floatPointer(0.5)}, true},
+		{&spanstore.TagRange{Key: "retries", Min: floatPointer(1)}, true},
+		{&spanstore.TagRange{Key: "retries", Min: floatPointer(3)}, false},
+		{&spanstore.TagRange{Key: "http.url", Min: floatPointer(0)}, false},
+		{&spanstore.TagRange{Key: "error", Min: floatPointer(0)}, false},
+		{&spanstore.TagNot{Query: &spanstore.TagExists{Key: "missing"}}, true},
+		{&spanstore.TagAnd{Queries: []spanstore.TagQuery{
+			&spanstore.TagExists{Key: "error"},
+			&spanstore.TagExists{Key: "missing"},
+		}}, false},
+		{&spanstore.TagOr{Queries: []spanstore.TagQuery{
+			&spanstore.TagExists{Key: "error"},
+			&spanstore.TagExists{Key: "missing"},
+		}}, true},
+	}
+	for _, test := range tests {
+		matcher, err := newTagMatcher(test.query)
+		require.NoError(t, err)
+		assert.Equal(t, test.matches, matcher(tags), "%v", test.query)
+	}
+}
+
+func TestTagMatcherInvalidRegexp(t *testing.T) {
+	_, err := newTagMatcher(&spanstore.TagNot{Query: &spanstore.TagRegexp{Key: "k", Pattern: "("}})
+	assert.EqualError(t, err, "error parsing regexp: missing closing ): `^(?:()$`")
+}
+
+func TestStoreFindTracesByTagQuery(t *testing.T) {
+	withPopulatedMemoryStore(func(store *Store) {
+		query := &spanstore.TraceQueryParameters{
+			ServiceName: testingSpan.Process.ServiceName,
+			NumTraces:   10,
+			TagQuery: &spanstore.TagOr{Queries: []spanstore.TagQuery{
+				&spanstore.TagPrefix{Key: "logKey", Prefix: "log"},
+				&spanstore.TagExists{Key: "missing"},
+			}},
+		}
+		traces, err := store.FindTraces(query)
+		require.NoError(t, err)
+		assert.Len(t, traces, 1)
+
+		page, err := store.FindTracePage(query)
+		require.NoError(t, err)
+		assert.Len(t, page.Traces, 1)
+
+		query.TagQuery = &spanstore.TagNot{Query: query.TagQuery}
+		traces, err = store.FindTraces(query)
+		require.NoError(t, err)
+		assert.Empty(t, traces)
+
+		query.TagQuery = &spanstore.TagRegexp{Key: "logKey", Pattern: "("}
+		_, err = store.FindTraces(query)
+		assert.Error(t, err)
+		_, err = store.FindTracePage(query)
+		assert.Error(t, err)
+	})
+}
+
+func TestStoreFindTracesTagAndMatchedBySameSpan(t *testing.T) {
+	store := NewStore()
+	traceID := model.TraceID{Low: 1}
+	for i, tag := range []model.KeyValue{model.String("k1", "v1"), model.String("k2", "v2")} {
+		require.NoError(t, store.WriteSpan(&model.Span{
+			TraceID:   traceID,
+			SpanID:    model.SpanID(i + 1),
+			StartTime: time.Now(),
+			Process:   &model.Process{ServiceName: "service"},
+			Tags:      model.KeyValues{tag},
+		}))
+	}
+	query := &spanstore.TraceQueryParameters{
+		ServiceName: "service",
+		NumTraces:   10,
+		TagQuery: &spanstore.TagAnd{Queries: []spanstore.TagQuery{
+			&spanstore.TagEquals{Key: "k1", Value: "v1"},
+			&spanstore.TagEquals{Key: "k2", Value: "v2"},
+		}},
+	}
+	traces, err := store.FindTraces(query)
+	require.NoError(t, err)
+	assert.Empty(t, traces)
+
+	query.TagQuery = &spanstore.TagOr{Queries: query.TagQuery.(*spanstore.TagAnd).Queries}
+	traces, err = store.FindTraces(query)
+	require.NoError(t, err)
+	assert.Len(t, traces, 1)
+}
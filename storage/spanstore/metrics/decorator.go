@@ -33,6 +33,7 @@ import (
 type ReadMetricsDecorator struct {
 	spanReader           spanstore.Reader
 	findTracesMetrics    *queryMetrics
+	findTracePageMetrics *queryMetrics
 	getTraceMetrics      *queryMetrics
 	getServicesMetrics   *queryMetrics
 	getOperationsMetrics *queryMetrics
@@ -64,6 +65,7 @@ func NewReadMetricsDecorator(spanReader spanstore.Reader, metricsFactory metrics
 	return &ReadMetricsDecorator{
 		spanReader:           spanReader,
 		findTracesMetrics:    buildQueryMetrics("FindTraces", metricsFactory),
+		findTracePageMetrics: buildQueryMetrics("FindTracePage", metricsFactory),
 		getTraceMetrics:      buildQueryMetrics("GetTrace", metricsFactory),
 		getServicesMetrics:   buildQueryMetrics("GetServices", metricsFactory),
 		getOperationsMetrics: buildQueryMetrics("GetOperations", metricsFactory),
@@ -85,6 +87,18 @@ func (m *ReadMetricsDecorator) FindTraces(traceQuery *spanstore.TraceQueryParame
 	return retMe, err
 }
 
+// FindTracePage implements spanstore.Reader#FindTracePage
+func (m *ReadMetricsDecorator) FindTracePage(traceQuery *spanstore.TraceQueryParameters) (*spanstore.TracePage, error) {
+	start := time.Now()
+	retMe, err := m.spanReader.FindTracePage(traceQuery)
+	var responses int
+	if retMe != nil {
+		responses = len(retMe.Traces)
+	}
+	m.findTracePageMetrics.emit(err, time.Since(start), responses)
+	return retMe, err
+}
+
 // GetTrace implements spanstore.Reader#GetTrace
 func (m *ReadMetricsDecorator) GetTrace(traceID model.TraceID) (*model.Trace, error) {
 	start := time.Now()
@@ -46,6 +46,8 @@ func TestSuccessfulUnderlyingCalls(t *testing.T) {
 	mrs.GetTrace(model.TraceID{})
 	mockReader.On("FindTraces", &spanstore.TraceQueryParameters{}).Return([]*model.Trace{}, nil)
 	mrs.FindTraces(&spanstore.TraceQueryParameters{})
+	mockReader.On("FindTracePage", &spanstore.TraceQueryParameters{}).Return(&spanstore.TracePage{}, nil)
+	mrs.FindTracePage(&spanstore.TraceQueryParameters{})
 	counters, gauges := mf.Snapshot()
 	expecteds := map[string]int64{
 		"GetOperations.attempts":  1,
@@ -57,6 +59,9 @@ func TestSuccessfulUnderlyingCalls(t *testing.T) {
 		"FindTraces.attempts":     1,
 		"FindTraces.successes":    1,
 		"FindTraces.errors":       0,
+		"FindTracePage.attempts":  1,
+		"FindTracePage.successes": 1,
+		"FindTracePage.errors":    0,
 		"GetServices.attempts":    1,
 		"GetServices.successes":   1,
 		"GetServices.errors":      0,
@@ -103,6 +108,8 @@ func TestFailingUnderlyingCalls(t *testing.T) {
 	mrs.GetTrace(model.TraceID{})
 	mockReader.On("FindTraces", &spanstore.TraceQueryParameters{}).Return(nil, errors.New("Failure"))
 	mrs.FindTraces(&spanstore.TraceQueryParameters{})
+	mockReader.On("FindTracePage", &spanstore.TraceQueryParameters{}).Return(nil, errors.New("Failure"))
+	mrs.FindTracePage(&spanstore.TraceQueryParameters{})
 	counters, gauges := mf.Snapshot()
 	expecteds := map[string]int64{
 		"GetOperations.attempts":  1,
@@ -114,6 +121,9 @@ func TestFailingUnderlyingCalls(t *testing.T) {
 		"FindTraces.attempts":     1,
 		"FindTraces.successes":    0,
 		"FindTraces.errors":       1,
+		"FindTracePage.attempts":  1,
+		"FindTracePage.successes": 0,
+		"FindTracePage.errors":    1,
 		"GetServices.attempts":    1,
 		"GetServices.successes":   0,
 		"GetServices.errors":      1,
@@ -29,6 +29,29 @@ type Reader struct {
 	mock.Mock
 }
 
+// FindTracePage provides a mock function with given fields: query
+func (_m *Reader) FindTracePage(query *spanstore.TraceQueryParameters) (*spanstore.TracePage, error) {
+	ret := _m.Called(query)
+
+	var r0 *spanstore.TracePage
+	if rf, ok := ret.Get(0).(func(*spanstore.TraceQueryParameters) *spanstore.TracePage); ok {
+		r0 = rf(query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*spanstore.TracePage)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*spanstore.TraceQueryParameters) error); ok {
+		r1 = rf(query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // FindTraces provides a mock function with given fields: query
 func (_m *Reader) FindTraces(query *spanstore.TraceQueryParameters) ([]*model.Trace, error) {
 	ret := _m.Called(query)
@@ -0,0 +1,164 @@
+//
+// Copyright (c) Sematext International
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package spanstore
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TagQuery is a boolean expression on the tags of a span, which include its process tags and log fields.
+// A span matches TraceQueryParameters when it matches both its Tags and its TagQuery.
+// The implementations of TagQuery are the nodes of the expression declared in this file.
+type TagQuery interface {
+	// String returns the expression in the syntax of the query service
+	String() string
+
+	tagQuery()
+}
+
+// TagAnd matches spans that match all of its Queries. All the Queries must be matched by the same span:
+// a trace whose spans match them separately does not match, in every span storage.
+type TagAnd struct {
+	Queries []TagQuery
+}
+
+// TagOr matches spans that match any of its Queries
+type TagOr struct {
+	Queries []TagQuery
+}
+
+// TagNot matches spans that do not match its Query
+type TagNot struct {
+	Query TagQuery
+}
+
+// TagEquals matches spans with a tag Key whose value, as a string, is Value
+type TagEquals struct {
+	Key   string
+	Value string
+}
+
+// TagPrefix matches spans with a tag Key whose value, as a string, starts with Prefix
+type TagPrefix struct {
+	Key    string
+	Prefix string
+}
+
+// TagRegexp matches spans with a tag Key whose whole value, as a string, matches the regular expression Pattern.
+// Patterns should be limited to the syntax shared by Go and Lucene: literals, '.', character classes,
+// grouping, alternation and repetition.
+type TagRegexp struct {
+	Key     string
+	Pattern string
+}
+
+// TagExists matches spans with a tag Key
+type TagExists struct {
+	Key string
+}
+
+// TagRange matches spans with a numeric tag Key whose value is in [Min, Max]. A nil bound is unbounded.
+type TagRange struct {
+	Key string
+	Min *float64
+	Max *float64
+}
+
+// UnsupportedTagQueryError is returned by Reader's FindTraces and FindTracePage if the storage
+// cannot serve a node of the TagQuery, or cannot combine the tags with other parameters of the query,
+// in which case Query is nil and Reason tells which.
+type UnsupportedTagQueryError struct {
+	Storage string
+	Query   TagQuery
+	Reason  string
+}
+
+func (e *UnsupportedTagQueryError) Error() string {
+	if e.Query == nil {
+		return fmt.Sprintf("%s span storage does not support tag queries %s", e.Storage, e.Reason)
+	}
+	return fmt.Sprintf("%s span storage does not support the tag query %s", e.Storage, e.Query)
+}
+
+func (*TagAnd) tagQuery()    {}
+func (*TagOr) tagQuery()     {}
+func (*TagNot) tagQuery()    {}
+func (*TagEquals) tagQuery() {}
+func (*TagPrefix) tagQuery() {}
+func (*TagRegexp) tagQuery() {}
+func (*TagExists) tagQuery() {}
+func (*TagRange) tagQuery()  {}
+
+func (q *TagAnd) String() string {
+	return joinTagQueries(q.Queries, " AND ")
+}
+
+func (q *TagOr) String() string {
+	return joinTagQueries(q.Queries, " OR ")
+}
+
+func (q *TagNot) String() string {
+	return "NOT " + q.Query.String()
+}
+
+func (q *TagEquals) String() string {
+	return q.Key + ":" + quoteTagValue(q.Value)
+}
+
+func (q *TagPrefix) String() string {
+	return q.Key + ":" + q.Prefix + "*"
+}
+
+func (q *TagRegexp) String() string {
+	return q.Key + ":/" + strings.Replace(q.Pattern, "/", `\/`, -1) + "/"
+}
+
+func (q *TagExists) String() string {
+	return q.Key + ":*"
+}
+
+func (q *TagRange) String() string {
+	return fmt.Sprintf("%s:[%s TO %s]", q.Key, formatTagBound(q.Min), formatTagBound(q.Max))
+}
+
+func joinTagQueries(queries []TagQuery, operator string) string {
+	parts := make([]string, len(queries))
+	for i, query := range queries {
+		parts[i] = query.String()
+	}
+	return "(" + strings.Join(parts, operator) + ")"
+}
+
+var tagValueQuoter = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+func quoteTagValue(value string) string {
+	return `"` + tagValueQuoter.Replace(value) + `"`
+}
+
+func formatTagBound(bound *float64) string {
+	if bound == nil {
+		return "*"
+	}
+	return strconv.FormatFloat(*bound, 'g', -1, 64)
+}